@@ -44,7 +44,7 @@ func newComposeService(ctx store.AciContext) aciComposeService {
 	}
 }
 
-func (cs *aciComposeService) Up(ctx context.Context, project *types.Project, detach bool) error {
+func (cs *aciComposeService) Up(ctx context.Context, project *types.Project, options compose.UpOptions) error {
 	logrus.Debugf("Up on project with name %q", project.Name)
 	groupDefinition, err := convert.ToContainerGroup(ctx, cs.ctx, *project, cs.storageLogin)
 	addTag(&groupDefinition, composeContainerTag)
@@ -55,7 +55,7 @@ func (cs *aciComposeService) Up(ctx context.Context, project *types.Project, det
 	return createOrUpdateACIContainers(ctx, cs.ctx, groupDefinition)
 }
 
-func (cs *aciComposeService) Down(ctx context.Context, project string) error {
+func (cs *aciComposeService) Down(ctx context.Context, project string, options compose.DownOptions) error {
 	logrus.Debugf("Down on project with name %q", project)
 
 	cg, err := deleteACIContainerGroup(ctx, cs.ctx, project)
@@ -125,10 +125,54 @@ func (cs *aciComposeService) List(ctx context.Context, project string) ([]compos
 	return stacks, nil
 }
 
-func (cs *aciComposeService) Logs(ctx context.Context, project string, w io.Writer) error {
+func (cs *aciComposeService) Logs(ctx context.Context, project string, w io.Writer, options compose.LogOptions) error {
 	return errdefs.ErrNotImplemented
 }
 
-func (cs *aciComposeService) Convert(ctx context.Context, project *types.Project) ([]byte, error) {
+func (cs *aciComposeService) Convert(ctx context.Context, project *types.Project, options compose.ConvertOptions) (compose.ConvertResult, error) {
+	return compose.ConvertResult{}, errdefs.ErrNotImplemented
+}
+
+func (cs *aciComposeService) Orphans(ctx context.Context, project string) ([]compose.Orphan, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (cs *aciComposeService) RemoveOrphans(ctx context.Context, orphans []compose.Orphan) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (cs *aciComposeService) ProjectOrphans(ctx context.Context, project *types.Project) ([]compose.Orphan, error) {
 	return nil, errdefs.ErrNotImplemented
 }
+
+func (cs *aciComposeService) DNSRecords(ctx context.Context, project string) ([]compose.DNSRecord, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (cs *aciComposeService) Drift(ctx context.Context, project string) ([]compose.DriftedResource, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (cs *aciComposeService) Exec(ctx context.Context, project string, options compose.ExecOptions) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (cs *aciComposeService) Run(ctx context.Context, project string, options compose.RunOptions) (int, error) {
+	return 0, errdefs.ErrNotImplemented
+}
+
+func (cs *aciComposeService) Events(ctx context.Context, project string, consumer func(compose.ServiceEvent), options compose.EventsOptions) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (cs *aciComposeService) Scale(ctx context.Context, project string, options compose.ScaleOptions) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (cs *aciComposeService) Stop(ctx context.Context, project string) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (cs *aciComposeService) Start(ctx context.Context, project string, options compose.StartOptions) error {
+	return errdefs.ErrNotImplemented
+}
@@ -30,17 +30,17 @@ type composeService struct {
 }
 
 // Up executes the equivalent to a `compose up`
-func (c *composeService) Up(context.Context, *types.Project, bool) error {
+func (c *composeService) Up(context.Context, *types.Project, compose.UpOptions) error {
 	return errdefs.ErrNotImplemented
 }
 
 // Down executes the equivalent to a `compose down`
-func (c *composeService) Down(context.Context, string) error {
+func (c *composeService) Down(context.Context, string, compose.DownOptions) error {
 	return errdefs.ErrNotImplemented
 }
 
 // Logs executes the equivalent to a `compose logs`
-func (c *composeService) Logs(context.Context, string, io.Writer) error {
+func (c *composeService) Logs(context.Context, string, io.Writer, compose.LogOptions) error {
 	return errdefs.ErrNotImplemented
 }
 
@@ -54,7 +54,61 @@ func (c *composeService) List(context.Context, string) ([]compose.Stack, error)
 	return nil, errdefs.ErrNotImplemented
 }
 
-// Convert translate compose model into backend's native format
-func (c *composeService) Convert(context.Context, *types.Project) ([]byte, error) {
+// Convert translate compose model into backend's native format, along with the warnings reported by the conversion
+func (c *composeService) Convert(context.Context, *types.Project, compose.ConvertOptions) (compose.ConvertResult, error) {
+	return compose.ConvertResult{}, errdefs.ErrNotImplemented
+}
+
+// Orphans lists resources left behind by removed projects
+func (c *composeService) Orphans(context.Context, string) ([]compose.Orphan, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+// RemoveOrphans deletes resources left behind by removed projects
+func (c *composeService) RemoveOrphans(context.Context, []compose.Orphan) error {
+	return errdefs.ErrNotImplemented
+}
+
+// ProjectOrphans lists resources created for a project outside of its deployment which it doesn't use anymore
+func (c *composeService) ProjectOrphans(context.Context, *types.Project) ([]compose.Orphan, error) {
 	return nil, errdefs.ErrNotImplemented
 }
+
+// DNSRecords lists the private IP addresses project's services host names resolve to
+func (c *composeService) DNSRecords(context.Context, string) ([]compose.DNSRecord, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+// Drift detects project's resources modified or deleted out of band since they were deployed
+func (c *composeService) Drift(context.Context, string) ([]compose.DriftedResource, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+// Exec runs a command in a running task of a service
+func (c *composeService) Exec(context.Context, string, compose.ExecOptions) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (c *composeService) Run(context.Context, string, compose.RunOptions) (int, error) {
+	return 0, errdefs.ErrNotImplemented
+}
+
+// Events reports the events emitted for project's services
+func (c *composeService) Events(context.Context, string, func(compose.ServiceEvent), compose.EventsOptions) error {
+	return errdefs.ErrNotImplemented
+}
+
+// Scale changes the number of replicas of running services
+func (c *composeService) Scale(context.Context, string, compose.ScaleOptions) error {
+	return errdefs.ErrNotImplemented
+}
+
+// Stop stops all tasks of project's services
+func (c *composeService) Stop(context.Context, string) error {
+	return errdefs.ErrNotImplemented
+}
+
+// Start restores the replicas of project's services
+func (c *composeService) Start(context.Context, string, compose.StartOptions) error {
+	return errdefs.ErrNotImplemented
+}
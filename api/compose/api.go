@@ -18,7 +18,10 @@ package compose
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/compose-spec/compose-go/types"
 )
@@ -26,17 +29,235 @@ import (
 // Service manages a compose project
 type Service interface {
 	// Up executes the equivalent to a `compose up`
-	Up(ctx context.Context, project *types.Project, detach bool) error
+	Up(ctx context.Context, project *types.Project, options UpOptions) error
 	// Down executes the equivalent to a `compose down`
-	Down(ctx context.Context, projectName string) error
+	Down(ctx context.Context, projectName string, options DownOptions) error
 	// Logs executes the equivalent to a `compose logs`
-	Logs(ctx context.Context, projectName string, w io.Writer) error
+	Logs(ctx context.Context, projectName string, w io.Writer, options LogOptions) error
 	// Ps executes the equivalent to a `compose ps`
 	Ps(ctx context.Context, projectName string) ([]ServiceStatus, error)
 	// List executes the equivalent to a `docker stack ls`
 	List(ctx context.Context, projectName string) ([]Stack, error)
-	// Convert translate compose model into backend's native format
-	Convert(ctx context.Context, project *types.Project) ([]byte, error)
+	// Convert translate compose model into backend's native format, along with the warnings reported by the conversion
+	Convert(ctx context.Context, project *types.Project, options ConvertOptions) (ConvertResult, error)
+	// Orphans lists resources left behind by removed projects, for all projects if projectName is empty
+	Orphans(ctx context.Context, projectName string) ([]Orphan, error)
+	// RemoveOrphans deletes resources left behind by removed projects
+	RemoveOrphans(ctx context.Context, orphans []Orphan) error
+	// ProjectOrphans lists resources created for a project outside of its deployment which it doesn't use anymore
+	ProjectOrphans(ctx context.Context, project *types.Project) ([]Orphan, error)
+	// DNSRecords lists the private IP addresses project's services host names resolve to
+	DNSRecords(ctx context.Context, projectName string) ([]DNSRecord, error)
+	// Drift detects project's resources modified or deleted out of band since they were deployed
+	Drift(ctx context.Context, projectName string) ([]DriftedResource, error)
+	// Exec executes the equivalent to a `compose exec`, running a command in a running task of a service
+	Exec(ctx context.Context, projectName string, options ExecOptions) error
+	// Run executes the equivalent to a `compose run`, running a one-off task of a service and returning its exit code
+	Run(ctx context.Context, projectName string, options RunOptions) (int, error)
+	// Events reports the events the platform emitted for project's services to consumer, until cancelled when following
+	Events(ctx context.Context, projectName string, consumer func(ServiceEvent), options EventsOptions) error
+	// Scale executes the equivalent to a `compose scale`, changing the number of replicas of running services
+	Scale(ctx context.Context, projectName string, options ScaleOptions) error
+	// Stop executes the equivalent to a `compose stop`, stopping all tasks of project's services without removing them
+	Stop(ctx context.Context, projectName string) error
+	// Start executes the equivalent to a `compose start`, restoring the replicas services had before they were stopped
+	Start(ctx context.Context, projectName string, options StartOptions) error
+}
+
+// StartOptions hold the options for a Start operation
+type StartOptions struct {
+	// Replicas is the number of replicas to run for services with no replicas recorded when stopped, by service name
+	Replicas map[string]int
+}
+
+// ScaleOptions hold the options for a Scale operation
+type ScaleOptions struct {
+	// Replicas is the number of replicas to run, by service name
+	Replicas map[string]int
+	// Force scales services managed by an autoscaling policy, which may later override the number of replicas
+	Force bool
+}
+
+// EventsOptions selects the service events reported by an Events operation
+type EventsOptions struct {
+	// Services restricts events to these services, all services when empty
+	Services []string
+	// Follow keeps reporting new events until cancelled
+	Follow bool
+}
+
+// ServiceEvent is an event the platform emitted for a service, such as a task placement failure
+type ServiceEvent struct {
+	ID        string    `json:"id"`
+	Service   string    `json:"service"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+}
+
+// LogOptions selects the log events shown by a Logs operation
+type LogOptions struct {
+	// Services restricts logs to these services, all services when empty
+	Services []string
+	// Since only shows log events emitted since this time, all retained events when zero
+	Since time.Time
+	// Tail only shows the last Tail events of each service, all events when negative
+	Tail int
+	// Follow keeps showing new log events until cancelled
+	Follow bool
+}
+
+// UpOptions hold the options for an Up operation
+type UpOptions struct {
+	// Detach returns as soon as deployment has been started, without waiting for completion
+	Detach bool
+	// SkipPreflight disables the check for required permissions before deployment
+	SkipPreflight bool
+	// InlineSecrets embeds secrets content in the deployment template instead of creating them beforehand
+	InlineSecrets bool
+	// SkipScan disables the image scan findings gate services may opt in
+	SkipScan bool
+	// Build builds and pushes images of all services with a build section, even those already setting an image
+	Build bool
+	// Force downgrades compatibility errors to warnings, dropping the incompatible attributes
+	Force bool
+	// DryRun prints the changes the deployment would apply to the stack instead of deploying it
+	DryRun bool
+	// Timeout fails the deployment when not completed in time, rolling it back unless NoRollback is set
+	Timeout time.Duration
+	// NoRollback keeps the resources of a failed stack creation for inspection instead of deleting the stack
+	NoRollback bool
+	// StackName deploys the project under this name, so a project can be deployed as several environments
+	StackName string
+	// EnvFilesBucket uploads services env_file to this bucket, so their content is kept out of the template
+	EnvFilesBucket string
+	// LabelsAsTags also sets services labels as tags on their resources
+	LabelsAsTags bool
+}
+
+// DownOptions hold the options for a Down operation
+type DownOptions struct {
+	// Force deletes the project even when other projects depend on its resources
+	Force bool
+	// Volumes confirms project's volumes get deleted with the project
+	Volumes bool
+	// Unprotect disables the termination protection of project's stack so it can be deleted
+	Unprotect bool
+}
+
+// ProtectedStackError is returned by Down when project's stack has termination protection, without DownOptions.Unprotect
+// being set
+type ProtectedStackError struct {
+	Stack  string
+	Region string
+}
+
+func (e *ProtectedStackError) Error() string {
+	return fmt.Sprintf("stack %s in region %s has termination protection, use --force to disable it and delete the stack", e.Stack, e.Region)
+}
+
+// VolumesDeletionError is returned by Down when it would delete project's volumes without DownOptions.Volumes being set
+type VolumesDeletionError struct {
+	Project string
+	Volumes []string
+}
+
+func (e *VolumesDeletionError) Error() string {
+	return fmt.Sprintf("deleting project %s also deletes volumes %s, use --volumes to confirm", e.Project, strings.Join(e.Volumes, ", "))
+}
+
+// ConvertOptions hold the options for a Convert operation
+type ConvertOptions struct {
+	// WarningsAsErrors lists the warning codes which make the conversion fail
+	WarningsAsErrors []string
+	// WarningsFormat selects how warnings get reported, either "text" (default) or "json"
+	WarningsFormat string
+	// InlineSecrets embeds secrets content in the converted template instead of creating them beforehand
+	InlineSecrets bool
+	// Format selects the converted template format, either "json" (default) or "yaml"
+	Format string
+	// Force downgrades compatibility errors to warnings, dropping the incompatible attributes
+	Force bool
+	// StackName converts the project for deployment under this name
+	StackName string
+	// EnvFilesBucket uploads services env_file to this bucket, so their content is kept out of the template
+	EnvFilesBucket string
+	// LabelsAsTags also sets services labels as tags on their resources
+	LabelsAsTags bool
+}
+
+// ConvertResult is the outcome of a compose model conversion
+type ConvertResult struct {
+	// Template is the compose model converted into backend's native format
+	Template []byte
+	// Warnings are the non-fatal issues detected while converting
+	Warnings []Warning
+}
+
+// Warning is a non-fatal issue detected while converting a compose model. Codes are stable across releases, so callers
+// can gate on them
+type Warning struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Service  string `json:"service,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Orphan is a resource created for a project which isn't managed by the project's stack anymore
+type Orphan struct {
+	ID      string
+	Type    string
+	Project string
+	Created time.Time
+	// MonthlyCost is an estimate in USD of the resource's monthly cost
+	MonthlyCost float64
+}
+
+// ExecOptions hold the options for an Exec operation
+type ExecOptions struct {
+	// Service to run the command in one of the running tasks of
+	Service string
+	// Container of the task to run the command in, service's own container if empty
+	Container string
+	Command   string
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+}
+
+// RunOptions hold the options for a Run operation
+type RunOptions struct {
+	// Service to run a one-off task of, with the same task definition and network configuration
+	Service string
+	// Command overrides the command of service's container
+	Command []string
+	// Stdout receives the logs of service's container while the task runs
+	Stdout io.Writer
+}
+
+// DNSRecord maps a service host name to the private IP address of one of its instances
+type DNSRecord struct {
+	Service string
+	Name    string
+	IP      string
+}
+
+// DriftedResource is a project's resource modified or deleted out of band since it was deployed
+type DriftedResource struct {
+	ID         string
+	PhysicalID string
+	Type       string
+	// Status is either MODIFIED or DELETED
+	Status      string
+	Differences []PropertyDifference
+}
+
+// PropertyDifference is the difference between the deployed value of a resource property and its actual one
+type PropertyDifference struct {
+	Path     string
+	Expected string
+	Actual   string
+	// Type is either ADD, REMOVE or NOT_EQUAL
+	Type string
 }
 
 // PortPublisher hold status about published port
@@ -49,12 +270,25 @@ type PortPublisher struct {
 
 // ServiceStatus hold status about a service
 type ServiceStatus struct {
-	ID         string
-	Name       string
+	ID   string
+	Name string
+	// Status is either RUNNING, STARTING while replicas are missing, or STOPPED once stopped by a Stop operation
+	Status     string
 	Replicas   int
 	Desired    int
 	Ports      []string
 	Publishers []PortPublisher
+	Tasks      []TaskStatus
+}
+
+// TaskStatus hold status about a running task of a service
+type TaskStatus struct {
+	ID      string
+	Started time.Time
+	// Status is the task's last known lifecycle state, such as RUNNING or PENDING
+	Status string
+	// Health is the task's health status, UNKNOWN when it has no health check
+	Health string
 }
 
 const (
@@ -70,6 +304,8 @@ const (
 	UNKNOWN string = "Unknown"
 	// FAILED indicates that stack deployment failed
 	FAILED string = "Failed"
+	// STOPPED indicates that services have been stopped, and run no task until started again
+	STOPPED string = "Stopped"
 )
 
 // Stack holds the name and state of a compose application/stack
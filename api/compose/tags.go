@@ -21,6 +21,8 @@ const (
 	ProjectTag = "com.docker.compose.project"
 	// NetworkTag allow to track resource related to a compose network
 	NetworkTag = "com.docker.compose.network"
+	// VolumeTag allow to track resource related to a compose volume
+	VolumeTag = "com.docker.compose.volume"
 	// ServiceTag allow to track resource related to a compose service
 	ServiceTag = "com.docker.compose.service"
 )
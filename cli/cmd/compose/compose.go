@@ -18,6 +18,7 @@ package compose
 
 import (
 	"context"
+	"time"
 
 	"github.com/compose-spec/compose-go/cli"
 
@@ -33,8 +34,29 @@ type composeOptions struct {
 	WorkingDir  string
 	ConfigPaths []string
 	Environment []string
+	Profiles    []string
 	Format      string
 	Detach      bool
+
+	SkipPreflight    bool
+	RemoveOrphans    bool
+	OrphansConfirmed bool
+	All              bool
+	InlineSecrets    bool
+	SkipScan         bool
+	Build            bool
+	Force            bool
+	Volumes          bool
+	DryRun           bool
+	NoRollback       bool
+	Timeout          time.Duration
+	StackName        string
+	EnvFilesBucket   string
+	LabelsAsTags     bool
+
+	WarningsAsErrors []string
+	WarningsFormat   string
+	ErrorFormat      string
 }
 
 func (o *composeOptions) toProjectName() (string, error) {
@@ -78,7 +100,14 @@ func Command(contextType string) *cobra.Command {
 		psCommand(),
 		listCommand(),
 		logsCommand(),
+		eventsCommand(),
+		scaleCommand(),
+		stopCommand(),
+		startCommand(),
 		convertCommand(),
+		execCommand(),
+		runCommand(),
+		alphaCommand(),
 	)
 
 	return command
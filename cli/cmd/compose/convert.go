@@ -19,52 +19,87 @@ package compose
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 
-	"github.com/compose-spec/compose-go/cli"
 	"github.com/spf13/cobra"
 
 	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
 )
 
+type convertOptions struct {
+	composeOptions
+	Output    string
+	Overwrite bool
+}
+
 func convertCommand() *cobra.Command {
-	opts := composeOptions{}
+	opts := convertOptions{}
 	convertCmd := &cobra.Command{
 		Use:   "convert",
 		Short: "Converts the compose file to a cloud format (default: cloudformation)",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runConvert(cmd.Context(), opts)
+			return reportError(opts.ErrorFormat, runConvert(cmd.Context(), opts))
 		},
 	}
 	convertCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
 	convertCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
 	convertCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
 	convertCmd.Flags().StringArrayVarP(&opts.Environment, "environment", "e", []string{}, "Environment variables")
+	convertCmd.Flags().StringArrayVar(&opts.Profiles, "profile", []string{}, "Enable the services of this profile, defaults to COMPOSE_PROFILES")
+	convertCmd.Flags().StringSliceVar(&opts.WarningsAsErrors, "warnings-as-errors", []string{}, "Comma separated list of warning codes to be considered as errors")
+	convertCmd.Flags().StringVar(&opts.WarningsFormat, "warnings-format", "text", "Format of the reported warnings. Values: [text | json]")
+	convertCmd.Flags().BoolVar(&opts.InlineSecrets, "inline-secrets", false, "Embed secrets content in the converted template instead of creating them")
+	convertCmd.Flags().StringVar(&opts.ErrorFormat, "error-format", "text", "Format of the reported error. Values: [text | json]")
+	convertCmd.Flags().StringVar(&opts.Format, "format", "json", "Format of the converted template. Values: [json | yaml]")
+	convertCmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Write to file instead of stdout")
+	convertCmd.Flags().BoolVar(&opts.Overwrite, "overwrite", false, "Overwrite the output file if it already exists")
+	convertCmd.Flags().BoolVar(&opts.Force, "force", false, "Ignore attributes incompatible with the target cloud platform instead of failing")
+	convertCmd.Flags().StringVar(&opts.StackName, "stack-name", "", "Convert the project for deployment as this CloudFormation stack")
+	convertCmd.Flags().BoolVar(&opts.LabelsAsTags, "labels-as-tags", false, "Also set services labels as tags on their resources")
+	convertCmd.Flags().StringVar(&opts.EnvFilesBucket, "env-files-bucket", "", "Reference env_file as environment files uploaded to this S3 bucket, instead of inlining their variables in the template")
 
 	return convertCmd
 }
 
-func runConvert(ctx context.Context, opts composeOptions) error {
-	var json []byte
-	c, err := client.New(ctx)
-	if err != nil {
-		return err
+func runConvert(ctx context.Context, opts convertOptions) error {
+	if opts.Format != "json" && opts.Format != "yaml" {
+		return fmt.Errorf("unsupported format %q", opts.Format)
+	}
+	if opts.Output != "" && !opts.Overwrite {
+		if _, err := os.Stat(opts.Output); err == nil {
+			return fmt.Errorf("%s already exists, use --overwrite to replace it", opts.Output)
+		}
 	}
 
-	options, err := opts.toProjectOptions()
+	c, err := client.New(ctx)
 	if err != nil {
 		return err
 	}
 
-	project, err := cli.ProjectFromOptions(options)
+	project, err := opts.toProject()
 	if err != nil {
 		return err
 	}
 
-	json, err = c.ComposeService().Convert(ctx, project)
+	converted, err := c.ComposeService().Convert(ctx, project, compose.ConvertOptions{
+		WarningsAsErrors: opts.WarningsAsErrors,
+		WarningsFormat:   opts.WarningsFormat,
+		InlineSecrets:    opts.InlineSecrets,
+		Format:           opts.Format,
+		Force:            opts.Force,
+		StackName:        opts.StackName,
+		EnvFilesBucket:   opts.EnvFilesBucket,
+		LabelsAsTags:     opts.LabelsAsTags,
+	})
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(string(json))
-	return nil
+	if opts.Output == "" {
+		fmt.Println(string(converted.Template))
+		return nil
+	}
+	return ioutil.WriteFile(opts.Output, converted.Template, 0644)
 }
@@ -0,0 +1,117 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
+)
+
+const dnsWatchInterval = 10 * time.Second
+
+type dnsExportOptions struct {
+	composeOptions
+	Output string
+	Watch  bool
+}
+
+func dnsExportCommand() *cobra.Command {
+	opts := dnsExportOptions{}
+	dnsCmd := &cobra.Command{
+		Use:   "dns-export",
+		Short: "Export project's services host names as an /etc/hosts fragment or a dnsmasq config",
+		Long: `Export project's services host names as an /etc/hosts fragment or a dnsmasq config.
+Only private IP addresses are exported, resolving them requires connectivity to the VPC, typically by a VPN.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDNSExport(cmd.Context(), opts)
+		},
+	}
+	dnsCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
+	dnsCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
+	dnsCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	dnsCmd.Flags().StringVar(&opts.Format, "format", "hosts", "Format the output. Values: [hosts | dnsmasq]")
+	dnsCmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Write to file instead of stdout")
+	dnsCmd.Flags().BoolVar(&opts.Watch, "watch", false, "Keep the export updated as services instances change")
+	return dnsCmd
+}
+
+func runDNSExport(ctx context.Context, opts dnsExportOptions) error {
+	if opts.Format != "hosts" && opts.Format != "dnsmasq" {
+		return fmt.Errorf("unsupported format %q", opts.Format)
+	}
+	c, err := client.New(ctx)
+	if err != nil {
+		return err
+	}
+	projectName, err := opts.toProjectName()
+	if err != nil {
+		return err
+	}
+
+	var previous []byte
+	for {
+		records, err := c.ComposeService().DNSRecords(ctx, projectName)
+		if err != nil {
+			return err
+		}
+		content := formatDNSRecords(records, opts.Format)
+		if !bytes.Equal(content, previous) {
+			if err := writeDNSExport(opts.Output, content); err != nil {
+				return err
+			}
+			previous = content
+		}
+		if !opts.Watch {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(dnsWatchInterval):
+		}
+	}
+}
+
+func formatDNSRecords(records []compose.DNSRecord, format string) []byte {
+	var b bytes.Buffer
+	for _, r := range records {
+		switch format {
+		case "dnsmasq":
+			fmt.Fprintf(&b, "host-record=%s,%s,%s\n", r.Name, r.Service, r.IP)
+		default:
+			fmt.Fprintf(&b, "%s\t%s %s\n", r.IP, r.Name, r.Service)
+		}
+	}
+	return b.Bytes()
+}
+
+func writeDNSExport(output string, content []byte) error {
+	if output == "" {
+		_, err := os.Stdout.Write(content)
+		return err
+	}
+	return ioutil.WriteFile(output, content, 0644)
+}
@@ -18,11 +18,18 @@ package compose
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
 
+	"github.com/moby/term"
 	"github.com/spf13/cobra"
 
 	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
 	"github.com/docker/compose-cli/progress"
+	"github.com/docker/compose-cli/prompt"
 )
 
 func downCommand() *cobra.Command {
@@ -30,12 +37,17 @@ func downCommand() *cobra.Command {
 	downCmd := &cobra.Command{
 		Use: "down",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDown(cmd.Context(), opts)
+			return reportError(opts.ErrorFormat, runDown(cmd.Context(), opts))
 		},
 	}
 	downCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
 	downCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
 	downCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	downCmd.Flags().BoolVar(&opts.Force, "force", false, "Delete project even if other projects depend on its resources, or disable its termination protection after confirmation")
+	downCmd.Flags().BoolVarP(&opts.Volumes, "volumes", "v", false, "Delete project's volumes without confirmation")
+	downCmd.Flags().BoolVar(&opts.All, "all", false, "Also delete resources created outside of the project's stack, after confirmation")
+	downCmd.Flags().BoolVar(&opts.OrphansConfirmed, "remove-orphans", false, "Also delete resources created outside of the project's stack, without confirmation")
+	downCmd.Flags().StringVar(&opts.ErrorFormat, "error-format", "text", "Format of the reported error. Values: [text | json]")
 
 	return downCmd
 }
@@ -46,12 +58,47 @@ func runDown(ctx context.Context, opts composeOptions) error {
 		return err
 	}
 
-	_, err = progress.Run(ctx, func(ctx context.Context) (string, error) {
-		projectName, err := opts.toProjectName()
-		if err != nil {
-			return "", err
+	projectName, err := opts.toProjectName()
+	if err != nil {
+		return err
+	}
+	unprotect := false
+	down := func(volumes bool) error {
+		_, err := progress.Run(ctx, func(ctx context.Context) (string, error) {
+			return projectName, c.ComposeService().Down(ctx, projectName, compose.DownOptions{
+				Force:     opts.Force,
+				Volumes:   volumes,
+				Unprotect: unprotect,
+			})
+		})
+		return err
+	}
+	err = down(opts.Volumes)
+	var protectedErr *compose.ProtectedStackError
+	if errors.As(err, &protectedErr) && opts.Force {
+		if _, isTerminal := term.GetFdInfo(os.Stdin); !isTerminal {
+			return err
+		}
+		confirm, perr := prompt.User{}.Confirm(fmt.Sprintf("Disable termination protection and delete stack %s in region %s?", protectedErr.Stack, protectedErr.Region), false)
+		if perr != nil || !confirm {
+			return perr
 		}
-		return projectName, c.ComposeService().Down(ctx, projectName)
-	})
-	return err
+		unprotect = true
+		err = down(opts.Volumes)
+	}
+	var volumesErr *compose.VolumesDeletionError
+	if errors.As(err, &volumesErr) {
+		if _, isTerminal := term.GetFdInfo(os.Stdin); !isTerminal {
+			return err
+		}
+		confirm, perr := prompt.User{}.Confirm(fmt.Sprintf("Delete volumes %s? This can't be undone", strings.Join(volumesErr.Volumes, ", ")), false)
+		if perr != nil || !confirm {
+			return perr
+		}
+		err = down(true)
+	}
+	if err != nil || !(opts.All || opts.OrphansConfirmed) {
+		return err
+	}
+	return listOrphans(ctx, c, projectName, "", true, opts.OrphansConfirmed)
 }
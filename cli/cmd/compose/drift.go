@@ -0,0 +1,97 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/formatter"
+)
+
+func driftCommand() *cobra.Command {
+	opts := composeOptions{}
+	driftCmd := &cobra.Command{
+		Use:   "drift",
+		Short: "List project's resources modified or deleted out of band since deployment",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDrift(cmd.Context(), opts)
+		},
+	}
+	addComposeCommonFlags(driftCmd.Flags(), &opts)
+	driftCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
+	driftCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	return driftCmd
+}
+
+func runDrift(ctx context.Context, opts composeOptions) error {
+	c, err := client.New(ctx)
+	if err != nil {
+		return err
+	}
+	projectName, err := opts.toProjectName()
+	if err != nil {
+		return err
+	}
+	drifted, err := c.ComposeService().Drift(ctx, projectName)
+	if err != nil {
+		return err
+	}
+	return printDrift(os.Stdout, drifted, opts.Format)
+}
+
+func printDrift(out io.Writer, drifted []compose.DriftedResource, format string) error {
+	if len(drifted) == 0 {
+		if format == "" || format == formatter.PRETTY {
+			_, _ = fmt.Fprintln(out, "No drifted resources found")
+		}
+		return nil
+	}
+	err := formatter.Print(drifted, format, out, func(w io.Writer) {
+		for _, resource := range drifted {
+			if len(resource.Differences) == 0 {
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t-\t-\t-\n", resource.ID, resource.Type, resource.Status)
+			}
+			for _, d := range resource.Differences {
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", resource.ID, resource.Type, resource.Status, d.Path, valueOrDash(d.Expected), valueOrDash(d.Actual))
+			}
+		}
+	}, "RESOURCE", "TYPE", "STATUS", "PROPERTY", "EXPECTED", "ACTUAL")
+	if err != nil || (format != "" && format != formatter.PRETTY) {
+		return err
+	}
+	for _, resource := range drifted {
+		if resource.Status == "MODIFIED" {
+			_, _ = fmt.Fprintln(out, "\nOut of band changes get overwritten once a deployment updates the modified resource, report these in the compose file first to keep them")
+			break
+		}
+	}
+	return nil
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/docker/compose-cli/errdefs"
+)
+
+const errorFormatJSON = "json"
+
+// reportError writes err in a machine-readable form when format is json, and marks it as reported
+func reportError(format string, err error) error {
+	if err == nil || format != errorFormatJSON || errdefs.IsErrCanceled(err) {
+		return err
+	}
+	raw, e := json.MarshalIndent(errdefs.ToDetails(err), "", "  ")
+	if e != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, string(raw))
+	return errdefs.Reported(err)
+}
@@ -0,0 +1,84 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
+)
+
+type eventsOptions struct {
+	composeOptions
+	Follow bool
+	JSON   bool
+}
+
+func eventsCommand() *cobra.Command {
+	opts := eventsOptions{}
+	eventsCmd := &cobra.Command{
+		Use:   "events [SERVICE...]",
+		Short: "Show the events emitted for services, such as tasks placement failures",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEvents(cmd.Context(), opts, args)
+		},
+	}
+	eventsCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
+	eventsCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
+	eventsCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	eventsCmd.Flags().BoolVar(&opts.Follow, "follow", false, "Follow new events")
+	eventsCmd.Flags().BoolVar(&opts.JSON, "json", false, "Output events as one JSON object per line")
+
+	return eventsCmd
+}
+
+func runEvents(ctx context.Context, opts eventsOptions, services []string) error {
+	c, err := client.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	projectName, err := opts.toProjectName()
+	if err != nil {
+		return err
+	}
+	return c.ComposeService().Events(ctx, projectName, printEvent(os.Stdout, opts.JSON), compose.EventsOptions{
+		Services: services,
+		Follow:   opts.Follow,
+	})
+}
+
+// printEvent writes events as text lines, or as JSON records which log aggregators can parse
+func printEvent(w io.Writer, asJSON bool) func(compose.ServiceEvent) {
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		return func(event compose.ServiceEvent) {
+			_ = encoder.Encode(event)
+		}
+	}
+	return func(event compose.ServiceEvent) {
+		_, _ = fmt.Fprintf(w, "%s %s %s\n", event.Timestamp.UTC().Format(time.RFC3339), event.Service, event.Message)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
+)
+
+type execOptions struct {
+	composeOptions
+	Container string
+}
+
+func execCommand() *cobra.Command {
+	opts := execOptions{}
+	execCmd := &cobra.Command{
+		Use:   "exec SERVICE COMMAND [ARGS...]",
+		Short: "Execute a command in a running task of a service",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExec(cmd.Context(), opts, args[0], shellJoin(args[1:]))
+		},
+	}
+	execCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
+	execCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
+	execCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	execCmd.Flags().StringVar(&opts.Container, "container", "", "Container of the task to execute the command in (default: service's container)")
+
+	return execCmd
+}
+
+// shellJoin joins args into the single command line ECS Exec runs, quoting arguments so they don't get split or expanded
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+func runExec(ctx context.Context, opts execOptions, service string, command string) error {
+	c, err := client.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	projectName, err := opts.toProjectName()
+	if err != nil {
+		return err
+	}
+	return c.ComposeService().Exec(ctx, projectName, compose.ExecOptions{
+		Service:   service,
+		Container: opts.Container,
+		Command:   command,
+		Stdin:     os.Stdin,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+	})
+}
@@ -59,7 +59,7 @@ func runList(ctx context.Context, opts composeOptions) error {
 	}
 
 	view := viewFromStackList(stackList)
-	return formatter.Print(view, opts.Format, os.Stdout, func(w io.Writer) {
+	return formatter.Print(jsonFromStackList(stackList), opts.Format, os.Stdout, func(w io.Writer) {
 		for _, stack := range view {
 			_, _ = fmt.Fprintf(w, "%s\t%s\n", stack.Name, stack.Status)
 		}
@@ -81,3 +81,24 @@ func viewFromStackList(stackList []compose.Stack) []stackView {
 	}
 	return retList
 }
+
+// stackJSON is the schema of projects printed by `ls --format json`, fields MUST be kept stable across releases
+type stackJSON struct {
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func jsonFromStackList(stackList []compose.Stack) []stackJSON {
+	retList := make([]stackJSON, len(stackList))
+	for i, s := range stackList {
+		retList[i] = stackJSON{
+			Name:   s.Name,
+			ID:     s.ID,
+			Status: s.Status,
+			Reason: s.Reason,
+		}
+	}
+	return retList
+}
@@ -18,29 +18,53 @@ package compose
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
 )
 
+type logsOptions struct {
+	composeOptions
+	Since  string
+	Tail   string
+	Follow bool
+}
+
 func logsCommand() *cobra.Command {
-	opts := composeOptions{}
+	opts := logsOptions{}
 	logsCmd := &cobra.Command{
-		Use: "logs",
+		Use: "logs [SERVICE...]",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runLogs(cmd.Context(), opts)
+			return runLogs(cmd.Context(), opts, args)
 		},
 	}
 	logsCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
 	logsCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
 	logsCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	logsCmd.Flags().StringArrayVar(&opts.Profiles, "profile", []string{}, "Only show logs of the services of this profile, defaults to COMPOSE_PROFILES")
+	logsCmd.Flags().StringVar(&opts.Since, "since", "", "Show logs since a timestamp (e.g. 2021-01-02T13:23:37Z) or relative duration (e.g. 42m)")
+	logsCmd.Flags().StringVar(&opts.Tail, "tail", "all", "Number of lines to show from the end of the logs of each service")
+	logsCmd.Flags().BoolVar(&opts.Follow, "follow", false, "Follow log output")
 
 	return logsCmd
 }
 
-func runLogs(ctx context.Context, opts composeOptions) error {
+func runLogs(ctx context.Context, opts logsOptions, services []string) error {
+	since, err := parseSince(opts.Since, time.Now())
+	if err != nil {
+		return err
+	}
+	tail, err := parseTail(opts.Tail)
+	if err != nil {
+		return err
+	}
+
 	c, err := client.New(ctx)
 	if err != nil {
 		return err
@@ -50,5 +74,48 @@ func runLogs(ctx context.Context, opts composeOptions) error {
 	if err != nil {
 		return err
 	}
-	return c.ComposeService().Logs(ctx, projectName, os.Stdout)
+	if len(services) == 0 {
+		// without services listed, logs of all services enabled by active profiles are shown
+		enabled, err := opts.enabledServices()
+		if err != nil {
+			return err
+		}
+		if enabled != nil && len(enabled) == 0 {
+			return nil
+		}
+		services = enabled
+	}
+	return c.ComposeService().Logs(ctx, projectName, os.Stdout, compose.LogOptions{
+		Services: services,
+		Since:    since,
+		Tail:     tail,
+		Follow:   opts.Follow,
+	})
+}
+
+// parseSince parses --since as either a duration before now or an RFC3339 timestamp
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q, must be a duration such as 42m or an RFC3339 timestamp", value)
+	}
+	return t, nil
+}
+
+// parseTail parses --tail as a number of lines, "all" is returned as -1
+func parseTail(value string) (int, error) {
+	if value == "all" {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid --tail %q, must be a positive number or all", value)
+	}
+	return n, nil
 }
@@ -0,0 +1,135 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+
+	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/formatter"
+	"github.com/docker/compose-cli/prompt"
+)
+
+func alphaCommand() *cobra.Command {
+	alphaCmd := &cobra.Command{
+		Use:   "alpha",
+		Short: "Experimental commands",
+	}
+	alphaCmd.AddCommand(orphansCommand(), dnsExportCommand(), driftCommand())
+	return alphaCmd
+}
+
+func orphansCommand() *cobra.Command {
+	opts := composeOptions{}
+	orphansCmd := &cobra.Command{
+		Use:   "orphans",
+		Short: "List resources left behind by removed projects",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOrphans(cmd.Context(), opts)
+		},
+	}
+	addComposeCommonFlags(orphansCmd.Flags(), &opts)
+	orphansCmd.Flags().BoolVar(&opts.RemoveOrphans, "delete", false, "Delete listed resources after confirmation")
+	return orphansCmd
+}
+
+func runOrphans(ctx context.Context, opts composeOptions) error {
+	c, err := client.New(ctx)
+	if err != nil {
+		return err
+	}
+	return listOrphans(ctx, c, opts.Name, opts.Format, opts.RemoveOrphans, false)
+}
+
+// listOrphans prints project's orphaned resources, and deletes them if requested to, after confirmation unless confirmed
+func listOrphans(ctx context.Context, c *client.Client, projectName string, format string, remove bool, confirmed bool) error {
+	orphans, err := c.ComposeService().Orphans(ctx, projectName)
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		if remove {
+			fmt.Println("No orphaned resources found")
+		}
+		return nil
+	}
+
+	err = printOrphans(orphans, format)
+	if err != nil || !remove {
+		return err
+	}
+	return removeOrphans(ctx, c, orphans, confirmed)
+}
+
+func printOrphans(orphans []compose.Orphan, format string) error {
+	view := viewFromOrphanList(orphans)
+	return formatter.Print(view, format, os.Stdout, func(w io.Writer) {
+		for _, orphan := range view {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t$%.2f\n", orphan.ID, orphan.Type, orphan.Project, orphan.Age, orphan.MonthlyCost)
+		}
+	}, "ID", "TYPE", "PROJECT", "AGE", "EST. MONTHLY COST")
+}
+
+// removeOrphans deletes orphaned resources, asking for confirmation first unless confirmed
+func removeOrphans(ctx context.Context, c *client.Client, orphans []compose.Orphan, confirmed bool) error {
+	if !confirmed {
+		if _, isTerminal := term.GetFdInfo(os.Stdin); !isTerminal {
+			fmt.Println("Use --remove-orphans to delete them")
+			return nil
+		}
+		confirm, err := prompt.User{}.Confirm(fmt.Sprintf("Delete %d resources? This can't be undone", len(orphans)), false)
+		if err != nil || !confirm {
+			return err
+		}
+	}
+	return c.ComposeService().RemoveOrphans(ctx, orphans)
+}
+
+type orphanView struct {
+	ID          string
+	Type        string
+	Project     string
+	Age         string
+	MonthlyCost float64
+}
+
+func viewFromOrphanList(orphans []compose.Orphan) []orphanView {
+	retList := make([]orphanView, len(orphans))
+	for i, o := range orphans {
+		age := "-"
+		if !o.Created.IsZero() {
+			age = units.HumanDuration(time.Since(o.Created))
+		}
+		retList[i] = orphanView{
+			ID:          o.ID,
+			Type:        o.Type,
+			Project:     o.Project,
+			Age:         age,
+			MonthlyCost: o.MonthlyCost,
+		}
+	}
+	return retList
+}
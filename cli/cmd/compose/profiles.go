@@ -0,0 +1,153 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/compose-spec/compose-go/cli"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// extensionProfiles lists the profiles enabling a service. The compose-go schema we depend on doesn't accept the
+// profiles attribute yet, so they're read from this extension
+const extensionProfiles = "x-profiles"
+
+// composeProfiles sets the active profiles when --profile isn't set, as a comma separated list
+const composeProfiles = "COMPOSE_PROFILES"
+
+// activeProfiles are the profiles set by --profile, or by COMPOSE_PROFILES when the flag isn't set
+func (o *composeOptions) activeProfiles() []string {
+	if len(o.Profiles) > 0 {
+		return o.Profiles
+	}
+	var profiles []string
+	for _, profile := range strings.Split(os.Getenv(composeProfiles), ",") {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles
+}
+
+// toProject loads the project, with only the services enabled by active profiles
+func (o *composeOptions) toProject() (*types.Project, error) {
+	options, err := o.toProjectOptions()
+	if err != nil {
+		return nil, err
+	}
+	project, err := cli.ProjectFromOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	err = applyProfiles(project, o.activeProfiles())
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// enabledServices lists the services enabled by active profiles. It's nil when the project is only known by its name
+// without a compose file to read profiles from, so that all services are considered
+func (o *composeOptions) enabledServices() ([]string, error) {
+	project, err := o.toProject()
+	if err != nil {
+		if o.Name != "" && len(o.activeProfiles()) == 0 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names := []string{}
+	for _, service := range project.Services {
+		names = append(names, service.Name)
+	}
+	return names, nil
+}
+
+// serviceProfiles returns the profiles enabling service, none meaning the service is always enabled
+func serviceProfiles(service types.ServiceConfig) ([]string, error) {
+	x, ok := service.Extensions[extensionProfiles]
+	if !ok {
+		return nil, nil
+	}
+	items, ok := x.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("service %s: %s must be a list of profiles", service.Name, extensionProfiles)
+	}
+	var profiles []string
+	for _, item := range items {
+		profile, ok := item.(string)
+		if !ok || profile == "" {
+			return nil, fmt.Errorf("service %s: %s must be a list of profiles", service.Name, extensionProfiles)
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// applyProfiles removes from project the services which aren't enabled by any of the active profiles. Services
+// depending on a removed service are rejected, as they'd depend on a service which isn't deployed, and so is a project
+// left without services
+func applyProfiles(project *types.Project, active []string) error {
+	var enabled, disabled types.Services
+	for _, service := range project.Services {
+		profiles, err := serviceProfiles(service)
+		if err != nil {
+			return err
+		}
+		if len(profiles) == 0 || anyProfile(profiles, active) {
+			enabled = append(enabled, service)
+		} else {
+			disabled = append(disabled, service)
+		}
+	}
+	for _, service := range enabled {
+		for dependency := range service.DependsOn {
+			for _, d := range disabled {
+				if d.Name == dependency {
+					return fmt.Errorf("service %s depends on %s, which isn't enabled by active profiles %s",
+						service.Name, dependency, formatProfiles(active))
+				}
+			}
+		}
+	}
+	if len(enabled) == 0 && len(disabled) > 0 {
+		return fmt.Errorf("no service enabled by active profiles %s", formatProfiles(active))
+	}
+	project.Services = enabled
+	return nil
+}
+
+func anyProfile(profiles []string, active []string) bool {
+	for _, p := range profiles {
+		for _, a := range active {
+			if p == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func formatProfiles(profiles []string) string {
+	if len(profiles) == 0 {
+		return "(none)"
+	}
+	return strings.Join(profiles, ", ")
+}
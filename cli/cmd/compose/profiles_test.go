@@ -0,0 +1,169 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+	"gotest.tools/v3/assert"
+)
+
+func profiled(name string, profiles ...interface{}) types.ServiceConfig {
+	service := types.ServiceConfig{Name: name}
+	if profiles != nil {
+		service.Extensions = map[string]interface{}{extensionProfiles: profiles}
+	}
+	return service
+}
+
+func TestApplyProfiles(t *testing.T) {
+	debug := profiled("debug", "debug")
+	debug.DependsOn = types.DependsOnConfig{"tools": {}}
+	for _, test := range []struct {
+		name     string
+		services types.Services
+		active   []string
+		enabled  []string
+		err      string
+	}{
+		{
+			name:     "services without profiles are always enabled",
+			services: types.Services{profiled("web"), profiled("db")},
+			enabled:  []string{"db", "web"},
+		},
+		{
+			name:     "services need an active profile",
+			services: types.Services{profiled("web"), profiled("debug", "debug"), profiled("metrics", "monitoring", "ops")},
+			active:   []string{"ops"},
+			enabled:  []string{"metrics", "web"},
+		},
+		{
+			name:     "no active profile",
+			services: types.Services{profiled("web"), profiled("debug", "debug")},
+			enabled:  []string{"web"},
+		},
+		{
+			name:     "dependency on disabled service",
+			services: types.Services{debug, profiled("tools", "tools")},
+			active:   []string{"debug"},
+			err:      "service debug depends on tools, which isn't enabled by active profiles debug",
+		},
+		{
+			name:     "dependency on service enabled by another profile",
+			services: types.Services{debug, profiled("tools", "tools")},
+			active:   []string{"debug", "tools"},
+			enabled:  []string{"debug", "tools"},
+		},
+		{
+			name:     "no service enabled",
+			services: types.Services{profiled("debug", "debug")},
+			err:      "no service enabled by active profiles (none)",
+		},
+		{
+			name:     "invalid profiles",
+			services: types.Services{{Name: "web", Extensions: map[string]interface{}{extensionProfiles: "debug"}}},
+			err:      "service web: x-profiles must be a list of profiles",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			project := &types.Project{Services: test.services}
+			err := applyProfiles(project, test.active)
+			if test.err != "" {
+				assert.Error(t, err, test.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, project.ServiceNames(), test.enabled)
+		})
+	}
+}
+
+func TestActiveProfiles(t *testing.T) {
+	defer os.Unsetenv(composeProfiles) // nolint:errcheck
+	for _, test := range []struct {
+		name     string
+		flag     []string
+		env      string
+		profiles []string
+	}{
+		{name: "none"},
+		{name: "environment", env: "debug, ops,,tools ", profiles: []string{"debug", "ops", "tools"}},
+		{name: "flag overrides environment", flag: []string{"monitoring"}, env: "debug", profiles: []string{"monitoring"}},
+		{name: "blank environment", env: " , "},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.NilError(t, os.Setenv(composeProfiles, test.env))
+			o := composeOptions{Profiles: test.flag}
+			assert.DeepEqual(t, o.activeProfiles(), test.profiles)
+		})
+	}
+}
+
+func TestEnabledServices(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "compose.yaml")
+	assert.NilError(t, ioutil.WriteFile(file, []byte(`
+services:
+  web:
+    image: nginx
+  debug:
+    image: busybox
+    x-profiles: [debug]
+`), 0644))
+	for _, test := range []struct {
+		name     string
+		options  composeOptions
+		services []string
+		err      string
+	}{
+		{
+			name:     "default profiles",
+			options:  composeOptions{ConfigPaths: []string{file}},
+			services: []string{"web"},
+		},
+		{
+			name:     "active profile",
+			options:  composeOptions{ConfigPaths: []string{file}, Profiles: []string{"debug"}},
+			services: []string{"debug", "web"},
+		},
+		{
+			name:    "project known by its name",
+			options: composeOptions{Name: "test", ConfigPaths: []string{filepath.Join(dir, "missing.yaml")}},
+		},
+		{
+			name:    "profile of project known by its name",
+			options: composeOptions{Name: "test", ConfigPaths: []string{filepath.Join(dir, "missing.yaml")}, Profiles: []string{"debug"}},
+			err:     "no such file or directory",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			services, err := test.options.enabledServices()
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			assert.NilError(t, err)
+			sort.Strings(services)
+			assert.DeepEqual(t, services, test.services)
+		})
+	}
+}
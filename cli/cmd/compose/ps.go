@@ -22,7 +22,9 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/docker/go-units"
 	"github.com/spf13/cobra"
 
 	"github.com/docker/compose-cli/api/client"
@@ -40,6 +42,7 @@ func psCommand() *cobra.Command {
 	}
 	psCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
 	psCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	psCmd.Flags().StringArrayVar(&opts.Profiles, "profile", []string{}, "Only list the services of this profile, defaults to COMPOSE_PROFILES")
 	addComposeCommonFlags(psCmd.Flags(), &opts)
 	return psCmd
 }
@@ -54,39 +57,132 @@ func runPs(ctx context.Context, opts composeOptions) error {
 	if err != nil {
 		return err
 	}
+	enabled, err := opts.enabledServices()
+	if err != nil {
+		return err
+	}
 	serviceList, err := c.ComposeService().Ps(ctx, projectName)
 	if err != nil {
 		return err
 	}
+	if enabled != nil {
+		serviceList = filterServiceStatusList(serviceList, enabled)
+	}
 
 	view := viewFromServiceStatusList(serviceList)
-	return formatter.Print(view, opts.Format, os.Stdout,
+	return formatter.Print(jsonFromServiceStatusList(serviceList), opts.Format, os.Stdout,
 		func(w io.Writer) {
 			for _, service := range view {
-				_, _ = fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\n", service.ID, service.Name, service.Replicas, service.Desired, strings.Join(service.Ports, ", "))
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%s\t%s\n", service.ID, service.Name, service.Status, service.Replicas, service.Desired, strings.Join(service.Ports, ", "), strings.Join(service.TaskAges, ", "))
 			}
 		},
-		"ID", "NAME", "REPLICAS", "PORTS")
+		"ID", "NAME", "STATUS", "REPLICAS", "PORTS", "TASK AGES")
+}
+
+// filterServiceStatusList keeps the status of services enabled by active profiles
+func filterServiceStatusList(serviceStatusList []compose.ServiceStatus, enabled []string) []compose.ServiceStatus {
+	filtered := []compose.ServiceStatus{}
+	for _, s := range serviceStatusList {
+		for _, name := range enabled {
+			if s.Name == name {
+				filtered = append(filtered, s)
+				break
+			}
+		}
+	}
+	return filtered
 }
 
 type serviceStatusView struct {
 	ID       string
 	Name     string
+	Status   string
 	Replicas int
 	Desired  int
 	Ports    []string
+	TaskAges []string
 }
 
 func viewFromServiceStatusList(serviceStatusList []compose.ServiceStatus) []serviceStatusView {
 	retList := make([]serviceStatusView, len(serviceStatusList))
 	for i, s := range serviceStatusList {
+		var ages []string
+		for _, t := range s.Tasks {
+			if !t.Started.IsZero() {
+				ages = append(ages, units.HumanDuration(time.Since(t.Started)))
+			}
+		}
 		retList[i] = serviceStatusView{
 			ID:       s.ID,
 			Name:     s.Name,
+			Status:   s.Status,
 			Replicas: s.Replicas,
 			Desired:  s.Desired,
 			Ports:    s.Ports,
+			TaskAges: ages,
+		}
+	}
+	return retList
+}
+
+// serviceStatusJSON is the schema of services printed by `ps --format json`, fields MUST be kept stable across releases
+type serviceStatusJSON struct {
+	Name      string           `json:"name"`
+	ID        string           `json:"id"`
+	Status    string           `json:"status"`
+	Desired   int              `json:"desired"`
+	Running   int              `json:"running"`
+	Endpoints []endpointJSON   `json:"endpoints"`
+	Tasks     []taskStatusJSON `json:"tasks"`
+}
+
+type endpointJSON struct {
+	DNSName       string `json:"dns_name"`
+	PublishedPort int    `json:"published_port"`
+	TargetPort    int    `json:"target_port"`
+	Protocol      string `json:"protocol"`
+}
+
+type taskStatusJSON struct {
+	ARN       string     `json:"arn"`
+	Status    string     `json:"status"`
+	Health    string     `json:"health"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+}
+
+func jsonFromServiceStatusList(serviceStatusList []compose.ServiceStatus) []serviceStatusJSON {
+	retList := make([]serviceStatusJSON, len(serviceStatusList))
+	for i, s := range serviceStatusList {
+		service := serviceStatusJSON{
+			Name:      s.Name,
+			ID:        s.ID,
+			Status:    s.Status,
+			Desired:   s.Desired,
+			Running:   s.Replicas,
+			Endpoints: []endpointJSON{},
+			Tasks:     []taskStatusJSON{},
+		}
+		for _, p := range s.Publishers {
+			service.Endpoints = append(service.Endpoints, endpointJSON{
+				DNSName:       p.URL,
+				PublishedPort: p.PublishedPort,
+				TargetPort:    p.TargetPort,
+				Protocol:      strings.ToLower(p.Protocol),
+			})
+		}
+		for _, t := range s.Tasks {
+			task := taskStatusJSON{
+				ARN:    t.ID,
+				Status: t.Status,
+				Health: t.Health,
+			}
+			if !t.Started.IsZero() {
+				started := t.Started
+				task.StartedAt = &started
+			}
+			service.Tasks = append(service.Tasks, task)
 		}
+		retList[i] = service
 	}
 	return retList
 }
@@ -0,0 +1,70 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
+)
+
+func runCommand() *cobra.Command {
+	opts := composeOptions{}
+	runCmd := &cobra.Command{
+		Use:   "run SERVICE [COMMAND] [ARGS...]",
+		Short: "Run a one-off task of a service",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRun(cmd.Context(), opts, args[0], args[1:])
+		},
+	}
+	runCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
+	runCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
+	runCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	runCmd.Flags().SetInterspersed(false)
+
+	return runCmd
+}
+
+func runRun(ctx context.Context, opts composeOptions, service string, command []string) error {
+	c, err := client.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	projectName, err := opts.toProjectName()
+	if err != nil {
+		return err
+	}
+	code, err := c.ComposeService().Run(ctx, projectName, compose.RunOptions{
+		Service: service,
+		Command: command,
+		Stdout:  os.Stdout,
+	})
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return errdefs.Reported(errdefs.ExitStatus(code))
+	}
+	return nil
+}
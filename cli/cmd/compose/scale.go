@@ -0,0 +1,95 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/progress"
+)
+
+type scaleOptions struct {
+	composeOptions
+	Force bool
+}
+
+func scaleCommand() *cobra.Command {
+	opts := scaleOptions{}
+	scaleCmd := &cobra.Command{
+		Use:   "scale SERVICE=REPLICAS...",
+		Short: "Set the number of replicas of running services, without redeploying the project",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return reportError(opts.ErrorFormat, runScale(cmd.Context(), opts, args))
+		},
+	}
+	scaleCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
+	scaleCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
+	scaleCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	scaleCmd.Flags().BoolVar(&opts.Force, "force", false, "Scale services even when their replicas are managed by an autoscaling policy")
+	scaleCmd.Flags().StringVar(&opts.ErrorFormat, "error-format", "text", "Format of the reported error. Values: [text | json]")
+
+	return scaleCmd
+}
+
+func runScale(ctx context.Context, opts scaleOptions, args []string) error {
+	replicas, err := parseReplicas(args)
+	if err != nil {
+		return err
+	}
+
+	c, err := client.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	projectName, err := opts.toProjectName()
+	if err != nil {
+		return err
+	}
+	_, err = progress.Run(ctx, func(ctx context.Context) (string, error) {
+		return projectName, c.ComposeService().Scale(ctx, projectName, compose.ScaleOptions{
+			Replicas: replicas,
+			Force:    opts.Force,
+		})
+	})
+	return err
+}
+
+// parseReplicas parses SERVICE=REPLICAS arguments
+func parseReplicas(args []string) (map[string]int, error) {
+	replicas := map[string]int{}
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid scale %q, must be SERVICE=REPLICAS", arg)
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid number of replicas %q for service %s", parts[1], parts[0])
+		}
+		replicas[parts[0]] = n
+	}
+	return replicas, nil
+}
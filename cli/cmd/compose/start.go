@@ -0,0 +1,79 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+
+	"github.com/compose-spec/compose-go/cli"
+	"github.com/spf13/cobra"
+
+	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/progress"
+)
+
+func startCommand() *cobra.Command {
+	opts := composeOptions{}
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start project's services stopped by stop, restoring their replicas",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return reportError(opts.ErrorFormat, runStart(cmd.Context(), opts))
+		},
+	}
+	startCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
+	startCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
+	startCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	startCmd.Flags().StringVar(&opts.ErrorFormat, "error-format", "text", "Format of the reported error. Values: [text | json]")
+
+	return startCmd
+}
+
+func runStart(ctx context.Context, opts composeOptions) error {
+	c, err := client.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	// services replicas are only needed for those not recorded when stopped, so the compose file is optional with -p
+	projectName := opts.Name
+	replicas := map[string]int{}
+	options, err := opts.toProjectOptions()
+	if err != nil {
+		return err
+	}
+	project, err := cli.ProjectFromOptions(options)
+	if err != nil && projectName == "" {
+		return err
+	}
+	if err == nil {
+		projectName = project.Name
+		for _, s := range project.Services {
+			if s.Deploy != nil && s.Deploy.Replicas != nil {
+				replicas[s.Name] = int(*s.Deploy.Replicas)
+			}
+		}
+	}
+
+	_, err = progress.Run(ctx, func(ctx context.Context) (string, error) {
+		return projectName, c.ComposeService().Start(ctx, projectName, compose.StartOptions{
+			Replicas: replicas,
+		})
+	})
+	return err
+}
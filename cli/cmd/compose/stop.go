@@ -0,0 +1,59 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/progress"
+)
+
+func stopCommand() *cobra.Command {
+	opts := composeOptions{}
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop all tasks of project's services, without removing the project",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return reportError(opts.ErrorFormat, runStop(cmd.Context(), opts))
+		},
+	}
+	stopCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
+	stopCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
+	stopCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
+	stopCmd.Flags().StringVar(&opts.ErrorFormat, "error-format", "text", "Format of the reported error. Values: [text | json]")
+
+	return stopCmd
+}
+
+func runStop(ctx context.Context, opts composeOptions) error {
+	c, err := client.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	projectName, err := opts.toProjectName()
+	if err != nil {
+		return err
+	}
+	_, err = progress.Run(ctx, func(ctx context.Context) (string, error) {
+		return projectName, c.ComposeService().Stop(ctx, projectName)
+	})
+	return err
+}
@@ -18,12 +18,14 @@ package compose
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/spf13/cobra"
 
-	"github.com/compose-spec/compose-go/cli"
+	"github.com/compose-spec/compose-go/types"
 
 	"github.com/docker/compose-cli/api/client"
+	"github.com/docker/compose-cli/api/compose"
 	"github.com/docker/compose-cli/context/store"
 	"github.com/docker/compose-cli/progress"
 )
@@ -33,42 +35,80 @@ func upCommand(contextType string) *cobra.Command {
 	upCmd := &cobra.Command{
 		Use: "up",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runUp(cmd.Context(), opts)
+			return reportError(opts.ErrorFormat, runUp(cmd.Context(), opts, contextType))
 		},
 	}
 	upCmd.Flags().StringVarP(&opts.Name, "project-name", "p", "", "Project name")
 	upCmd.Flags().StringVar(&opts.WorkingDir, "workdir", "", "Work dir")
 	upCmd.Flags().StringArrayVarP(&opts.ConfigPaths, "file", "f", []string{}, "Compose configuration files")
 	upCmd.Flags().StringArrayVarP(&opts.Environment, "environment", "e", []string{}, "Environment variables")
+	upCmd.Flags().StringArrayVar(&opts.Profiles, "profile", []string{}, "Enable the services of this profile, defaults to COMPOSE_PROFILES")
 	upCmd.Flags().BoolVarP(&opts.Detach, "detach", "d", false, " Detached mode: Run containers in the background")
+	upCmd.Flags().StringVar(&opts.ErrorFormat, "error-format", "text", "Format of the reported error. Values: [text | json]")
 
 	if contextType == store.AciContextType {
 		upCmd.Flags().StringVar(&opts.DomainName, "domainname", "", "Container NIS domain name")
 	}
+	if contextType == store.EcsContextType {
+		upCmd.Flags().BoolVar(&opts.SkipPreflight, "skip-preflight", false, "Skip the check for required AWS permissions before deployment")
+		upCmd.Flags().BoolVar(&opts.InlineSecrets, "inline-secrets", false, "Embed secrets content in the CloudFormation template")
+		upCmd.Flags().BoolVar(&opts.SkipScan, "skip-scan", false, "Deploy without checking image scan findings of services setting x-aws-image-scan")
+		upCmd.Flags().BoolVar(&opts.Build, "build", false, "Build and push images of all services with a build section to Amazon ECR")
+		upCmd.Flags().BoolVar(&opts.Force, "force", false, "Ignore attributes incompatible with Amazon ECS instead of failing")
+		upCmd.Flags().DurationVar(&opts.Timeout, "timeout", 0, "Fail the deployment if not completed within this duration, such as 30m")
+		upCmd.Flags().BoolVar(&opts.NoRollback, "no-rollback", false, "Keep the resources of a failed stack creation to inspect them")
+		upCmd.Flags().BoolVar(&opts.OrphansConfirmed, "remove-orphans", false, "Delete resources created for the project which it doesn't use anymore, without confirmation")
+		upCmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Print the changes deployment would apply to the CloudFormation stack, without deploying")
+		upCmd.Flags().StringVar(&opts.StackName, "stack-name", "", "Deploy the project as this CloudFormation stack, such as an environment of the project")
+		upCmd.Flags().BoolVar(&opts.LabelsAsTags, "labels-as-tags", false, "Also set services labels as tags on their resources")
+		upCmd.Flags().StringVar(&opts.EnvFilesBucket, "env-files-bucket", "", "Upload env_file to this S3 bucket and load them as environment files, instead of inlining their variables in the template")
+	}
 
 	return upCmd
 }
 
-func runUp(ctx context.Context, opts composeOptions) error {
+func runUp(ctx context.Context, opts composeOptions, contextType string) error {
 	c, err := client.New(ctx)
 	if err != nil {
 		return err
 	}
 
+	var project *types.Project
 	_, err = progress.Run(ctx, func(ctx context.Context) (string, error) {
-		options, err := opts.toProjectOptions()
+		project, err = opts.toProject()
 		if err != nil {
 			return "", err
 		}
-		project, err := cli.ProjectFromOptions(options)
 		if opts.DomainName != "" {
 			//arbitrarily set the domain name on the first service ; ACI backend will expose the entire project
 			project.Services[0].DomainName = opts.DomainName
 		}
-		if err != nil {
-			return "", err
-		}
-		return "", c.ComposeService().Up(ctx, project, opts.Detach)
+		return "", c.ComposeService().Up(ctx, project, compose.UpOptions{
+			Detach:         opts.Detach,
+			SkipPreflight:  opts.SkipPreflight,
+			InlineSecrets:  opts.InlineSecrets,
+			SkipScan:       opts.SkipScan,
+			Force:          opts.Force,
+			Build:          opts.Build,
+			DryRun:         opts.DryRun,
+			Timeout:        opts.Timeout,
+			NoRollback:     opts.NoRollback,
+			StackName:      opts.StackName,
+			EnvFilesBucket: opts.EnvFilesBucket,
+			LabelsAsTags:   opts.LabelsAsTags,
+		})
 	})
-	return err
+	// resources used by the previous deployment are only released once the stack got updated
+	if err != nil || contextType != store.EcsContextType || opts.Detach || opts.DryRun {
+		return err
+	}
+	orphans, err := c.ComposeService().ProjectOrphans(ctx, project)
+	if err != nil || len(orphans) == 0 {
+		return err
+	}
+	fmt.Printf("Project %s doesn't use these resources anymore:\n", project.Name)
+	if err := printOrphans(orphans, ""); err != nil {
+		return err
+	}
+	return removeOrphans(ctx, c, orphans, opts.OrphansConfirmed)
 }
@@ -58,6 +58,15 @@ func createEcsCommand() *cobra.Command {
 	cmd.Flags().StringVar(&opts.Region, "region", "", "Region")
 	cmd.Flags().StringVar(&opts.AwsID, "key-id", "", "AWS Access Key ID")
 	cmd.Flags().StringVar(&opts.AwsSecret, "secret-key", "", "AWS Secret Access Key")
+	cmd.Flags().StringVar(&opts.GrafanaURL, "grafana-url", "", "Grafana URL to post deployment annotations to")
+	cmd.Flags().StringVar(&opts.GrafanaTokenSecret, "grafana-token-secret", "", "Secrets Manager secret holding the Grafana API token used to post deployment annotations (default $GRAFANA_TOKEN)")
+	cmd.Flags().StringVar(&opts.Cluster, "cluster", "", "Default ECS cluster, used when compose file doesn't set x-aws-cluster")
+	cmd.Flags().StringVar(&opts.VPC, "vpc", "", "Default VPC, used when compose file doesn't set x-aws-vpc")
+	cmd.Flags().StringToStringVar(&opts.Tags, "tag", nil, "Default tags to set on resources, as key=value")
+	cmd.Flags().IntVar(&opts.MaxRetries, "max-retries", 0, "Number of times throttled or failed AWS API calls are retried (default 8)")
+	cmd.Flags().StringVar(&opts.Endpoint, "endpoint-url", "", "Endpoint of all AWS services, such as LocalStack (default $AWS_ENDPOINT_URL)")
+	cmd.Flags().BoolVar(&opts.S3ForcePathStyle, "s3-force-path-style", false, "Use path-style addressing for S3 buckets")
+	cmd.Flags().BoolVar(&opts.InsecureSkipVerify, "no-verify-ssl", false, "Don't verify TLS certificates of AWS endpoints")
 	return cmd
 }
 
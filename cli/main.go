@@ -233,8 +233,12 @@ func exit(ctx string, err error, ctype string) {
 
 		os.Exit(1)
 	}
+	if errdefs.IsReported(err) {
+		os.Exit(errdefs.ExitCode(err))
+	}
 
-	fatal(err)
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(errdefs.ExitCode(err))
 }
 
 func fatal(err error) {
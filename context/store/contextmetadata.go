@@ -51,8 +51,17 @@ type AciContext struct {
 
 // EcsContext is the context for the AWS backend
 type EcsContext struct {
-	Profile string `json:",omitempty"`
-	Region  string `json:",omitempty"`
+	Profile            string            `json:",omitempty"`
+	Region             string            `json:",omitempty"`
+	GrafanaURL         string            `json:",omitempty"`
+	GrafanaTokenSecret string            `json:",omitempty"`
+	Cluster            string            `json:",omitempty"`
+	VPC                string            `json:",omitempty"`
+	Tags               map[string]string `json:",omitempty"`
+	MaxRetries         int               `json:",omitempty"`
+	Endpoint           string            `json:",omitempty"`
+	S3ForcePathStyle   bool              `json:",omitempty"`
+	InsecureSkipVerify bool              `json:",omitempty"`
 }
 
 // AwsContext is the context for the ecs plugin
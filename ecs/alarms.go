@@ -0,0 +1,161 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"strings"
+
+	cloudwatchapi "github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/cloudwatch"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// alarmsConfig is set by x-aws-alarms, as the thresholds of the alarms to create for a service. Alarms left to 0
+// aren't created
+type alarmsConfig struct {
+	cpu            int
+	memory         int
+	http5xx        int
+	unhealthyHosts int
+}
+
+func serviceAlarms(service types.ServiceConfig) (*alarmsConfig, error) {
+	x, ok := service.Extensions[extensionAlarms]
+	if !ok {
+		return nil, nil
+	}
+	m, ok := x.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a mapping of alarm thresholds", extensionAlarms)
+	}
+	config := &alarmsConfig{}
+	for key, value := range m {
+		i, ok := value.(int)
+		if !ok || i < 1 {
+			return nil, fmt.Errorf("%s %s must be a positive integer", extensionAlarms, key)
+		}
+		switch key {
+		case "cpu", "memory":
+			if i > 100 {
+				return nil, fmt.Errorf("%s %s utilization threshold must be between 1 and 100, got %d", extensionAlarms, key, i)
+			}
+			if key == "cpu" {
+				config.cpu = i
+			} else {
+				config.memory = i
+			}
+		case "http_5xx":
+			config.http5xx = i
+		case "unhealthy_hosts":
+			config.unhealthyHosts = i
+		default:
+			return nil, fmt.Errorf("unsupported %s attribute %s, must be one of cpu, memory, http_5xx or unhealthy_hosts", extensionAlarms, key)
+		}
+	}
+	return config, nil
+}
+
+// createAlarms creates the CloudWatch alarms set by x-aws-alarms for service, notifying the project's SNS topic.
+// Dimensions reference the cluster, service and target groups so alarms follow them when they get replaced
+func createAlarms(project *types.Project, resources awsResources, template *cloudformation.Template, service types.ServiceConfig, targetGroups []string) error {
+	config, err := serviceAlarms(service)
+	if err != nil || config == nil {
+		return err
+	}
+	if (config.http5xx > 0 || config.unhealthyHosts > 0) &&
+		(resources.loadBalancerType != elbv2.LoadBalancerTypeEnumApplication || len(targetGroups) == 0) {
+		return fmt.Errorf("%s http_5xx and unhealthy_hosts require service to expose ports through an application load balancer", extensionAlarms)
+	}
+	topics, err := snsTopics(project)
+	if err != nil {
+		return err
+	}
+
+	serviceDimensions := []cloudwatch.Alarm_Dimension{
+		{Name: "ClusterName", Value: resources.cluster},
+		{Name: "ServiceName", Value: cloudformation.GetAtt(serviceResourceName(service.Name), "Name")},
+	}
+	for _, utilization := range []struct {
+		name      string
+		metric    string
+		threshold int
+	}{
+		{"CPU", "CPUUtilization", config.cpu},
+		{"Memory", "MemoryUtilization", config.memory},
+	} {
+		if utilization.threshold == 0 {
+			continue
+		}
+		template.Resources[fmt.Sprintf("%s%sAlarm", normalizeResourceName(service.Name), utilization.name)] = &cloudwatch.Alarm{
+			AlarmActions:       topics,
+			AlarmDescription:   fmt.Sprintf("%s %s above %d%%", service.Name, utilization.metric, utilization.threshold),
+			ComparisonOperator: cloudwatchapi.ComparisonOperatorGreaterThanOrEqualToThreshold,
+			Dimensions:         serviceDimensions,
+			EvaluationPeriods:  5,
+			MetricName:         utilization.metric,
+			Namespace:          "AWS/ECS",
+			OKActions:          topics,
+			Period:             60,
+			Statistic:          cloudwatchapi.StatisticAverage,
+			Threshold:          float64(utilization.threshold),
+		}
+	}
+
+	for _, targetGroup := range targetGroups {
+		prefix := strings.TrimSuffix(targetGroup, "TargetGroup")
+		dimensions := []cloudwatch.Alarm_Dimension{
+			{Name: "LoadBalancer", Value: loadBalancerFullName(template, resources)},
+			{Name: "TargetGroup", Value: cloudformation.GetAtt(targetGroup, "TargetGroupFullName")},
+		}
+		if config.http5xx > 0 {
+			template.Resources[fmt.Sprintf("%sHTTP5xxAlarm", prefix)] = &cloudwatch.Alarm{
+				AlarmActions:       topics,
+				AlarmDescription:   fmt.Sprintf("%s targets returned at least %d HTTP 5xx responses in a minute", service.Name, config.http5xx),
+				ComparisonOperator: cloudwatchapi.ComparisonOperatorGreaterThanOrEqualToThreshold,
+				Dimensions:         dimensions,
+				EvaluationPeriods:  1,
+				MetricName:         "HTTPCode_Target_5XX_Count",
+				Namespace:          "AWS/ApplicationELB",
+				OKActions:          topics,
+				Period:             60,
+				Statistic:          cloudwatchapi.StatisticSum,
+				Threshold:          float64(config.http5xx),
+				// no metric is reported while targets don't fail requests
+				TreatMissingData: "notBreaching",
+			}
+		}
+		if config.unhealthyHosts > 0 {
+			template.Resources[fmt.Sprintf("%sUnhealthyHostsAlarm", prefix)] = &cloudwatch.Alarm{
+				AlarmActions:       topics,
+				AlarmDescription:   fmt.Sprintf("%s has at least %d unhealthy targets", service.Name, config.unhealthyHosts),
+				ComparisonOperator: cloudwatchapi.ComparisonOperatorGreaterThanOrEqualToThreshold,
+				Dimensions:         dimensions,
+				EvaluationPeriods:  3,
+				MetricName:         "UnHealthyHostCount",
+				Namespace:          "AWS/ApplicationELB",
+				OKActions:          topics,
+				Period:             60,
+				Statistic:          cloudwatchapi.StatisticMaximum,
+				Threshold:          float64(config.unhealthyHosts),
+			}
+		}
+	}
+	return nil
+}
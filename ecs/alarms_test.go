@@ -0,0 +1,118 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/cloudwatch"
+	"gotest.tools/v3/assert"
+)
+
+func TestAlarms(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  foo:
+    image: hello_world
+    ports:
+      - 80:80
+    x-aws-alarms:
+      cpu: 85
+      memory: 90
+      http_5xx: 10
+      unhealthy_hosts: 1
+x-aws-sns_topic: arn:aws:sns:eu-west-3:123456789012:alerts
+`)
+	topics := []string{"arn:aws:sns:eu-west-3:123456789012:alerts"}
+	serviceDimensions := []cloudwatch.Alarm_Dimension{
+		{Name: "ClusterName", Value: cloudformation.Ref("Cluster")},
+		{Name: "ServiceName", Value: cloudformation.GetAtt("FooService", "Name")},
+	}
+	targetDimensions := []cloudwatch.Alarm_Dimension{
+		{Name: "LoadBalancer", Value: cloudformation.GetAtt("LoadBalancer", "LoadBalancerFullName")},
+		{Name: "TargetGroup", Value: cloudformation.GetAtt("FooTCP80TargetGroup", "TargetGroupFullName")},
+	}
+	for name, expected := range map[string]struct {
+		metric     string
+		threshold  float64
+		dimensions []cloudwatch.Alarm_Dimension
+	}{
+		"FooCPUAlarm":                 {"CPUUtilization", 85, serviceDimensions},
+		"FooMemoryAlarm":              {"MemoryUtilization", 90, serviceDimensions},
+		"FooTCP80HTTP5xxAlarm":        {"HTTPCode_Target_5XX_Count", 10, targetDimensions},
+		"FooTCP80UnhealthyHostsAlarm": {"UnHealthyHostCount", 1, targetDimensions},
+	} {
+		alarm := template.Resources[name].(*cloudwatch.Alarm)
+		assert.Equal(t, alarm.MetricName, expected.metric)
+		assert.Equal(t, alarm.Threshold, expected.threshold)
+		assert.DeepEqual(t, alarm.Dimensions, expected.dimensions)
+		assert.DeepEqual(t, alarm.AlarmActions, topics)
+		assert.DeepEqual(t, alarm.OKActions, topics)
+	}
+}
+
+func TestNoAlarms(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  foo:
+    image: hello_world
+    ports:
+      - 80:80
+`)
+	for name, resource := range template.Resources {
+		_, ok := resource.(*cloudwatch.Alarm)
+		assert.Check(t, !ok, name)
+	}
+}
+
+func TestInvalidAlarms(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  foo:
+    image: hello_world
+    x-aws-alarms: 85
+`: "x-aws-alarms must be a mapping of alarm thresholds",
+		`
+services:
+  foo:
+    image: hello_world
+    x-aws-alarms:
+      cpu: 120
+`: "x-aws-alarms cpu utilization threshold must be between 1 and 100, got 120",
+		`
+services:
+  foo:
+    image: hello_world
+    x-aws-alarms:
+      disk: 50
+`: "unsupported x-aws-alarms attribute disk, must be one of cpu, memory, http_5xx or unhealthy_hosts",
+		`
+services:
+  foo:
+    image: hello_world
+    x-aws-alarms:
+      http_5xx: 10
+`: "x-aws-alarms http_5xx and unhealthy_hosts require service to expose ports through an application load balancer",
+	} {
+		project := loadConfig(t, yaml)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.ErrorContains(t, err, expected)
+	}
+}
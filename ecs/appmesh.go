@@ -0,0 +1,276 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/appmesh"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/compose-spec/compose-go/types"
+)
+
+const (
+	envoyImage = "public.ecr.aws/appmesh/aws-appmesh-envoy:v1.15.1.0-prod"
+	// envoyUID is the user Envoy runs as, which traffic isn't intercepted by the proxy configuration
+	envoyUID = "1337"
+	// envoyEgressIgnoredIPs are the task metadata and instance metadata endpoints, which must be reached directly
+	envoyEgressIgnoredIPs = "169.254.170.2,169.254.169.254"
+	// envoyMemoryReservation is Envoy's memory reservation, in MiB
+	envoyMemoryReservation = 256
+)
+
+// appMesh returns the existing mesh set by x-aws-appmesh for services to join
+func appMesh(project *types.Project) (string, bool, error) {
+	x, ok := project.Extensions[extensionAppMesh]
+	if !ok {
+		return "", false, nil
+	}
+	config, ok := x.(map[string]interface{})
+	if !ok {
+		return "", false, fmt.Errorf("%s must be a mapping", extensionAppMesh)
+	}
+	for key := range config {
+		if key != "mesh" {
+			return "", false, fmt.Errorf("unsupported %s attribute %s", extensionAppMesh, key)
+		}
+	}
+	mesh, _ := config["mesh"].(string)
+	if mesh == "" {
+		return "", false, fmt.Errorf("%s requires the name of a mesh", extensionAppMesh)
+	}
+	if project.Extensions[extensionServiceConnect] == true {
+		return "", false, fmt.Errorf("%s can't be set with %s, as both route traffic between services through a proxy", extensionAppMesh, extensionServiceConnect)
+	}
+	for _, service := range project.Services {
+		if _, ok := service.Extensions[extensionProxyConfiguration]; ok {
+			return "", false, serviceError(service.Name, fmt.Errorf("%s can't be set with %s, which injects the Envoy proxy", extensionProxyConfiguration, extensionAppMesh))
+		}
+	}
+	return mesh, true, nil
+}
+
+// meshed tells if service's task joins the mesh. Sidecars are part of their owner's task, scheduled tasks aren't
+// reached by other services, and tasks on ECS Anywhere instances can't run the proxy. Tasks exposing no port have no
+// application port for the proxy configuration to intercept
+func meshed(project *types.Project, service types.ServiceConfig) bool {
+	if _, ok, _ := appMesh(project); !ok {
+		return false
+	}
+	_, sidecar := sidecarOf(service)
+	return !sidecar && !isScheduled(service) && !externalTask(project, service) && len(taskPorts(project, service)) > 0
+}
+
+func virtualNodeResourceName(service string) string {
+	return fmt.Sprintf("%sVirtualNode", normalizeResourceName(service))
+}
+
+// virtualServiceName is the name services reach service by, as registered in Cloud Map
+func virtualServiceName(project *types.Project, service string) string {
+	return fmt.Sprintf("%s.%s.local", service, project.Name)
+}
+
+// taskPorts are the ports exposed by containers of service's task
+func taskPorts(project *types.Project, service types.ServiceConfig) []types.ServicePortConfig {
+	var ports []types.ServicePortConfig
+	for _, member := range taskServices(project, service) {
+		ports = append(ports, member.Ports...)
+	}
+	return ports
+}
+
+// appMeshPolicy grants Envoy access to the configuration of service's virtual node
+func appMeshPolicy(service types.ServiceConfig) iam.Role_Policy {
+	return iam.Role_Policy{
+		PolicyDocument: &PolicyDocument{
+			Statement: []PolicyStatement{
+				{
+					Effect:   "Allow",
+					Action:   []string{actionStreamAggregatedResources},
+					Resource: []string{cloudformation.Ref(virtualNodeResourceName(service.Name))},
+				},
+			},
+		},
+		PolicyName: "AppMesh",
+	}
+}
+
+// createMeshResources creates the virtual node of service, listening on its task ports, and the virtual service other
+// services of the mesh reach it by. Services service depends on which joined the mesh are declared as the node backends
+func createMeshResources(project *types.Project, service types.ServiceConfig, template *cloudformation.Template, mesh string) error {
+	var listeners []appmesh.VirtualNode_Listener
+	for _, port := range taskPorts(project, service) {
+		if port.Protocol != "" && port.Protocol != "tcp" {
+			return fmt.Errorf("%s only supports TCP ports, got %s port %d", extensionAppMesh, port.Protocol, port.Target)
+		}
+		listeners = append(listeners, appmesh.VirtualNode_Listener{
+			HealthCheck: meshHealthCheck(service, port),
+			PortMapping: &appmesh.VirtualNode_PortMapping{
+				Port:     int(port.Target),
+				Protocol: "tcp",
+			},
+		})
+	}
+
+	dependencies := map[string]bool{}
+	for _, member := range taskServices(project, service) {
+		for name := range member.DependsOn {
+			dependencies[name] = true
+		}
+	}
+	var names []string
+	for name := range dependencies {
+		dependency, err := project.GetService(name)
+		if err != nil || !meshed(project, dependency) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var backends []appmesh.VirtualNode_Backend
+	for _, name := range names {
+		backends = append(backends, appmesh.VirtualNode_Backend{
+			VirtualService: &appmesh.VirtualNode_VirtualServiceBackend{
+				VirtualServiceName: virtualServiceName(project, name),
+			},
+		})
+	}
+
+	node := virtualNodeResourceName(service.Name)
+	template.Resources[node] = &appmesh.VirtualNode{
+		MeshName: mesh,
+		Spec: &appmesh.VirtualNode_VirtualNodeSpec{
+			Backends:  backends,
+			Listeners: listeners,
+			ServiceDiscovery: &appmesh.VirtualNode_ServiceDiscovery{
+				DNS: &appmesh.VirtualNode_DnsServiceDiscovery{
+					Hostname: virtualServiceName(project, service.Name),
+				},
+			},
+		},
+		Tags:            projectTags(project),
+		VirtualNodeName: fmt.Sprintf("%s-%s", project.Name, service.Name),
+	}
+	template.Resources[fmt.Sprintf("%sVirtualService", normalizeResourceName(service.Name))] = &appmesh.VirtualService{
+		MeshName: mesh,
+		Spec: &appmesh.VirtualService_VirtualServiceSpec{
+			Provider: &appmesh.VirtualService_VirtualServiceProvider{
+				VirtualNode: &appmesh.VirtualService_VirtualNodeServiceProvider{
+					VirtualNodeName: cloudformation.GetAtt(node, "VirtualNodeName"),
+				},
+			},
+		},
+		Tags:               projectTags(project),
+		VirtualServiceName: virtualServiceName(project, service.Name),
+	}
+	return nil
+}
+
+// meshHealthCheck checks port with the compose healthcheck timings, within the bounds App Mesh supports
+func meshHealthCheck(service types.ServiceConfig, port types.ServicePortConfig) *appmesh.VirtualNode_HealthCheck {
+	check := service.HealthCheck
+	if check == nil || check.Disable || len(check.Test) == 0 || check.Test[0] == "NONE" {
+		return nil
+	}
+	interval, timeout, retries := 30, 30, 3
+	if check.Interval != nil {
+		interval = durationToInt(check.Interval)
+	}
+	if check.Timeout != nil {
+		timeout = durationToInt(check.Timeout)
+	}
+	if check.Retries != nil {
+		retries = int(*check.Retries)
+	}
+	return &appmesh.VirtualNode_HealthCheck{
+		HealthyThreshold:   2,
+		IntervalMillis:     bounded(interval, 5, 300) * 1000,
+		Port:               int(port.Target),
+		Protocol:           "tcp",
+		TimeoutMillis:      bounded(timeout, 2, 60) * 1000,
+		UnhealthyThreshold: bounded(retries, 2, 10),
+	}
+}
+
+func bounded(value int, min int, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// addEnvoy injects the Envoy proxy of service's virtual node in its task, and configures the task for Envoy to
+// intercept traffic to and from the application containers, which only start once Envoy is healthy
+func (b *ecsAPIService) addEnvoy(project *types.Project, service types.ServiceConfig, definition *ecs.TaskDefinition) {
+	envoy := fmt.Sprintf("%s_Envoy", normalizeResourceName(service.Name))
+	members := map[string]bool{}
+	for _, member := range taskServices(project, service) {
+		members[member.Name] = true
+	}
+	for i, container := range definition.ContainerDefinitions {
+		if !members[container.Name] {
+			continue
+		}
+		definition.ContainerDefinitions[i].DependsOnProp = append(container.DependsOnProp, ecs.TaskDefinition_ContainerDependency{
+			Condition:     ecsapi.ContainerConditionHealthy,
+			ContainerName: envoy,
+		})
+	}
+	definition.ContainerDefinitions = append(definition.ContainerDefinitions, ecs.TaskDefinition_ContainerDefinition{
+		Environment: []ecs.TaskDefinition_KeyValuePair{
+			{Name: "APPMESH_RESOURCE_ARN", Value: cloudformation.Ref(virtualNodeResourceName(service.Name))},
+		},
+		Essential: true,
+		HealthCheck: &ecs.TaskDefinition_HealthCheck{
+			Command:     []string{"CMD-SHELL", "curl -s http://localhost:9901/server_info | grep state | grep -q LIVE"},
+			Interval:    5,
+			Retries:     3,
+			StartPeriod: 10,
+			Timeout:     2,
+		},
+		Image:             envoyImage,
+		LogConfiguration:  b.getLogConfiguration(service, project),
+		MemoryReservation: envoyMemoryReservation,
+		Name:              envoy,
+		User:              envoyUID,
+	})
+
+	var appPorts []string
+	for _, port := range taskPorts(project, service) {
+		appPorts = append(appPorts, strconv.Itoa(int(port.Target)))
+	}
+	definition.ProxyConfiguration = &ecs.TaskDefinition_ProxyConfiguration{
+		ContainerName: envoy,
+		ProxyConfigurationProperties: []ecs.TaskDefinition_KeyValuePair{
+			{Name: "AppPorts", Value: strings.Join(appPorts, ",")},
+			{Name: "EgressIgnoredIPs", Value: envoyEgressIgnoredIPs},
+			{Name: "IgnoredUID", Value: envoyUID},
+			{Name: "ProxyEgressPort", Value: defaultProxyEgressPort},
+			{Name: "ProxyIngressPort", Value: defaultProxyIngressPort},
+		},
+		Type: ecsapi.ProxyConfigurationTypeAppmesh,
+	}
+}
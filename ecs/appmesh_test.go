@@ -0,0 +1,188 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/appmesh"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"gotest.tools/v3/assert"
+)
+
+func TestAppMesh(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  front:
+    image: nginx
+    ports:
+      - 80:80
+    healthcheck:
+      test: ["CMD", "curl", "localhost"]
+      interval: 10s
+      timeout: 2s
+      retries: 1
+    depends_on:
+      - back
+      - worker
+  back:
+    image: app
+    ports:
+      - 8080:8080
+  worker:
+    image: worker
+x-aws-appmesh:
+  mesh: my-mesh
+`)
+	node := template.Resources["FrontVirtualNode"].(*appmesh.VirtualNode)
+	assert.Equal(t, node.MeshName, "my-mesh")
+	assert.Equal(t, node.VirtualNodeName, "Test-front")
+	assert.DeepEqual(t, node.Spec.Listeners, []appmesh.VirtualNode_Listener{
+		{
+			HealthCheck: &appmesh.VirtualNode_HealthCheck{
+				HealthyThreshold:   2,
+				IntervalMillis:     10000,
+				Port:               80,
+				Protocol:           "tcp",
+				TimeoutMillis:      2000,
+				UnhealthyThreshold: 2,
+			},
+			PortMapping: &appmesh.VirtualNode_PortMapping{Port: 80, Protocol: "tcp"},
+		},
+	})
+	// worker exposes no port, so isn't a backend to reach
+	assert.DeepEqual(t, node.Spec.Backends, []appmesh.VirtualNode_Backend{
+		{VirtualService: &appmesh.VirtualNode_VirtualServiceBackend{VirtualServiceName: "back.Test.local"}},
+	})
+	assert.Equal(t, node.Spec.ServiceDiscovery.DNS.Hostname, "front.Test.local")
+
+	service := template.Resources["FrontVirtualService"].(*appmesh.VirtualService)
+	assert.Equal(t, service.VirtualServiceName, "front.Test.local")
+	assert.Equal(t, service.Spec.Provider.VirtualNode.VirtualNodeName, cloudformation.GetAtt("FrontVirtualNode", "VirtualNodeName"))
+	// worker has no port for Envoy to intercept, so doesn't join the mesh
+	for _, name := range []string{"WorkerVirtualNode", "WorkerVirtualService"} {
+		_, ok := template.Resources[name]
+		assert.Check(t, !ok, name)
+	}
+	for _, c := range template.Resources["WorkerTaskDefinition"].(*ecs.TaskDefinition).ContainerDefinitions {
+		assert.Check(t, c.Name != "Worker_Envoy")
+	}
+
+	def := template.Resources["FrontTaskDefinition"].(*ecs.TaskDefinition)
+	// default task memory fits Envoy's reservation
+	assert.Equal(t, def.Memory, "512")
+	containers := map[string]ecs.TaskDefinition_ContainerDefinition{}
+	for _, c := range def.ContainerDefinitions {
+		containers[c.Name] = c
+	}
+	envoy := containers["Front_Envoy"]
+	assert.Equal(t, envoy.User, "1337")
+	assert.DeepEqual(t, envoy.Environment, []ecs.TaskDefinition_KeyValuePair{
+		{Name: "APPMESH_RESOURCE_ARN", Value: cloudformation.Ref("FrontVirtualNode")},
+	})
+	assert.Check(t, hasDependency(containers["front"].DependsOnProp, ecs.TaskDefinition_ContainerDependency{Condition: "HEALTHY", ContainerName: "Front_Envoy"}))
+	assert.DeepEqual(t, def.ProxyConfiguration, &ecs.TaskDefinition_ProxyConfiguration{
+		ContainerName: "Front_Envoy",
+		ProxyConfigurationProperties: []ecs.TaskDefinition_KeyValuePair{
+			{Name: "AppPorts", Value: "80"},
+			{Name: "EgressIgnoredIPs", Value: "169.254.170.2,169.254.169.254"},
+			{Name: "IgnoredUID", Value: "1337"},
+			{Name: "ProxyEgressPort", Value: "15001"},
+			{Name: "ProxyIngressPort", Value: "15000"},
+		},
+		Type: "APPMESH",
+	})
+
+	role := template.Resources["FrontTaskRole"].(*iam.Role)
+	assert.Equal(t, len(role.Policies), 1)
+	assert.DeepEqual(t, role.Policies[0].PolicyDocument.(*PolicyDocument).Statement, []PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   []string{"appmesh:StreamAggregatedResources"},
+			Resource: []string{cloudformation.Ref("FrontVirtualNode")},
+		},
+	})
+}
+
+func hasDependency(dependencies []ecs.TaskDefinition_ContainerDependency, expected ecs.TaskDefinition_ContainerDependency) bool {
+	for _, d := range dependencies {
+		if d.Condition == expected.Condition && d.ContainerName == expected.ContainerName {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInvalidAppMesh(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  front:
+    image: nginx
+x-aws-appmesh: my-mesh
+`: "x-aws-appmesh must be a mapping",
+		`
+services:
+  front:
+    image: nginx
+x-aws-appmesh:
+  name: my-mesh
+`: "unsupported x-aws-appmesh attribute name",
+		`
+services:
+  front:
+    image: nginx
+x-aws-appmesh:
+  mesh: my-mesh
+x-aws-service_connect: true
+`: "x-aws-appmesh can't be set with x-aws-service_connect, as both route traffic between services through a proxy",
+		`
+services:
+  front:
+    image: nginx
+    ports:
+      - 53:53/udp
+x-aws-appmesh:
+  mesh: my-mesh
+`: "x-aws-appmesh only supports TCP ports, got udp port 53",
+	} {
+		project := loadConfig(t, yaml)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.ErrorContains(t, err, expected)
+	}
+}
+
+func TestAppMeshTaskSize(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  front:
+    image: nginx
+    ports:
+      - 80:80
+    deploy:
+      resources:
+        limits:
+          memory: 512M
+x-aws-appmesh:
+  mesh: my-mesh
+`)
+	def := template.Resources["FrontTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, def.Memory, "1024")
+}
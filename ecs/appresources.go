@@ -0,0 +1,287 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/dynamodb"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/awslabs/goformation/v4/cloudformation/s3"
+	"github.com/awslabs/goformation/v4/cloudformation/sns"
+	"github.com/awslabs/goformation/v4/cloudformation/sqs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+const (
+	resourceTypeQueue  = "sqs"
+	resourceTypeTopic  = "sns"
+	resourceTypeTable  = "dynamodb"
+	resourceTypeBucket = "s3"
+)
+
+// applicationResource is an AWS resource declared by x-aws-resources for services to use
+type applicationResource struct {
+	Name     string
+	Type     string
+	Services []string
+	// Fifo makes a sqs queue a FIFO one
+	Fifo bool
+	// HashKey is the dynamodb table (string) partition key
+	HashKey string
+}
+
+func (r applicationResource) resourceName() string {
+	var suffix string
+	switch r.Type {
+	case resourceTypeQueue:
+		suffix = "Queue"
+	case resourceTypeTopic:
+		suffix = "Topic"
+	case resourceTypeTable:
+		suffix = "Table"
+	case resourceTypeBucket:
+		suffix = "Bucket"
+	}
+	return normalizeResourceName(r.Name) + suffix
+}
+
+func (r applicationResource) usedBy(service string) bool {
+	for _, s := range r.Services {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// parseApplicationResources reads x-aws-resources, sorted by name
+func parseApplicationResources(project *types.Project) ([]applicationResource, error) {
+	x, ok := project.Extensions[extensionResources]
+	if !ok {
+		return nil, nil
+	}
+	declared, ok := x.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a mapping of resource names to definitions", extensionResources)
+	}
+	var resources []applicationResource
+	for name, d := range declared {
+		definition, ok := d.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid definition for resource %s", name)
+		}
+		r := applicationResource{
+			Name:    name,
+			HashKey: "id",
+		}
+		r.Type, _ = definition["type"].(string)
+		switch r.Type {
+		case resourceTypeQueue, resourceTypeTopic, resourceTypeTable, resourceTypeBucket:
+		default:
+			return nil, fmt.Errorf("resource %s has unsupported type %q, supported types are sqs, sns, dynamodb and s3. "+
+				"Use a CloudFormation overlay for other resources", name, r.Type)
+		}
+		if v, ok := definition["fifo"].(bool); ok {
+			r.Fifo = v
+		}
+		if v, ok := definition["hash_key"].(string); ok {
+			r.HashKey = v
+		}
+		if services, ok := definition["services"].([]interface{}); ok {
+			for _, s := range services {
+				service := fmt.Sprint(s)
+				if _, err := project.GetService(service); err != nil {
+					return nil, fmt.Errorf("resource %s is used by an unknown service %s", name, service)
+				}
+				r.Services = append(r.Services, service)
+			}
+		}
+		resources = append(resources, r)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Name < resources[j].Name
+	})
+	return resources, nil
+}
+
+func (b *ecsAPIService) createApplicationResources(project *types.Project, template *cloudformation.Template) error {
+	resources, err := parseApplicationResources(project)
+	if err != nil {
+		return err
+	}
+	for _, r := range resources {
+		switch r.Type {
+		case resourceTypeQueue:
+			template.Resources[r.resourceName()] = &sqs.Queue{
+				FifoQueue: r.Fifo,
+				Tags:      projectTags(project),
+			}
+		case resourceTypeTopic:
+			template.Resources[r.resourceName()] = &sns.Topic{
+				Tags: projectTags(project),
+			}
+		case resourceTypeTable:
+			template.Resources[r.resourceName()] = &dynamodb.Table{
+				AttributeDefinitions: []dynamodb.Table_AttributeDefinition{
+					{
+						AttributeName: r.HashKey,
+						AttributeType: "S",
+					},
+				},
+				KeySchema: []dynamodb.Table_KeySchema{
+					{
+						AttributeName: r.HashKey,
+						KeyType:       "HASH",
+					},
+				},
+				BillingMode: "PAY_PER_REQUEST",
+				Tags:        projectTags(project),
+			}
+		case resourceTypeBucket:
+			template.Resources[r.resourceName()] = &s3.Bucket{
+				BucketEncryption: &s3.Bucket_BucketEncryption{
+					ServerSideEncryptionConfiguration: []s3.Bucket_ServerSideEncryptionRule{
+						{
+							ServerSideEncryptionByDefault: &s3.Bucket_ServerSideEncryptionByDefault{
+								SSEAlgorithm: "AES256",
+							},
+						},
+					},
+				},
+				PublicAccessBlockConfiguration: &s3.Bucket_PublicAccessBlockConfiguration{
+					BlockPublicAcls:       true,
+					BlockPublicPolicy:     true,
+					IgnorePublicAcls:      true,
+					RestrictPublicBuckets: true,
+				},
+				Tags: projectTags(project),
+			}
+		}
+	}
+	return nil
+}
+
+// applicationResourcesEnvironment exposes resources used by service as environment variables
+func applicationResourcesEnvironment(project *types.Project, service types.ServiceConfig) ([]ecs.TaskDefinition_KeyValuePair, error) {
+	resources, err := parseApplicationResources(project)
+	if err != nil {
+		return nil, err
+	}
+	var pairs []ecs.TaskDefinition_KeyValuePair
+	for _, r := range resources {
+		if !r.usedBy(service.Name) {
+			continue
+		}
+		prefix := toEnvName(r.Name)
+		resource := r.resourceName()
+		switch r.Type {
+		case resourceTypeQueue:
+			pairs = append(pairs,
+				ecs.TaskDefinition_KeyValuePair{Name: prefix + "_QUEUE_URL", Value: cloudformation.Ref(resource)},
+				ecs.TaskDefinition_KeyValuePair{Name: prefix + "_QUEUE_ARN", Value: cloudformation.GetAtt(resource, "Arn")})
+		case resourceTypeTopic:
+			pairs = append(pairs,
+				ecs.TaskDefinition_KeyValuePair{Name: prefix + "_TOPIC_ARN", Value: cloudformation.Ref(resource)})
+		case resourceTypeTable:
+			pairs = append(pairs,
+				ecs.TaskDefinition_KeyValuePair{Name: prefix + "_TABLE_NAME", Value: cloudformation.Ref(resource)},
+				ecs.TaskDefinition_KeyValuePair{Name: prefix + "_TABLE_ARN", Value: cloudformation.GetAtt(resource, "Arn")})
+		case resourceTypeBucket:
+			pairs = append(pairs,
+				ecs.TaskDefinition_KeyValuePair{Name: prefix + "_BUCKET_NAME", Value: cloudformation.Ref(resource)},
+				ecs.TaskDefinition_KeyValuePair{Name: prefix + "_BUCKET_ARN", Value: cloudformation.GetAtt(resource, "Arn")})
+		}
+	}
+	return pairs, nil
+}
+
+// applicationResourcesPolicies grants service least-privilege access to the resources it uses
+func applicationResourcesPolicies(project *types.Project, service types.ServiceConfig) ([]iam.Role_Policy, error) {
+	resources, err := parseApplicationResources(project)
+	if err != nil {
+		return nil, err
+	}
+	var statements []PolicyStatement
+	for _, r := range resources {
+		if !r.usedBy(service.Name) {
+			continue
+		}
+		resource := r.resourceName()
+		switch r.Type {
+		case resourceTypeQueue:
+			statements = append(statements, PolicyStatement{
+				Effect: "Allow",
+				Action: []string{
+					"sqs:SendMessage",
+					"sqs:ReceiveMessage",
+					"sqs:DeleteMessage",
+					"sqs:ChangeMessageVisibility",
+					"sqs:GetQueueAttributes",
+					"sqs:GetQueueUrl",
+				},
+				Resource: []string{cloudformation.GetAtt(resource, "Arn")},
+			})
+		case resourceTypeTopic:
+			statements = append(statements, PolicyStatement{
+				Effect:   "Allow",
+				Action:   []string{"sns:Publish"},
+				Resource: []string{cloudformation.Ref(resource)},
+			})
+		case resourceTypeTable:
+			statements = append(statements, PolicyStatement{
+				Effect: "Allow",
+				Action: []string{
+					"dynamodb:GetItem",
+					"dynamodb:BatchGetItem",
+					"dynamodb:Query",
+					"dynamodb:Scan",
+					"dynamodb:PutItem",
+					"dynamodb:UpdateItem",
+					"dynamodb:DeleteItem",
+					"dynamodb:BatchWriteItem",
+					"dynamodb:ConditionCheckItem",
+				},
+				Resource: []string{cloudformation.GetAtt(resource, "Arn")},
+			})
+		case resourceTypeBucket:
+			statements = append(statements, PolicyStatement{
+				Effect:   "Allow",
+				Action:   []string{"s3:ListBucket"},
+				Resource: []string{cloudformation.GetAtt(resource, "Arn")},
+			}, PolicyStatement{
+				Effect:   "Allow",
+				Action:   []string{"s3:GetObject", "s3:PutObject", "s3:DeleteObject"},
+				Resource: []string{cloudformation.Join("", []string{cloudformation.GetAtt(resource, "Arn"), "/*"})},
+			})
+		}
+	}
+	if len(statements) == 0 {
+		return nil, nil
+	}
+	return []iam.Role_Policy{
+		{
+			PolicyDocument: &PolicyDocument{
+				Statement: statements,
+			},
+			PolicyName: fmt.Sprintf("%sGrantAccessToResources", service.Name),
+		},
+	}, nil
+}
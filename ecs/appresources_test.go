@@ -0,0 +1,80 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/dynamodb"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/awslabs/goformation/v4/cloudformation/sqs"
+	"gotest.tools/v3/assert"
+)
+
+func TestApplicationResources(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  worker:
+    image: worker
+  front:
+    image: nginx
+x-aws-resources:
+  jobs:
+    type: sqs
+    fifo: true
+    services: [worker]
+  orders:
+    type: dynamodb
+    hash_key: orderId
+    services: [worker]
+`)
+	queue := template.Resources["JobsQueue"].(*sqs.Queue)
+	assert.Check(t, queue.FifoQueue)
+	table := template.Resources["OrdersTable"].(*dynamodb.Table)
+	assert.Equal(t, table.KeySchema[0].AttributeName, "orderId")
+
+	def := template.Resources["WorkerTaskDefinition"].(*ecs.TaskDefinition)
+	env := getMainContainer(def, t).Environment
+	assert.Equal(t, get(env, "JOBS_QUEUE_URL"), cloudformation.Ref("JobsQueue"))
+	assert.Equal(t, get(env, "ORDERS_TABLE_NAME"), cloudformation.Ref("OrdersTable"))
+
+	role := template.Resources["WorkerTaskRole"].(*iam.Role)
+	assert.Equal(t, role.Policies[0].PolicyName, "workerGrantAccessToResources")
+	policy := role.Policies[0].PolicyDocument.(*PolicyDocument)
+	assert.DeepEqual(t, policy.Statement[0].Resource, []string{cloudformation.GetAtt("JobsQueue", "Arn")})
+	assert.DeepEqual(t, policy.Statement[1].Resource, []string{cloudformation.GetAtt("OrdersTable", "Arn")})
+
+	assert.Check(t, template.Resources["FrontTaskRole"] == nil)
+	def = template.Resources["FrontTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, get(getMainContainer(def, t).Environment, "JOBS_QUEUE_URL"), "")
+}
+
+func TestUnsupportedApplicationResource(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  worker:
+    image: worker
+x-aws-resources:
+  cache:
+    type: elasticache
+`)
+	_, err := (&ecsAPIService{}).convert(project, awsResources{})
+	assert.ErrorContains(t, err, `resource cache has unsupported type "elasticache"`)
+	assert.ErrorContains(t, err, "CloudFormation overlay")
+}
@@ -18,21 +18,255 @@ package ecs
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
 	applicationautoscaling2 "github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	cloudwatchapi "github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/awslabs/goformation/v4/cloudformation"
 	"github.com/awslabs/goformation/v4/cloudformation/applicationautoscaling"
+	"github.com/awslabs/goformation/v4/cloudformation/cloudwatch"
 	"github.com/awslabs/goformation/v4/cloudformation/iam"
 	"github.com/compose-spec/compose-go/types"
 )
 
-func (b *ecsAPIService) createAutoscalingPolicy(project *types.Project, resources awsResources, template *cloudformation.Template, service types.ServiceConfig) {
+// autoscalingConfig is set by deploy.x-aws-autoscaling, either as the CPU utilization target or as the capacity
+// bounds and the targets of each metric to track
+type autoscalingConfig struct {
+	min               int
+	max               int
+	cpu               int
+	memory            int
+	requestsPerTarget int
+	scaleInCooldown   int
+	scaleOutCooldown  int
+	disableScaleIn    bool
+	step              *stepScaling
+}
+
+// stepScaling is set by x-aws-autoscaling type step, adjusting capacity by steps as the metric crosses thresholds.
+// Steps with a positive adjustment scale out, the ones with a negative adjustment scale in
+type stepScaling struct {
+	metric string
+	steps  []scalingStep
+}
+
+type scalingStep struct {
+	threshold  int
+	adjustment int
+}
+
+func hasAutoscaling(service types.ServiceConfig) bool {
 	if service.Deploy == nil {
-		return
+		return false
+	}
+	_, ok := service.Deploy.Extensions[extensionAutoScaling]
+	return ok
+}
+
+func serviceAutoscaling(service types.ServiceConfig) (*autoscalingConfig, error) {
+	if !hasAutoscaling(service) {
+		return nil, nil
+	}
+	x := service.Deploy.Extensions[extensionAutoScaling]
+	if cpu, ok := x.(int); ok {
+		return &autoscalingConfig{max: 10, cpu: cpu}, checkUtilizationTarget("cpu", cpu)
+	}
+	m, ok := x.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a CPU utilization target, or a mapping of capacity bounds and metric targets", extensionAutoScaling)
+	}
+	config := &autoscalingConfig{max: 10, scaleInCooldown: 60, scaleOutCooldown: 60}
+	var (
+		step   bool
+		metric string
+		steps  []scalingStep
+	)
+	for key, value := range m {
+		switch key {
+		case "type":
+			if value != "step" && value != "target_tracking" {
+				return nil, fmt.Errorf("unsupported %s type %v, must be one of step or target_tracking", extensionAutoScaling, value)
+			}
+			step = value == "step"
+			continue
+		case "metric":
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s metric must be a string", extensionAutoScaling)
+			}
+			metric = s
+			continue
+		case "steps":
+			var err error
+			if steps, err = scalingSteps(value); err != nil {
+				return nil, err
+			}
+			continue
+		case "disable_scale_in":
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s disable_scale_in must be a boolean", extensionAutoScaling)
+			}
+			config.disableScaleIn = b
+			continue
+		}
+		i, ok := value.(int)
+		if !ok || i < 0 {
+			return nil, fmt.Errorf("%s %s must be a positive integer", extensionAutoScaling, key)
+		}
+		switch key {
+		case "min":
+			config.min = i
+		case "max":
+			config.max = i
+		case "cpu", "memory":
+			if err := checkUtilizationTarget(key, i); err != nil {
+				return nil, err
+			}
+			if key == "cpu" {
+				config.cpu = i
+			} else {
+				config.memory = i
+			}
+		case "requests_per_target":
+			config.requestsPerTarget = i
+		case "scale_in_cooldown", "scale_out_cooldown":
+			if i == 0 {
+				// CloudFormation would fall back to the default cooldown
+				return nil, fmt.Errorf("%s %s must be at least 1 second", extensionAutoScaling, key)
+			}
+			if key == "scale_in_cooldown" {
+				config.scaleInCooldown = i
+			} else {
+				config.scaleOutCooldown = i
+			}
+		default:
+			return nil, fmt.Errorf("unsupported %s attribute %s", extensionAutoScaling, key)
+		}
+	}
+	if config.min > config.max {
+		return nil, fmt.Errorf("%s min %d is greater than max %d", extensionAutoScaling, config.min, config.max)
 	}
-	v, ok := service.Deploy.Extensions[extensionAutoScaling]
+	if step {
+		var err error
+		if config.step, err = checkStepScaling(config, metric, steps); err != nil {
+			return nil, err
+		}
+	} else if metric != "" || steps != nil {
+		return nil, fmt.Errorf("%s metric and steps require type step", extensionAutoScaling)
+	}
+	if config.cpu == 0 && config.memory == 0 && config.requestsPerTarget == 0 && config.step == nil {
+		return nil, fmt.Errorf("%s requires at least one of cpu, memory or requests_per_target", extensionAutoScaling)
+	}
+	return config, nil
+}
+
+func scalingSteps(value interface{}) ([]scalingStep, error) {
+	list, ok := value.([]interface{})
 	if !ok {
-		return
+		return nil, fmt.Errorf("%s steps must be a list of thresholds and adjustments", extensionAutoScaling)
+	}
+	var steps []scalingStep
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s steps must be a list of thresholds and adjustments", extensionAutoScaling)
+		}
+		var s scalingStep
+		for key, value := range m {
+			i, ok := value.(int)
+			if !ok {
+				return nil, fmt.Errorf("%s step %s must be an integer", extensionAutoScaling, key)
+			}
+			switch key {
+			case "threshold":
+				s.threshold = i
+			case "adjustment":
+				s.adjustment = i
+			default:
+				return nil, fmt.Errorf("unsupported %s step attribute %s", extensionAutoScaling, key)
+			}
+		}
+		steps = append(steps, s)
+	}
+	return steps, nil
+}
+
+// checkStepScaling validates the step scaling of metric, which can't also be tracked by a target tracking policy
+func checkStepScaling(config *autoscalingConfig, metric string, steps []scalingStep) (*stepScaling, error) {
+	switch metric {
+	case "cpu":
+		if config.cpu > 0 {
+			return nil, fmt.Errorf("%s cpu can't be scaled by both step and target tracking policies", extensionAutoScaling)
+		}
+	case "memory":
+		if config.memory > 0 {
+			return nil, fmt.Errorf("%s memory can't be scaled by both step and target tracking policies", extensionAutoScaling)
+		}
+	case "":
+		return nil, fmt.Errorf("%s type step requires a metric", extensionAutoScaling)
+	default:
+		return nil, fmt.Errorf("unsupported %s step metric %s, must be one of cpu or memory", extensionAutoScaling, metric)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%s type step requires steps", extensionAutoScaling)
+	}
+	thresholds := map[int]bool{}
+	lowestOut, highestIn := 101, 0
+	for _, s := range steps {
+		if err := checkUtilizationTarget(metric, s.threshold); err != nil {
+			return nil, err
+		}
+		if thresholds[s.threshold] {
+			return nil, fmt.Errorf("%s step threshold %d is set more than once", extensionAutoScaling, s.threshold)
+		}
+		thresholds[s.threshold] = true
+		switch {
+		case s.adjustment > 0 && s.threshold < lowestOut:
+			lowestOut = s.threshold
+		case s.adjustment < 0 && s.threshold > highestIn:
+			highestIn = s.threshold
+		case s.adjustment == 0:
+			return nil, fmt.Errorf("%s step adjustment for threshold %d can't be 0", extensionAutoScaling, s.threshold)
+		}
+	}
+	if highestIn > 0 && config.disableScaleIn {
+		return nil, fmt.Errorf("%s disable_scale_in can't be set with steps scaling in", extensionAutoScaling)
+	}
+	if highestIn >= lowestOut {
+		return nil, fmt.Errorf("%s scale in threshold %d must be lower than scale out threshold %d", extensionAutoScaling, highestIn, lowestOut)
+	}
+	return &stepScaling{metric: metric, steps: steps}, nil
+}
+
+func checkUtilizationTarget(metric string, target int) error {
+	if target < 1 || target > 100 {
+		return fmt.Errorf("%s %s utilization target must be between 1 and 100, got %d", extensionAutoScaling, metric, target)
+	}
+	return nil
+}
+
+// createAutoscalingPolicy creates a scalable target for service and a target tracking policy per metric, plus step
+// scaling policies, which all share the target capacity bounds. targetGroups are the target groups of service's published ports
+func (b *ecsAPIService) createAutoscalingPolicy(project *types.Project, resources awsResources, template *cloudformation.Template,
+	service types.ServiceConfig, targetGroups []string) error {
+	config, err := serviceAutoscaling(service)
+	if err != nil || config == nil {
+		return err
+	}
+	var requestsLabel string
+	if config.requestsPerTarget > 0 {
+		if resources.loadBalancerType != elbv2.LoadBalancerTypeEnumApplication || len(targetGroups) != 1 {
+			return fmt.Errorf("%s requests_per_target requires service to expose exactly one port through an application load balancer", extensionAutoScaling)
+		}
+		requestsLabel = cloudformation.Join("/", []string{
+			loadBalancerFullName(template, resources),
+			cloudformation.GetAtt(targetGroups[0], "TargetGroupFullName"),
+		})
 	}
 
 	role := fmt.Sprintf("%sAutoScalingRole", normalizeResourceName(service.Name))
@@ -66,8 +300,8 @@ func (b *ecsAPIService) createAutoscalingPolicy(project *types.Project, resource
 
 	target := fmt.Sprintf("%sScalableTarget", normalizeResourceName(service.Name))
 	template.Resources[target] = &applicationautoscaling.ScalableTarget{
-		MaxCapacity:                10,
-		MinCapacity:                0,
+		MaxCapacity:                config.max,
+		MinCapacity:                config.min,
 		ResourceId:                 resourceID,
 		RoleARN:                    cloudformation.GetAtt(role, "Arn"),
 		ScalableDimension:          applicationautoscaling2.ScalableDimensionEcsServiceDesiredCount,
@@ -75,19 +309,155 @@ func (b *ecsAPIService) createAutoscalingPolicy(project *types.Project, resource
 		AWSCloudFormationDependsOn: []string{serviceResourceName(service.Name)},
 	}
 
-	policy := fmt.Sprintf("%sScalingPolicy", normalizeResourceName(service.Name))
-	template.Resources[policy] = &applicationautoscaling.ScalingPolicy{
-		PolicyType:                     "TargetTrackingScaling",
-		PolicyName:                     policy,
-		ScalingTargetId:                cloudformation.Ref(target),
-		StepScalingPolicyConfiguration: nil,
-		TargetTrackingScalingPolicyConfiguration: &applicationautoscaling.ScalingPolicy_TargetTrackingScalingPolicyConfiguration{
-			PredefinedMetricSpecification: &applicationautoscaling.ScalingPolicy_PredefinedMetricSpecification{
-				PredefinedMetricType: applicationautoscaling2.MetricTypeEcsserviceAverageCpuutilization,
+	// the CPU policy keeps the name it had when it was the only one supported, so updating a stack doesn't replace it
+	for _, metric := range []struct {
+		policy string
+		target int
+		metric string
+		label  string
+	}{
+		{"ScalingPolicy", config.cpu, applicationautoscaling2.MetricTypeEcsserviceAverageCpuutilization, ""},
+		{"MemoryScalingPolicy", config.memory, applicationautoscaling2.MetricTypeEcsserviceAverageMemoryUtilization, ""},
+		{"RequestsScalingPolicy", config.requestsPerTarget, applicationautoscaling2.MetricTypeAlbrequestCountPerTarget, requestsLabel},
+	} {
+		if metric.target == 0 {
+			continue
+		}
+		policy := fmt.Sprintf("%s%s", normalizeResourceName(service.Name), metric.policy)
+		template.Resources[policy] = &applicationautoscaling.ScalingPolicy{
+			PolicyType:                     "TargetTrackingScaling",
+			PolicyName:                     policy,
+			ScalingTargetId:                cloudformation.Ref(target),
+			StepScalingPolicyConfiguration: nil,
+			TargetTrackingScalingPolicyConfiguration: &applicationautoscaling.ScalingPolicy_TargetTrackingScalingPolicyConfiguration{
+				PredefinedMetricSpecification: &applicationautoscaling.ScalingPolicy_PredefinedMetricSpecification{
+					PredefinedMetricType: metric.metric,
+					ResourceLabel:        metric.label,
+				},
+				ScaleOutCooldown: config.scaleOutCooldown,
+				ScaleInCooldown:  config.scaleInCooldown,
+				DisableScaleIn:   config.disableScaleIn,
+				TargetValue:      float64(metric.target),
 			},
-			ScaleOutCooldown: 60,
-			ScaleInCooldown:  60,
-			TargetValue:      float64(v.(int)),
-		},
+		}
+	}
+	if config.step != nil {
+		createStepScaling(template, resources, service, target, config)
+	}
+	return nil
+}
+
+// createStepScaling creates a step scaling policy for each scaling direction, with the CloudWatch alarm triggering it
+// once the metric crosses the first step threshold. Step bounds are relative to this threshold
+func createStepScaling(template *cloudformation.Template, resources awsResources, service types.ServiceConfig, target string, config *autoscalingConfig) {
+	var scaleOut, scaleIn []scalingStep
+	for _, s := range config.step.steps {
+		if s.adjustment > 0 {
+			scaleOut = append(scaleOut, s)
+		} else {
+			scaleIn = append(scaleIn, s)
+		}
+	}
+	// scale out steps apply from their threshold up, scale in steps from their threshold down
+	sort.Slice(scaleOut, func(i, j int) bool { return scaleOut[i].threshold < scaleOut[j].threshold })
+	sort.Slice(scaleIn, func(i, j int) bool { return scaleIn[i].threshold > scaleIn[j].threshold })
+
+	metricName := "CPUUtilization"
+	if config.step.metric == "memory" {
+		metricName = "MemoryUtilization"
+	}
+	for _, direction := range []struct {
+		name     string
+		steps    []scalingStep
+		operator string
+		cooldown int
+	}{
+		{"ScaleOut", scaleOut, cloudwatchapi.ComparisonOperatorGreaterThanOrEqualToThreshold, config.scaleOutCooldown},
+		{"ScaleIn", scaleIn, cloudwatchapi.ComparisonOperatorLessThanOrEqualToThreshold, config.scaleInCooldown},
+	} {
+		if len(direction.steps) == 0 {
+			continue
+		}
+		threshold := direction.steps[0].threshold
+		var adjustments []stepAdjustment
+		for i, s := range direction.steps {
+			// the first step is bounded by the threshold, 0, and the last one is unbounded past it
+			adjustment := stepAdjustment{ScalingAdjustment: s.adjustment}
+			bound := float64(s.threshold - threshold)
+			var next *float64
+			if i+1 < len(direction.steps) {
+				next = aws.Float64(float64(direction.steps[i+1].threshold - threshold))
+			}
+			if s.adjustment > 0 {
+				adjustment.MetricIntervalLowerBound = &bound
+				adjustment.MetricIntervalUpperBound = next
+			} else {
+				adjustment.MetricIntervalUpperBound = &bound
+				adjustment.MetricIntervalLowerBound = next
+			}
+			adjustments = append(adjustments, adjustment)
+		}
+
+		policy := fmt.Sprintf("%s%sPolicy", normalizeResourceName(service.Name), direction.name)
+		template.Resources[policy] = &stepScalingPolicy{
+			ScalingPolicy: applicationautoscaling.ScalingPolicy{
+				PolicyType:      "StepScaling",
+				PolicyName:      policy,
+				ScalingTargetId: cloudformation.Ref(target),
+				StepScalingPolicyConfiguration: &applicationautoscaling.ScalingPolicy_StepScalingPolicyConfiguration{
+					AdjustmentType:        applicationautoscaling2.AdjustmentTypeChangeInCapacity,
+					Cooldown:              direction.cooldown,
+					MetricAggregationType: applicationautoscaling2.MetricAggregationTypeAverage,
+				},
+			},
+			StepAdjustments: adjustments,
+		}
+		template.Resources[fmt.Sprintf("%s%sAlarm", normalizeResourceName(service.Name), direction.name)] = &cloudwatch.Alarm{
+			AlarmActions:       []string{cloudformation.Ref(policy)},
+			ComparisonOperator: direction.operator,
+			Dimensions: []cloudwatch.Alarm_Dimension{
+				{Name: "ClusterName", Value: resources.cluster},
+				{Name: "ServiceName", Value: cloudformation.GetAtt(serviceResourceName(service.Name), "Name")},
+			},
+			EvaluationPeriods: 1,
+			MetricName:        metricName,
+			Namespace:         "AWS/ECS",
+			Period:            60,
+			Statistic:         cloudwatchapi.StatisticAverage,
+			Threshold:         float64(threshold),
+		}
+	}
+}
+
+// stepScalingPolicy is a step scaling policy which adjustments keep their bounds set to 0, goformation omitting them
+// otherwise so the step would be unbounded
+type stepScalingPolicy struct {
+	applicationautoscaling.ScalingPolicy
+	StepAdjustments []stepAdjustment
+}
+
+// stepAdjustment is a step of a step scaling policy, relative to the alarm threshold. A nil bound is unbounded
+type stepAdjustment struct {
+	MetricIntervalLowerBound *float64 `json:",omitempty"`
+	MetricIntervalUpperBound *float64 `json:",omitempty"`
+	ScalingAdjustment        int
+}
+
+func (r stepScalingPolicy) MarshalJSON() ([]byte, error) {
+	return marshalResource(r.ScalingPolicy, func(properties map[string]interface{}) {
+		if configuration, ok := properties["StepScalingPolicyConfiguration"].(map[string]interface{}); ok {
+			configuration["StepAdjustments"] = r.StepAdjustments
+		}
+	})
+}
+
+// loadBalancerFullName is the load balancer name CloudWatch metrics are reported for, which ends its ARN
+func loadBalancerFullName(template *cloudformation.Template, resources awsResources) string {
+	if _, ok := template.Resources["LoadBalancer"]; ok {
+		return cloudformation.GetAtt("LoadBalancer", "LoadBalancerFullName")
+	}
+	if parsed, err := arn.Parse(resources.loadBalancer); err == nil {
+		return strings.TrimPrefix(parsed.Resource, "loadbalancer/")
 	}
+	return resources.loadBalancer
 }
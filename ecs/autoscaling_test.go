@@ -17,9 +17,13 @@
 package ecs
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/goformation/v4/cloudformation"
 	autoscaling "github.com/awslabs/goformation/v4/cloudformation/applicationautoscaling"
+	"github.com/awslabs/goformation/v4/cloudformation/cloudwatch"
 	"gotest.tools/v3/assert"
 )
 
@@ -39,3 +43,262 @@ services:
 	}
 	assert.Check(t, policy.TargetTrackingScalingPolicyConfiguration.TargetValue == float64(75))
 }
+
+func TestAutoScalingMetrics(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  foo:
+    image: hello_world
+    ports:
+      - 80:80
+    deploy:
+      x-aws-autoscaling:
+        min: 2
+        max: 10
+        cpu: 70
+        memory: 80
+        requests_per_target: 500
+`)
+	target := template.Resources["FooScalableTarget"].(*autoscaling.ScalableTarget)
+	assert.Equal(t, target.MinCapacity, 2)
+	assert.Equal(t, target.MaxCapacity, 10)
+
+	for name, expected := range map[string]struct {
+		metric string
+		target float64
+	}{
+		"FooScalingPolicy":         {"ECSServiceAverageCPUUtilization", 70},
+		"FooMemoryScalingPolicy":   {"ECSServiceAverageMemoryUtilization", 80},
+		"FooRequestsScalingPolicy": {"ALBRequestCountPerTarget", 500},
+	} {
+		policy := template.Resources[name].(*autoscaling.ScalingPolicy)
+		assert.Equal(t, policy.ScalingTargetId, cloudformation.Ref("FooScalableTarget"))
+		config := policy.TargetTrackingScalingPolicyConfiguration
+		assert.Equal(t, config.PredefinedMetricSpecification.PredefinedMetricType, expected.metric)
+		assert.Equal(t, config.TargetValue, expected.target)
+	}
+
+	policy := template.Resources["FooRequestsScalingPolicy"].(*autoscaling.ScalingPolicy)
+	assert.Equal(t, policy.TargetTrackingScalingPolicyConfiguration.PredefinedMetricSpecification.ResourceLabel, cloudformation.Join("/", []string{
+		cloudformation.GetAtt("LoadBalancer", "LoadBalancerFullName"),
+		cloudformation.GetAtt("FooTCP80TargetGroup", "TargetGroupFullName"),
+	}))
+}
+
+func TestStepScaling(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  worker:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        type: step
+        max: 20
+        metric: cpu
+        scale_out_cooldown: 30
+        steps:
+          - threshold: 90
+            adjustment: 5
+          - threshold: 70
+            adjustment: 2
+          - threshold: 20
+            adjustment: -1
+        memory: 80
+        scale_in_cooldown: 120
+`)
+	out := template.Resources["WorkerScaleOutPolicy"].(*stepScalingPolicy)
+	assert.Equal(t, out.PolicyType, "StepScaling")
+	assert.Equal(t, out.StepScalingPolicyConfiguration.Cooldown, 30)
+	assert.DeepEqual(t, out.StepAdjustments, []stepAdjustment{
+		{MetricIntervalLowerBound: aws.Float64(0), MetricIntervalUpperBound: aws.Float64(20), ScalingAdjustment: 2},
+		{MetricIntervalLowerBound: aws.Float64(20), ScalingAdjustment: 5},
+	})
+	alarm := template.Resources["WorkerScaleOutAlarm"].(*cloudwatch.Alarm)
+	assert.Equal(t, alarm.MetricName, "CPUUtilization")
+	assert.Equal(t, alarm.Threshold, float64(70))
+	assert.Equal(t, alarm.ComparisonOperator, "GreaterThanOrEqualToThreshold")
+	assert.DeepEqual(t, alarm.AlarmActions, []string{cloudformation.Ref("WorkerScaleOutPolicy")})
+
+	in := template.Resources["WorkerScaleInPolicy"].(*stepScalingPolicy)
+	assert.Equal(t, in.StepScalingPolicyConfiguration.Cooldown, 120)
+	assert.DeepEqual(t, in.StepAdjustments, []stepAdjustment{
+		{MetricIntervalUpperBound: aws.Float64(0), ScalingAdjustment: -1},
+	})
+	alarm = template.Resources["WorkerScaleInAlarm"].(*cloudwatch.Alarm)
+	assert.Equal(t, alarm.Threshold, float64(20))
+	assert.Equal(t, alarm.ComparisonOperator, "LessThanOrEqualToThreshold")
+
+	memory := template.Resources["WorkerMemoryScalingPolicy"].(*autoscaling.ScalingPolicy)
+	assert.Equal(t, memory.TargetTrackingScalingPolicyConfiguration.ScaleInCooldown, 120)
+	assert.Equal(t, memory.TargetTrackingScalingPolicyConfiguration.ScaleOutCooldown, 30)
+}
+
+func TestStepScalingMarshalledBounds(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  worker:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        type: step
+        max: 20
+        metric: memory
+        steps:
+          - threshold: 80
+            adjustment: 1
+          - threshold: 20
+            adjustment: -1
+`)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	var marshalled struct {
+		Resources map[string]struct {
+			Properties struct {
+				StepScalingPolicyConfiguration struct {
+					StepAdjustments []map[string]interface{}
+				}
+			}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	assert.DeepEqual(t, marshalled.Resources["WorkerScaleOutPolicy"].Properties.StepScalingPolicyConfiguration.StepAdjustments, []map[string]interface{}{
+		{"MetricIntervalLowerBound": float64(0), "ScalingAdjustment": float64(1)},
+	})
+	assert.DeepEqual(t, marshalled.Resources["WorkerScaleInPolicy"].Properties.StepScalingPolicyConfiguration.StepAdjustments, []map[string]interface{}{
+		{"MetricIntervalUpperBound": float64(0), "ScalingAdjustment": float64(-1)},
+	})
+}
+
+func TestTargetTrackingDisableScaleIn(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        cpu: 60
+        disable_scale_in: true
+`)
+	policy := template.Resources["FooScalingPolicy"].(*autoscaling.ScalingPolicy)
+	assert.Check(t, policy.TargetTrackingScalingPolicyConfiguration.DisableScaleIn)
+	assert.Equal(t, policy.TargetTrackingScalingPolicyConfiguration.ScaleInCooldown, 60)
+}
+
+func TestAutoScalingExistingLoadBalancer(t *testing.T) {
+	assert.Equal(t, loadBalancerFullName(&cloudformation.Template{Resources: cloudformation.Resources{}}, awsResources{
+		loadBalancer: "arn:aws:elasticloadbalancing:us-east-1:012345678910:loadbalancer/app/my-lb/50dc6c495c0c9188",
+	}), "app/my-lb/50dc6c495c0c9188")
+}
+
+func TestInvalidAutoScaling(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        requests_per_target: 500
+`: "x-aws-autoscaling requests_per_target requires service to expose exactly one port through an application load balancer",
+		`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        min: 5
+        max: 2
+        cpu: 70
+`: "x-aws-autoscaling min 5 is greater than max 2",
+		`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        max: 2
+`: "x-aws-autoscaling requires at least one of cpu, memory or requests_per_target",
+		`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        memory: 120
+`: "x-aws-autoscaling memory utilization target must be between 1 and 100, got 120",
+		`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        cpu: 70
+        disk: 50
+`: "unsupported x-aws-autoscaling attribute disk",
+		`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        type: step
+        metric: cpu
+        cpu: 70
+        steps:
+          - threshold: 80
+            adjustment: 2
+`: "x-aws-autoscaling cpu can't be scaled by both step and target tracking policies",
+		`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        type: step
+        metric: cpu
+        steps:
+          - threshold: 50
+            adjustment: 2
+          - threshold: 60
+            adjustment: -1
+`: "x-aws-autoscaling scale in threshold 60 must be lower than scale out threshold 50",
+		`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        type: step
+        metric: memory
+        disable_scale_in: true
+        steps:
+          - threshold: 20
+            adjustment: -1
+`: "x-aws-autoscaling disable_scale_in can't be set with steps scaling in",
+		`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        cpu: 70
+        steps:
+          - threshold: 80
+            adjustment: 2
+`: "x-aws-autoscaling metric and steps require type step",
+		`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      x-aws-autoscaling:
+        cpu: 70
+        scale_in_cooldown: 0
+`: "x-aws-autoscaling scale_in_cooldown must be at least 1 second",
+	} {
+		project := loadConfig(t, yaml)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.ErrorContains(t, err, expected)
+	}
+}
@@ -19,4 +19,9 @@ package ecs
 const (
 	awsTypeCapacityProvider = "AWS::ECS::CapacityProvider"
 	awsTypeAutoscalingGroup = "AWS::AutoScaling::AutoScalingGroup"
+	awsTypeSecurityGroup    = "AWS::EC2::SecurityGroup"
+	awsTypeCloudMap         = "AWS::ServiceDiscovery::PrivateDnsNamespace"
+	awsTypeCloudMapService  = "AWS::ServiceDiscovery::Service"
+	awsTypeLogGroup         = "AWS::Logs::LogGroup"
+	awsTypeStack            = "AWS::CloudFormation::Stack"
 )
@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/aws/aws-sdk-go/aws"
+	ec2api "github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/awslabs/goformation/v4/cloudformation/ec2"
 	"github.com/awslabs/goformation/v4/cloudformation/elasticloadbalancingv2"
@@ -28,16 +30,27 @@ import (
 	"github.com/awslabs/goformation/v4/cloudformation/ecs"
 	"github.com/compose-spec/compose-go/types"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 )
 
 // awsResources hold the AWS component being used or created to support services definition
 type awsResources struct {
 	vpc              string
 	subnets          []string
+	zones            map[string]string // availability zone by subnet ID
 	cluster          string
 	loadBalancer     string
 	loadBalancerType string
 	securityGroups   map[string]string
+	mountTargets     map[string][]string // EFS mount targets by volume
+	mountZones       map[string][]string // availability zones external volumes already have a mount target in
+	secrets          map[string]string   // ARN of secrets created by SDK, by name
+	cidrs            map[string]string   // CIDR block by subnet ID
+	publicSubnets    map[string]bool     // subnets routing traffic to an internet gateway, by ID
+	networkSubnets   map[string][]string // subnets selected by network
+	capacityProvider string              // shared capacity provider, not managed by project's stack
+	autoScalingGroup string              // existing Auto Scaling group ARN the project's capacity provider attaches to
+	codeDeployed     map[string]string   // task definition deployed services with a CODE_DEPLOY controller run, by service
 }
 
 func (r *awsResources) serviceSecurityGroups(service types.ServiceConfig) []string {
@@ -48,6 +61,16 @@ func (r *awsResources) serviceSecurityGroups(service types.ServiceConfig) []stri
 	return groups
 }
 
+func (r *awsResources) subnetsInZone(zone string, candidates []string) []string {
+	var subnets []string
+	for _, subnet := range candidates {
+		if r.zones[subnet] == zone {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets
+}
+
 func (r *awsResources) allSecurityGroups() []string {
 	var securityGroups []string
 	for _, r := range r.securityGroups {
@@ -56,26 +79,106 @@ func (r *awsResources) allSecurityGroups() []string {
 	return securityGroups
 }
 
-// parse look into compose project for configured resource to use, and check they are valid
+// maxParallelLookups is the maximum number of AWS resources looked up concurrently while parsing a project
+const maxParallelLookups = 4
+
+// parse look into compose project for configured resource to use, and check they are valid.
+// Independent lookups run concurrently, the first one to fail cancels the others
 func (b *ecsAPIService) parse(ctx context.Context, project *types.Project) (awsResources, error) {
 	r := awsResources{}
-	var err error
-	r.cluster, err = b.parseClusterExtension(ctx, project)
-	if err != nil {
-		return r, err
+	warned := len(b.warnings)
+	eg, ctx := errgroup.WithContext(ctx)
+	slots := make(chan struct{}, maxParallelLookups)
+	lookup := func(fn func() error) {
+		eg.Go(func() error {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			return fn()
+		})
 	}
-	r.vpc, r.subnets, err = b.parseVPCExtension(ctx, project)
+
+	var (
+		cluster          string
+		capacityProvider string
+		createCapacity   bool
+		vpc              string
+		subnets          []*ec2api.Subnet
+		publicSubnets    map[string]bool
+		autoScalingGroup string
+	)
+	lookup(func() error {
+		var err error
+		cluster, err = b.parseClusterExtension(ctx, project)
+		if err != nil {
+			return err
+		}
+		capacityProvider, createCapacity, err = b.parseCapacityProviderExtension(ctx, project, cluster)
+		return err
+	})
+	lookup(func() error {
+		var err error
+		vpc, subnets, err = b.parseVPCExtension(ctx, project)
+		if err != nil {
+			return err
+		}
+		var ids []string
+		for _, subnet := range subnets {
+			ids = append(ids, aws.StringValue(subnet.SubnetId))
+		}
+		publicSubnets, err = b.SDK.GetPublicSubnets(ctx, vpc, ids)
+		if err != nil {
+			return err
+		}
+		autoScalingGroup, err = b.parseAutoScalingGroupExtension(ctx, project, vpc, ids)
+		return err
+	})
+	lookup(func() error {
+		var err error
+		r.loadBalancer, r.loadBalancerType, err = b.parseLoadBalancerExtension(ctx, project)
+		return err
+	})
+	lookup(func() error {
+		var err error
+		r.securityGroups, err = b.parseSecurityGroupExtension(ctx, project)
+		return err
+	})
+	// external volumes lookups are run by their own group, so they don't hold slots of other lookups
+	eg.Go(func() error {
+		var err error
+		r.mountZones, err = b.parseExternalVolumes(ctx, project)
+		return err
+	})
+	err := eg.Wait()
 	if err != nil {
 		return r, err
 	}
-	r.loadBalancer, r.loadBalancerType, err = b.parseLoadBalancerExtension(ctx, project)
-	if err != nil {
-		return r, err
+	// lookups warnings are reported in a stable order, whichever lookup completed first
+	b.warnings.sortFrom(warned)
+
+	r.cluster = cluster
+	r.capacityProvider = capacityProvider
+	r.vpc = vpc
+	r.autoScalingGroup = autoScalingGroup
+	r.zones = map[string]string{}
+	r.cidrs = map[string]string{}
+	r.publicSubnets = publicSubnets
+	for _, subnet := range subnets {
+		id := aws.StringValue(subnet.SubnetId)
+		r.subnets = append(r.subnets, id)
+		r.zones[id] = aws.StringValue(subnet.AvailabilityZone)
+		r.cidrs[id] = aws.StringValue(subnet.CidrBlock)
 	}
-	r.securityGroups, err = b.parseSecurityGroupExtension(ctx, project)
+	err = r.parseNetworkSubnets(project)
 	if err != nil {
 		return r, err
 	}
+	b.sharedProvider = sharedCapacityProvider{}
+	if createCapacity {
+		b.sharedProvider, err = b.sharedCapacityProviderTemplate(ctx, project, r)
+		if err != nil {
+			return r, err
+		}
+	}
 	return r, nil
 }
 
@@ -89,12 +192,67 @@ func (b *ecsAPIService) parseClusterExtension(ctx context.Context, project *type
 		if !ok {
 			return "", fmt.Errorf("cluster does not exist: %s", cluster)
 		}
+		err = b.checkClusterInsights(ctx, project, cluster)
+		if err != nil {
+			return "", err
+		}
 		return cluster, nil
 	}
 	return "", nil
 }
 
-func (b *ecsAPIService) parseVPCExtension(ctx context.Context, project *types.Project) (string, []string, error) {
+// parseCapacityProviderExtension checks the shared capacity provider set by x-aws-capacity-provider can run project's services,
+// and tells whether it doesn't exist yet, to be created for them. A capacity provider can only be associated with one
+// cluster, so projects sharing it also share an existing cluster
+func (b *ecsAPIService) parseCapacityProviderExtension(ctx context.Context, project *types.Project, cluster string) (string, bool, error) {
+	x, ok := project.Extensions[extensionCapacityProvider]
+	if !ok {
+		return "", false, nil
+	}
+	provider := fmt.Sprint(x)
+	if cluster == "" {
+		return "", false, fmt.Errorf("%s requires %s to be set, as a capacity provider is bound to a single cluster", extensionCapacityProvider, extensionCluster)
+	}
+	group, err := b.SDK.GetCapacityProviderAutoScalingGroup(ctx, provider)
+	if err != nil {
+		return "", false, err
+	}
+	if group == "" {
+		// the capacity provider created for the project launches a machine type meeting its requirements
+		return provider, true, nil
+	}
+	providers, err := b.SDK.GetClusterCapacityProviders(ctx, cluster)
+	if err != nil {
+		return "", false, err
+	}
+	var associated bool
+	for _, p := range providers {
+		associated = associated || p == provider
+	}
+	if !associated {
+		return "", false, fmt.Errorf("capacity provider %s isn't associated with cluster %s", provider, cluster)
+	}
+
+	requirements, err := getResourceRequirements(project)
+	if err != nil {
+		return "", false, err
+	}
+	instanceTypes, err := b.SDK.GetAutoScalingGroupInstanceTypes(ctx, group)
+	if err != nil {
+		return "", false, err
+	}
+	machines, err := b.SDK.DescribeInstanceTypes(ctx, instanceTypes)
+	if err != nil {
+		return "", false, err
+	}
+	err = checkMachines("capacity provider "+provider, machines, requirements)
+	if err != nil {
+		return "", false, err
+	}
+	return provider, false, nil
+}
+
+func (b *ecsAPIService) parseVPCExtension(ctx context.Context, project *types.Project) (string, []*ec2api.Subnet, error) {
 	var vpc string
 	if x, ok := project.Extensions[extensionVPC]; ok {
 		vpc = x.(string)
@@ -147,7 +305,7 @@ func (b *ecsAPIService) parseSecurityGroupExtension(ctx context.Context, project
 			sg = net.Name
 		}
 		if x, ok := net.Extensions[extensionSecurityGroup]; ok {
-			logrus.Warn("to use an existing security-group, use `network.external` and `network.name` in your compose file")
+			b.warn(warningSecurityGroupExtension, severityWarning, "", "to use an existing security-group, use `network.external` and `network.name` in your compose file")
 			logrus.Debugf("Security Group for network %q set by user to %q", net.Name, x)
 			sg = x.(string)
 		}
@@ -174,10 +332,19 @@ func (b *ecsAPIService) ensureCluster(r *awsResources, project *types.Project, t
 	if r.cluster != "" {
 		return
 	}
-	template.Resources["Cluster"] = &ecs.Cluster{
+	cluster := &ecs.Cluster{
 		ClusterName: project.Name,
 		Tags:        projectTags(project),
 	}
+	if value, ok, _ := containerInsights(project); ok {
+		cluster.ClusterSettings = []ecs.Cluster_ClusterSettings{
+			{
+				Name:  containerInsightsSetting,
+				Value: value,
+			},
+		}
+	}
+	template.Resources["Cluster"] = cluster
 	r.cluster = cloudformation.Ref("Cluster")
 }
 
@@ -210,7 +377,7 @@ func (b *ecsAPIService) ensureLoadBalancer(r *awsResources, project *types.Proje
 		return
 	}
 	if allServices(project.Services, func(it types.ServiceConfig) bool {
-		return len(it.Ports) == 0
+		return len(it.Ports) == 0 || externalTask(project, it)
 	}) {
 		logrus.Debug("Application does not expose any public port, so no need for a LoadBalancer")
 		return
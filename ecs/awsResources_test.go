@@ -0,0 +1,221 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	efsapi "github.com/aws/aws-sdk-go/service/efs"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+)
+
+func (m *mockEC2) DescribeVpcAttributeWithContext(_ aws.Context, in *ec2.DescribeVpcAttributeInput, _ ...request.Option) (*ec2.DescribeVpcAttributeOutput, error) {
+	args := m.Called(aws.StringValue(in.VpcId))
+	return args.Get(0).(*ec2.DescribeVpcAttributeOutput), args.Error(1)
+}
+
+func (m *mockEC2) DescribeSubnetsWithContext(_ aws.Context, in *ec2.DescribeSubnetsInput, _ ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	args := m.Called(aws.StringValue(in.Filters[0].Values[0]))
+	return args.Get(0).(*ec2.DescribeSubnetsOutput), args.Error(1)
+}
+
+func (m *mockEC2) DescribeRouteTablesPagesWithContext(_ aws.Context, in *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool, _ ...request.Option) error {
+	args := m.Called(aws.StringValue(in.Filters[0].Values[0]))
+	fn(args.Get(0).(*ec2.DescribeRouteTablesOutput), true)
+	return args.Error(1)
+}
+
+func (m *mockEC2) DescribeSecurityGroupsWithContext(_ aws.Context, in *ec2.DescribeSecurityGroupsInput, _ ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	args := m.Called(aws.StringValue(in.GroupIds[0]))
+	return args.Get(0).(*ec2.DescribeSecurityGroupsOutput), args.Error(1)
+}
+
+type mockELB struct {
+	elbv2iface.ELBV2API
+	mock.Mock
+}
+
+func (m *mockELB) DescribeLoadBalancersWithContext(_ aws.Context, in *elbv2.DescribeLoadBalancersInput, _ ...request.Option) (*elbv2.DescribeLoadBalancersOutput, error) {
+	args := m.Called(aws.StringValue(in.LoadBalancerArns[0]))
+	return args.Get(0).(*elbv2.DescribeLoadBalancersOutput), args.Error(1)
+}
+
+// barrier holds the mocked AWS API calls reaching it until all of them did, which only happens if they run concurrently
+type barrier struct {
+	arrived  sync.WaitGroup
+	released chan struct{}
+	timedOut int32
+	// inFlight counts the calls held by the barrier, maxInFlight the most of them held together
+	inFlight    int32
+	maxInFlight int32
+}
+
+func newBarrier(calls int) *barrier {
+	b := &barrier{released: make(chan struct{})}
+	b.arrived.Add(calls)
+	go func() {
+		b.arrived.Wait()
+		close(b.released)
+	}()
+	return b
+}
+
+// first makes the first call of a mocked AWS API reach the barrier, later calls are polls of the same lookup
+func (b *barrier) first() func(mock.Arguments) {
+	var once sync.Once
+	return func(mock.Arguments) {
+		once.Do(b.wait)
+	}
+}
+
+func (b *barrier) wait() {
+	held := atomic.AddInt32(&b.inFlight, 1)
+	defer atomic.AddInt32(&b.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&b.maxInFlight)
+		if held <= max || atomic.CompareAndSwapInt32(&b.maxInFlight, max, held) {
+			break
+		}
+	}
+	b.arrived.Done()
+	select {
+	case <-b.released:
+	case <-time.After(5 * time.Second):
+		atomic.StoreInt32(&b.timedOut, 1)
+	}
+}
+
+// concurrentLookups is the number of independent lookups parse must run concurrently, which first calls reach a barrier:
+// cluster, VPC, load balancer, security groups, and one per external volume
+const concurrentLookups = 6
+
+func slowLookupsBackend(securityGroups []*ec2.SecurityGroup, concurrent *barrier) *ecsAPIService {
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeClustersWithContext", "shared").Run(concurrent.first()).Return(&ecsapi.DescribeClustersOutput{
+		Clusters: []*ecsapi.Cluster{{ClusterName: aws.String("shared")}},
+	}, nil)
+
+	ec2Mock := &mockEC2{}
+	ec2Mock.On("DescribeVpcAttributeWithContext", "vpc-123").Run(concurrent.first()).Return(&ec2.DescribeVpcAttributeOutput{
+		EnableDnsSupport: &ec2.AttributeBooleanValue{Value: aws.Bool(true)},
+	}, nil)
+	ec2Mock.On("DescribeSubnetsWithContext", "vpc-123").Return(&ec2.DescribeSubnetsOutput{
+		Subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-1"), AvailabilityZone: aws.String("eu-west-3a"), CidrBlock: aws.String("10.0.1.0/24")},
+			{SubnetId: aws.String("subnet-2"), AvailabilityZone: aws.String("eu-west-3b"), CidrBlock: aws.String("10.0.2.0/24")},
+		},
+	}, nil)
+	// subnet-1 uses the main route table, routing to an internet gateway, while subnet-2 routes to a NAT gateway
+	ec2Mock.On("DescribeRouteTablesPagesWithContext", "vpc-123").Return(&ec2.DescribeRouteTablesOutput{
+		RouteTables: []*ec2.RouteTable{
+			{
+				Associations: []*ec2.RouteTableAssociation{{Main: aws.Bool(true)}},
+				Routes:       []*ec2.Route{{GatewayId: aws.String("local")}, {GatewayId: aws.String("igw-123")}},
+			},
+			{
+				Associations: []*ec2.RouteTableAssociation{{SubnetId: aws.String("subnet-2")}},
+				Routes:       []*ec2.Route{{GatewayId: aws.String("local")}, {NatGatewayId: aws.String("nat-123")}},
+			},
+		},
+	}, nil)
+	ec2Mock.On("DescribeSecurityGroupsWithContext", "sg-123").Run(concurrent.first()).Return(&ec2.DescribeSecurityGroupsOutput{
+		SecurityGroups: securityGroups,
+	}, nil)
+
+	elbMock := &mockELB{}
+	elbMock.On("DescribeLoadBalancersWithContext", "arn:aws:elasticloadbalancing:eu-west-3:012345678910:loadbalancer/app/front/123").Run(concurrent.first()).Return(&elbv2.DescribeLoadBalancersOutput{
+		LoadBalancers: []*elbv2.LoadBalancer{{Type: aws.String(elbv2.LoadBalancerTypeEnumApplication)}},
+	}, nil)
+
+	efsMock := &mockEFS{}
+	for _, id := range []string{"fs-12345678", "fs-87654321"} {
+		efsMock.On("DescribeFileSystemsWithContext", id).Run(concurrent.first()).Return(&efsapi.DescribeFileSystemsOutput{
+			FileSystems: []*efsapi.FileSystemDescription{{LifeCycleState: aws.String(efsapi.LifeCycleStateAvailable)}},
+		}, nil)
+		efsMock.On("DescribeMountTargetsWithContext", id).Return(&efsapi.DescribeMountTargetsOutput{
+			MountTargets: []*efsapi.MountTargetDescription{{AvailabilityZoneName: aws.String("eu-west-3a"), LifeCycleState: aws.String(efsapi.LifeCycleStateAvailable)}},
+		}, nil)
+	}
+	return &ecsAPIService{Region: "eu-west-3", SDK: sdk{ECS: ecsMock, EC2: ec2Mock, ELB: elbMock, EFS: efsMock}}
+}
+
+const slowLookupsProject = `
+x-aws-cluster: shared
+x-aws-vpc: vpc-123
+x-aws-loadbalancer: arn:aws:elasticloadbalancing:eu-west-3:012345678910:loadbalancer/app/front/123
+services:
+  web:
+    image: nginx
+    ports:
+      - 80:80
+    networks:
+      - front
+    volumes:
+      - data:/data
+      - logs:/logs
+networks:
+  front:
+    external: true
+    name: sg-123
+volumes:
+  data:
+    external: true
+    name: fs-12345678
+  logs:
+    external: true
+    name: fs-87654321
+`
+
+func TestParseLooksUpResourcesConcurrently(t *testing.T) {
+	concurrent := newBarrier(concurrentLookups)
+	backend := slowLookupsBackend([]*ec2.SecurityGroup{{GroupId: aws.String("sg-123")}}, concurrent)
+	project := loadConfig(t, slowLookupsProject)
+
+	resources, err := backend.parse(context.TODO(), project)
+	assert.NilError(t, err)
+
+	assert.Equal(t, atomic.LoadInt32(&concurrent.timedOut), int32(0), "independent lookups didn't run concurrently")
+	assert.Equal(t, atomic.LoadInt32(&concurrent.maxInFlight), int32(concurrentLookups))
+	assert.Equal(t, resources.cluster, "shared")
+	assert.Equal(t, resources.vpc, "vpc-123")
+	assert.DeepEqual(t, resources.subnets, []string{"subnet-1", "subnet-2"})
+	assert.DeepEqual(t, resources.zones, map[string]string{"subnet-1": "eu-west-3a", "subnet-2": "eu-west-3b"})
+	assert.DeepEqual(t, resources.publicSubnets, map[string]bool{"subnet-1": true, "subnet-2": false})
+	assert.Equal(t, resources.loadBalancerType, elbv2.LoadBalancerTypeEnumApplication)
+	assert.DeepEqual(t, resources.securityGroups, map[string]string{"front": "sg-123"})
+	assert.DeepEqual(t, resources.mountZones, map[string][]string{"data": {"eu-west-3a"}, "logs": {"eu-west-3a"}})
+	assert.Equal(t, project.Volumes["logs"].Name, "fs-87654321")
+}
+
+func TestParseReturnsFirstLookupError(t *testing.T) {
+	backend := slowLookupsBackend(nil, newBarrier(concurrentLookups))
+	project := loadConfig(t, slowLookupsProject)
+
+	_, err := backend.parse(context.TODO(), project)
+	assert.Error(t, err, "security group sg-123 doesn't exist")
+}
@@ -18,8 +18,10 @@ package ecs
 
 import (
 	"context"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 
 	"github.com/docker/compose-cli/api/compose"
@@ -31,6 +33,7 @@ import (
 	"github.com/docker/compose-cli/context/cloud"
 	"github.com/docker/compose-cli/context/store"
 	"github.com/docker/compose-cli/errdefs"
+	"github.com/docker/compose-cli/prompt"
 )
 
 const backendType = store.EcsContextType
@@ -43,6 +46,19 @@ type ContextParams struct {
 
 	AwsID     string
 	AwsSecret string
+
+	GrafanaURL         string
+	GrafanaTokenSecret string
+
+	Cluster string
+	VPC     string
+	Tags    map[string]string
+
+	MaxRetries int
+
+	Endpoint           string
+	S3ForcePathStyle   bool
+	InsecureSkipVerify bool
 }
 
 func init() {
@@ -63,11 +79,13 @@ func service(ctx context.Context) (backend.Service, error) {
 
 func getEcsAPIService(ecsCtx store.EcsContext) (*ecsAPIService, error) {
 	sess, err := session.NewSessionWithOptions(session.Options{
-		Profile:           ecsCtx.Profile,
-		SharedConfigState: session.SharedConfigEnable,
-		Config: aws.Config{
+		Profile:                 ecsCtx.Profile,
+		SharedConfigState:       session.SharedConfigEnable,
+		AssumeRoleTokenProvider: mfaTokenProvider(prompt.User{}),
+		AssumeRoleDuration:      assumeRoleDuration,
+		Config: *request.WithRetryer(withEndpoint(&aws.Config{
 			Region: aws.String(ecsCtx.Region),
-		},
+		}, ecsCtx), newRetryer(ecsCtx.MaxRetries)),
 	})
 	if err != nil {
 		return nil, err
@@ -78,13 +96,40 @@ func getEcsAPIService(ecsCtx store.EcsContext) (*ecsAPIService, error) {
 		ctx:    ecsCtx,
 		Region: ecsCtx.Region,
 		SDK:    sdk,
+		sess:   sess,
 	}, nil
 }
 
 type ecsAPIService struct {
-	ctx    store.EcsContext
-	Region string
-	SDK    sdk
+	ctx      store.EcsContext
+	Region   string
+	SDK      sdk
+	warnings convertWarnings
+	// warningsLock guards warnings reported by lookups running concurrently
+	warningsLock sync.Mutex
+	// owners are the services owning the resources created for their task, by logical ID
+	owners map[string]string
+	// builder builds service images, defaults to the local Docker engine
+	builder imageBuilder
+	// registry verifies images hosted outside of Amazon ECR
+	registry registryClient
+	// digests are the image digests resolved by Convert, by service, which deployment markers report
+	digests map[string]string
+	// nested are the nested stacks templates produced by Convert, which Up uploads
+	nested nestedTemplates
+	// codeDeployed are the task definitions deployed CODE_DEPLOY services run, kept by Convert, by service, which Up
+	// deploys the new ones of with CodeDeploy
+	codeDeployed map[string]string
+	// unbuilt are the services up --dry-run didn't build the image of, which Convert doesn't verify
+	unbuilt map[string]bool
+	// secrets are the ARNs of the file secrets created by Up before conversion, by name
+	secrets map[string]string
+	// sharedProvider is the x-aws-capacity-provider capacity provider Convert found missing, which Up creates
+	sharedProvider sharedCapacityProvider
+	// sess is the context's session, SDK clients are created from, unless they use project's role
+	sess *session.Session
+	// role is the project's x-aws-role_arn SDK clients have assumed
+	role string
 }
 
 func (a *ecsAPIService) ContainerService() containers.Service {
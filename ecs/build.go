@@ -0,0 +1,331 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+	moby "github.com/docker/docker/api/types"
+	"github.com/docker/docker/builder/dockerignore"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/docker/compose-cli/progress"
+)
+
+// maxParallelBuilds bounds the number of images built and pushed concurrently
+const maxParallelBuilds = 4
+
+// imageBuilder builds and pushes images
+type imageBuilder interface {
+	// Build builds an image from build config and returns its ID
+	Build(ctx context.Context, build types.BuildConfig) (string, error)
+	Tag(ctx context.Context, id string, ref string) error
+	Push(ctx context.Context, ref string, registryAuth string) error
+}
+
+// buildImagesEnabled tells if project opts in x-aws-build_images to build services without an image before deployment
+func buildImagesEnabled(project *types.Project) (bool, error) {
+	x, ok := project.Extensions[extensionBuildImages]
+	if !ok {
+		return false, nil
+	}
+	enabled, ok := x.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean", extensionBuildImages)
+	}
+	return enabled, nil
+}
+
+// imageRepository is the ECR repository service's image gets pushed to. It's named after the compose project, not the
+// stack it's deployed as, so environments of a project share their images
+func imageRepository(projectName string, service string) string {
+	return strings.ToLower(projectName + "/" + service)
+}
+
+// buildImages builds services with a build section and pushes them to an ECR repository <project>/<service> created
+// if absent, then sets services image to the pushed one. Services which already set an image are only built with force.
+// projectName is the compose project name, before it takes the stack name
+func (b *ecsAPIService) buildImages(ctx context.Context, project *types.Project, projectName string, force bool) error {
+	builds, err := servicesToBuild(project, force)
+	if err != nil || len(builds) == 0 {
+		return err
+	}
+
+	builder, err := b.imageBuilder()
+	if err != nil {
+		return err
+	}
+	auth, err := b.SDK.GetRegistryAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	slots := make(chan struct{}, maxParallelBuilds)
+	for _, i := range builds {
+		i := i
+		eg.Go(func() error {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			image, err := b.buildImage(ctx, builder, projectName, project.Services[i], auth)
+			if err != nil {
+				return err
+			}
+			project.Services[i].Image = image
+			// image has been built, build section is not an unsupported attribute anymore
+			project.Services[i].Build = nil
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// servicesToBuild returns the index of the services up builds the image of, when x-aws-build_images or --build enables builds
+func servicesToBuild(project *types.Project, force bool) ([]int, error) {
+	enabled, err := buildImagesEnabled(project)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled && !force {
+		return nil, nil
+	}
+	var builds []int
+	for i, service := range project.Services {
+		if service.Build == nil || (service.Image != "" && !force) {
+			continue
+		}
+		builds = append(builds, i)
+	}
+	return builds, nil
+}
+
+// dryRunImage is the tag of the placeholder image up --dry-run previews services it would build the image of with
+const dryRunImage = "dry-run"
+
+// skipImageBuilds makes services up would build the image of use their image as is, or a placeholder when they have
+// none, so up --dry-run previews the deployment without building nor pushing anything. Conversion doesn't verify those
+// images, as they don't exist yet
+func (b *ecsAPIService) skipImageBuilds(project *types.Project, projectName string, force bool) error {
+	builds, err := servicesToBuild(project, force)
+	if err != nil {
+		return err
+	}
+	b.unbuilt = map[string]bool{}
+	for _, i := range builds {
+		service := &project.Services[i]
+		if service.Image == "" {
+			service.Image = fmt.Sprintf("%s:%s", imageRepository(projectName, service.Name), dryRunImage)
+		}
+		service.Build = nil
+		b.unbuilt[service.Name] = true
+	}
+	return nil
+}
+
+func (b *ecsAPIService) buildImage(ctx context.Context, builder imageBuilder, projectName string, service types.ServiceConfig, auth string) (string, error) {
+	w := progress.ContextWriter(ctx)
+	id := fmt.Sprintf("%s image build", service.Name)
+	w.Event(progress.Event{
+		ID:         id,
+		Status:     progress.Working,
+		StatusText: "BUILD_IN_PROGRESS",
+	})
+	failed := func(err error) (string, error) {
+		w.Event(progress.Event{
+			ID:         id,
+			Status:     progress.Error,
+			StatusText: "BUILD_FAILED",
+		})
+		return "", fmt.Errorf("service %s: %w", service.Name, err)
+	}
+
+	repository, err := b.SDK.EnsureRepository(ctx, projectName, imageRepository(projectName, service.Name))
+	if err != nil {
+		return failed(err)
+	}
+	image, err := builder.Build(ctx, *service.Build)
+	if err != nil {
+		return failed(err)
+	}
+	ref := fmt.Sprintf("%s:%s", repository, imageTag(*service.Build, image))
+	if err := builder.Tag(ctx, image, ref); err != nil {
+		return failed(err)
+	}
+	w.Event(progress.Event{
+		ID:         id,
+		Status:     progress.Working,
+		StatusText: "PUSH_IN_PROGRESS",
+	})
+	if err := builder.Push(ctx, ref, auth); err != nil {
+		return failed(err)
+	}
+	w.Event(progress.Event{
+		ID:         id,
+		Status:     progress.Done,
+		StatusText: ref,
+	})
+	return ref, nil
+}
+
+// imageTag returns the git commit of the build context when the commit holds all the files the image is built from,
+// otherwise the image digest
+func imageTag(build types.BuildConfig, image string) string {
+	if revision, ok := gitRevision(build); ok {
+		return revision
+	}
+	digest := strings.TrimPrefix(image, "sha256:")
+	if len(digest) > 12 {
+		digest = digest[:12]
+	}
+	return digest
+}
+
+func gitRevision(build types.BuildConfig) (string, bool) {
+	dir := build.Context
+	dockerfile := build.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	if filepath.IsAbs(dockerfile) {
+		rel, err := filepath.Rel(dir, dockerfile)
+		if err != nil {
+			return "", false
+		}
+		dockerfile = rel
+	}
+	dockerfile = filepath.Clean(dockerfile)
+	// a Dockerfile outside of the build context isn't committed with it
+	if dockerfile == ".." || strings.HasPrefix(dockerfile, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if err := exec.Command("git", "-C", dir, "ls-files", "--error-unmatch", "--", dockerfile).Run(); err != nil {
+		return "", false
+	}
+	// ignored files, such as generated sources or a local configuration, are sent to the builder without being committed
+	status, err := exec.Command("git", "-C", dir, "status", "--porcelain", "--ignored", "--", ".").Output()
+	if err != nil || len(strings.TrimSpace(string(status))) > 0 {
+		return "", false
+	}
+	revision, err := exec.Command("git", "-C", dir, "rev-parse", "--short=12", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(revision)), true
+}
+
+func (b *ecsAPIService) imageBuilder() (imageBuilder, error) {
+	if b.builder != nil {
+		return b.builder, nil
+	}
+	apiClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+	b.builder = mobyBuilder{client: apiClient}
+	return b.builder, nil
+}
+
+// mobyBuilder builds images with the local Docker engine
+type mobyBuilder struct {
+	client client.APIClient
+}
+
+func (m mobyBuilder) Build(ctx context.Context, build types.BuildConfig) (string, error) {
+	excludes, err := readDockerignore(build.Context)
+	if err != nil {
+		return "", err
+	}
+	buildContext, err := archive.TarWithOptions(build.Context, &archive.TarOptions{
+		ExcludePatterns: excludes,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer buildContext.Close() // nolint:errcheck
+
+	dockerfile := build.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	response, err := m.client.ImageBuild(ctx, buildContext, moby.ImageBuildOptions{
+		Dockerfile:  dockerfile,
+		BuildArgs:   build.Args,
+		Labels:      build.Labels,
+		CacheFrom:   build.CacheFrom,
+		NetworkMode: build.Network,
+		Target:      build.Target,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close() // nolint:errcheck
+
+	var id string
+	err = jsonmessage.DisplayJSONMessagesStream(response.Body, ioutil.Discard, 0, false, func(msg jsonmessage.JSONMessage) {
+		var result moby.BuildResult
+		if msg.Aux != nil && json.Unmarshal(*msg.Aux, &result) == nil && result.ID != "" {
+			id = result.ID
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("build of %s didn't report an image ID", build.Context)
+	}
+	return id, nil
+}
+
+func (m mobyBuilder) Tag(ctx context.Context, id string, ref string) error {
+	return m.client.ImageTag(ctx, id, ref)
+}
+
+func (m mobyBuilder) Push(ctx context.Context, ref string, registryAuth string) error {
+	output, err := m.client.ImagePush(ctx, ref, moby.ImagePushOptions{
+		RegistryAuth: registryAuth,
+	})
+	if err != nil {
+		return err
+	}
+	defer output.Close() // nolint:errcheck
+	return jsonmessage.DisplayJSONMessagesStream(output, ioutil.Discard, 0, false, nil)
+}
+
+func readDockerignore(context string) ([]string, error) {
+	f, err := os.Open(filepath.Join(context, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint:errcheck
+	return dockerignore.ReadAll(f)
+}
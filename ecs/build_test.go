@@ -0,0 +1,273 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/compose-spec/compose-go/types"
+	moby "github.com/docker/docker/api/types"
+	"gotest.tools/v3/assert"
+)
+
+const builtImage = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+type fakeBuilder struct {
+	mu     sync.Mutex
+	builds []string
+	pushed []string
+}
+
+func (f *fakeBuilder) Build(_ context.Context, build types.BuildConfig) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.builds = append(f.builds, build.Context)
+	return builtImage, nil
+}
+
+func (f *fakeBuilder) Tag(_ context.Context, id string, ref string) error {
+	return nil
+}
+
+func (f *fakeBuilder) Push(_ context.Context, ref string, registryAuth string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pushed = append(f.pushed, ref)
+	return nil
+}
+
+func (m *mockECR) DescribeRepositoriesWithContext(_ aws.Context, in *ecr.DescribeRepositoriesInput, _ ...request.Option) (*ecr.DescribeRepositoriesOutput, error) {
+	args := m.Called(aws.StringValue(in.RepositoryNames[0]))
+	return args.Get(0).(*ecr.DescribeRepositoriesOutput), args.Error(1)
+}
+
+func (m *mockECR) CreateRepositoryWithContext(_ aws.Context, in *ecr.CreateRepositoryInput, _ ...request.Option) (*ecr.CreateRepositoryOutput, error) {
+	args := m.Called(aws.StringValue(in.RepositoryName))
+	return args.Get(0).(*ecr.CreateRepositoryOutput), args.Error(1)
+}
+
+func (m *mockECR) GetAuthorizationTokenWithContext(_ aws.Context, in *ecr.GetAuthorizationTokenInput, _ ...request.Option) (*ecr.GetAuthorizationTokenOutput, error) {
+	args := m.Called()
+	return args.Get(0).(*ecr.GetAuthorizationTokenOutput), args.Error(1)
+}
+
+func authorizationToken() *ecr.GetAuthorizationTokenOutput {
+	return &ecr.GetAuthorizationTokenOutput{
+		AuthorizationData: []*ecr.AuthorizationData{
+			{
+				AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:secret"))),
+				ProxyEndpoint:      aws.String("https://123456789012.dkr.ecr.eu-west-3.amazonaws.com"),
+			},
+		},
+	}
+}
+
+func TestBuildImages(t *testing.T) {
+	dir := t.TempDir()
+	project := loadConfig(t, `
+services:
+  api:
+    build: `+filepath.Join(dir, "api")+`
+  web:
+    build: `+filepath.Join(dir, "web")+`
+    image: 123456789012.dkr.ecr.eu-west-3.amazonaws.com/web:1.0
+  db:
+    image: postgres
+x-aws-build_images: true
+`)
+	m := &mockECR{}
+	m.On("GetAuthorizationTokenWithContext").Return(authorizationToken(), nil)
+	notFound := awserr.New(ecr.ErrCodeRepositoryNotFoundException, "not found", nil)
+	m.On("DescribeRepositoriesWithContext", "test/api").Return(&ecr.DescribeRepositoriesOutput{}, notFound)
+	m.On("CreateRepositoryWithContext", "test/api").Return(&ecr.CreateRepositoryOutput{
+		Repository: &ecr.Repository{RepositoryUri: aws.String("123456789012.dkr.ecr.eu-west-3.amazonaws.com/test/api")},
+	}, nil)
+	builder := &fakeBuilder{}
+	backend := &ecsAPIService{SDK: sdk{ECR: m}, builder: builder}
+
+	assert.NilError(t, backend.buildImages(context.TODO(), project, project.Name, false))
+	m.AssertExpectations(t)
+	assert.DeepEqual(t, builder.builds, []string{filepath.Join(dir, "api")})
+	assert.DeepEqual(t, builder.pushed, []string{"123456789012.dkr.ecr.eu-west-3.amazonaws.com/test/api:0123456789ab"})
+
+	api, err := project.GetService("api")
+	assert.NilError(t, err)
+	assert.Equal(t, api.Image, "123456789012.dkr.ecr.eu-west-3.amazonaws.com/test/api:0123456789ab")
+	assert.Check(t, api.Build == nil)
+	web, err := project.GetService("web")
+	assert.NilError(t, err)
+	assert.Equal(t, web.Image, "123456789012.dkr.ecr.eu-west-3.amazonaws.com/web:1.0")
+}
+
+func TestBuildImagesForce(t *testing.T) {
+	dir := t.TempDir()
+	project := loadConfig(t, `
+services:
+  web:
+    build: `+dir+`
+    image: nginx
+`)
+	m := &mockECR{}
+	m.On("GetAuthorizationTokenWithContext").Return(authorizationToken(), nil)
+	m.On("DescribeRepositoriesWithContext", "test/web").Return(&ecr.DescribeRepositoriesOutput{
+		Repositories: []*ecr.Repository{{RepositoryUri: aws.String("123456789012.dkr.ecr.eu-west-3.amazonaws.com/test/web")}},
+	}, nil)
+	builder := &fakeBuilder{}
+	backend := &ecsAPIService{SDK: sdk{ECR: m}, builder: builder}
+
+	assert.NilError(t, backend.buildImages(context.TODO(), project, project.Name, false))
+	assert.Equal(t, len(builder.builds), 0)
+	assert.Equal(t, project.Services[0].Image, "nginx")
+
+	assert.NilError(t, backend.buildImages(context.TODO(), project, project.Name, true))
+	m.AssertExpectations(t)
+	assert.DeepEqual(t, builder.pushed, []string{"123456789012.dkr.ecr.eu-west-3.amazonaws.com/test/web:0123456789ab"})
+	assert.Equal(t, project.Services[0].Image, "123456789012.dkr.ecr.eu-west-3.amazonaws.com/test/web:0123456789ab")
+}
+
+func TestSkipImageBuilds(t *testing.T) {
+	dir := t.TempDir()
+	project := loadConfig(t, `
+services:
+  api:
+    build: `+filepath.Join(dir, "api")+`
+  web:
+    build: `+filepath.Join(dir, "web")+`
+    image: 123456789012.dkr.ecr.eu-west-3.amazonaws.com/web:1.0
+  db:
+    image: postgres
+x-aws-build_images: true
+`)
+	builder := &fakeBuilder{}
+	backend := &ecsAPIService{builder: builder}
+
+	assert.NilError(t, backend.skipImageBuilds(project, project.Name, false))
+	assert.Equal(t, len(builder.builds), 0)
+	api, err := project.GetService("api")
+	assert.NilError(t, err)
+	assert.Equal(t, api.Image, "test/api:dry-run")
+	assert.Check(t, api.Build == nil)
+	// the placeholder image isn't looked up in a registry
+	digest, err := backend.imageDigest(context.TODO(), api)
+	assert.NilError(t, err)
+	assert.Equal(t, digest, "")
+
+	web, err := project.GetService("web")
+	assert.NilError(t, err)
+	assert.Equal(t, web.Image, "123456789012.dkr.ecr.eu-west-3.amazonaws.com/web:1.0")
+	assert.DeepEqual(t, backend.unbuilt, map[string]bool{"api": true})
+}
+
+func TestBuildImagesStackName(t *testing.T) {
+	dir := t.TempDir()
+	project := loadConfig(t, `
+services:
+  web:
+    build: `+dir+`
+x-aws-build_images: true
+x-aws-stack_name: test-staging
+`)
+	assert.NilError(t, applyStackName(project, ""))
+	m := &mockECR{}
+	m.On("GetAuthorizationTokenWithContext").Return(authorizationToken(), nil)
+	m.On("DescribeRepositoriesWithContext", "test/web").Return(&ecr.DescribeRepositoriesOutput{
+		Repositories: []*ecr.Repository{{RepositoryUri: aws.String("123456789012.dkr.ecr.eu-west-3.amazonaws.com/test/web")}},
+	}, nil)
+	backend := &ecsAPIService{SDK: sdk{ECR: m}, builder: &fakeBuilder{}}
+
+	// environments deployed from the project share its image repositories
+	assert.NilError(t, backend.buildImages(context.TODO(), project, "test", false))
+	m.AssertExpectations(t)
+	assert.Equal(t, project.Name, "test-staging")
+	assert.Equal(t, project.Services[0].Image, "123456789012.dkr.ecr.eu-west-3.amazonaws.com/test/web:0123456789ab")
+}
+
+func TestBuildImagesExtension(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  web:
+    image: nginx
+x-aws-build_images: yes please
+`)
+	backend := &ecsAPIService{}
+	err := backend.buildImages(context.TODO(), project, project.Name, false)
+	assert.Error(t, err, "x-aws-build_images must be a boolean")
+}
+
+func TestImageTagGitRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...).Output()
+		assert.NilError(t, err)
+		return strings.TrimSpace(string(out))
+	}
+	build := types.BuildConfig{Context: dir}
+	assert.Equal(t, imageTag(build, builtImage), "0123456789ab")
+
+	git("init", "-q")
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM nginx\n"), 0644))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.env\n"), 0644))
+	git("add", "Dockerfile", ".gitignore")
+	git("commit", "-q", "-m", "init")
+	assert.Equal(t, imageTag(build, builtImage), git("rev-parse", "--short=12", "HEAD"))
+	assert.Equal(t, imageTag(types.BuildConfig{Context: dir, Dockerfile: filepath.Join(dir, "Dockerfile")}, builtImage), git("rev-parse", "--short=12", "HEAD"))
+
+	// a Dockerfile outside of the context, or not committed, isn't described by the revision
+	assert.Equal(t, imageTag(types.BuildConfig{Context: dir, Dockerfile: "../Dockerfile"}, builtImage), "0123456789ab")
+	assert.Equal(t, imageTag(types.BuildConfig{Context: dir, Dockerfile: "Dockerfile.dev"}, builtImage), "0123456789ab")
+
+	// ignored files are part of the build context
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "local.env"), []byte("DEBUG=1\n"), 0644))
+	assert.Equal(t, imageTag(build, builtImage), "0123456789ab")
+	assert.NilError(t, os.Remove(filepath.Join(dir, "local.env")))
+
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM alpine\n"), 0644))
+	assert.Equal(t, imageTag(build, builtImage), "0123456789ab")
+}
+
+func TestGetRegistryAuth(t *testing.T) {
+	m := &mockECR{}
+	m.On("GetAuthorizationTokenWithContext").Return(authorizationToken(), nil)
+	auth, err := sdk{ECR: m}.GetRegistryAuth(context.TODO())
+	assert.NilError(t, err)
+
+	decoded, err := base64.URLEncoding.DecodeString(auth)
+	assert.NilError(t, err)
+	var config moby.AuthConfig
+	assert.NilError(t, json.Unmarshal(decoded, &config))
+	assert.DeepEqual(t, config, moby.AuthConfig{
+		Username:      "AWS",
+		Password:      "secret",
+		ServerAddress: "https://123456789012.dkr.ecr.eu-west-3.amazonaws.com",
+	})
+}
@@ -0,0 +1,168 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// fargateMaxCPU lists regions where Fargate doesn't offer all task sizes, with the largest CPU size available.
+// There's no API to discover supported sizes, this table is maintained based on AWS announcements
+var fargateMaxCPU = map[string]int64{
+	"cn-north-1":     4096,
+	"cn-northwest-1": 4096,
+	"us-gov-east-1":  4096,
+	"us-gov-west-1":  4096,
+}
+
+// checkFargateSize fails if the task size isn't offered by Fargate in deployment region
+func (b *ecsAPIService) checkFargateSize(cpu string) error {
+	max, ok := fargateMaxCPU[b.Region]
+	if !ok {
+		return nil
+	}
+	units, err := strconv.ParseInt(cpu, 10, 64)
+	if err != nil {
+		return err
+	}
+	if units > max {
+		return fmt.Errorf("task size of %g vCPU is not available on Fargate in region %s (max %g vCPU), reduce resources limits, deploy to another region or use EC2 launch type",
+			float64(units)/1024, b.Region, float64(max)/1024)
+	}
+	return nil
+}
+
+// capacityStrategy is a capacity provider to run a service's tasks on, as set by x-aws-fallback-capacity
+type capacityStrategy struct {
+	CapacityProvider string
+	Weight           int
+	Base             int
+}
+
+// strategyService is a Service running its tasks on the capacity providers selected by a strategy, not supported by goformation
+type strategyService struct {
+	ecs.Service
+	CapacityProviderStrategy []capacityStrategy
+}
+
+func (r strategyService) MarshalJSON() ([]byte, error) {
+	return marshalResource(r.Service, func(properties map[string]interface{}) {
+		properties["CapacityProviderStrategy"] = r.CapacityProviderStrategy
+	})
+}
+
+// fallbackCapacity returns the capacity provider strategy to retry service with when capacity is unavailable
+func fallbackCapacity(service types.ServiceConfig) ([]capacityStrategy, error) {
+	x, ok := service.Extensions[extensionFallbackCapacity]
+	if !ok {
+		return nil, nil
+	}
+	items, ok := x.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("service %s: %s must be a list of capacity providers", service.Name, extensionFallbackCapacity)
+	}
+	var strategy []capacityStrategy
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("service %s: %s must be a list of capacity providers", service.Name, extensionFallbackCapacity)
+		}
+		provider, ok := m["capacity_provider"].(string)
+		if !ok || provider == "" {
+			return nil, fmt.Errorf("service %s: %s entries require a capacity_provider", service.Name, extensionFallbackCapacity)
+		}
+		s := capacityStrategy{CapacityProvider: provider, Weight: 1}
+		for key, value := range m {
+			switch key {
+			case "capacity_provider":
+			case "weight", "base":
+				i, ok := value.(int)
+				if !ok || i < 0 {
+					return nil, fmt.Errorf("service %s: %s %s of %s must be a positive integer", service.Name, extensionFallbackCapacity, key, provider)
+				}
+				if key == "weight" {
+					s.Weight = i
+				} else {
+					s.Base = i
+				}
+			default:
+				return nil, fmt.Errorf("service %s: unsupported %s attribute %s", service.Name, extensionFallbackCapacity, key)
+			}
+		}
+		strategy = append(strategy, s)
+	}
+	return strategy, nil
+}
+
+// fallbackCapacities returns the fallback capacity provider strategies set by project's services, by ECS service resource name
+func fallbackCapacities(project *types.Project) (map[string][]capacityStrategy, error) {
+	fallbacks := map[string][]capacityStrategy{}
+	for _, service := range project.Services {
+		strategy, err := fallbackCapacity(service)
+		if err != nil {
+			return nil, err
+		}
+		if strategy != nil {
+			fallbacks[serviceResourceName(service.Name)] = strategy
+		}
+	}
+	return fallbacks, nil
+}
+
+// fargateCapacityProviders are managed by AWS, and only need to be associated with the cluster to be used
+var fargateCapacityProviders = map[string]bool{
+	"FARGATE":      true,
+	"FARGATE_SPOT": true,
+}
+
+// addFallbackCapacityProviders associates the cluster created by the stack with Fargate capacity providers services fall back to
+func addFallbackCapacityProviders(project *types.Project, template *cloudformation.Template) error {
+	r, ok := template.Resources["Cluster"]
+	if !ok {
+		// existing cluster must already be associated with fallback capacity providers
+		return nil
+	}
+	cluster := r.(*ecs.Cluster)
+	associated := map[string]bool{}
+	for _, p := range cluster.CapacityProviders {
+		associated[p] = true
+	}
+	for _, service := range project.Services {
+		strategy, err := fallbackCapacity(service)
+		if err != nil {
+			return err
+		}
+		for _, s := range strategy {
+			if fargateCapacityProviders[s.CapacityProvider] && !associated[s.CapacityProvider] {
+				associated[s.CapacityProvider] = true
+				cluster.CapacityProviders = append(cluster.CapacityProviders, s.CapacityProvider)
+			}
+		}
+	}
+	return nil
+}
+
+// isCapacityUnavailable tells if an ECS service event reports tasks can't be placed due to a lack of capacity
+func isCapacityUnavailable(message string) bool {
+	return strings.Contains(strings.ToLower(message), "capacity is unavailable")
+}
@@ -0,0 +1,165 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+func TestFargateLargeTaskSize(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  foo:
+    image: hello_world
+    deploy:
+      resources:
+        limits:
+          cpus: '16'
+          memory: 32Gb
+`)
+	def := template.Resources["FooTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, def.Cpu, "16384")
+	assert.Equal(t, def.Memory, "32768")
+}
+
+func TestFargateTaskSizeUnavailableInRegion(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  foo:
+    image: hello_world
+    deploy:
+      resources:
+        limits:
+          cpus: '8'
+          memory: 16Gb
+`)
+	backend := &ecsAPIService{Region: "cn-north-1"}
+	_, err := backend.convert(project, awsResources{})
+	assert.Error(t, err, "task size of 8 vCPU is not available on Fargate in region cn-north-1 (max 4 vCPU), reduce resources limits, deploy to another region or use EC2 launch type")
+}
+
+func TestFallbackCapacity(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  foo:
+    image: hello_world
+    x-aws-fallback-capacity:
+      - capacity_provider: FARGATE_SPOT
+        weight: 3
+      - capacity_provider: FARGATE
+        base: 1
+`)
+	fallbacks, err := fallbackCapacities(project)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, fallbacks, map[string][]capacityStrategy{
+		"FooService": {
+			{CapacityProvider: "FARGATE_SPOT", Weight: 3},
+			{CapacityProvider: "FARGATE", Weight: 1, Base: 1},
+		},
+	})
+
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	cluster := template.Resources["Cluster"].(*ecs.Cluster)
+	assert.DeepEqual(t, cluster.CapacityProviders, []string{"FARGATE_SPOT", "FARGATE"})
+}
+
+func TestFallbackCapacityMalformed(t *testing.T) {
+	for value, expected := range map[string]string{
+		"FARGATE_SPOT":   "service foo: x-aws-fallback-capacity must be a list of capacity providers",
+		"[FARGATE_SPOT]": "service foo: x-aws-fallback-capacity must be a list of capacity providers",
+		"[{weight: 1}]":  "service foo: x-aws-fallback-capacity entries require a capacity_provider",
+		"[{capacity_provider: FARGATE, weight: -1}]": "service foo: x-aws-fallback-capacity weight of FARGATE must be a positive integer",
+		"[{capacity_provider: FARGATE, spot: true}]": "service foo: unsupported x-aws-fallback-capacity attribute spot",
+	} {
+		project := loadConfig(t, `
+services:
+  foo:
+    image: hello_world
+    x-aws-fallback-capacity: `+value+`
+`)
+		_, err := fallbackCapacities(project)
+		assert.Error(t, err, expected, value)
+	}
+}
+
+func TestCheckStackStateCapacityUnavailable(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "test").Return(&cloudformation.ListStackResourcesOutput{
+		StackResourceSummaries: []*cloudformation.StackResourceSummary{
+			{LogicalResourceId: aws.String("Cluster"), ResourceType: aws.String("AWS::ECS::Cluster"), PhysicalResourceId: aws.String("cluster")},
+			{LogicalResourceId: aws.String("FooService"), ResourceType: aws.String("AWS::ECS::Service"), PhysicalResourceId: aws.String("arn:foo")},
+		},
+	}, nil)
+	deployed := time.Now().Add(-time.Minute)
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeServicesWithContext", "arn:foo").Return(&ecsapi.DescribeServicesOutput{
+		Services: []*ecsapi.Service{
+			{
+				ServiceArn:     aws.String("arn:foo"),
+				TaskDefinition: aws.String("arn:foo-task:1"),
+				Deployments: []*ecsapi.Deployment{
+					{Status: aws.String("PRIMARY"), CreatedAt: aws.Time(deployed)},
+				},
+				Events: []*ecsapi.ServiceEvent{
+					{CreatedAt: aws.Time(deployed.Add(time.Second)), Message: aws.String("(service foo) was unable to place a task. Reason: Capacity is unavailable at this time.")},
+				},
+			},
+		},
+	}, nil)
+	ecsMock.On("ListTasksWithContext", "RUNNING").Return(&ecsapi.ListTasksOutput{}, nil)
+	ecsMock.On("ListTasksWithContext", "STOPPED").Return(&ecsapi.ListTasksOutput{}, nil)
+	ecsMock.On("UpdateServiceWithContext", "arn:foo").Return(&ecsapi.UpdateServiceOutput{}, nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cf, ECS: ecsMock}}
+	fallbacks := map[string][]capacityStrategy{
+		"FooService": {{CapacityProvider: "FARGATE_SPOT", Weight: 1}},
+	}
+	err := backend.checkStackState(context.TODO(), "test", fallbacks)
+	assert.NilError(t, err)
+	ecsMock.AssertNumberOfCalls(t, "UpdateServiceWithContext", 1)
+
+	// fallback is only attempted once
+	err = backend.checkStackState(context.TODO(), "test", fallbacks)
+	assert.Error(t, err, "FooService capacity is unavailable to place tasks: deploy to another region, use EC2 launch type, reduce resources limits or set x-aws-fallback-capacity")
+	ecsMock.AssertNumberOfCalls(t, "UpdateServiceWithContext", 1)
+}
+
+func (m *mockECS) DescribeServicesWithContext(_ aws.Context, in *ecsapi.DescribeServicesInput, _ ...request.Option) (*ecsapi.DescribeServicesOutput, error) {
+	args := m.Called(aws.StringValue(in.Services[0]))
+	return args.Get(0).(*ecsapi.DescribeServicesOutput), args.Error(1)
+}
+
+func (m *mockECS) ListTasksWithContext(_ aws.Context, in *ecsapi.ListTasksInput, _ ...request.Option) (*ecsapi.ListTasksOutput, error) {
+	args := m.Called(aws.StringValue(in.DesiredStatus))
+	return args.Get(0).(*ecsapi.ListTasksOutput), args.Error(1)
+}
+
+func (m *mockECS) UpdateServiceWithContext(_ aws.Context, in *ecsapi.UpdateServiceInput, _ ...request.Option) (*ecsapi.UpdateServiceOutput, error) {
+	args := m.Called(aws.StringValue(in.Service))
+	return args.Get(0).(*ecsapi.UpdateServiceOutput), args.Error(1)
+}
@@ -20,8 +20,12 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/aws-sdk-go/service/elbv2"
@@ -31,105 +35,357 @@ import (
 	"github.com/awslabs/goformation/v4/cloudformation/ecs"
 	"github.com/awslabs/goformation/v4/cloudformation/elasticloadbalancingv2"
 	"github.com/awslabs/goformation/v4/cloudformation/iam"
-	"github.com/awslabs/goformation/v4/cloudformation/logs"
 	"github.com/awslabs/goformation/v4/cloudformation/secretsmanager"
 	cloudmap "github.com/awslabs/goformation/v4/cloudformation/servicediscovery"
 	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
 )
 
-func (b *ecsAPIService) Convert(ctx context.Context, project *types.Project) ([]byte, error) {
-	err := b.checkCompatibility(project)
+func (b *ecsAPIService) Convert(ctx context.Context, project *types.Project, options compose.ConvertOptions) (compose.ConvertResult, error) {
+	template, err := b.convertProject(ctx, project, options)
 	if err != nil {
-		return nil, err
+		return compose.ConvertResult{}, err
+	}
+	return compose.ConvertResult{
+		Template: template,
+		Warnings: b.warnings.toAPI(),
+	}, nil
+}
+
+// convertProject converts project into a CloudFormation template, reporting warnings as selected by options
+func (b *ecsAPIService) convertProject(ctx context.Context, project *types.Project, options compose.ConvertOptions) ([]byte, error) {
+	b.warnings = nil
+	if err := checkTemplateFormat(options.Format); err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+	if err := applyStackName(project, options.StackName); err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+	if err := checkResourceNames(project); err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+	applyEnvFilesBucket(project, options.EnvFilesBucket)
+	applyLabelsAsTags(project, options.LabelsAsTags)
+	if err := b.assumeProjectRole(project); err != nil {
+		return nil, classify(err, errdefs.ErrAuthentication)
+	}
+	err := b.checkCompatibility(project, options.Force)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+
+	err = b.applyContextDefaults(project)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
 	}
 
 	resources, err := b.parse(ctx, project)
 	if err != nil {
-		return nil, err
+		return nil, classify(err, errdefs.ErrValidation)
 	}
 
+	err = b.resolveImages(ctx, project)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+
+	if !options.InlineSecrets {
+		resources.secrets, err = b.secretRefs(ctx, project)
+		if err != nil {
+			return nil, classify(err, errdefs.ErrValidation)
+		}
+	}
+
+	resources.codeDeployed, err = b.codeDeployedTaskDefinitions(ctx, project)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+	b.codeDeployed = resources.codeDeployed
+
 	template, err := b.convert(project, resources)
 	if err != nil {
-		return nil, err
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+
+	err = b.checkKMSKeyPolicy(ctx, project)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+
+	err = b.checkFileSystemSettings(ctx, project)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+
+	err = b.checkExecutionRoles(ctx, project, resources)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+
+	err = b.createCrossAccountPullPolicies(ctx, project, template)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
 	}
 
 	// Create a NFS inbound rule on each mount target for volumes
 	// as "source security group" use an arbitrary network attached to service(s) who mounts target volume
 	for n, vol := range project.Volumes {
+		if !vol.External.External || isLocalVolume(vol) {
+			continue
+		}
 		err := b.SDK.WithVolumeSecurityGroups(ctx, vol.Name, func(securityGroups []string) error {
 			return b.createNFSmountIngress(securityGroups, project, n, template)
 		})
 		if err != nil {
-			return nil, err
+			return nil, classify(err, errdefs.ErrValidation)
 		}
 	}
 
 	err = b.createCapacityProvider(ctx, project, template, resources)
 	if err != nil {
-		return nil, err
+		return nil, classify(err, errdefs.ErrValidation)
 	}
 
-	return marshall(template)
+	err = b.warnings.report(os.Stderr, options.WarningsFormat)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+	err = b.warnings.check(options.WarningsAsErrors)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+
+	raw, err := marshall(template)
+	if err != nil {
+		return nil, err
+	}
+	raw, err = applyServiceConnect(project, raw)
+	if err != nil {
+		return nil, err
+	}
+	raw, err = applyExecuteCommand(project, raw)
+	if err != nil {
+		return nil, err
+	}
+	raw, err = applyServiceOverrides(project, raw, b.owners)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+	raw, err = applyOverlay(project, raw)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+	b.nested = nestedTemplates{}
+	bucket, create, err := b.resolveTemplateBucket(ctx, project)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+	if bucket == "" {
+		return formatTemplate(raw, options.Format)
+	}
+	objectURL := func(bucket string, key string) string {
+		return b.SDK.ObjectURL(b.Region, bucket, key)
+	}
+	raw, templates, err := splitNestedStacks(project, raw, b.owners, bucket, objectURL)
+	if err != nil {
+		return nil, classify(err, errdefs.ErrValidation)
+	}
+	b.nested = nestedTemplates{bucket: bucket, createBucket: create, templates: templates}
+	return formatTemplate(raw, options.Format)
 }
 
 // Convert a compose project into a CloudFormation template
 func (b *ecsAPIService) convert(project *types.Project, resources awsResources) (*cloudformation.Template, error) {
+	err := checkSidecars(project)
+	if err != nil {
+		return nil, err
+	}
+	err = checkUserTags(project)
+	if err != nil {
+		return nil, err
+	}
+
 	template := cloudformation.NewTemplate()
 	b.ensureResources(&resources, project, template)
 
+	err = checkExternalCluster(project, template)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkVolumeBackends(project)
+	if err != nil {
+		return nil, err
+	}
+
+	connect, err := serviceConnect(project)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := containerInsights(project); err != nil {
+		return nil, err
+	}
+	mesh, _, err := appMesh(project)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.createVolumes(project, template, &resources)
+	if err != nil {
+		return nil, err
+	}
+	err = b.createAccessPoints(project, template)
+	if err != nil {
+		return nil, err
+	}
+
+	// secretRefs are the references tasks use to access secrets, by name. They are all registered
+	// before any service is converted, so that policies only rely on them
+	secretRefs := map[string]string{}
 	for name, secret := range project.Secrets {
-		err := b.createSecret(project, name, secret, template)
+		ref, err := b.createSecret(project, name, secret, template, resources)
 		if err != nil {
 			return nil, err
 		}
+		secretRefs[name] = ref
+	}
+
+	err = b.createApplicationResources(project, template)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.createConfigs(project, template)
+	if err != nil {
+		return nil, err
 	}
 
-	b.createLogGroup(project, template)
+	err = b.createLogGroup(project, template)
+	if err != nil {
+		return nil, err
+	}
 
 	// Private DNS namespace will allow DNS name for the services to be <service>.<project>.local
-	b.createCloudMap(project, template, resources.vpc)
+	b.createCloudMap(project, template, resources.vpc, connect)
 
+	b.owners = map[string]string{}
 	for _, service := range project.Services {
-		taskExecutionRole := b.createTaskExecutionRole(project, service, template)
-		taskRole := b.createTaskRole(project, service, template)
+		if _, ok := sidecarOf(service); ok {
+			// converted as a container of the task it's a sidecar of
+			continue
+		}
+		members := taskServices(project, service)
+		b.checkLabelTags(project, service)
+		existing := map[string]bool{}
+		for name := range template.Resources {
+			existing[name] = true
+		}
 
-		definition, err := b.createTaskDefinition(project, service)
+		executionRoleArn, err := b.taskExecutionRoleArn(project, service, secretRefs, template)
 		if err != nil {
-			return nil, err
+			return nil, serviceError(service.Name, err)
 		}
-		definition.ExecutionRoleArn = cloudformation.Ref(taskExecutionRole)
-		if taskRole != "" {
-			definition.TaskRoleArn = cloudformation.Ref(taskRole)
+		taskRoleArn, err := b.taskRoleArn(project, service, template)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
 		}
 
+		definition, err := b.createTaskDefinition(project, service, secretRefs)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+		err = setProxyConfiguration(service, definition)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+		b.addXRayDaemon(project, service, definition)
+		if meshed(project, service) {
+			if err := createMeshResources(project, service, template, mesh); err != nil {
+				return nil, serviceError(service.Name, err)
+			}
+			b.addEnvoy(project, service, definition)
+		}
+		definition.ExecutionRoleArn = executionRoleArn
+		definition.TaskRoleArn = taskRoleArn
+		parameterizeImages(project, members, definition, template)
+
 		taskDefinition := fmt.Sprintf("%sTaskDefinition", normalizeResourceName(service.Name))
 		template.Resources[taskDefinition] = definition
 
-		var healthCheck *cloudmap.Service_HealthCheckConfig
-		serviceRegistry := b.createServiceRegistry(service, template, healthCheck)
+		schedule, scheduled, err := serviceSchedule(service)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+		if scheduled {
+			if err := checkScheduledTask(service, members); err != nil {
+				return nil, serviceError(service.Name, err)
+			}
+		}
+		if err := checkDeployMode(service); err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+
+		// Cloud Map registers the task once, sidecars are reached by other containers of the task on localhost.
+		// Scheduled tasks aren't reached by other services, so they're not registered, neither are tasks on ECS Anywhere
+		// instances as Cloud Map can't resolve their address
+		ext := taskExternal(members)
+		var serviceRegistries []ecs.Service_ServiceRegistry
+		if !connect && !scheduled && !ext {
+			var healthCheck *cloudmap.Service_HealthCheckConfig
+			serviceRegistries = append(serviceRegistries, b.createServiceRegistry(project, service, template, healthCheck))
+		}
+
+		controller, err := deploymentController(service)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+		if controller == ecsapi.DeploymentControllerTypeCodeDeploy {
+			if err := checkCodeDeploy(service, members); err != nil {
+				return nil, serviceError(service.Name, err)
+			}
+		}
 
 		var (
-			dependsOn []string
-			serviceLB []ecs.Service_LoadBalancer
+			dependsOn    []string
+			serviceLB    []ecs.Service_LoadBalancer
+			targetGroups []string
+			traffic      blueGreenTraffic
 		)
-		for _, port := range service.Ports {
-			for net := range service.Networks {
-				b.createIngress(service, net, port, template, resources)
+		for _, member := range members {
+			if ext {
+				// ports are bound on the ECS Anywhere instance, which can't be registered to a load balancer
+				break
 			}
+			for _, port := range member.Ports {
+				// sidecars share the task's network interface
+				for net := range service.Networks {
+					b.createIngress(member, net, port, template, resources)
+				}
 
-			protocol := strings.ToUpper(port.Protocol)
-			if resources.loadBalancerType == elbv2.LoadBalancerTypeEnumApplication {
-				// we don't set Https as a certificate must be specified for HTTPS listeners
-				protocol = elbv2.ProtocolEnumHttp
+				protocol := strings.ToUpper(port.Protocol)
+				if resources.loadBalancerType == elbv2.LoadBalancerTypeEnumApplication {
+					// we don't set Https as a certificate must be specified for HTTPS listeners
+					protocol = elbv2.ProtocolEnumHttp
+				}
+				targetGroupName := b.createTargetGroup(project, member, port, template, protocol, resources.vpc)
+				targetGroups = append(targetGroups, targetGroupName)
+				listenerName := b.createListener(member, port, template, targetGroupName, resources.loadBalancer, protocol)
+				dependsOn = append(dependsOn, listenerName)
+				if controller == ecsapi.DeploymentControllerTypeCodeDeploy {
+					traffic, err = b.createBlueGreenTraffic(member, port, template, targetGroupName, listenerName)
+					if err != nil {
+						return nil, serviceError(service.Name, err)
+					}
+					dependsOn = append(dependsOn, traffic.testListener)
+				}
+				serviceLB = append(serviceLB, ecs.Service_LoadBalancer{
+					ContainerName:  member.Name,
+					ContainerPort:  int(port.Target),
+					TargetGroupArn: cloudformation.Ref(targetGroupName),
+				})
 			}
-			targetGroupName := b.createTargetGroup(project, service, port, template, protocol, resources.vpc)
-			listenerName := b.createListener(service, port, template, targetGroupName, resources.loadBalancer, protocol)
-			dependsOn = append(dependsOn, listenerName)
-			serviceLB = append(serviceLB, ecs.Service_LoadBalancer{
-				ContainerName:  service.Name,
-				ContainerPort:  int(port.Target),
-				TargetGroupArn: cloudformation.Ref(targetGroupName),
-			})
 		}
 
 		desiredCount := 1
@@ -137,55 +393,191 @@ func (b *ecsAPIService) convert(project *types.Project, resources awsResources)
 			desiredCount = int(*service.Deploy.Replicas)
 		}
 
-		for dependency := range service.DependsOn {
-			dependsOn = append(dependsOn, serviceResourceName(dependency))
+		dependencies, err := serviceDependencies(project, service)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+		dependsOn = append(dependsOn, dependencies...)
+
+		mounted := map[string]bool{}
+		for _, member := range members {
+			for _, volume := range member.Volumes {
+				if mounted[volume.Source] {
+					continue
+				}
+				mounted[volume.Source] = true
+				dependsOn = append(dependsOn, resources.mountTargets[volume.Source]...)
+			}
 		}
 
-		minPercent, maxPercent, err := computeRollingUpdateLimits(service)
+		subnets, err := resources.serviceSubnets(project, service)
 		if err != nil {
-			return nil, err
+			return nil, serviceError(service.Name, err)
+		}
+
+		// CodeDeploy replaces the tasks set at once, and DAEMON services run a task per instance, so rolling update limits don't apply
+		var deploymentConfiguration *ecs.Service_DeploymentConfiguration
+		schedulingStrategy := ecsapi.SchedulingStrategyReplica
+		if globalMode(service) {
+			schedulingStrategy = ecsapi.SchedulingStrategyDaemon
+			desiredCount = 0
+		} else if controller == ecsapi.DeploymentControllerTypeEcs {
+			minPercent, maxPercent, err := computeRollingUpdateLimits(service)
+			if err != nil {
+				return nil, serviceError(service.Name, err)
+			}
+			deploymentConfiguration = &ecs.Service_DeploymentConfiguration{
+				MaximumPercent:        maxPercent,
+				MinimumHealthyPercent: minPercent,
+			}
+		}
+		if _, err := fallbackCapacity(service); err != nil {
+			return nil, serviceError(service.Name, err)
 		}
 
-		assignPublicIP := ecsapi.AssignPublicIpEnabled
 		launchType := ecsapi.LaunchTypeFargate
-		platformVersion := "1.4.0" // LATEST which is set to 1.3.0 (?) which doesn’t allow efs volumes.
-		if requireEC2(service) {
-			assignPublicIP = ecsapi.AssignPublicIpDisabled
+		platformVersion := fargatePlatformVersion
+		switch {
+		case ext:
+			launchType = launchTypeExternal
+			platformVersion = ""
+		case taskRequiresEC2(members):
 			launchType = ecsapi.LaunchTypeEc2
 			platformVersion = "" // The platform version must be null when specifying an EC2 launch type
 		}
+		var assignPublicIP string
+		if !ext {
+			assignPublicIP, err = publicIPAssignment(service, launchType == ecsapi.LaunchTypeEc2)
+			if err != nil {
+				return nil, serviceError(service.Name, err)
+			}
+		}
+		if launchType == ecsapi.LaunchTypeFargate && assignPublicIP == ecsapi.AssignPublicIpDisabled {
+			b.checkPrivateTaskSubnets(service, subnets, resources)
+		}
+
+		if scheduled {
+			b.createScheduledTask(project, service, template, resources, scheduledTask{
+				schedule:        schedule,
+				taskDefinition:  taskDefinition,
+				taskCount:       desiredCount,
+				launchType:      launchType,
+				platformVersion: platformVersion,
+				assignPublicIP:  assignPublicIP,
+				securityGroups:  resources.serviceSecurityGroups(service),
+				subnets:         subnets,
+				dependsOn:       dependsOn,
+			})
+			for name := range template.Resources {
+				if !existing[name] {
+					b.owners[name] = service.Name
+				}
+			}
+			continue
+		}
+
+		placementConstraints, err := placementConstraints(service)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+		placementStrategies, err := placementStrategies(service)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+		if (len(placementConstraints) > 0 || len(placementStrategies) > 0) && launchType == ecsapi.LaunchTypeFargate {
+			return nil, serviceError(service.Name, fmt.Errorf("deploy.placement.constraints and %s require EC2 launch type, as Fargate places tasks itself", extensionPlacementStrategy))
+		}
+
+		// EXTERNAL tasks use bridge or host network mode on their instance, which don't take an awsvpc configuration
+		networkConfiguration := &ecs.Service_NetworkConfiguration{
+			AwsvpcConfiguration: &ecs.Service_AwsVpcConfiguration{
+				AssignPublicIp: assignPublicIP,
+				SecurityGroups: resources.serviceSecurityGroups(service),
+				Subnets:        subnets,
+			},
+		}
+		if ext {
+			networkConfiguration = nil
+		}
+
+		// the shared capacity provider only scales its Auto Scaling group for the services which strategy selects it
+		var strategy []capacityStrategy
+		if launchType == ecsapi.LaunchTypeEc2 && resources.capacityProvider != "" {
+			strategy = []capacityStrategy{{CapacityProvider: resources.capacityProvider, Weight: 1}}
+			launchType = ""
+		}
+
+		serviceTaskDefinition := cloudformation.Ref(normalizeResourceName(taskDefinition))
+		if deployed, ok := resources.codeDeployed[service.Name]; ok && controller == ecsapi.DeploymentControllerTypeCodeDeploy {
+			// CloudFormation can't update the task definition of a service deployed by CodeDeploy, up deploys the new one
+			serviceTaskDefinition = deployed
+		}
 
-		template.Resources[serviceResourceName(service.Name)] = &ecs.Service{
+		ecsService := ecs.Service{
 			AWSCloudFormationDependsOn: dependsOn,
 			Cluster:                    resources.cluster,
 			DesiredCount:               desiredCount,
 			DeploymentController: &ecs.Service_DeploymentController{
-				Type: ecsapi.DeploymentControllerTypeEcs,
+				Type: controller,
 			},
-			DeploymentConfiguration: &ecs.Service_DeploymentConfiguration{
-				MaximumPercent:        maxPercent,
-				MinimumHealthyPercent: minPercent,
-			},
-			LaunchType: launchType,
-			// TODO we miss support for https://github.com/aws/containers-roadmap/issues/631 to select a capacity provider
-			LoadBalancers: serviceLB,
-			NetworkConfiguration: &ecs.Service_NetworkConfiguration{
-				AwsvpcConfiguration: &ecs.Service_AwsVpcConfiguration{
-					AssignPublicIp: assignPublicIP,
-					SecurityGroups: resources.serviceSecurityGroups(service),
-					Subnets:        resources.subnets,
-				},
-			},
-			PlatformVersion:    platformVersion,
-			PropagateTags:      ecsapi.PropagateTagsService,
-			SchedulingStrategy: ecsapi.SchedulingStrategyReplica,
-			ServiceRegistries:  []ecs.Service_ServiceRegistry{serviceRegistry},
-			Tags:               serviceTags(project, service),
-			TaskDefinition:     cloudformation.Ref(normalizeResourceName(taskDefinition)),
+			DeploymentConfiguration: deploymentConfiguration,
+			LaunchType:              launchType,
+			// TODO we miss support for https://github.com/aws/containers-roadmap/issues/631 to select the capacity provider
+			// created by the stack, which a service can only reference once associated with the cluster
+			LoadBalancers:        serviceLB,
+			NetworkConfiguration: networkConfiguration,
+			PlacementConstraints: placementConstraints,
+			PlacementStrategies:  placementStrategies,
+			PlatformVersion:      platformVersion,
+			PropagateTags:        ecsapi.PropagateTagsService,
+			SchedulingStrategy:   schedulingStrategy,
+			ServiceRegistries:    serviceRegistries,
+			Tags:                 serviceTags(project, service),
+			TaskDefinition:       serviceTaskDefinition,
+		}
+		if strategy != nil {
+			template.Resources[serviceResourceName(service.Name)] = &strategyService{Service: ecsService, CapacityProviderStrategy: strategy}
+		} else {
+			template.Resources[serviceResourceName(service.Name)] = &ecsService
+		}
+
+		if controller == ecsapi.DeploymentControllerTypeCodeDeploy {
+			b.createDeploymentGroup(project, service, template, resources, traffic)
 		}
 
-		b.createAutoscalingPolicy(project, resources, template, service)
+		err = b.createAutoscalingPolicy(project, resources, template, service, targetGroups)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+
+		err = b.createTaskRecycling(project, resources, template, service)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+
+		err = createAlarms(project, resources, template, service, targetGroups)
+		if err != nil {
+			return nil, serviceError(service.Name, err)
+		}
+		for name := range template.Resources {
+			if !existing[name] {
+				b.owners[name] = service.Name
+			}
+		}
+	}
+	err = addFallbackCapacityProviders(project, template)
+	if err != nil {
+		return nil, err
+	}
+	err = applyRoleSettings(project, template)
+	if err != nil {
+		return nil, err
+	}
+	err = b.createDashboard(project, resources, template)
+	if err != nil {
+		return nil, err
 	}
+	b.createOutputs(project, resources, template)
 	return template, nil
 }
 
@@ -197,6 +589,7 @@ func (b *ecsAPIService) createIngress(service types.ServiceConfig, net string, p
 		protocol = allProtocols
 	}
 	ingress := fmt.Sprintf("%s%dIngress", normalizeResourceName(net), port.Target)
+	b.warn(warningPublicIngress, severityInfo, service.Name, "port %d/%s is open to 0.0.0.0/0 on network %s", port.Target, port.Protocol, net)
 	template.Resources[ingress] = &ec2.SecurityGroupIngress{
 		CidrIp:      "0.0.0.0/0",
 		Description: fmt.Sprintf("%s:%d/%s on %s nextwork", service.Name, port.Target, port.Protocol, net),
@@ -207,36 +600,52 @@ func (b *ecsAPIService) createIngress(service types.ServiceConfig, net string, p
 	}
 }
 
-func (b *ecsAPIService) createSecret(project *types.Project, name string, s types.SecretConfig, template *cloudformation.Template) error {
+// createSecret registers secret s, and returns the reference tasks use to access it
+func (b *ecsAPIService) createSecret(project *types.Project, name string, s types.SecretConfig, template *cloudformation.Template, resources awsResources) (string, error) {
 	if s.External.External {
-		return nil
+		return secretValueFrom(s), nil
+	}
+	if isSSMParameter(s) {
+		return "", fmt.Errorf("secret %s is an SSM parameter and must be declared as external", name)
+	}
+	if arn, ok := resources.secrets[name]; ok {
+		return arn, nil
 	}
 	sensitiveData, err := ioutil.ReadFile(s.File)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if !utf8.Valid(sensitiveData) {
+		return "", fmt.Errorf("secret %s is binary and can't be embedded in the CloudFormation template", name)
+	}
+
+	key, _, err := kmsKey(project)
+	if err != nil {
+		return "", err
 	}
 
 	resource := fmt.Sprintf("%sSecret", normalizeResourceName(s.Name))
+	b.warn(warningSecretInTemplate, severityWarning, "", "content of secret %s is embedded in the CloudFormation template", name)
 	template.Resources[resource] = &secretsmanager.Secret{
 		Description:  fmt.Sprintf("Secret %s", s.Name),
 		SecretString: string(sensitiveData),
+		KmsKeyId:     key,
 		Tags:         projectTags(project),
 	}
-	s.Name = cloudformation.Ref(resource)
-	project.Secrets[name] = s
-	return nil
+	return cloudformation.Ref(resource), nil
 }
 
-func (b *ecsAPIService) createLogGroup(project *types.Project, template *cloudformation.Template) {
-	retention := 0
-	if v, ok := project.Extensions[extensionRetention]; ok {
-		retention = v.(int)
+// percentExtension reads an update_config percent extension, which YAML may parse as an int, a float or a string
+func percentExtension(service types.ServiceConfig, key string) (int, bool, error) {
+	x, ok := service.Deploy.UpdateConfig.Extensions[key]
+	if !ok {
+		return 0, false, nil
 	}
-	logGroup := fmt.Sprintf("/docker-compose/%s", project.Name)
-	template.Resources["LogGroup"] = &logs.LogGroup{
-		LogGroupName:    logGroup,
-		RetentionInDays: retention,
+	f, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(x)), 64)
+	if err != nil || f != math.Trunc(f) {
+		return 0, false, fmt.Errorf("service %s: invalid %s: %v is not an integer", service.Name, key, x)
 	}
+	return int(f), true, nil
 }
 
 func computeRollingUpdateLimits(service types.ServiceConfig) (int, int, error) {
@@ -246,37 +655,67 @@ func computeRollingUpdateLimits(service types.ServiceConfig) (int, int, error) {
 		return minPercent, maxPercent, nil
 	}
 	updateConfig := service.Deploy.UpdateConfig
-	min, okMin := updateConfig.Extensions[extensionMinPercent]
+	min, okMin, err := percentExtension(service, extensionMinPercent)
+	if err != nil {
+		return minPercent, maxPercent, err
+	}
 	if okMin {
-		minPercent = min.(int)
+		minPercent = min
 	}
-	max, okMax := updateConfig.Extensions[extensionMaxPercent]
-	if okMax {
-		maxPercent = max.(int)
+	max, okMax, err := percentExtension(service, extensionMaxPercent)
+	if err != nil {
+		return minPercent, maxPercent, err
 	}
-	if okMin && okMax {
-		return minPercent, maxPercent, nil
+	if okMax {
+		maxPercent = max
 	}
 
-	if updateConfig.Parallelism != nil {
+	if updateConfig.Parallelism != nil && !(okMin && okMax) {
 		parallelism := int(*updateConfig.Parallelism)
 		if service.Deploy.Replicas == nil {
 			return minPercent, maxPercent,
 				fmt.Errorf("rolling update configuration require deploy.replicas to be set")
 		}
 		replicas := int(*service.Deploy.Replicas)
-		if replicas < parallelism {
-			return minPercent, maxPercent,
-				fmt.Errorf("deploy.replicas (%d) must be greater than deploy.update_config.parallelism (%d)", replicas, parallelism)
+		// parallelism 0 updates all tasks at once, as does a parallelism above replicas. Derived limits are kept
+		// within ECS ranges, so only x-aws-min_percent and x-aws-max_percent can be reported as invalid
+		if parallelism == 0 || parallelism > replicas {
+			parallelism = replicas
 		}
-		if !okMin {
+		if replicas > 0 && !okMin {
 			minPercent = (replicas - parallelism) * 100 / replicas
 		}
-		if !okMax {
-			maxPercent = (replicas + parallelism) * 100 / replicas
+		if replicas > 0 && !okMax {
+			// rounded up, as ECS rounds the maximum number of running tasks down
+			maxPercent = ((replicas+parallelism)*100 + replicas - 1) / replicas
 		}
 	}
-	return minPercent, maxPercent, nil
+	return minPercent, maxPercent, checkRollingUpdateLimits(service, minPercent, maxPercent)
+}
+
+// checkRollingUpdateLimits makes sure ECS can run a rolling update within limits, which requires to either stop a
+// running task before starting a new one, or to start a new one first
+func checkRollingUpdateLimits(service types.ServiceConfig, minPercent int, maxPercent int) error {
+	if minPercent < 0 || minPercent > 100 {
+		return fmt.Errorf("service %s: %s (%d) must be between 0 and 100", service.Name, extensionMinPercent, minPercent)
+	}
+	if maxPercent < 100 || maxPercent > 200 {
+		return fmt.Errorf("service %s: %s (%d) must be between 100 and 200", service.Name, extensionMaxPercent, maxPercent)
+	}
+	replicas := 1
+	if service.Deploy.Replicas != nil {
+		replicas = int(*service.Deploy.Replicas)
+	}
+	if replicas == 0 {
+		return nil
+	}
+	minTasks := replicas * minPercent / 100
+	maxTasks := replicas * maxPercent / 100
+	if minTasks >= replicas && maxTasks <= replicas {
+		return fmt.Errorf("service %s: %s (%d) and %s (%d) don't allow to stop or start a task to update %d replicas",
+			service.Name, extensionMinPercent, minPercent, extensionMaxPercent, maxPercent, replicas)
+	}
+	return nil
 }
 
 func (b *ecsAPIService) createListener(service types.ServiceConfig, port types.ServicePortConfig,
@@ -328,7 +767,7 @@ func (b *ecsAPIService) createTargetGroup(project *types.Project, service types.
 	return targetGroupName
 }
 
-func (b *ecsAPIService) createServiceRegistry(service types.ServiceConfig, template *cloudformation.Template, healthCheck *cloudmap.Service_HealthCheckConfig) ecs.Service_ServiceRegistry {
+func (b *ecsAPIService) createServiceRegistry(project *types.Project, service types.ServiceConfig, template *cloudformation.Template, healthCheck *cloudmap.Service_HealthCheckConfig) ecs.Service_ServiceRegistry {
 	serviceRegistration := fmt.Sprintf("%sServiceDiscoveryEntry", normalizeResourceName(service.Name))
 	serviceRegistry := ecs.Service_ServiceRegistry{
 		RegistryArn: cloudformation.GetAtt(serviceRegistration, "Arn"),
@@ -351,41 +790,113 @@ func (b *ecsAPIService) createServiceRegistry(service types.ServiceConfig, templ
 			},
 			RoutingPolicy: cloudmapapi.RoutingPolicyMultivalue,
 		},
+		Tags: serviceTags(project, service),
 	}
 	return serviceRegistry
 }
 
-func (b *ecsAPIService) createTaskExecutionRole(project *types.Project, service types.ServiceConfig, template *cloudformation.Template) string {
+func (b *ecsAPIService) createTaskExecutionRole(project *types.Project, service types.ServiceConfig, secretRefs map[string]string, template *cloudformation.Template) (string, error) {
 	taskExecutionRole := fmt.Sprintf("%sTaskExecutionRole", normalizeResourceName(service.Name))
-	policies := b.createPolicies(project, service)
+	var policies []iam.Role_Policy
+	for _, member := range taskServices(project, service) {
+		memberPolicies, err := b.createPolicies(project, member, secretRefs)
+		if err != nil {
+			return "", err
+		}
+		policies = append(policies, memberPolicies...)
+	}
+	managedPolicies := []string{
+		b.partitionArn(ecsTaskExecutionPolicy),
+		b.partitionArn(ecrReadOnlyPolicy),
+	}
+	if name, ok := logsGroup(project); ok {
+		// ECS managed policy grants logging to any log group, restrict it to the one containers log to
+		managedPolicies = []string{b.partitionArn(ecrReadOnlyPolicy)}
+		policies = append(policies, iam.Role_Policy{
+			PolicyDocument: &PolicyDocument{
+				Statement: []PolicyStatement{
+					{
+						Effect:   "Allow",
+						Action:   []string{actionCreateLogStream, actionPutLogEvents},
+						Resource: []string{logsGroupArn(name)},
+					},
+				},
+			},
+			PolicyName: fmt.Sprintf("%sGrantAccessToLogGroup", service.Name),
+		})
+	}
+	managed := map[string]bool{}
+	for _, p := range managedPolicies {
+		managed[p] = true
+	}
+	for _, member := range taskServices(project, service) {
+		if v, ok := member.Extensions[extensionExecutionManagedPolicies]; ok {
+			arns, ok := v.([]interface{})
+			if !ok {
+				return "", fmt.Errorf("service %s: %s must be a list of policy ARNs, got %v", member.Name, extensionExecutionManagedPolicies, v)
+			}
+			for _, a := range arns {
+				s, ok := a.(string)
+				if !ok || s == "" {
+					return "", fmt.Errorf("service %s: %s must be a list of policy ARNs, got %v", member.Name, extensionExecutionManagedPolicies, a)
+				}
+				if !managed[s] {
+					managed[s] = true
+					managedPolicies = append(managedPolicies, s)
+				}
+			}
+		}
+	}
+	if len(managedPolicies) > maxManagedPolicies {
+		return "", fmt.Errorf("task execution role can't have more than %d managed policies, got %d", maxManagedPolicies, len(managedPolicies))
+	}
 	template.Resources[taskExecutionRole] = &iam.Role{
 		AssumeRolePolicyDocument: ecsTaskAssumeRolePolicyDocument,
 		Policies:                 policies,
-		ManagedPolicyArns: []string{
-			ecsTaskExecutionPolicy,
-			ecrReadOnlyPolicy,
-		},
-		Tags: serviceTags(project, service),
+		ManagedPolicyArns:        managedPolicies,
+		Tags:                     serviceTags(project, service),
 	}
-	return taskExecutionRole
+	return taskExecutionRole, nil
 }
 
-func (b *ecsAPIService) createTaskRole(project *types.Project, service types.ServiceConfig, template *cloudformation.Template) string {
+func (b *ecsAPIService) createTaskRole(project *types.Project, service types.ServiceConfig, template *cloudformation.Template) (string, error) {
 	taskRole := fmt.Sprintf("%sTaskRole", normalizeResourceName(service.Name))
 	rolePolicies := []iam.Role_Policy{}
-	if roles, ok := service.Extensions[extensionRole]; ok {
-		rolePolicies = append(rolePolicies, iam.Role_Policy{
-			PolicyDocument: roles,
-		})
-	}
 	managedPolicies := []string{}
-	if v, ok := service.Extensions[extensionManagedPolicies]; ok {
-		for _, s := range v.([]interface{}) {
-			managedPolicies = append(managedPolicies, s.(string))
+	managed := map[string]bool{}
+	// task role is shared by all containers of the task
+	for _, member := range taskServices(project, service) {
+		if roles, ok := member.Extensions[extensionRole]; ok {
+			rolePolicies = append(rolePolicies, iam.Role_Policy{
+				PolicyDocument: roles,
+			})
+		}
+		resourcesPolicies, err := applicationResourcesPolicies(project, member)
+		if err != nil {
+			return "", err
 		}
+		rolePolicies = append(rolePolicies, resourcesPolicies...)
+		rolePolicies = append(rolePolicies, volumesAccessPolicies(project, member)...)
+		if v, ok := member.Extensions[extensionManagedPolicies]; ok {
+			for _, s := range v.([]interface{}) {
+				if !managed[s.(string)] {
+					managed[s.(string)] = true
+					managedPolicies = append(managedPolicies, s.(string))
+				}
+			}
+		}
+	}
+	if executeCommandEnabled(project, service) {
+		rolePolicies = append(rolePolicies, executeCommandPolicy(project))
+	}
+	if meshed(project, service) {
+		rolePolicies = append(rolePolicies, appMeshPolicy(service))
+	}
+	if xrayEnabled(project, service) && !managed[b.partitionArn(xrayDaemonWriteAccess)] {
+		managedPolicies = append(managedPolicies, b.partitionArn(xrayDaemonWriteAccess))
 	}
 	if len(rolePolicies) == 0 && len(managedPolicies) == 0 {
-		return ""
+		return "", nil
 	}
 	template.Resources[taskRole] = &iam.Role{
 		AssumeRolePolicyDocument: ecsTaskAssumeRolePolicyDocument,
@@ -393,42 +904,132 @@ func (b *ecsAPIService) createTaskRole(project *types.Project, service types.Ser
 		ManagedPolicyArns:        managedPolicies,
 		Tags:                     serviceTags(project, service),
 	}
-	return taskRole
+	return taskRole, nil
 }
 
-func (b *ecsAPIService) createCloudMap(project *types.Project, template *cloudformation.Template, vpc string) {
+func (b *ecsAPIService) createCloudMap(project *types.Project, template *cloudformation.Template, vpc string, connect bool) {
+	if connect {
+		// Service Connect resolves services by its proxies, so doesn't need DNS records
+		template.Resources["CloudMap"] = &cloudmap.HttpNamespace{
+			Description: fmt.Sprintf("Service Connect namespace for Docker Compose project %s", project.Name),
+			Name:        fmt.Sprintf("%s.local", project.Name),
+			Tags:        projectTags(project),
+		}
+		return
+	}
 	template.Resources["CloudMap"] = &cloudmap.PrivateDnsNamespace{
 		Description: fmt.Sprintf("Service Map for Docker Compose project %s", project.Name),
 		Name:        fmt.Sprintf("%s.local", project.Name),
 		Vpc:         vpc,
+		Tags:        projectTags(project),
 	}
 }
 
-func (b *ecsAPIService) createPolicies(project *types.Project, service types.ServiceConfig) []iam.Role_Policy {
-	var arns []string
-	if value, ok := service.Extensions[extensionPullCredentials]; ok {
-		arns = append(arns, value.(string))
+// createPolicies grants service's task execution role access to the secrets it consumes, and only those
+func (b *ecsAPIService) createPolicies(project *types.Project, service types.ServiceConfig, secretRefs map[string]string) ([]iam.Role_Policy, error) {
+	var statements []PolicyStatement
+	secret, ok, err := pullCredentials(service)
+	if err != nil {
+		return nil, err
 	}
+	if ok {
+		statements = append(statements, PolicyStatement{
+			Sid:      "PullCredentials",
+			Effect:   "Allow",
+			Action:   []string{actionGetSecretValue, actionGetParameters, actionDecrypt},
+			Resource: []string{secret},
+		})
+	}
+
+	var arns, parameters []string
+	var encrypted bool
+	seen := map[string]bool{}
 	for _, secret := range service.Secrets {
-		arns = append(arns, project.Secrets[secret.Source].Name)
+		if seen[secret.Source] {
+			continue
+		}
+		seen[secret.Source] = true
+		if isSSMParameter(project.Secrets[secret.Source]) {
+			parameters = append(parameters, secretRefs[secret.Source])
+			continue
+		}
+		arns = append(arns, secretPolicyResource(project, secret.Source, secretRefs[secret.Source]))
+		encrypted = encrypted || !project.Secrets[secret.Source].External.External
 	}
 	if len(arns) > 0 {
-		return []iam.Role_Policy{
-			{
-				PolicyDocument: &PolicyDocument{
-					Statement: []PolicyStatement{
-						{
-							Effect:   "Allow",
-							Action:   []string{actionGetSecretValue, actionGetParameters, actionDecrypt},
-							Resource: arns,
-						},
+		statements = append(statements, PolicyStatement{
+			Sid:      "Secrets",
+			Effect:   "Allow",
+			Action:   []string{actionGetSecretValue, actionDecrypt},
+			Resource: arns,
+		})
+		// secrets created by the project are encrypted by the customer-managed key, which may belong to
+		// another account: the key ARN is used as is, cross-account access is up to the key policy
+		if key, ok, _ := kmsKey(project); ok && encrypted {
+			statements = append(statements, PolicyStatement{
+				Sid:      "KMSKey",
+				Effect:   "Allow",
+				Action:   []string{actionDecrypt},
+				Resource: []string{key},
+			})
+		}
+	}
+	if len(parameters) > 0 {
+		statements = append(statements, PolicyStatement{
+			Sid:      "SSMParameters",
+			Effect:   "Allow",
+			Action:   []string{actionGetParameters},
+			Resource: parameters,
+		}, PolicyStatement{
+			// SecureString parameters are encrypted by KMS, restrict decryption to SSM usage
+			Sid:      "DecryptSSMParameters",
+			Effect:   "Allow",
+			Action:   []string{actionDecrypt},
+			Resource: []string{"*"},
+			Condition: map[string]interface{}{
+				"StringEquals": map[string]interface{}{
+					"kms:ViaService": cloudformation.Sub("ssm.${AWS::Region}.amazonaws.com"),
+				},
+			},
+		})
+	}
+	if configs := serviceConfigs(project, service); len(configs) > 0 {
+		statements = append(statements, PolicyStatement{
+			Sid:      "Configs",
+			Effect:   "Allow",
+			Action:   []string{actionGetParameters},
+			Resource: configs,
+		})
+	}
+	var policies []iam.Role_Policy
+	if len(statements) > 0 {
+		policies = append(policies, iam.Role_Policy{
+			PolicyDocument: &PolicyDocument{
+				Statement: statements,
+			},
+			PolicyName: fmt.Sprintf("%sGrantAccessToSecrets", service.Name),
+		})
+	}
+	if bucket, ok := envFilesBucket(project); ok && len(service.EnvFile) > 0 {
+		policies = append(policies, iam.Role_Policy{
+			PolicyDocument: &PolicyDocument{
+				Statement: []PolicyStatement{
+					{
+						Effect:   "Allow",
+						Action:   []string{actionGetObject},
+						Resource: []string{s3Arn(b.partition(), bucket, envFilesPrefix(project, service)+"*")},
+					},
+					{
+						Effect:   "Allow",
+						Action:   []string{actionGetBucket},
+						Resource: []string{s3Arn(b.partition(), bucket, "")},
 					},
 				},
-				PolicyName: fmt.Sprintf("%sGrantAccessToSecrets", service.Name),
 			},
-		}
+			PolicyName: fmt.Sprintf("%sGrantAccessToEnvFiles", service.Name),
+		})
 	}
-	return nil
+	return policies, nil
 }
 
 func networkResourceName(network string) string {
@@ -17,8 +17,12 @@
 package ecs
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/docker/compose-cli/api/compose"
@@ -27,12 +31,17 @@ import (
 	"github.com/awslabs/goformation/v4/cloudformation"
 	"github.com/awslabs/goformation/v4/cloudformation/ec2"
 	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/efs"
 	"github.com/awslabs/goformation/v4/cloudformation/elasticloadbalancingv2"
 	"github.com/awslabs/goformation/v4/cloudformation/iam"
 	"github.com/awslabs/goformation/v4/cloudformation/logs"
+	"github.com/awslabs/goformation/v4/cloudformation/secretsmanager"
+	"github.com/awslabs/goformation/v4/cloudformation/tags"
+	"github.com/awslabs/goformation/v4/intrinsics"
 	"github.com/compose-spec/compose-go/cli"
 	"github.com/compose-spec/compose-go/loader"
 	"github.com/compose-spec/compose-go/types"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/golden"
 )
@@ -44,6 +53,174 @@ func TestSimpleConvert(t *testing.T) {
 	golden.Assert(t, result, expected)
 }
 
+func TestDeterministicConvert(t *testing.T) {
+	services := map[string]string{
+		"front": `
+  front:
+    image: nginx
+    ports:
+      - 80:80
+    networks:
+      - public
+      - private
+    environment:
+      - A=1
+      - B=2
+      - C=3
+      - D=4
+    depends_on:
+      - back
+      - cache
+`,
+		"back": `
+  back:
+    image: app
+    networks:
+      - private
+      - storage
+    environment:
+      - X=1
+      - Y=2
+      - Z=3
+`,
+		"cache": `
+  cache:
+    image: redis
+    networks:
+      - private
+`,
+	}
+	networks := `
+networks:
+  public:
+  private:
+  storage:
+x-aws-tags:
+  team: payments
+  env: staging
+  owner: me
+`
+	convert := func(order ...string) string {
+		yaml := "services:"
+		for _, name := range order {
+			yaml += services[name]
+		}
+		template, err := (&ecsAPIService{}).convert(loadConfig(t, yaml+networks), awsResources{})
+		assert.NilError(t, err)
+		raw, err := marshall(template)
+		assert.NilError(t, err)
+		return string(raw)
+	}
+	expected := convert("front", "back", "cache")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, convert("front", "back", "cache"), expected)
+	}
+	assert.Equal(t, convert("cache", "back", "front"), expected)
+}
+
+func TestConvertYAMLRoundTrip(t *testing.T) {
+	template, err := (&ecsAPIService{}).convert(loadConfig(t, `
+services:
+  front:
+    image: nginx:1.19
+    ports:
+      - 80:80
+    depends_on:
+      - back
+  back:
+    image: app
+x-aws-parameterize_images: true
+`), awsResources{})
+	assert.NilError(t, err)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+
+	yaml, err := formatTemplate(raw, "yaml")
+	assert.NilError(t, err)
+	assert.Check(t, !strings.Contains(string(yaml), "!"), "short form intrinsic functions aren't expected")
+	assert.Check(t, strings.Contains(string(yaml), "Fn::Sub"))
+
+	// goformation can't unmarshall the template as resources, as the init containers hack sets Essential as a string
+	options := &intrinsics.ProcessorOptions{NoProcess: true}
+	expected, err := intrinsics.ProcessJSON(raw, options)
+	assert.NilError(t, err)
+	actual, err := intrinsics.ProcessYAML(yaml, options)
+	assert.NilError(t, err)
+	assert.Equal(t, string(actual), string(expected))
+
+	_, err = formatTemplate(raw, "xml")
+	assert.Error(t, err, `unsupported template format "xml", must be json or yaml`)
+}
+
+func TestLabelsAsTagsFlag(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  api:
+    image: nginx
+    labels:
+      component: api
+`)
+	assert.Check(t, !labelsAsTags(project))
+	applyLabelsAsTags(project, false)
+	assert.Check(t, !labelsAsTags(project))
+	applyLabelsAsTags(project, true)
+	assert.Check(t, labelsAsTags(project))
+
+	project.Extensions[extensionLabelsAsTags] = "yes"
+	assert.Check(t, !labelsAsTags(project))
+}
+
+func TestUserTags(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  api:
+    image: nginx
+    ports:
+      - 80:80
+    labels:
+      env: production
+      component: api
+x-aws-tags:
+  team: payments
+  env: staging
+x-aws-labels_as_tags: true
+`)
+	ignore := cmpopts.IgnoreUnexported(tags.Tag{})
+	projectTags := []tags.Tag{
+		{Key: "com.docker.compose.project", Value: "Test"},
+		{Key: "env", Value: "staging"},
+		{Key: "team", Value: "payments"},
+	}
+	assert.DeepEqual(t, template.Resources["Cluster"].(*ecs.Cluster).Tags, projectTags, ignore)
+	assert.DeepEqual(t, template.Resources["LoadBalancer"].(*elasticloadbalancingv2.LoadBalancer).Tags, projectTags, ignore)
+	assert.DeepEqual(t, template.Resources["ApiTCP80TargetGroup"].(*elasticloadbalancingv2.TargetGroup).Tags, projectTags, ignore)
+
+	serviceTags := []tags.Tag{
+		{Key: "com.docker.compose.project", Value: "Test"},
+		{Key: "com.docker.compose.service", Value: "api"},
+		{Key: "component", Value: "api"},
+		{Key: "env", Value: "production"},
+		{Key: "team", Value: "payments"},
+	}
+	assert.DeepEqual(t, template.Resources["ApiService"].(*ecs.Service).Tags, serviceTags, ignore)
+	assert.DeepEqual(t, template.Resources["ApiTaskDefinition"].(*ecs.TaskDefinition).Tags, serviceTags, ignore)
+	assert.DeepEqual(t, template.Resources["ApiTaskExecutionRole"].(*iam.Role).Tags, serviceTags, ignore)
+
+	stackTags, err := projectStackTags(loadConfig(t, `
+services:
+  api:
+    image: nginx
+x-aws-tags:
+  team: payments
+`))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, stackTags, map[string]string{
+		"com.docker.compose.project":          "Test",
+		"com.docker.compose.volumes.deletion": "delete",
+		"team":                                "payments",
+	})
+}
+
 func TestLogging(t *testing.T) {
 	template := convertYaml(t, `
 services:
@@ -53,7 +230,7 @@ services:
       options:
         awslogs-datetime-pattern: "FOO"
 
-x-aws-logs_retention: 10
+x-aws-logs_retention: 14
 `)
 	def := template.Resources["FooTaskDefinition"].(*ecs.TaskDefinition)
 	logging := getMainContainer(def, t).LogConfiguration
@@ -64,7 +241,7 @@ x-aws-logs_retention: 10
 	}
 
 	logGroup := template.Resources["LogGroup"].(*logs.LogGroup)
-	assert.Equal(t, logGroup.RetentionInDays, 10)
+	assert.Equal(t, logGroup.RetentionInDays, 14)
 }
 
 func TestEnvFile(t *testing.T) {
@@ -87,6 +264,57 @@ services:
 	assert.Check(t, found, "environment variable FOO not set")
 }
 
+func TestEnvFileUploadedToS3(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  foo:
+    image: hello_world
+    env_file:
+      - testdata/input/envfile
+    environment:
+      - "ZOT=QIX"
+x-aws-env_files_bucket: mybucket
+`)
+	def := template.Resources["FooTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.Equal(t, get(container.Environment, "FOO"), "")
+	assert.Equal(t, get(container.Environment, "ZOT"), "QIX")
+	assert.Equal(t, len(container.EnvironmentFiles), 1)
+	assert.Equal(t, container.EnvironmentFiles[0].Type, "s3")
+	assert.Check(t, strings.HasPrefix(container.EnvironmentFiles[0].Value, "arn:aws:s3:::mybucket/Test/foo/"))
+	assert.Check(t, strings.HasSuffix(container.EnvironmentFiles[0].Value, "-envfile"))
+
+	role := template.Resources["FooTaskExecutionRole"].(*iam.Role)
+	assert.Equal(t, len(role.Policies), 1)
+	assert.Equal(t, role.Policies[0].PolicyName, "fooGrantAccessToEnvFiles")
+	policy := role.Policies[0].PolicyDocument.(*PolicyDocument)
+	assert.DeepEqual(t, policy.Statement[0].Resource, []string{"arn:aws:s3:::mybucket/Test/foo/*"})
+}
+
+func TestEnvFilesBucketFlag(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  foo:
+    image: hello_world
+    env_file:
+      - testdata/input/envfile
+x-aws-env_files_bucket: mybucket
+`)
+	applyEnvFilesBucket(project, "")
+	bucket, ok := envFilesBucket(project)
+	assert.Check(t, ok)
+	assert.Equal(t, bucket, "mybucket")
+
+	applyEnvFilesBucket(project, "otherbucket")
+	bucket, ok = envFilesBucket(project)
+	assert.Check(t, ok)
+	assert.Equal(t, bucket, "otherbucket")
+
+	project.Extensions[extensionEnvFilesBucket] = 42
+	_, ok = envFilesBucket(project)
+	assert.Check(t, !ok)
+}
+
 func TestEnvFileAndEnv(t *testing.T) {
 	template := convertYaml(t, `
 services:
@@ -109,6 +337,122 @@ services:
 	assert.Check(t, found, "environment variable FOO not set")
 }
 
+func TestServiceLogsRetention(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  api:
+    image: hello_world
+    x-aws-logs_retention: 90
+  worker:
+    image: hello_world
+    x-aws-logs_retention: 3
+  cron:
+    image: hello_world
+
+x-aws-logs_retention: 14
+`)
+	_, ok := template.Resources["LogGroup"]
+	assert.Check(t, !ok)
+	for service, retention := range map[string]int{"Api": 90, "Worker": 3, "Cron": 14} {
+		logGroup := template.Resources[service+"LogGroup"].(*logs.LogGroup)
+		assert.Equal(t, logGroup.LogGroupName, "/docker-compose/Test/"+strings.ToLower(service))
+		assert.Equal(t, logGroup.RetentionInDays, retention)
+
+		def := template.Resources[service+"TaskDefinition"].(*ecs.TaskDefinition)
+		logging := getMainContainer(def, t).LogConfiguration
+		assert.Equal(t, logging.Options["awslogs-group"], cloudformation.Ref(service+"LogGroup"))
+	}
+}
+
+func TestLogsRetentionInvalid(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  foo:
+    image: hello_world
+    x-aws-logs_retention: 4
+`)
+	backend := &ecsAPIService{}
+	_, err := backend.convert(project, awsResources{})
+	assert.Error(t, err, "service foo: x-aws-logs_retention must be one of 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1827, 3653, got 4")
+}
+
+func TestLogsRetain(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  foo:
+    image: hello_world
+
+x-aws-logs_retain: true
+`)
+	logGroup := template.Resources["LogGroup"].(*logs.LogGroup)
+	assert.Equal(t, string(logGroup.AWSCloudFormationDeletionPolicy), "Retain")
+}
+
+func TestLogsGroup(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  foo:
+    image: hello_world
+
+x-aws-logs_group: /my/central/group
+`)
+	_, ok := template.Resources["LogGroup"]
+	assert.Check(t, !ok)
+	assert.Equal(t, template.Outputs["LogGroup"].Value, "/my/central/group")
+
+	def := template.Resources["FooTaskDefinition"].(*ecs.TaskDefinition)
+	logging := getMainContainer(def, t).LogConfiguration
+	assert.Equal(t, logging.Options["awslogs-group"], "/my/central/group")
+
+	role := template.Resources["FooTaskExecutionRole"].(*iam.Role)
+	assert.DeepEqual(t, role.ManagedPolicyArns, []string{ecrReadOnlyPolicy})
+	assert.Equal(t, len(role.Policies), 1)
+	assert.DeepEqual(t, role.Policies[0].PolicyDocument.(*PolicyDocument).Statement, []PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   []string{actionCreateLogStream, actionPutLogEvents},
+			Resource: []string{cloudformation.Sub("arn:${AWS::Partition}:logs:${AWS::Region}:${AWS::AccountId}:log-group:/my/central/group:*")},
+		},
+	})
+}
+
+func TestLoggingOptions(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  foo:
+    image: hello_world
+    logging:
+      driver: awslogs
+      options:
+        mode: non-blocking
+        max-buffer-size: 4m
+        awslogs-datetime-format: "%Y-%m-%d"
+        awslogs-multiline-pattern: "^INFO"
+        awslogs-stream-prefix: app
+        tag: "{{.Name}}"
+`)
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	def := template.Resources["FooTaskDefinition"].(*ecs.TaskDefinition)
+	logging := getMainContainer(def, t).LogConfiguration
+	assert.DeepEqual(t, logging.Options, map[string]string{
+		"awslogs-region":            cloudformation.Ref("AWS::Region"),
+		"awslogs-group":             cloudformation.Ref("LogGroup"),
+		"awslogs-stream-prefix":     "app",
+		"awslogs-datetime-format":   "%Y-%m-%d",
+		"awslogs-multiline-pattern": "^INFO",
+		"mode":                      "non-blocking",
+		"max-buffer-size":           "4m",
+	})
+
+	assert.Equal(t, len(backend.warnings), 1)
+	warning := backend.warnings[0]
+	assert.Equal(t, warning.Code, warningUnknownLoggingOption)
+	assert.Equal(t, warning.Service, "foo")
+	assert.Equal(t, warning.Message, "logging option tag is ignored as not supported by awslogs driver")
+}
+
 func TestRollingUpdateLimits(t *testing.T) {
 	template := convertYaml(t, `
 services:
@@ -124,6 +468,37 @@ services:
 	assert.Check(t, service.DeploymentConfiguration.MinimumHealthyPercent == 50)
 }
 
+func TestRollingUpdateDerivedLimitsStayInRange(t *testing.T) {
+	for _, c := range []struct {
+		replicas    int
+		parallelism int
+		extension   string
+		min         int
+		max         int
+	}{
+		{replicas: 1, parallelism: 2, min: 0, max: 200},
+		{replicas: 3, parallelism: 0, min: 0, max: 200},
+		{replicas: 3, parallelism: 1, min: 66, max: 134},
+		{replicas: 3, parallelism: 1, extension: "x-aws-min_percent: 100", min: 100, max: 134},
+		{replicas: 0, parallelism: 1, min: 100, max: 200},
+	} {
+		project := loadConfig(t, fmt.Sprintf(`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      replicas: %d
+      update_config:
+        parallelism: %d
+        %s
+`, c.replicas, c.parallelism, c.extension))
+		min, max, err := computeRollingUpdateLimits(project.Services[0])
+		assert.NilError(t, err)
+		assert.Equal(t, min, c.min)
+		assert.Equal(t, max, c.max)
+	}
+}
+
 func TestRollingUpdateExtension(t *testing.T) {
 	template := convertYaml(t, `
 services:
@@ -139,6 +514,81 @@ services:
 	assert.Check(t, service.DeploymentConfiguration.MinimumHealthyPercent == 25)
 }
 
+func TestRollingUpdateExtensionNumericShapes(t *testing.T) {
+	for _, value := range []string{"50", "50.0", `"50"`, `" 50 "`} {
+		project := loadConfig(t, fmt.Sprintf(`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      update_config:
+        x-aws-min_percent: %s
+`, value))
+		min, max, err := computeRollingUpdateLimits(project.Services[0])
+		assert.NilError(t, err, value)
+		assert.Equal(t, min, 50, value)
+		assert.Equal(t, max, 200, value)
+	}
+}
+
+func TestRollingUpdateExtensionMalformed(t *testing.T) {
+	for value, expected := range map[string]string{
+		"50.5":    "service foo: invalid x-aws-min_percent: 50.5 is not an integer",
+		`"50%"`:   "service foo: invalid x-aws-min_percent: 50% is not an integer",
+		`"fifty"`: "service foo: invalid x-aws-min_percent: fifty is not an integer",
+		"true":    "service foo: invalid x-aws-min_percent: true is not an integer",
+		"":        "service foo: invalid x-aws-min_percent: <nil> is not an integer",
+		"[50]":    "service foo: invalid x-aws-min_percent: [50] is not an integer",
+		"{a: 50}": "service foo: invalid x-aws-min_percent: map[a:50] is not an integer",
+	} {
+		project := loadConfig(t, fmt.Sprintf(`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      update_config:
+        x-aws-min_percent: %s
+`, value))
+		_, _, err := computeRollingUpdateLimits(project.Services[0])
+		assert.Error(t, err, expected, value)
+	}
+}
+
+func TestRollingUpdateExtensionOutOfRange(t *testing.T) {
+	for _, c := range []struct {
+		replicas int
+		min      int
+		max      int
+		expected string
+	}{
+		{replicas: 2, min: -1, max: 200, expected: "service foo: x-aws-min_percent (-1) must be between 0 and 100"},
+		{replicas: 2, min: 150, max: 200, expected: "service foo: x-aws-min_percent (150) must be between 0 and 100"},
+		{replicas: 2, min: 50, max: 80, expected: "service foo: x-aws-max_percent (80) must be between 100 and 200"},
+		{replicas: 2, min: 50, max: 300, expected: "service foo: x-aws-max_percent (300) must be between 100 and 200"},
+		{replicas: 1, min: 100, max: 100, expected: "service foo: x-aws-min_percent (100) and x-aws-max_percent (100) don't allow to stop or start a task to update 1 replicas"},
+		{replicas: 1, min: 100, max: 150, expected: "service foo: x-aws-min_percent (100) and x-aws-max_percent (150) don't allow to stop or start a task to update 1 replicas"},
+		{replicas: 2, min: 100, max: 150},
+		{replicas: 0, min: 100, max: 100},
+	} {
+		project := loadConfig(t, fmt.Sprintf(`
+services:
+  foo:
+    image: hello_world
+    deploy:
+      replicas: %d
+      update_config:
+        x-aws-min_percent: %d
+        x-aws-max_percent: %d
+`, c.replicas, c.min, c.max))
+		_, _, err := computeRollingUpdateLimits(project.Services[0])
+		if c.expected == "" {
+			assert.NilError(t, err)
+		} else {
+			assert.Error(t, err, c.expected)
+		}
+	}
+}
+
 func TestRolePolicy(t *testing.T) {
 	template := convertYaml(t, `
 services:
@@ -159,6 +609,57 @@ services:
 	assert.DeepEqual(t, []string{"secret"}, policy.Statement[0].Resource)
 }
 
+func TestPullCredentialsMalformed(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  foo:
+    image: hello_world
+    x-aws-pull_credentials:
+      name: secret
+`)
+	_, err := (&ecsAPIService{}).convert(project, awsResources{})
+	assert.ErrorContains(t, err, "x-aws-pull_credentials must be the ARN or name of a secret, got map[name:secret]")
+}
+
+func TestSecretPoliciesScopedToService(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  front:
+    image: hello_world
+    x-aws-pull_credentials: arn:aws:secretsmanager:eu-west-3:123456789012:secret:registry
+    secrets:
+      - db
+  back:
+    image: hello_world
+secrets:
+  db:
+    file: ./testdata/input/envfile
+`)
+	front := template.Resources["FrontTaskExecutionRole"].(*iam.Role)
+	assert.Equal(t, len(front.Policies), 1)
+	policy := front.Policies[0].PolicyDocument.(*PolicyDocument)
+	assert.Equal(t, len(policy.Statement), 2)
+	assert.Equal(t, policy.Statement[0].Sid, "PullCredentials")
+	assert.DeepEqual(t, policy.Statement[0].Resource, []string{"arn:aws:secretsmanager:eu-west-3:123456789012:secret:registry"})
+	assert.Equal(t, policy.Statement[1].Sid, "Secrets")
+	assert.Equal(t, len(policy.Statement[1].Resource), 1)
+	ref := policy.Statement[1].Resource[0]
+	_, ok := template.Resources[resourceFromRef(t, ref)]
+	assert.Check(t, ok, "policy references unknown resource %s", ref)
+
+	back := template.Resources["BackTaskExecutionRole"].(*iam.Role)
+	assert.Equal(t, len(back.Policies), 0)
+}
+
+// resourceFromRef decodes a cloudformation.Ref intrinsic to the referenced resource name
+func resourceFromRef(t *testing.T, ref string) string {
+	decoded, err := base64.StdEncoding.DecodeString(ref)
+	assert.NilError(t, err)
+	var intrinsic map[string]string
+	assert.NilError(t, json.Unmarshal(decoded, &intrinsic))
+	return intrinsic["Ref"]
+}
+
 func TestMapNetworksToSecurityGroups(t *testing.T) {
 	template := convertYaml(t, `
 services:
@@ -211,6 +712,30 @@ func TestLoadBalancerTypeApplication(t *testing.T) {
 	}
 }
 
+func TestPublicIngressWarning(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    ports:
+      - 80:80
+`)
+	backend := &ecsAPIService{}
+	_, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(backend.warnings), 1)
+	warning := backend.warnings[0]
+	assert.Equal(t, warning.Code, warningPublicIngress)
+	assert.Equal(t, warning.Severity, severityInfo)
+	assert.Equal(t, warning.Service, "test")
+
+	var buf bytes.Buffer
+	assert.NilError(t, backend.warnings.report(&buf, warningsFormatJSON))
+	var reported []convertWarning
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &reported))
+	assert.DeepEqual(t, reported, []convertWarning(backend.warnings))
+}
+
 func TestNoLoadBalancerIfNoPortExposed(t *testing.T) {
 	template := convertYaml(t, `
 services:
@@ -240,6 +765,28 @@ services:
 	assert.Check(t, service.DesiredCount == 10)
 }
 
+func TestServiceZeroReplicas(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: nginx
+    deploy:
+      replicas: 0
+  default:
+    image: nginx
+`)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	var marshalled struct {
+		Resources map[string]struct {
+			Properties map[string]interface{}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	assert.Equal(t, marshalled.Resources["TestService"].Properties["DesiredCount"], float64(0))
+	assert.Equal(t, marshalled.Resources["DefaultService"].Properties["DesiredCount"], float64(1))
+}
+
 func TestTaskSizeConvert(t *testing.T) {
 	template := convertYaml(t, `
 services:
@@ -374,6 +921,120 @@ services:
 	assert.Equal(t, container.WorkingDirectory, "working_dir")
 }
 
+func TestInitTtyAndStdinOpen(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: "image"
+    init: true
+    tty: true
+    stdin_open: true
+`)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.Check(t, container.LinuxParameters.InitProcessEnabled)
+	assert.Check(t, container.PseudoTerminal)
+	assert.Check(t, container.Interactive)
+
+	template = convertYaml(t, `
+services:
+  test:
+    image: "image"
+`)
+	def = template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container = getMainContainer(def, t)
+	assert.Check(t, !container.LinuxParameters.InitProcessEnabled)
+	assert.Check(t, !container.PseudoTerminal)
+	assert.Check(t, !container.Interactive)
+}
+
+func TestTmpfsAndShmSize(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: "image"
+    tmpfs:
+      - /run
+      - /tmp:size=64m,noexec
+    shm_size: 256m
+`)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.DeepEqual(t, container.LinuxParameters.Tmpfs, []ecs.TaskDefinition_Tmpfs{
+		{ContainerPath: "/run", Size: 100},
+		{ContainerPath: "/tmp", Size: 64, MountOptions: []string{"noexec"}},
+	})
+	assert.Equal(t, container.LinuxParameters.SharedMemorySize, 256)
+	assert.DeepEqual(t, def.RequiresCompatibilities, []string{"EC2"})
+
+	service := template.Resources["TestService"].(*ecs.Service)
+	assert.Equal(t, service.LaunchType, "EC2")
+}
+
+func TestHealthCheck(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  exec:
+    image: "image"
+    healthcheck:
+      test: ["CMD", "curl", "-f", "http://localhost"]
+      interval: 30s
+      timeout: 5s
+      retries: 3
+      start_period: 1m
+  shell:
+    image: "image"
+    healthcheck:
+      test: curl -f http://localhost || exit 1
+  none:
+    image: "image"
+    healthcheck:
+      test: ["NONE"]
+  disabled:
+    image: "image"
+    healthcheck:
+      disable: true
+`)
+	def := template.Resources["ExecTaskDefinition"].(*ecs.TaskDefinition)
+	assert.DeepEqual(t, getMainContainer(def, t).HealthCheck, &ecs.TaskDefinition_HealthCheck{
+		Command:     []string{"CMD", "curl", "-f", "http://localhost"},
+		Interval:    30,
+		Timeout:     5,
+		Retries:     3,
+		StartPeriod: 60,
+	}, cmpopts.IgnoreUnexported(ecs.TaskDefinition_HealthCheck{}))
+
+	def = template.Resources["ShellTaskDefinition"].(*ecs.TaskDefinition)
+	assert.DeepEqual(t, getMainContainer(def, t).HealthCheck.Command, []string{"CMD-SHELL", "curl -f http://localhost || exit 1"})
+
+	for _, name := range []string{"NoneTaskDefinition", "DisabledTaskDefinition"} {
+		def = template.Resources[name].(*ecs.TaskDefinition)
+		assert.Check(t, getMainContainer(def, t).HealthCheck == nil, name)
+	}
+}
+
+func TestHealthCheckErrors(t *testing.T) {
+	for _, tc := range []struct {
+		healthcheck string
+		err         string
+	}{
+		{"{ test: [CMD] }", "healthcheck test CMD requires a command"},
+		{"{ test: [EXEC, curl] }", "healthcheck test must start with CMD, CMD-SHELL or NONE"},
+		{"{ test: [CMD, curl], interval: 1s }", "healthcheck interval must be between 5 and 300, got 1"},
+		{"{ test: [CMD, curl], retries: 20 }", "healthcheck retries must be between 1 and 10, got 20"},
+	} {
+		project := loadConfig(t, `
+services:
+  test:
+    image: "image"
+    healthcheck: `+tc.healthcheck+`
+`)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.ErrorContains(t, err, tc.err)
+	}
+}
+
 func get(l []ecs.TaskDefinition_KeyValuePair, name string) string {
 	for _, e := range l {
 		if e.Name == name {
@@ -383,6 +1044,410 @@ func get(l []ecs.TaskDefinition_KeyValuePair, name string) string {
 	return ""
 }
 
+func TestSecretKeysAsEnvironment(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - source: db
+        x-aws-keys: [username, password]
+      - source: token
+secrets:
+  db:
+    external: true
+    name: arn:aws:secretsmanager:eu-west-3:123456789012:secret:db
+  token:
+    external: true
+    name: arn:aws:secretsmanager:eu-west-3:123456789012:secret:token
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.DeepEqual(t, container.Secrets, []ecs.TaskDefinition_Secret{
+		{
+			Name:      "DB_USERNAME",
+			ValueFrom: cloudformation.Join("", []string{"arn:aws:secretsmanager:eu-west-3:123456789012:secret:db", ":username::"}),
+		},
+		{
+			Name:      "DB_PASSWORD",
+			ValueFrom: cloudformation.Join("", []string{"arn:aws:secretsmanager:eu-west-3:123456789012:secret:db", ":password::"}),
+		},
+	}, cmpopts.IgnoreUnexported(ecs.TaskDefinition_Secret{}))
+
+	role := template.Resources["TestTaskExecutionRole"].(*iam.Role)
+	policy := role.Policies[0].PolicyDocument.(*PolicyDocument)
+	assert.DeepEqual(t, policy.Statement[0].Resource, []string{
+		"arn:aws:secretsmanager:eu-west-3:123456789012:secret:db",
+		"arn:aws:secretsmanager:eu-west-3:123456789012:secret:token",
+	})
+}
+
+func TestSSMParameterSecrets(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - db
+      - token
+secrets:
+  db:
+    external: true
+    name: arn:aws:ssm:eu-west-3:123456789012:parameter/db
+  token:
+    external: true
+    name: /app/token
+    x-aws-ssm_parameter: true
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	for name := range template.Resources {
+		assert.Check(t, !strings.HasSuffix(name, "Secret"), name)
+	}
+
+	token := cloudformation.Sub("arn:${AWS::Partition}:ssm:${AWS::Region}:${AWS::AccountId}:parameter/app/token")
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	sidecar := def.ContainerDefinitions[0]
+	assert.Equal(t, sidecar.Name, "Test_Secrets_InitContainer")
+	assert.DeepEqual(t, sidecar.Secrets, []ecs.TaskDefinition_Secret{
+		{Name: "db", ValueFrom: "arn:aws:ssm:eu-west-3:123456789012:parameter/db"},
+		{Name: "token", ValueFrom: token},
+	}, cmpopts.IgnoreUnexported(ecs.TaskDefinition_Secret{}))
+
+	role := template.Resources["TestTaskExecutionRole"].(*iam.Role)
+	policy := role.Policies[0].PolicyDocument.(*PolicyDocument)
+	assert.Equal(t, len(policy.Statement), 2)
+	assert.DeepEqual(t, policy.Statement[0].Action, []string{actionGetParameters})
+	assert.DeepEqual(t, policy.Statement[0].Resource, []string{"arn:aws:ssm:eu-west-3:123456789012:parameter/db", token})
+	assert.DeepEqual(t, policy.Statement[1].Action, []string{actionDecrypt})
+}
+
+func TestSSMParameterSecretMustBeExternal(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - token
+secrets:
+  token:
+    file: ./testdata/input/envfile
+    x-aws-ssm_parameter: true
+`)
+	backend := &ecsAPIService{}
+	_, err := backend.convert(project, awsResources{})
+	assert.ErrorContains(t, err, "must be declared as external")
+}
+
+func TestSecretCreatedBySDK(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - db
+secrets:
+  db:
+    file: ./testdata/input/envfile
+`)
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{
+		secrets: map[string]string{"db": "arn:aws:secretsmanager:eu-west-3:123456789012:secret:Test/db"},
+	})
+	assert.NilError(t, err)
+	for name, r := range template.Resources {
+		_, ok := r.(*secretsmanager.Secret)
+		assert.Check(t, !ok, name)
+	}
+	assert.Equal(t, len(backend.warnings), 0)
+
+	role := template.Resources["TestTaskExecutionRole"].(*iam.Role)
+	policy := role.Policies[0].PolicyDocument.(*PolicyDocument)
+	assert.DeepEqual(t, policy.Statement[0].Resource, []string{"arn:aws:secretsmanager:eu-west-3:123456789012:secret:Test/db"})
+}
+
+func TestBinarySecretCantBeInlined(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - cert
+secrets:
+  cert:
+    file: ./testdata/input/binary
+`)
+	backend := &ecsAPIService{}
+	_, err := backend.convert(project, awsResources{})
+	assert.ErrorContains(t, err, "secret cert is binary")
+}
+
+func TestReadOnlyAndUser(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    read_only: true
+    user: "1000:1000"
+    volumes:
+      - data:/data
+volumes:
+  data:
+    external: true
+    name: fs-123abc
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.Equal(t, container.ReadonlyRootFilesystem, true)
+	assert.Equal(t, container.User, "1000:1000")
+	assert.Equal(t, len(container.MountPoints), 1)
+	assert.Equal(t, container.MountPoints[0].ContainerPath, "/data")
+	assert.Equal(t, container.MountPoints[0].ReadOnly, false)
+}
+
+func TestWorkingDirEntrypointAndCommand(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: hello_world
+    working_dir: /app
+    entrypoint: ["sh", "-c"]
+    command: ["echo hello"]
+`)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.Equal(t, container.WorkingDirectory, "/app")
+	assert.DeepEqual(t, container.EntryPoint, []string{"sh", "-c"})
+	assert.DeepEqual(t, container.Command, []string{"echo hello"})
+}
+
+func TestEmptyEntrypointAndCommand(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  unset:
+    image: hello_world
+  empty:
+    image: hello_world
+    entrypoint: []
+    command: []
+`)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	var marshalled struct {
+		Resources map[string]struct {
+			Properties struct {
+				ContainerDefinitions []map[string]interface{}
+			}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	mainContainer := func(resource string, name string) map[string]interface{} {
+		for _, container := range marshalled.Resources[resource].Properties.ContainerDefinitions {
+			if container["Name"] == name {
+				return container
+			}
+		}
+		t.Fatalf("%s has no %s container", resource, name)
+		return nil
+	}
+	container := mainContainer("UnsetTaskDefinition", "unset")
+	_, ok := container["EntryPoint"]
+	assert.Check(t, !ok)
+	_, ok = container["Command"]
+	assert.Check(t, !ok)
+
+	container = mainContainer("EmptyTaskDefinition", "empty")
+	assert.DeepEqual(t, container["EntryPoint"], []interface{}{})
+	assert.DeepEqual(t, container["Command"], []interface{}{})
+}
+
+func TestHostnameIgnored(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    hostname: myhost
+`)
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.Equal(t, container.Hostname, "")
+	assert.Equal(t, len(backend.warnings), 1)
+	assert.Equal(t, backend.warnings[0].Service, "test")
+}
+
+func TestOneZoneVolume(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  db:
+    image: mysql
+    volumes:
+      - data:/var/lib/mysql
+volumes:
+  data:
+    driver_opts:
+      availability_zone: us-east-1b
+`)
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{
+		subnets: []string{"subnet1", "subnet2", "subnet3"},
+		zones: map[string]string{
+			"subnet1": "us-east-1a",
+			"subnet2": "us-east-1b",
+			"subnet3": "us-east-1b",
+		},
+	})
+	assert.NilError(t, err)
+
+	fs := template.Resources["DataFilesystem"].(*oneZoneFileSystem)
+	assert.Equal(t, fs.AvailabilityZoneName, "us-east-1b")
+	raw, err := json.Marshal(fs)
+	assert.NilError(t, err)
+	assert.Check(t, strings.Contains(string(raw), `"AvailabilityZoneName":"us-east-1b"`))
+
+	mountTarget := template.Resources["DataNFSMountTargetOnSubnet2"].(*efs.MountTarget)
+	assert.Equal(t, mountTarget.FileSystemId, cloudformation.Ref("DataFilesystem"))
+	assert.Check(t, template.Resources["DataNFSMountTargetOnSubnet3"] == nil)
+
+	service := template.Resources["DbService"].(*ecs.Service)
+	assert.DeepEqual(t, service.NetworkConfiguration.AwsvpcConfiguration.Subnets, []string{"subnet2", "subnet3"})
+	assert.DeepEqual(t, service.AWSCloudFormationDependsOn, []string{"DataNFSMountTargetOnSubnet2"})
+
+	def := template.Resources["DbTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, def.Volumes[0].EFSVolumeConfiguration.FilesystemId, cloudformation.Ref("DataFilesystem"))
+	assert.Equal(t, backend.warnings[0].Code, warningSingleAvailabilityZone)
+}
+
+func TestOneZoneVolumeErrors(t *testing.T) {
+	resources := awsResources{
+		subnets: []string{"subnet1", "subnet2"},
+		zones: map[string]string{
+			"subnet1": "us-east-1a",
+			"subnet2": "us-east-1b",
+		},
+	}
+	project := loadConfig(t, `
+services:
+  db:
+    image: mysql
+    volumes:
+      - data:/var/lib/mysql
+volumes:
+  data:
+    driver_opts:
+      availability_zone: us-east-1c
+`)
+	_, err := (&ecsAPIService{}).convert(project, resources)
+	assert.ErrorContains(t, err, "volume data requires availability zone us-east-1c")
+
+	project = loadConfig(t, `
+services:
+  db:
+    image: mysql
+    deploy:
+      replicas: 2
+    volumes:
+      - data:/var/lib/mysql
+volumes:
+  data:
+    driver_opts:
+      availability_zone: us-east-1a
+`)
+	_, err = (&ecsAPIService{}).convert(project, resources)
+	assert.ErrorContains(t, err, "service db requires 2 replicas to be spread across availability zones")
+}
+
+func TestServiceDependsOn(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  web:
+    image: nginx
+    depends_on:
+      db:
+        condition: service_healthy
+      cache:
+        condition: service_started
+  db:
+    image: mysql
+    healthcheck:
+      test: ["CMD", "mysqladmin", "ping"]
+  cache:
+    image: redis
+`)
+	service := template.Resources["WebService"].(*ecs.Service)
+	assert.DeepEqual(t, service.AWSCloudFormationDependsOn, []string{"CacheService", "DbService"})
+}
+
+func TestServiceDependsOnHealthyRequiresHealthCheck(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  web:
+    image: nginx
+    depends_on:
+      db:
+        condition: service_healthy
+  db:
+    image: mysql
+    healthcheck:
+      disable: true
+`)
+	_, err := (&ecsAPIService{}).convert(project, awsResources{})
+	assert.Error(t, err, "service web depends on db being healthy, but db has neither a healthcheck nor ports exposed by load balancer")
+}
+
+func TestLabelsAsDockerLabelsAndTags(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  api:
+    image: nginx
+    labels:
+      com.datadoghq.ad.check_names: '["nginx"]'
+      owner: team#payments
+      aws:cloudformation: reserved
+      `+strings.Repeat("k", 129)+`: too long
+`)
+	applyLabelsAsTags(project, true)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+
+	def := template.Resources["ApiTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, getMainContainer(def, t).DockerLabels["com.datadoghq.ad.check_names"], `["nginx"]`)
+	assert.Equal(t, len(getMainContainer(def, t).DockerLabels), 4)
+
+	ignore := cmpopts.IgnoreUnexported(tags.Tag{})
+	assert.DeepEqual(t, template.Resources["ApiService"].(*ecs.Service).Tags, []tags.Tag{
+		{Key: "com.docker.compose.project", Value: "Test"},
+		{Key: "com.docker.compose.service", Value: "api"},
+		{Key: "com.datadoghq.ad.check_names", Value: "__nginx__"},
+		{Key: "owner", Value: "team_payments"},
+	}, ignore)
+
+	var skipped []string
+	for _, w := range backend.warnings {
+		if w.Code == warningInvalidTag {
+			skipped = append(skipped, w.Message)
+		}
+	}
+	assert.DeepEqual(t, skipped, []string{
+		"label aws:cloudformation isn't set as a tag, as it exceeds tags limits or uses a reserved key",
+		"label " + strings.Repeat("k", 129) + " isn't set as a tag, as it exceeds tags limits or uses a reserved key",
+	})
+}
+
 func TestResourcesHaveProjectTagSet(t *testing.T) {
 	template := convertYaml(t, `
 services:
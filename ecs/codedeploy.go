@@ -0,0 +1,378 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/codedeploy"
+	"github.com/awslabs/goformation/v4/cloudformation/elasticloadbalancingv2"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/compose-spec/compose-go/types"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/docker/compose-cli/progress"
+)
+
+// testListenerPortOffset is added to the published port to select the test listener port, unless x-aws-test_listener_port is set
+const testListenerPortOffset = 10000
+
+// deploymentController returns the deployment controller set by x-aws-deployment_controller, ECS rolling updates by default
+func deploymentController(service types.ServiceConfig) (string, error) {
+	x, ok := service.Extensions[extensionDeploymentController]
+	if !ok {
+		return ecsapi.DeploymentControllerTypeEcs, nil
+	}
+	controller := fmt.Sprint(x)
+	switch controller {
+	case ecsapi.DeploymentControllerTypeEcs, ecsapi.DeploymentControllerTypeCodeDeploy:
+		return controller, nil
+	default:
+		return "", fmt.Errorf("%s must be %s or %s, got %q", extensionDeploymentController,
+			ecsapi.DeploymentControllerTypeEcs, ecsapi.DeploymentControllerTypeCodeDeploy, controller)
+	}
+}
+
+// checkCodeDeploy makes sure a service deployed by CodeDeploy doesn't rely on ECS rolling updates, and exposes the single
+// port CodeDeploy shifts traffic for
+func checkCodeDeploy(service types.ServiceConfig, members []types.ServiceConfig) error {
+	for _, extension := range []string{extensionMinPercent, extensionMaxPercent, extensionFallbackCapacity} {
+		if _, ok := service.Extensions[extension]; ok {
+			return fmt.Errorf("%s can't be set with %s %s", extension, extensionDeploymentController, ecsapi.DeploymentControllerTypeCodeDeploy)
+		}
+	}
+	if service.Deploy != nil && service.Deploy.UpdateConfig != nil {
+		return fmt.Errorf("deploy.update_config can't be set with %s %s", extensionDeploymentController, ecsapi.DeploymentControllerTypeCodeDeploy)
+	}
+	ports := 0
+	for _, member := range members {
+		ports += len(member.Ports)
+	}
+	if ports != 1 {
+		return fmt.Errorf("%s %s requires service to expose exactly one port, got %d", extensionDeploymentController, ecsapi.DeploymentControllerTypeCodeDeploy, ports)
+	}
+	return nil
+}
+
+func testListenerPort(service types.ServiceConfig, port types.ServicePortConfig) (int, error) {
+	x, ok := service.Extensions[extensionTestListenerPort]
+	if !ok {
+		return int(port.Published) + testListenerPortOffset, nil
+	}
+	testPort, err := strconv.Atoi(fmt.Sprint(x))
+	if err != nil || testPort < 1 || testPort > 65535 || testPort == int(port.Published) {
+		return 0, fmt.Errorf("%s must be a port number other than %d, got %v", extensionTestListenerPort, port.Published, x)
+	}
+	return testPort, nil
+}
+
+// blueGreenTraffic are the target groups CodeDeploy shifts traffic between, and the listeners routing production and
+// test traffic to them
+type blueGreenTraffic struct {
+	blue         string
+	green        string
+	listener     string
+	testListener string
+}
+
+// createBlueGreenTraffic adds the replacement target group and the test listener CodeDeploy requires, next to the ones
+// created for port
+func (b *ecsAPIService) createBlueGreenTraffic(service types.ServiceConfig, port types.ServicePortConfig, template *cloudformation.Template,
+	targetGroupName string, listenerName string) (blueGreenTraffic, error) {
+	testPort, err := testListenerPort(service, port)
+	if err != nil {
+		return blueGreenTraffic{}, err
+	}
+
+	green := strings.TrimSuffix(targetGroupName, "TargetGroup") + "GreenTargetGroup"
+	targetGroup := *template.Resources[targetGroupName].(*elasticloadbalancingv2.TargetGroup)
+	template.Resources[green] = &targetGroup
+
+	testListenerName := strings.TrimSuffix(listenerName, "Listener") + "TestListener"
+	listener := *template.Resources[listenerName].(*elasticloadbalancingv2.Listener)
+	listener.Port = testPort
+	template.Resources[testListenerName] = &listener
+
+	return blueGreenTraffic{
+		blue:         targetGroupName,
+		green:        green,
+		listener:     listenerName,
+		testListener: testListenerName,
+	}, nil
+}
+
+// createDeploymentGroup creates the CodeDeploy application and deployment group running blue/green deployments of service
+func (b *ecsAPIService) createDeploymentGroup(project *types.Project, service types.ServiceConfig, template *cloudformation.Template,
+	resources awsResources, traffic blueGreenTraffic) {
+	name := normalizeResourceName(service.Name)
+	application := fmt.Sprintf("%sCodeDeployApplication", name)
+	template.Resources[application] = &codedeploy.Application{
+		ComputePlatform: "ECS",
+	}
+
+	role := fmt.Sprintf("%sCodeDeployRole", name)
+	template.Resources[role] = &iam.Role{
+		AssumeRolePolicyDocument: codeDeployAssumeRolePolicyDocument,
+		ManagedPolicyArns:        []string{b.partitionArn(codeDeployRoleForECS)},
+		Tags:                     serviceTags(project, service),
+	}
+
+	template.Resources[fmt.Sprintf("%sDeploymentGroup", name)] = &ecsDeploymentGroup{
+		DeploymentGroup: codedeploy.DeploymentGroup{
+			ApplicationName: cloudformation.Ref(application),
+			AutoRollbackConfiguration: &codedeploy.DeploymentGroup_AutoRollbackConfiguration{
+				Enabled: true,
+				Events:  []string{"DEPLOYMENT_FAILURE"},
+			},
+			DeploymentConfigName: "CodeDeployDefault.ECSAllAtOnce",
+			DeploymentStyle: &codedeploy.DeploymentGroup_DeploymentStyle{
+				DeploymentOption: "WITH_TRAFFIC_CONTROL",
+				DeploymentType:   "BLUE_GREEN",
+			},
+			ServiceRoleArn: cloudformation.GetAtt(role, "Arn"),
+		},
+		BlueGreenDeploymentConfiguration: blueGreenDeploymentConfiguration{
+			DeploymentReadyOption: deploymentReadyOption{
+				ActionOnTimeout: "CONTINUE_DEPLOYMENT",
+			},
+			TerminateBlueInstancesOnDeploymentSuccess: blueInstanceTerminationOption{
+				Action:                       "TERMINATE",
+				TerminationWaitTimeInMinutes: 5,
+			},
+		},
+		ECSServices: []ecsService{
+			{
+				ClusterName: resources.cluster,
+				ServiceName: cloudformation.GetAtt(serviceResourceName(service.Name), "Name"),
+			},
+		},
+		LoadBalancerInfo: loadBalancerInfo{
+			TargetGroupPairInfoList: []targetGroupPairInfo{
+				{
+					ProdTrafficRoute: trafficRoute{ListenerArns: []string{cloudformation.Ref(traffic.listener)}},
+					TestTrafficRoute: trafficRoute{ListenerArns: []string{cloudformation.Ref(traffic.testListener)}},
+					TargetGroups: []targetGroupInfo{
+						{Name: cloudformation.GetAtt(traffic.blue, "TargetGroupName")},
+						{Name: cloudformation.GetAtt(traffic.green, "TargetGroupName")},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ecsDeploymentGroup is a DeploymentGroup deploying an ECS service, not supported by goformation
+type ecsDeploymentGroup struct {
+	codedeploy.DeploymentGroup
+	BlueGreenDeploymentConfiguration blueGreenDeploymentConfiguration
+	ECSServices                      []ecsService
+	LoadBalancerInfo                 loadBalancerInfo
+}
+
+type blueGreenDeploymentConfiguration struct {
+	DeploymentReadyOption                     deploymentReadyOption
+	TerminateBlueInstancesOnDeploymentSuccess blueInstanceTerminationOption
+}
+
+type deploymentReadyOption struct {
+	ActionOnTimeout string
+}
+
+type blueInstanceTerminationOption struct {
+	Action                       string
+	TerminationWaitTimeInMinutes int
+}
+
+type ecsService struct {
+	ClusterName string
+	ServiceName string
+}
+
+type loadBalancerInfo struct {
+	TargetGroupPairInfoList []targetGroupPairInfo
+}
+
+type targetGroupPairInfo struct {
+	ProdTrafficRoute trafficRoute
+	TestTrafficRoute trafficRoute
+	TargetGroups     []targetGroupInfo
+}
+
+type trafficRoute struct {
+	ListenerArns []string
+}
+
+type targetGroupInfo struct {
+	Name string
+}
+
+func (r ecsDeploymentGroup) MarshalJSON() ([]byte, error) {
+	return marshalResource(r.DeploymentGroup, func(properties map[string]interface{}) {
+		properties["BlueGreenDeploymentConfiguration"] = r.BlueGreenDeploymentConfiguration
+		properties["ECSServices"] = r.ECSServices
+		properties["LoadBalancerInfo"] = r.LoadBalancerInfo
+	})
+}
+
+// codeDeployedTaskDefinitions returns the task definitions deployed services with a CODE_DEPLOY controller run, by
+// service. CloudFormation refuses to update their task definition, so the template keeps it, and up then deploys the
+// new one with CodeDeploy
+func (b *ecsAPIService) codeDeployedTaskDefinitions(ctx context.Context, project *types.Project) (map[string]string, error) {
+	blueGreen := map[string]bool{}
+	for _, service := range project.Services {
+		if controller, _ := deploymentController(service); controller == ecsapi.DeploymentControllerTypeCodeDeploy {
+			blueGreen[service.Name] = true
+		}
+	}
+	if len(blueGreen) == 0 {
+		return nil, nil
+	}
+	exists, err := b.SDK.StackExists(ctx, project.Name)
+	if err != nil || !exists {
+		return nil, err
+	}
+	_, services, err := b.projectServices(ctx, project.Name)
+	if err != nil {
+		return nil, err
+	}
+	deployed := map[string]string{}
+	for name, svc := range services {
+		if !blueGreen[name] || svc.DeploymentController == nil {
+			continue
+		}
+		if aws.StringValue(svc.DeploymentController.Type) == ecsapi.DeploymentControllerTypeCodeDeploy {
+			deployed[name] = aws.StringValue(svc.TaskDefinition)
+		}
+	}
+	return deployed, nil
+}
+
+// stackPhysicalIDs returns the physical IDs of stack's resources, and of its nested stacks ones, by logical ID
+func (b *ecsAPIService) stackPhysicalIDs(ctx context.Context, stack string) (map[string]string, error) {
+	resources, err := b.SDK.ListStackResources(ctx, stack)
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]string{}
+	for _, r := range resources {
+		ids[r.LogicalID] = r.ARN
+		if r.Type == awsTypeStack && r.ARN != "" {
+			nested, err := b.stackPhysicalIDs(ctx, r.ARN)
+			if err != nil {
+				return nil, err
+			}
+			for id, arn := range nested {
+				ids[id] = arn
+			}
+		}
+	}
+	return ids, nil
+}
+
+// deployCodeDeployedServices starts a CodeDeploy deployment of the task definition the stack now has for services which
+// task definition was kept by conversion, and waits for them to complete
+func (b *ecsAPIService) deployCodeDeployedServices(ctx context.Context, project *types.Project) error {
+	if len(b.codeDeployed) == 0 {
+		return nil
+	}
+	ids, err := b.stackPhysicalIDs(ctx, project.Name)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name := range b.codeDeployed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := progress.ContextWriter(ctx)
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, name := range names {
+		resourceName := normalizeResourceName(name)
+		taskDefinition := ids[fmt.Sprintf("%sTaskDefinition", resourceName)]
+		if taskDefinition == "" || taskDefinition == b.codeDeployed[name] {
+			continue
+		}
+		service, err := project.GetService(name)
+		if err != nil {
+			return err
+		}
+		appSpec, err := codeDeployAppSpec(project, service, taskDefinition)
+		if err != nil {
+			return err
+		}
+		name := name
+		application := ids[fmt.Sprintf("%sCodeDeployApplication", resourceName)]
+		group := ids[fmt.Sprintf("%sDeploymentGroup", resourceName)]
+		eg.Go(func() error {
+			w.Event(progress.Event{
+				ID:         name,
+				Status:     progress.Working,
+				StatusText: "Deploying with CodeDeploy",
+			})
+			id, err := b.SDK.CreateECSDeployment(ctx, application, group, appSpec)
+			if err != nil {
+				return fmt.Errorf("%s: can't start CodeDeploy deployment: %w", name, err)
+			}
+			if err := b.SDK.WaitDeploymentSuccessful(ctx, id); err != nil {
+				return fmt.Errorf("%s: CodeDeploy deployment %s didn't succeed: %w", name, id, err)
+			}
+			w.Event(progress.Event{
+				ID:         name,
+				Status:     progress.Done,
+				StatusText: "Deployed with CodeDeploy",
+			})
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// codeDeployAppSpec returns the AppSpec CodeDeploy replaces service's tasks set with tasks of taskDefinition from
+func codeDeployAppSpec(project *types.Project, service types.ServiceConfig, taskDefinition string) (string, error) {
+	for _, member := range taskServices(project, service) {
+		for _, port := range member.Ports {
+			appSpec, err := json.Marshal(map[string]interface{}{
+				"version": "0.0",
+				"Resources": []interface{}{
+					map[string]interface{}{
+						"TargetService": map[string]interface{}{
+							"Type": "AWS::ECS::Service",
+							"Properties": map[string]interface{}{
+								"TaskDefinition": taskDefinition,
+								"LoadBalancerInfo": map[string]interface{}{
+									"ContainerName": member.Name,
+									"ContainerPort": port.Target,
+								},
+							},
+						},
+					},
+				},
+			})
+			return string(appSpec), err
+		}
+	}
+	return "", fmt.Errorf("%s %s requires service to expose a port", extensionDeploymentController, ecsapi.DeploymentControllerTypeCodeDeploy)
+}
@@ -0,0 +1,262 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	cf "github.com/aws/aws-sdk-go/service/cloudformation"
+	cdapi "github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/codedeploy/codedeployiface"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/codedeploy"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/elasticloadbalancingv2"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+)
+
+func TestCodeDeployController(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  api:
+    image: app
+    ports:
+      - 80:80
+    x-aws-deployment_controller: CODE_DEPLOY
+`)
+	service := template.Resources["ApiService"].(*ecs.Service)
+	assert.Equal(t, service.DeploymentController.Type, "CODE_DEPLOY")
+	assert.Check(t, service.DeploymentConfiguration == nil)
+	assert.Equal(t, len(service.LoadBalancers), 1)
+	assert.Equal(t, service.LoadBalancers[0].TargetGroupArn, cloudformation.Ref("ApiTCP80TargetGroup"))
+	assert.DeepEqual(t, service.AWSCloudFormationDependsOn, []string{"ApiTCP80Listener", "ApiTCP80TestListener"})
+
+	green := template.Resources["ApiTCP80GreenTargetGroup"].(*elasticloadbalancingv2.TargetGroup)
+	assert.Equal(t, green.Port, 80)
+	testListener := template.Resources["ApiTCP80TestListener"].(*elasticloadbalancingv2.Listener)
+	assert.Equal(t, testListener.Port, 10080)
+	assert.Equal(t, testListener.DefaultActions[0].ForwardConfig.TargetGroups[0].TargetGroupArn, cloudformation.Ref("ApiTCP80TargetGroup"))
+
+	raw, err := json.Marshal(template.Resources["ApiDeploymentGroup"])
+	assert.NilError(t, err)
+	var group struct {
+		Type       string
+		Properties struct {
+			ApplicationName string
+			DeploymentStyle struct {
+				DeploymentType string
+			}
+			ECSServices []struct {
+				ServiceName interface{}
+			}
+			LoadBalancerInfo struct {
+				TargetGroupPairInfoList []struct {
+					ProdTrafficRoute struct{ ListenerArns []interface{} }
+					TestTrafficRoute struct{ ListenerArns []interface{} }
+					TargetGroups     []struct{ Name interface{} }
+				}
+			}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &group))
+	assert.Equal(t, group.Type, "AWS::CodeDeploy::DeploymentGroup")
+	assert.Equal(t, group.Properties.DeploymentStyle.DeploymentType, "BLUE_GREEN")
+	assert.Equal(t, len(group.Properties.ECSServices), 1)
+	pairs := group.Properties.LoadBalancerInfo.TargetGroupPairInfoList
+	assert.Equal(t, len(pairs), 1)
+	assert.Equal(t, len(pairs[0].TargetGroups), 2)
+	assert.Equal(t, len(pairs[0].ProdTrafficRoute.ListenerArns), 1)
+	assert.Equal(t, len(pairs[0].TestTrafficRoute.ListenerArns), 1)
+
+	assert.Check(t, template.Resources["ApiCodeDeployApplication"] != nil)
+	assert.Check(t, template.Resources["ApiCodeDeployRole"] != nil)
+}
+
+func TestCodeDeployMarshalledReferences(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  api:
+    image: app
+    ports:
+      - 80:80
+    x-aws-deployment_controller: CODE_DEPLOY
+    x-aws-test_listener_port: 8080
+`)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	var marshalled struct {
+		Resources map[string]struct {
+			Properties map[string]interface{}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	assert.Equal(t, marshalled.Resources["ApiTCP80TestListener"].Properties["Port"], float64(8080))
+	info := marshalled.Resources["ApiDeploymentGroup"].Properties["LoadBalancerInfo"].(map[string]interface{})
+	pair := info["TargetGroupPairInfoList"].([]interface{})[0].(map[string]interface{})
+	assert.DeepEqual(t, pair["TargetGroups"], []interface{}{
+		map[string]interface{}{"Name": map[string]interface{}{"Fn::GetAtt": []interface{}{"ApiTCP80TargetGroup", "TargetGroupName"}}},
+		map[string]interface{}{"Name": map[string]interface{}{"Fn::GetAtt": []interface{}{"ApiTCP80GreenTargetGroup", "TargetGroupName"}}},
+	})
+	assert.DeepEqual(t, pair["TestTrafficRoute"], map[string]interface{}{
+		"ListenerArns": []interface{}{map[string]interface{}{"Ref": "ApiTCP80TestListener"}},
+	})
+}
+
+func TestCodeDeployErrors(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  api:
+    image: app
+    ports:
+      - 80:80
+    x-aws-deployment_controller: EXTERNAL
+`: `x-aws-deployment_controller must be ECS or CODE_DEPLOY, got "EXTERNAL"`,
+		`
+services:
+  api:
+    image: app
+    ports:
+      - 80:80
+    x-aws-deployment_controller: CODE_DEPLOY
+    x-aws-min_percent: 50
+`: "x-aws-min_percent can't be set with x-aws-deployment_controller CODE_DEPLOY",
+		`
+services:
+  api:
+    image: app
+    ports:
+      - 80:80
+    deploy:
+      replicas: 2
+      update_config:
+        parallelism: 1
+    x-aws-deployment_controller: CODE_DEPLOY
+`: "deploy.update_config can't be set with x-aws-deployment_controller CODE_DEPLOY",
+		`
+services:
+  api:
+    image: app
+    x-aws-deployment_controller: CODE_DEPLOY
+`: "x-aws-deployment_controller CODE_DEPLOY requires service to expose exactly one port, got 0",
+		`
+services:
+  api:
+    image: app
+    ports:
+      - 80:80
+    x-aws-deployment_controller: CODE_DEPLOY
+    x-aws-test_listener_port: 80
+`: "x-aws-test_listener_port must be a port number other than 80, got 80",
+	} {
+		_, err := (&ecsAPIService{}).convert(loadConfig(t, yaml), awsResources{})
+		assert.Error(t, err, expected)
+	}
+}
+
+func TestCodeDeployMarshalledDependsOn(t *testing.T) {
+	raw, err := json.Marshal(ecsDeploymentGroup{
+		DeploymentGroup: codedeploy.DeploymentGroup{
+			AWSCloudFormationDependsOn: []string{"ApiService"},
+		},
+	})
+	assert.NilError(t, err)
+	var marshalled struct {
+		DependsOn  []string
+		Properties map[string]interface{}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	assert.DeepEqual(t, marshalled.DependsOn, []string{"ApiService"})
+	assert.Check(t, marshalled.Properties["LoadBalancerInfo"] != nil)
+}
+
+func TestCodeDeployKeepsDeployedTaskDefinition(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  api:
+    image: app
+    ports:
+      - 80:80
+    x-aws-deployment_controller: CODE_DEPLOY
+`)
+	template, err := (&ecsAPIService{}).convert(project, awsResources{
+		codeDeployed: map[string]string{"api": "arn:aws:ecs:eu-west-3:012345678912:task-definition/api:1"},
+	})
+	assert.NilError(t, err)
+	service := template.Resources["ApiService"].(*ecs.Service)
+	assert.Equal(t, service.TaskDefinition, "arn:aws:ecs:eu-west-3:012345678912:task-definition/api:1")
+	assert.Check(t, template.Resources["ApiTaskDefinition"] != nil)
+}
+
+type mockCodeDeploy struct {
+	codedeployiface.CodeDeployAPI
+	mock.Mock
+}
+
+func (m *mockCodeDeploy) CreateDeploymentWithContext(_ aws.Context, in *cdapi.CreateDeploymentInput, _ ...request.Option) (*cdapi.CreateDeploymentOutput, error) {
+	args := m.Called(aws.StringValue(in.ApplicationName), aws.StringValue(in.DeploymentGroupName), aws.StringValue(in.Revision.AppSpecContent.Content))
+	return args.Get(0).(*cdapi.CreateDeploymentOutput), args.Error(1)
+}
+
+func (m *mockCodeDeploy) WaitUntilDeploymentSuccessfulWithContext(_ aws.Context, in *cdapi.GetDeploymentInput, _ ...request.WaiterOption) error {
+	args := m.Called(aws.StringValue(in.DeploymentId))
+	return args.Error(0)
+}
+
+func TestDeployCodeDeployedServices(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  api:
+    image: app
+    ports:
+      - 80:8080
+    x-aws-deployment_controller: CODE_DEPLOY
+  web:
+    image: app
+    ports:
+      - 443:443
+    x-aws-deployment_controller: CODE_DEPLOY
+`)
+	resources := &mockCloudFormation{}
+	resources.On("ListStackResourcesWithContext", "Test").Return(&cf.ListStackResourcesOutput{
+		StackResourceSummaries: []*cf.StackResourceSummary{
+			{LogicalResourceId: aws.String("ApiTaskDefinition"), ResourceType: aws.String("AWS::ECS::TaskDefinition"), PhysicalResourceId: aws.String("api:2")},
+			{LogicalResourceId: aws.String("ApiCodeDeployApplication"), ResourceType: aws.String("AWS::CodeDeploy::Application"), PhysicalResourceId: aws.String("api-app")},
+			{LogicalResourceId: aws.String("ApiDeploymentGroup"), ResourceType: aws.String("AWS::CodeDeploy::DeploymentGroup"), PhysicalResourceId: aws.String("api-group")},
+			{LogicalResourceId: aws.String("WebTaskDefinition"), ResourceType: aws.String("AWS::ECS::TaskDefinition"), PhysicalResourceId: aws.String("web:1")},
+		},
+	}, nil)
+	deployments := &mockCodeDeploy{}
+	appSpec := `{"Resources":[{"TargetService":{"Properties":{"LoadBalancerInfo":{"ContainerName":"api","ContainerPort":8080},"TaskDefinition":"api:2"},"Type":"AWS::ECS::Service"}}],"version":"0.0"}`
+	deployments.On("CreateDeploymentWithContext", "api-app", "api-group", appSpec).Return(&cdapi.CreateDeploymentOutput{DeploymentId: aws.String("d-1")}, nil)
+	deployments.On("WaitUntilDeploymentSuccessfulWithContext", "d-1").Return(nil)
+
+	b := &ecsAPIService{
+		SDK: sdk{CF: resources, CD: deployments},
+		// web's task definition didn't change, so it doesn't get deployed
+		codeDeployed: map[string]string{"api": "api:1", "web": "web:1"},
+	}
+	err := b.deployCodeDeployedServices(context.Background(), project)
+	assert.NilError(t, err)
+	deployments.AssertExpectations(t)
+	deployments.AssertNumberOfCalls(t, "CreateDeploymentWithContext", 1)
+}
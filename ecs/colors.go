@@ -18,6 +18,7 @@ package ecs
 
 import (
 	"fmt"
+	"hash/fnv"
 	"strconv"
 )
 
@@ -49,7 +50,7 @@ func makeColorFunc(code string) colorFunc {
 	}
 }
 
-var loop = make(chan colorFunc)
+var rainbow []colorFunc
 
 func init() {
 	colors := map[string]colorFunc{}
@@ -58,24 +59,23 @@ func init() {
 		colors["intense_"+name] = makeColorFunc(strconv.Itoa(30+i) + ";1")
 	}
 
-	go func() {
-		i := 0
-		rainbow := []colorFunc{
-			colors["cyan"],
-			colors["yellow"],
-			colors["green"],
-			colors["magenta"],
-			colors["blue"],
-			colors["intense_cyan"],
-			colors["intense_yellow"],
-			colors["intense_green"],
-			colors["intense_magenta"],
-			colors["intense_blue"],
-		}
+	rainbow = []colorFunc{
+		colors["cyan"],
+		colors["yellow"],
+		colors["green"],
+		colors["magenta"],
+		colors["blue"],
+		colors["intense_cyan"],
+		colors["intense_yellow"],
+		colors["intense_green"],
+		colors["intense_magenta"],
+		colors["intense_blue"],
+	}
+}
 
-		for {
-			loop <- rainbow[i]
-			i = (i + 1) % len(rainbow)
-		}
-	}()
+// colorOf selects the color of a name, so a service keeps the same color across commands
+func colorOf(name string) colorFunc {
+	h := fnv.New32a()
+	h.Write([]byte(name)) // nolint:errcheck
+	return rainbow[h.Sum32()%uint32(len(rainbow))]
 }
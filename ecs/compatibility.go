@@ -18,42 +18,149 @@ package ecs
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/compose-spec/compose-go/compatibility"
-	"github.com/compose-spec/compose-go/errdefs"
 	"github.com/compose-spec/compose-go/types"
-	"github.com/sirupsen/logrus"
+	"github.com/hashicorp/go-multierror"
 )
 
-func (b *ecsAPIService) checkCompatibility(project *types.Project) error {
-	var checker compatibility.Checker = &fargateCompatibilityChecker{
-		compatibility.AllowList{
+// checkCompatibility reports all attributes of project ECS can't deploy at once. Incompatible attributes
+// listed by x-aws-ignore, or all of them when force is set, are downgraded to warnings and dropped from project
+func (b *ecsAPIService) checkCompatibility(project *types.Project, force bool) error {
+	ignored, err := ignoredAttributes(project)
+	if err != nil {
+		return err
+	}
+	checker := &fargateCompatibilityChecker{
+		AllowList: compatibility.AllowList{
 			Supported: compatibleComposeAttributes,
 		},
+		ignore: func(attribute string) bool {
+			if force {
+				return true
+			}
+			for _, a := range ignored {
+				if a == attribute {
+					return true
+				}
+			}
+			return false
+		},
 	}
 	compatibility.Check(project, checker)
+	for i := range project.Services {
+		if err := checkLaunchType(project.Services[i]); err != nil {
+			return serviceError(project.Services[i].Name, err)
+		}
+		checker.checkExternal(project, &project.Services[i])
+	}
 	for _, err := range checker.Errors() {
-		if errdefs.IsIncompatibleError(err) {
-			return err
+		b.warn(warningUnsupportedAttribute, severityWarning, "", "%s", err.Error())
+	}
+	for _, f := range checker.ignored {
+		b.warn(warningIgnoredAttribute, severityWarning, f.Service, "%s ignored: %s", f.Attribute, f.Reason)
+	}
+	if len(checker.findings) > 0 {
+		sort.SliceStable(checker.findings, func(i, j int) bool {
+			return checker.findings[i].Service < checker.findings[j].Service
+		})
+		var errs *multierror.Error
+		for _, f := range checker.findings {
+			errs = multierror.Append(errs, f)
 		}
-		logrus.Warn(err.Error())
+		errs.ErrorFormat = formatFindings
+		return errs
 	}
-	if !compatibility.IsCompatible(checker) {
-		return fmt.Errorf("compose file is incompatible with Amazon ECS")
+	for _, service := range project.Services {
+		if attributes := unsupportedByFargate(service); len(attributes) > 0 && !externalTask(project, service) {
+			b.warn(warningEC2LaunchType, severityInfo, service.Name, "deployed with EC2 launch type as %s is not supported by Fargate", strings.Join(attributes, ", "))
+		}
 	}
 	return nil
 }
 
+// ignoredAttributes parses the x-aws-ignore list of incompatible attributes to drop instead of failing
+func ignoredAttributes(project *types.Project) ([]string, error) {
+	x, ok := project.Extensions[extensionIgnore]
+	if !ok {
+		return nil, nil
+	}
+	items, ok := x.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of attributes", extensionIgnore)
+	}
+	var attributes []string
+	for _, item := range items {
+		attribute, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of attributes", extensionIgnore)
+		}
+		attributes = append(attributes, attribute)
+	}
+	return attributes, nil
+}
+
+// compatibilityFinding is a service attribute ECS can't deploy
+type compatibilityFinding struct {
+	Service   string
+	Attribute string
+	Reason    string
+}
+
+func (f compatibilityFinding) Error() string {
+	return fmt.Sprintf("service %s: %s: %s", f.Service, f.Attribute, f.Reason)
+}
+
+func formatFindings(errs []error) string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = "  " + err.Error()
+	}
+	return fmt.Sprintf("compose file is incompatible with Amazon ECS, use --force or %s to ignore those attributes:\n%s",
+		extensionIgnore, strings.Join(messages, "\n"))
+}
+
+// fargatePlatformVersion is used for services deployed with FARGATE launch type, as LATEST is set to 1.3.0 which doesn't allow efs volumes
+const fargatePlatformVersion = "1.4.0"
+
 type fargateCompatibilityChecker struct {
 	compatibility.AllowList
+	// ignore tells if an incompatible attribute gets dropped rather than failing the check
+	ignore   func(attribute string) bool
+	findings []compatibilityFinding
+	ignored  []compatibilityFinding
+}
+
+// incompatible records a finding for service attribute, and returns true when the attribute is ignored and must be dropped
+func (c *fargateCompatibilityChecker) incompatible(service *types.ServiceConfig, attribute string, reason string, args ...interface{}) bool {
+	f := compatibilityFinding{
+		Service:   service.Name,
+		Attribute: attribute,
+		Reason:    fmt.Sprintf(reason, args...),
+	}
+	if c.ignore != nil && c.ignore(attribute) {
+		c.ignored = append(c.ignored, f)
+		return true
+	}
+	c.findings = append(c.findings, f)
+	return false
 }
 
 var compatibleComposeAttributes = []string{
 	"services.command",
+	"services.configs",
+	"services.configs.source",
+	"services.configs.target",
 	"services.container_name",
 	"services.cap_drop",
 	"services.depends_on",
 	"services.deploy",
+	"services.deploy.mode",
+	"services.deploy.placement",
+	"services.deploy.placement.constraints",
 	"services.deploy.replicas",
 	"services.deploy.resources.limits",
 	"services.deploy.resources.limits.cpus",
@@ -63,19 +170,26 @@ var compatibleComposeAttributes = []string{
 	"services.deploy.resources.reservations.memory",
 	"services.deploy.resources.reservations.generic_resources",
 	"services.deploy.resources.reservations.generic_resources.discrete_resource_spec",
+	"services.deploy.restart_policy",
+	"services.deploy.restart_policy.condition",
+	"services.deploy.restart_policy.max_attempts",
 	"services.deploy.update_config",
 	"services.deploy.update_config.parallelism",
+	"services.dns_search",
 	"services.entrypoint",
 	"services.environment",
 	"services.env_file",
 	"services.healthcheck",
+	"services.healthcheck.disable",
 	"services.healthcheck.interval",
 	"services.healthcheck.retries",
 	"services.healthcheck.start_period",
 	"services.healthcheck.test",
 	"services.healthcheck.timeout",
+	"services.hostname",
 	"services.image",
 	"services.init",
+	"services.labels",
 	"services.logging",
 	"services.logging.options",
 	"services.networks",
@@ -83,58 +197,203 @@ var compatibleComposeAttributes = []string{
 	"services.ports.mode",
 	"services.ports.target",
 	"services.ports.protocol",
+	"services.read_only",
+	"services.restart",
 	"services.secrets",
 	"services.secrets.source",
 	"services.secrets.target",
+	"services.shm_size",
+	"services.stdin_open",
+	"services.stop_grace_period",
+	"services.tmpfs",
+	"services.tty",
 	"services.user",
 	"services.volumes",
 	"services.volumes.read_only",
 	"services.volumes.source",
 	"services.volumes.target",
 	"services.working_dir",
+	"configs.external",
+	"configs.name",
+	"configs.file",
 	"secrets.external",
 	"secrets.name",
 	"secrets.file",
+	"networks.ipam.config",
+	"networks.ipam.config.subnet",
 	"volumes",
+	"volumes.driver_opts",
 	"volumes.external",
+	"volumes.name",
 }
 
 func (c *fargateCompatibilityChecker) CheckImage(service *types.ServiceConfig) {
 	if service.Image == "" {
-		c.Incompatible("service %s doesn't define a Docker image to run", service.Name)
+		// there's nothing to deploy without an image, so this one can't be ignored
+		c.findings = append(c.findings, compatibilityFinding{
+			Service:   service.Name,
+			Attribute: "services.image",
+			Reason:    "doesn't define a Docker image to run",
+		})
 	}
 }
 
-func (c *fargateCompatibilityChecker) CheckPortsPublished(p *types.ServicePortConfig) {
-	if p.Published == 0 {
-		p.Published = p.Target
+func (c *fargateCompatibilityChecker) CheckPorts(service *types.ServiceConfig) bool {
+	if !c.AllowList.CheckPorts(service) {
+		return false
 	}
-	if p.Published != p.Target {
-		c.Incompatible("published port can't be set to a distinct value than container port")
+	for i := range service.Ports {
+		p := &service.Ports[i]
+		if p.Published == 0 {
+			p.Published = p.Target
+		}
+		if p.Published != p.Target && !external(*service) {
+			if c.incompatible(service, "services.ports.published", "published port %d can't be set to a distinct value than container port %d", p.Published, p.Target) {
+				p.Published = p.Target
+			}
+		}
 	}
+	return true
+}
+
+func (c *fargateCompatibilityChecker) CheckPortsPublished(p *types.ServicePortConfig) {
+	// published ports are checked by CheckPorts, which knows about the service they belong to
 }
 
 func (c *fargateCompatibilityChecker) CheckCapAdd(service *types.ServiceConfig) {
+	if requireEC2(*service) || external(*service) {
+		// EC2 and EXTERNAL launch types allow any linux capability to be added
+		return
+	}
 	add := []string{}
 	for _, cap := range service.CapAdd {
 		switch cap {
 		case "SYS_PTRACE":
 			add = append(add, cap)
 		default:
-			c.Incompatible("ECS doesn't allow to add capability %s", cap)
+			if !c.incompatible(service, "services.cap_add", "ECS doesn't allow to add capability %s with launch type %s", cap, ecsapi.LaunchTypeFargate) {
+				add = append(add, cap)
+			}
 		}
 	}
 	service.CapAdd = add
 }
 
-func (c *fargateCompatibilityChecker) CheckLoggingDriver(config *types.LoggingConfig) {
-	if config.Driver != "" && config.Driver != "awslogs" {
-		c.Unsupported("services.logging.driver %s is not supported", config.Driver)
+// CheckDNS rejects DNS servers on tasks in awsvpc network mode, which Fargate and EC2 tasks both use
+func (c *fargateCompatibilityChecker) CheckDNS(service *types.ServiceConfig) {
+	if len(service.DNS) == 0 || serviceNetworkMode(*service) != ecsapi.NetworkModeAwsvpc {
+		return
+	}
+	if c.incompatible(service, "services.dns", "ECS doesn't allow to set dns servers with network mode %s", ecsapi.NetworkModeAwsvpc) {
+		service.DNS = nil
 	}
 }
 
-func (c *fargateCompatibilityChecker) CheckVolumeConfigExternal(config *types.VolumeConfig) {
-	if !config.External.External {
-		c.Unsupported("non-external volumes are not supported")
+// CheckNetworkMode accepts the network modes ECS Anywhere instances run tasks with
+func (c *fargateCompatibilityChecker) CheckNetworkMode(service *types.ServiceConfig) {
+	if external(*service) {
+		switch service.NetworkMode {
+		case "", "bridge", "host":
+			return
+		}
+		if c.incompatible(service, "services.network_mode", "ECS doesn't allow network mode %s with launch type %s", service.NetworkMode, launchTypeExternal) {
+			service.NetworkMode = ""
+		}
+		return
+	}
+	c.AllowList.CheckNetworkMode(service)
+}
+
+// CheckExtraHosts rejects extra hosts on tasks in awsvpc network mode, which Fargate and EC2 tasks both use
+func (c *fargateCompatibilityChecker) CheckExtraHosts(service *types.ServiceConfig) {
+	if len(service.ExtraHosts) == 0 || serviceNetworkMode(*service) != ecsapi.NetworkModeAwsvpc {
+		return
+	}
+	if c.incompatible(service, "services.extra_hosts", "ECS doesn't allow to set extra_hosts with network mode %s", ecsapi.NetworkModeAwsvpc) {
+		service.ExtraHosts = nil
+	}
+}
+
+// CheckRestart rejects restart policies ECS services can't express, as they replace stopped tasks without limit
+func (c *fargateCompatibilityChecker) CheckRestart(service *types.ServiceConfig) {
+	supported, reason := restartPolicySupported, restartPolicyReason
+	if isScheduled(*service) {
+		supported, reason = scheduledRestartPolicySupported, scheduledRestartPolicyReason
+	}
+	if deploy := service.Deploy; deploy != nil && deploy.RestartPolicy != nil {
+		policy := deploy.RestartPolicy
+		var attempts uint64
+		if policy.MaxAttempts != nil {
+			attempts = *policy.MaxAttempts
+		}
+		if !supported(policy.Condition, attempts) {
+			if c.incompatible(service, "services.deploy.restart_policy", reason, describeRestartPolicy(policy.Condition, attempts)) {
+				deploy.RestartPolicy = nil
+			}
+		}
+	}
+	if service.Restart != "" {
+		condition, attempts := parseRestart(service.Restart)
+		if !supported(condition, attempts) {
+			if c.incompatible(service, "services.restart", reason, service.Restart) {
+				service.Restart = ""
+			}
+		}
+	}
+}
+
+const (
+	restartPolicyReason          = "ECS services always replace stopped tasks, restart policy %s can't be expressed, consider running the task on schedule with " + extensionSchedule + " instead"
+	scheduledRestartPolicyReason = "scheduled tasks run once per schedule and are never restarted, restart policy %s can't be expressed"
+)
+
+// parseRestart converts a service restart value into the equivalent restart policy condition and max attempts
+func parseRestart(restart string) (string, uint64) {
+	parts := strings.SplitN(restart, ":", 2)
+	switch parts[0] {
+	case "no":
+		return "none", 0
+	case "on-failure":
+		var attempts uint64
+		if len(parts) == 2 {
+			_, _ = fmt.Sscanf(parts[1], "%d", &attempts)
+		}
+		return "on-failure", attempts
+	default:
+		return "any", 0
+	}
+}
+
+func restartPolicySupported(condition string, attempts uint64) bool {
+	switch condition {
+	case "", "any", "on-failure":
+		return attempts == 0
+	default:
+		return false
+	}
+}
+
+// scheduledRestartPolicySupported accepts the policies matching a task EventBridge runs once per schedule
+func scheduledRestartPolicySupported(condition string, attempts uint64) bool {
+	return condition == "" || condition == "none"
+}
+
+func describeRestartPolicy(condition string, attempts uint64) string {
+	if attempts > 0 {
+		return fmt.Sprintf("%s with max_attempts %d", condition, attempts)
+	}
+	return condition
+}
+
+func (c *fargateCompatibilityChecker) CheckVolumeConfigDriver(config *types.VolumeConfig) {
+	if config.Driver != "" && config.Driver != volumeDriverLocal {
+		c.Unsupported("volumes.driver %s is not supported", config.Driver)
+		config.Driver = ""
+	}
+}
+
+func (c *fargateCompatibilityChecker) CheckLoggingDriver(config *types.LoggingConfig) {
+	if config.Driver != "" && config.Driver != "awslogs" {
+		c.Unsupported("services.logging.driver %s is not supported", config.Driver)
 	}
 }
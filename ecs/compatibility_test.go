@@ -0,0 +1,371 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+func TestFargateCapAdd(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    cap_add:
+      - SYS_PTRACE
+      - NET_ADMIN
+`)
+	backend := &ecsAPIService{}
+	err := backend.checkCompatibility(project, false)
+	assert.ErrorContains(t, err, "service test: services.cap_add: ECS doesn't allow to add capability NET_ADMIN with launch type FARGATE")
+}
+
+func TestEC2CapAdd(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    cap_add:
+      - NET_ADMIN
+    deploy:
+      resources:
+        reservations:
+          generic_resources:
+            - discrete_resource_spec:
+                kind: gpus
+                value: 1
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.DeepEqual(t, project.Services[0].CapAdd, []string{"NET_ADMIN"})
+}
+
+func TestEC2LaunchTypeWarning(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    shm_size: 64m
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.Equal(t, len(backend.warnings), 1)
+	assert.Equal(t, backend.warnings[0].Code, warningEC2LaunchType)
+	assert.Equal(t, backend.warnings[0].Message, "deployed with EC2 launch type as shm_size is not supported by Fargate")
+}
+
+func TestUnsupportedAttributeWarning(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    mac_address: "02:42:ac:11:65:43"
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.Equal(t, len(backend.warnings), 1)
+	assert.Equal(t, backend.warnings[0].Code, warningUnsupportedAttribute)
+	assert.ErrorContains(t, backend.warnings.check([]string{warningUnsupportedAttribute}), "services.mac_address")
+	assert.NilError(t, backend.warnings.check([]string{warningPublicIngress}))
+}
+
+func TestFargateDNS(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    dns: 10.0.0.2
+`)
+	backend := &ecsAPIService{}
+	err := backend.checkCompatibility(project, false)
+	assert.ErrorContains(t, err, "service test: services.dns: ECS doesn't allow to set dns servers with network mode awsvpc")
+}
+
+func TestEC2DNS(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    dns: 10.0.0.2
+    shm_size: 64m
+`)
+	backend := &ecsAPIService{}
+	err := backend.checkCompatibility(project, false)
+	assert.ErrorContains(t, err, "service test: services.dns: ECS doesn't allow to set dns servers with network mode awsvpc")
+}
+
+func TestExternalDNS(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    dns: 10.0.0.2
+    extra_hosts:
+      - "db.legacy:10.1.2.3"
+    x-aws-launch_type: EXTERNAL
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.DeepEqual(t, []string(project.Services[0].DNS), []string{"10.0.0.2"})
+	assert.DeepEqual(t, []string(project.Services[0].ExtraHosts), []string{"db.legacy:10.1.2.3"})
+}
+
+func TestFargateDNSSearch(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: nginx
+    dns_search: corp.internal
+`)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.DeepEqual(t, container.DnsSearchDomains, []string{"corp.internal"})
+	assert.Check(t, container.DnsServers == nil)
+}
+
+func TestEC2DNSSearch(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    dns_search: corp.internal
+    shm_size: 64m
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.DeepEqual(t, []string(project.Services[0].DNSSearch), []string{"corp.internal"})
+	assert.Equal(t, len(backend.warnings), 1)
+	assert.Equal(t, backend.warnings[0].Code, warningEC2LaunchType)
+}
+
+func TestFargateExtraHosts(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: nginx
+    extra_hosts:
+      - "db.legacy:10.1.2.3"
+`)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.DeepEqual(t, container.ExtraHosts, []ecs.TaskDefinition_HostEntry{
+		{Hostname: "db.legacy", IpAddress: "10.1.2.3"},
+	})
+}
+
+func TestEC2ExtraHosts(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    extra_hosts:
+      - "db.legacy:10.1.2.3"
+    shm_size: 64m
+`)
+	backend := &ecsAPIService{}
+	err := backend.checkCompatibility(project, false)
+	assert.ErrorContains(t, err, "service test: services.extra_hosts: ECS doesn't allow to set extra_hosts with network mode awsvpc")
+}
+
+func TestAllIncompatibilitiesReported(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  foo:
+    image: nginx
+    cap_add:
+      - NET_ADMIN
+    ports:
+      - 8080:80
+  bar:
+    dns: 10.0.0.2
+`)
+	backend := &ecsAPIService{}
+	err := backend.checkCompatibility(project, false)
+	assert.Error(t, err, `compose file is incompatible with Amazon ECS, use --force or x-aws-ignore to ignore those attributes:
+  service bar: services.dns: ECS doesn't allow to set dns servers with network mode awsvpc
+  service bar: services.image: doesn't define a Docker image to run
+  service foo: services.cap_add: ECS doesn't allow to add capability NET_ADMIN with launch type FARGATE
+  service foo: services.ports.published: published port 8080 can't be set to a distinct value than container port 80`)
+}
+
+func TestIgnoreIncompatibleAttributes(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    cap_add:
+      - NET_ADMIN
+    dns: 10.0.0.2
+x-aws-ignore:
+  - services.cap_add
+`)
+	backend := &ecsAPIService{}
+	err := backend.checkCompatibility(project, false)
+	assert.Error(t, err, `compose file is incompatible with Amazon ECS, use --force or x-aws-ignore to ignore those attributes:
+  service test: services.dns: ECS doesn't allow to set dns servers with network mode awsvpc`)
+
+	project = loadConfig(t, `
+services:
+  test:
+    image: nginx
+    cap_add:
+      - NET_ADMIN
+    dns: 10.0.0.2
+x-aws-ignore:
+  - services.cap_add
+  - services.dns
+`)
+	backend = &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.DeepEqual(t, project.Services[0].CapAdd, []string{})
+	assert.Check(t, project.Services[0].DNS == nil)
+	assert.Equal(t, len(backend.warnings), 2)
+	assert.Equal(t, backend.warnings[0].Code, warningIgnoredAttribute)
+	assert.Equal(t, backend.warnings[0].Service, "test")
+	assert.Equal(t, backend.warnings[0].Message, "services.cap_add ignored: ECS doesn't allow to add capability NET_ADMIN with launch type FARGATE")
+}
+
+func TestForceIncompatibleAttributes(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    ports:
+      - 8080:80
+    extra_hosts:
+      - "db.legacy:10.1.2.3"
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, true))
+	assert.Equal(t, project.Services[0].Ports[0].Published, uint32(80))
+	assert.Check(t, project.Services[0].ExtraHosts == nil)
+	assert.Equal(t, len(backend.warnings), 2)
+	assert.Equal(t, backend.warnings[0].Code, warningIgnoredAttribute)
+}
+
+func TestForceRequiresImage(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    cap_add:
+      - NET_ADMIN
+`)
+	backend := &ecsAPIService{}
+	err := backend.checkCompatibility(project, true)
+	assert.ErrorContains(t, err, "service test: services.image: doesn't define a Docker image to run")
+}
+
+func TestInvalidIgnoreExtension(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+x-aws-ignore: services.cap_add
+`)
+	backend := &ecsAPIService{}
+	err := backend.checkCompatibility(project, false)
+	assert.Error(t, err, "x-aws-ignore must be a list of attributes")
+}
+
+func TestRestartPolicies(t *testing.T) {
+	for _, supported := range []string{"always", "unless-stopped", "on-failure"} {
+		project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    restart: `+supported+`
+`)
+		backend := &ecsAPIService{}
+		assert.NilError(t, backend.checkCompatibility(project, false), supported)
+		assert.Equal(t, len(backend.warnings), 0, supported)
+	}
+
+	for restart, policy := range map[string]string{`"no"`: "no", "on-failure:3": "on-failure:3"} {
+		project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    restart: `+restart+`
+`)
+		backend := &ecsAPIService{}
+		err := backend.checkCompatibility(project, false)
+		assert.ErrorContains(t, err, "service test: services.restart: ECS services always replace stopped tasks, restart policy "+policy+" can't be expressed, consider running the task on schedule with x-aws-schedule instead")
+	}
+}
+
+func TestDeployRestartPolicies(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    deploy:
+      restart_policy:
+        condition: on-failure
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.Equal(t, project.Services[0].Deploy.RestartPolicy.Condition, "on-failure")
+
+	project = loadConfig(t, `
+services:
+  test:
+    image: nginx
+    deploy:
+      restart_policy:
+        condition: on-failure
+        max_attempts: 3
+  job:
+    image: nginx
+    deploy:
+      restart_policy:
+        condition: none
+`)
+	backend = &ecsAPIService{}
+	err := backend.checkCompatibility(project, false)
+	assert.ErrorContains(t, err, "service job: services.deploy.restart_policy: ECS services always replace stopped tasks, restart policy none can't be expressed")
+	assert.ErrorContains(t, err, "service test: services.deploy.restart_policy: ECS services always replace stopped tasks, restart policy on-failure with max_attempts 3 can't be expressed")
+
+	backend = &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, true))
+	assert.Check(t, project.Services[0].Deploy.RestartPolicy == nil)
+	assert.Equal(t, backend.warnings[0].Code, warningIgnoredAttribute)
+}
+
+func TestScheduledRestartPolicies(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    restart: "no"
+    x-aws-schedule: rate(1 day)
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+
+	project = loadConfig(t, `
+services:
+  test:
+    image: nginx
+    restart: always
+    x-aws-schedule: rate(1 day)
+`)
+	err := backend.checkCompatibility(project, false)
+	assert.ErrorContains(t, err, "service test: services.restart: scheduled tasks run once per schedule and are never restarted, restart policy always can't be expressed")
+}
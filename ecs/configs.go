@@ -0,0 +1,221 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/ssm"
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose-cli/ecs/secrets"
+)
+
+// SSM parameters size limits, by tier
+const (
+	standardParameterSize = 4096
+	advancedParameterSize = 8192
+)
+
+func configResourceName(name string) string {
+	return fmt.Sprintf("%sConfig", normalizeResourceName(name))
+}
+
+// configParameterName is the name of the SSM parameter storing content of a project's config
+func configParameterName(project *types.Project, name string) string {
+	c := project.Configs[name]
+	if c.External.External {
+		if c.Name != "" {
+			return c.Name
+		}
+		return name
+	}
+	return fmt.Sprintf("/docker-compose/%s/configs/%s", project.Name, name)
+}
+
+// createConfigs stores configs content as SSM parameters. Configs aren't sensitive, so content is set in the template
+func (b *ecsAPIService) createConfigs(project *types.Project, template *cloudformation.Template) error {
+	for name, c := range project.Configs {
+		if c.External.External {
+			continue
+		}
+		content, err := ioutil.ReadFile(c.File)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(content) {
+			return fmt.Errorf("config %s is binary, which SSM parameters don't support", name)
+		}
+		tier := "Standard"
+		switch {
+		case len(content) > advancedParameterSize:
+			return fmt.Errorf("config %s exceeds the %d bytes SSM parameters size limit", name, advancedParameterSize)
+		case len(content) > standardParameterSize:
+			tier = "Advanced"
+		}
+		tags := map[string]string{}
+		for _, t := range projectTags(project) {
+			tags[t.Key] = t.Value
+		}
+		template.Resources[configResourceName(name)] = &ssm.Parameter{
+			Description: fmt.Sprintf("Config %s", name),
+			Name:        configParameterName(project, name),
+			Tags:        tags,
+			Tier:        tier,
+			Type:        "String",
+			Value:       string(content),
+		}
+	}
+	return nil
+}
+
+// serviceConfigs lists the SSM parameters ARNs of the configs used by service
+func serviceConfigs(project *types.Project, service types.ServiceConfig) []string {
+	var arns []string
+	seen := map[string]bool{}
+	for _, c := range service.Configs {
+		if seen[c.Source] {
+			continue
+		}
+		seen[c.Source] = true
+		arns = append(arns, ssmParameterArn(configParameterName(project, c.Source)))
+	}
+	return arns
+}
+
+// configsDependencies lists the config resources service's task definition depends on, as parameters are referenced by ARN
+func configsDependencies(project *types.Project, service types.ServiceConfig) []string {
+	var dependencies []string
+	seen := map[string]bool{}
+	for _, c := range service.Configs {
+		if seen[c.Source] || project.Configs[c.Source].External.External {
+			continue
+		}
+		seen[c.Source] = true
+		dependencies = append(dependencies, configResourceName(c.Source))
+	}
+	return dependencies
+}
+
+// toConfigsEnvironment exposes configs with x-aws-environment set as environment variables
+func toConfigsEnvironment(project *types.Project, service types.ServiceConfig) []ecs.TaskDefinition_Secret {
+	var environment []ecs.TaskDefinition_Secret
+	for _, c := range service.Configs {
+		name, ok := c.Extensions[extensionEnvironment].(string)
+		if !ok {
+			continue
+		}
+		environment = append(environment, ecs.TaskDefinition_Secret{
+			Name:      name,
+			ValueFrom: ssmParameterArn(configParameterName(project, c.Source)),
+		})
+	}
+	return environment
+}
+
+// createConfigsSideCar creates an init container writing configs to their target path. As task volumes are directories,
+// a volume is created per target directory, which hides the image content of this directory
+func createConfigsSideCar(project *types.Project, service types.ServiceConfig, logConfiguration *ecs.TaskDefinition_LogConfiguration) (
+	[]ecs.TaskDefinition_Volume,
+	[]ecs.TaskDefinition_MountPoint,
+	*ecs.TaskDefinition_ContainerDefinition,
+	error) {
+	files := map[string][]types.ServiceConfigObjConfig{}
+	for _, c := range service.Configs {
+		if _, ok := c.Extensions[extensionEnvironment]; ok {
+			continue
+		}
+		if c.Target == "" {
+			c.Target = "/" + c.Source
+		}
+		if !path.IsAbs(c.Target) {
+			return nil, nil, nil, fmt.Errorf("service %s: config %s target must be an absolute path", service.Name, c.Source)
+		}
+		dir := path.Dir(c.Target)
+		if dir == "/" {
+			return nil, nil, nil, fmt.Errorf("service %s: config %s can't be created in root directory, set a target in a sub-directory", service.Name, c.Source)
+		}
+		files[dir] = append(files[dir], c)
+	}
+	if len(files) == 0 {
+		return nil, nil, nil, nil
+	}
+	var dirs []string
+	for dir := range files {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var (
+		volumes      []ecs.TaskDefinition_Volume
+		mounts       []ecs.TaskDefinition_MountPoint
+		initMounts   []ecs.TaskDefinition_MountPoint
+		args         []secrets.Secret
+		configValues []ecs.TaskDefinition_Secret
+	)
+	seen := map[string]bool{}
+	for i, dir := range dirs {
+		volume := fmt.Sprintf("configs-%d", i)
+		volumes = append(volumes, ecs.TaskDefinition_Volume{
+			Name: volume,
+		})
+		mounts = append(mounts, ecs.TaskDefinition_MountPoint{
+			ContainerPath: dir,
+			ReadOnly:      true,
+			SourceVolume:  volume,
+		})
+		initMounts = append(initMounts, ecs.TaskDefinition_MountPoint{
+			ContainerPath: "/run/secrets/" + volume,
+			ReadOnly:      false,
+			SourceVolume:  volume,
+		})
+		for _, c := range files[dir] {
+			env := toEnvName(c.Source)
+			if !seen[c.Source] {
+				seen[c.Source] = true
+				configValues = append(configValues, ecs.TaskDefinition_Secret{
+					Name:      env,
+					ValueFrom: ssmParameterArn(configParameterName(project, c.Source)),
+				})
+			}
+			args = append(args, secrets.Secret{
+				Name: env,
+				Path: path.Join(volume, path.Base(c.Target)),
+			})
+		}
+	}
+	command, err := json.Marshal(args)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return volumes, mounts, &ecs.TaskDefinition_ContainerDefinition{
+		Name:             fmt.Sprintf("%s_Configs_InitContainer", normalizeResourceName(service.Name)),
+		Image:            secretsInitContainerImage,
+		Command:          []string{string(command)},
+		Essential:        false,
+		LogConfiguration: logConfiguration,
+		MountPoints:      initMounts,
+		Secrets:          configValues,
+	}, nil
+}
@@ -0,0 +1,137 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/awslabs/goformation/v4/cloudformation/ssm"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/ecs/secrets"
+)
+
+func TestConfigsAsFilesAndEnvironment(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: nginx
+    configs:
+      - source: nginx
+        target: /etc/nginx/nginx.conf
+      - source: settings
+        x-aws-environment: SETTINGS
+configs:
+  nginx:
+    file: ./testdata/input/envfile
+  settings:
+    external: true
+    name: /shared/settings
+`)
+	parameter := template.Resources["NginxConfig"].(*ssm.Parameter)
+	assert.Equal(t, parameter.Name, "/docker-compose/Test/configs/nginx")
+	assert.Equal(t, parameter.Type, "String")
+	assert.Equal(t, parameter.Tier, "Standard")
+	_, ok := template.Resources["SettingsConfig"]
+	assert.Check(t, !ok)
+
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	assert.DeepEqual(t, def.AWSCloudFormationDependsOn, []string{"NginxConfig"})
+	var initContainer ecs.TaskDefinition_ContainerDefinition
+	for _, c := range def.ContainerDefinitions {
+		if c.Name == "Test_Configs_InitContainer" {
+			initContainer = c
+		}
+	}
+	assert.Equal(t, initContainer.Image, "docker/ecs-secrets-sidecar:1.1")
+	assert.Equal(t, initContainer.Secrets[0].Name, "NGINX")
+	assert.Equal(t, initContainer.Secrets[0].ValueFrom, ssmParameterArn("/docker-compose/Test/configs/nginx"))
+	var args []secrets.Secret
+	assert.NilError(t, json.Unmarshal([]byte(initContainer.Command[0]), &args))
+	assert.DeepEqual(t, args, []secrets.Secret{{Name: "NGINX", Path: "configs-0/nginx.conf"}})
+	assert.DeepEqual(t, initContainer.MountPoints, []ecs.TaskDefinition_MountPoint{
+		{ContainerPath: "/run/secrets/configs-0", SourceVolume: "configs-0"},
+	})
+
+	container := getMainContainer(def, t)
+	assert.DeepEqual(t, container.MountPoints, []ecs.TaskDefinition_MountPoint{
+		{ContainerPath: "/etc/nginx", ReadOnly: true, SourceVolume: "configs-0"},
+	})
+	assert.DeepEqual(t, container.Secrets, []ecs.TaskDefinition_Secret{
+		{Name: "SETTINGS", ValueFrom: ssmParameterArn("/shared/settings")},
+	})
+
+	role := template.Resources["TestTaskExecutionRole"].(*iam.Role)
+	policy := role.Policies[0].PolicyDocument.(*PolicyDocument)
+	assert.Equal(t, policy.Statement[0].Sid, "Configs")
+	assert.DeepEqual(t, policy.Statement[0].Action, []string{actionGetParameters})
+	assert.DeepEqual(t, policy.Statement[0].Resource, []string{
+		ssmParameterArn("/docker-compose/Test/configs/nginx"),
+		ssmParameterArn("/shared/settings"),
+	})
+}
+
+func TestConfigInRootDirectory(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    configs:
+      - nginx
+configs:
+  nginx:
+    file: ./testdata/input/envfile
+`)
+	backend := &ecsAPIService{}
+	_, err := backend.convert(project, awsResources{})
+	assert.ErrorContains(t, err, "config nginx can't be created in root directory")
+}
+
+func TestBinaryConfig(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+configs:
+  data:
+    file: ./testdata/input/binary
+`)
+	backend := &ecsAPIService{}
+	_, err := backend.convert(project, awsResources{})
+	assert.ErrorContains(t, err, "config data is binary")
+}
+
+func TestConfigsAreCompatible(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    configs:
+      - source: nginx
+        target: /etc/nginx/nginx.conf
+configs:
+  nginx:
+    file: ./testdata/input/envfile
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.Equal(t, len(backend.warnings), 0)
+	assert.Equal(t, len(project.Services[0].Configs), 1)
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2/terminal"
@@ -49,8 +50,17 @@ func (h contextCreateAWSHelper) createContextData(_ context.Context, opts Contex
 	secretKey := opts.AwsSecret
 
 	ecsCtx := store.EcsContext{
-		Profile: opts.Profile,
-		Region:  opts.Region,
+		Profile:            opts.Profile,
+		Region:             opts.Region,
+		GrafanaURL:         opts.GrafanaURL,
+		GrafanaTokenSecret: opts.GrafanaTokenSecret,
+		Cluster:            opts.Cluster,
+		VPC:                opts.VPC,
+		Tags:               opts.Tags,
+		MaxRetries:         opts.MaxRetries,
+		Endpoint:           opts.Endpoint,
+		S3ForcePathStyle:   opts.S3ForcePathStyle,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
 	}
 
 	if h.missingRequiredFlags(ecsCtx) {
@@ -74,10 +84,19 @@ func (h contextCreateAWSHelper) createContextData(_ context.Context, opts Contex
 		}
 		ecsCtx.Region = region
 
-		accessKey, secretKey, err = h.askCredentials()
+		err = h.askDefaults(&ecsCtx)
 		if err != nil {
 			return nil, "", err
 		}
+
+		// credentials of a profile assuming a role come from its source profile, with an MFA token prompted on use
+		section := profilesList[ecsCtx.Profile]
+		if !section.HasKey("role_arn") {
+			accessKey, secretKey, err = h.askCredentials()
+			if err != nil {
+				return nil, "", err
+			}
+		}
 	}
 	if accessKey != "" && secretKey != "" {
 		if err := h.saveCredentials(ecsCtx.Profile, accessKey, secretKey); err != nil {
@@ -169,6 +188,11 @@ func (h contextCreateAWSHelper) chooseProfile(section map[string]ini.Section) (s
 
 func (h contextCreateAWSHelper) chooseRegion(region string, section ini.Section) (string, error) {
 	defaultRegion := region
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if defaultRegion == "" {
+			defaultRegion = os.Getenv(env)
+		}
+	}
 	if defaultRegion == "" && section.Name() != "" {
 		reg, err := section.GetKey("region")
 		if err == nil {
@@ -182,6 +206,58 @@ func (h contextCreateAWSHelper) chooseRegion(region string, section ini.Section)
 	return result, nil
 }
 
+// askDefaults prompts for the cluster, VPC and tags projects use when their compose file doesn't set them
+func (h contextCreateAWSHelper) askDefaults(ctx *store.EcsContext) error {
+	cluster, err := h.user.Input("Default ECS cluster (leave empty to create one per project)", ctx.Cluster)
+	if err != nil {
+		return err
+	}
+	vpc, err := h.user.Input("Default VPC (leave empty to use the account's default VPC)", ctx.VPC)
+	if err != nil {
+		return err
+	}
+	input, err := h.user.Input("Default tags, as comma separated key=value pairs", formatTagsInput(ctx.Tags))
+	if err != nil {
+		return err
+	}
+	tags, err := parseTagsInput(input)
+	if err != nil {
+		return err
+	}
+	ctx.Cluster = strings.TrimSpace(cluster)
+	ctx.VPC = strings.TrimSpace(vpc)
+	ctx.Tags = tags
+	return nil
+}
+
+// parseTagsInput parses tags entered as comma separated key=value pairs
+func parseTagsInput(input string) (map[string]string, error) {
+	var tags map[string]string
+	for _, pair := range strings.Split(input, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid tag %q, must be key=value", pair)
+		}
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		tags[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return tags, nil
+}
+
+func formatTagsInput(tags map[string]string) string {
+	var pairs []string
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
 func (h contextCreateAWSHelper) askCredentials() (string, string, error) {
 	confirm, err := h.user.Confirm("Enter credentials", false)
 	if err != nil {
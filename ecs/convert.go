@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -33,21 +34,23 @@ import (
 	"github.com/awslabs/goformation/v4/cloudformation/ecs"
 	"github.com/compose-spec/compose-go/types"
 	"github.com/docker/cli/opts"
+	"github.com/docker/go-units"
 	"github.com/joho/godotenv"
 )
 
-const secretsInitContainerImage = "docker/ecs-secrets-sidecar"
+// secretsInitContainerImage is pinned to the ecs/secrets release creating files at Secret.Path, which configs rely on
+const secretsInitContainerImage = "docker/ecs-secrets-sidecar:1.1"
 const searchDomainInitContainerImage = "docker/ecs-searchdomain-sidecar"
 
-func (b *ecsAPIService) createTaskDefinition(project *types.Project, service types.ServiceConfig) (*ecs.TaskDefinition, error) {
-	cpu, mem, err := toLimits(service)
+// createContainerDefinitions returns the definitions of service's container and of its init containers, and the volumes they use
+func (b *ecsAPIService) createContainerDefinitions(project *types.Project, service types.ServiceConfig, secretRefs map[string]string) ([]ecs.TaskDefinition_ContainerDefinition, []ecs.TaskDefinition_Volume, error) {
+	_, memReservation := toContainerReservation(service)
+	credential, err := getRepoCredentials(service)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	_, memReservation := toContainerReservation(service)
-	credential := getRepoCredentials(service)
 
-	logConfiguration := getLogConfiguration(service, project)
+	logConfiguration := b.getLogConfiguration(service, project)
 
 	var (
 		initContainers []ecs.TaskDefinition_ContainerDefinition
@@ -55,22 +58,35 @@ func (b *ecsAPIService) createTaskDefinition(project *types.Project, service typ
 		mounts         []ecs.TaskDefinition_MountPoint
 	)
 	if len(service.Secrets) > 0 {
-		secretsVolume, secretsMount, secretsSideCar, err := createSecretsSideCar(project, service, logConfiguration)
+		secretsVolume, secretsMount, secretsSideCar, err := createSecretsSideCar(project, service, secretRefs, logConfiguration)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		initContainers = append(initContainers, secretsSideCar)
 		volumes = append(volumes, secretsVolume)
 		mounts = append(mounts, secretsMount)
 	}
 
-	initContainers = append(initContainers, ecs.TaskDefinition_ContainerDefinition{
-		Name:             fmt.Sprintf("%s_ResolvConf_InitContainer", normalizeResourceName(service.Name)),
-		Image:            searchDomainInitContainerImage,
-		Essential:        false,
-		Command:          []string{b.Region + ".compute.internal", project.Name + ".local"},
-		LogConfiguration: logConfiguration,
-	})
+	configsVolumes, configsMounts, configsSideCar, err := createConfigsSideCar(project, service, logConfiguration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if configsSideCar != nil {
+		initContainers = append(initContainers, *configsSideCar)
+		volumes = append(volumes, configsVolumes...)
+		mounts = append(mounts, configsMounts...)
+	}
+
+	// tasks on ECS Anywhere instances aren't registered in Cloud Map, so they don't search its domain
+	if !externalTask(project, service) {
+		initContainers = append(initContainers, ecs.TaskDefinition_ContainerDefinition{
+			Name:             fmt.Sprintf("%s_ResolvConf_InitContainer", normalizeResourceName(service.Name)),
+			Image:            searchDomainInitContainerImage,
+			Essential:        false,
+			Command:          []string{b.Region + ".compute.internal", project.Name + ".local"},
+			LogConfiguration: logConfiguration,
+		})
+	}
 
 	var dependencies []ecs.TaskDefinition_ContainerDependency
 	for _, c := range initContainers {
@@ -81,24 +97,55 @@ func (b *ecsAPIService) createTaskDefinition(project *types.Project, service typ
 	}
 
 	for _, v := range service.Volumes {
-		source := project.Volumes[v.Source]
-		volumes = append(volumes, ecs.TaskDefinition_Volume{
-			EFSVolumeConfiguration: &ecs.TaskDefinition_EFSVolumeConfiguration{
-				FilesystemId:  source.Name,
-				RootDirectory: source.DriverOpts["root_directory"],
-			},
-			Name: v.Source,
-		})
+		volume := taskVolume(project, v)
+		volumes = append(volumes, volume)
 		mounts = append(mounts, ecs.TaskDefinition_MountPoint{
 			ContainerPath: v.Target,
 			ReadOnly:      v.ReadOnly,
-			SourceVolume:  v.Source,
+			SourceVolume:  volume.Name,
 		})
 	}
 
 	pairs, err := createEnvironment(project, service)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	resourcesEnvironment, err := applicationResourcesEnvironment(project, service)
+	if err != nil {
+		return nil, nil, err
+	}
+	pairs = append(pairs, resourcesEnvironment...)
+
+	linuxParameters, err := toLinuxParameters(service)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	environmentFiles, err := toEnvironmentFiles(project, service, b.partition())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostname := service.Hostname
+	if hostname != "" && serviceNetworkMode(service) == ecsapi.NetworkModeAwsvpc {
+		// ECS rejects hostname for tasks using awsvpc network mode, EXTERNAL services running in bridge or host mode keep it
+		b.warn(warningUnsupportedAttribute, severityWarning, service.Name, "hostname %s is ignored with network mode awsvpc, use service name to resolve service", hostname)
+		hostname = ""
+	}
+
+	healthCheck, err := toHealthCheck(service)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start, err := startTimeout(service)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keySecrets, err := toKeySecrets(project, service, secretRefs)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var reservations *types.Resource
@@ -107,23 +154,25 @@ func (b *ecsAPIService) createTaskDefinition(project *types.Project, service typ
 	}
 
 	containers := append(initContainers, ecs.TaskDefinition_ContainerDefinition{
-		Command:                service.Command,
+		Command:                toCommand(service.Command),
 		DisableNetworking:      service.NetworkMode == "none",
 		DependsOnProp:          dependencies,
 		DnsSearchDomains:       service.DNSSearch,
 		DnsServers:             service.DNS,
+		DockerLabels:           service.Labels,
 		DockerSecurityOptions:  service.SecurityOpt,
-		EntryPoint:             service.Entrypoint,
+		EntryPoint:             toCommand(service.Entrypoint),
 		Environment:            pairs,
+		EnvironmentFiles:       environmentFiles,
 		Essential:              true,
 		ExtraHosts:             toHostEntryPtr(service.ExtraHosts),
 		FirelensConfiguration:  nil,
-		HealthCheck:            toHealthCheck(service.HealthCheck),
-		Hostname:               service.Hostname,
+		HealthCheck:            healthCheck,
+		Hostname:               hostname,
 		Image:                  service.Image,
-		Interactive:            false,
+		Interactive:            service.StdinOpen,
 		Links:                  nil,
-		LinuxParameters:        toLinuxParameters(service),
+		LinuxParameters:        linuxParameters,
 		LogConfiguration:       logConfiguration,
 		MemoryReservation:      memReservation,
 		MountPoints:            mounts,
@@ -134,8 +183,9 @@ func (b *ecsAPIService) createTaskDefinition(project *types.Project, service typ
 		ReadonlyRootFilesystem: service.ReadOnly,
 		RepositoryCredentials:  credential,
 		ResourceRequirements:   toTaskResourceRequirements(reservations),
-		StartTimeout:           0,
-		StopTimeout:            durationToInt(service.StopGracePeriod),
+		Secrets:                append(keySecrets, toConfigsEnvironment(project, service)...),
+		StartTimeout:           toSeconds(start),
+		StopTimeout:            toSeconds(stopTimeout(service)),
 		SystemControls:         toSystemControls(service.Sysctls),
 		Ulimits:                toUlimits(service.Ulimits),
 		User:                   service.User,
@@ -143,8 +193,59 @@ func (b *ecsAPIService) createTaskDefinition(project *types.Project, service typ
 		WorkingDirectory:       service.WorkingDir,
 	})
 
+	return containers, volumes, nil
+}
+
+func (b *ecsAPIService) createTaskDefinition(project *types.Project, service types.ServiceConfig, secretRefs map[string]string) (*ecs.TaskDefinition, error) {
+	members := taskServices(project, service)
+	reservedCPU, reservedMem := taskReservations(project, service)
+	cpu, mem, err := toTaskLimits(members, reservedCPU, reservedMem)
+	if err != nil {
+		return nil, err
+	}
+	ec2 := taskRequiresEC2(members)
+	ext := taskExternal(members)
+	if !ec2 && !ext {
+		err = b.checkFargateSize(cpu)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, member := range members {
+		if err := checkContainerTimeouts(member, ec2 || ext); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		containers []ecs.TaskDefinition_ContainerDefinition
+		volumes    []ecs.TaskDefinition_Volume
+		dependsOn  []string
+	)
+	for i, member := range members {
+		memberContainers, memberVolumes, err := b.createContainerDefinitions(project, member, secretRefs)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			renameSidecarVolumes(member, memberContainers, memberVolumes)
+		}
+		containers = append(containers, memberContainers...)
+		volumes = mergeVolumes(volumes, memberVolumes)
+		dependsOn = append(dependsOn, configsDependencies(project, member)...)
+	}
+	err = setSidecarDependencies(members, containers)
+	if err != nil {
+		return nil, err
+	}
+
 	launchType := ecsapi.LaunchTypeFargate
-	if requireEC2(service) {
+	networkMode := ecsapi.NetworkModeAwsvpc
+	switch {
+	case ext:
+		launchType = launchTypeExternal
+		networkMode = externalNetworkMode(service)
+	case ec2:
 		launchType = ecsapi.LaunchTypeEc2
 	}
 
@@ -154,14 +255,16 @@ func (b *ecsAPIService) createTaskDefinition(project *types.Project, service typ
 		Family:               fmt.Sprintf("%s-%s", project.Name, service.Name),
 		IpcMode:              service.Ipc,
 		Memory:               mem,
-		NetworkMode:          ecsapi.NetworkModeAwsvpc, // FIXME could be set by service.NetworkMode, Fargate only supports network mode ‘awsvpc’.
+		NetworkMode:          networkMode, // FIXME could be set by service.NetworkMode, Fargate only supports network mode ‘awsvpc’.
 		PidMode:              service.Pid,
 		PlacementConstraints: toPlacementConstraints(service.Deploy),
 		ProxyConfiguration:   nil,
 		RequiresCompatibilities: []string{
 			launchType,
 		},
-		Volumes: volumes,
+		Tags:                       serviceTags(project, service),
+		Volumes:                    volumes,
+		AWSCloudFormationDependsOn: dependsOn,
 	}, nil
 }
 
@@ -181,7 +284,19 @@ func toTaskResourceRequirements(reservations *types.Resource) []ecs.TaskDefiniti
 	return requirements
 }
 
-func createSecretsSideCar(project *types.Project, service types.ServiceConfig, logConfiguration *ecs.TaskDefinition_LogConfiguration) (
+// emptyCommand marks an entrypoint or command explicitly set to an empty list, which goformation would omit like an
+// unset one. marshall turns it into an empty list, so it resets the image's one as docker does
+const emptyCommand = ""
+
+// toCommand maps an empty entrypoint or command to the emptyCommand marker, keeping an unset one nil
+func toCommand(command types.ShellCommand) []string {
+	if command != nil && len(command) == 0 {
+		return []string{emptyCommand}
+	}
+	return command
+}
+
+func createSecretsSideCar(project *types.Project, service types.ServiceConfig, secretRefs map[string]string, logConfiguration *ecs.TaskDefinition_LogConfiguration) (
 	ecs.TaskDefinition_Volume,
 	ecs.TaskDefinition_MountPoint,
 	ecs.TaskDefinition_ContainerDefinition,
@@ -201,27 +316,16 @@ func createSecretsSideCar(project *types.Project, service types.ServiceConfig, l
 		taskSecrets []ecs.TaskDefinition_Secret
 	)
 	for _, s := range service.Secrets {
-		secretConfig := project.Secrets[s.Source]
 		if s.Target == "" {
 			s.Target = s.Source
 		}
 		taskSecrets = append(taskSecrets, ecs.TaskDefinition_Secret{
 			Name:      s.Target,
-			ValueFrom: secretConfig.Name,
+			ValueFrom: secretRefs[s.Source],
 		})
-		var keys []string
-		if ext, ok := secretConfig.Extensions[extensionKeys]; ok {
-			if key, ok := ext.(string); ok {
-				keys = append(keys, key)
-			} else {
-				for _, k := range ext.([]interface{}) {
-					keys = append(keys, k.(string))
-				}
-			}
-		}
 		args = append(args, secrets.Secret{
 			Name: s.Target,
-			Keys: keys,
+			Keys: getSecretKeys(project, s),
 		})
 	}
 	command, err := json.Marshal(args)
@@ -246,8 +350,66 @@ func createSecretsSideCar(project *types.Project, service types.ServiceConfig, l
 	return secretsVolume, secretsMount, secretsSideCar, nil
 }
 
+// getSecretKeys returns the JSON keys selected by x-aws-keys on the service's secret, or on the secret definition
+func getSecretKeys(project *types.Project, secret types.ServiceSecretConfig) []string {
+	ext, ok := secret.Extensions[extensionKeys]
+	if !ok {
+		ext, ok = project.Secrets[secret.Source].Extensions[extensionKeys]
+	}
+	if !ok {
+		return nil
+	}
+	if key, ok := ext.(string); ok {
+		return []string{key}
+	}
+	var keys []string
+	for _, k := range ext.([]interface{}) {
+		keys = append(keys, k.(string))
+	}
+	return keys
+}
+
+// toKeySecrets exposes selected JSON keys of secrets as environment variables, named SECRET_KEY. ECS only selects keys of
+// a secret referenced by ARN
+func toKeySecrets(project *types.Project, service types.ServiceConfig, secretRefs map[string]string) ([]ecs.TaskDefinition_Secret, error) {
+	var keySecrets []ecs.TaskDefinition_Secret
+	for _, s := range service.Secrets {
+		target := s.Target
+		if target == "" {
+			target = s.Source
+		}
+		if isSSMParameter(project.Secrets[s.Source]) {
+			// SSM parameters don't support JSON key selection
+			continue
+		}
+		keys := getSecretKeys(project, s)
+		if len(keys) > 0 && referencedByName(project, s.Source, secretRefs[s.Source]) {
+			return nil, fmt.Errorf("%s of secret %s requires its ARN, which %s isn't: deploy it with up first, or reference the external secret by ARN", extensionKeys, s.Source, secretRefs[s.Source])
+		}
+		for _, key := range keys {
+			if key == "*" {
+				// can't be expanded without reading the secret
+				continue
+			}
+			keySecrets = append(keySecrets, ecs.TaskDefinition_Secret{
+				Name:      toEnvName(target + "_" + key),
+				ValueFrom: cloudformation.Join("", []string{secretRefs[s.Source], ":" + key + "::"}),
+			})
+		}
+	}
+	return keySecrets, nil
+}
+
+var invalidEnvNameCharacters = regexp.MustCompile("[^a-zA-Z0-9]+")
+
+func toEnvName(s string) string {
+	return strings.ToUpper(invalidEnvNameCharacters.ReplaceAllString(s, "_"))
+}
+
 func createEnvironment(project *types.Project, service types.ServiceConfig) ([]ecs.TaskDefinition_KeyValuePair, error) {
+	_, uploaded := envFilesBucket(project)
 	environment := map[string]*string{}
+	fromFiles := map[string]string{}
 	for _, f := range service.EnvFile {
 		if !filepath.IsAbs(f) {
 			f = filepath.Join(project.WorkingDir, f)
@@ -266,7 +428,9 @@ func createEnvironment(project *types.Project, service types.ServiceConfig) ([]e
 			return nil, err
 		}
 		for k, v := range env {
+			v := v
 			environment[k] = &v
+			fromFiles[k] = v
 		}
 	}
 	for k, v := range service.Environment {
@@ -275,6 +439,10 @@ func createEnvironment(project *types.Project, service types.ServiceConfig) ([]e
 
 	var pairs []ecs.TaskDefinition_KeyValuePair
 	for k, v := range environment {
+		if fromFile, ok := fromFiles[k]; uploaded && ok && v != nil && *v == fromFile {
+			// loaded by ECS from the uploaded env_file
+			continue
+		}
 		name := k
 		var value string
 		if v != nil {
@@ -288,16 +456,38 @@ func createEnvironment(project *types.Project, service types.ServiceConfig) ([]e
 	return pairs, nil
 }
 
-func getLogConfiguration(service types.ServiceConfig, project *types.Project) *ecs.TaskDefinition_LogConfiguration {
+// awslogsOptions are the awslogs log driver options which can be set by compose logging options
+var awslogsOptions = map[string]bool{
+	"mode":                      true,
+	"max-buffer-size":           true,
+	"awslogs-datetime-format":   true,
+	"awslogs-multiline-pattern": true,
+	"awslogs-stream-prefix":     true,
+	"awslogs-region":            true,
+	"awslogs-group":             true,
+}
+
+func (b *ecsAPIService) getLogConfiguration(service types.ServiceConfig, project *types.Project) *ecs.TaskDefinition_LogConfiguration {
 	options := map[string]string{
 		"awslogs-region":        cloudformation.Ref("AWS::Region"),
-		"awslogs-group":         cloudformation.Ref("LogGroup"),
+		"awslogs-group":         logsGroupRef(project, service),
 		"awslogs-stream-prefix": project.Name,
 	}
 	if service.Logging != nil {
-		for k, v := range service.Logging.Options {
-			if strings.HasPrefix(k, "awslogs-") {
-				options[k] = v
+		keys := make([]string, 0, len(service.Logging.Options))
+		for k := range service.Logging.Options {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			switch {
+			case awslogsOptions[k]:
+				options[k] = service.Logging.Options[k]
+			case strings.HasPrefix(k, "awslogs-"):
+				b.warn(warningUnknownLoggingOption, severityWarning, service.Name, "unknown awslogs option %s might be rejected by ECS", k)
+				options[k] = service.Logging.Options[k]
+			default:
+				b.warn(warningUnknownLoggingOption, severityWarning, service.Name, "logging option %s is ignored as not supported by awslogs driver", k)
 			}
 		}
 	}
@@ -321,30 +511,61 @@ func toSystemControls(sysctls types.Mapping) []ecs.TaskDefinition_SystemControl
 
 const miB = 1024 * 1024
 
-func toLimits(service types.ServiceConfig) (string, string, error) {
-	mem, cpu, err := getConfiguredLimits(service)
-	if err != nil {
-		return "", "", err
+// taskReservations returns the CPU units and memory reserved by the containers conversion adds to service's task, which
+// the task size has to fit along with its members limits
+func taskReservations(project *types.Project, service types.ServiceConfig) (int64, types.UnitBytes) {
+	var (
+		cpu int64
+		mem types.UnitBytes
+	)
+	if xrayEnabled(project, service) {
+		cpu += xrayDaemonCPU
+		mem += xrayDaemonMemoryReservation * miB
 	}
-	if requireEC2(service) {
-		// just return configured limits expressed in Mb and CPU units
+	if meshed(project, service) {
+		mem += envoyMemoryReservation * miB
+	}
+	return cpu, mem
+}
+
+// toTaskLimits returns the task size to run services containers, sized by the sum of their limits and of the resources
+// reserved by containers added to the task
+func toTaskLimits(services []types.ServiceConfig, reservedCPU int64, reservedMem types.UnitBytes) (string, string, error) {
+	var (
+		mem types.UnitBytes
+		cpu int64
+	)
+	for _, service := range services {
+		m, c, err := getConfiguredLimits(service)
+		if err != nil {
+			return "", "", err
+		}
+		mem += m
+		cpu += c
+	}
+	if taskRequiresEC2(services) {
+		// just return configured limits expressed in Mb and CPU units, tasks without limits aren't sized
 		var cpuLimit, memLimit string
 		if cpu > 0 {
-			cpuLimit = fmt.Sprint(cpu)
+			cpuLimit = fmt.Sprint(cpu + reservedCPU)
 		}
 		if mem > 0 {
-			memLimit = fmt.Sprint(mem / miB)
+			memLimit = fmt.Sprint((mem + reservedMem) / miB)
 		}
 		return cpuLimit, memLimit, nil
 	}
+	cpu += reservedCPU
+	mem += reservedMem
 
 	// All possible cpu/mem values for Fargate
 	fargateCPUToMem := map[int64][]types.UnitBytes{
-		256:  {512, 1024, 2048},
-		512:  {1024, 2048, 3072, 4096},
-		1024: {2048, 3072, 4096, 5120, 6144, 7168, 8192},
-		2048: {4096, 5120, 6144, 7168, 8192, 9216, 10240, 11264, 12288, 13312, 14336, 15360, 16384},
-		4096: {8192, 9216, 10240, 11264, 12288, 13312, 14336, 15360, 16384, 17408, 18432, 19456, 20480, 21504, 22528, 23552, 24576, 25600, 26624, 27648, 28672, 29696, 30720},
+		256:   {512, 1024, 2048},
+		512:   {1024, 2048, 3072, 4096},
+		1024:  {2048, 3072, 4096, 5120, 6144, 7168, 8192},
+		2048:  {4096, 5120, 6144, 7168, 8192, 9216, 10240, 11264, 12288, 13312, 14336, 15360, 16384},
+		4096:  {8192, 9216, 10240, 11264, 12288, 13312, 14336, 15360, 16384, 17408, 18432, 19456, 20480, 21504, 22528, 23552, 24576, 25600, 26624, 27648, 28672, 29696, 30720},
+		8192:  {16384, 20480, 24576, 28672, 32768, 36864, 40960, 45056, 49152, 53248, 57344, 61440},
+		16384: {32768, 40960, 49152, 57344, 65536, 73728, 81920, 90112, 98304, 106496, 114688, 122880},
 	}
 	cpuLimit := "256"
 	memLimit := "512"
@@ -453,30 +674,58 @@ func toUlimits(ulimits map[string]*types.UlimitsConfig) []ecs.TaskDefinition_Uli
 	return u
 }
 
-func toLinuxParameters(service types.ServiceConfig) *ecs.TaskDefinition_LinuxParameters {
+func toLinuxParameters(service types.ServiceConfig) (*ecs.TaskDefinition_LinuxParameters, error) {
+	tmpfs, err := toTmpfs(service.Tmpfs)
+	if err != nil {
+		return nil, err
+	}
+	var shmSize int
+	if service.ShmSize != "" {
+		size, err := units.RAMInBytes(service.ShmSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shm_size %q: %s", service.ShmSize, err)
+		}
+		shmSize = int(size / miB)
+	}
 	return &ecs.TaskDefinition_LinuxParameters{
 		Capabilities:       toKernelCapabilities(service.CapAdd, service.CapDrop),
 		Devices:            nil,
 		InitProcessEnabled: service.Init != nil && *service.Init,
 		MaxSwap:            0,
-		// FIXME SharedMemorySize:   service.ShmSize,
-		Swappiness: 0,
-		Tmpfs:      toTmpfs(service.Tmpfs),
-	}
+		SharedMemorySize:   shmSize,
+		Swappiness:         0,
+		Tmpfs:              tmpfs,
+	}, nil
 }
 
-func toTmpfs(tmpfs types.StringList) []ecs.TaskDefinition_Tmpfs {
+func toTmpfs(tmpfs types.StringList) ([]ecs.TaskDefinition_Tmpfs, error) {
 	if tmpfs == nil || len(tmpfs) == 0 {
-		return nil
+		return nil, nil
 	}
 	o := []ecs.TaskDefinition_Tmpfs{}
-	for _, path := range tmpfs {
-		o = append(o, ecs.TaskDefinition_Tmpfs{
-			ContainerPath: path,
+	for _, t := range tmpfs {
+		// tmpfs can be set as `/path:opt1,opt2`, size being a mount option
+		parts := strings.SplitN(t, ":", 2)
+		mount := ecs.TaskDefinition_Tmpfs{
+			ContainerPath: parts[0],
 			Size:          100, // size is required on ECS, unlimited by the compose spec
-		})
+		}
+		if len(parts) == 2 {
+			for _, opt := range strings.Split(parts[1], ",") {
+				if strings.HasPrefix(opt, "size=") {
+					size, err := units.RAMInBytes(strings.TrimPrefix(opt, "size="))
+					if err != nil {
+						return nil, fmt.Errorf("invalid tmpfs size %q: %s", opt, err)
+					}
+					mount.Size = int(size / miB)
+					continue
+				}
+				mount.MountOptions = append(mount.MountOptions, opt)
+			}
+		}
+		o = append(o, mount)
 	}
-	return o
+	return o, nil
 }
 
 func toKernelCapabilities(add []string, drop []string) *ecs.TaskDefinition_KernelCapabilities {
@@ -490,21 +739,51 @@ func toKernelCapabilities(add []string, drop []string) *ecs.TaskDefinition_Kerne
 
 }
 
-func toHealthCheck(check *types.HealthCheckConfig) *ecs.TaskDefinition_HealthCheck {
-	if check == nil {
-		return nil
+// toHealthCheck converts compose healthcheck to the container health check. ECS only runs CMD and CMD-SHELL
+// tests, and zero values stand for ECS defaults
+func toHealthCheck(service types.ServiceConfig) (*ecs.TaskDefinition_HealthCheck, error) {
+	check := service.HealthCheck
+	if check == nil || check.Disable || len(check.Test) == 0 {
+		return nil, nil
+	}
+	switch check.Test[0] {
+	case "NONE":
+		return nil, nil
+	case "CMD", "CMD-SHELL":
+		if len(check.Test) < 2 {
+			return nil, fmt.Errorf("service %s: healthcheck test %s requires a command", service.Name, check.Test[0])
+		}
+	default:
+		return nil, fmt.Errorf("service %s: healthcheck test must start with CMD, CMD-SHELL or NONE, got %q", service.Name, check.Test[0])
 	}
+
 	retries := 0
 	if check.Retries != nil {
 		retries = int(*check.Retries)
 	}
-	return &ecs.TaskDefinition_HealthCheck{
+	healthCheck := &ecs.TaskDefinition_HealthCheck{
 		Command:     check.Test,
 		Interval:    durationToInt(check.Interval),
 		Retries:     retries,
 		StartPeriod: durationToInt(check.StartPeriod),
 		Timeout:     durationToInt(check.Timeout),
 	}
+	for _, limit := range []struct {
+		attribute string
+		set       bool
+		value     int
+		min, max  int
+	}{
+		{"interval", check.Interval != nil, healthCheck.Interval, 5, 300},
+		{"timeout", check.Timeout != nil, healthCheck.Timeout, 2, 60},
+		{"retries", check.Retries != nil, healthCheck.Retries, 1, 10},
+		{"start_period", check.StartPeriod != nil, healthCheck.StartPeriod, 0, 300},
+	} {
+		if limit.set && (limit.value < limit.min || limit.value > limit.max) {
+			return nil, fmt.Errorf("service %s: healthcheck %s must be between %d and %d, got %d", service.Name, limit.attribute, limit.min, limit.max, limit.value)
+		}
+	}
+	return healthCheck, nil
 }
 
 func durationToInt(interval *types.Duration) int {
@@ -530,15 +809,31 @@ func toHostEntryPtr(hosts types.HostsList) []ecs.TaskDefinition_HostEntry {
 	return e
 }
 
-func getRepoCredentials(service types.ServiceConfig) *ecs.TaskDefinition_RepositoryCredentials {
-	if value, ok := service.Extensions[extensionPullCredentials]; ok {
-		return &ecs.TaskDefinition_RepositoryCredentials{CredentialsParameter: value.(string)}
+func getRepoCredentials(service types.ServiceConfig) (*ecs.TaskDefinition_RepositoryCredentials, error) {
+	secret, ok, err := pullCredentials(service)
+	if err != nil || !ok {
+		return nil, err
 	}
-	return nil
+	return &ecs.TaskDefinition_RepositoryCredentials{CredentialsParameter: secret}, nil
 }
 
 func requireEC2(s types.ServiceConfig) bool {
-	return gpuRequirements(s) > 0
+	return gpuRequirements(s) > 0 || len(unsupportedByFargate(s)) > 0
+}
+
+// unsupportedByFargate lists the service attributes which can't run on Fargate
+func unsupportedByFargate(s types.ServiceConfig) []string {
+	var attributes []string
+	if len(s.Tmpfs) > 0 {
+		attributes = append(attributes, "tmpfs")
+	}
+	if s.ShmSize != "" {
+		attributes = append(attributes, "shm_size")
+	}
+	if globalMode(s) {
+		attributes = append(attributes, "deploy.mode global")
+	}
+	return attributes
 }
 
 func gpuRequirements(s types.ServiceConfig) int64 {
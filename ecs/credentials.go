@@ -0,0 +1,70 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose-cli/prompt"
+)
+
+// assumeRoleDuration is how long assumed role credentials are valid, so a deployment doesn't outlive them and prompt
+// for another MFA token. It is the maximum duration for a role assumed by another role
+const assumeRoleDuration = time.Hour
+
+// mfaTokenProvider prompts the user for the MFA token of a role set by shared config with mfa_serial. Credentials are
+// cached by the session, so the token is only prompted once during the command
+func mfaTokenProvider(user prompt.UI) func() (string, error) {
+	return func() (string, error) {
+		if !interactive() {
+			return "", fmt.Errorf("assuming role requires an MFA token, which can't be prompted as input is not a terminal")
+		}
+		return user.Input("MFA token", "")
+	}
+}
+
+// assumeProjectRole switches SDK clients to the role set by project's x-aws-role_arn, assumed with context's credentials
+func (b *ecsAPIService) assumeProjectRole(project *types.Project) error {
+	x, ok := project.Extensions[extensionRoleArn]
+	if !ok {
+		return nil
+	}
+	role := fmt.Sprint(x)
+	parsed, err := arn.Parse(role)
+	if err != nil || parsed.Service != "iam" {
+		return fmt.Errorf("%s %q is not an IAM role ARN", extensionRoleArn, role)
+	}
+	if role == b.role {
+		return nil
+	}
+	if b.sess == nil {
+		return fmt.Errorf("%s can't be assumed without an AWS session", extensionRoleArn)
+	}
+	// all clients share the same credentials, retrieved once and refreshed when they expire
+	creds := stscreds.NewCredentials(b.sess, role, func(p *stscreds.AssumeRoleProvider) {
+		p.Duration = assumeRoleDuration
+	})
+	b.SDK = newSDK(b.sess.Copy(&aws.Config{Credentials: creds}))
+	b.role = role
+	return nil
+}
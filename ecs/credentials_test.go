@@ -0,0 +1,122 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	efsapi "github.com/aws/aws-sdk-go/service/efs"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/prompt"
+)
+
+const assumeRoleResponse = `<AssumeRoleResponse>
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ASIAPROJECTROLE</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`
+
+func TestAssumeProjectRole(t *testing.T) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("eu-west-3"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	assert.NilError(t, err)
+	var assumed []*sts.AssumeRoleInput
+	sess.Handlers.Send.Clear()
+	sess.Handlers.Send.PushBack(func(r *request.Request) {
+		assumed = append(assumed, r.Params.(*sts.AssumeRoleInput))
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(assumeRoleResponse)),
+		}
+	})
+
+	project := loadConfig(t, `
+x-aws-role_arn: arn:aws:iam::012345678910:role/deployer
+services:
+  foo:
+    image: hello_world
+`)
+	backend := &ecsAPIService{Region: "eu-west-3", SDK: newSDK(sess), sess: sess}
+	assert.NilError(t, backend.assumeProjectRole(project))
+
+	creds := backend.SDK.ECS.(*ecsapi.ECS).Config.Credentials
+	value, err := creds.Get()
+	assert.NilError(t, err)
+	assert.Equal(t, value.AccessKeyID, "ASIAPROJECTROLE")
+	assert.Equal(t, len(assumed), 1)
+	assert.Equal(t, aws.StringValue(assumed[0].RoleArn), "arn:aws:iam::012345678910:role/deployer")
+	assert.Equal(t, aws.Int64Value(assumed[0].DurationSeconds), int64(3600))
+
+	// credentials are shared by all clients, and the role isn't assumed again for the same project
+	assert.Check(t, backend.SDK.EFS.(*efsapi.EFS).Config.Credentials == creds)
+	assert.NilError(t, backend.assumeProjectRole(project))
+	_, err = backend.SDK.ECS.(*ecsapi.ECS).Config.Credentials.Get()
+	assert.NilError(t, err)
+	assert.Equal(t, len(assumed), 1)
+}
+
+func TestAssumeProjectRoleInvalidArn(t *testing.T) {
+	project := loadConfig(t, `
+x-aws-role_arn: arn:aws:s3:::bucket
+services:
+  foo:
+    image: hello_world
+`)
+	err := (&ecsAPIService{}).assumeProjectRole(project)
+	assert.Error(t, err, `x-aws-role_arn "arn:aws:s3:::bucket" is not an IAM role ARN`)
+}
+
+type tokenUI struct {
+	prompt.UI
+	token string
+}
+
+func (u tokenUI) Input(message string, defaultValue string) (string, error) {
+	return u.token, nil
+}
+
+func TestMFATokenProvider(t *testing.T) {
+	defer func(f func() bool) { interactive = f }(interactive)
+	provider := mfaTokenProvider(tokenUI{token: "123456"})
+
+	interactive = func() bool { return true }
+	token, err := provider()
+	assert.NilError(t, err)
+	assert.Equal(t, token, "123456")
+
+	interactive = func() bool { return false }
+	_, err = provider()
+	assert.ErrorContains(t, err, "can't be prompted as input is not a terminal")
+}
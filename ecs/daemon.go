@@ -0,0 +1,62 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// globalMode tells if service runs one task per container instance, which ECS only supports with EC2 launch type
+func globalMode(service types.ServiceConfig) bool {
+	return service.Deploy != nil && service.Deploy.Mode == "global"
+}
+
+// checkDeployMode rejects settings which rely on a desired count of tasks, as DAEMON services don't have one
+func checkDeployMode(service types.ServiceConfig) error {
+	if service.Deploy == nil {
+		return nil
+	}
+	switch service.Deploy.Mode {
+	case "", "replicated":
+		return nil
+	case "global":
+	default:
+		return fmt.Errorf("unsupported deploy.mode %q", service.Deploy.Mode)
+	}
+	if service.Deploy.Replicas != nil {
+		return fmt.Errorf("deploy.replicas can't be set with deploy.mode global, which runs a task on each container instance")
+	}
+	if service.Deploy.UpdateConfig != nil {
+		return fmt.Errorf("deploy.update_config can't be set with deploy.mode global")
+	}
+	for _, extension := range []string{extensionMinPercent, extensionMaxPercent,
+		extensionFallbackCapacity, extensionMaxTaskLifetime, extensionSchedule, extensionPlacementStrategy} {
+		if _, ok := service.Extensions[extension]; ok {
+			return fmt.Errorf("%s can't be set with deploy.mode global", extension)
+		}
+	}
+	if hasAutoscaling(service) {
+		return fmt.Errorf("deploy.%s can't be set with deploy.mode global", extensionAutoScaling)
+	}
+	if controller, _ := deploymentController(service); controller == ecsapi.DeploymentControllerTypeCodeDeploy {
+		return fmt.Errorf("%s %s can't be set with deploy.mode global", extensionDeploymentController, controller)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"testing"
+
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+func TestGlobalModeDaemonService(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  agent:
+    image: datadog/agent
+    deploy:
+      mode: global
+`)
+	service := template.Resources["AgentService"].(*ecs.Service)
+	assert.Equal(t, service.SchedulingStrategy, ecsapi.SchedulingStrategyDaemon)
+	assert.Equal(t, service.LaunchType, ecsapi.LaunchTypeEc2)
+	assert.Equal(t, service.PlatformVersion, "")
+	assert.Check(t, service.DeploymentConfiguration == nil)
+
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	var marshalled struct {
+		Resources map[string]struct {
+			Properties map[string]interface{}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	_, ok := marshalled.Resources["AgentService"].Properties["DesiredCount"]
+	assert.Check(t, !ok)
+}
+
+func TestGlobalModeCompatibility(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  agent:
+    image: datadog/agent
+    deploy:
+      mode: global
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.Equal(t, project.Services[0].Deploy.Mode, "global")
+	assert.Equal(t, backend.warnings[0].Code, warningEC2LaunchType)
+}
+
+func TestGlobalModeInvalid(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  agent:
+    image: datadog/agent
+    deploy:
+      mode: global
+      replicas: 2
+`: "deploy.replicas can't be set with deploy.mode global",
+		`
+services:
+  agent:
+    image: datadog/agent
+    deploy:
+      mode: global
+      x-aws-autoscaling:
+        cpu: 75
+        max: 4
+`: "deploy.x-aws-autoscaling can't be set with deploy.mode global",
+		`
+services:
+  agent:
+    image: datadog/agent
+    deploy:
+      mode: global
+    x-aws-fallback-capacity: FARGATE_SPOT
+`: "x-aws-fallback-capacity can't be set with deploy.mode global",
+		`
+services:
+  agent:
+    image: datadog/agent
+    deploy:
+      mode: spread
+`: `unsupported deploy.mode "spread"`,
+	} {
+		project := loadConfig(t, yaml)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.ErrorContains(t, err, expected)
+	}
+}
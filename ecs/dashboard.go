@@ -0,0 +1,208 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/cloudwatch"
+	"github.com/awslabs/goformation/v4/cloudformation/elasticloadbalancingv2"
+	"github.com/compose-spec/compose-go/types"
+)
+
+const (
+	// dashboardBodyLimit is the maximum size of a dashboard body
+	dashboardBodyLimit = 100 * 1024
+	// dashboardWidgetsLimit is the maximum number of widgets of a dashboard
+	dashboardWidgetsLimit = 500
+	// dashboardRefSize is the size a value resolved by CloudFormation is assumed to take in the dashboard body
+	dashboardRefSize = 128
+)
+
+// dashboardEnabled tells if project opts in x-aws-dashboard to get a CloudWatch dashboard
+func dashboardEnabled(project *types.Project) (bool, error) {
+	x, ok := project.Extensions[extensionDashboard]
+	if !ok {
+		return false, nil
+	}
+	enabled, ok := x.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean", extensionDashboard)
+	}
+	return enabled, nil
+}
+
+// dashboardBody builds a dashboard body, as a JSON document which values only known once the stack is deployed, like
+// resource names, get joined in by CloudFormation
+type dashboardBody struct {
+	widgets []interface{}
+	refs    []string
+}
+
+var dashboardRefPattern = regexp.MustCompile(`@ref:(\d+)@`)
+
+// ref returns a placeholder for value in the dashboard body, replaced by value when the body gets rendered
+func (d *dashboardBody) ref(value string) string {
+	d.refs = append(d.refs, value)
+	return fmt.Sprintf("@ref:%d@", len(d.refs)-1)
+}
+
+func (d *dashboardBody) marshal() (string, error) {
+	raw, err := json.Marshal(map[string]interface{}{"widgets": d.widgets})
+	return string(raw), err
+}
+
+// size estimates the size of the dashboard body once rendered
+func (d *dashboardBody) size() (int, error) {
+	raw, err := d.marshal()
+	if err != nil {
+		return 0, err
+	}
+	return len(dashboardRefPattern.ReplaceAllString(raw, "")) + len(d.refs)*dashboardRefSize, nil
+}
+
+// render joins the values referenced by the dashboard body placeholders with its JSON document
+func (d *dashboardBody) render() (string, error) {
+	raw, err := d.marshal()
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	last := 0
+	for _, match := range dashboardRefPattern.FindAllStringSubmatchIndex(raw, -1) {
+		i, _ := strconv.Atoi(raw[match[2]:match[3]])
+		parts = append(parts, raw[last:match[0]], d.refs[i])
+		last = match[1]
+	}
+	parts = append(parts, raw[last:])
+	return cloudformation.Join("", parts), nil
+}
+
+func (d *dashboardBody) metricWidget(title string, region string, metrics [][]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "metric",
+		"width":  12,
+		"height": 6,
+		"properties": map[string]interface{}{
+			"title":   title,
+			"region":  region,
+			"view":    "timeSeries",
+			"period":  300,
+			"metrics": metrics,
+		},
+	}
+}
+
+// createDashboard creates the CloudWatch dashboard set by x-aws-dashboard, showing project logs and, by service, CPU
+// and memory utilization with requests and latency of its target groups. Services which widgets don't fit in the
+// dashboard body limits are left out with a warning
+func (b *ecsAPIService) createDashboard(project *types.Project, resources awsResources, template *cloudformation.Template) error {
+	enabled, err := dashboardEnabled(project)
+	if err != nil || !enabled {
+		return err
+	}
+
+	body := &dashboardBody{}
+	region := body.ref(cloudformation.Ref("AWS::Region"))
+
+	groups := map[string]bool{}
+	for _, service := range project.Services {
+		groups[logsGroupRef(project, service)] = true
+	}
+	var names []string
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+	var sources []string
+	for _, group := range names {
+		sources = append(sources, fmt.Sprintf("SOURCE '%s'", body.ref(group)))
+	}
+	body.widgets = append(body.widgets, map[string]interface{}{
+		"type":   "log",
+		"width":  24,
+		"height": 6,
+		"properties": map[string]interface{}{
+			"title":  fmt.Sprintf("%s logs", project.Name),
+			"region": region,
+			"view":   "table",
+			"query":  strings.Join(append(sources, "fields @timestamp, @logStream, @message", "sort @timestamp desc", "limit 100"), " | "),
+		},
+	})
+
+	targetGroups := map[string][]string{}
+	for name, resource := range template.Resources {
+		if _, ok := resource.(*elasticloadbalancingv2.TargetGroup); ok {
+			targetGroups[b.owners[name]] = append(targetGroups[b.owners[name]], name)
+		}
+	}
+
+	var services []string
+	for _, service := range project.Services {
+		if _, ok := template.Resources[serviceResourceName(service.Name)]; ok {
+			services = append(services, service.Name)
+		}
+	}
+	sort.Strings(services)
+	for i, service := range services {
+		widgets, refs := len(body.widgets), len(body.refs)
+		serviceName := body.ref(cloudformation.GetAtt(serviceResourceName(service), "Name"))
+		cluster := body.ref(resources.cluster)
+		body.widgets = append(body.widgets, body.metricWidget(fmt.Sprintf("%s CPU and memory utilization", service), region, [][]interface{}{
+			{"AWS/ECS", "CPUUtilization", "ClusterName", cluster, "ServiceName", serviceName, map[string]string{"stat": "Average"}},
+			{"AWS/ECS", "MemoryUtilization", "ClusterName", cluster, "ServiceName", serviceName, map[string]string{"stat": "Average"}},
+		}))
+
+		sort.Strings(targetGroups[service])
+		for _, targetGroup := range targetGroups[service] {
+			loadBalancer := body.ref(loadBalancerFullName(template, resources))
+			group := body.ref(cloudformation.GetAtt(targetGroup, "TargetGroupFullName"))
+			body.widgets = append(body.widgets, body.metricWidget(fmt.Sprintf("%s requests and latency", service), region, [][]interface{}{
+				{"AWS/ApplicationELB", "RequestCount", "LoadBalancer", loadBalancer, "TargetGroup", group, map[string]string{"stat": "Sum"}},
+				{"AWS/ApplicationELB", "TargetResponseTime", "LoadBalancer", loadBalancer, "TargetGroup", group, map[string]interface{}{"stat": "Average", "yAxis": "right"}},
+			}))
+		}
+
+		size, err := body.size()
+		if err != nil {
+			return err
+		}
+		if size > dashboardBodyLimit || len(body.widgets) > dashboardWidgetsLimit {
+			body.widgets, body.refs = body.widgets[:widgets], body.refs[:refs]
+			b.warn(warningDashboardTrimmed, severityWarning, "", "%s only shows the first %d of %d services, as dashboards are limited to %d widgets and %d KB",
+				extensionDashboard, i, len(services), dashboardWidgetsLimit, dashboardBodyLimit/1024)
+			break
+		}
+	}
+
+	dashboard, err := body.render()
+	if err != nil {
+		return err
+	}
+	// dashboards aren't regional, so the region is part of the name for projects deployed in several regions
+	template.Resources["Dashboard"] = &cloudwatch.Dashboard{
+		DashboardBody: dashboard,
+		DashboardName: cloudformation.Sub(fmt.Sprintf("%s-${AWS::Region}", project.Name)),
+	}
+	return nil
+}
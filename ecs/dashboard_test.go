@@ -0,0 +1,109 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"gotest.tools/v3/assert"
+)
+
+// renderedDashboard returns the dashboard body of template, with values resolved by CloudFormation replaced by their
+// intrinsic function
+func renderedDashboard(t *testing.T, template *cloudformation.Template) map[string]interface{} {
+	raw, err := template.JSON()
+	assert.NilError(t, err)
+	var parsed struct {
+		Resources map[string]struct {
+			Properties struct {
+				DashboardBody map[string][]interface{}
+			}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &parsed))
+	join := parsed.Resources["Dashboard"].Properties.DashboardBody["Fn::Join"]
+	var body strings.Builder
+	for _, part := range join[1].([]interface{}) {
+		if s, ok := part.(string); ok {
+			body.WriteString(s)
+			continue
+		}
+		for fn := range part.(map[string]interface{}) {
+			body.WriteString(fn)
+		}
+	}
+	var dashboard map[string]interface{}
+	assert.NilError(t, json.Unmarshal([]byte(body.String()), &dashboard))
+	return dashboard
+}
+
+func TestDashboard(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  web:
+    image: nginx
+    ports:
+      - 80:80
+  db:
+    image: postgres
+x-aws-dashboard: true
+`)
+	dashboard := renderedDashboard(t, template)
+	var titles []string
+	for _, widget := range dashboard["widgets"].([]interface{}) {
+		titles = append(titles, widget.(map[string]interface{})["properties"].(map[string]interface{})["title"].(string))
+	}
+	assert.DeepEqual(t, titles, []string{
+		"Test logs",
+		"db CPU and memory utilization",
+		"web CPU and memory utilization",
+		"web requests and latency",
+	})
+	logs := dashboard["widgets"].([]interface{})[0].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, logs["query"], "SOURCE 'Ref' | fields @timestamp, @logStream, @message | sort @timestamp desc | limit 100")
+}
+
+func TestNoDashboard(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  web:
+    image: nginx
+`)
+	_, ok := template.Resources["Dashboard"]
+	assert.Check(t, !ok)
+}
+
+func TestDashboardTrimmed(t *testing.T) {
+	yaml := "x-aws-dashboard: true\nservices:\n"
+	for i := 0; i < 300; i++ {
+		yaml += fmt.Sprintf("  service%03d:\n    image: nginx\n", i)
+	}
+	project := loadConfig(t, yaml)
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+
+	widgets := renderedDashboard(t, template)["widgets"].([]interface{})
+	assert.Check(t, len(widgets) < 301)
+	assert.Equal(t, len(backend.warnings), 1)
+	assert.Equal(t, backend.warnings[0].Code, warningDashboardTrimmed)
+	assert.Equal(t, backend.warnings[0].Message, fmt.Sprintf("x-aws-dashboard only shows the first %d of 300 services, as dashboards are limited to 500 widgets and 100 KB", len(widgets)-1))
+}
@@ -0,0 +1,76 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// applyContextDefaults set extensions stored on the ECS context when compose file doesn't declare them.
+// Compose file values always win.
+func (b *ecsAPIService) applyContextDefaults(project *types.Project) error {
+	if project.Extensions == nil {
+		project.Extensions = map[string]interface{}{}
+	}
+	if err := checkUserTags(project); err != nil {
+		return err
+	}
+	b.applyContextDefault(project, extensionCluster, b.ctx.Cluster)
+	b.applyContextDefault(project, extensionVPC, b.ctx.VPC)
+
+	if len(b.ctx.Tags) == 0 {
+		return nil
+	}
+	fileTags, _ := project.Extensions[extensionTags].(map[string]interface{})
+	merged := map[string]interface{}{}
+	var fromContext, fromFile []string
+	for k, v := range b.ctx.Tags {
+		if _, ok := fileTags[k]; !ok {
+			fromContext = append(fromContext, k)
+		}
+		merged[k] = v
+	}
+	for k, v := range fileTags {
+		merged[k] = v
+		fromFile = append(fromFile, k)
+	}
+	sort.Strings(fromFile)
+	sort.Strings(fromContext)
+	if len(fromContext) > 0 {
+		b.warn(warningContextDefault, severityInfo, "", "%s %s set by context", extensionTags, strings.Join(fromContext, ", "))
+	}
+	if len(fromFile) > 0 {
+		b.warn(warningContextDefault, severityInfo, "", "%s %s set by compose file", extensionTags, strings.Join(fromFile, ", "))
+	}
+	project.Extensions[extensionTags] = merged
+	return nil
+}
+
+func (b *ecsAPIService) applyContextDefault(project *types.Project, extension string, value string) {
+	if value == "" {
+		return
+	}
+	if x, ok := project.Extensions[extension]; ok {
+		b.warn(warningContextDefault, severityInfo, "", "%s %s set by compose file, context default %s is ignored", extension, x, value)
+		return
+	}
+	b.warn(warningContextDefault, severityInfo, "", "%s %s set by context", extension, value)
+	project.Extensions[extension] = value
+}
@@ -0,0 +1,113 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/context/store"
+)
+
+func TestContextDefaults(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+x-aws-vpc: vpc-file
+x-aws-tags:
+  team: backend
+`)
+	backend := &ecsAPIService{
+		ctx: store.EcsContext{
+			Cluster: "cluster-context",
+			VPC:     "vpc-context",
+			Tags: map[string]string{
+				"team":        "platform",
+				"cost-center": "1234",
+			},
+		},
+	}
+	assert.NilError(t, backend.applyContextDefaults(project))
+	assert.Equal(t, project.Extensions[extensionCluster], "cluster-context")
+	assert.Equal(t, project.Extensions[extensionVPC], "vpc-file")
+	resourceTags := map[string]string{}
+	for _, tag := range projectTags(project) {
+		resourceTags[tag.Key] = tag.Value
+	}
+	assert.DeepEqual(t, resourceTags, map[string]string{
+		compose.ProjectTag: "Test",
+		"cost-center":      "1234",
+		"team":             "backend",
+	})
+
+	var messages []string
+	for _, w := range backend.warnings {
+		assert.Equal(t, w.Code, warningContextDefault)
+		messages = append(messages, w.Message)
+	}
+	assert.DeepEqual(t, messages, []string{
+		"x-aws-cluster cluster-context set by context",
+		"x-aws-vpc vpc-file set by compose file, context default vpc-context is ignored",
+		"x-aws-tags cost-center set by context",
+		"x-aws-tags team set by compose file",
+	})
+}
+
+func TestContextDefaultsInvalidTags(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+x-aws-tags: backend
+`)
+	backend := &ecsAPIService{ctx: store.EcsContext{Tags: map[string]string{"team": "platform"}}}
+	assert.Error(t, backend.applyContextDefaults(project), "x-aws-tags must be a mapping of tag keys to values")
+	_, err := backend.convert(project, awsResources{})
+	assert.Error(t, err, "x-aws-tags must be a mapping of tag keys to values")
+}
+
+// answers is a prompt.UI replying to inputs by message
+type answers map[string]string
+
+func (a answers) Select(message string, options []string) (int, error) { return 0, nil }
+func (a answers) Input(message string, defaultValue string) (string, error) {
+	if answer, ok := a[message]; ok {
+		return answer, nil
+	}
+	return defaultValue, nil
+}
+func (a answers) Confirm(message string, defaultValue bool) (bool, error) { return defaultValue, nil }
+func (a answers) Password(message string) (string, error)                 { return "", nil }
+
+func TestAskContextDefaults(t *testing.T) {
+	h := contextCreateAWSHelper{user: answers{
+		"Default ECS cluster (leave empty to create one per project)": " shared ",
+		"Default tags, as comma separated key=value pairs":            "team=platform, cost-center=1234",
+	}}
+	ctx := store.EcsContext{VPC: "vpc-123"}
+	assert.NilError(t, h.askDefaults(&ctx))
+	assert.Equal(t, ctx.Cluster, "shared")
+	assert.Equal(t, ctx.VPC, "vpc-123")
+	assert.DeepEqual(t, ctx.Tags, map[string]string{"team": "platform", "cost-center": "1234"})
+
+	_, err := parseTagsInput("team")
+	assert.Error(t, err, `invalid tag "team", must be key=value`)
+	assert.Equal(t, formatTagsInput(ctx.Tags), "cost-center=1234,team=platform")
+}
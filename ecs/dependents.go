@@ -0,0 +1,134 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// stackDependent is a resource outside of a stack which relies on one of the stack resources
+type stackDependent struct {
+	Dependent  string
+	References string
+}
+
+func (d stackDependent) String() string {
+	return fmt.Sprintf("%s references %s", d.Dependent, d.References)
+}
+
+// securityGroupReference is a security group which rules reference other security groups
+type securityGroupReference struct {
+	GroupID    string
+	Project    string
+	References []string
+}
+
+// cloudMapService is a service registered in a Cloud Map namespace
+type cloudMapService struct {
+	ID      string
+	ARN     string
+	Name    string
+	Project string
+}
+
+func owner(project string) string {
+	if project == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (project %s)", project)
+}
+
+// stackDependents lists the resources from other stacks and projects which would break if stack was deleted:
+// stacks importing its exports, security groups referencing its security groups and services registered in its Cloud Map namespace
+func (b *ecsAPIService) stackDependents(ctx context.Context, stack string, resources stackResources) ([]stackDependent, error) {
+	var dependents []stackDependent
+
+	exports, err := b.SDK.ListStackExports(ctx, stack)
+	if err != nil {
+		return nil, err
+	}
+	for _, export := range exports {
+		stacks, err := b.SDK.ListImports(ctx, export)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stacks {
+			if s == stack {
+				continue
+			}
+			dependents = append(dependents, stackDependent{
+				Dependent:  "stack " + s,
+				References: "export " + export,
+			})
+		}
+	}
+
+	owned := map[string]bool{}
+	var groups, namespaces []string
+	for _, r := range resources {
+		owned[r.ARN] = true
+		switch r.Type {
+		case awsTypeSecurityGroup:
+			groups = append(groups, r.ARN)
+		case awsTypeCloudMap:
+			namespaces = append(namespaces, r.ARN)
+		}
+	}
+
+	if len(groups) > 0 {
+		references, err := b.SDK.GetSecurityGroupReferences(ctx, groups)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range references {
+			if owned[r.GroupID] {
+				continue
+			}
+			dependents = append(dependents, stackDependent{
+				Dependent:  fmt.Sprintf("security group %s%s", r.GroupID, owner(r.Project)),
+				References: "security group " + strings.Join(r.References, ", "),
+			})
+		}
+	}
+
+	for _, namespace := range namespaces {
+		services, err := b.SDK.ListNamespaceServices(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range services {
+			if owned[s.ID] || owned[s.ARN] {
+				continue
+			}
+			dependents = append(dependents, stackDependent{
+				Dependent:  fmt.Sprintf("Cloud Map service %s%s", s.Name, owner(s.Project)),
+				References: "namespace " + namespace,
+			})
+		}
+	}
+	return dependents, nil
+}
+
+func dependentsError(stack string, dependents []stackDependent) error {
+	lines := make([]string, len(dependents))
+	for i, d := range dependents {
+		lines[i] = "  - " + d.String()
+	}
+	return fmt.Errorf("refusing to delete %s as other projects depend on it:\n%s\nuse --force to delete it anyway", stack, strings.Join(lines, "\n"))
+}
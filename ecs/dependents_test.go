@@ -0,0 +1,181 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/servicediscovery"
+	"github.com/aws/aws-sdk-go/service/servicediscovery/servicediscoveryiface"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
+)
+
+func TestDownRefusedWhenOtherProjectsDependOnStack(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "front").Return(&cloudformation.ListStackResourcesOutput{
+		StackResourceSummaries: []*cloudformation.StackResourceSummary{
+			{LogicalResourceId: aws.String("DefaultNetwork"), ResourceType: aws.String(awsTypeSecurityGroup), PhysicalResourceId: aws.String("sg-front")},
+			{LogicalResourceId: aws.String("CloudMap"), ResourceType: aws.String(awsTypeCloudMap), PhysicalResourceId: aws.String("ns-front")},
+			{LogicalResourceId: aws.String("WebServiceDiscoveryEntry"), ResourceType: aws.String(awsTypeCloudMapService), PhysicalResourceId: aws.String("srv-web")},
+		},
+	}, nil)
+	cf.On("DescribeStacksWithContext", "front").Return(&cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{
+			{
+				Outputs: []*cloudformation.Output{
+					{OutputKey: aws.String("Vpc"), ExportName: aws.String("front-vpc")},
+					{OutputKey: aws.String("Unexported")},
+				},
+			},
+		},
+	}, nil)
+	cf.On("ListImportsWithContext", "front-vpc").Return(&cloudformation.ListImportsOutput{
+		Imports: aws.StringSlice([]string{"back"}),
+	}, nil)
+
+	ec2Mock := &mockEC2{}
+	ec2Mock.On("DescribeSecurityGroupsPagesWithContext", "ip-permission.group-id").Return(&ec2.DescribeSecurityGroupsOutput{
+		SecurityGroups: []*ec2.SecurityGroup{
+			{
+				GroupId: aws.String("sg-front"),
+				IpPermissions: []*ec2.IpPermission{
+					{UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-front")}}},
+				},
+			},
+			{
+				GroupId: aws.String("sg-back"),
+				Tags:    []*ec2.Tag{{Key: aws.String(compose.ProjectTag), Value: aws.String("back")}},
+				IpPermissions: []*ec2.IpPermission{
+					{UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-front")}}},
+				},
+			},
+		},
+	}, nil)
+	ec2Mock.On("DescribeSecurityGroupsPagesWithContext", "egress.ip-permission.group-id").Return(&ec2.DescribeSecurityGroupsOutput{}, nil)
+
+	sd := &mockServiceDiscovery{}
+	sd.On("ListServicesPagesWithContext", "ns-front").Return(&servicediscovery.ListServicesOutput{
+		Services: []*servicediscovery.ServiceSummary{
+			{Id: aws.String("srv-web"), Arn: aws.String("arn:aws:servicediscovery:eu-west-3:123456789012:service/srv-web"), Name: aws.String("web")},
+			{Id: aws.String("srv-api"), Arn: aws.String("arn:aws:servicediscovery:eu-west-3:123456789012:service/srv-api"), Name: aws.String("api")},
+		},
+	}, nil)
+	sd.On("ListTagsForResourceWithContext", "arn:aws:servicediscovery:eu-west-3:123456789012:service/srv-web").Return(&servicediscovery.ListTagsForResourceOutput{
+		Tags: []*servicediscovery.Tag{{Key: aws.String(compose.ProjectTag), Value: aws.String("front")}},
+	}, nil)
+	sd.On("ListTagsForResourceWithContext", "arn:aws:servicediscovery:eu-west-3:123456789012:service/srv-api").Return(&servicediscovery.ListTagsForResourceOutput{
+		Tags: []*servicediscovery.Tag{{Key: aws.String(compose.ProjectTag), Value: aws.String("api")}},
+	}, nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cf, EC2: ec2Mock, SD: sd}}
+	err := backend.Down(context.TODO(), "front", compose.DownOptions{})
+	assert.Error(t, err, `refusing to delete front as other projects depend on it:
+  - stack back references export front-vpc
+  - security group sg-back (project back) references security group sg-front
+  - Cloud Map service api (project api) references namespace ns-front
+use --force to delete it anyway`)
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeValidation)
+	cf.AssertNotCalled(t, "DeleteStackWithContext", mock.Anything)
+}
+
+func TestListImportsOfUnusedExport(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("ListImportsWithContext", "front-vpc").Return((*cloudformation.ListImportsOutput)(nil),
+		errors.New("ValidationError: Export 'front-vpc' is not imported by any stack."))
+	stacks, err := sdk{CF: cf}.ListImports(context.TODO(), "front-vpc")
+	assert.NilError(t, err)
+	assert.Equal(t, len(stacks), 0)
+}
+
+type mockCloudFormation struct {
+	cloudformationiface.CloudFormationAPI
+	mock.Mock
+}
+
+func (m *mockCloudFormation) ListStackResourcesWithContext(_ aws.Context, in *cloudformation.ListStackResourcesInput, _ ...request.Option) (*cloudformation.ListStackResourcesOutput, error) {
+	args := m.Called(aws.StringValue(in.StackName))
+	return args.Get(0).(*cloudformation.ListStackResourcesOutput), args.Error(1)
+}
+
+func (m *mockCloudFormation) DescribeStacksWithContext(_ aws.Context, in *cloudformation.DescribeStacksInput, _ ...request.Option) (*cloudformation.DescribeStacksOutput, error) {
+	args := m.Called(aws.StringValue(in.StackName))
+	return args.Get(0).(*cloudformation.DescribeStacksOutput), args.Error(1)
+}
+
+func (m *mockCloudFormation) ListImportsWithContext(_ aws.Context, in *cloudformation.ListImportsInput, _ ...request.Option) (*cloudformation.ListImportsOutput, error) {
+	args := m.Called(aws.StringValue(in.ExportName))
+	return args.Get(0).(*cloudformation.ListImportsOutput), args.Error(1)
+}
+
+type mockEC2 struct {
+	ec2iface.EC2API
+	mock.Mock
+}
+
+func (m *mockEC2) DescribeSecurityGroupsPagesWithContext(_ aws.Context, in *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool, _ ...request.Option) error {
+	args := m.Called(aws.StringValue(in.Filters[0].Name))
+	fn(args.Get(0).(*ec2.DescribeSecurityGroupsOutput), true)
+	return args.Error(1)
+}
+
+type mockServiceDiscovery struct {
+	servicediscoveryiface.ServiceDiscoveryAPI
+	mock.Mock
+}
+
+func (m *mockServiceDiscovery) ListServicesPagesWithContext(_ aws.Context, in *servicediscovery.ListServicesInput, fn func(*servicediscovery.ListServicesOutput, bool) bool, _ ...request.Option) error {
+	args := m.Called(aws.StringValue(in.Filters[0].Values[0]))
+	fn(args.Get(0).(*servicediscovery.ListServicesOutput), true)
+	return args.Error(1)
+}
+
+func (m *mockServiceDiscovery) ListTagsForResourceWithContext(_ aws.Context, in *servicediscovery.ListTagsForResourceInput, _ ...request.Option) (*servicediscovery.ListTagsForResourceOutput, error) {
+	args := m.Called(aws.StringValue(in.ResourceARN))
+	return args.Get(0).(*servicediscovery.ListTagsForResourceOutput), args.Error(1)
+}
+
+func (m *mockCloudFormation) CreateChangeSetWithContext(_ aws.Context, in *cloudformation.CreateChangeSetInput, _ ...request.Option) (*cloudformation.CreateChangeSetOutput, error) {
+	args := m.Called(aws.StringValue(in.StackName))
+	return args.Get(0).(*cloudformation.CreateChangeSetOutput), args.Error(1)
+}
+
+func (m *mockCloudFormation) WaitUntilChangeSetCreateCompleteWithContext(_ aws.Context, in *cloudformation.DescribeChangeSetInput, _ ...request.WaiterOption) error {
+	args := m.Called(aws.StringValue(in.ChangeSetName))
+	return args.Error(0)
+}
+
+func (m *mockCloudFormation) DescribeChangeSetWithContext(_ aws.Context, in *cloudformation.DescribeChangeSetInput, _ ...request.Option) (*cloudformation.DescribeChangeSetOutput, error) {
+	args := m.Called(aws.StringValue(in.ChangeSetName))
+	return args.Get(0).(*cloudformation.DescribeChangeSetOutput), args.Error(1)
+}
+
+func (m *mockCloudFormation) DeleteChangeSetWithContext(_ aws.Context, in *cloudformation.DeleteChangeSetInput, _ ...request.Option) (*cloudformation.DeleteChangeSetOutput, error) {
+	args := m.Called(aws.StringValue(in.ChangeSetName))
+	return &cloudformation.DeleteChangeSetOutput{}, args.Error(0)
+}
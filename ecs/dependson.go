@@ -0,0 +1,72 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// serviceDependencies returns the ECS services the task of a service depends on. CloudFormation only creates a service once
+// the ones it depends on are stable, which for service_healthy requires a health check telling ECS when tasks are healthy.
+// Dependencies between containers of the same task are set by setSidecarDependencies
+func serviceDependencies(project *types.Project, service types.ServiceConfig) ([]string, error) {
+	var dependsOn []string
+	seen := map[string]bool{}
+	for _, member := range taskServices(project, service) {
+		for _, name := range sortedDependencies(member) {
+			switch condition := member.DependsOn[name].Condition; condition {
+			case "", types.ServiceConditionStarted:
+			case types.ServiceConditionHealthy:
+				upstream, err := project.GetService(name)
+				if err != nil {
+					return nil, err
+				}
+				healthCheck, err := toHealthCheck(upstream)
+				if err != nil {
+					return nil, err
+				}
+				if healthCheck == nil && len(upstream.Ports) == 0 {
+					return nil, fmt.Errorf("service %s depends on %s being healthy, but %s has neither a healthcheck nor ports exposed by load balancer", member.Name, name, name)
+				}
+			default:
+				return nil, fmt.Errorf("service %s depends on %s with unsupported condition %q", member.Name, name, condition)
+			}
+			owner := taskOwner(project, name)
+			if owner == service.Name || seen[owner] {
+				continue
+			}
+			if upstream, err := project.GetService(owner); err == nil && isScheduled(upstream) {
+				return nil, fmt.Errorf("service %s depends on %s, which only runs on schedule", member.Name, name)
+			}
+			seen[owner] = true
+			dependsOn = append(dependsOn, serviceResourceName(owner))
+		}
+	}
+	return dependsOn, nil
+}
+
+func sortedDependencies(service types.ServiceConfig) []string {
+	var names []string
+	for name := range service.DependsOn {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,88 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+// privateNetworks are the RFC 1918 address ranges, the only ones DNS records get exported for
+var privateNetworks = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}
+
+func isPrivateIP(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, cidr := range privateNetworks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err == nil && network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *ecsAPIService) DNSRecords(ctx context.Context, project string) ([]compose.DNSRecord, error) {
+	resources, err := b.SDK.ListStackResources(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	namespace := ""
+	for _, r := range resources {
+		if r.Type == awsTypeCloudMap {
+			namespace = r.ARN
+		}
+	}
+	if namespace == "" {
+		return nil, fmt.Errorf("project %s has no Cloud Map namespace", project)
+	}
+
+	services, err := b.SDK.ListNamespaceServices(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	records := []compose.DNSRecord{}
+	for _, service := range services {
+		ips, err := b.SDK.ListInstanceIPs(ctx, service.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if !isPrivateIP(ip) {
+				continue
+			}
+			records = append(records, compose.DNSRecord{
+				Service: service.Name,
+				Name:    fmt.Sprintf("%s.%s.local", service.Name, project),
+				IP:      ip,
+			})
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		return records[i].IP < records[j].IP
+	})
+	return records, nil
+}
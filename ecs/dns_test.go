@@ -0,0 +1,92 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/servicediscovery"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+func TestDNSRecords(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "front").Return(&cloudformation.ListStackResourcesOutput{
+		StackResourceSummaries: []*cloudformation.StackResourceSummary{
+			{LogicalResourceId: aws.String("CloudMap"), ResourceType: aws.String(awsTypeCloudMap), PhysicalResourceId: aws.String("ns-front")},
+		},
+	}, nil)
+	sd := &mockServiceDiscovery{}
+	sd.On("ListServicesPagesWithContext", "ns-front").Return(&servicediscovery.ListServicesOutput{
+		Services: []*servicediscovery.ServiceSummary{
+			{Id: aws.String("srv-web"), Arn: aws.String("arn:aws:servicediscovery:eu-west-3:123456789012:service/srv-web"), Name: aws.String("web")},
+			{Id: aws.String("srv-db"), Arn: aws.String("arn:aws:servicediscovery:eu-west-3:123456789012:service/srv-db"), Name: aws.String("db")},
+		},
+	}, nil)
+	sd.On("ListTagsForResourceWithContext", "arn:aws:servicediscovery:eu-west-3:123456789012:service/srv-web").Return(&servicediscovery.ListTagsForResourceOutput{}, nil)
+	sd.On("ListTagsForResourceWithContext", "arn:aws:servicediscovery:eu-west-3:123456789012:service/srv-db").Return(&servicediscovery.ListTagsForResourceOutput{}, nil)
+	sd.On("ListInstancesPagesWithContext", "srv-web").Return(&servicediscovery.ListInstancesOutput{
+		Instances: []*servicediscovery.InstanceSummary{
+			{Attributes: aws.StringMap(map[string]string{"AWS_INSTANCE_IPV4": "10.0.1.12"})},
+			{Attributes: aws.StringMap(map[string]string{"AWS_INSTANCE_IPV4": "52.47.1.2"})},
+			{Attributes: aws.StringMap(map[string]string{"AWS_INSTANCE_PORT": "80"})},
+		},
+	}, nil)
+	sd.On("ListInstancesPagesWithContext", "srv-db").Return(&servicediscovery.ListInstancesOutput{
+		Instances: []*servicediscovery.InstanceSummary{
+			{Attributes: aws.StringMap(map[string]string{"AWS_INSTANCE_IPV4": "172.31.4.7"})},
+		},
+	}, nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cf, SD: sd}}
+	records, err := backend.DNSRecords(context.TODO(), "front")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, records, []compose.DNSRecord{
+		{Service: "db", Name: "db.front.local", IP: "172.31.4.7"},
+		{Service: "web", Name: "web.front.local", IP: "10.0.1.12"},
+	})
+}
+
+func TestDNSRecordsWithoutNamespace(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "front").Return(&cloudformation.ListStackResourcesOutput{}, nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cf}}
+	_, err := backend.DNSRecords(context.TODO(), "front")
+	assert.Error(t, err, "project front has no Cloud Map namespace")
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	assert.Check(t, isPrivateIP("10.1.2.3"))
+	assert.Check(t, isPrivateIP("172.16.0.1"))
+	assert.Check(t, isPrivateIP("192.168.1.1"))
+	assert.Check(t, !isPrivateIP("172.32.0.1"))
+	assert.Check(t, !isPrivateIP("8.8.8.8"))
+	assert.Check(t, !isPrivateIP("not-an-ip"))
+}
+
+func (m *mockServiceDiscovery) ListInstancesPagesWithContext(_ aws.Context, in *servicediscovery.ListInstancesInput, fn func(*servicediscovery.ListInstancesOutput, bool) bool, _ ...request.Option) error {
+	args := m.Called(aws.StringValue(in.ServiceId))
+	fn(args.Get(0).(*servicediscovery.ListInstancesOutput), true)
+	return args.Error(1)
+}
@@ -18,36 +18,83 @@ package ecs
 
 import (
 	"context"
+	"time"
 
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
 	"github.com/docker/compose-cli/progress"
 )
 
-func (b *ecsAPIService) Down(ctx context.Context, project string) error {
+func (b *ecsAPIService) Down(ctx context.Context, project string, options compose.DownOptions) error {
+	start := time.Now()
 	resources, err := b.SDK.ListStackResources(ctx, project)
 	if err != nil {
-		return err
+		return classify(err, errdefs.ErrValidation)
+	}
+
+	if !options.Force {
+		dependents, err := b.stackDependents(ctx, project, resources)
+		if err != nil {
+			return classify(err, errdefs.ErrDeploymentFailed)
+		}
+		if len(dependents) > 0 {
+			return classify(dependentsError(project, dependents), errdefs.ErrValidation)
+		}
+	}
+
+	protected, err := b.SDK.GetStackTerminationProtection(ctx, project)
+	if err != nil {
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+	if protected && !options.Unprotect {
+		return classify(&compose.ProtectedStackError{Stack: project, Region: b.Region}, errdefs.ErrValidation)
+	}
+	topics, err := b.SDK.GetStackNotificationARNs(ctx, project)
+	if err != nil {
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+
+	err = b.prepareVolumesDeletion(ctx, project, resources, options)
+	if err != nil {
+		return classify(err, errdefs.ErrValidation)
+	}
+
+	if protected {
+		err = b.SDK.UpdateTerminationProtection(ctx, project, false)
+		if err != nil {
+			return classify(err, errdefs.ErrDeploymentFailed)
+		}
 	}
 
 	err = resources.apply(awsTypeCapacityProvider, delete(ctx, b.SDK.DeleteCapacityProvider))
 	if err != nil {
-		return err
+		return classify(err, errdefs.ErrDeploymentFailed)
 	}
 
 	err = resources.apply(awsTypeAutoscalingGroup, delete(ctx, b.SDK.DeleteAutoscalingGroup))
 	if err != nil {
-		return err
+		return classify(err, errdefs.ErrDeploymentFailed)
 	}
 
 	previousEvents, err := b.previousStackEvents(ctx, project)
 	if err != nil {
-		return err
+		return classify(err, errdefs.ErrDeploymentFailed)
 	}
 
 	err = b.SDK.DeleteStack(ctx, project)
 	if err != nil {
-		return err
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+	err = b.WaitStackCompletion(ctx, project, stackDelete, previousEvents...)
+	if err != nil {
+		b.notifyDeployment(ctx, topics, newDeletionNotification(project, start, deploymentFailed, err))
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+	b.notifyDeployment(ctx, topics, newDeletionNotification(project, start, deploymentSucceeded, nil))
+	if resources.hasType(awsTypeStack) {
+		err = b.removeNestedTemplates(ctx, project)
 	}
-	return b.WaitStackCompletion(ctx, project, stackDelete, previousEvents...)
+	return classify(err, errdefs.ErrDeploymentFailed)
 }
 
 func (b *ecsAPIService) previousStackEvents(ctx context.Context, project string) ([]string, error) {
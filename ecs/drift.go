@@ -0,0 +1,35 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+func (b *ecsAPIService) Drift(ctx context.Context, project string) ([]compose.DriftedResource, error) {
+	drifted, err := b.SDK.DetectStackDrift(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(drifted, func(i, j int) bool {
+		return drifted[i].ID < drifted[j].ID
+	})
+	return drifted, nil
+}
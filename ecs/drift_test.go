@@ -0,0 +1,109 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+func (m *mockCloudFormation) DetectStackDriftWithContext(_ aws.Context, in *cloudformation.DetectStackDriftInput, _ ...request.Option) (*cloudformation.DetectStackDriftOutput, error) {
+	args := m.Called(aws.StringValue(in.StackName))
+	return args.Get(0).(*cloudformation.DetectStackDriftOutput), args.Error(1)
+}
+
+func (m *mockCloudFormation) DescribeStackDriftDetectionStatusWithContext(_ aws.Context, in *cloudformation.DescribeStackDriftDetectionStatusInput, _ ...request.Option) (*cloudformation.DescribeStackDriftDetectionStatusOutput, error) {
+	args := m.Called(aws.StringValue(in.StackDriftDetectionId))
+	return args.Get(0).(*cloudformation.DescribeStackDriftDetectionStatusOutput), args.Error(1)
+}
+
+func (m *mockCloudFormation) DescribeStackResourceDriftsPagesWithContext(_ aws.Context, in *cloudformation.DescribeStackResourceDriftsInput, fn func(*cloudformation.DescribeStackResourceDriftsOutput, bool) bool, _ ...request.Option) error {
+	args := m.Called(aws.StringValue(in.StackName), aws.StringValueSlice(in.StackResourceDriftStatusFilters))
+	fn(args.Get(0).(*cloudformation.DescribeStackResourceDriftsOutput), true)
+	return args.Error(1)
+}
+
+func TestDrift(t *testing.T) {
+	interval := driftDetectionPollInterval
+	driftDetectionPollInterval = time.Millisecond
+	defer func() { driftDetectionPollInterval = interval }()
+
+	cf := &mockCloudFormation{}
+	cf.On("DetectStackDriftWithContext", "test").Return(&cloudformation.DetectStackDriftOutput{
+		StackDriftDetectionId: aws.String("detection"),
+	}, nil)
+	cf.On("DescribeStackDriftDetectionStatusWithContext", "detection").Return(&cloudformation.DescribeStackDriftDetectionStatusOutput{
+		DetectionStatus: aws.String(cloudformation.StackDriftDetectionStatusDetectionInProgress),
+	}, nil).Once()
+	cf.On("DescribeStackDriftDetectionStatusWithContext", "detection").Return(&cloudformation.DescribeStackDriftDetectionStatusOutput{
+		DetectionStatus: aws.String(cloudformation.StackDriftDetectionStatusDetectionComplete),
+	}, nil)
+	cf.On("DescribeStackResourceDriftsPagesWithContext", "test", []string{"MODIFIED", "DELETED"}).Return(&cloudformation.DescribeStackResourceDriftsOutput{
+		StackResourceDrifts: []*cloudformation.StackResourceDrift{
+			{
+				LogicalResourceId:        aws.String("WebTaskDefinition"),
+				PhysicalResourceId:       aws.String("arn:aws:ecs:eu-west-3:123456789012:task-definition/test-web:3"),
+				ResourceType:             aws.String("AWS::ECS::TaskDefinition"),
+				StackResourceDriftStatus: aws.String(cloudformation.StackResourceDriftStatusModified),
+				PropertyDifferences: []*cloudformation.PropertyDifference{
+					{
+						PropertyPath:   aws.String("/ContainerDefinitions/0/Memory"),
+						ExpectedValue:  aws.String("512"),
+						ActualValue:    aws.String("1024"),
+						DifferenceType: aws.String(cloudformation.DifferenceTypeNotEqual),
+					},
+				},
+			},
+			{
+				LogicalResourceId:        aws.String("DefaultNetwork"),
+				PhysicalResourceId:       aws.String("sg-123"),
+				ResourceType:             aws.String("AWS::EC2::SecurityGroup"),
+				StackResourceDriftStatus: aws.String(cloudformation.StackResourceDriftStatusDeleted),
+			},
+		},
+	}, nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cf}}
+	drifted, err := backend.Drift(context.TODO(), "test")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, drifted, []compose.DriftedResource{
+		{
+			ID:         "DefaultNetwork",
+			PhysicalID: "sg-123",
+			Type:       "AWS::EC2::SecurityGroup",
+			Status:     "DELETED",
+		},
+		{
+			ID:         "WebTaskDefinition",
+			PhysicalID: "arn:aws:ecs:eu-west-3:123456789012:task-definition/test-web:3",
+			Type:       "AWS::ECS::TaskDefinition",
+			Status:     "MODIFIED",
+			Differences: []compose.PropertyDifference{
+				{Path: "/ContainerDefinitions/0/Memory", Expected: "512", Actual: "1024", Type: "NOT_EQUAL"},
+			},
+		},
+	})
+	cf.AssertNumberOfCalls(t, "DescribeStackDriftDetectionStatusWithContext", 2)
+}
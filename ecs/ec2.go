@@ -18,83 +18,429 @@ package ecs
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
+	"strings"
 
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/awslabs/goformation/v4/cloudformation"
 	"github.com/awslabs/goformation/v4/cloudformation/autoscaling"
+	"github.com/awslabs/goformation/v4/cloudformation/ec2"
 	"github.com/awslabs/goformation/v4/cloudformation/ecs"
 	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/awslabs/goformation/v4/cloudformation/tags"
 	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/go-units"
+	"github.com/sirupsen/logrus"
 )
 
 func (b *ecsAPIService) createCapacityProvider(ctx context.Context, project *types.Project, template *cloudformation.Template, resources awsResources) error {
-	var ec2 bool
+	var ec2, gpu bool
 	for _, s := range project.Services {
 		if requireEC2(s) {
 			ec2 = true
-			break
+		}
+		if gpuRequirements(s) > 0 {
+			gpu = true
 		}
 	}
 
 	if !ec2 {
+		for _, extension := range []string{extensionAutoScalingGroup, extensionManagedScaling, extensionManagedTerminationProtection,
+			extensionEC2Spot, extensionEC2UserData, extensionEC2VolumeSize, extensionEC2VolumeType} {
+			if _, ok := project.Extensions[extension]; ok {
+				return fmt.Errorf("%s is set but no service requires EC2 capacity", extension)
+			}
+		}
+		return nil
+	}
+	if resources.capacityProvider != "" {
+		for _, extension := range []string{extensionManagedScaling, extensionManagedTerminationProtection,
+			extensionEC2Spot, extensionEC2UserData, extensionEC2VolumeSize, extensionEC2VolumeType} {
+			if _, ok := project.Extensions[extension]; ok {
+				return fmt.Errorf("%s can't be set with %s, which is managed outside of project", extension, extensionCapacityProvider)
+			}
+		}
+		// shared capacity provider is already associated with the cluster, and must outlive the stack
 		return nil
 	}
 
-	ami, err := b.SDK.GetParameter(ctx, "/aws/service/ecs/optimized-ami/amazon-linux-2/gpu/recommended")
+	scaling, err := capacityProviderScaling(project)
 	if err != nil {
 		return err
 	}
-
-	machineType, err := guessMachineType(project)
+	protection, err := managedTerminationProtection(project)
 	if err != nil {
 		return err
 	}
+	terminationProtection := ecsapi.ManagedTerminationProtectionDisabled
+	if protection {
+		terminationProtection = ecsapi.ManagedTerminationProtectionEnabled
+	}
+
+	group := resources.autoScalingGroup
+	for _, extension := range []string{extensionEC2Spot, extensionEC2UserData, extensionEC2VolumeSize, extensionEC2VolumeType} {
+		if _, ok := project.Extensions[extension]; ok && group != "" {
+			return fmt.Errorf("%s can't be set with %s, which launch configuration is managed outside of project", extension, extensionAutoScalingGroup)
+		}
+	}
+	if group == "" {
+		err = b.createAutoScalingGroup(ctx, project, template, resources, gpu, protection)
+		if err != nil {
+			return err
+		}
+		group = cloudformation.Ref("AutoscalingGroup")
+	}
+
+	template.Resources["CapacityProvider"] = &capacityProvider{
+		CapacityProvider: ecs.CapacityProvider{
+			AutoScalingGroupProvider: &ecs.CapacityProvider_AutoScalingGroupProvider{
+				AutoScalingGroupArn: group,
+				ManagedScaling: &ecs.CapacityProvider_ManagedScaling{
+					Status:                 ecsapi.ManagedScalingStatusEnabled,
+					TargetCapacity:         scaling.targetCapacity,
+					MinimumScalingStepSize: scaling.minStepSize,
+					MaximumScalingStepSize: scaling.maxStepSize,
+				},
+				ManagedTerminationProtection: terminationProtection,
+			},
+			Tags: projectTags(project),
+		},
+		InstanceWarmupPeriod: scaling.instanceWarmup,
+	}
+
+	cluster := template.Resources["Cluster"].(*ecs.Cluster)
+	cluster.CapacityProviders = append(cluster.CapacityProviders, cloudformation.Ref("CapacityProvider"))
+
+	return nil
+}
 
+// sharedCapacityProviderTag tags the stack of a capacity provider created for x-aws-capacity-provider, with its name
+const sharedCapacityProviderTag = "com.docker.compose.shared-capacity-provider"
+
+// sharedCapacityProvider is the missing capacity provider set by x-aws-capacity-provider, deployed by its own stack
+// so it outlives the projects sharing it
+type sharedCapacityProvider struct {
+	name     string
+	cluster  string
+	template []byte
+}
+
+// sharedCapacityProviderStack is the name of the stack deploying the shared capacity provider
+func sharedCapacityProviderStack(provider string) string {
+	// stack names don't accept underscores, which capacity provider names do
+	return "compose-capacity-provider-" + strings.ReplaceAll(provider, "_", "-")
+}
+
+// sharedCapacityProviderTemplate declares the shared capacity provider, with the Auto Scaling group it manages, launching
+// a machine type meeting project's requirements in its subnets
+func (b *ecsAPIService) sharedCapacityProviderTemplate(ctx context.Context, project *types.Project, resources awsResources) (sharedCapacityProvider, error) {
+	provider := fmt.Sprint(project.Extensions[extensionCapacityProvider])
+	var gpu bool
+	for _, s := range project.Services {
+		if gpuRequirements(s) > 0 {
+			gpu = true
+		}
+	}
+	template := cloudformation.NewTemplate()
+	// instances get the VPC default security group, as tasks use their own network interface and security groups
+	err := b.createAutoScalingGroup(ctx, project, template, awsResources{subnets: resources.subnets}, gpu, false)
+	if err != nil {
+		return sharedCapacityProvider{}, err
+	}
+	providerTags := []tags.Tag{{Key: sharedCapacityProviderTag, Value: provider}}
+	template.Resources["EC2InstanceRole"].(*iam.Role).Tags = providerTags
 	template.Resources["CapacityProvider"] = &ecs.CapacityProvider{
+		Name: provider,
 		AutoScalingGroupProvider: &ecs.CapacityProvider_AutoScalingGroupProvider{
 			AutoScalingGroupArn: cloudformation.Ref("AutoscalingGroup"),
 			ManagedScaling: &ecs.CapacityProvider_ManagedScaling{
+				Status:         ecsapi.ManagedScalingStatusEnabled,
 				TargetCapacity: 100,
 			},
+			ManagedTerminationProtection: ecsapi.ManagedTerminationProtectionDisabled,
 		},
-		Tags: projectTags(project),
+		Tags: providerTags,
+	}
+	raw, err := marshall(template)
+	if err != nil {
+		return sharedCapacityProvider{}, err
+	}
+	return sharedCapacityProvider{name: provider, cluster: resources.cluster, template: raw}, nil
+}
+
+// createSharedCapacityProvider deploys the missing shared capacity provider, unless a project sharing it concurrently
+// already does, then associates it with the cluster
+func (b *ecsAPIService) createSharedCapacityProvider(ctx context.Context) error {
+	provider := b.sharedProvider
+	if provider.template == nil {
+		return nil
+	}
+	stack := sharedCapacityProviderStack(provider.name)
+	exists, err := b.SDK.StackExists(ctx, stack)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		logrus.Infof("Creating shared capacity provider %s", provider.name)
+		err = b.SDK.CreateStack(ctx, stack, provider.template, map[string]string{sharedCapacityProviderTag: provider.name}, 0, true, "", nil)
+		if err != nil {
+			return err
+		}
+	}
+	err = b.SDK.WaitStackComplete(ctx, stack, stackCreate)
+	if err != nil {
+		return fmt.Errorf("failed to create shared capacity provider %s: %w", provider.name, err)
+	}
+	return b.SDK.AddClusterCapacityProvider(ctx, provider.cluster, provider.name)
+}
+
+// createAutoScalingGroup creates the Auto Scaling group backing project's capacity provider, unless x-aws-autoscaling_group
+// sets an existing one
+func (b *ecsAPIService) createAutoScalingGroup(ctx context.Context, project *types.Project, template *cloudformation.Template, resources awsResources, gpu bool, protection bool) error {
+	amiParameter := "/aws/service/ecs/optimized-ami/amazon-linux-2/recommended"
+	if gpu {
+		amiParameter = "/aws/service/ecs/optimized-ami/amazon-linux-2/gpu/recommended"
+	}
+	ami, err := b.SDK.GetParameter(ctx, amiParameter)
+	if err != nil {
+		return err
 	}
 
-	template.Resources["AutoscalingGroup"] = &autoscaling.AutoScalingGroup{
-		LaunchConfigurationName: cloudformation.Ref("LaunchConfiguration"),
-		MaxSize:                 "10", //TODO
-		MinSize:                 "1",
-		VPCZoneIdentifier:       resources.subnets,
+	spot, err := ec2Spot(project)
+	if err != nil {
+		return err
+	}
+	var machineType string
+	if spot != nil {
+		err = b.checkSpotInstanceTypes(ctx, project, spot)
+		machineType = spot.instanceTypes[0]
+	} else {
+		machineType, err = guessMachineType(project)
+	}
+	if err != nil {
+		return err
 	}
 
-	userData := base64.StdEncoding.EncodeToString([]byte(
-		fmt.Sprintf("#!/bin/bash\necho ECS_CLUSTER=%s >> /etc/ecs/ecs.config", project.Name)))
+	group := &autoscaling.AutoScalingGroup{
+		MaxSize:           "10", //TODO
+		MinSize:           "1",
+		VPCZoneIdentifier: resources.subnets,
+		// managed termination protection requires new instances to be protected, the capacity provider then
+		// removes protection from instances which don't run tasks anymore
+		NewInstancesProtectedFromScaleIn: protection,
+	}
+
+	userData, err := instanceUserData(project, spot != nil)
+	if err != nil {
+		return err
+	}
+	volume, err := instanceRootVolume(project)
+	if err != nil {
+		return err
+	}
 
-	template.Resources["LaunchConfiguration"] = &autoscaling.LaunchConfiguration{
-		ImageId:            ami,
-		InstanceType:       machineType,
-		SecurityGroups:     resources.allSecurityGroups(),
-		IamInstanceProfile: cloudformation.Ref("EC2InstanceProfile"),
-		UserData:           userData,
+	if spot != nil {
+		// mixed instances policies require a launch template
+		template.Resources["LaunchTemplate"] = &ec2.LaunchTemplate{
+			LaunchTemplateData: &ec2.LaunchTemplate_LaunchTemplateData{
+				BlockDeviceMappings: volume.launchTemplateMappings(),
+				ImageId:             ami,
+				InstanceType:        machineType,
+				SecurityGroupIds:    resources.allSecurityGroups(),
+				IamInstanceProfile: &ec2.LaunchTemplate_IamInstanceProfile{
+					Arn: cloudformation.GetAtt("EC2InstanceProfile", "Arn"),
+				},
+				UserData: userData,
+			},
+		}
+		group.MixedInstancesPolicy = spot.mixedInstancesPolicy("LaunchTemplate")
+		template.Resources["AutoscalingGroup"] = &spotAutoScalingGroup{AutoScalingGroup: *group}
+	} else {
+		group.LaunchConfigurationName = cloudformation.Ref("LaunchConfiguration")
+		template.Resources["AutoscalingGroup"] = group
+		template.Resources["LaunchConfiguration"] = &autoscaling.LaunchConfiguration{
+			BlockDeviceMappings: volume.launchConfigurationMappings(),
+			ImageId:             ami,
+			InstanceType:        machineType,
+			SecurityGroups:      resources.allSecurityGroups(),
+			IamInstanceProfile:  cloudformation.Ref("EC2InstanceProfile"),
+			UserData:            userData,
+		}
 	}
 
 	template.Resources["EC2InstanceProfile"] = &iam.InstanceProfile{
 		Roles: []string{cloudformation.Ref("EC2InstanceRole")},
 	}
 
+	boundary, path, err := roleSettings(project)
+	if err != nil {
+		return err
+	}
 	template.Resources["EC2InstanceRole"] = &iam.Role{
 		AssumeRolePolicyDocument: ec2InstanceAssumeRolePolicyDocument,
 		ManagedPolicyArns: []string{
-			ecsEC2InstanceRole,
+			b.partitionArn(ecsEC2InstanceRole),
 		},
-		Tags: projectTags(project),
+		PermissionsBoundary: boundary,
+		Path:                path,
+		Tags:                projectTags(project),
 	}
+	return nil
+}
 
-	cluster := template.Resources["Cluster"].(*ecs.Cluster)
-	cluster.CapacityProviders = []string{
-		cloudformation.Ref("CapacityProvider"),
+// managedScaling is set by x-aws-managed_scaling, tuning how the capacity provider scales its Auto Scaling group
+type managedScaling struct {
+	targetCapacity int
+	minStepSize    int
+	maxStepSize    int
+	instanceWarmup int
+}
+
+func capacityProviderScaling(project *types.Project) (managedScaling, error) {
+	scaling := managedScaling{targetCapacity: 100}
+	x, ok := project.Extensions[extensionManagedScaling]
+	if !ok {
+		return scaling, nil
+	}
+	m, ok := x.(map[string]interface{})
+	if !ok {
+		return scaling, fmt.Errorf("%s must be a mapping of managed scaling settings", extensionManagedScaling)
+	}
+	for key, value := range m {
+		i, ok := value.(int)
+		if !ok {
+			return scaling, fmt.Errorf("%s %s must be an integer", extensionManagedScaling, key)
+		}
+		var min, max int
+		switch key {
+		case "target_capacity":
+			scaling.targetCapacity, min, max = i, 1, 100
+		case "min_step_size":
+			scaling.minStepSize, min, max = i, 1, 10000
+		case "max_step_size":
+			scaling.maxStepSize, min, max = i, 1, 10000
+		case "instance_warmup":
+			scaling.instanceWarmup, min, max = i, 0, 10000
+		default:
+			return scaling, fmt.Errorf("unsupported %s attribute %s", extensionManagedScaling, key)
+		}
+		if i < min || i > max {
+			return scaling, fmt.Errorf("%s %s must be between %d and %d, got %d", extensionManagedScaling, key, min, max, i)
+		}
+	}
+	if scaling.minStepSize > 0 && scaling.maxStepSize > 0 && scaling.minStepSize > scaling.maxStepSize {
+		return scaling, fmt.Errorf("%s min_step_size %d is greater than max_step_size %d", extensionManagedScaling, scaling.minStepSize, scaling.maxStepSize)
+	}
+	return scaling, nil
+}
+
+// managedTerminationProtection is set by x-aws-managed_termination_protection, so the capacity provider prevents instances
+// running tasks from being terminated on scale in
+func managedTerminationProtection(project *types.Project) (bool, error) {
+	x, ok := project.Extensions[extensionManagedTerminationProtection]
+	if !ok {
+		return false, nil
+	}
+	enabled, ok := x.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean", extensionManagedTerminationProtection)
+	}
+	return enabled, nil
+}
+
+// parseAutoScalingGroupExtension checks the existing Auto Scaling group set by x-aws-autoscaling_group launches instances
+// in the project's VPC which can run its services, and returns its ARN
+func (b *ecsAPIService) parseAutoScalingGroupExtension(ctx context.Context, project *types.Project, vpc string, subnets []string) (string, error) {
+	x, ok := project.Extensions[extensionAutoScalingGroup]
+	if !ok {
+		return "", nil
+	}
+	name := fmt.Sprint(x)
+	if _, ok := project.Extensions[extensionCapacityProvider]; ok {
+		return "", fmt.Errorf("%s can't be set with %s, which already has an Auto Scaling group", extensionAutoScalingGroup, extensionCapacityProvider)
+	}
+	group, err := b.SDK.GetAutoScalingGroup(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if len(group.subnets) == 0 {
+		return "", fmt.Errorf("auto scaling group %s doesn't launch instances in VPC %s", name, vpc)
+	}
+	inVPC := map[string]bool{}
+	for _, subnet := range subnets {
+		inVPC[subnet] = true
+	}
+	for _, subnet := range group.subnets {
+		if !inVPC[subnet] {
+			return "", fmt.Errorf("auto scaling group %s subnet %s isn't in VPC %s", name, subnet, vpc)
+		}
+	}
+	protection, err := managedTerminationProtection(project)
+	if err != nil {
+		return "", err
+	}
+	if protection && !group.protectedFromScaleIn {
+		return "", fmt.Errorf("%s requires auto scaling group %s to protect new instances from scale in", extensionManagedTerminationProtection, name)
+	}
+
+	requirements, err := getResourceRequirements(project)
+	if err != nil {
+		return "", err
+	}
+	instanceTypes, err := b.SDK.GetAutoScalingGroupInstanceTypes(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	machines, err := b.SDK.DescribeInstanceTypes(ctx, instanceTypes)
+	if err != nil {
+		return "", err
 	}
+	err = checkMachines("auto scaling group "+name, machines, requirements)
+	if err != nil {
+		return "", err
+	}
+	return group.arn, nil
+}
+
+// capacityProvider is a CapacityProvider which managed scaling sets an instance warmup period, not supported by goformation
+type capacityProvider struct {
+	ecs.CapacityProvider
+	InstanceWarmupPeriod int
+}
 
+func (r capacityProvider) MarshalJSON() ([]byte, error) {
+	return marshalResource(r.CapacityProvider, func(properties map[string]interface{}) {
+		if r.InstanceWarmupPeriod == 0 {
+			return
+		}
+		if provider, ok := properties["AutoScalingGroupProvider"].(map[string]interface{}); ok {
+			if scaling, ok := provider["ManagedScaling"].(map[string]interface{}); ok {
+				scaling["InstanceWarmupPeriod"] = r.InstanceWarmupPeriod
+			}
+		}
+	})
+}
+
+// checkMachines fails if an instance type the capacity provider, or Auto Scaling group, can launch doesn't meet project's requirements
+func checkMachines(source string, machines []machine, requirements *resourceRequirements) error {
+	var mismatches []string
+	for _, m := range machines {
+		var missing []string
+		if m.memory <= requirements.memory {
+			missing = append(missing, fmt.Sprintf("memory %s <= %s", units.BytesSize(float64(m.memory)), units.BytesSize(float64(requirements.memory))))
+		}
+		if m.cpus < requirements.cpus {
+			missing = append(missing, fmt.Sprintf("cpus %g < %g", m.cpus, requirements.cpus))
+		}
+		if m.gpus < requirements.gpus {
+			missing = append(missing, fmt.Sprintf("gpus %d < %d", m.gpus, requirements.gpus))
+		}
+		if len(missing) > 0 {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %s", m.id, strings.Join(missing, ", ")))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%s instance types don't meet services requirements:\n%s", source, strings.Join(mismatches, "\n"))
+	}
 	return nil
 }
@@ -0,0 +1,428 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	cfapi "github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+)
+
+const sharedCapacityProviderYaml = `
+services:
+  test:
+    image: hello_world
+    deploy:
+      resources:
+        reservations:
+          memory: 8G
+          generic_resources:
+            - discrete_resource_spec:
+                kind: gpus
+                value: 1
+x-aws-cluster: shared
+x-aws-capacity-provider: shared-gpu
+`
+
+func sharedCapacityProviderBackend(instanceType *ec2.InstanceTypeInfo) *ecsAPIService {
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeCapacityProvidersWithContext", "shared-gpu").Return(&ecsapi.DescribeCapacityProvidersOutput{
+		CapacityProviders: []*ecsapi.CapacityProvider{
+			{
+				Name:   aws.String("shared-gpu"),
+				Status: aws.String(ecsapi.CapacityProviderStatusActive),
+				AutoScalingGroupProvider: &ecsapi.AutoScalingGroupProvider{
+					AutoScalingGroupArn: aws.String("arn:aws:autoscaling:eu-west-3:123456789012:autoScalingGroup:uuid:autoScalingGroupName/gpu"),
+				},
+			},
+		},
+	}, nil)
+	ecsMock.On("DescribeClustersWithContext", "shared").Return(&ecsapi.DescribeClustersOutput{
+		Clusters: []*ecsapi.Cluster{
+			{CapacityProviders: aws.StringSlice([]string{"FARGATE", "shared-gpu"})},
+		},
+	}, nil)
+	ag := &mockAutoScaling{}
+	ag.On("DescribeAutoScalingGroupsWithContext", "gpu").Return(&autoscaling.DescribeAutoScalingGroupsOutput{
+		AutoScalingGroups: []*autoscaling.Group{
+			{LaunchConfigurationName: aws.String("gpu-lc")},
+		},
+	}, nil)
+	ag.On("DescribeLaunchConfigurationsWithContext", "gpu-lc").Return(&autoscaling.DescribeLaunchConfigurationsOutput{
+		LaunchConfigurations: []*autoscaling.LaunchConfiguration{
+			{InstanceType: instanceType.InstanceType},
+		},
+	}, nil)
+	ec2Mock := &mockEC2{}
+	ec2Mock.On("DescribeInstanceTypesWithContext", aws.StringValue(instanceType.InstanceType)).Return(&ec2.DescribeInstanceTypesOutput{
+		InstanceTypes: []*ec2.InstanceTypeInfo{instanceType},
+	}, nil)
+	return &ecsAPIService{SDK: sdk{ECS: ecsMock, AG: ag, EC2: ec2Mock}}
+}
+
+func TestSharedCapacityProvider(t *testing.T) {
+	project := loadConfig(t, sharedCapacityProviderYaml)
+	backend := sharedCapacityProviderBackend(&ec2.InstanceTypeInfo{
+		InstanceType: aws.String("g4dn.xlarge"),
+		VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(4)},
+		MemoryInfo:   &ec2.MemoryInfo{SizeInMiB: aws.Int64(16384)},
+		GpuInfo:      &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{{Count: aws.Int64(1)}}},
+	})
+	provider, create, err := backend.parseCapacityProviderExtension(context.TODO(), project, "shared")
+	assert.NilError(t, err)
+	assert.Equal(t, provider, "shared-gpu")
+	assert.Check(t, !create)
+
+	template := cloudformation.NewTemplate()
+	err = backend.createCapacityProvider(context.TODO(), project, template, awsResources{
+		cluster:          "shared",
+		capacityProvider: provider,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(template.Resources), 0)
+}
+
+func TestSharedCapacityProviderMismatch(t *testing.T) {
+	project := loadConfig(t, sharedCapacityProviderYaml)
+	backend := sharedCapacityProviderBackend(&ec2.InstanceTypeInfo{
+		InstanceType: aws.String("m5.large"),
+		VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)},
+		MemoryInfo:   &ec2.MemoryInfo{SizeInMiB: aws.Int64(8192)},
+	})
+	_, _, err := backend.parseCapacityProviderExtension(context.TODO(), project, "shared")
+	assert.Error(t, err, "capacity provider shared-gpu instance types don't meet services requirements:\n"+
+		"m5.large: memory 8GiB <= 8GiB, gpus 0 < 1")
+}
+
+func TestSharedCapacityProviderRequiresCluster(t *testing.T) {
+	project := loadConfig(t, sharedCapacityProviderYaml)
+	backend := &ecsAPIService{}
+	_, _, err := backend.parseCapacityProviderExtension(context.TODO(), project, "")
+	assert.ErrorContains(t, err, "x-aws-capacity-provider requires x-aws-cluster to be set")
+}
+
+func TestSharedCapacityProviderCreated(t *testing.T) {
+	project := loadConfig(t, sharedCapacityProviderYaml)
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeCapacityProvidersWithContext", "shared-gpu").Return(&ecsapi.DescribeCapacityProvidersOutput{}, nil)
+	ecsMock.On("DescribeClustersWithContext", "shared").Return(&ecsapi.DescribeClustersOutput{
+		Clusters: []*ecsapi.Cluster{
+			{CapacityProviders: aws.StringSlice([]string{"FARGATE"})},
+		},
+	}, nil)
+	ecsMock.On("PutClusterCapacityProvidersWithContext", "shared", []string{"FARGATE", "shared-gpu"}).Return(nil)
+	ssmMock := &mockSSM{}
+	ssmMock.On("GetParameterWithContext", "/aws/service/ecs/optimized-ami/amazon-linux-2/gpu/recommended").Return(&ssm.GetParameterOutput{
+		Parameter: &ssm.Parameter{Value: aws.String(`{"image_id": "ami-123456"}`)},
+	}, nil)
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "compose-capacity-provider-shared-gpu").Return(&cfapi.DescribeStacksOutput{}, nil)
+	cf.On("CreateStackWithContext", "DELETE", int64(0)).Return(nil)
+	cf.On("WaitUntilStackCreateCompleteWithContext", "compose-capacity-provider-shared-gpu").Return(nil)
+	backend := &ecsAPIService{SDK: sdk{ECS: ecsMock, SSM: ssmMock, CF: cf}}
+
+	provider, create, err := backend.parseCapacityProviderExtension(context.TODO(), project, "shared")
+	assert.NilError(t, err)
+	assert.Equal(t, provider, "shared-gpu")
+	assert.Check(t, create)
+
+	backend.sharedProvider, err = backend.sharedCapacityProviderTemplate(context.TODO(), project, awsResources{
+		cluster: "shared",
+		subnets: []string{"subnet1", "subnet2"},
+	})
+	assert.NilError(t, err)
+	var parsed struct {
+		Resources map[string]struct {
+			Properties map[string]interface{}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(backend.sharedProvider.template, &parsed))
+	capacity := parsed.Resources["CapacityProvider"].Properties
+	assert.Equal(t, capacity["Name"], "shared-gpu")
+	assert.DeepEqual(t, capacity["Tags"], []interface{}{
+		map[string]interface{}{"Key": sharedCapacityProviderTag, "Value": "shared-gpu"},
+	})
+	configuration := parsed.Resources["LaunchConfiguration"].Properties
+	assert.Equal(t, configuration["InstanceType"], "g4dn.xlarge")
+	_, ok := configuration["SecurityGroups"]
+	assert.Check(t, !ok)
+
+	assert.NilError(t, backend.createSharedCapacityProvider(context.TODO()))
+	cf.AssertExpectations(t)
+	ecsMock.AssertExpectations(t)
+}
+
+func TestSharedCapacityProviderStrategy(t *testing.T) {
+	project := loadConfig(t, sharedCapacityProviderYaml)
+	template, err := (&ecsAPIService{}).convert(project, awsResources{
+		cluster:          "shared",
+		capacityProvider: "shared-gpu",
+	})
+	assert.NilError(t, err)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	var marshalled struct {
+		Resources map[string]struct {
+			Properties map[string]interface{}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	properties := marshalled.Resources["TestService"].Properties
+	_, ok := properties["LaunchType"]
+	assert.Check(t, !ok)
+	assert.DeepEqual(t, properties["CapacityProviderStrategy"], []interface{}{
+		map[string]interface{}{"CapacityProvider": "shared-gpu", "Weight": float64(1), "Base": float64(0)},
+	})
+}
+
+const existingAutoScalingGroupYaml = `
+services:
+  test:
+    image: hello_world
+    deploy:
+      resources:
+        reservations:
+          memory: 8G
+          generic_resources:
+            - discrete_resource_spec:
+                kind: gpus
+                value: 1
+x-aws-autoscaling_group: workers
+x-aws-managed_termination_protection: true
+x-aws-managed_scaling:
+  target_capacity: 80
+  min_step_size: 1
+  max_step_size: 5
+  instance_warmup: 120
+`
+
+func existingAutoScalingGroupBackend(group *autoscaling.Group) *ecsAPIService {
+	group.LaunchConfigurationName = aws.String("workers-lc")
+	ag := &mockAutoScaling{}
+	ag.On("DescribeAutoScalingGroupsWithContext", "workers").Return(&autoscaling.DescribeAutoScalingGroupsOutput{
+		AutoScalingGroups: []*autoscaling.Group{group},
+	}, nil)
+	ag.On("DescribeLaunchConfigurationsWithContext", "workers-lc").Return(&autoscaling.DescribeLaunchConfigurationsOutput{
+		LaunchConfigurations: []*autoscaling.LaunchConfiguration{
+			{InstanceType: aws.String("g4dn.xlarge")},
+		},
+	}, nil)
+	ec2Mock := &mockEC2{}
+	ec2Mock.On("DescribeInstanceTypesWithContext", "g4dn.xlarge").Return(&ec2.DescribeInstanceTypesOutput{
+		InstanceTypes: []*ec2.InstanceTypeInfo{
+			{
+				InstanceType: aws.String("g4dn.xlarge"),
+				VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(4)},
+				MemoryInfo:   &ec2.MemoryInfo{SizeInMiB: aws.Int64(16384)},
+				GpuInfo:      &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{{Count: aws.Int64(1)}}},
+			},
+		},
+	}, nil)
+	return &ecsAPIService{SDK: sdk{AG: ag, EC2: ec2Mock}}
+}
+
+const workersGroupArn = "arn:aws:autoscaling:eu-west-3:123456789012:autoScalingGroup:uuid:autoScalingGroupName/workers"
+
+func TestExistingAutoScalingGroup(t *testing.T) {
+	project := loadConfig(t, existingAutoScalingGroupYaml)
+	backend := existingAutoScalingGroupBackend(&autoscaling.Group{
+		AutoScalingGroupARN:              aws.String(workersGroupArn),
+		VPCZoneIdentifier:                aws.String("subnet1,subnet2"),
+		NewInstancesProtectedFromScaleIn: aws.Bool(true),
+	})
+	group, err := backend.parseAutoScalingGroupExtension(context.TODO(), project, "vpc", []string{"subnet1", "subnet2", "subnet3"})
+	assert.NilError(t, err)
+	assert.Equal(t, group, workersGroupArn)
+
+	template := cloudformation.NewTemplate()
+	template.Resources["Cluster"] = &ecs.Cluster{}
+	err = backend.createCapacityProvider(context.TODO(), project, template, awsResources{
+		cluster:          cloudformation.Ref("Cluster"),
+		autoScalingGroup: group,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(template.Resources), 2)
+	assert.DeepEqual(t, template.Resources["Cluster"].(*ecs.Cluster).CapacityProviders, []string{cloudformation.Ref("CapacityProvider")})
+
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	var parsed struct {
+		Resources map[string]struct {
+			Properties struct {
+				AutoScalingGroupProvider struct {
+					AutoScalingGroupArn          string
+					ManagedTerminationProtection string
+					ManagedScaling               map[string]interface{}
+				}
+			}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &parsed))
+	provider := parsed.Resources["CapacityProvider"].Properties.AutoScalingGroupProvider
+	assert.Equal(t, provider.AutoScalingGroupArn, workersGroupArn)
+	assert.Equal(t, provider.ManagedTerminationProtection, "ENABLED")
+	assert.DeepEqual(t, provider.ManagedScaling, map[string]interface{}{
+		"Status":                 "ENABLED",
+		"TargetCapacity":         float64(80),
+		"MinimumScalingStepSize": float64(1),
+		"MaximumScalingStepSize": float64(5),
+		"InstanceWarmupPeriod":   float64(120),
+	})
+}
+
+func TestCapacityProviderMarshalledAttributes(t *testing.T) {
+	raw, err := json.Marshal(capacityProvider{
+		CapacityProvider: ecs.CapacityProvider{
+			AutoScalingGroupProvider: &ecs.CapacityProvider_AutoScalingGroupProvider{
+				AutoScalingGroupArn: cloudformation.Ref("AutoscalingGroup"),
+				ManagedScaling:      &ecs.CapacityProvider_ManagedScaling{Status: "ENABLED"},
+			},
+			AWSCloudFormationDependsOn:      []string{"Cluster"},
+			AWSCloudFormationDeletionPolicy: "Retain",
+		},
+		InstanceWarmupPeriod: 60,
+	})
+	assert.NilError(t, err)
+	var parsed struct {
+		Type           string
+		DependsOn      []string
+		DeletionPolicy string
+		Properties     struct {
+			AutoScalingGroupProvider struct {
+				ManagedScaling map[string]interface{}
+			}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &parsed))
+	assert.Equal(t, parsed.Type, "AWS::ECS::CapacityProvider")
+	assert.DeepEqual(t, parsed.DependsOn, []string{"Cluster"})
+	assert.Equal(t, parsed.DeletionPolicy, "Retain")
+	assert.DeepEqual(t, parsed.Properties.AutoScalingGroupProvider.ManagedScaling, map[string]interface{}{
+		"Status":               "ENABLED",
+		"InstanceWarmupPeriod": float64(60),
+	})
+}
+
+func TestExistingAutoScalingGroupOutOfVPC(t *testing.T) {
+	project := loadConfig(t, existingAutoScalingGroupYaml)
+	backend := existingAutoScalingGroupBackend(&autoscaling.Group{
+		AutoScalingGroupARN:              aws.String(workersGroupArn),
+		VPCZoneIdentifier:                aws.String("subnet1,subnet-other"),
+		NewInstancesProtectedFromScaleIn: aws.Bool(true),
+	})
+	_, err := backend.parseAutoScalingGroupExtension(context.TODO(), project, "vpc", []string{"subnet1", "subnet2"})
+	assert.Error(t, err, "auto scaling group workers subnet subnet-other isn't in VPC vpc")
+}
+
+func TestExistingAutoScalingGroupNotProtected(t *testing.T) {
+	project := loadConfig(t, existingAutoScalingGroupYaml)
+	backend := existingAutoScalingGroupBackend(&autoscaling.Group{
+		AutoScalingGroupARN: aws.String(workersGroupArn),
+		VPCZoneIdentifier:   aws.String("subnet1"),
+	})
+	_, err := backend.parseAutoScalingGroupExtension(context.TODO(), project, "vpc", []string{"subnet1", "subnet2"})
+	assert.Error(t, err, "x-aws-managed_termination_protection requires auto scaling group workers to protect new instances from scale in")
+}
+
+func TestInvalidManagedScaling(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  test:
+    image: hello_world
+x-aws-managed_scaling:
+  target_capacity: 120
+`: "x-aws-managed_scaling target_capacity must be between 1 and 100, got 120",
+		`
+services:
+  test:
+    image: hello_world
+x-aws-managed_scaling:
+  min_step_size: 10
+  max_step_size: 2
+`: "x-aws-managed_scaling min_step_size 10 is greater than max_step_size 2",
+		`
+services:
+  test:
+    image: hello_world
+x-aws-managed_scaling:
+  warmup: 10
+`: "unsupported x-aws-managed_scaling attribute warmup",
+	} {
+		_, err := capacityProviderScaling(loadConfig(t, yaml))
+		assert.Error(t, err, expected)
+	}
+}
+
+type mockECS struct {
+	ecsiface.ECSAPI
+	mock.Mock
+}
+
+func (m *mockECS) DescribeCapacityProvidersWithContext(_ aws.Context, in *ecsapi.DescribeCapacityProvidersInput, _ ...request.Option) (*ecsapi.DescribeCapacityProvidersOutput, error) {
+	args := m.Called(aws.StringValue(in.CapacityProviders[0]))
+	return args.Get(0).(*ecsapi.DescribeCapacityProvidersOutput), args.Error(1)
+}
+
+func (m *mockECS) DescribeClustersWithContext(_ aws.Context, in *ecsapi.DescribeClustersInput, _ ...request.Option) (*ecsapi.DescribeClustersOutput, error) {
+	args := m.Called(aws.StringValue(in.Clusters[0]))
+	return args.Get(0).(*ecsapi.DescribeClustersOutput), args.Error(1)
+}
+
+func (m *mockECS) PutClusterCapacityProvidersWithContext(_ aws.Context, in *ecsapi.PutClusterCapacityProvidersInput, _ ...request.Option) (*ecsapi.PutClusterCapacityProvidersOutput, error) {
+	args := m.Called(aws.StringValue(in.Cluster), aws.StringValueSlice(in.CapacityProviders))
+	return &ecsapi.PutClusterCapacityProvidersOutput{}, args.Error(0)
+}
+
+func (m *mockCloudFormation) WaitUntilStackCreateCompleteWithContext(_ aws.Context, in *cfapi.DescribeStacksInput, _ ...request.WaiterOption) error {
+	args := m.Called(aws.StringValue(in.StackName))
+	return args.Error(0)
+}
+
+type mockAutoScaling struct {
+	autoscalingiface.AutoScalingAPI
+	mock.Mock
+}
+
+func (m *mockAutoScaling) DescribeAutoScalingGroupsWithContext(_ aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, _ ...request.Option) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	args := m.Called(aws.StringValue(in.AutoScalingGroupNames[0]))
+	return args.Get(0).(*autoscaling.DescribeAutoScalingGroupsOutput), args.Error(1)
+}
+
+func (m *mockAutoScaling) DescribeLaunchConfigurationsWithContext(_ aws.Context, in *autoscaling.DescribeLaunchConfigurationsInput, _ ...request.Option) (*autoscaling.DescribeLaunchConfigurationsOutput, error) {
+	args := m.Called(aws.StringValue(in.LaunchConfigurationNames[0]))
+	return args.Get(0).(*autoscaling.DescribeLaunchConfigurationsOutput), args.Error(1)
+}
+
+func (m *mockEC2) DescribeInstanceTypesWithContext(_ aws.Context, in *ec2.DescribeInstanceTypesInput, _ ...request.Option) (*ec2.DescribeInstanceTypesOutput, error) {
+	args := m.Called(aws.StringValue(in.InstanceTypes[0]))
+	return args.Get(0).(*ec2.DescribeInstanceTypesOutput), args.Error(1)
+}
@@ -0,0 +1,93 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/compose-spec/compose-go/types"
+)
+
+func (i ecrImage) repositoryArn() string {
+	return fmt.Sprintf("arn:%s:ecr:%s:%s:repository/%s", regionPartition(i.region), i.region, i.registry, i.repository)
+}
+
+// createCrossAccountPullPolicies grants task execution roles to pull ECR images hosted by another account, as ECR
+// managed policy only applies to the deploying account's repositories. They are skipped with a warning when the
+// deploying account can't be identified
+func (b *ecsAPIService) createCrossAccountPullPolicies(ctx context.Context, project *types.Project, template *cloudformation.Template) error {
+	var (
+		account    string
+		unknownErr error
+	)
+	for _, service := range project.Services {
+		image, ok := parseECRImage(service.Image)
+		if !ok {
+			continue
+		}
+		if image.region != b.Region {
+			b.warn(warningCrossRegionImage, severityWarning, service.Name, "image %s is pulled from region %s, which adds latency and data transfer cost, consider replicating it to %s. It can't be verified before deployment", image.ref, image.region, b.Region)
+		}
+		if account == "" && unknownErr == nil {
+			account, unknownErr = b.callerAccount(ctx)
+			if unknownErr != nil {
+				b.warn(warningCrossAccountImage, severityWarning, "", "can't get caller identity to check ECR images are hosted by the deploying account, cross-account pull permissions are not granted: %s", unknownErr)
+			}
+		}
+		if unknownErr != nil || image.registry == account {
+			continue
+		}
+
+		owner := taskOwner(project, service.Name)
+		role, ok := template.Resources[fmt.Sprintf("%sTaskExecutionRole", normalizeResourceName(owner))].(*iam.Role)
+		if !ok {
+			b.warn(warningCrossAccountImage, severityWarning, service.Name, "image %s is hosted by account %s, task execution role must be allowed to pull from repository %s", image.ref, image.registry, image.repositoryArn())
+			continue
+		}
+		role.Policies = append(role.Policies, iam.Role_Policy{
+			PolicyDocument: &PolicyDocument{
+				Statement: []PolicyStatement{
+					{
+						Effect:   "Allow",
+						Action:   []string{actionBatchCheckLayerAvailability, actionBatchGetImage, actionGetDownloadURLForLayer},
+						Resource: []string{image.repositoryArn()},
+					},
+				},
+			},
+			PolicyName: fmt.Sprintf("%sGrantPullFromRepository", normalizeResourceName(service.Name)),
+		})
+		b.warn(warningCrossAccountImage, severityWarning, service.Name, "image %s is hosted by account %s, repository %s policy must allow account %s to pull it", image.ref, image.registry, image.repositoryArn(), account)
+	}
+	return nil
+}
+
+// callerAccount returns the ID of the account the SDK is authenticated to
+func (b *ecsAPIService) callerAccount(ctx context.Context) (string, error) {
+	caller, err := b.SDK.GetCallerIdentity(ctx)
+	if err != nil {
+		return "", err
+	}
+	identity, err := arn.Parse(caller)
+	if err != nil {
+		return "", err
+	}
+	return identity.AccountID, nil
+}
@@ -0,0 +1,111 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+)
+
+func TestCrossAccountPullPolicies(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  local:
+    image: 012345678910.dkr.ecr.us-east-1.amazonaws.com/local:1.0
+  remote:
+    image: 123456789012.dkr.ecr.eu-west-1.amazonaws.com/app:tag
+`)
+	stsMock := &mockSTS{}
+	stsMock.On("GetCallerIdentityWithContext").Return(&sts.GetCallerIdentityOutput{
+		Arn: aws.String("arn:aws:sts::012345678910:assumed-role/deploy/session"),
+	}, nil)
+	backend := &ecsAPIService{Region: "us-east-1", SDK: sdk{STS: stsMock}}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+
+	err = backend.createCrossAccountPullPolicies(context.TODO(), project, template)
+	assert.NilError(t, err)
+
+	local := template.Resources["LocalTaskExecutionRole"].(*iam.Role)
+	assert.Equal(t, len(local.Policies), 0)
+	remote := template.Resources["RemoteTaskExecutionRole"].(*iam.Role)
+	assert.Equal(t, len(remote.Policies), 1)
+	statement := remote.Policies[0].PolicyDocument.(*PolicyDocument).Statement[0]
+	assert.DeepEqual(t, statement.Resource, []string{"arn:aws:ecr:eu-west-1:123456789012:repository/app"})
+
+	assert.DeepEqual(t, backend.warnings, convertWarnings{
+		{
+			Code:     warningCrossRegionImage,
+			Severity: severityWarning,
+			Service:  "remote",
+			Message:  "image 123456789012.dkr.ecr.eu-west-1.amazonaws.com/app:tag is pulled from region eu-west-1, which adds latency and data transfer cost, consider replicating it to us-east-1. It can't be verified before deployment",
+		},
+		{
+			Code:     warningCrossAccountImage,
+			Severity: severityWarning,
+			Service:  "remote",
+			Message:  "image 123456789012.dkr.ecr.eu-west-1.amazonaws.com/app:tag is hosted by account 123456789012, repository arn:aws:ecr:eu-west-1:123456789012:repository/app policy must allow account 012345678910 to pull it",
+		},
+	})
+}
+
+func TestCrossAccountPullPoliciesWithoutCallerIdentity(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  remote:
+    image: 123456789012.dkr.ecr.us-east-1.amazonaws.com/app:tag
+  other:
+    image: 123456789012.dkr.ecr.us-east-1.amazonaws.com/other:tag
+`)
+	stsMock := &mockSTS{}
+	stsMock.On("GetCallerIdentityWithContext").Return((*sts.GetCallerIdentityOutput)(nil), errors.New("expired token")).Once()
+	backend := &ecsAPIService{Region: "us-east-1", SDK: sdk{STS: stsMock}}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+
+	err = backend.createCrossAccountPullPolicies(context.TODO(), project, template)
+	assert.NilError(t, err)
+	remote := template.Resources["RemoteTaskExecutionRole"].(*iam.Role)
+	assert.Equal(t, len(remote.Policies), 0)
+	assert.DeepEqual(t, backend.warnings, convertWarnings{
+		{
+			Code:     warningCrossAccountImage,
+			Severity: severityWarning,
+			Message:  "can't get caller identity to check ECR images are hosted by the deploying account, cross-account pull permissions are not granted: expired token",
+		},
+	})
+	stsMock.AssertExpectations(t)
+}
+
+type mockSTS struct {
+	stsiface.STSAPI
+	mock.Mock
+}
+
+func (m *mockSTS) GetCallerIdentityWithContext(_ aws.Context, _ *sts.GetCallerIdentityInput, _ ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+	args := m.Called()
+	return args.Get(0).(*sts.GetCallerIdentityOutput), args.Error(1)
+}
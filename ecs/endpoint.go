@@ -0,0 +1,78 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+
+	"github.com/docker/compose-cli/context/store"
+)
+
+// endpointEnvVar sets the endpoint of all AWS services when context doesn't, typically a LocalStack edge endpoint
+const endpointEnvVar = "AWS_ENDPOINT_URL"
+
+// withEndpoint applies the AWS endpoint override of the context, or set by environment, to the session configuration
+func withEndpoint(config *aws.Config, ecsCtx store.EcsContext) *aws.Config {
+	endpoint := ecsCtx.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv(endpointEnvVar)
+	}
+	if endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+	}
+	if ecsCtx.S3ForcePathStyle {
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	if ecsCtx.InsecureSkipVerify {
+		config.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
+	}
+	return config
+}
+
+// regionPartition returns the AWS partition of a region, such as aws-cn or aws-us-gov, defaults to aws
+func regionPartition(region string) string {
+	if p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return p.ID()
+	}
+	return endpoints.AwsPartitionID
+}
+
+// partition returns the AWS partition project is deployed to
+func (b *ecsAPIService) partition() string {
+	return regionPartition(b.Region)
+}
+
+// partitionArn returns an ARN of the aws partition, such as an AWS managed policy, in the partition project is deployed to
+func (b *ecsAPIService) partitionArn(s string) string {
+	parsed, err := arn.Parse(s)
+	if err != nil {
+		return s
+	}
+	parsed.Partition = b.partition()
+	return parsed.String()
+}
@@ -0,0 +1,84 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/context/store"
+)
+
+func TestEndpointOverride(t *testing.T) {
+	config := withEndpoint(&aws.Config{}, store.EcsContext{})
+	assert.Check(t, config.Endpoint == nil)
+	assert.Check(t, config.HTTPClient == nil)
+
+	os.Setenv(endpointEnvVar, "http://localhost:4566") // nolint:errcheck
+	defer os.Unsetenv(endpointEnvVar)                  // nolint:errcheck
+	config = withEndpoint(&aws.Config{}, store.EcsContext{})
+	assert.Equal(t, aws.StringValue(config.Endpoint), "http://localhost:4566")
+
+	config = withEndpoint(&aws.Config{}, store.EcsContext{
+		Endpoint:           "https://localstack:4566",
+		S3ForcePathStyle:   true,
+		InsecureSkipVerify: true,
+	})
+	assert.Equal(t, aws.StringValue(config.Endpoint), "https://localstack:4566")
+	assert.Equal(t, aws.BoolValue(config.S3ForcePathStyle), true)
+	assert.Check(t, config.HTTPClient != nil)
+}
+
+func TestRegionPartition(t *testing.T) {
+	for region, partition := range map[string]string{
+		"eu-west-3":     "aws",
+		"cn-north-1":    "aws-cn",
+		"us-gov-west-1": "aws-us-gov",
+		"":              "aws",
+	} {
+		assert.Equal(t, regionPartition(region), partition, region)
+	}
+}
+
+func TestManagedPoliciesPartition(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  foo:
+    image: hello_world
+    env_file:
+      - testdata/input/envfile
+x-aws-env_files_bucket: mybucket
+`)
+	backend := &ecsAPIService{Region: "cn-north-1"}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+
+	role := template.Resources["FooTaskExecutionRole"].(*iam.Role)
+	assert.DeepEqual(t, role.ManagedPolicyArns, []string{
+		"arn:aws-cn:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy",
+		"arn:aws-cn:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly",
+	})
+	def := template.Resources["FooTaskDefinition"].(*ecs.TaskDefinition)
+	value := getMainContainer(def, t).EnvironmentFiles[0].Value
+	assert.Check(t, strings.HasPrefix(value, "arn:aws-cn:s3:::mybucket/Test/foo/"), value)
+}
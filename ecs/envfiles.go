@@ -0,0 +1,130 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// envFile is a service env_file uploaded to S3, so its content is kept out of the CloudFormation template
+type envFile struct {
+	key     string
+	content []byte
+}
+
+// envFilesBucket returns the S3 bucket env_files get uploaded to, if user opted-in by --env-files-bucket or
+// x-aws-env_files_bucket. env_files are otherwise inlined as environment variables
+func envFilesBucket(project *types.Project) (string, bool) {
+	bucket, ok := project.Extensions[extensionEnvFilesBucket].(string)
+	return bucket, ok && bucket != ""
+}
+
+// applyEnvFilesBucket sets the bucket selected by --env-files-bucket, which wins over x-aws-env_files_bucket
+func applyEnvFilesBucket(project *types.Project, bucket string) {
+	if bucket == "" {
+		return
+	}
+	if project.Extensions == nil {
+		project.Extensions = map[string]interface{}{}
+	}
+	project.Extensions[extensionEnvFilesBucket] = bucket
+}
+
+func envFilesPrefix(project *types.Project, service types.ServiceConfig) string {
+	return fmt.Sprintf("%s/%s/", project.Name, service.Name)
+}
+
+func s3Arn(partition string, bucket string, key string) string {
+	if key == "" {
+		return fmt.Sprintf("arn:%s:s3:::%s", partition, bucket)
+	}
+	return fmt.Sprintf("arn:%s:s3:::%s/%s", partition, bucket, key)
+}
+
+// serviceEnvFiles computes S3 object keys for service's env_files. Keys include a content digest so that
+// any change to an env_file results in a new task definition
+func serviceEnvFiles(project *types.Project, service types.ServiceConfig) ([]envFile, error) {
+	var files []envFile
+	for _, f := range service.EnvFile {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(project.WorkingDir, f)
+		}
+		content, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(content)
+		files = append(files, envFile{
+			key:     fmt.Sprintf("%s%x-%s", envFilesPrefix(project, service), digest[:6], filepath.Base(f)),
+			content: content,
+		})
+	}
+	return files, nil
+}
+
+func toEnvironmentFiles(project *types.Project, service types.ServiceConfig, partition string) ([]ecs.TaskDefinition_EnvironmentFile, error) {
+	bucket, ok := envFilesBucket(project)
+	if !ok {
+		return nil, nil
+	}
+	files, err := serviceEnvFiles(project, service)
+	if err != nil {
+		return nil, err
+	}
+	var environmentFiles []ecs.TaskDefinition_EnvironmentFile
+	for _, f := range files {
+		environmentFiles = append(environmentFiles, ecs.TaskDefinition_EnvironmentFile{
+			Type:  "s3",
+			Value: s3Arn(partition, bucket, f.key),
+		})
+	}
+	return environmentFiles, nil
+}
+
+// uploadEnvFiles uploads env_files to S3 before deployment so tasks can load them
+func (b *ecsAPIService) uploadEnvFiles(ctx context.Context, project *types.Project) error {
+	bucket, ok := envFilesBucket(project)
+	if !ok {
+		return nil
+	}
+	var uploaded []string
+	for _, service := range project.Services {
+		files, err := serviceEnvFiles(project, service)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			err = b.SDK.PutObject(ctx, bucket, f.key, f.content)
+			if err != nil {
+				return err
+			}
+			uploaded = append(uploaded, s3Arn(b.partition(), bucket, f.key))
+		}
+	}
+	if len(uploaded) == 0 {
+		return nil
+	}
+	// S3 objects are not managed by the stack, so `down` won't remove them
+	return b.recordInventory(ctx, project.Name, uploaded)
+}
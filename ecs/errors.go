@@ -0,0 +1,138 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+
+	"github.com/docker/compose-cli/errdefs"
+)
+
+// authErrorCodes are AWS error codes for missing, invalid or insufficient credentials
+var authErrorCodes = map[string]bool{
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"AuthFailure":                 true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"IncompleteSignature":         true,
+	"InvalidAccessKeyId":          true,
+	"InvalidClientTokenId":        true,
+	"InvalidSignatureException":   true,
+	"MissingAuthenticationToken":  true,
+	"NoCredentialProviders":       true,
+	"OptInRequired":               true,
+	"SignatureDoesNotMatch":       true,
+	"UnauthorizedOperation":       true,
+	"UnrecognizedClientException": true,
+}
+
+// quotaErrorCodes are AWS error codes for reached quotas and limits which don't follow the XXXLimitExceeded convention
+var quotaErrorCodes = map[string]bool{
+	"ServiceQuotaExceededException": true,
+	"QuotaExceededException":        true,
+	"TooManyLoadBalancers":          true,
+	"TooManyTargetGroups":           true,
+	"TooManyListeners":              true,
+	"TooManyRules":                  true,
+}
+
+// throttleErrorCodes are AWS error codes for API rate limits, which are transient unlike quotas
+var throttleErrorCodes = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"ThrottledException":                     true,
+	"TooManyRequestsException":               true,
+	"RequestLimitExceeded":                   true,
+	"RequestThrottled":                       true,
+	"RequestThrottledException":              true,
+	"ProvisionedThroughputExceededException": true,
+}
+
+// awsErrorKind returns the kind of error matching an AWS error code, nil if none does
+func awsErrorKind(code string) error {
+	switch {
+	case authErrorCodes[code]:
+		return errdefs.ErrAuthentication
+	case throttleErrorCodes[code]:
+		return errdefs.ErrThrottled
+	case quotaErrorCodes[code], strings.HasSuffix(code, "LimitExceeded"), strings.HasSuffix(code, "LimitExceededException"):
+		return errdefs.ErrQuotaExceeded
+	}
+	return nil
+}
+
+// classify returns err as an errdefs.Error of kind, unless it already is one or is an AWS error of a more specific kind
+func classify(err error, kind error) error {
+	if err == nil || errdefs.IsErrCanceled(err) || errors.Is(err, context.Canceled) {
+		return err
+	}
+	var known *errdefs.Error
+	if errors.As(err, &known) {
+		return err
+	}
+	classified := &errdefs.Error{Kind: kind, Err: err}
+	if errors.Is(err, context.DeadlineExceeded) {
+		classified.Kind = errdefs.ErrTimeout
+	}
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		classified.ProviderCode = aerr.Code()
+		if k := awsErrorKind(aerr.Code()); k != nil {
+			classified.Kind = k
+		}
+		if aerr.Code() == request.WaiterResourceNotReadyErrorCode && strings.Contains(aerr.Message(), "exceeded wait attempts") {
+			classified.Kind = errdefs.ErrTimeout
+		}
+	}
+	return classified
+}
+
+// serviceError returns err as an errdefs.Error related to service
+func serviceError(service string, err error) error {
+	err = classify(err, errdefs.ErrValidation)
+	var classified *errdefs.Error
+	if errors.As(err, &classified) && classified.Service == "" {
+		classified.Service = service
+	}
+	return err
+}
+
+// stackEventErrorCode extracts the AWS error code CloudFormation includes in resource status reasons
+var stackEventErrorCode = regexp.MustCompile(`Error Code: (\w+)`)
+
+// stackEventError is the error a resource failing to deploy makes the stack to be rolled back with
+func stackEventError(resource string, reason string) error {
+	err := &errdefs.Error{
+		Kind:     errdefs.ErrDeploymentFailed,
+		Resource: resource,
+		Err:      errors.New(reason),
+	}
+	if match := stackEventErrorCode.FindStringSubmatch(reason); match != nil {
+		err.ProviderCode = match[1]
+		if kind := awsErrorKind(match[1]); kind != nil {
+			err.Kind = kind
+		}
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
+)
+
+func TestIncompatibleProjectIsValidationError(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    cap_add:
+      - NET_ADMIN
+`)
+	_, err := (&ecsAPIService{}).Convert(context.TODO(), project, compose.ConvertOptions{})
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeValidation)
+	assert.Equal(t, errdefs.ToDetails(err).Code, "validation-error")
+}
+
+func TestServiceErrorDetails(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    healthcheck:
+      test: ["CMD", "curl", "localhost"]
+      retries: 20
+`)
+	_, err := (&ecsAPIService{}).convert(project, awsResources{})
+	assert.ErrorContains(t, err, "retries")
+	details := errdefs.ToDetails(err)
+	assert.Equal(t, details.Code, "validation-error")
+	assert.Equal(t, details.Service, "test")
+}
+
+func TestClassifyAWSErrors(t *testing.T) {
+	err := classify(errors.Wrap(awserr.New("ExpiredTokenException", "The security token included in the request is expired", nil), "creating stack"), errdefs.ErrDeploymentFailed)
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeAuthentication)
+	assert.Equal(t, errdefs.ToDetails(err).ProviderCode, "ExpiredTokenException")
+
+	err = classify(awserr.New("LimitExceededException", "Max number of stacks reached", nil), errdefs.ErrDeploymentFailed)
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeQuotaExceeded)
+
+	err = classify(awserr.New("RequestLimitExceeded", "Request limit exceeded", nil), errdefs.ErrDeploymentFailed)
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeThrottled)
+	assert.Equal(t, errdefs.ToDetails(err).Code, "throttled")
+
+	err = classify(awserr.New("ValidationError", "Stack with id test does not exist", nil), errdefs.ErrValidation)
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeValidation)
+	assert.Equal(t, errdefs.ToDetails(err).ProviderCode, "ValidationError")
+}
+
+func TestClassifyTimeout(t *testing.T) {
+	err := classify(awserr.New(request.WaiterResourceNotReadyErrorCode, "exceeded wait attempts", nil), errdefs.ErrDeploymentFailed)
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeTimeout)
+
+	err = classify(awserr.New(request.WaiterResourceNotReadyErrorCode, "failed waiting for successful resource state", nil), errdefs.ErrDeploymentFailed)
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeDeploymentFailed)
+
+	err = classify(fmt.Errorf("describing stack: %w", context.DeadlineExceeded), errdefs.ErrDeploymentFailed)
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeTimeout)
+}
+
+func TestClassifyKeepsKnownErrors(t *testing.T) {
+	known := &errdefs.Error{Kind: errdefs.ErrValidation, Err: errors.New("invalid")}
+	err := classify(known, errdefs.ErrDeploymentFailed)
+	assert.Equal(t, err, error(known))
+
+	assert.Equal(t, classify(context.Canceled, errdefs.ErrDeploymentFailed), context.Canceled)
+	assert.NilError(t, classify(nil, errdefs.ErrDeploymentFailed))
+}
+
+func TestStackEventError(t *testing.T) {
+	err := stackEventError("WebService", "Service arn:aws:ecs:eu-west-3:123456789012:service/web did not stabilize.")
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeDeploymentFailed)
+	assert.DeepEqual(t, errdefs.ToDetails(err), errdefs.Details{
+		Code:     "deployment-failed",
+		Message:  "Service arn:aws:ecs:eu-west-3:123456789012:service/web did not stabilize.",
+		Resource: "WebService",
+	})
+
+	err = stackEventError("DefaultNetwork", "The maximum number of VPCs has been reached. (Service: AmazonEC2; Status Code: 400; Error Code: VpcLimitExceeded; Request ID: 42)")
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeQuotaExceeded)
+	assert.Equal(t, errdefs.ToDetails(err).ProviderCode, "VpcLimitExceeded")
+	assert.Equal(t, errdefs.ToDetails(err).Resource, "DefaultNetwork")
+}
@@ -0,0 +1,178 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/progress"
+)
+
+// eventsPollInterval is the delay between DescribeServices calls when following service events
+var eventsPollInterval = 5 * time.Second
+
+func (b *ecsAPIService) Events(ctx context.Context, projectName string, consumer func(compose.ServiceEvent), options compose.EventsOptions) error {
+	cluster, err := b.SDK.GetStackClusterID(ctx, projectName)
+	if err != nil {
+		return err
+	}
+	arns, err := b.SDK.ListStackServices(ctx, projectName)
+	if err != nil {
+		return err
+	}
+	if len(arns) == 0 {
+		return nil
+	}
+
+	tracker := newServiceEvents(time.Time{}, options.Services)
+	for {
+		events, err := b.SDK.GetServicesEvents(ctx, cluster, arns)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, event := range tracker.filter(events) {
+			consumer(event)
+		}
+		if !options.Follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(eventsPollInterval):
+		}
+	}
+}
+
+// serviceEvents tracks the ECS service events already reported, as DescribeServices returns the last ones on each call
+type serviceEvents struct {
+	since    time.Time
+	services map[string]bool // services to report events of, all when empty
+	known    map[string]struct{}
+	// cluster and arns are the stack's services events are polled for, resolved again once the stack changed them
+	cluster  string
+	arns     []string
+	resolved bool
+	changes  map[string]struct{} // stack events of clusters and services
+	polled   time.Time
+}
+
+func newServiceEvents(since time.Time, services []string) *serviceEvents {
+	e := &serviceEvents{
+		since:    since,
+		services: map[string]bool{},
+		known:    map[string]struct{}{},
+		changes:  map[string]struct{}{},
+	}
+	for _, s := range services {
+		e.services[s] = true
+	}
+	return e
+}
+
+// stackChanged gets the stack's services resolved again when stack events show a cluster or service changed
+func (e *serviceEvents) stackChanged(events []*cloudformation.StackEvent) {
+	for _, event := range events {
+		switch aws.StringValue(event.ResourceType) {
+		case "AWS::ECS::Cluster", "AWS::ECS::Service":
+		default:
+			continue
+		}
+		if _, ok := e.changes[aws.StringValue(event.EventId)]; ok {
+			continue
+		}
+		e.changes[aws.StringValue(event.EventId)] = struct{}{}
+		e.resolved = false
+	}
+}
+
+// filter returns the events not reported yet, in chronological order
+func (e *serviceEvents) filter(events []compose.ServiceEvent) []compose.ServiceEvent {
+	var selected []compose.ServiceEvent
+	for _, event := range events {
+		if _, ok := e.known[event.ID]; ok {
+			continue
+		}
+		if event.Timestamp.Before(e.since) || len(e.services) > 0 && !e.services[event.Service] {
+			continue
+		}
+		e.known[event.ID] = struct{}{}
+		selected = append(selected, event)
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].Timestamp.Before(selected[j].Timestamp)
+	})
+	return selected
+}
+
+// reportServiceEvents reports the new events of stack's services as the progress of the service, as they explain why
+// tasks fail to be placed or to become healthy before the stack update fails. Services are polled every
+// eventsPollInterval, and only looked up in the stack again once stack events show they changed
+func (b *ecsAPIService) reportServiceEvents(ctx context.Context, name string, tracker *serviceEvents, w progress.Writer) error {
+	if time.Since(tracker.polled) < eventsPollInterval {
+		return nil
+	}
+	tracker.polled = time.Now()
+	if !tracker.resolved {
+		resources, err := b.SDK.ListStackResources(ctx, name)
+		if err != nil {
+			return err
+		}
+		tracker.cluster, tracker.arns = "", nil
+		for _, r := range resources {
+			switch r.Type {
+			case "AWS::ECS::Cluster":
+				tracker.cluster = r.ARN
+			case "AWS::ECS::Service":
+				if r.ARN != "" {
+					tracker.arns = append(tracker.arns, r.ARN)
+				}
+			}
+		}
+		tracker.resolved = true
+	}
+	if len(tracker.arns) == 0 {
+		return nil
+	}
+	events, err := b.SDK.GetServicesEvents(ctx, tracker.cluster, tracker.arns)
+	if err != nil {
+		return err
+	}
+	for _, event := range tracker.filter(events) {
+		status := progress.Working
+		if strings.HasSuffix(event.Message, "has reached a steady state.") {
+			status = progress.Done
+		}
+		w.Event(progress.Event{
+			ID:         event.Service,
+			Status:     status,
+			StatusText: fmt.Sprintf("%s %s", event.Timestamp.Format(time.RFC3339), event.Message),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/progress"
+)
+
+func servicesEvents(services ...*ecsapi.Service) *ecsapi.DescribeServicesOutput {
+	return &ecsapi.DescribeServicesOutput{Services: services}
+}
+
+func taggedService(name string, events ...*ecsapi.ServiceEvent) *ecsapi.Service {
+	return &ecsapi.Service{
+		ServiceName: aws.String("test-" + name),
+		Tags:        []*ecsapi.Tag{{Key: aws.String(compose.ServiceTag), Value: aws.String(name)}},
+		Events:      events,
+	}
+}
+
+func ecsServiceEvent(id string, timestamp int64, message string) *ecsapi.ServiceEvent {
+	return &ecsapi.ServiceEvent{
+		Id:        aws.String(id),
+		CreatedAt: aws.Time(time.Unix(timestamp, 0)),
+		Message:   aws.String(message),
+	}
+}
+
+func stackServices() *cloudformation.ListStackResourcesOutput {
+	return &cloudformation.ListStackResourcesOutput{
+		StackResourceSummaries: []*cloudformation.StackResourceSummary{
+			{LogicalResourceId: aws.String("Cluster"), ResourceType: aws.String("AWS::ECS::Cluster"), PhysicalResourceId: aws.String("arn:cluster")},
+			{LogicalResourceId: aws.String("WebService"), ResourceType: aws.String("AWS::ECS::Service"), PhysicalResourceId: aws.String("arn:web")},
+			{LogicalResourceId: aws.String("DbService"), ResourceType: aws.String("AWS::ECS::Service"), PhysicalResourceId: aws.String("arn:db")},
+		},
+	}
+}
+
+func TestEventsFollow(t *testing.T) {
+	defer func(d time.Duration) { eventsPollInterval = d }(eventsPollInterval)
+	eventsPollInterval = time.Millisecond
+
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "test").Return(stackServices(), nil)
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeServicesWithContext", "arn:web").Return(servicesEvents(
+		taggedService("web", ecsServiceEvent("2", 2, "(service test-web) has started 1 tasks")),
+		taggedService("db", ecsServiceEvent("1", 1, "(service test-db) was unable to place a task")),
+	), nil).Once()
+	// DescribeServices returns events already reported along with new ones
+	ecsMock.On("DescribeServicesWithContext", "arn:web").Return(servicesEvents(
+		taggedService("web",
+			ecsServiceEvent("3", 3, "(service test-web) has reached a steady state."),
+			ecsServiceEvent("2", 2, "(service test-web) has started 1 tasks")),
+		taggedService("db", ecsServiceEvent("1", 1, "(service test-db) was unable to place a task")),
+	), nil)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	var reported []string
+	backend := &ecsAPIService{SDK: sdk{CF: cf, ECS: ecsMock}}
+	err := backend.Events(ctx, "test", func(e compose.ServiceEvent) {
+		reported = append(reported, fmt.Sprintf("%s %d %s", e.Service, e.Timestamp.Unix(), e.Message))
+		if e.ID == "3" {
+			cancel()
+		}
+	}, compose.EventsOptions{Follow: true})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reported, []string{
+		"db 1 (service test-db) was unable to place a task",
+		"web 2 (service test-web) has started 1 tasks",
+		"web 3 (service test-web) has reached a steady state.",
+	})
+}
+
+func TestEventsServices(t *testing.T) {
+	tracker := newServiceEvents(time.Unix(2, 0), []string{"web"})
+	events := tracker.filter([]compose.ServiceEvent{
+		{ID: "1", Service: "web", Timestamp: time.Unix(1, 0), Message: "previous deployment"},
+		{ID: "3", Service: "web", Timestamp: time.Unix(3, 0), Message: "started"},
+		{ID: "2", Service: "db", Timestamp: time.Unix(2, 0), Message: "not selected"},
+	})
+	assert.DeepEqual(t, events, []compose.ServiceEvent{
+		{ID: "3", Service: "web", Timestamp: time.Unix(3, 0), Message: "started"},
+	})
+	assert.Equal(t, len(tracker.filter(events)), 0)
+}
+
+func TestReportServiceEvents(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "test").Return(stackServices(), nil)
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeServicesWithContext", "arn:web").Return(servicesEvents(
+		taggedService("web", ecsServiceEvent("2", 2, "(service test-web) has reached a steady state.")),
+		taggedService("db", ecsServiceEvent("1", 1, "(service test-db) was unable to place a task")),
+	), nil)
+
+	w := &recordingWriter{}
+	backend := &ecsAPIService{SDK: sdk{CF: cf, ECS: ecsMock}}
+	err := backend.reportServiceEvents(context.TODO(), "test", newServiceEvents(time.Time{}, nil), w)
+	assert.NilError(t, err)
+	var reported []string
+	for _, e := range w.events {
+		reported = append(reported, fmt.Sprintf("%s %d %s", e.ID, e.Status, e.StatusText))
+	}
+	assert.DeepEqual(t, reported, []string{
+		fmt.Sprintf("db %d %s (service test-db) was unable to place a task", progress.Working, time.Unix(1, 0).Format(time.RFC3339)),
+		fmt.Sprintf("web %d %s (service test-web) has reached a steady state.", progress.Done, time.Unix(2, 0).Format(time.RFC3339)),
+	})
+}
+
+func TestReportServiceEventsResolvesServicesOnChange(t *testing.T) {
+	defer func(d time.Duration) { eventsPollInterval = d }(eventsPollInterval)
+	eventsPollInterval = 0
+
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "test").Return(stackServices(), nil)
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeServicesWithContext", "arn:web").Return(servicesEvents(
+		taggedService("web", ecsServiceEvent("1", 1, "(service test-web) has started 1 tasks")),
+	), nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cf, ECS: ecsMock}}
+	tracker := newServiceEvents(time.Time{}, nil)
+	for i := 0; i < 3; i++ {
+		assert.NilError(t, backend.reportServiceEvents(context.TODO(), "test", tracker, &recordingWriter{}))
+	}
+	cf.AssertNumberOfCalls(t, "ListStackResourcesWithContext", 1)
+	ecsMock.AssertNumberOfCalls(t, "DescribeServicesWithContext", 3)
+
+	serviceCreated := []*cloudformation.StackEvent{{
+		EventId:      aws.String("WebService-CREATE_COMPLETE"),
+		ResourceType: aws.String("AWS::ECS::Service"),
+	}}
+	tracker.stackChanged(serviceCreated)
+	assert.NilError(t, backend.reportServiceEvents(context.TODO(), "test", tracker, &recordingWriter{}))
+	tracker.stackChanged(serviceCreated)
+	assert.NilError(t, backend.reportServiceEvents(context.TODO(), "test", tracker, &recordingWriter{}))
+	cf.AssertNumberOfCalls(t, "ListStackResourcesWithContext", 2)
+}
+
+func TestReportServiceEventsPollInterval(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "test").Return(stackServices(), nil)
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeServicesWithContext", "arn:web").Return(servicesEvents(), nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cf, ECS: ecsMock}}
+	tracker := newServiceEvents(time.Time{}, nil)
+	assert.NilError(t, backend.reportServiceEvents(context.TODO(), "test", tracker, &recordingWriter{}))
+	assert.NilError(t, backend.reportServiceEvents(context.TODO(), "test", tracker, &recordingWriter{}))
+	ecsMock.AssertNumberOfCalls(t, "DescribeServicesWithContext", 1)
+}
@@ -0,0 +1,212 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+// sessionManagerPlugin is the AWS CLI plugin streaming Session Manager sessions, which ECS Exec relies on
+const sessionManagerPlugin = "session-manager-plugin"
+
+// executeCommandEnabled tells if ECS Exec is enabled by x-aws-enable_execute_command, a service setting overriding the
+// project one
+func executeCommandEnabled(project *types.Project, service types.ServiceConfig) bool {
+	x, ok := service.Extensions[extensionEnableExecuteCommand]
+	if !ok {
+		x, ok = project.Extensions[extensionEnableExecuteCommand]
+	}
+	return ok && x == true
+}
+
+// executeCommandPolicy grants the task role access to the Session Manager channels ECS Exec opens in containers
+func executeCommandPolicy(project *types.Project) iam.Role_Policy {
+	statements := []PolicyStatement{
+		{
+			Effect: "Allow",
+			Action: []string{
+				actionCreateControlChannel,
+				actionCreateDataChannel,
+				actionOpenControlChannel,
+				actionOpenDataChannel,
+			},
+			Resource: []string{"*"},
+		},
+	}
+	if key, ok, _ := kmsKey(project); ok {
+		statements = append(statements, PolicyStatement{
+			Effect:   "Allow",
+			Action:   []string{actionDecrypt},
+			Resource: []string{key},
+		})
+	}
+	return iam.Role_Policy{
+		PolicyDocument: &PolicyDocument{
+			Statement: statements,
+		},
+		PolicyName: "ExecuteCommand",
+	}
+}
+
+// applyExecuteCommand enables ECS Exec on services, and sets the cluster to encrypt sessions with x-aws-kms_key.
+// goformation doesn't support these properties, so they are set on the marshalled template
+func applyExecuteCommand(project *types.Project, raw []byte) ([]byte, error) {
+	var services []string
+	for _, service := range project.Services {
+		if _, ok := sidecarOf(service); ok || isScheduled(service) {
+			continue
+		}
+		if executeCommandEnabled(project, service) {
+			services = append(services, serviceResourceName(service.Name))
+		}
+	}
+	if len(services) == 0 {
+		return raw, nil
+	}
+
+	var template map[string]interface{}
+	if err := json.Unmarshal(raw, &template); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %s", err)
+	}
+	resources := template["Resources"].(map[string]interface{})
+	for _, name := range services {
+		properties := resources[name].(map[string]interface{})["Properties"].(map[string]interface{})
+		properties["EnableExecuteCommand"] = true
+	}
+	if key, ok, _ := kmsKey(project); ok {
+		if cluster, ok := resources["Cluster"].(map[string]interface{}); ok {
+			properties, ok := cluster["Properties"].(map[string]interface{})
+			if !ok {
+				properties = map[string]interface{}{}
+				cluster["Properties"] = properties
+			}
+			properties["Configuration"] = map[string]interface{}{
+				"ExecuteCommandConfiguration": map[string]interface{}{
+					"KmsKeyId": key,
+					"Logging":  "DEFAULT",
+				},
+			}
+		}
+	}
+	return json.MarshalIndent(sortArrays(template), "", "  ")
+}
+
+func (b *ecsAPIService) Exec(ctx context.Context, project string, options compose.ExecOptions) error {
+	plugin, err := exec.LookPath(sessionManagerPlugin)
+	if err != nil {
+		return fmt.Errorf("%s is required to execute commands, see https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html", sessionManagerPlugin)
+	}
+
+	cluster, err := b.SDK.GetStackClusterID(ctx, project)
+	if err != nil {
+		return err
+	}
+	serviceARN, err := b.findService(ctx, project, cluster, options.Service)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := b.SDK.GetServiceTasks(ctx, cluster, serviceARN, false)
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		return fmt.Errorf("service %s has no running task", options.Service)
+	}
+	task := tasks[0]
+
+	container := options.Container
+	if container == "" {
+		container = options.Service
+	}
+	var runtimeID string
+	for _, c := range task.Containers {
+		if aws.StringValue(c.Name) == container {
+			runtimeID = aws.StringValue(c.RuntimeId)
+		}
+	}
+	if runtimeID == "" {
+		return fmt.Errorf("task %s has no running container %s", aws.StringValue(task.TaskArn), container)
+	}
+
+	session, err := b.SDK.ExecuteCommand(ctx, cluster, aws.StringValue(task.TaskArn), container, options.Command)
+	if err != nil {
+		return err
+	}
+	target := fmt.Sprintf("ecs:%s_%s_%s", resourceName(cluster), resourceName(aws.StringValue(task.TaskArn)), runtimeID)
+	return startSession(ctx, plugin, b.Region, b.SDK.ECSEndpoint(b.Region), session, target, options)
+}
+
+// findService returns the ARN of the ECS service project deploys for a compose service
+func (b *ecsAPIService) findService(ctx context.Context, project string, cluster string, service string) (string, error) {
+	servicesARN, err := b.SDK.ListStackServices(ctx, project)
+	if err != nil {
+		return "", err
+	}
+	for _, arn := range servicesARN {
+		state, err := b.SDK.DescribeService(ctx, cluster, arn)
+		if err != nil {
+			return "", err
+		}
+		if state.Name == service {
+			return arn, nil
+		}
+	}
+	return "", fmt.Errorf("no service %s in project %s", service, project)
+}
+
+// resourceName returns the last part of an ECS resource ARN, which is the resource name or ID
+func resourceName(id string) string {
+	if !arn.IsARN(id) {
+		return id
+	}
+	parsed, err := arn.Parse(id)
+	if err != nil {
+		return id
+	}
+	parts := strings.Split(parsed.Resource, "/")
+	return parts[len(parts)-1]
+}
+
+// startSession streams the session by the Session Manager plugin, the same way `aws ecs execute-command` does
+func startSession(ctx context.Context, plugin string, region string, endpoint string, session executeCommandSession, target string,
+	options compose.ExecOptions) error {
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	targetJSON, err := json.Marshal(map[string]string{"Target": target})
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, plugin, string(sessionJSON), region, "StartSession", "", string(targetJSON), endpoint)
+	cmd.Stdin = options.Stdin
+	cmd.Stdout = options.Stdout
+	cmd.Stderr = options.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,133 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"gotest.tools/v3/assert"
+)
+
+func TestExecuteCommandTaskRole(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  front:
+    image: nginx
+  back:
+    image: app
+    x-aws-enable_execute_command: false
+x-aws-enable_execute_command: true
+x-aws-kms_key: `+testKMSKey+`
+`)
+	role := template.Resources["FrontTaskRole"].(*iam.Role)
+	assert.Equal(t, len(role.Policies), 1)
+	assert.Equal(t, role.Policies[0].PolicyName, "ExecuteCommand")
+	policy := role.Policies[0].PolicyDocument.(*PolicyDocument)
+	assert.DeepEqual(t, policy.Statement, []PolicyStatement{
+		{
+			Effect: "Allow",
+			Action: []string{
+				"ssmmessages:CreateControlChannel",
+				"ssmmessages:CreateDataChannel",
+				"ssmmessages:OpenControlChannel",
+				"ssmmessages:OpenDataChannel",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			Effect:   "Allow",
+			Action:   []string{"kms:Decrypt"},
+			Resource: []string{testKMSKey},
+		},
+	})
+	_, ok := template.Resources["BackTaskRole"]
+	assert.Check(t, !ok)
+}
+
+func TestApplyExecuteCommand(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  front:
+    image: nginx
+    x-aws-enable_execute_command: true
+  back:
+    image: app
+x-aws-kms_key: `+testKMSKey+`
+`)
+	template, err := (&ecsAPIService{}).convert(project, awsResources{})
+	assert.NilError(t, err)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	raw, err = applyExecuteCommand(project, raw)
+	assert.NilError(t, err)
+
+	var marshalled struct {
+		Resources map[string]struct {
+			Properties map[string]interface{}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	assert.Equal(t, marshalled.Resources["FrontService"].Properties["EnableExecuteCommand"], true)
+	_, ok := marshalled.Resources["BackService"].Properties["EnableExecuteCommand"]
+	assert.Check(t, !ok)
+	assert.DeepEqual(t, marshalled.Resources["Cluster"].Properties["Configuration"], map[string]interface{}{
+		"ExecuteCommandConfiguration": map[string]interface{}{
+			"KmsKeyId": testKMSKey,
+			"Logging":  "DEFAULT",
+		},
+	})
+}
+
+func TestExecuteCommandPreCreatedTaskRole(t *testing.T) {
+	backend := &ecsAPIService{}
+	_, err := backend.convert(loadConfig(t, `
+services:
+  front:
+    image: nginx
+    x-aws-task_role_arn: arn:aws:iam::012345678910:role/front
+    x-aws-enable_execute_command: true
+`), awsResources{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, backend.warnings, convertWarnings{
+		{
+			Code:     warningRolePermissions,
+			Severity: severityWarning,
+			Service:  "front",
+			Message:  "task role arn:aws:iam::012345678910:role/front must grant ssmmessages channels access for ECS Exec",
+		},
+	})
+}
+
+func TestResourceName(t *testing.T) {
+	assert.Equal(t, resourceName("arn:aws:ecs:eu-west-3:012345678910:cluster/project"), "project")
+	assert.Equal(t, resourceName("arn:aws:ecs:eu-west-3:012345678910:task/project/0123456789abcdef"), "0123456789abcdef")
+	assert.Equal(t, resourceName("project"), "project")
+}
+
+func TestECSEndpoint(t *testing.T) {
+	sess, err := session.NewSession(aws.NewConfig().WithRegion("cn-north-1"))
+	assert.NilError(t, err)
+	assert.Equal(t, newSDK(sess).ECSEndpoint("cn-north-1"), "https://ecs.cn-north-1.amazonaws.com.cn")
+
+	sess, err = session.NewSession(aws.NewConfig().WithRegion("eu-west-3").WithEndpoint("https://ecs.example.com"))
+	assert.NilError(t, err)
+	assert.Equal(t, newSDK(sess).ECSEndpoint("eu-west-3"), "https://ecs.example.com")
+}
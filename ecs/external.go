@@ -0,0 +1,149 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// launchTypeExternal runs tasks on ECS Anywhere instances, registered to the cluster from outside AWS
+const launchTypeExternal = "EXTERNAL"
+
+// external tells if service is set by x-aws-launch_type to run on ECS Anywhere instances
+func external(service types.ServiceConfig) bool {
+	return service.Extensions[extensionLaunchType] == launchTypeExternal
+}
+
+// checkLaunchType validates x-aws-launch_type, as Fargate and EC2 launch types are selected from service requirements
+func checkLaunchType(service types.ServiceConfig) error {
+	if x, ok := service.Extensions[extensionLaunchType]; ok && x != launchTypeExternal {
+		return fmt.Errorf("%s must be %s, got %v: %s and %s launch types are selected from service requirements",
+			extensionLaunchType, launchTypeExternal, x, ecsapi.LaunchTypeFargate, ecsapi.LaunchTypeEc2)
+	}
+	return nil
+}
+
+// taskExternal tells if a task made of services runs on ECS Anywhere instances
+func taskExternal(services []types.ServiceConfig) bool {
+	for _, s := range services {
+		if external(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// externalTask tells if service runs in a task on ECS Anywhere instances, which for a sidecar is its owner's task
+func externalTask(project *types.Project, service types.ServiceConfig) bool {
+	if name, ok := sidecarOf(service); ok {
+		if owner, err := project.GetService(name); err == nil {
+			service = owner
+		}
+	}
+	return taskExternal(taskServices(project, service))
+}
+
+// checkExternalCluster rejects tasks running on ECS Anywhere instances in a cluster created by the stack, as instances
+// must be registered to the cluster before tasks can be placed
+func checkExternalCluster(project *types.Project, template *cloudformation.Template) error {
+	if _, ok := template.Resources["Cluster"]; !ok {
+		return nil
+	}
+	for _, service := range project.Services {
+		if external(service) {
+			return serviceError(service.Name, fmt.Errorf("%s %s requires %s to be set, as ECS Anywhere instances must be registered to an existing cluster",
+				extensionLaunchType, launchTypeExternal, extensionCluster))
+		}
+	}
+	return nil
+}
+
+// externalNetworkMode is the network mode of tasks on ECS Anywhere instances, which don't support awsvpc
+func externalNetworkMode(service types.ServiceConfig) string {
+	if service.NetworkMode == "host" {
+		return ecsapi.NetworkModeHost
+	}
+	return ecsapi.NetworkModeBridge
+}
+
+// serviceNetworkMode is the network mode of the task running service, awsvpc for both Fargate and EC2 launch types
+func serviceNetworkMode(service types.ServiceConfig) string {
+	if external(service) {
+		return externalNetworkMode(service)
+	}
+	return ecsapi.NetworkModeAwsvpc
+}
+
+// externalIncompatibleExtensions are the service extensions which don't apply to ECS Anywhere instances
+var externalIncompatibleExtensions = []struct {
+	extension string
+	reason    string
+}{
+	{extensionFallbackCapacity, "capacity providers don't manage ECS Anywhere instances"},
+	{extensionDeploymentController, "CodeDeploy requires a load balancer, which ECS Anywhere services can't be registered to"},
+	{extensionProxyConfiguration, "proxy configuration requires network mode awsvpc, which ECS Anywhere instances don't support"},
+	{extensionServiceConnect, "Service Connect isn't supported on ECS Anywhere instances"},
+	{extensionSchedule, "scheduled tasks can't run on ECS Anywhere instances"},
+	{extensionAssignPublicIP, "tasks on ECS Anywhere instances use their instance network"},
+}
+
+// checkExternal reports the attributes of service which don't apply to ECS Anywhere instances, if it runs there
+func (c *fargateCompatibilityChecker) checkExternal(project *types.Project, service *types.ServiceConfig) {
+	if !externalTask(project, *service) {
+		return
+	}
+	if len(service.Volumes) > 0 {
+		if c.incompatible(service, "services.volumes", "EFS volumes can't be mounted on ECS Anywhere instances") {
+			service.Volumes = nil
+		}
+	}
+	for _, x := range externalIncompatibleExtensions {
+		if x.extension == extensionDeploymentController && service.Extensions[x.extension] == ecsapi.DeploymentControllerTypeEcs {
+			continue
+		}
+		if _, ok := service.Extensions[x.extension]; !ok {
+			continue
+		}
+		if c.incompatible(service, x.extension, x.reason) {
+			// the builtin delete is shadowed in this package
+			extensions := map[string]interface{}{}
+			for key, value := range service.Extensions {
+				if key != x.extension {
+					extensions[key] = value
+				}
+			}
+			service.Extensions = extensions
+		}
+	}
+	// project extensions can't be dropped for a single service, which is left out of them when ignored
+	for _, x := range []struct {
+		extension string
+		reason    string
+	}{
+		{extensionCapacityProvider, "capacity providers don't manage ECS Anywhere instances, service won't use them"},
+		{extensionAppMesh, "App Mesh isn't supported on ECS Anywhere instances, service won't join the mesh"},
+		{extensionServiceConnect, "Service Connect isn't supported on ECS Anywhere instances, service won't be exposed by Service Connect"},
+	} {
+		if value, ok := project.Extensions[x.extension]; ok && value != false {
+			c.incompatible(service, x.extension, x.reason)
+		}
+	}
+}
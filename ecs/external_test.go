@@ -0,0 +1,122 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+const externalYaml = `
+services:
+  test:
+    image: nginx
+    x-aws-launch_type: EXTERNAL
+    ports:
+      - 8080:80
+    cap_add:
+      - NET_ADMIN
+  host:
+    image: agent
+    hostname: agent
+    network_mode: host
+    x-aws-launch_type: EXTERNAL
+x-aws-cluster: anywhere
+`
+
+func TestExternalLaunchType(t *testing.T) {
+	project := loadConfig(t, externalYaml)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	template, err := backend.convert(project, awsResources{cluster: "anywhere"})
+	assert.NilError(t, err)
+
+	service := template.Resources["TestService"].(*ecs.Service)
+	assert.Equal(t, service.LaunchType, launchTypeExternal)
+	assert.Equal(t, service.PlatformVersion, "")
+	assert.Check(t, service.NetworkConfiguration == nil)
+	assert.Equal(t, len(service.LoadBalancers), 0)
+	assert.Equal(t, len(service.ServiceRegistries), 0)
+
+	definition := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	assert.DeepEqual(t, definition.RequiresCompatibilities, []string{launchTypeExternal})
+	assert.Equal(t, definition.NetworkMode, "bridge")
+	assert.Equal(t, definition.ContainerDefinitions[0].PortMappings[0].HostPort, 8080)
+	assert.Equal(t, definition.ContainerDefinitions[0].PortMappings[0].ContainerPort, 80)
+
+	definition = template.Resources["HostTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, definition.NetworkMode, "host")
+	assert.Equal(t, definition.ContainerDefinitions[0].Hostname, "agent")
+	assert.Equal(t, len(backend.warnings), 0)
+
+	_, ok := template.Resources["LoadBalancer"]
+	assert.Check(t, !ok)
+	_, ok = template.Resources["TestTCP8080TargetGroup"]
+	assert.Check(t, !ok)
+}
+
+func TestExternalRequiresCluster(t *testing.T) {
+	_, err := (&ecsAPIService{}).convert(loadConfig(t, `
+services:
+  test:
+    image: nginx
+    x-aws-launch_type: EXTERNAL
+`), awsResources{})
+	assert.ErrorContains(t, err, "x-aws-launch_type EXTERNAL requires x-aws-cluster to be set")
+}
+
+func TestExternalInvalidLaunchType(t *testing.T) {
+	err := (&ecsAPIService{}).checkCompatibility(loadConfig(t, `
+services:
+  test:
+    image: nginx
+    x-aws-launch_type: FARGATE
+`), false)
+	assert.ErrorContains(t, err, "x-aws-launch_type must be EXTERNAL, got FARGATE")
+}
+
+func TestExternalIncompatibleAttributes(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    x-aws-launch_type: EXTERNAL
+    x-aws-schedule: rate(1 hour)
+    x-aws-proxy-configuration:
+      container_name: envoy
+    volumes:
+      - data:/data
+volumes:
+  data:
+x-aws-cluster: anywhere
+x-aws-appmesh:
+  mesh: my-mesh
+`)
+	err := (&ecsAPIService{}).checkCompatibility(project, false)
+	assert.ErrorContains(t, err, "service test: services.volumes: EFS volumes can't be mounted on ECS Anywhere instances")
+	assert.ErrorContains(t, err, "service test: x-aws-schedule: scheduled tasks can't run on ECS Anywhere instances")
+	assert.ErrorContains(t, err, "service test: x-aws-proxy-configuration: proxy configuration requires network mode awsvpc")
+	assert.ErrorContains(t, err, "service test: x-aws-appmesh: App Mesh isn't supported on ECS Anywhere instances")
+
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, true))
+	_, ok := project.Services[0].Extensions[extensionSchedule]
+	assert.Check(t, !ok)
+	assert.Equal(t, len(project.Services[0].Volumes), 0)
+}
@@ -79,6 +79,49 @@ var gpufamily = family{
 	},
 }
 
+var generalfamily = family{
+	{
+		id:     "m5.large",
+		cpus:   2,
+		memory: 8 * units.GiB,
+	},
+	{
+		id:     "m5.xlarge",
+		cpus:   4,
+		memory: 16 * units.GiB,
+	},
+	{
+		id:     "m5.2xlarge",
+		cpus:   8,
+		memory: 32 * units.GiB,
+	},
+	{
+		id:     "m5.4xlarge",
+		cpus:   16,
+		memory: 64 * units.GiB,
+	},
+	{
+		id:     "m5.8xlarge",
+		cpus:   32,
+		memory: 128 * units.GiB,
+	},
+	{
+		id:     "m5.12xlarge",
+		cpus:   48,
+		memory: 192 * units.GiB,
+	},
+	{
+		id:     "m5.16xlarge",
+		cpus:   64,
+		memory: 256 * units.GiB,
+	},
+	{
+		id:     "m5.24xlarge",
+		cpus:   96,
+		memory: 384 * units.GiB,
+	},
+}
+
 type filterFn func(machine) bool
 
 func (f family) filter(fn filterFn) family {
@@ -99,14 +142,18 @@ func (f family) firstOrError(msg string, args ...interface{}) (machine, error) {
 }
 
 func guessMachineType(project *types.Project) (string, error) {
-	// we select a machine type to match all gpus-bound services requirements
+	// we select a machine type to match all EC2-bound services requirements
 	// once https://github.com/aws/containers-roadmap/issues/631 is implemented we can define dedicated CapacityProviders per service.
 	requirements, err := getResourceRequirements(project)
 	if err != nil {
 		return "", err
 	}
 
-	instanceType, err := gpufamily.
+	machines, name := gpufamily, "G4"
+	if requirements.gpus == 0 {
+		machines, name = generalfamily, "M5"
+	}
+	instanceType, err := machines.
 		filter(func(m machine) bool {
 			return m.memory > requirements.memory // actual memory available for ECS tasks < total machine memory
 		}).
@@ -116,7 +163,7 @@ func guessMachineType(project *types.Project) (string, error) {
 		filter(func(m machine) bool {
 			return m.gpus >= requirements.gpus
 		}).
-		firstOrError("none of the Amazon EC2 G4 instance types meet the requirements for memory:%d cpu:%f gpus:%d", requirements.memory, requirements.cpus, requirements.gpus)
+		firstOrError("none of the Amazon EC2 %s instance types meet the requirements for memory:%d cpu:%f gpus:%d", name, requirements.memory, requirements.cpus, requirements.gpus)
 	if err != nil {
 		return "", err
 	}
@@ -132,7 +179,7 @@ type resourceRequirements struct {
 func getResourceRequirements(project *types.Project) (*resourceRequirements, error) {
 	return toResourceRequirementsSlice(project).
 		filter(func(requirements *resourceRequirements) bool {
-			return requirements.gpus != 0
+			return requirements != nil
 		}).
 		max()
 }
@@ -145,6 +192,9 @@ type eitherRequirementsOrError struct {
 func toResourceRequirementsSlice(project *types.Project) eitherRequirementsOrError {
 	var requirements []*resourceRequirements
 	for _, service := range project.Services {
+		if !requireEC2(service) {
+			continue
+		}
 		r, err := toResourceRequirements(service)
 		if err != nil {
 			return eitherRequirementsOrError{nil, err}
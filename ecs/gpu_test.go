@@ -98,6 +98,23 @@ services:
 			want:    "g4dn.12xlarge",
 			wantErr: false,
 		},
+		{
+			name: "no-gpu, shm_size",
+			yaml: `
+services:
+    front:
+        image: nginx
+    learning:
+        image: postgres
+        shm_size: 256m
+        deploy:
+            resources:
+                reservations:
+                   memory: 12Gb
+`,
+			want:    "m5.xlarge",
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -20,6 +20,10 @@ const (
 	ecsTaskExecutionPolicy = "arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy"
 	ecrReadOnlyPolicy      = "arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly"
 	ecsEC2InstanceRole     = "arn:aws:iam::aws:policy/service-role/AmazonEC2ContainerServiceforEC2Role"
+	codeDeployRoleForECS   = "arn:aws:iam::aws:policy/AWSCodeDeployRoleForECS"
+
+	// maxManagedPolicies is the default IAM quota of managed policies attached to a role
+	maxManagedPolicies = 10
 
 	actionGetSecretValue  = "secretsmanager:GetSecretValue"
 	actionGetParameters   = "ssm:GetParameters"
@@ -28,12 +32,33 @@ const (
 	actionGetMetrics      = "cloudwatch:GetMetricStatistics"
 	actionDescribeService = "ecs:DescribeServices"
 	actionUpdateService   = "ecs:UpdateService"
+	actionGetObject       = "s3:GetObject"
+	actionGetBucket       = "s3:GetBucketLocation"
+	actionListTasks       = "ecs:ListTasks"
+	actionDescribeTasks   = "ecs:DescribeTasks"
+	actionStopTask        = "ecs:StopTask"
+	actionCreateLogStream = "logs:CreateLogStream"
+	actionPutLogEvents    = "logs:PutLogEvents"
+	actionClientMount     = "elasticfilesystem:ClientMount"
+	actionClientWrite     = "elasticfilesystem:ClientWrite"
+
+	actionCreateControlChannel = "ssmmessages:CreateControlChannel"
+	actionCreateDataChannel    = "ssmmessages:CreateDataChannel"
+	actionOpenControlChannel   = "ssmmessages:OpenControlChannel"
+	actionOpenDataChannel      = "ssmmessages:OpenDataChannel"
+
+	actionStreamAggregatedResources = "appmesh:StreamAggregatedResources"
+
+	actionBatchCheckLayerAvailability = "ecr:BatchCheckLayerAvailability"
+	actionBatchGetImage               = "ecr:BatchGetImage"
+	actionGetDownloadURLForLayer      = "ecr:GetDownloadUrlForLayer"
 )
 
 var (
 	ecsTaskAssumeRolePolicyDocument     = policyDocument("ecs-tasks.amazonaws.com")
 	ec2InstanceAssumeRolePolicyDocument = policyDocument("ec2.amazonaws.com")
 	ausocalingAssumeRolePolicyDocument  = policyDocument("application-autoscaling.amazonaws.com")
+	codeDeployAssumeRolePolicyDocument  = policyDocument("codedeploy.amazonaws.com")
 )
 
 func policyDocument(service string) PolicyDocument {
@@ -61,10 +86,12 @@ type PolicyDocument struct {
 
 // PolicyStatement describes an IAM policy statement
 type PolicyStatement struct {
-	Effect    string          `json:",omitempty"`
-	Action    []string        `json:",omitempty"`
-	Principal PolicyPrincipal `json:",omitempty"`
-	Resource  []string        `json:",omitempty"`
+	Sid       string                 `json:",omitempty"`
+	Effect    string                 `json:",omitempty"`
+	Action    []string               `json:",omitempty"`
+	Principal PolicyPrincipal        `json:",omitempty"`
+	Resource  []string               `json:",omitempty"`
+	Condition map[string]interface{} `json:",omitempty"`
 }
 
 // PolicyPrincipal describes an IAM policy principal
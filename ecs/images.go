@@ -0,0 +1,64 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// splitImageTag splits image into repository and tag, which defaults to latest. Images pinned by digest are not split
+func splitImageTag(image string) (string, string, bool) {
+	if strings.Contains(image, "@") {
+		return "", "", false
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		// no tag, or colon is the registry port separator
+		return image, "latest", true
+	}
+	return image[:i], image[i+1:], true
+}
+
+// parameterizeImages sets container images of the task to use a <Service>ImageTag template parameter as tag when project
+// sets x-aws-parameterize_images, so a new version can be deployed by updating the stack parameters only
+func parameterizeImages(project *types.Project, members []types.ServiceConfig, definition *ecs.TaskDefinition, template *cloudformation.Template) {
+	if x, ok := project.Extensions[extensionParameterizeImages]; !ok || x != true {
+		return
+	}
+	for _, member := range members {
+		repository, tag, ok := splitImageTag(member.Image)
+		if !ok {
+			continue
+		}
+		parameter := fmt.Sprintf("%sImageTag", normalizeResourceName(member.Name))
+		template.Parameters[parameter] = cloudformation.Parameter{
+			Type:        "String",
+			Description: fmt.Sprintf("Tag of service %s image %s", member.Name, repository),
+			Default:     tag,
+		}
+		for i, container := range definition.ContainerDefinitions {
+			if container.Name == member.Name {
+				definition.ContainerDefinitions[i].Image = cloudformation.Sub(fmt.Sprintf("%s:${%s}", repository, parameter))
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+func TestSplitImageTag(t *testing.T) {
+	for image, expected := range map[string][2]string{
+		"nginx":                    {"nginx", "latest"},
+		"nginx:1.19":               {"nginx", "1.19"},
+		"registry:5000/app":        {"registry:5000/app", "latest"},
+		"registry:5000/team/app:2": {"registry:5000/team/app", "2"},
+		"012345678910.dkr.ecr.eu-west-3.amazonaws.com/app:v1": {"012345678910.dkr.ecr.eu-west-3.amazonaws.com/app", "v1"},
+	} {
+		repository, tag, ok := splitImageTag(image)
+		assert.Check(t, ok, image)
+		assert.Equal(t, repository, expected[0], image)
+		assert.Equal(t, tag, expected[1], image)
+	}
+	_, _, ok := splitImageTag("nginx@sha256:4cf620a5c81390ee209398ecc18e5fb9dd0f5155cd82adcbae532fec94006fb9")
+	assert.Check(t, !ok)
+}
+
+func TestParameterizeImages(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  front:
+    image: 012345678910.dkr.ecr.eu-west-3.amazonaws.com/front:v1.2
+  proxy:
+    image: envoyproxy/envoy
+    x-aws-sidecar_of: front
+  pinned:
+    image: redis@sha256:4cf620a5c81390ee209398ecc18e5fb9dd0f5155cd82adcbae532fec94006fb9
+x-aws-parameterize_images: true
+`)
+	assert.DeepEqual(t, template.Parameters, cloudformation.Parameters{
+		"FrontImageTag": {
+			Type:        "String",
+			Description: "Tag of service front image 012345678910.dkr.ecr.eu-west-3.amazonaws.com/front",
+			Default:     "v1.2",
+		},
+		"ProxyImageTag": {
+			Type:        "String",
+			Description: "Tag of service proxy image envoyproxy/envoy",
+			Default:     "latest",
+		},
+	})
+
+	def := template.Resources["FrontTaskDefinition"].(*ecs.TaskDefinition)
+	images := map[string]string{}
+	for _, container := range def.ContainerDefinitions {
+		images[container.Name] = container.Image
+	}
+	assert.Equal(t, images["front"], cloudformation.Sub("012345678910.dkr.ecr.eu-west-3.amazonaws.com/front:${FrontImageTag}"))
+	assert.Equal(t, images["proxy"], cloudformation.Sub("envoyproxy/envoy:${ProxyImageTag}"))
+
+	def = template.Resources["PinnedTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, getMainContainer(def, t).Image, "redis@sha256:4cf620a5c81390ee209398ecc18e5fb9dd0f5155cd82adcbae532fec94006fb9")
+}
@@ -0,0 +1,60 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+const (
+	containerInsightsSetting  = "containerInsights"
+	containerInsightsEnabled  = "enabled"
+	containerInsightsDisabled = "disabled"
+)
+
+// containerInsights returns the CloudWatch Container Insights setting set by x-aws-container_insights
+func containerInsights(project *types.Project) (string, bool, error) {
+	x, ok := project.Extensions[extensionContainerInsights]
+	if !ok {
+		return "", false, nil
+	}
+	value := fmt.Sprint(x)
+	if value != containerInsightsEnabled && value != containerInsightsDisabled {
+		return "", false, fmt.Errorf("%s must be %s or %s, got %q", extensionContainerInsights, containerInsightsEnabled, containerInsightsDisabled, value)
+	}
+	return value, true, nil
+}
+
+// checkClusterInsights warns when Container Insights are requested, but the existing cluster set by x-aws-cluster
+// doesn't have them enabled, as the setting can't be changed by the stack
+func (b *ecsAPIService) checkClusterInsights(ctx context.Context, project *types.Project, cluster string) error {
+	value, ok, err := containerInsights(project)
+	if err != nil || !ok || value != containerInsightsEnabled {
+		return err
+	}
+	settings, err := b.SDK.GetClusterSettings(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	if settings[containerInsightsSetting] != containerInsightsEnabled {
+		b.warn(warningContainerInsights, severityWarning, "", "Container Insights are disabled on cluster %s, %s has no effect on an existing cluster", cluster, extensionContainerInsights)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestContainerInsights(t *testing.T) {
+	project := load(t, "testdata/input/container-insights.yaml")
+	result := convertResultAsString(t, project)
+	golden.Assert(t, result, "insights/container-insights.golden")
+}
+
+func TestContainerInsightsInvalid(t *testing.T) {
+	_, err := (&ecsAPIService{}).convert(loadConfig(t, `
+services:
+  test:
+    image: nginx
+x-aws-container_insights: "yes"
+`), awsResources{})
+	assert.Error(t, err, `x-aws-container_insights must be enabled or disabled, got "yes"`)
+}
+
+func TestContainerInsightsExistingCluster(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+x-aws-cluster: shared
+x-aws-container_insights: enabled
+`)
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeClustersWithContext", "shared").Return(&ecsapi.DescribeClustersOutput{
+		Clusters: []*ecsapi.Cluster{
+			{
+				ClusterName: aws.String("shared"),
+				Settings: []*ecsapi.ClusterSetting{
+					{Name: aws.String("containerInsights"), Value: aws.String("disabled")},
+				},
+			},
+		},
+	}, nil)
+	backend := &ecsAPIService{SDK: sdk{ECS: ecsMock}}
+	cluster, err := backend.parseClusterExtension(context.TODO(), project)
+	assert.NilError(t, err)
+	assert.Equal(t, cluster, "shared")
+	assert.DeepEqual(t, backend.warnings, convertWarnings{
+		{
+			Code:     warningContainerInsights,
+			Severity: severityWarning,
+			Message:  "Container Insights are disabled on cluster shared, x-aws-container_insights has no effect on an existing cluster",
+		},
+	})
+}
@@ -0,0 +1,172 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/awslabs/goformation/v4/cloudformation/logs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// kmsKey returns the customer-managed KMS key set by x-aws-kms_key. IAM policies require a key ARN, not an alias
+func kmsKey(project *types.Project) (string, bool, error) {
+	x, ok := project.Extensions[extensionKMSKey]
+	if !ok {
+		return "", false, nil
+	}
+	key := fmt.Sprint(x)
+	parsed, err := arn.Parse(key)
+	if err != nil || parsed.Service != "kms" || !strings.HasPrefix(parsed.Resource, "key/") {
+		return "", false, fmt.Errorf("%s must be a KMS key ARN, got %q", extensionKMSKey, key)
+	}
+	return key, true, nil
+}
+
+// encryptedLogGroup is a LogGroup encrypted by a KMS key, not supported by goformation
+type encryptedLogGroup struct {
+	logs.LogGroup
+	KmsKeyId string
+}
+
+func (r encryptedLogGroup) MarshalJSON() ([]byte, error) {
+	return marshalResource(r.LogGroup, func(properties map[string]interface{}) {
+		properties["KmsKeyId"] = r.KmsKeyId
+	})
+}
+
+// checkKMSKeyPolicy warns when the key policy doesn't let ECS tasks and CloudWatch logs use the key
+func (b *ecsAPIService) checkKMSKeyPolicy(ctx context.Context, project *types.Project) error {
+	key, ok, err := kmsKey(project)
+	if err != nil || !ok {
+		return err
+	}
+	policy, err := b.SDK.GetKeyPolicy(ctx, key)
+	if err != nil {
+		b.warn(warningKMSKeyPolicy, severityWarning, "", "can't read policy of key %s, check it grants usage to ECS tasks and CloudWatch logs: %s", key, err)
+		return nil
+	}
+	document, err := parseKeyPolicy(policy)
+	if err != nil {
+		return err
+	}
+
+	// IAM policies of the account running the tasks only apply if key policy delegates to this account
+	parsed, _ := arn.Parse(key)
+	principals := []string{"ecs-tasks.amazonaws.com", "arn:" + parsed.Partition + ":iam::" + parsed.AccountID + ":root"}
+	caller, err := b.SDK.GetCallerIdentity(ctx)
+	if err != nil {
+		b.warn(warningKMSKeyPolicy, severityWarning, "", "can't get caller identity to check policy of key %s: %s", key, err)
+		return nil
+	}
+	if identity, err := arn.Parse(caller); err == nil && identity.AccountID != parsed.AccountID {
+		principals = append(principals, identity.AccountID, "arn:"+identity.Partition+":iam::"+identity.AccountID+":root")
+	}
+	if !document.allows(principals, actionDecrypt) {
+		b.warn(warningKMSKeyPolicy, severityWarning, "", "policy of key %s doesn't allow ECS tasks to use it for decryption", key)
+	}
+	if !document.allows([]string{fmt.Sprintf("logs.%s.amazonaws.com", parsed.Region)}, "kms:Encrypt") {
+		b.warn(warningKMSKeyPolicy, severityWarning, "", "policy of key %s doesn't allow CloudWatch logs to use it for encryption", key)
+	}
+	return nil
+}
+
+// keyPolicy is the subset of a KMS key policy document used to check permissions
+type keyPolicy struct {
+	Statement []keyPolicyStatement
+}
+
+type keyPolicyStatement struct {
+	Effect    string
+	Principal keyPolicyPrincipal
+	Action    stringOrSlice
+}
+
+type keyPolicyPrincipal struct {
+	AWS     stringOrSlice
+	Service stringOrSlice
+}
+
+func (p *keyPolicyPrincipal) UnmarshalJSON(b []byte) error {
+	var any string
+	if json.Unmarshal(b, &any) == nil {
+		p.AWS = []string{any}
+		return nil
+	}
+	type principal keyPolicyPrincipal
+	return json.Unmarshal(b, (*principal)(p))
+}
+
+type stringOrSlice []string
+
+func (s *stringOrSlice) UnmarshalJSON(b []byte) error {
+	var single string
+	if json.Unmarshal(b, &single) == nil {
+		*s = []string{single}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(s))
+}
+
+func parseKeyPolicy(policy string) (keyPolicy, error) {
+	var document keyPolicy
+	err := json.Unmarshal([]byte(policy), &document)
+	if err != nil {
+		// single statement policies don't use an array
+		var single struct {
+			Statement keyPolicyStatement
+		}
+		if json.Unmarshal([]byte(policy), &single) != nil {
+			return document, fmt.Errorf("invalid key policy: %w", err)
+		}
+		document.Statement = []keyPolicyStatement{single.Statement}
+	}
+	return document, nil
+}
+
+// allows tells if one of principals is allowed action by the key policy
+func (p keyPolicy) allows(principals []string, action string) bool {
+	for _, s := range p.Statement {
+		if s.Effect != "Allow" || !matchAction(s.Action, action) {
+			continue
+		}
+		for _, granted := range append(s.Principal.AWS, s.Principal.Service...) {
+			if granted == "*" {
+				return true
+			}
+			for _, principal := range principals {
+				if granted == principal {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func matchAction(actions []string, action string) bool {
+	for _, a := range actions {
+		if a == "*" || a == "kms:*" || a == action || (strings.HasSuffix(a, "*") && strings.HasPrefix(action, strings.TrimSuffix(a, "*"))) {
+			return true
+		}
+	}
+	return false
+}
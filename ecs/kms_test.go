@@ -0,0 +1,168 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/awslabs/goformation/v4/cloudformation/secretsmanager"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+)
+
+const testKMSKey = "arn:aws:kms:eu-west-3:210987654321:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+func TestKMSKeyEncryptsSecretsAndLogs(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - db
+secrets:
+  db:
+    file: ./testdata/input/envfile
+x-aws-kms_key: `+testKMSKey+`
+`)
+	secret := template.Resources["DbSecret"].(*secretsmanager.Secret)
+	assert.Equal(t, secret.KmsKeyId, testKMSKey)
+
+	logGroup := template.Resources["LogGroup"].(*encryptedLogGroup)
+	assert.Equal(t, logGroup.KmsKeyId, testKMSKey)
+	raw, err := json.Marshal(logGroup)
+	assert.NilError(t, err)
+	var marshalled struct {
+		Type       string
+		Properties map[string]interface{}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	assert.Equal(t, marshalled.Type, "AWS::Logs::LogGroup")
+	assert.Equal(t, marshalled.Properties["KmsKeyId"], testKMSKey)
+	assert.Equal(t, marshalled.Properties["LogGroupName"], "/docker-compose/Test")
+
+	role := template.Resources["TestTaskExecutionRole"].(*iam.Role)
+	policy := role.Policies[0].PolicyDocument.(*PolicyDocument)
+	last := policy.Statement[len(policy.Statement)-1]
+	assert.Equal(t, last.Sid, "KMSKey")
+	assert.DeepEqual(t, last.Action, []string{actionDecrypt})
+	assert.DeepEqual(t, last.Resource, []string{testKMSKey})
+}
+
+func TestKMSKeyEncryptedLogGroupRetained(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: hello_world
+x-aws-kms_key: `+testKMSKey+`
+x-aws-logs_retain: true
+`)
+	raw, err := json.Marshal(template.Resources["LogGroup"])
+	assert.NilError(t, err)
+	var marshalled struct {
+		DeletionPolicy string
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	assert.Equal(t, marshalled.DeletionPolicy, "Retain")
+}
+
+func TestKMSKeyMustBeAnARN(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+x-aws-kms_key: alias/compose
+`)
+	backend := &ecsAPIService{}
+	_, err := backend.convert(project, awsResources{})
+	assert.ErrorContains(t, err, "x-aws-kms_key must be a KMS key ARN")
+}
+
+func TestKeyPolicyAllows(t *testing.T) {
+	policy, err := parseKeyPolicy(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"AWS": "arn:aws:iam::210987654321:root"},
+      "Action": "kms:*",
+      "Resource": "*"
+    },
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": ["logs.eu-west-3.amazonaws.com"]},
+      "Action": ["kms:Encrypt*", "kms:Decrypt*", "kms:GenerateDataKey*"],
+      "Resource": "*"
+    },
+    {
+      "Effect": "Deny",
+      "Principal": "*",
+      "Action": "kms:Decrypt",
+      "Resource": "*"
+    }
+  ]
+}`)
+	assert.NilError(t, err)
+	assert.Check(t, policy.allows([]string{"arn:aws:iam::210987654321:root"}, "kms:Decrypt"))
+	assert.Check(t, policy.allows([]string{"logs.eu-west-3.amazonaws.com"}, "kms:Encrypt"))
+	assert.Check(t, !policy.allows([]string{"logs.eu-west-3.amazonaws.com"}, "kms:ScheduleKeyDeletion"))
+	assert.Check(t, !policy.allows([]string{"arn:aws:iam::123456789012:root", "ecs-tasks.amazonaws.com"}, "kms:Decrypt"))
+
+	single, err := parseKeyPolicy(`{"Statement": {"Effect": "Allow", "Principal": "*", "Action": "kms:Decrypt"}}`)
+	assert.NilError(t, err)
+	assert.Check(t, single.allows([]string{"arn:aws:iam::123456789012:root"}, "kms:Decrypt"))
+}
+
+type mockKMS struct {
+	kmsiface.KMSAPI
+	mock.Mock
+}
+
+func (m *mockKMS) GetKeyPolicyWithContext(_ aws.Context, in *kms.GetKeyPolicyInput, _ ...request.Option) (*kms.GetKeyPolicyOutput, error) {
+	args := m.Called(aws.StringValue(in.KeyId))
+	return args.Get(0).(*kms.GetKeyPolicyOutput), args.Error(1)
+}
+
+func TestKMSKeyPolicyCheckSkippedWithoutCallerIdentity(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+x-aws-kms_key: `+testKMSKey+`
+`)
+	kmsMock := &mockKMS{}
+	kmsMock.On("GetKeyPolicyWithContext", testKMSKey).Return(&kms.GetKeyPolicyOutput{
+		Policy: aws.String(`{"Statement":[]}`),
+	}, nil)
+	stsMock := &mockSTS{}
+	stsMock.On("GetCallerIdentityWithContext").Return((*sts.GetCallerIdentityOutput)(nil), errors.New("expired token"))
+	backend := &ecsAPIService{SDK: sdk{KMS: kmsMock, STS: stsMock}}
+
+	err := backend.checkKMSKeyPolicy(context.TODO(), project)
+	assert.NilError(t, err)
+	assert.Equal(t, len(backend.warnings), 1)
+	assert.Equal(t, backend.warnings[0].Code, warningKMSKeyPolicy)
+	assert.Equal(t, backend.warnings[0].Message, "can't get caller identity to check policy of key "+testKMSKey+": expired token")
+}
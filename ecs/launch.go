@@ -0,0 +1,149 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/awslabs/goformation/v4/cloudformation/autoscaling"
+	"github.com/awslabs/goformation/v4/cloudformation/ec2"
+	"github.com/compose-spec/compose-go/types"
+)
+
+const (
+	// userDataBoundary separates the MIME parts of user data, so cloud-init runs the bootstrap script before the custom one
+	userDataBoundary = "==COMPOSE_USER_DATA=="
+	// rootDeviceName is the root volume of ECS optimized Amazon Linux 2 AMIs
+	rootDeviceName = "/dev/xvda"
+	maxVolumeSize  = 16384
+)
+
+var volumeTypes = []string{"gp2", "gp3", "io1", "io2", "st1", "sc1", "standard"}
+
+// instanceUserData is the base64 encoded user data of capacity provider instances, registering them to project's cluster.
+// A custom x-aws-ec2_user_data script or cloud-config runs after this bootstrap script, as a separate MIME part
+func instanceUserData(project *types.Project, spot bool) (string, error) {
+	bootstrap := fmt.Sprintf("#!/bin/bash\necho ECS_CLUSTER=%s >> /etc/ecs/ecs.config", project.Name)
+	if spot {
+		// the agent drains container instances on spot interruption notice, so tasks get rescheduled before reclamation
+		bootstrap += "\necho ECS_ENABLE_SPOT_INSTANCE_DRAINING=true >> /etc/ecs/ecs.config"
+	}
+	x, ok := project.Extensions[extensionEC2UserData]
+	if !ok {
+		return base64.StdEncoding.EncodeToString([]byte(bootstrap)), nil
+	}
+	custom, ok := x.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", extensionEC2UserData)
+	}
+	var contentType string
+	switch {
+	case strings.HasPrefix(custom, "#cloud-config"):
+		contentType = "text/cloud-config"
+	case strings.HasPrefix(custom, "#!"):
+		contentType = "text/x-shellscript"
+	default:
+		return "", fmt.Errorf("%s must be a script starting with #! or a #cloud-config document", extensionEC2UserData)
+	}
+	if strings.Contains(custom, "ECS_CLUSTER") {
+		return "", fmt.Errorf("%s can't set ECS_CLUSTER, which registers instances to the project's cluster", extensionEC2UserData)
+	}
+	if strings.Contains(custom, userDataBoundary) {
+		return "", fmt.Errorf("%s can't contain the %s MIME boundary", extensionEC2UserData, userDataBoundary)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%q\nMIME-Version: 1.0\n\n", userDataBoundary)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/x-shellscript", bootstrap},
+		{contentType, custom},
+	} {
+		fmt.Fprintf(&b, "--%s\nContent-Type: %s; charset=\"us-ascii\"\n\n%s\n\n", userDataBoundary, part.contentType, strings.TrimSuffix(part.content, "\n"))
+	}
+	fmt.Fprintf(&b, "--%s--\n", userDataBoundary)
+	return base64.StdEncoding.EncodeToString([]byte(b.String())), nil
+}
+
+// rootVolume is set by x-aws-ec2_volume_size and x-aws-ec2_volume_type, sizing the root EBS volume of capacity provider
+// instances, which stores the images they pull
+type rootVolume struct {
+	size       int
+	volumeType string
+}
+
+func instanceRootVolume(project *types.Project) (*rootVolume, error) {
+	var volume *rootVolume
+	if x, ok := project.Extensions[extensionEC2VolumeSize]; ok {
+		size, ok := x.(int)
+		if !ok || size < 1 || size > maxVolumeSize {
+			return nil, fmt.Errorf("%s must be a size in GiB between 1 and %d, got %v", extensionEC2VolumeSize, maxVolumeSize, x)
+		}
+		volume = &rootVolume{size: size}
+	}
+	if x, ok := project.Extensions[extensionEC2VolumeType]; ok {
+		volumeType := fmt.Sprint(x)
+		var supported bool
+		for _, t := range volumeTypes {
+			supported = supported || t == volumeType
+		}
+		if !supported {
+			return nil, fmt.Errorf("unsupported %s %q, must be one of %s", extensionEC2VolumeType, volumeType, strings.Join(volumeTypes, ", "))
+		}
+		if volume == nil {
+			volume = &rootVolume{}
+		}
+		volume.volumeType = volumeType
+	}
+	return volume, nil
+}
+
+func (v *rootVolume) launchConfigurationMappings() []autoscaling.LaunchConfiguration_BlockDeviceMapping {
+	if v == nil {
+		return nil
+	}
+	return []autoscaling.LaunchConfiguration_BlockDeviceMapping{
+		{
+			DeviceName: rootDeviceName,
+			Ebs: &autoscaling.LaunchConfiguration_BlockDevice{
+				DeleteOnTermination: true,
+				VolumeSize:          v.size,
+				VolumeType:          v.volumeType,
+			},
+		},
+	}
+}
+
+func (v *rootVolume) launchTemplateMappings() []ec2.LaunchTemplate_BlockDeviceMapping {
+	if v == nil {
+		return nil
+	}
+	return []ec2.LaunchTemplate_BlockDeviceMapping{
+		{
+			DeviceName: rootDeviceName,
+			Ebs: &ec2.LaunchTemplate_Ebs{
+				DeleteOnTermination: true,
+				VolumeSize:          v.size,
+				VolumeType:          v.volumeType,
+			},
+		},
+	}
+}
@@ -0,0 +1,104 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/autoscaling"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+const customLaunchYaml = `
+services:
+  worker:
+    image: hello_world
+    shm_size: 64M
+x-aws-ec2_user_data: |
+  #!/bin/bash
+  sysctl -w vm.max_map_count=262144
+x-aws-ec2_volume_size: 200
+x-aws-ec2_volume_type: gp3
+`
+
+func TestCustomLaunchSettings(t *testing.T) {
+	project := loadConfig(t, customLaunchYaml)
+	ssmMock := &mockSSM{}
+	ssmMock.On("GetParameterWithContext", "/aws/service/ecs/optimized-ami/amazon-linux-2/recommended").Return(&ssm.GetParameterOutput{
+		Parameter: &ssm.Parameter{Value: aws.String(`{"image_id": "ami-123456"}`)},
+	}, nil)
+	backend := &ecsAPIService{SDK: sdk{SSM: ssmMock}}
+	template := cloudformation.NewTemplate()
+	template.Resources["Cluster"] = &ecs.Cluster{}
+	err := backend.createCapacityProvider(context.TODO(), project, template, awsResources{
+		cluster: cloudformation.Ref("Cluster"),
+		subnets: []string{"subnet1", "subnet2"},
+	})
+	assert.NilError(t, err)
+
+	launch := template.Resources["LaunchConfiguration"].(*autoscaling.LaunchConfiguration)
+	userData, err := base64.StdEncoding.DecodeString(launch.UserData)
+	assert.NilError(t, err)
+	golden.Assert(t, string(userData), "ec2/ec2-user-data.golden")
+
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	golden.Assert(t, string(raw)+"\n", "ec2/ec2-launch-settings.golden")
+}
+
+func TestSpotLaunchTemplateSettings(t *testing.T) {
+	project := loadConfig(t, customLaunchYaml+`
+x-aws-ec2_spot:
+  instance_types:
+    - m5.large
+    - m5a.large
+`)
+	template := cloudformation.NewTemplate()
+	template.Resources["Cluster"] = &ecs.Cluster{}
+	err := spotBackend().createCapacityProvider(context.TODO(), project, template, awsResources{
+		cluster: cloudformation.Ref("Cluster"),
+		subnets: []string{"subnet1", "subnet2"},
+	})
+	assert.NilError(t, err)
+
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	golden.Assert(t, string(raw)+"\n", "ec2/ec2-spot-launch-template.golden")
+}
+
+func TestInvalidLaunchSettings(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		"x-aws-ec2_user_data: sysctl -w vm.max_map_count=262144":                              "x-aws-ec2_user_data must be a script starting with #! or a #cloud-config document",
+		"x-aws-ec2_user_data: \"#!/bin/bash\\necho ECS_CLUSTER=other > /etc/ecs/ecs.config\"": "x-aws-ec2_user_data can't set ECS_CLUSTER, which registers instances to the project's cluster",
+		"x-aws-ec2_volume_size: 20000":                                                        "x-aws-ec2_volume_size must be a size in GiB between 1 and 16384, got 20000",
+		"x-aws-ec2_volume_type: magnetic":                                                     `unsupported x-aws-ec2_volume_type "magnetic", must be one of gp2, gp3, io1, io2, st1, sc1, standard`,
+	} {
+		project := loadConfig(t, "services:\n  worker:\n    image: hello_world\n"+yaml)
+		_, err := instanceUserData(project, false)
+		if err == nil {
+			_, err = instanceRootVolume(project)
+		}
+		assert.Error(t, err, expected)
+	}
+}
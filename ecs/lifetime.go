@@ -0,0 +1,194 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/events"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/awslabs/goformation/v4/cloudformation/lambda"
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+const lambdaBasicExecutionPolicy = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+
+var lambdaAssumeRolePolicyDocument = policyDocument("lambda.amazonaws.com")
+
+// recycleTasksCode stops tasks older than LIFETIME seconds, oldest first, never more at once than
+// deployment configuration allows, and only when service is stable so ECS can replace them.
+const recycleTasksCode = `import datetime
+import os
+
+import boto3
+
+ecs = boto3.client('ecs')
+
+
+def handler(event, context):
+    cluster = os.environ['CLUSTER']
+    service = os.environ['SERVICE']
+    lifetime = datetime.timedelta(seconds=int(os.environ['LIFETIME']))
+    min_percent = int(os.environ['MIN_PERCENT'])
+    max_percent = int(os.environ['MAX_PERCENT'])
+
+    svc = ecs.describe_services(cluster=cluster, services=[service])['services'][0]
+    desired = svc['desiredCount']
+    if svc['runningCount'] < desired or len(svc['deployments']) > 1:
+        return
+    batch = min(max(1, desired * (100 - min_percent) // 100),
+                max(1, desired * (max_percent - 100) // 100))
+
+    arns = []
+    for page in ecs.get_paginator('list_tasks').paginate(cluster=cluster, serviceName=service, desiredStatus='RUNNING'):
+        arns.extend(page['taskArns'])
+    if not arns:
+        return
+    now = datetime.datetime.now(datetime.timezone.utc)
+    tasks = []
+    for i in range(0, len(arns), 100):
+        tasks.extend(ecs.describe_tasks(cluster=cluster, tasks=arns[i:i + 100])['tasks'])
+    expired = sorted([t for t in tasks if 'startedAt' in t and now - t['startedAt'] > lifetime],
+                     key=lambda t: t['startedAt'])
+    for t in expired[:batch]:
+        ecs.stop_task(cluster=cluster, task=t['taskArn'], reason='maximum task lifetime exceeded')
+`
+
+// getMaxTaskLifetime parses x-aws-max-task-lifetime
+func getMaxTaskLifetime(service types.ServiceConfig) (time.Duration, bool, error) {
+	x, ok := service.Extensions[extensionMaxTaskLifetime]
+	if !ok {
+		return 0, false, nil
+	}
+	lifetime, err := time.ParseDuration(fmt.Sprint(x))
+	if err != nil {
+		return 0, false, fmt.Errorf("service %s: invalid %s: %w", service.Name, extensionMaxTaskLifetime, err)
+	}
+	if lifetime < 5*time.Minute {
+		return 0, false, fmt.Errorf("service %s: %s must be at least 5m", service.Name, extensionMaxTaskLifetime)
+	}
+	return lifetime, true, nil
+}
+
+// recycleSchedule checks for expired tasks often enough for them not to exceed lifetime by more than ~10%
+func recycleSchedule(lifetime time.Duration) string {
+	minutes := int(lifetime.Minutes() / 10)
+	switch {
+	case minutes <= 1:
+		return "rate(1 minute)"
+	case minutes > 60:
+		return "rate(60 minutes)"
+	}
+	return fmt.Sprintf("rate(%d minutes)", minutes)
+}
+
+func (b *ecsAPIService) createTaskRecycling(project *types.Project, resources awsResources, template *cloudformation.Template, service types.ServiceConfig) error {
+	lifetime, ok, err := getMaxTaskLifetime(service)
+	if err != nil || !ok {
+		return err
+	}
+	minPercent, maxPercent, err := computeRollingUpdateLimits(service)
+	if err != nil {
+		return err
+	}
+
+	serviceResource := serviceResourceName(service.Name)
+	role := fmt.Sprintf("%sRecycleTasksRole", normalizeResourceName(service.Name))
+	template.Resources[role] = &iam.Role{
+		AssumeRolePolicyDocument: lambdaAssumeRolePolicyDocument,
+		ManagedPolicyArns:        []string{b.partitionArn(lambdaBasicExecutionPolicy)},
+		Policies: []iam.Role_Policy{
+			{
+				PolicyDocument: &PolicyDocument{
+					Statement: []PolicyStatement{
+						{
+							Effect:   "Allow",
+							Action:   []string{actionDescribeService},
+							Resource: []string{cloudformation.Ref(serviceResource)},
+						},
+						{
+							Effect:   "Allow",
+							Action:   []string{actionListTasks, actionDescribeTasks},
+							Resource: []string{"*"},
+						},
+						{
+							Effect:   "Allow",
+							Action:   []string{actionStopTask},
+							Resource: []string{"*"},
+							Condition: map[string]interface{}{
+								"StringEquals": map[string]string{
+									"aws:ResourceTag/" + compose.ProjectTag: project.Name,
+									"aws:ResourceTag/" + compose.ServiceTag: service.Name,
+								},
+							},
+						},
+					},
+				},
+				PolicyName: fmt.Sprintf("%sRecycleTasks", service.Name),
+			},
+		},
+		Tags: serviceTags(project, service),
+	}
+
+	function := fmt.Sprintf("%sRecycleTasksFunction", normalizeResourceName(service.Name))
+	template.Resources[function] = &lambda.Function{
+		Code: &lambda.Function_Code{
+			ZipFile: recycleTasksCode,
+		},
+		Description: fmt.Sprintf("Stop %s tasks running for more than %s", service.Name, lifetime),
+		Environment: &lambda.Function_Environment{
+			Variables: map[string]string{
+				"CLUSTER":     resources.cluster,
+				"SERVICE":     cloudformation.GetAtt(serviceResource, "Name"),
+				"LIFETIME":    strconv.Itoa(int(lifetime.Seconds())),
+				"MIN_PERCENT": strconv.Itoa(minPercent),
+				"MAX_PERCENT": strconv.Itoa(maxPercent),
+			},
+		},
+		Handler: "index.handler",
+		Role:    cloudformation.GetAtt(role, "Arn"),
+		Runtime: "python3.12",
+		Timeout: 60,
+		Tags:    serviceTags(project, service),
+	}
+
+	rule := fmt.Sprintf("%sRecycleTasksSchedule", normalizeResourceName(service.Name))
+	template.Resources[rule] = &events.Rule{
+		Description:        fmt.Sprintf("Recycle %s tasks", service.Name),
+		ScheduleExpression: recycleSchedule(lifetime),
+		State:              "ENABLED",
+		Targets: []events.Rule_Target{
+			{
+				Arn: cloudformation.GetAtt(function, "Arn"),
+				Id:  "RecycleTasks",
+			},
+		},
+	}
+
+	template.Resources[fmt.Sprintf("%sRecycleTasksPermission", normalizeResourceName(service.Name))] = &lambda.Permission{
+		Action:       "lambda:InvokeFunction",
+		FunctionName: cloudformation.Ref(function),
+		Principal:    "events.amazonaws.com",
+		SourceArn:    cloudformation.GetAtt(rule, "Arn"),
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/awslabs/goformation/v4/cloudformation/events"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/awslabs/goformation/v4/cloudformation/lambda"
+	"gotest.tools/v3/assert"
+)
+
+func TestMaxTaskLifetime(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  foo:
+    image: hello_world
+    x-aws-max-task-lifetime: 24h
+    deploy:
+      replicas: 4
+      update_config:
+        parallelism: 2
+`)
+	function := template.Resources["FooRecycleTasksFunction"].(*lambda.Function)
+	assert.Equal(t, function.Environment.Variables["LIFETIME"], "86400")
+	assert.Equal(t, function.Environment.Variables["MIN_PERCENT"], "50")
+	assert.Equal(t, function.Environment.Variables["MAX_PERCENT"], "150")
+
+	rule := template.Resources["FooRecycleTasksSchedule"].(*events.Rule)
+	assert.Equal(t, rule.ScheduleExpression, "rate(60 minutes)")
+
+	role := template.Resources["FooRecycleTasksRole"].(*iam.Role)
+	statements := role.Policies[0].PolicyDocument.(*PolicyDocument).Statement
+	stop := statements[len(statements)-1]
+	assert.DeepEqual(t, stop.Action, []string{actionStopTask})
+	assert.DeepEqual(t, stop.Condition["StringEquals"], map[string]string{
+		"aws:ResourceTag/com.docker.compose.project": "Test",
+		"aws:ResourceTag/com.docker.compose.service": "foo",
+	})
+
+	_, ok := template.Resources["FooRecycleTasksPermission"].(*lambda.Permission)
+	assert.Check(t, ok)
+}
+
+func TestMaxTaskLifetimeInvalid(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  foo:
+    image: hello_world
+    x-aws-max-task-lifetime: 1m
+`)
+	backend := &ecsAPIService{}
+	_, err := backend.convert(project, awsResources{})
+	assert.ErrorContains(t, err, "must be at least 5m")
+}
+
+func TestRecycleSchedule(t *testing.T) {
+	assert.Equal(t, recycleSchedule(5*time.Minute), "rate(1 minute)")
+	assert.Equal(t, recycleSchedule(2*time.Hour), "rate(12 minutes)")
+	assert.Equal(t, recycleSchedule(72*time.Hour), "rate(60 minutes)")
+}
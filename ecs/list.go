@@ -18,11 +18,21 @@ package ecs
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
 
 	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
+	"github.com/docker/compose-cli/progress"
 )
 
+// errCapacityUnavailable is reported when ECS can't place a service's tasks until capacity is made available
+var errCapacityUnavailable = errors.New("capacity is unavailable to place tasks")
+
 func (b *ecsAPIService) List(ctx context.Context, project string) ([]compose.Stack, error) {
 	stacks, err := b.SDK.ListStacks(ctx, project)
 	if err != nil {
@@ -31,7 +41,7 @@ func (b *ecsAPIService) List(ctx context.Context, project string) ([]compose.Sta
 
 	for _, stack := range stacks {
 		if stack.Status == compose.STARTING {
-			if err := b.checkStackState(ctx, stack.Name); err != nil {
+			if err := b.checkStackState(ctx, stack.Name, nil); err != nil {
 				stack.Status = compose.FAILED
 				stack.Reason = err.Error()
 			}
@@ -41,7 +51,9 @@ func (b *ecsAPIService) List(ctx context.Context, project string) ([]compose.Sta
 
 }
 
-func (b *ecsAPIService) checkStackState(ctx context.Context, name string) error {
+// checkStackState fails if a service of the stack can't run its tasks. Services listed in fallbacks are redeployed
+// once with their fallback capacity provider strategy when capacity is unavailable
+func (b *ecsAPIService) checkStackState(ctx context.Context, name string, fallbacks map[string][]capacityStrategy) error {
 	resources, err := b.SDK.ListStackResources(ctx, name)
 	if err != nil {
 		return err
@@ -70,41 +82,101 @@ func (b *ecsAPIService) checkStackState(ctx context.Context, name string) error
 		return err
 	}
 	for service, taskDef := range services {
-		if err := b.checkServiceState(ctx, cluster, service, taskDef); err != nil {
-			return fmt.Errorf("%s %s", svcNames[service], err.Error())
+		err := b.checkServiceState(ctx, cluster, service, taskDef)
+		if err == errCapacityUnavailable {
+			logicalID := svcNames[service]
+			if strategy := fallbacks[logicalID]; len(strategy) > 0 {
+				// fallback is only attempted once
+				fallbacks[logicalID] = nil
+				progress.ContextWriter(ctx).Event(progress.Event{
+					ID:         logicalID,
+					Status:     progress.Working,
+					StatusText: "capacity unavailable, retrying with fallback capacity",
+				})
+				if err := b.SDK.UpdateServiceCapacity(ctx, cluster, service, strategy); err != nil {
+					return err
+				}
+				continue
+			}
+			err = fmt.Errorf("%w: deploy to another region, use EC2 launch type, reduce resources limits or set %s", err, extensionFallbackCapacity)
+		}
+		if err != nil {
+			return &errdefs.Error{
+				Kind:     errdefs.ErrDeploymentFailed,
+				Resource: svcNames[service],
+				Err:      fmt.Errorf("%s %s", svcNames[service], err.Error()),
+			}
 		}
 	}
 	return nil
 }
 
+// taskFailureThreshold is the number of tasks failing to start after which a service is considered broken
+const taskFailureThreshold = 3
+
+// checkServiceState fails when tasks of the service's task definition repeatedly stop without any running, with the
+// reason the last one stopped
 func (b *ecsAPIService) checkServiceState(ctx context.Context, cluster string, service string, taskdef string) error {
 	runningTasks, err := b.SDK.GetServiceTasks(ctx, cluster, service, false)
 	if err != nil {
 		return err
 	}
-	if len(runningTasks) > 0 {
-		return nil
+	// during an update, tasks of the previous task definition keep running until the new ones are healthy
+	for _, t := range runningTasks {
+		if aws.StringValue(t.TaskDefinitionArn) == taskdef {
+			return nil
+		}
 	}
 	stoppedTasks, err := b.SDK.GetServiceTasks(ctx, cluster, service, true)
 	if err != nil {
 		return err
 	}
 	if len(stoppedTasks) == 0 {
+		events, err := b.SDK.GetServiceEvents(ctx, cluster, service)
+		if err != nil {
+			return err
+		}
+		for _, message := range events {
+			if isCapacityUnavailable(message) {
+				return errCapacityUnavailable
+			}
+		}
 		return nil
 	}
 	// filter tasks by task definition
-	tasks := []string{}
+	var (
+		failures int
+		last     *ecsapi.Task
+	)
 	for _, t := range stoppedTasks {
-		if t.TaskDefinitionArn != nil && *t.TaskDefinitionArn == taskdef {
-			tasks = append(tasks, *t.TaskArn)
+		if aws.StringValue(t.TaskDefinitionArn) != taskdef {
+			continue
+		}
+		failures++
+		if last == nil || aws.TimeValue(t.StoppedAt).After(aws.TimeValue(last.StoppedAt)) {
+			last = t
 		}
 	}
-	if len(tasks) == 0 {
+	if failures < taskFailureThreshold {
 		return nil
 	}
-	reason, err := b.SDK.GetTaskStoppedReason(ctx, cluster, tasks[0])
-	if err != nil {
-		return err
+	return fmt.Errorf("%d tasks failed to start, last one stopped with %s", failures, taskStoppedReason(last))
+}
+
+// taskStoppedReason explains why a task stopped, with the reason or exit code of its failed containers
+func taskStoppedReason(task *ecsapi.Task) string {
+	reason := fmt.Sprintf("%s: %s", aws.StringValue(task.StopCode), aws.StringValue(task.StoppedReason))
+	var containers []string
+	for _, c := range task.Containers {
+		switch {
+		case aws.StringValue(c.Reason) != "":
+			containers = append(containers, fmt.Sprintf("%s: %s", aws.StringValue(c.Name), aws.StringValue(c.Reason)))
+		case aws.Int64Value(c.ExitCode) != 0:
+			containers = append(containers, fmt.Sprintf("%s exited with code %d", aws.StringValue(c.Name), aws.Int64Value(c.ExitCode)))
+		}
+	}
+	if len(containers) > 0 {
+		reason = fmt.Sprintf("%s (%s)", reason, strings.Join(containers, ", "))
 	}
-	return fmt.Errorf("%s", reason)
+	return reason
 }
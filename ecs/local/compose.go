@@ -25,6 +25,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	types2 "github.com/docker/docker/api/types"
@@ -40,7 +41,7 @@ import (
 	"golang.org/x/mod/semver"
 )
 
-func (e ecsLocalSimulation) Up(ctx context.Context, project *types.Project, detach bool) error {
+func (e ecsLocalSimulation) Up(ctx context.Context, project *types.Project, options compose.UpOptions) error {
 	cmd := exec.Command("docker-compose", "version", "--short")
 	b := bytes.Buffer{}
 	b.WriteString("v")
@@ -57,19 +58,19 @@ func (e ecsLocalSimulation) Up(ctx context.Context, project *types.Project, deta
 		return fmt.Errorf("ECS simulation mode require Docker-compose 1.27, found %s", version)
 	}
 
-	converted, err := e.Convert(ctx, project)
+	converted, err := e.Convert(ctx, project, compose.ConvertOptions{})
 	if err != nil {
 		return err
 	}
 
 	cmd = exec.Command("docker-compose", "--context", "default", "--project-directory", project.WorkingDir, "--project-name", project.Name, "-f", "-", "up")
-	cmd.Stdin = strings.NewReader(string(converted))
+	cmd.Stdin = strings.NewReader(string(converted.Template))
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
-func (e ecsLocalSimulation) Convert(ctx context.Context, project *types.Project) ([]byte, error) {
+func (e ecsLocalSimulation) Convert(ctx context.Context, project *types.Project, options compose.ConvertOptions) (compose.ConvertResult, error) {
 	project.Networks["credentials_network"] = types.NetworkConfig{
 		Driver: "bridge",
 		Ipam: types.IPAMConfig{
@@ -85,7 +86,7 @@ func (e ecsLocalSimulation) Convert(ctx context.Context, project *types.Project)
 	// On Windows, this directory can be found at "%UserProfile%\.aws"
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return nil, err
+		return compose.ConvertResult{}, err
 	}
 
 	for i, service := range project.Services {
@@ -137,10 +138,11 @@ func (e ecsLocalSimulation) Convert(ctx context.Context, project *types.Project)
 		"secrets":  project.Secrets,
 		"configs":  project.Configs,
 	}
-	return yaml.Marshal(config)
+	template, err := yaml.Marshal(config)
+	return compose.ConvertResult{Template: template}, err
 }
 
-func (e ecsLocalSimulation) Down(ctx context.Context, projectName string) error {
+func (e ecsLocalSimulation) Down(ctx context.Context, projectName string, options compose.DownOptions) error {
 	cmd := exec.Command("docker-compose", "--context", "default", "--project-name", projectName, "-f", "-", "down", "--remove-orphans")
 	cmd.Stdin = strings.NewReader(string(`
 services:
@@ -152,7 +154,7 @@ services:
 	return cmd.Run()
 }
 
-func (e ecsLocalSimulation) Logs(ctx context.Context, projectName string, w io.Writer) error {
+func (e ecsLocalSimulation) Logs(ctx context.Context, projectName string, w io.Writer, options compose.LogOptions) error {
 	list, err := e.moby.ContainerList(ctx, types2.ContainerListOptions{
 		Filters: filters.NewArgs(filters.Arg("label", "com.docker.compose.project="+projectName)),
 	})
@@ -172,7 +174,15 @@ func (e ecsLocalSimulation) Logs(ctx context.Context, projectName string, w io.W
 	if err != nil {
 		return err
 	}
-	cmd := exec.Command("docker-compose", "--context", "default", "--project-name", projectName, "-f", "-", "logs", "-f")
+	args := []string{"--context", "default", "--project-name", projectName, "-f", "-", "logs"}
+	if options.Follow {
+		args = append(args, "--follow")
+	}
+	if options.Tail >= 0 {
+		args = append(args, "--tail", strconv.Itoa(options.Tail))
+	}
+	args = append(args, options.Services...)
+	cmd := exec.Command("docker-compose", args...)
 	cmd.Stdin = strings.NewReader(string(marshal))
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -185,3 +195,36 @@ func (e ecsLocalSimulation) Ps(ctx context.Context, projectName string) ([]compo
 func (e ecsLocalSimulation) List(ctx context.Context, projectName string) ([]compose.Stack, error) {
 	return nil, errors.Wrap(errdefs.ErrNotImplemented, "use docker-compose ls")
 }
+func (e ecsLocalSimulation) Orphans(ctx context.Context, projectName string) ([]compose.Orphan, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+func (e ecsLocalSimulation) RemoveOrphans(ctx context.Context, orphans []compose.Orphan) error {
+	return errdefs.ErrNotImplemented
+}
+func (e ecsLocalSimulation) ProjectOrphans(ctx context.Context, project *types.Project) ([]compose.Orphan, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+func (e ecsLocalSimulation) DNSRecords(ctx context.Context, projectName string) ([]compose.DNSRecord, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+func (e ecsLocalSimulation) Drift(ctx context.Context, projectName string) ([]compose.DriftedResource, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+func (e ecsLocalSimulation) Exec(ctx context.Context, projectName string, options compose.ExecOptions) error {
+	return errdefs.ErrNotImplemented
+}
+func (e ecsLocalSimulation) Run(ctx context.Context, projectName string, options compose.RunOptions) (int, error) {
+	return 0, errors.Wrap(errdefs.ErrNotImplemented, "use docker-compose run")
+}
+func (e ecsLocalSimulation) Events(ctx context.Context, projectName string, consumer func(compose.ServiceEvent), options compose.EventsOptions) error {
+	return errors.Wrap(errdefs.ErrNotImplemented, "use docker-compose events")
+}
+func (e ecsLocalSimulation) Scale(ctx context.Context, projectName string, options compose.ScaleOptions) error {
+	return errors.Wrap(errdefs.ErrNotImplemented, "use docker-compose scale")
+}
+func (e ecsLocalSimulation) Stop(ctx context.Context, projectName string) error {
+	return errors.Wrap(errdefs.ErrNotImplemented, "use docker-compose stop")
+}
+func (e ecsLocalSimulation) Start(ctx context.Context, projectName string, options compose.StartOptions) error {
+	return errors.Wrap(errdefs.ErrNotImplemented, "use docker-compose start")
+}
@@ -0,0 +1,155 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/logs"
+	"github.com/awslabs/goformation/v4/cloudformation/policies"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// logsRetentionDays are the retention periods supported by CloudWatch logs
+var logsRetentionDays = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1827, 3653}
+
+// logsRetention returns the retention set by x-aws-logs_retention, 0 if unset
+func logsRetention(extensions map[string]interface{}) (int, error) {
+	x, ok := extensions[extensionRetention]
+	if !ok {
+		return 0, nil
+	}
+	for _, days := range logsRetentionDays {
+		if x == days {
+			return days, nil
+		}
+	}
+	allowed := make([]string, len(logsRetentionDays))
+	for i, days := range logsRetentionDays {
+		allowed[i] = strconv.Itoa(days)
+	}
+	return 0, fmt.Errorf("%s must be one of %s, got %v", extensionRetention, strings.Join(allowed, ", "), x)
+}
+
+// logsGroup returns the existing log group set by x-aws-logs_group for project's containers to log to
+func logsGroup(project *types.Project) (string, bool) {
+	x, ok := project.Extensions[extensionLogsGroup]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(x), true
+}
+
+// serviceLogsGroups tells if services get a log group each, as required to set retention by service
+func serviceLogsGroups(project *types.Project) bool {
+	for _, service := range project.Services {
+		if _, ok := service.Extensions[extensionRetention]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func logGroupResourceName(service string) string {
+	return fmt.Sprintf("%sLogGroup", normalizeResourceName(service))
+}
+
+// logsGroupRef is the name of the log group service's containers log to
+func logsGroupRef(project *types.Project, service types.ServiceConfig) string {
+	if name, ok := logsGroup(project); ok {
+		return name
+	}
+	if serviceLogsGroups(project) {
+		return cloudformation.Ref(logGroupResourceName(service.Name))
+	}
+	return cloudformation.Ref("LogGroup")
+}
+
+// logsGroupArn returns the ARN covering log streams of the log group
+func logsGroupArn(name string) string {
+	return cloudformation.Sub("arn:${AWS::Partition}:logs:${AWS::Region}:${AWS::AccountId}:log-group:" + name + ":*")
+}
+
+func (b *ecsAPIService) createLogGroup(project *types.Project, template *cloudformation.Template) error {
+	if name, ok := logsGroup(project); ok {
+		if _, ok := project.Extensions[extensionRetention]; ok || serviceLogsGroups(project) {
+			b.warn(warningUnsupportedAttribute, severityWarning, "", "%s is ignored as log group %s is not managed by the stack", extensionRetention, name)
+		}
+		// recorded for logs to be read from the same group
+		template.Outputs["LogGroup"] = cloudformation.Output{
+			Value: name,
+		}
+		return nil
+	}
+	retention, err := logsRetention(project.Extensions)
+	if err != nil {
+		return err
+	}
+	if !serviceLogsGroups(project) {
+		return b.addLogGroup(project, template, "LogGroup", fmt.Sprintf("/docker-compose/%s", project.Name), retention)
+	}
+
+	names := make([]string, 0, len(project.Services))
+	services := map[string]types.ServiceConfig{}
+	for _, service := range project.Services {
+		names = append(names, service.Name)
+		services[service.Name] = service
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		serviceRetention, err := logsRetention(services[name].Extensions)
+		if err != nil {
+			return fmt.Errorf("service %s: %w", name, err)
+		}
+		if serviceRetention == 0 {
+			serviceRetention = retention
+		}
+		err = b.addLogGroup(project, template, logGroupResourceName(name), fmt.Sprintf("/docker-compose/%s/%s", project.Name, name), serviceRetention)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *ecsAPIService) addLogGroup(project *types.Project, template *cloudformation.Template, resource string, name string, retention int) error {
+	logGroup := logs.LogGroup{
+		LogGroupName:    name,
+		RetentionInDays: retention,
+	}
+	if retain, ok := project.Extensions[extensionLogsRetain]; ok && retain == true {
+		// logs survive stack deletion, and must be deleted explicitly
+		logGroup.AWSCloudFormationDeletionPolicy = policies.DeletionPolicy("Retain")
+	}
+	key, ok, err := kmsKey(project)
+	if err != nil {
+		return err
+	}
+	if ok {
+		template.Resources[resource] = &encryptedLogGroup{
+			LogGroup: logGroup,
+			KmsKeyId: key,
+		}
+		return nil
+	}
+	template.Resources[resource] = &logGroup
+	return nil
+}
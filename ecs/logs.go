@@ -21,24 +21,256 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/docker/compose-cli/api/compose"
 )
 
-func (b *ecsAPIService) Logs(ctx context.Context, project string, w io.Writer) error {
-	consumer := logConsumer{
+// logsPollInterval is the delay between queries for new log events when following logs
+var logsPollInterval = 2 * time.Second
+
+// logsReorderWindow is how far back new log events are queried when following logs, as CloudWatch can ingest an event
+// after newer ones
+const logsReorderWindow = 10 * time.Second
+
+// logsTailWindow is how far back log events are first queried when tailing logs without a start time, doubled until each
+// service got enough events
+const logsTailWindow = time.Hour
+
+// logQuery selects log streams of a log group by prefix, the one of service's containers if set
+type logQuery struct {
+	group   string
+	prefix  string
+	service string
+}
+
+func (b *ecsAPIService) Logs(ctx context.Context, project string, w io.Writer, options compose.LogOptions) error {
+	// without a start time, the last events to print are searched back from now, and followed from now
+	tailing := options.Tail >= 0 && options.Since.IsZero()
+	services := options.Services
+	if len(services) == 0 && tailing {
+		// tailing logs is queried by service, so the search stops once each of them has enough events
+		_, deployed, err := b.projectServices(ctx, project)
+		if err != nil {
+			return err
+		}
+		for name := range deployed {
+			services = append(services, name)
+		}
+		sort.Strings(services)
+	}
+	queries, err := b.logQueries(ctx, project, services)
+	if err != nil {
+		return err
+	}
+	consumer := &logConsumer{
 		colors: map[string]colorFunc{},
 		width:  0,
 		writer: w,
 	}
-	err := b.SDK.GetLogs(ctx, project, consumer.Log)
-	return err
+
+	var start int64
+	if !options.Since.IsZero() {
+		start = options.Since.UnixNano() / int64(time.Millisecond)
+	}
+	last := start
+	var events []logEvent
+	if tailing {
+		last = time.Now().UnixNano() / int64(time.Millisecond)
+		events, err = b.queryLastLogs(ctx, queries, options.Tail, last)
+	} else {
+		events, err = b.queryLogs(ctx, queries, start, 0)
+	}
+	if err != nil {
+		return err
+	}
+	// events are seen by ID, as following logs queries again the most recent ones
+	seen := map[string]int64{}
+	for _, e := range events {
+		seen[e.ID] = e.Timestamp
+		if e.Timestamp > last {
+			last = e.Timestamp
+		}
+	}
+	for _, e := range tailLogs(events, options.Tail) {
+		consumer.Log(e.Service, e.Task, e.Message)
+	}
+
+	for options.Follow {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logsPollInterval):
+		}
+		from := last - logsReorderWindow.Milliseconds()
+		if from < start {
+			from = start
+		}
+		recent := map[string]int64{}
+		for id, timestamp := range seen {
+			if timestamp >= from {
+				recent[id] = timestamp
+			}
+		}
+		seen = recent
+		events, err := b.queryLogs(ctx, queries, from, 0)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, e := range events {
+			if _, ok := seen[e.ID]; ok {
+				continue
+			}
+			seen[e.ID] = e.Timestamp
+			if e.Timestamp > last {
+				last = e.Timestamp
+			}
+			consumer.Log(e.Service, e.Task, e.Message)
+		}
+	}
+	return nil
+}
+
+// logQueries selects the log streams of project's services, or of all services if none is set
+func (b *ecsAPIService) logQueries(ctx context.Context, project string, services []string) ([]logQuery, error) {
+	var logGroups []string
+	outputs, err := b.SDK.ListStackOutputs(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	if logGroup, ok := outputs["LogGroup"]; ok {
+		// log group set by x-aws-logs_group can be shared with other projects
+		logGroups = []string{logGroup}
+	} else {
+		resources, err := b.SDK.ListStackResources(ctx, project)
+		if err != nil {
+			return nil, err
+		}
+		// services get a log group each when they set their own retention
+		for _, r := range resources {
+			if r.Type == awsTypeLogGroup {
+				logGroups = append(logGroups, r.ARN)
+			}
+		}
+		if len(logGroups) == 0 {
+			logGroups = []string{fmt.Sprintf("/docker-compose/%s", project)}
+		}
+	}
+
+	// streams are prefixed by project name, then named after the service's container
+	var queries []logQuery
+	for _, group := range logGroups {
+		if len(services) == 0 {
+			queries = append(queries, logQuery{group: group, prefix: project + "/"})
+		}
+		for _, service := range services {
+			queries = append(queries, logQuery{group: group, prefix: fmt.Sprintf("%s/%s/", project, service), service: service})
+		}
+	}
+	return queries, nil
+}
+
+// queryLastLogs returns the last n log events sent before now to the log streams selected by queries, ordered by
+// timestamp. Time windows are queried back from now, so logs aren't read since the log group was created
+func (b *ecsAPIService) queryLastLogs(ctx context.Context, queries []logQuery, n int, now int64) ([]logEvent, error) {
+	var events []logEvent
+	counts := map[string]int{}
+	pending := queries
+	end := now
+	window := logsTailWindow.Milliseconds()
+	for len(pending) > 0 {
+		start := end - window
+		if start < 0 {
+			start = 0
+		}
+		found, err := b.queryLogs(ctx, pending, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range found {
+			counts[e.Service]++
+		}
+		counts[""] += len(found)
+		events = append(found, events...)
+		if start == 0 {
+			break
+		}
+		// a service's streams can be spread over log groups, its queries are all done once it has enough events
+		var remaining []logQuery
+		for _, q := range pending {
+			if counts[q.service] < n {
+				remaining = append(remaining, q)
+			}
+		}
+		pending = remaining
+		end = start - 1
+		window *= 2
+	}
+	return events, nil
+}
+
+// queryLogs returns log events sent since start and until end if set to the log streams selected by queries, ordered by
+// timestamp
+func (b *ecsAPIService) queryLogs(ctx context.Context, queries []logQuery, start int64, end int64) ([]logEvent, error) {
+	results := make([][]logEvent, len(queries))
+	eg, ctx := errgroup.WithContext(ctx)
+	for i, q := range queries {
+		i, q := i, q
+		eg.Go(func() error {
+			events, err := b.SDK.FilterLogEvents(ctx, q.group, q.prefix, start, end)
+			results[i] = events
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	var events []logEvent
+	for _, r := range results {
+		events = append(events, r...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp < events[j].Timestamp
+	})
+	return events, nil
+}
+
+// tailLogs selects the last n log events of each service, all of them when n is negative
+func tailLogs(events []logEvent, n int) []logEvent {
+	if n < 0 {
+		return events
+	}
+	counts := map[string]int{}
+	var selected []logEvent
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		if counts[e.Service] >= n {
+			continue
+		}
+		counts[e.Service]++
+		selected = append(selected, e)
+	}
+	for i, j := 0, len(selected)-1; i < j; i, j = i+1, j-1 {
+		selected[i], selected[j] = selected[j], selected[i]
+	}
+	return selected
 }
 
 func (l *logConsumer) Log(service, container, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	cf, ok := l.colors[service]
 	if !ok {
-		cf = <-loop
+		cf = colorOf(service)
 		l.colors[service] = cf
 		l.computeWidth()
 	}
@@ -61,6 +293,7 @@ func (l *logConsumer) computeWidth() {
 }
 
 type logConsumer struct {
+	mu     sync.Mutex
 	colors map[string]colorFunc
 	width  int
 	writer io.Writer
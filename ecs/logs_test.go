@@ -0,0 +1,180 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+func (m *mockCloudWatchLogs) FilterLogEventsPagesWithContext(_ aws.Context, in *cloudwatchlogs.FilterLogEventsInput, fn func(*cloudwatchlogs.FilterLogEventsOutput, bool) bool, _ ...request.Option) error {
+	args := m.Called(aws.StringValue(in.LogGroupName), aws.StringValue(in.LogStreamNamePrefix), aws.Int64Value(in.StartTime), aws.Int64Value(in.EndTime))
+	pages := args.Get(0).([]*cloudwatchlogs.FilterLogEventsOutput)
+	for i, page := range pages {
+		if !fn(page, i == len(pages)-1) {
+			break
+		}
+	}
+	return args.Error(1)
+}
+
+func logEventsPage(events ...*cloudwatchlogs.FilteredLogEvent) *cloudwatchlogs.FilterLogEventsOutput {
+	return &cloudwatchlogs.FilterLogEventsOutput{Events: events}
+}
+
+func filteredLogEvent(service string, timestamp int64, message string) *cloudwatchlogs.FilteredLogEvent {
+	return &cloudwatchlogs.FilteredLogEvent{
+		EventId:       aws.String(fmt.Sprintf("%s-%d", service, timestamp)),
+		LogStreamName: aws.String("test/" + service + "/0123456789abcdef"),
+		Timestamp:     aws.Int64(timestamp),
+		Message:       aws.String(message),
+	}
+}
+
+// cancellingWriter cancels the command once it has written a line containing stop
+type cancellingWriter struct {
+	bytes.Buffer
+	stop   string
+	cancel func()
+}
+
+func (w *cancellingWriter) Write(p []byte) (int, error) {
+	if strings.Contains(string(p), w.stop) {
+		defer w.cancel()
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestLogsTailAndFollow(t *testing.T) {
+	defer func(d time.Duration) { logsPollInterval = d }(logsPollInterval)
+	logsPollInterval = time.Millisecond
+
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "test").Return(&cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{{
+			Outputs: []*cloudformation.Output{{OutputKey: aws.String("LogGroup"), OutputValue: aws.String("/shared")}},
+		}},
+	}, nil)
+
+	since := time.Unix(1000, 0)
+	start := int64(1000000)
+	logs := &mockCloudWatchLogs{}
+	logs.On("FilterLogEventsPagesWithContext", "/shared", "test/web/", start, int64(0)).Return([]*cloudwatchlogs.FilterLogEventsOutput{
+		logEventsPage(filteredLogEvent("web", start+100, "web starting")),
+		logEventsPage(filteredLogEvent("web", start+200, "web listening")),
+	}, nil).Once()
+	logs.On("FilterLogEventsPagesWithContext", "/shared", "test/db/", start, int64(0)).Return([]*cloudwatchlogs.FilterLogEventsOutput{
+		logEventsPage(filteredLogEvent("db", start+50, "db starting"), filteredLogEvent("db", start+300, "db accepting connections")),
+	}, nil).Once()
+	// following logs queries again recent events, which are only shown once
+	logs.On("FilterLogEventsPagesWithContext", "/shared", "test/web/", start, int64(0)).Return([]*cloudwatchlogs.FilterLogEventsOutput{
+		logEventsPage(filteredLogEvent("web", start+200, "web listening"), filteredLogEvent("web", start+400, "web ready")),
+	}, nil)
+	logs.On("FilterLogEventsPagesWithContext", "/shared", "test/db/", start, int64(0)).Return([]*cloudwatchlogs.FilterLogEventsOutput{
+		logEventsPage(filteredLogEvent("db", start+300, "db accepting connections"), filteredLogEvent("db", start+350, "db checkpoint")),
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	out := &cancellingWriter{stop: "web ready", cancel: cancel}
+	backend := &ecsAPIService{SDK: sdk{CF: cf, CW: logs}}
+	err := backend.Logs(ctx, "test", out, compose.LogOptions{
+		Services: []string{"web", "db"},
+		Since:    since,
+		Tail:     1,
+		Follow:   true,
+	})
+	assert.NilError(t, err)
+
+	line := func(service string, message string) string {
+		return fmt.Sprintf("%s %s\n", colorOf(service)(fmt.Sprintf("%-6s |", service)), message)
+	}
+	assert.Equal(t, out.String(), line("web", "web listening")+
+		line("db", "db accepting connections")+
+		line("db", "db checkpoint")+
+		line("web", "web ready"))
+}
+
+func TestLogsAllServices(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "test").Return(&cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{{}},
+	}, nil)
+	cf.On("ListStackResourcesWithContext", "test").Return(&cloudformation.ListStackResourcesOutput{}, nil)
+	logs := &mockCloudWatchLogs{}
+	logs.On("FilterLogEventsPagesWithContext", "/docker-compose/test", "test/", int64(0), int64(0)).Return([]*cloudwatchlogs.FilterLogEventsOutput{
+		logEventsPage(filteredLogEvent("web", 2, "second"), filteredLogEvent("db", 1, "first")),
+	}, nil)
+
+	out := &bytes.Buffer{}
+	backend := &ecsAPIService{SDK: sdk{CF: cf, CW: logs}}
+	err := backend.Logs(context.TODO(), "test", out, compose.LogOptions{Tail: -1})
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), fmt.Sprintf("%s first\n%s second\n",
+		colorOf("db")(fmt.Sprintf("%-5s |", "db")), colorOf("web")(fmt.Sprintf("%-6s |", "web"))))
+}
+
+func TestLogsTailQueriesBackInTime(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "test").Return(&cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{{
+			Outputs: []*cloudformation.Output{{OutputKey: aws.String("LogGroup"), OutputValue: aws.String("/shared")}},
+		}},
+	}, nil)
+	logs := &mockCloudWatchLogs{}
+	logs.On("FilterLogEventsPagesWithContext", "/shared", "test/web/", mock.Anything, mock.Anything).Return([]*cloudwatchlogs.FilterLogEventsOutput{
+		logEventsPage(filteredLogEvent("web", 30, "web ready")),
+	}, nil).Once()
+	logs.On("FilterLogEventsPagesWithContext", "/shared", "test/web/", mock.Anything, mock.Anything).Return([]*cloudwatchlogs.FilterLogEventsOutput{
+		logEventsPage(filteredLogEvent("web", 10, "web starting"), filteredLogEvent("web", 20, "web listening")),
+	}, nil).Once()
+	logs.On("FilterLogEventsPagesWithContext", "/shared", "test/db/", mock.Anything, mock.Anything).Return([]*cloudwatchlogs.FilterLogEventsOutput{
+		logEventsPage(filteredLogEvent("db", 15, "db starting"), filteredLogEvent("db", 25, "db ready")),
+	}, nil).Once()
+
+	out := &bytes.Buffer{}
+	backend := &ecsAPIService{SDK: sdk{CF: cf, CW: logs}}
+	err := backend.Logs(context.TODO(), "test", out, compose.LogOptions{Services: []string{"web", "db"}, Tail: 2})
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(out.String(), "\n"), 4)
+	assert.Check(t, strings.Contains(out.String(), "web listening") && !strings.Contains(out.String(), "web starting"))
+
+	// db got enough events from the first window, web is queried again in a twice larger window right before it
+	logs.AssertNumberOfCalls(t, "FilterLogEventsPagesWithContext", 3)
+	var windows [][]int64
+	for _, call := range logs.Calls {
+		if call.Arguments.String(1) == "test/web/" {
+			windows = append(windows, []int64{call.Arguments.Get(2).(int64), call.Arguments.Get(3).(int64)})
+		}
+	}
+	assert.Equal(t, windows[0][1]-windows[0][0], logsTailWindow.Milliseconds())
+	assert.Equal(t, windows[1][1], windows[0][0]-1)
+	assert.Equal(t, windows[1][1]-windows[1][0], 2*logsTailWindow.Milliseconds())
+}
@@ -0,0 +1,212 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/sirupsen/logrus"
+)
+
+// deploymentMarker describes a successful deployment, to be correlated with application metrics
+type deploymentMarker struct {
+	Project  string
+	Services []string
+	Images   map[string]string
+	Time     time.Time
+}
+
+func (m deploymentMarker) text() string {
+	images := []string{}
+	for _, service := range m.Services {
+		images = append(images, fmt.Sprintf("%s=%s", service, m.Images[service]))
+	}
+	return fmt.Sprintf("Deployed %s: %s", m.Project, strings.Join(images, ", "))
+}
+
+func deployMarkersEnabled(project *types.Project) bool {
+	if v, ok := project.Extensions[extensionDeployMarkers]; ok {
+		enabled, ok := v.(bool)
+		return ok && enabled
+	}
+	return false
+}
+
+// newDeploymentMarker collects the deployed images for services, or all project services if none is set. Images are
+// reported by the digest they were resolved to when known, as tags may since have moved
+func newDeploymentMarker(project *types.Project, services []string, digests map[string]string) deploymentMarker {
+	if len(services) == 0 {
+		services = project.ServiceNames()
+	}
+	sort.Strings(services)
+	images := map[string]string{}
+	for _, name := range services {
+		service, err := project.GetService(name)
+		if err != nil {
+			continue
+		}
+		images[name] = deployedImage(service, digests[name])
+	}
+	return deploymentMarker{
+		Project:  project.Name,
+		Services: services,
+		Images:   images,
+		Time:     time.Now(),
+	}
+}
+
+// deployedImage is service image pinned by digest, unless it's unknown or the image already is
+func deployedImage(service types.ServiceConfig, digest string) string {
+	if digest == "" || strings.Contains(service.Image, "@") {
+		return service.Image
+	}
+	repository, _, ok := splitImageTag(service.Image)
+	if !ok {
+		repository = service.Image
+	}
+	return fmt.Sprintf("%s@%s", repository, digest)
+}
+
+// changedServices maps CloudFormation resources updated by a change set to compose services
+func changedServices(project *types.Project, resources []string) []string {
+	changed := map[string]struct{}{}
+	for _, r := range resources {
+		for _, service := range project.Services {
+			if r == serviceResourceName(service.Name) || r == fmt.Sprintf("%sTaskDefinition", normalizeResourceName(service.Name)) {
+				changed[service.Name] = struct{}{}
+			}
+		}
+	}
+	services := []string{}
+	for s := range changed {
+		services = append(services, s)
+	}
+	return services
+}
+
+// publishDeploymentMarkers posts deployment markers. Failures are reported as warnings and never fail the deployment
+func (b *ecsAPIService) publishDeploymentMarkers(ctx context.Context, marker deploymentMarker) {
+	if err := b.SDK.PutDeploymentMetric(ctx, marker.Project, marker.Services); err != nil {
+		logrus.Warnf("failed to publish deployment metric to CloudWatch: %s", err.Error())
+	}
+	if b.ctx.GrafanaURL == "" {
+		return
+	}
+	token, err := b.grafanaToken(ctx)
+	if err != nil {
+		logrus.Warnf("failed to read Grafana API token: %s", err.Error())
+		return
+	}
+	grafana := newGrafanaClient(b.ctx.GrafanaURL, token)
+	if err := grafana.annotate(ctx, marker); err != nil {
+		logrus.Warnf("failed to publish deployment annotation to Grafana: %s", err.Error())
+	}
+}
+
+// grafanaTokenEnv sets the Grafana API token, so it's never stored in context metadata
+const grafanaTokenEnv = "GRAFANA_TOKEN"
+
+// grafanaToken returns the Grafana API token set by GRAFANA_TOKEN, or stored in the context's Secrets Manager secret
+func (b *ecsAPIService) grafanaToken(ctx context.Context) (string, error) {
+	if token, ok := os.LookupEnv(grafanaTokenEnv); ok {
+		return token, nil
+	}
+	if b.ctx.GrafanaTokenSecret == "" {
+		return "", nil
+	}
+	return b.SDK.GetSecretValue(ctx, b.ctx.GrafanaTokenSecret)
+}
+
+type grafanaClient struct {
+	url     string
+	token   string
+	client  *http.Client
+	retries int
+	backoff time.Duration
+}
+
+func newGrafanaClient(url string, token string) grafanaClient {
+	return grafanaClient{
+		url:     strings.TrimSuffix(url, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		retries: 3,
+		backoff: time.Second,
+	}
+}
+
+type grafanaAnnotation struct {
+	Time int64    `json:"time"`
+	Tags []string `json:"tags"`
+	Text string   `json:"text"`
+}
+
+func (g grafanaClient) annotate(ctx context.Context, marker deploymentMarker) error {
+	body, err := json.Marshal(grafanaAnnotation{
+		Time: marker.Time.UnixNano() / int64(time.Millisecond),
+		Tags: append([]string{"docker-compose", marker.Project}, marker.Services...),
+		Text: marker.text(),
+	})
+	if err != nil {
+		return err
+	}
+
+	backoff := g.backoff
+	for attempt := 1; ; attempt++ {
+		err = g.post(ctx, body)
+		if err == nil || attempt >= g.retries {
+			return err
+		}
+		logrus.Debugf("Grafana annotation attempt %d failed: %s", attempt, err.Error())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (g grafanaClient) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, g.url+"/api/annotations", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint:errcheck
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", req.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestGrafanaAnnotationRetries(t *testing.T) {
+	var (
+		calls      int
+		annotation grafanaAnnotation
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, r.URL.Path, "/api/annotations")
+		assert.Equal(t, r.Header.Get("Authorization"), "Bearer token")
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&annotation))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := newGrafanaClient(server.URL+"/", "token")
+	client.backoff = time.Millisecond
+	err := client.annotate(context.Background(), deploymentMarker{
+		Project:  "test",
+		Services: []string{"front"},
+		Images:   map[string]string{"front": "nginx@sha256:1234"},
+		Time:     time.Unix(1, 0),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, calls, 3)
+	assert.Equal(t, annotation.Time, int64(1000))
+	assert.DeepEqual(t, annotation.Tags, []string{"docker-compose", "test", "front"})
+	assert.Equal(t, annotation.Text, "Deployed test: front=nginx@sha256:1234")
+}
+
+func TestGrafanaAnnotationFailure(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := newGrafanaClient(server.URL, "")
+	client.backoff = time.Millisecond
+	err := client.annotate(context.Background(), deploymentMarker{Project: "test"})
+	assert.ErrorContains(t, err, "401 Unauthorized")
+	assert.Equal(t, calls, 3)
+}
+
+func TestChangedServices(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  front:
+    image: nginx
+  back:
+    image: mysql
+x-aws-deploy-markers: true
+`)
+	assert.Check(t, deployMarkersEnabled(project))
+	changed := changedServices(project, []string{"FrontTaskDefinition", "LogGroup"})
+	assert.DeepEqual(t, changed, []string{"front"})
+
+	marker := newDeploymentMarker(project, nil, map[string]string{"front": "sha256:1234"})
+	assert.DeepEqual(t, marker.Services, []string{"back", "front"})
+	assert.Equal(t, marker.Images["back"], "mysql")
+	assert.Equal(t, marker.Images["front"], "nginx@sha256:1234")
+}
+
+func TestGrafanaTokenFromEnv(t *testing.T) {
+	assert.NilError(t, os.Setenv(grafanaTokenEnv, "token"))
+	defer os.Unsetenv(grafanaTokenEnv) // nolint:errcheck
+	backend := &ecsAPIService{}
+	token, err := backend.grafanaToken(context.TODO())
+	assert.NilError(t, err)
+	assert.Equal(t, token, "token")
+}
@@ -19,9 +19,17 @@ package ecs
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/sanathkr/yaml"
+)
+
+const (
+	templateFormatJSON = "json"
+	templateFormatYAML = "yaml"
 )
 
 func marshall(template *cloudformation.Template) ([]byte, error) {
@@ -39,6 +47,14 @@ func marshall(template *cloudformation.Template) ([]byte, error) {
 		if resources, ok := input["Resources"]; ok {
 			for _, uresource := range resources.(map[string]interface{}) {
 				if resource, ok := uresource.(map[string]interface{}); ok {
+					if resource["Type"] == "AWS::ECS::Service" {
+						// goformation omits DesiredCount when set to 0, which CloudFormation then defaults to 1.
+						// DAEMON services run a task per container instance and refuse a desired count
+						properties := resource["Properties"].(map[string]interface{})
+						if _, ok := properties["DesiredCount"]; !ok && properties["SchedulingStrategy"] != ecsapi.SchedulingStrategyDaemon {
+							properties["DesiredCount"] = 0
+						}
+					}
 					if resource["Type"] == "AWS::ECS::TaskDefinition" {
 						properties := resource["Properties"].(map[string]interface{})
 						for _, def := range properties["ContainerDefinitions"].([]interface{}) {
@@ -46,6 +62,11 @@ func marshall(template *cloudformation.Template) ([]byte, error) {
 							if strings.HasSuffix(containerDefinition["Name"].(string), "_InitContainer") {
 								containerDefinition["Essential"] = "false"
 							}
+							for _, key := range []string{"EntryPoint", "Command"} {
+								if command, ok := containerDefinition[key].([]interface{}); ok && len(command) == 1 && command[0] == emptyCommand {
+									containerDefinition[key] = []interface{}{}
+								}
+							}
 						}
 					}
 				}
@@ -53,9 +74,119 @@ func marshall(template *cloudformation.Template) ([]byte, error) {
 		}
 	}
 
+	if input, ok := unmarshalled.(map[string]interface{}); ok {
+		if outputs, ok := input["Outputs"].(map[string]interface{}); ok {
+			for name, uoutput := range outputs {
+				// goformation always sets Export, which CloudFormation rejects without a name
+				if output, ok := uoutput.(map[string]interface{}); ok {
+					if export, ok := output["Export"].(map[string]interface{}); ok && len(export) == 0 {
+						trimmed := map[string]interface{}{}
+						for k, v := range output {
+							if k != "Export" {
+								trimmed[k] = v
+							}
+						}
+						outputs[name] = trimmed
+					}
+				}
+			}
+		}
+	}
+
+	// JSON objects are marshalled with sorted keys, but arrays built from maps need to be sorted for the template to
+	// be the same on each conversion
+	unmarshalled = sortArrays(unmarshalled)
+
 	raw, err = json.MarshalIndent(unmarshalled, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("invalid JSON: %s", err)
 	}
 	return raw, err
 }
+
+// marshalResource marshals resource as goformation does, so it keeps its DependsOn, DeletionPolicy, UpdatePolicy and
+// other attributes, then lets set sets the properties goformation doesn't support yet
+func marshalResource(resource json.Marshaler, set func(properties map[string]interface{})) ([]byte, error) {
+	raw, err := resource.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var marshalled map[string]interface{}
+	if err := json.Unmarshal(raw, &marshalled); err != nil {
+		return nil, err
+	}
+	properties, ok := marshalled["Properties"].(map[string]interface{})
+	if !ok {
+		properties = map[string]interface{}{}
+	}
+	set(properties)
+	marshalled["Properties"] = properties
+	return json.Marshal(marshalled)
+}
+
+// sortedArrays are the template properties which order doesn't matter, and the field to sort their items by
+var sortedArrays = map[string]string{
+	"Tags":             "Key",
+	"Environment":      "Name",
+	"SecurityGroups":   "",
+	"SecurityGroupIds": "",
+	"DependsOn":        "",
+}
+
+func sortArrays(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			item = sortArrays(item)
+			if array, ok := item.([]interface{}); ok {
+				if field, ok := sortedArrays[key]; ok {
+					sort.SliceStable(array, func(i, j int) bool {
+						return sortKey(array[i], field) < sortKey(array[j], field)
+					})
+				}
+			}
+			v[key] = item
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = sortArrays(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// sortKey returns the field of item to sort by, or its JSON representation when item isn't an object
+func sortKey(item interface{}, field string) string {
+	if object, ok := item.(map[string]interface{}); ok && field != "" {
+		return fmt.Sprint(object[field])
+	}
+	if s, ok := item.(string); ok {
+		return s
+	}
+	raw, _ := json.Marshal(item)
+	return string(raw)
+}
+
+func checkTemplateFormat(format string) error {
+	switch format {
+	case "", templateFormatJSON, templateFormatYAML:
+		return nil
+	default:
+		return fmt.Errorf("unsupported template format %q, must be %s or %s", format, templateFormatJSON, templateFormatYAML)
+	}
+}
+
+// formatTemplate converts the marshalled JSON template into the requested format. YAML output uses the long form of
+// intrinsic functions, so it can be parsed back as is
+func formatTemplate(raw []byte, format string) ([]byte, error) {
+	if err := checkTemplateFormat(format); err != nil {
+		return nil, err
+	}
+	if format == templateFormatYAML {
+		return yaml.JSONToYAML(raw)
+	}
+	return raw, nil
+}
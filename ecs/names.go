@@ -0,0 +1,127 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
+)
+
+// stackNameRegexp matches CloudFormation stack names, which also have to be valid Cloud Map namespace labels
+var stackNameRegexp = regexp.MustCompile("^[a-zA-Z][-a-zA-Z0-9]*$")
+
+const maxStackNameLength = 128
+
+// applyStackName names project after the stack it gets deployed as, set by name or else by x-aws-stack_name, so the
+// same compose file can be deployed as several environments. All resources names and tags follow the stack name
+func applyStackName(project *types.Project, name string) error {
+	if name == "" {
+		x, ok := project.Extensions[extensionStackName]
+		if !ok {
+			return nil
+		}
+		if name, ok = x.(string); !ok {
+			return fmt.Errorf("%s must be a string", extensionStackName)
+		}
+	}
+	project.Name = name
+	return nil
+}
+
+// checkStackOwner fails if stack exists but wasn't deployed by Docker Compose under this name, rather than updating it
+func (b *ecsAPIService) checkStackOwner(ctx context.Context, stack string) error {
+	tags, err := b.SDK.GetStackTags(ctx, stack)
+	if err != nil {
+		return err
+	}
+	if tags[compose.ProjectTag] != stack {
+		return &errdefs.Error{
+			Kind:     errdefs.ErrAlreadyExists,
+			Resource: stack,
+			Err: fmt.Errorf("stack %s already exists and wasn't deployed by Docker Compose, use --stack-name or %s to deploy under another name",
+				stack, extensionStackName),
+		}
+	}
+	return nil
+}
+
+// checkResourceNames fails if project name can't be used as a stack name, or some project's resources get the same
+// logical ID once their name is normalized, which would make them silently overwrite each other in the template
+func checkResourceNames(project *types.Project) error {
+	var problems []string
+	if !stackNameRegexp.MatchString(project.Name) || len(project.Name) > maxStackNameLength {
+		problems = append(problems, fmt.Sprintf("project name %q must start with a letter and only contain alphanumeric characters and hyphens, up to %d characters", project.Name, maxStackNameLength))
+	}
+
+	var services, networks, volumes, secrets, configs []string
+	for _, s := range project.Services {
+		services = append(services, s.Name)
+	}
+	for name := range project.Networks {
+		networks = append(networks, name)
+	}
+	for name := range project.Volumes {
+		volumes = append(volumes, name)
+	}
+	for name := range project.Secrets {
+		secrets = append(secrets, name)
+	}
+	for name := range project.Configs {
+		configs = append(configs, name)
+	}
+	problems = append(problems, nameCollisions("services", services)...)
+	problems = append(problems, nameCollisions("networks", networks)...)
+	problems = append(problems, nameCollisions("volumes", volumes)...)
+	problems = append(problems, nameCollisions("secrets", secrets)...)
+	problems = append(problems, nameCollisions("configs", configs)...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid resource names:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// nameCollisions lists names of kind which don't produce a usable logical ID, or share it with another one
+func nameCollisions(kind string, names []string) []string {
+	sort.Strings(names)
+	ids := map[string][]string{}
+	var problems []string
+	for _, name := range names {
+		id := normalizeResourceName(name)
+		if id == "" {
+			problems = append(problems, fmt.Sprintf("%s %q must contain alphanumeric characters", kind, name))
+			continue
+		}
+		ids[id] = append(ids[id], name)
+	}
+	var clashing []string
+	for id, names := range ids {
+		if len(names) > 1 {
+			clashing = append(clashing, fmt.Sprintf("%s %s all use resource name %s", kind, strings.Join(names, ", "), id))
+		}
+	}
+	sort.Strings(clashing)
+	return append(problems, clashing...)
+}
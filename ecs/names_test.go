@@ -0,0 +1,130 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awscloudformation "github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/logs"
+	cloudmap "github.com/awslabs/goformation/v4/cloudformation/servicediscovery"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
+)
+
+func TestInvalidProjectName(t *testing.T) {
+	for _, name := range []string{"1project", "--", "my_project"} {
+		project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+`)
+		project.Name = name
+		err := checkResourceNames(project)
+		assert.ErrorContains(t, err, "must start with a letter and only contain alphanumeric characters and hyphens", name)
+	}
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+`)
+	project.Name = "my-project2"
+	assert.NilError(t, checkResourceNames(project))
+}
+
+func TestResourceNameCollisions(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  my-api:
+    image: nginx
+  my.api:
+    image: nginx
+  front:
+    image: nginx
+volumes:
+  db_data: {}
+  dbdata: {}
+secrets:
+  "--":
+    file: ./secret.txt
+`)
+	err := checkResourceNames(project)
+	assert.Error(t, err, `invalid resource names:
+  services my-api, my.api all use resource name Myapi
+  volumes db_data, dbdata all use resource name Dbdata
+  secrets "--" must contain alphanumeric characters`)
+}
+
+func TestRejectNamesBeforeAWSCalls(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  my-api:
+    image: nginx
+  my_api:
+    image: nginx
+`)
+	// backend has no AWS client set, so any call to AWS would panic
+	err := (&ecsAPIService{}).Up(context.TODO(), project, compose.UpOptions{})
+	assert.ErrorContains(t, err, "services my-api, my_api all use resource name Myapi")
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeValidation)
+
+	_, err = (&ecsAPIService{}).Convert(context.TODO(), project, compose.ConvertOptions{})
+	assert.ErrorContains(t, err, "services my-api, my_api all use resource name Myapi")
+}
+
+func TestStackName(t *testing.T) {
+	project := loadConfig(t, `
+x-aws-stack_name: test-staging
+services:
+  test:
+    image: nginx
+`)
+	assert.NilError(t, applyStackName(project, ""))
+	assert.Equal(t, project.Name, "test-staging")
+	// stack name set by flag overrides the compose file
+	assert.NilError(t, applyStackName(project, "test-prod"))
+	assert.Equal(t, project.Name, "test-prod")
+
+	template, err := (&ecsAPIService{}).convert(project, awsResources{})
+	assert.NilError(t, err)
+	assert.Equal(t, template.Resources["LogGroup"].(*logs.LogGroup).LogGroupName, "/docker-compose/test-prod")
+	assert.Equal(t, template.Resources["CloudMap"].(*cloudmap.PrivateDnsNamespace).Name, "test-prod.local")
+	assert.Equal(t, template.Resources["CloudMap"].(*cloudmap.PrivateDnsNamespace).Tags[0].Value, "test-prod")
+}
+
+func TestStackOwner(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "test").Return(&awscloudformation.DescribeStacksOutput{
+		Stacks: []*awscloudformation.Stack{{
+			Tags: []*awscloudformation.Tag{{Key: aws.String(compose.ProjectTag), Value: aws.String("test")}},
+		}},
+	}, nil)
+	cf.On("DescribeStacksWithContext", "network").Return(&awscloudformation.DescribeStacksOutput{
+		Stacks: []*awscloudformation.Stack{{}},
+	}, nil)
+	backend := &ecsAPIService{SDK: sdk{CF: cf}}
+	assert.NilError(t, backend.checkStackOwner(context.TODO(), "test"))
+
+	err := backend.checkStackOwner(context.TODO(), "network")
+	assert.Error(t, err, "stack network already exists and wasn't deployed by Docker Compose, use --stack-name or x-aws-stack_name to deploy under another name")
+	assert.Assert(t, errors.Is(err, errdefs.ErrAlreadyExists))
+}
@@ -0,0 +1,367 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// templateBucket returns the S3 bucket set by x-aws-template_bucket to host nested stacks templates. When set, or when
+// x-aws-nested_stacks is, each task gets its resources deployed by a nested stack, so large projects don't exceed
+// CloudFormation template limits. The bucket is empty for the one up creates, when x-aws-template_bucket isn't set
+func templateBucket(project *types.Project) (string, bool, error) {
+	if x, ok := project.Extensions[extensionTemplateBucket]; ok {
+		return fmt.Sprint(x), true, nil
+	}
+	x, ok := project.Extensions[extensionNestedStacks]
+	if !ok {
+		return "", false, nil
+	}
+	enabled, ok := x.(bool)
+	if !ok {
+		return "", false, fmt.Errorf("%s must be a boolean, got %v", extensionNestedStacks, x)
+	}
+	return "", enabled, nil
+}
+
+// createdTemplateBucketPrefix prefixes the name of the buckets up creates to host nested stacks templates
+const createdTemplateBucketPrefix = "docker-compose-templates-"
+
+// resolveTemplateBucket returns the bucket hosting project's nested stacks templates, empty when project doesn't deploy
+// nested stacks, and if up has to create it. The created one is shared by all projects deployed in the account and
+// region, as templates keys are prefixed by project
+func (b *ecsAPIService) resolveTemplateBucket(ctx context.Context, project *types.Project) (string, bool, error) {
+	bucket, ok, err := templateBucket(project)
+	if err != nil || !ok || bucket != "" {
+		return bucket, false, err
+	}
+	account, err := b.callerAccount(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	return fmt.Sprintf("%s%s-%s", createdTemplateBucketPrefix, account, b.Region), true, nil
+}
+
+func nestedTemplatesPrefix(project string) string {
+	return fmt.Sprintf("%s/templates/", project)
+}
+
+// nestedTemplates are the nested stacks templates Convert produced, for up to upload
+type nestedTemplates struct {
+	bucket       string
+	createBucket bool
+	templates    []nestedTemplate
+}
+
+// nestedStack is the template of a nested stack, and the parameters the parent stack passes to it
+type nestedStack struct {
+	name       string
+	service    string
+	resources  map[string]interface{}
+	parameters map[string]interface{}
+	outputs    map[string]interface{}
+	values     map[string]interface{}
+	dependsOn  map[string]bool
+}
+
+// nestedTemplate is a nested stack template, with the S3 bucket and object key it gets uploaded to
+type nestedTemplate struct {
+	bucket  string
+	key     string
+	content []byte
+}
+
+var subVariableRegexp = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+
+// nestedStacks splits template into a parent template with shared resources, and a nested stack per task with the
+// resources created for it. References between stacks are passed as nested stacks parameters and outputs
+type nestedStacks struct {
+	owners map[string]string
+	stacks map[string]*nestedStack
+}
+
+func (n nestedStacks) owner(name string) string {
+	return n.owners[name]
+}
+
+// resolve returns the expression to reference target's attribute from context, empty for the parent stack
+func (n nestedStacks) resolve(context string, target string, attribute string, expression interface{}) interface{} {
+	owner := n.owner(target)
+	if owner == context || strings.HasPrefix(target, "AWS::") {
+		// pseudo parameters are available to all stacks
+		return expression
+	}
+	name := normalizeResourceName(target + attribute)
+	value := expression
+	if owner != "" {
+		stack := n.stacks[owner]
+		stack.outputs[name] = map[string]interface{}{"Value": expression}
+		value = map[string]interface{}{"Fn::GetAtt": []interface{}{stack.name, "Outputs." + name}}
+	}
+	if context == "" {
+		return value
+	}
+	stack := n.stacks[context]
+	stack.parameters[name] = map[string]interface{}{"Type": "String"}
+	stack.values[name] = value
+	return map[string]interface{}{"Ref": name}
+}
+
+// rewrite replaces references in value to resources of another stack
+func (n nestedStacks) rewrite(context string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 1 {
+			if target, ok := v["Ref"].(string); ok {
+				return n.resolve(context, target, "", v)
+			}
+			if att, ok := v["Fn::GetAtt"].([]interface{}); ok && len(att) == 2 {
+				return n.resolve(context, fmt.Sprint(att[0]), fmt.Sprint(att[1]), v)
+			}
+			if sub, ok := v["Fn::Sub"].(string); ok {
+				for _, match := range subVariableRegexp.FindAllStringSubmatch(sub, -1) {
+					if !strings.Contains(match[1], ".") {
+						// variable name is kept, so the nested stack gets a parameter with the same name
+						n.resolve(context, match[1], "", map[string]interface{}{"Ref": match[1]})
+					}
+				}
+				return v
+			}
+		}
+		rewritten := map[string]interface{}{}
+		for key, value := range v {
+			rewritten[key] = n.rewrite(context, value)
+		}
+		return rewritten
+	case []interface{}:
+		rewritten := make([]interface{}, len(v))
+		for i, value := range v {
+			rewritten[i] = n.rewrite(context, value)
+		}
+		return rewritten
+	default:
+		return v
+	}
+}
+
+// rewriteDependsOn replaces dependencies on resources of another stack by a dependency on this stack
+func (n nestedStacks) rewriteDependsOn(context string, resource map[string]interface{}) map[string]interface{} {
+	var dependencies []interface{}
+	switch d := resource["DependsOn"].(type) {
+	case string:
+		dependencies = []interface{}{d}
+	case []interface{}:
+		dependencies = d
+	default:
+		return resource
+	}
+	var kept []interface{}
+	for _, d := range dependencies {
+		dependency := fmt.Sprint(d)
+		owner := n.owner(dependency)
+		if owner == context {
+			kept = append(kept, dependency)
+			continue
+		}
+		if owner != "" {
+			dependency = n.stacks[owner].name
+		}
+		if context == "" {
+			kept = append(kept, dependency)
+			continue
+		}
+		n.stacks[context].dependsOn[dependency] = true
+	}
+	if len(kept) == 0 {
+		return withoutKey(resource, "DependsOn")
+	}
+	resource["DependsOn"] = kept
+	return resource
+}
+
+// splitNestedStacks splits the marshalled template into a parent template and a template per nested stack. owners
+// are the services owning the resources created for their task, objectURL the URL nested templates are read from
+func splitNestedStacks(project *types.Project, raw []byte, owners map[string]string, bucket string, objectURL func(bucket string, key string) string) ([]byte, []nestedTemplate, error) {
+	var parent map[string]interface{}
+	err := json.Unmarshal(raw, &parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	resources, _ := parent["Resources"].(map[string]interface{})
+
+	n := nestedStacks{
+		owners: owners,
+		stacks: map[string]*nestedStack{},
+	}
+	for _, service := range owners {
+		if _, ok := n.stacks[service]; !ok {
+			n.stacks[service] = &nestedStack{
+				name:       normalizeResourceName(service) + "Stack",
+				service:    service,
+				resources:  map[string]interface{}{},
+				parameters: map[string]interface{}{},
+				outputs:    map[string]interface{}{},
+				values:     map[string]interface{}{},
+				dependsOn:  map[string]bool{},
+			}
+		}
+	}
+
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parentResources := map[string]interface{}{}
+	for _, name := range names {
+		context := n.owner(name)
+		resource := n.rewriteDependsOn(context, n.rewrite(context, resources[name]).(map[string]interface{}))
+		if context == "" {
+			parentResources[name] = resource
+			continue
+		}
+		n.stacks[context].resources[name] = resource
+	}
+	if outputs, ok := parent["Outputs"]; ok {
+		parent["Outputs"] = n.rewrite("", outputs)
+	}
+
+	services := make([]string, 0, len(n.stacks))
+	for service := range n.stacks {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	var templates []nestedTemplate
+	for _, service := range services {
+		stack := n.stacks[service]
+		nested := map[string]interface{}{
+			"AWSTemplateFormatVersion": "2010-09-09",
+			"Resources":                stack.resources,
+		}
+		if len(stack.parameters) > 0 {
+			nested["Parameters"] = stack.parameters
+		}
+		if len(stack.outputs) > 0 {
+			nested["Outputs"] = stack.outputs
+		}
+		content, err := json.MarshalIndent(nested, "", "  ")
+		if err != nil {
+			return nil, nil, err
+		}
+		digest := sha256.Sum256(content)
+		key := fmt.Sprintf("%s%x-%s.json", nestedTemplatesPrefix(project.Name), digest[:6], service)
+		templates = append(templates, nestedTemplate{bucket: bucket, key: key, content: content})
+
+		config, err := project.GetService(service)
+		if err != nil {
+			return nil, nil, err
+		}
+		properties := map[string]interface{}{
+			"TemplateURL": objectURL(bucket, key),
+			"Tags":        serviceTags(project, config),
+		}
+		if len(stack.values) > 0 {
+			properties["Parameters"] = stack.values
+		}
+		resource := map[string]interface{}{
+			"Type":       awsTypeStack,
+			"Properties": properties,
+		}
+		if len(stack.dependsOn) > 0 {
+			var dependsOn []string
+			for d := range stack.dependsOn {
+				dependsOn = append(dependsOn, d)
+			}
+			sort.Strings(dependsOn)
+			resource["DependsOn"] = dependsOn
+		}
+		parentResources[stack.name] = resource
+	}
+	parent["Resources"] = parentResources
+
+	content, err := json.MarshalIndent(parent, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, templates, nil
+}
+
+func withoutKey(values map[string]interface{}, key string) map[string]interface{} {
+	kept := map[string]interface{}{}
+	for k, v := range values {
+		if k != key {
+			kept[k] = v
+		}
+	}
+	return kept
+}
+
+// uploadNestedTemplates uploads the nested stacks templates Convert produced, before the parent stack gets deployed,
+// creating the bucket when project doesn't set one. They are recorded in the project inventory, so `down` removes them
+// with the stack
+func (b *ecsAPIService) uploadNestedTemplates(ctx context.Context, project *types.Project) error {
+	if b.nested.createBucket {
+		err := b.SDK.CreateBucket(ctx, b.nested.bucket, b.Region)
+		if err != nil {
+			return err
+		}
+	}
+	var uploaded []string
+	for _, t := range b.nested.templates {
+		err := b.SDK.PutObject(ctx, t.bucket, t.key, t.content)
+		if err != nil {
+			return err
+		}
+		uploaded = append(uploaded, s3Arn(b.partition(), t.bucket, t.key))
+	}
+	if len(uploaded) == 0 {
+		return nil
+	}
+	return b.recordInventory(ctx, project.Name, uploaded)
+}
+
+// removeNestedTemplates deletes the nested stacks templates uploaded for project, once its stack has been deleted
+func (b *ecsAPIService) removeNestedTemplates(ctx context.Context, project string) error {
+	inventories, err := b.SDK.GetInventories(ctx)
+	if err != nil {
+		return err
+	}
+	var removed []string
+	for _, a := range inventories[project] {
+		bucket, key, ok := s3Object(a)
+		if !ok || !strings.HasPrefix(key, nestedTemplatesPrefix(project)) {
+			continue
+		}
+		err = b.SDK.DeleteObject(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
+		removed = append(removed, a)
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	return b.SDK.PutInventory(ctx, project, without(inventories[project], removed))
+}
@@ -0,0 +1,166 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"gotest.tools/v3/assert"
+)
+
+func TestSplitNestedStacks(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  front:
+    image: nginx
+    ports:
+      - 80:80
+    depends_on:
+      - back
+  back:
+    image: redis
+x-aws-template_bucket: templates
+`)
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+
+	objectURL := func(bucket string, key string) string {
+		return backend.SDK.ObjectURL("eu-west-3", bucket, key)
+	}
+	parent, templates, err := splitNestedStacks(project, raw, backend.owners, "templates", objectURL)
+	assert.NilError(t, err)
+	assert.Equal(t, len(templates), 2)
+	assert.Check(t, strings.HasPrefix(templates[0].key, "Test/templates/"))
+	assert.Check(t, strings.HasSuffix(templates[0].key, "-back.json"))
+
+	var p struct {
+		Resources map[string]struct {
+			Type       string
+			DependsOn  []string
+			Properties map[string]interface{}
+		}
+		Outputs map[string]struct {
+			Value interface{}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(parent, &p))
+	for _, name := range []string{"Cluster", "CloudMap", "LoadBalancer", "LogGroup", "FrontStack", "BackStack"} {
+		_, ok := p.Resources[name]
+		assert.Check(t, ok, name)
+	}
+	_, ok := p.Resources["FrontService"]
+	assert.Check(t, !ok)
+	front := p.Resources["FrontStack"]
+	assert.Equal(t, front.Type, "AWS::CloudFormation::Stack")
+	assert.DeepEqual(t, front.DependsOn, []string{"BackStack"})
+	assert.Equal(t, front.Properties["TemplateURL"], "https://templates.s3.eu-west-3.amazonaws.com/"+templates[1].key)
+	assert.DeepEqual(t, p.Outputs["FrontServiceArn"].Value, map[string]interface{}{
+		"Fn::GetAtt": []interface{}{"FrontStack", "Outputs.FrontService"},
+	})
+
+	for _, nested := range templates {
+		var n struct {
+			Parameters map[string]interface{}
+			Resources  map[string]interface{}
+		}
+		assert.NilError(t, json.Unmarshal(nested.content, &n))
+		_, ok := n.Parameters["AWSRegion"]
+		assert.Check(t, !ok, "pseudo parameters must not be passed to nested stacks")
+		// all references must resolve within the nested stack
+		var check func(v interface{})
+		check = func(v interface{}) {
+			switch v := v.(type) {
+			case map[string]interface{}:
+				if ref, ok := v["Ref"].(string); ok {
+					_, resource := n.Resources[ref]
+					_, parameter := n.Parameters[ref]
+					assert.Check(t, resource || parameter || strings.HasPrefix(ref, "AWS::"), "%s: unresolved Ref %s", nested.key, ref)
+				}
+				if att, ok := v["Fn::GetAtt"].([]interface{}); ok {
+					_, resource := n.Resources[att[0].(string)]
+					assert.Check(t, resource, "%s: unresolved GetAtt %s", nested.key, att[0])
+				}
+				for _, value := range v {
+					check(value)
+				}
+			case []interface{}:
+				for _, value := range v {
+					check(value)
+				}
+			}
+		}
+		check(n.Resources)
+	}
+}
+
+func TestResolveTemplateBucket(t *testing.T) {
+	stsMock := &mockSTS{}
+	stsMock.On("GetCallerIdentityWithContext").Return(&sts.GetCallerIdentityOutput{
+		Arn: aws.String("arn:aws:sts::012345678910:assumed-role/deploy/session"),
+	}, nil)
+	backend := &ecsAPIService{Region: "eu-west-3", SDK: sdk{STS: stsMock}}
+
+	for yaml, expected := range map[string]struct {
+		bucket string
+		create bool
+	}{
+		"":                                 {},
+		"x-aws-nested_stacks: false":       {},
+		"x-aws-template_bucket: templates": {bucket: "templates"},
+		"x-aws-nested_stacks: true":        {bucket: "docker-compose-templates-012345678910-eu-west-3", create: true},
+	} {
+		project := loadConfig(t, `
+services:
+  front:
+    image: nginx
+`+yaml+`
+`)
+		bucket, create, err := backend.resolveTemplateBucket(context.TODO(), project)
+		assert.NilError(t, err, yaml)
+		assert.Equal(t, bucket, expected.bucket, yaml)
+		assert.Equal(t, create, expected.create, yaml)
+	}
+
+	project := loadConfig(t, `
+services:
+  front:
+    image: nginx
+x-aws-nested_stacks: yes please
+`)
+	_, _, err := backend.resolveTemplateBucket(context.TODO(), project)
+	assert.Error(t, err, "x-aws-nested_stacks must be a boolean, got yes please")
+}
+
+func TestObjectURL(t *testing.T) {
+	sess, err := session.NewSession(aws.NewConfig().WithRegion("cn-north-1"))
+	assert.NilError(t, err)
+	assert.Equal(t, newSDK(sess).ObjectURL("cn-north-1", "templates", "Test/templates/front.json"), "https://templates.s3.cn-north-1.amazonaws.com.cn/Test/templates/front.json")
+	assert.Equal(t, newSDK(sess).ObjectURL("cn-north-1", "my.templates", "front.json"), "https://s3.cn-north-1.amazonaws.com.cn/my.templates/front.json")
+
+	sess, err = session.NewSession(aws.NewConfig().WithRegion("eu-west-3").WithEndpoint("http://localhost:4566").WithS3ForcePathStyle(true))
+	assert.NilError(t, err)
+	assert.Equal(t, newSDK(sess).ObjectURL("eu-west-3", "templates", "front.json"), "http://localhost:4566/templates/front.json")
+}
@@ -0,0 +1,122 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/compose-spec/compose-go/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	deploymentStarted    = "started"
+	deploymentSucceeded  = "succeeded"
+	deploymentFailed     = "failed"
+	deploymentRolledBack = "rolled back"
+)
+
+// snsTopics returns the SNS topic set by x-aws-sns_topic, to be notified of stack and deployment events
+func snsTopics(project *types.Project) ([]string, error) {
+	x, ok := project.Extensions[extensionSNSTopic]
+	if !ok {
+		return nil, nil
+	}
+	topic, ok := x.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an SNS topic ARN", extensionSNSTopic)
+	}
+	parsed, err := arn.Parse(topic)
+	if err != nil || parsed.Service != "sns" {
+		return nil, fmt.Errorf("%s must be an SNS topic ARN, got %q", extensionSNSTopic, topic)
+	}
+	return []string{topic}, nil
+}
+
+// deploymentNotification summarizes the outcome of a deployment or deletion of a project
+type deploymentNotification struct {
+	Project  string            `json:"project"`
+	Command  string            `json:"command"`
+	Outcome  string            `json:"outcome"`
+	Error    string            `json:"error,omitempty"`
+	Services []string          `json:"services,omitempty"`
+	Images   map[string]string `json:"images,omitempty"`
+	Duration string            `json:"duration"`
+}
+
+// newDeploymentNotification summarizes deployment of project started at start, which failed on err if not nil. Images
+// are reported by digests, when resolved
+func newDeploymentNotification(project *types.Project, digests map[string]string, start time.Time, outcome string, err error) deploymentNotification {
+	deployed := newDeploymentMarker(project, nil, digests)
+	notification := deploymentNotification{
+		Project:  project.Name,
+		Command:  "up",
+		Outcome:  outcome,
+		Services: deployed.Services,
+		Images:   deployed.Images,
+		Duration: time.Since(start).Round(time.Second).String(),
+	}
+	if err != nil {
+		notification.Error = err.Error()
+	}
+	return notification
+}
+
+// newDeletionNotification summarizes deletion of project started at start, which failed on err if not nil
+func newDeletionNotification(project string, start time.Time, outcome string, err error) deploymentNotification {
+	notification := deploymentNotification{
+		Project:  project,
+		Command:  "down",
+		Outcome:  outcome,
+		Duration: time.Since(start).Round(time.Second).String(),
+	}
+	if err != nil {
+		notification.Error = err.Error()
+	}
+	return notification
+}
+
+func (n deploymentNotification) subject() string {
+	subject := fmt.Sprintf("docker compose %s %s: %s after %s", n.Command, n.Project, n.Outcome, n.Duration)
+	// SNS rejects subjects longer than 100 characters
+	if len(subject) > 100 {
+		subject = subject[:100]
+	}
+	return subject
+}
+
+// notifyDeployment publishes notification to topics. Failures, like a missing publish permission, are reported as
+// warnings and never fail the deployment
+func (b *ecsAPIService) notifyDeployment(ctx context.Context, topics []string, notification deploymentNotification) {
+	if len(topics) == 0 {
+		return
+	}
+	message, err := json.Marshal(notification)
+	if err != nil {
+		logrus.Warnf("failed to publish deployment notification: %s", err.Error())
+		return
+	}
+	for _, topic := range topics {
+		if err := b.SDK.Publish(ctx, topic, notification.subject(), string(message)); err != nil {
+			logrus.Warnf("failed to publish deployment notification to %s: %s", topic, err.Error())
+		}
+	}
+}
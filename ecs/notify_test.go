@@ -0,0 +1,98 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+)
+
+func TestSNSTopics(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+x-aws-sns_topic: arn:aws:sns:eu-west-3:123456789012:deployments
+`)
+	topics, err := snsTopics(project)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, topics, []string{"arn:aws:sns:eu-west-3:123456789012:deployments"})
+
+	project.Extensions[extensionSNSTopic] = "arn:aws:sqs:eu-west-3:123456789012:deployments"
+	_, err = snsTopics(project)
+	assert.Error(t, err, `x-aws-sns_topic must be an SNS topic ARN, got "arn:aws:sqs:eu-west-3:123456789012:deployments"`)
+}
+
+func TestNotifyDeployment(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  web:
+    image: nginx:1.19
+  db:
+    image: postgres:13
+`)
+	topic := "arn:aws:sns:eu-west-3:123456789012:deployments"
+	snsMock := &mockSNS{}
+	snsMock.On("PublishWithContext", topic, mock.Anything, mock.Anything).Return(nil)
+
+	backend := &ecsAPIService{SDK: sdk{SNS: snsMock}}
+	backend.notifyDeployment(context.TODO(), []string{topic}, newDeploymentNotification(project, nil, time.Now(), deploymentRolledBack, errors.New("service web failed to stabilize")))
+
+	assert.Equal(t, len(snsMock.Calls), 1)
+	assert.Equal(t, snsMock.Calls[0].Arguments.String(1), "docker compose up Test: rolled back after 0s")
+	var notification deploymentNotification
+	assert.NilError(t, json.Unmarshal([]byte(snsMock.Calls[0].Arguments.String(2)), &notification))
+	assert.DeepEqual(t, notification, deploymentNotification{
+		Project:  "Test",
+		Command:  "up",
+		Outcome:  deploymentRolledBack,
+		Error:    "service web failed to stabilize",
+		Services: []string{"db", "web"},
+		Images:   map[string]string{"db": "postgres:13", "web": "nginx:1.19"},
+		Duration: "0s",
+	})
+}
+
+func TestNotifyDeploymentWithoutPermission(t *testing.T) {
+	topic := "arn:aws:sns:eu-west-3:123456789012:deployments"
+	snsMock := &mockSNS{}
+	snsMock.On("PublishWithContext", topic, mock.Anything, mock.Anything).Return(errors.New("AuthorizationError: not authorized to perform SNS:Publish"))
+
+	backend := &ecsAPIService{SDK: sdk{SNS: snsMock}}
+	backend.notifyDeployment(context.TODO(), []string{topic}, newDeletionNotification("test", time.Now(), deploymentSucceeded, nil))
+	snsMock.AssertCalled(t, "PublishWithContext", topic, "docker compose down test: succeeded after 0s", mock.Anything)
+}
+
+type mockSNS struct {
+	snsiface.SNSAPI
+	mock.Mock
+}
+
+func (m *mockSNS) PublishWithContext(_ aws.Context, in *sns.PublishInput, _ ...request.Option) (*sns.PublishOutput, error) {
+	args := m.Called(aws.StringValue(in.TopicArn), aws.StringValue(in.Subject), aws.StringValue(in.Message))
+	return &sns.PublishOutput{}, args.Error(0)
+}
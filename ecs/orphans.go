@@ -0,0 +1,423 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+const (
+	awsTypeFileSystem = "AWS::EFS::FileSystem"
+	awsTypeRepository = "AWS::ECR::Repository"
+	awsTypeSecret     = "AWS::SecretsManager::Secret"
+	awsTypeObject     = "AWS::S3::Object"
+
+	// inventoryPath is the SSM parameters path where resources created outside the CloudFormation stack are recorded
+	inventoryPath = "/docker-compose/inventory/"
+
+	// stackNameTag is set by CloudFormation on resources it creates
+	stackNameTag = "aws:cloudformation:stack-name"
+
+	// sharedProjectTag is the compose project name stacks are deployed from, before they take the stack name, which is
+	// also set on the image repositories their environments share
+	sharedProjectTag = "com.docker.compose.shared-project"
+)
+
+// estimated prices in USD, based on us-east-1 public pricing
+const (
+	efsGBMonth    = 0.30
+	ecrGBMonth    = 0.10
+	s3GBMonth     = 0.023
+	secretMonthly = 0.40
+)
+
+// taggedResource is an AWS resource discovered for a project
+type taggedResource struct {
+	ARN     string
+	Tags    map[string]string
+	Created time.Time
+	Size    int64
+}
+
+// resourceType guesses CloudFormation resource type from ARN, as tagging API doesn't tell
+func resourceType(resourceArn string) string {
+	parsed, err := arn.Parse(resourceArn)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case parsed.Service == "elasticfilesystem" && strings.HasPrefix(parsed.Resource, "file-system/"):
+		return awsTypeFileSystem
+	case parsed.Service == "ecr" && strings.HasPrefix(parsed.Resource, "repository/"):
+		return awsTypeRepository
+	case parsed.Service == "secretsmanager" && strings.HasPrefix(parsed.Resource, "secret:"):
+		return awsTypeSecret
+	case parsed.Service == "s3" && strings.Contains(parsed.Resource, "/"):
+		return awsTypeObject
+	}
+	return ""
+}
+
+func estimateMonthlyCost(awsType string, size int64) float64 {
+	gb := float64(size) / (1 << 30)
+	switch awsType {
+	case awsTypeFileSystem:
+		return gb * efsGBMonth
+	case awsTypeRepository:
+		return gb * ecrGBMonth
+	case awsTypeObject:
+		return gb * s3GBMonth
+	case awsTypeSecret:
+		return secretMonthly
+	}
+	return 0
+}
+
+// findOrphans selects resources which don't belong to a stack anymore. Resources attached to a stack
+// which still exists, or to a project which stack still exists, are never reported. Resources shared by the
+// environments of a compose project are kept while a stack deployed from it exists.
+func findOrphans(resources []taggedResource, stackExists func(name string) (bool, error), sharedProjectExists func(project string) (bool, error)) ([]compose.Orphan, error) {
+	shared := map[string]bool{}
+	sharedLives := func(project string) (bool, error) {
+		if e, ok := shared[project]; ok {
+			return e, nil
+		}
+		e, err := sharedProjectExists(project)
+		if err != nil {
+			return false, err
+		}
+		shared[project] = e
+		return e, nil
+	}
+	exists := map[string]bool{}
+	stackLives := func(name string) (bool, error) {
+		if e, ok := exists[name]; ok {
+			return e, nil
+		}
+		e, err := stackExists(name)
+		if err != nil {
+			return false, err
+		}
+		exists[name] = e
+		return e, nil
+	}
+
+	seen := map[string]bool{}
+	orphans := []compose.Orphan{}
+	for _, r := range resources {
+		awsType := resourceType(r.ARN)
+		if awsType == "" || seen[r.ARN] {
+			continue
+		}
+		seen[r.ARN] = true
+		project := r.Tags[compose.ProjectTag]
+		var stacks []string
+		if stack, ok := r.Tags[stackNameTag]; ok {
+			stacks = append(stacks, stack)
+		}
+		stacks = append(stacks, project)
+
+		orphan := true
+		for _, stack := range stacks {
+			live, err := stackLives(stack)
+			if err != nil {
+				return nil, err
+			}
+			if live {
+				orphan = false
+				break
+			}
+		}
+		if sharedProject, ok := r.Tags[sharedProjectTag]; ok && orphan {
+			live, err := sharedLives(sharedProject)
+			if err != nil {
+				return nil, err
+			}
+			orphan = !live
+		}
+		if !orphan {
+			continue
+		}
+		orphans = append(orphans, compose.Orphan{
+			ID:          r.ARN,
+			Type:        awsType,
+			Project:     project,
+			Created:     r.Created,
+			MonthlyCost: estimateMonthlyCost(awsType, r.Size),
+		})
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Project != orphans[j].Project {
+			return orphans[i].Project < orphans[j].Project
+		}
+		return orphans[i].ID < orphans[j].ID
+	})
+	return orphans, nil
+}
+
+func (b *ecsAPIService) Orphans(ctx context.Context, project string) ([]compose.Orphan, error) {
+	resources, err := b.SDK.GetTaggedResources(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range resources {
+		if resourceType(r.ARN) == awsTypeSecret {
+			resources[i].Created, err = b.SDK.GetSecretCreationDate(ctx, r.ARN)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// EFS and ECR specific listing provide creation date and size, so they come first
+	filesystems, err := b.SDK.ListFileSystems(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	repositories, err := b.SDK.ListRepositories(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	resources = append(append(filesystems, repositories...), resources...)
+
+	inventories, err := b.SDK.GetInventories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for p, arns := range inventories {
+		if project != "" && p != project {
+			continue
+		}
+		for _, a := range arns {
+			r := taggedResource{
+				ARN:  a,
+				Tags: map[string]string{compose.ProjectTag: p},
+			}
+			if bucket, key, ok := s3Object(a); ok {
+				r.Created, r.Size, err = b.SDK.DescribeObject(ctx, bucket, key)
+				if err != nil {
+					return nil, err
+				}
+			}
+			resources = append(resources, r)
+		}
+	}
+
+	return findOrphans(resources, func(name string) (bool, error) {
+		return b.SDK.StackExists(ctx, name)
+	}, func(project string) (bool, error) {
+		return b.SDK.SharedProjectStackExists(ctx, project)
+	})
+}
+
+func (b *ecsAPIService) ProjectOrphans(ctx context.Context, project *types.Project) ([]compose.Orphan, error) {
+	filesystems, err := b.SDK.ListFileSystems(ctx, project.Name)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := b.SDK.GetTaggedResources(ctx, project.Name)
+	if err != nil {
+		return nil, err
+	}
+	resources = append(filesystems, resources...)
+
+	inventories, err := b.SDK.GetInventories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range inventories[project.Name] {
+		resources = append(resources, taggedResource{
+			ARN:  a,
+			Tags: map[string]string{compose.ProjectTag: project.Name},
+		})
+	}
+
+	envFiles := map[string]bool{}
+	if bucket, ok := envFilesBucket(project); ok {
+		for _, service := range project.Services {
+			files, err := serviceEnvFiles(project, service)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range files {
+				envFiles[s3Arn(b.partition(), bucket, f.key)] = true
+			}
+		}
+	}
+	return findUnreferenced(project, resources, envFiles), nil
+}
+
+// findUnreferenced selects resources created for project outside of its stack which the project doesn't use anymore:
+// file systems of removed volumes, secrets removed or declared external, and env_files previous versions
+func findUnreferenced(project *types.Project, resources []taggedResource, envFiles map[string]bool) []compose.Orphan {
+	seen := map[string]bool{}
+	orphans := []compose.Orphan{}
+	for _, r := range resources {
+		awsType := resourceType(r.ARN)
+		if seen[r.ARN] || r.Tags[compose.ProjectTag] != project.Name {
+			continue
+		}
+		seen[r.ARN] = true
+		var unreferenced bool
+		switch awsType {
+		case awsTypeFileSystem:
+			// file systems created by the stack are retained when their volume is removed
+			if volume, ok := r.Tags[compose.VolumeTag]; ok {
+				_, used := project.Volumes[volume]
+				unreferenced = !used
+			}
+		case awsTypeSecret:
+			if _, ok := r.Tags[stackNameTag]; ok {
+				// inline secrets are managed by the stack
+				continue
+			}
+			if name, ok := projectSecretName(project, r.ARN); ok {
+				secret, used := project.Secrets[name]
+				unreferenced = !used || secret.External.External
+			}
+		case awsTypeObject:
+			_, key, _ := s3Object(r.ARN)
+			if !strings.HasPrefix(key, nestedTemplatesPrefix(project.Name)) {
+				unreferenced = !envFiles[r.ARN]
+			}
+		}
+		if !unreferenced {
+			continue
+		}
+		orphans = append(orphans, compose.Orphan{
+			ID:          r.ARN,
+			Type:        awsType,
+			Project:     project.Name,
+			Created:     r.Created,
+			MonthlyCost: estimateMonthlyCost(awsType, r.Size),
+		})
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].ID < orphans[j].ID
+	})
+	return orphans
+}
+
+// projectSecretName returns the name of the project secret uploaded as secretArn, which Secrets Manager suffixes with
+// 6 random characters
+func projectSecretName(project *types.Project, secretArn string) (string, bool) {
+	parsed, err := arn.Parse(secretArn)
+	if err != nil {
+		return "", false
+	}
+	name := strings.TrimPrefix(parsed.Resource, "secret:")
+	prefix := project.Name + "/"
+	if !strings.HasPrefix(name, prefix) || len(name) < len(prefix)+7 || name[len(name)-7] != '-' {
+		return "", false
+	}
+	return strings.TrimPrefix(name[:len(name)-7], prefix), true
+}
+
+func (b *ecsAPIService) RemoveOrphans(ctx context.Context, orphans []compose.Orphan) error {
+	removed := map[string][]string{}
+	for _, o := range orphans {
+		parsed, err := arn.Parse(o.ID)
+		if err != nil {
+			return err
+		}
+		switch o.Type {
+		case awsTypeFileSystem:
+			err = b.SDK.DeleteFileSystem(ctx, strings.TrimPrefix(parsed.Resource, "file-system/"))
+		case awsTypeRepository:
+			err = b.SDK.DeleteRepository(ctx, strings.TrimPrefix(parsed.Resource, "repository/"))
+		case awsTypeSecret:
+			err = b.SDK.DeleteSecret(ctx, o.ID, false)
+		case awsTypeObject:
+			bucket, key, _ := s3Object(o.ID)
+			err = b.SDK.DeleteObject(ctx, bucket, key)
+			removed[o.Project] = append(removed[o.Project], o.ID)
+		default:
+			err = fmt.Errorf("don't know how to delete %s %s", o.Type, o.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	inventories, err := b.SDK.GetInventories(ctx)
+	if err != nil {
+		return err
+	}
+	for project, arns := range removed {
+		err = b.SDK.PutInventory(ctx, project, without(inventories[project], arns))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordInventory adds resources created outside of project's stack to the project inventory
+func (b *ecsAPIService) recordInventory(ctx context.Context, project string, arns []string) error {
+	inventories, err := b.SDK.GetInventories(ctx)
+	if err != nil {
+		return err
+	}
+	inventory := inventories[project]
+	known := map[string]bool{}
+	for _, a := range inventory {
+		known[a] = true
+	}
+	for _, a := range arns {
+		if !known[a] {
+			inventory = append(inventory, a)
+		}
+	}
+	return b.SDK.PutInventory(ctx, project, inventory)
+}
+
+func s3Object(objectArn string) (string, string, bool) {
+	parsed, err := arn.Parse(objectArn)
+	if err != nil || parsed.Service != "s3" {
+		return "", "", false
+	}
+	parts := strings.SplitN(parsed.Resource, "/", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func without(values []string, removed []string) []string {
+	skip := map[string]bool{}
+	for _, r := range removed {
+		skip[r] = true
+	}
+	var kept []string
+	for _, v := range values {
+		if !skip[v] {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,220 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+func TestFindOrphans(t *testing.T) {
+	created := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	resources := []taggedResource{
+		{
+			ARN:     "arn:aws:elasticfilesystem:eu-west-3:123456789012:file-system/fs-removed",
+			Tags:    map[string]string{compose.ProjectTag: "removed"},
+			Created: created,
+			Size:    10 << 30,
+		},
+		{
+			ARN:  "arn:aws:elasticfilesystem:eu-west-3:123456789012:file-system/fs-removed",
+			Tags: map[string]string{compose.ProjectTag: "removed"},
+		},
+		{
+			ARN:  "arn:aws:secretsmanager:eu-west-3:123456789012:secret:db",
+			Tags: map[string]string{compose.ProjectTag: "removed", stackNameTag: "removed"},
+		},
+		{
+			ARN:  "arn:aws:ecr:eu-west-3:123456789012:repository/live",
+			Tags: map[string]string{compose.ProjectTag: "live"},
+		},
+		{
+			// belongs to a live stack, even if tagged for a removed project
+			ARN:  "arn:aws:secretsmanager:eu-west-3:123456789012:secret:shared",
+			Tags: map[string]string{compose.ProjectTag: "removed", stackNameTag: "live"},
+		},
+		{
+			ARN:  "arn:aws:sqs:eu-west-3:123456789012:queue",
+			Tags: map[string]string{compose.ProjectTag: "removed"},
+		},
+	}
+	calls := map[string]int{}
+	orphans, err := findOrphans(resources, func(name string) (bool, error) {
+		calls[name]++
+		return name == "live", nil
+	}, func(project string) (bool, error) {
+		return false, nil
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, orphans, []compose.Orphan{
+		{
+			ID:          "arn:aws:elasticfilesystem:eu-west-3:123456789012:file-system/fs-removed",
+			Type:        awsTypeFileSystem,
+			Project:     "removed",
+			Created:     created,
+			MonthlyCost: 10 * efsGBMonth,
+		},
+		{
+			ID:          "arn:aws:secretsmanager:eu-west-3:123456789012:secret:db",
+			Type:        awsTypeSecret,
+			Project:     "removed",
+			MonthlyCost: secretMonthly,
+		},
+	})
+	assert.DeepEqual(t, calls, map[string]int{"removed": 1, "live": 1})
+}
+
+func TestFindOrphansSharedRepository(t *testing.T) {
+	resources := []taggedResource{
+		{
+			// shared by the environments of project test, test-staging stack is live
+			ARN:  "arn:aws:ecr:eu-west-3:123456789012:repository/test/web",
+			Tags: map[string]string{compose.ProjectTag: "test", sharedProjectTag: "test"},
+		},
+		{
+			ARN:  "arn:aws:ecr:eu-west-3:123456789012:repository/removed/web",
+			Tags: map[string]string{compose.ProjectTag: "removed", sharedProjectTag: "removed"},
+		},
+	}
+	stacks := map[string]map[string]string{
+		"test-staging": {compose.ProjectTag: "test-staging", sharedProjectTag: "test"},
+	}
+	orphans, err := findOrphans(resources, func(name string) (bool, error) {
+		_, ok := stacks[name]
+		return ok, nil
+	}, func(project string) (bool, error) {
+		for _, tags := range stacks {
+			if tags[sharedProjectTag] == project {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, orphans, []compose.Orphan{
+		{
+			ID:      "arn:aws:ecr:eu-west-3:123456789012:repository/removed/web",
+			Type:    awsTypeRepository,
+			Project: "removed",
+		},
+	})
+}
+
+func TestS3Object(t *testing.T) {
+	bucket, key, ok := s3Object("arn:aws:s3:::bucket/project/service/abcdef-.env")
+	assert.Check(t, ok)
+	assert.Equal(t, bucket, "bucket")
+	assert.Equal(t, key, "project/service/abcdef-.env")
+	assert.Equal(t, resourceType("arn:aws:s3:::bucket/project/service/abcdef-.env"), awsTypeObject)
+
+	_, _, ok = s3Object("arn:aws:s3:::bucket")
+	assert.Check(t, !ok)
+	assert.Equal(t, resourceType("arn:aws:s3:::bucket"), "")
+}
+
+func TestFindUnreferenced(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  web:
+    image: nginx
+volumes:
+  data: {}
+secrets:
+  db:
+    file: ./testdata/secret.txt
+  external:
+    external: true
+    name: arn:aws:secretsmanager:eu-west-3:123456789012:secret:external-AbCdEf
+`)
+	resources := []taggedResource{
+		{
+			ARN:  "arn:aws:elasticfilesystem:eu-west-3:123456789012:file-system/fs-data",
+			Tags: map[string]string{compose.ProjectTag: "Test", compose.VolumeTag: "data"},
+		},
+		{
+			ARN:  "arn:aws:elasticfilesystem:eu-west-3:123456789012:file-system/fs-removed",
+			Tags: map[string]string{compose.ProjectTag: "Test", compose.VolumeTag: "removed"},
+			Size: 1 << 30,
+		},
+		{
+			ARN:  "arn:aws:secretsmanager:eu-west-3:123456789012:secret:Test/db-AbCdEf",
+			Tags: map[string]string{compose.ProjectTag: "Test"},
+		},
+		{
+			ARN:  "arn:aws:secretsmanager:eu-west-3:123456789012:secret:Test/api_key-GhIjKl",
+			Tags: map[string]string{compose.ProjectTag: "Test"},
+		},
+		{
+			// declared external since it was uploaded
+			ARN:  "arn:aws:secretsmanager:eu-west-3:123456789012:secret:Test/external-MnOpQr",
+			Tags: map[string]string{compose.ProjectTag: "Test"},
+		},
+		{
+			// inline secret, managed by the stack
+			ARN:  "arn:aws:secretsmanager:eu-west-3:123456789012:secret:TestDbSecret-StUvWx",
+			Tags: map[string]string{compose.ProjectTag: "Test", stackNameTag: "Test"},
+		},
+		{
+			ARN:  "arn:aws:s3:::bucket/Test/web/0123456789ab-web.env",
+			Tags: map[string]string{compose.ProjectTag: "Test"},
+		},
+		{
+			ARN:  "arn:aws:s3:::bucket/Test/web/ba9876543210-web.env",
+			Tags: map[string]string{compose.ProjectTag: "Test"},
+		},
+		{
+			ARN:  "arn:aws:s3:::bucket/Test/templates/WebStack.json",
+			Tags: map[string]string{compose.ProjectTag: "Test"},
+		},
+		{
+			ARN:  "arn:aws:elasticfilesystem:eu-west-3:123456789012:file-system/fs-other",
+			Tags: map[string]string{compose.ProjectTag: "other", compose.VolumeTag: "removed"},
+		},
+	}
+	orphans := findUnreferenced(project, resources, map[string]bool{
+		"arn:aws:s3:::bucket/Test/web/0123456789ab-web.env": true,
+	})
+	assert.DeepEqual(t, orphans, []compose.Orphan{
+		{
+			ID:          "arn:aws:elasticfilesystem:eu-west-3:123456789012:file-system/fs-removed",
+			Type:        awsTypeFileSystem,
+			Project:     "Test",
+			MonthlyCost: efsGBMonth,
+		},
+		{
+			ID:      "arn:aws:s3:::bucket/Test/web/ba9876543210-web.env",
+			Type:    awsTypeObject,
+			Project: "Test",
+		},
+		{
+			ID:          "arn:aws:secretsmanager:eu-west-3:123456789012:secret:Test/api_key-GhIjKl",
+			Type:        awsTypeSecret,
+			Project:     "Test",
+			MonthlyCost: secretMonthly,
+		},
+		{
+			ID:          "arn:aws:secretsmanager:eu-west-3:123456789012:secret:Test/external-MnOpQr",
+			Type:        awsTypeSecret,
+			Project:     "Test",
+			MonthlyCost: secretMonthly,
+		},
+	})
+}
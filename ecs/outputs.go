@@ -0,0 +1,95 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/elasticloadbalancingv2"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// createOutputs records endpoints and identifiers of the deployed project as stack outputs, so they can be read
+// without querying each service. Outputs are exported when project sets x-aws-exports_prefix
+func (b *ecsAPIService) createOutputs(project *types.Project, resources awsResources, template *cloudformation.Template) {
+	if _, ok := template.Resources["Cluster"]; ok {
+		template.Outputs["ClusterArn"] = cloudformation.Output{
+			Value: cloudformation.GetAtt("Cluster", "Arn"),
+		}
+	} else {
+		template.Outputs["ClusterArn"] = cloudformation.Output{
+			Value: resources.cluster,
+		}
+	}
+	template.Outputs["CloudMapNamespace"] = cloudformation.Output{
+		Value: cloudformation.Ref("CloudMap"),
+	}
+
+	for _, service := range project.Services {
+		if _, ok := sidecarOf(service); !ok && !isScheduled(service) {
+			template.Outputs[serviceResourceName(service.Name)+"Arn"] = cloudformation.Output{
+				Value: cloudformation.Ref(serviceResourceName(service.Name)),
+			}
+		}
+	}
+
+	// a load balancer set by x-aws-loadbalancer isn't managed by the stack, so its DNS name can't be resolved by the template
+	if _, ok := template.Resources["LoadBalancer"]; ok {
+		dnsName := cloudformation.GetAtt("LoadBalancer", "DNSName")
+		template.Outputs["LoadBalancerDNSName"] = cloudformation.Output{
+			Value: dnsName,
+		}
+		for _, service := range project.Services {
+			for _, port := range service.Ports {
+				protocol := port.Protocol
+				if resources.loadBalancerType == elbv2.LoadBalancerTypeEnumApplication {
+					protocol = elbv2.ProtocolEnumHttp
+				}
+				listenerName := fmt.Sprintf("%s%s%dListener", normalizeResourceName(service.Name), strings.ToUpper(port.Protocol), port.Target)
+				if listener, ok := template.Resources[listenerName].(*elasticloadbalancingv2.Listener); ok && listener.Protocol != "" {
+					protocol = listener.Protocol
+				}
+				template.Outputs[fmt.Sprintf("%s%dURL", normalizeResourceName(service.Name), port.Published)] = cloudformation.Output{
+					Value: cloudformation.Join("", []string{listenerScheme(protocol) + "://", dnsName, fmt.Sprintf(":%d", port.Published)}),
+				}
+			}
+		}
+	}
+
+	if x, ok := project.Extensions[extensionExportsPrefix]; ok {
+		for name, output := range template.Outputs {
+			output.Export = cloudformation.Export{
+				Name: fmt.Sprintf("%s-%s", x, name),
+			}
+			template.Outputs[name] = output
+		}
+	}
+}
+
+// listenerScheme is the URL scheme clients use to reach a listener, TLS terminated by the load balancer is reached by https
+func listenerScheme(protocol string) string {
+	switch strings.ToUpper(protocol) {
+	case elbv2.ProtocolEnumHttps, elbv2.ProtocolEnumTls:
+		return "https"
+	case elbv2.ProtocolEnumHttp:
+		return "http"
+	}
+	return strings.ToLower(protocol)
+}
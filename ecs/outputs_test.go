@@ -0,0 +1,113 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/elasticloadbalancingv2"
+	"gotest.tools/v3/assert"
+)
+
+func TestOutputs(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  front:
+    image: nginx
+    ports:
+      - 8080:8080
+  back:
+    image: redis
+    ports:
+      - 6379:6379
+x-aws-exports_prefix: shop
+`)
+	dnsName := cloudformation.GetAtt("LoadBalancer", "DNSName")
+	assert.DeepEqual(t, template.Outputs, cloudformation.Outputs{
+		"ClusterArn": {
+			Value:  cloudformation.GetAtt("Cluster", "Arn"),
+			Export: cloudformation.Export{Name: "shop-ClusterArn"},
+		},
+		"CloudMapNamespace": {
+			Value:  cloudformation.Ref("CloudMap"),
+			Export: cloudformation.Export{Name: "shop-CloudMapNamespace"},
+		},
+		"FrontServiceArn": {
+			Value:  cloudformation.Ref("FrontService"),
+			Export: cloudformation.Export{Name: "shop-FrontServiceArn"},
+		},
+		"BackServiceArn": {
+			Value:  cloudformation.Ref("BackService"),
+			Export: cloudformation.Export{Name: "shop-BackServiceArn"},
+		},
+		"LoadBalancerDNSName": {
+			Value:  dnsName,
+			Export: cloudformation.Export{Name: "shop-LoadBalancerDNSName"},
+		},
+		"Front8080URL": {
+			Value:  cloudformation.Join("", []string{"tcp://", dnsName, ":8080"}),
+			Export: cloudformation.Export{Name: "shop-Front8080URL"},
+		},
+		"Back6379URL": {
+			Value:  cloudformation.Join("", []string{"tcp://", dnsName, ":6379"}),
+			Export: cloudformation.Export{Name: "shop-Back6379URL"},
+		},
+	})
+}
+
+func TestOutputsHTTPSListener(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  front:
+    image: nginx
+    ports:
+      - 443:443
+`)
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, template.Outputs["Front443URL"].Value, cloudformation.Join("", []string{"http://", cloudformation.GetAtt("LoadBalancer", "DNSName"), ":443"}))
+
+	template.Resources["FrontTCP443Listener"].(*elasticloadbalancingv2.Listener).Protocol = elbv2.ProtocolEnumHttps
+	backend.createOutputs(project, awsResources{}, template)
+	assert.DeepEqual(t, template.Outputs["Front443URL"].Value, cloudformation.Join("", []string{"https://", cloudformation.GetAtt("LoadBalancer", "DNSName"), ":443"}))
+
+	for protocol, scheme := range map[string]string{"HTTPS": "https", "TLS": "https", "HTTP": "http", "TCP": "tcp", "UDP": "udp"} {
+		assert.Equal(t, listenerScheme(protocol), scheme)
+	}
+}
+
+func TestOutputsExternalLoadBalancer(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  front:
+    image: nginx
+    ports:
+      - 80:80
+`)
+	template, err := (&ecsAPIService{}).convert(project, awsResources{
+		loadBalancer:     "arn:aws:elasticloadbalancing:eu-west-3:012345678910:loadbalancer/app/shared/1234",
+		loadBalancerType: "application",
+	})
+	assert.NilError(t, err)
+	_, ok := template.Outputs["LoadBalancerDNSName"]
+	assert.Check(t, !ok)
+	_, ok = template.Outputs["Front80URL"]
+	assert.Check(t, !ok)
+}
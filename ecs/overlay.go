@@ -0,0 +1,105 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// overlaySections are the template sections x-aws-cloudformation can override or extend
+var overlaySections = map[string]bool{
+	"Resources": true,
+	"Outputs":   true,
+}
+
+// applyOverlay deep-merges the partial template set by x-aws-cloudformation over the generated one. Objects are merged,
+// with overlay values winning, so unexposed properties can be set on generated resources, and new resources be added
+func applyOverlay(project *types.Project, raw []byte) ([]byte, error) {
+	x, ok := project.Extensions[extensionCloudFormation]
+	if !ok {
+		return raw, nil
+	}
+	b, err := json.Marshal(x)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", extensionCloudFormation, err)
+	}
+	var overlay map[string]interface{}
+	if err := json.Unmarshal(b, &overlay); err != nil {
+		return nil, fmt.Errorf("%s must be a partial CloudFormation template", extensionCloudFormation)
+	}
+	for section := range overlay {
+		if !overlaySections[section] {
+			return nil, fmt.Errorf("%s: unsupported section %q, only Resources and Outputs can be overlaid", extensionCloudFormation, section)
+		}
+	}
+
+	var template map[string]interface{}
+	if err := json.Unmarshal(raw, &template); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %s", err)
+	}
+	if err := mergeOverlay("", template, overlay); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(sortArrays(template), "", "  ")
+}
+
+func mergeOverlay(path string, target map[string]interface{}, overlay map[string]interface{}) error {
+	keys := make([]string, 0, len(overlay))
+	for key := range overlay {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := overlay[key]
+		at := key
+		if path != "" {
+			at = path + "." + key
+		}
+		existing, ok := target[key]
+		if !ok || existing == nil {
+			target[key] = value
+			continue
+		}
+		if jsonKind(existing) != jsonKind(value) {
+			return fmt.Errorf("%s: can't merge %s over %s at %s", extensionCloudFormation, jsonKind(value), jsonKind(existing), at)
+		}
+		if object, ok := value.(map[string]interface{}); ok {
+			if err := mergeOverlay(at, existing.(map[string]interface{}), object); err != nil {
+				return err
+			}
+			continue
+		}
+		target[key] = value
+	}
+	return nil
+}
+
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return "value"
+	}
+}
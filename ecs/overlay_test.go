@@ -0,0 +1,121 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func overlaidTemplate(t *testing.T, yaml string) (map[string]interface{}, error) {
+	project := loadConfig(t, yaml)
+	template, err := (&ecsAPIService{}).convert(project, awsResources{})
+	assert.NilError(t, err)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	raw, err = applyOverlay(project, raw)
+	if err != nil {
+		return nil, err
+	}
+	var overlaid map[string]interface{}
+	assert.NilError(t, json.Unmarshal(raw, &overlaid))
+	return overlaid, nil
+}
+
+func TestApplyOverlay(t *testing.T) {
+	template, err := overlaidTemplate(t, `
+services:
+  front:
+    image: nginx
+    ports:
+      - 80:80
+x-aws-cloudformation:
+  Resources:
+    FrontTCP80TargetGroup:
+      Properties:
+        Port: 8080
+        TargetGroupAttributes:
+          - Key: deregistration_delay.timeout_seconds
+            Value: "30"
+    Queue:
+      Type: AWS::SQS::Queue
+      Properties:
+        QueueName: jobs
+  Outputs:
+    QueueURL:
+      Value:
+        Ref: Queue
+`)
+	assert.NilError(t, err)
+	resources := template["Resources"].(map[string]interface{})
+
+	targetGroup := resources["FrontTCP80TargetGroup"].(map[string]interface{})
+	assert.Equal(t, targetGroup["Type"], "AWS::ElasticLoadBalancingV2::TargetGroup")
+	properties := targetGroup["Properties"].(map[string]interface{})
+	assert.Equal(t, properties["Port"], float64(8080))
+	assert.Equal(t, properties["Protocol"], "HTTP")
+	assert.DeepEqual(t, properties["TargetGroupAttributes"], []interface{}{
+		map[string]interface{}{"Key": "deregistration_delay.timeout_seconds", "Value": "30"},
+	})
+
+	assert.DeepEqual(t, resources["Queue"], map[string]interface{}{
+		"Type":       "AWS::SQS::Queue",
+		"Properties": map[string]interface{}{"QueueName": "jobs"},
+	})
+	outputs := template["Outputs"].(map[string]interface{})
+	assert.DeepEqual(t, outputs["QueueURL"], map[string]interface{}{
+		"Value": map[string]interface{}{"Ref": "Queue"},
+	})
+	assert.Check(t, outputs["ClusterArn"] != nil)
+}
+
+func TestApplyOverlayErrors(t *testing.T) {
+	_, err := overlaidTemplate(t, `
+services:
+  front:
+    image: nginx
+x-aws-cloudformation:
+  Resources:
+    FrontTaskDefinition:
+      Properties:
+        ContainerDefinitions: nginx
+`)
+	assert.Error(t, err, "x-aws-cloudformation: can't merge value over array at Resources.FrontTaskDefinition.Properties.ContainerDefinitions")
+
+	_, err = overlaidTemplate(t, `
+services:
+  front:
+    image: nginx
+x-aws-cloudformation:
+  Resources:
+    FrontTaskDefinition: none
+`)
+	assert.Error(t, err, "x-aws-cloudformation: can't merge value over object at Resources.FrontTaskDefinition")
+
+	_, err = overlaidTemplate(t, `
+services:
+  front:
+    image: nginx
+x-aws-cloudformation:
+  Parameters:
+    Foo:
+      Type: String
+`)
+	assert.Error(t, err, `x-aws-cloudformation: unsupported section "Parameters", only Resources and Outputs can be overlaid`)
+}
@@ -0,0 +1,201 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// overridesTargets maps the pseudo logical IDs x-aws-overrides paths start with to the type of service's resources
+var overridesTargets = map[string]string{
+	"Service":        "AWS::ECS::Service",
+	"TaskDefinition": "AWS::ECS::TaskDefinition",
+	"TargetGroup":    "AWS::ElasticLoadBalancingV2::TargetGroup",
+	"Listener":       "AWS::ElasticLoadBalancingV2::Listener",
+}
+
+// overridePathSegment matches a property name followed by optional array indexes, like `ContainerDefinitions[0]`
+var overridePathSegment = regexp.MustCompile(`^([A-Za-z0-9_]+)((?:\[\d+\])*)$`)
+
+// pathElement is either an object key or an array index
+type pathElement struct {
+	key   string
+	index int
+}
+
+func parseOverridePath(path string) (string, []pathElement, error) {
+	segments := strings.Split(path, ".")
+	if len(segments) < 2 {
+		return "", nil, fmt.Errorf("invalid path %q, must be <resource>.<property>", path)
+	}
+	target := segments[0]
+	if _, ok := overridesTargets[target]; !ok {
+		return "", nil, fmt.Errorf("invalid path %q, resource must be one of Service, TaskDefinition, TargetGroup or Listener", path)
+	}
+	var elements []pathElement
+	for _, segment := range segments[1:] {
+		match := overridePathSegment.FindStringSubmatch(segment)
+		if match == nil {
+			return "", nil, fmt.Errorf("invalid path %q, unexpected %q", path, segment)
+		}
+		elements = append(elements, pathElement{key: match[1], index: -1})
+		for _, index := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(match[2], "["), "]"), "][") {
+			if index == "" {
+				continue
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil {
+				return "", nil, err
+			}
+			elements = append(elements, pathElement{index: i})
+		}
+	}
+	return target, elements, nil
+}
+
+// applyServiceOverrides sets the properties declared by services' x-aws-overrides on the resources generated for them.
+// Paths are resolved against the pseudo logical IDs of overridesTargets, so users don't need to know generated names
+func applyServiceOverrides(project *types.Project, raw []byte, owners map[string]string) ([]byte, error) {
+	var template map[string]interface{}
+	overridden := false
+	for _, service := range project.Services {
+		x, ok := service.Extensions[extensionOverrides]
+		if !ok {
+			continue
+		}
+		overrides, ok := x.(map[string]interface{})
+		if !ok {
+			return nil, serviceError(service.Name, fmt.Errorf("%s must be a map of property paths to values", extensionOverrides))
+		}
+		if template == nil {
+			if err := json.Unmarshal(raw, &template); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %s", err)
+			}
+		}
+		resources := template["Resources"].(map[string]interface{})
+
+		paths := make([]string, 0, len(overrides))
+		for path := range overrides {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			target, elements, err := parseOverridePath(path)
+			if err != nil {
+				return nil, serviceError(service.Name, fmt.Errorf("%s: %s", extensionOverrides, err))
+			}
+			names := serviceResourcesOfType(resources, owners, service.Name, overridesTargets[target])
+			if len(names) == 0 {
+				return nil, serviceError(service.Name, fmt.Errorf("%s: invalid path %q, service has no %s resource", extensionOverrides, path, target))
+			}
+			for _, name := range names {
+				resource := resources[name].(map[string]interface{})
+				properties, ok := resource["Properties"].(map[string]interface{})
+				if !ok {
+					properties = map[string]interface{}{}
+					resource["Properties"] = properties
+				}
+				if err := setOverride(properties, elements, overrides[path]); err != nil {
+					return nil, serviceError(service.Name, fmt.Errorf("%s: invalid path %q, %s", extensionOverrides, path, err))
+				}
+			}
+			overridden = true
+		}
+	}
+	if !overridden {
+		return raw, nil
+	}
+	return json.MarshalIndent(sortArrays(template), "", "  ")
+}
+
+// serviceResourcesOfType lists the logical IDs of service's resources with type
+func serviceResourcesOfType(resources map[string]interface{}, owners map[string]string, service string, typ string) []string {
+	var names []string
+	for name, owner := range owners {
+		if owner != service {
+			continue
+		}
+		if resource, ok := resources[name].(map[string]interface{}); ok && resource["Type"] == typ {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setOverride sets value at path in properties, creating missing objects. Array elements must already exist
+func setOverride(properties map[string]interface{}, path []pathElement, value interface{}) error {
+	var current interface{} = properties
+	for i, element := range path {
+		last := i == len(path)-1
+		if element.index < 0 {
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s isn't an object", pathString(path[:i]))
+			}
+			if last {
+				object[element.key] = value
+				return nil
+			}
+			next, ok := object[element.key]
+			if !ok || next == nil {
+				if path[i+1].index >= 0 {
+					return fmt.Errorf("%s doesn't exist", pathString(path[:i+1]))
+				}
+				next = map[string]interface{}{}
+				object[element.key] = next
+			}
+			current = next
+			continue
+		}
+		array, ok := current.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s isn't an array", pathString(path[:i]))
+		}
+		if element.index >= len(array) {
+			return fmt.Errorf("%s has no element %d", pathString(path[:i]), element.index)
+		}
+		if last {
+			array[element.index] = value
+			return nil
+		}
+		current = array[element.index]
+	}
+	return nil
+}
+
+func pathString(path []pathElement) string {
+	var b strings.Builder
+	for _, element := range path {
+		if element.index >= 0 {
+			fmt.Fprintf(&b, "[%d]", element.index)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(element.key)
+	}
+	return b.String()
+}
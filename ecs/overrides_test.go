@@ -0,0 +1,98 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func overriddenTemplate(t *testing.T, yaml string) (map[string]interface{}, error) {
+	project := loadConfig(t, yaml)
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	raw, err = applyServiceOverrides(project, raw, backend.owners)
+	if err != nil {
+		return nil, err
+	}
+	var overridden map[string]interface{}
+	assert.NilError(t, json.Unmarshal(raw, &overridden))
+	return overridden["Resources"].(map[string]interface{}), nil
+}
+
+func resourceProperties(resources map[string]interface{}, name string) map[string]interface{} {
+	return resources[name].(map[string]interface{})["Properties"].(map[string]interface{})
+}
+
+func TestApplyServiceOverrides(t *testing.T) {
+	resources, err := overriddenTemplate(t, `
+services:
+  front:
+    image: nginx
+    ports:
+      - 80:80
+    x-aws-overrides:
+      Service.PropagateTags: TASK_DEFINITION
+      TaskDefinition.ContainerDefinitions[0].DockerLabels:
+        com.example.team: payments
+      TargetGroup.TargetGroupAttributes:
+        - Key: deregistration_delay.timeout_seconds
+          Value: "30"
+      Listener.Port: 8080
+  back:
+    image: app
+`)
+	assert.NilError(t, err)
+	assert.Equal(t, resourceProperties(resources, "FrontService")["PropagateTags"], "TASK_DEFINITION")
+	assert.Equal(t, resourceProperties(resources, "BackService")["PropagateTags"], "SERVICE")
+
+	containers := resourceProperties(resources, "FrontTaskDefinition")["ContainerDefinitions"].([]interface{})
+	assert.DeepEqual(t, containers[0].(map[string]interface{})["DockerLabels"], map[string]interface{}{
+		"com.example.team": "payments",
+	})
+	assert.DeepEqual(t, resourceProperties(resources, "FrontTCP80TargetGroup")["TargetGroupAttributes"], []interface{}{
+		map[string]interface{}{"Key": "deregistration_delay.timeout_seconds", "Value": "30"},
+	})
+	assert.Equal(t, resourceProperties(resources, "FrontTCP80Listener")["Port"], float64(8080))
+}
+
+func TestApplyServiceOverridesErrors(t *testing.T) {
+	for path, expected := range map[string]string{
+		"Service":                                 `x-aws-overrides: invalid path "Service", must be <resource>.<property>`,
+		"Cluster.ClusterName":                     `x-aws-overrides: invalid path "Cluster.ClusterName", resource must be one of Service, TaskDefinition, TargetGroup or Listener`,
+		"TargetGroup.Port":                        `x-aws-overrides: invalid path "TargetGroup.Port", service has no TargetGroup resource`,
+		"TaskDefinition.ContainerDefinitions[5]":  `x-aws-overrides: invalid path "TaskDefinition.ContainerDefinitions[5]", ContainerDefinitions has no element 5`,
+		"TaskDefinition.Family[0]":                `x-aws-overrides: invalid path "TaskDefinition.Family[0]", Family isn't an array`,
+		"Service.DesiredCount.Value":              `x-aws-overrides: invalid path "Service.DesiredCount.Value", DesiredCount isn't an object`,
+		"Service.Missing[0].Name":                 `x-aws-overrides: invalid path "Service.Missing[0].Name", Missing doesn't exist`,
+		"TaskDefinition.ContainerDefinitions[-1]": `x-aws-overrides: invalid path "TaskDefinition.ContainerDefinitions[-1]", unexpected "ContainerDefinitions[-1]"`,
+	} {
+		_, err := overriddenTemplate(t, `
+services:
+  front:
+    image: nginx
+    x-aws-overrides:
+      `+path+`: 1
+`)
+		assert.Error(t, err, expected, path)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// isSSMParameter tells if secret is an SSM parameter, rather than a Secrets Manager secret
+func isSSMParameter(secret types.SecretConfig) bool {
+	if x, ok := secret.Extensions[extensionSSMParameter]; ok {
+		if b, ok := x.(bool); ok && b {
+			return true
+		}
+	}
+	parsed, err := arn.Parse(secret.Name)
+	if err != nil {
+		return false
+	}
+	return parsed.Service == "ssm" && strings.HasPrefix(parsed.Resource, "parameter/")
+}
+
+// ssmParameterArn returns the ARN for an SSM parameter referenced by name or ARN
+func ssmParameterArn(name string) string {
+	if arn.IsARN(name) {
+		return name
+	}
+	return cloudformation.Sub("arn:${AWS::Partition}:ssm:${AWS::Region}:${AWS::AccountId}:parameter/" + strings.TrimPrefix(name, "/"))
+}
+
+// secretValueFrom returns the reference to a secret value as set on task definition's Secrets
+func secretValueFrom(secret types.SecretConfig) string {
+	if isSSMParameter(secret) {
+		return ssmParameterArn(secret.Name)
+	}
+	return secret.Name
+}
@@ -0,0 +1,128 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+const (
+	// ECS quotas on service placement
+	maxPlacementConstraints      = 10
+	maxPlacementStrategies       = 5
+	maxPlacementExpressionLength = 2000
+)
+
+// nodeLabelConstraint matches compose constraints on node labels, which are ECS container instance custom attributes
+var nodeLabelConstraint = regexp.MustCompile(`^node\.labels\.([^\s=!]+)\s*(==|!=)\s*(\S.*)$`)
+
+// placementConstraints converts deploy.placement.constraints into memberOf constraints. Constraints on node labels are
+// translated into attributes expressions, others are passed through as cluster query language expressions
+func placementConstraints(service types.ServiceConfig) ([]ecs.Service_PlacementConstraint, error) {
+	if service.Deploy == nil || len(service.Deploy.Placement.Constraints) == 0 {
+		return nil, nil
+	}
+	constraints := service.Deploy.Placement.Constraints
+	if len(constraints) > maxPlacementConstraints {
+		return nil, fmt.Errorf("deploy.placement.constraints can't have more than %d constraints", maxPlacementConstraints)
+	}
+	var placement []ecs.Service_PlacementConstraint
+	for _, constraint := range constraints {
+		expression := strings.TrimSpace(constraint)
+		if match := nodeLabelConstraint.FindStringSubmatch(expression); match != nil {
+			expression = fmt.Sprintf("attribute:%s %s %s", match[1], match[2], strings.TrimSpace(match[3]))
+		} else if strings.HasPrefix(expression, "node.") {
+			return nil, fmt.Errorf("placement constraint %q has no ECS equivalent, only node.labels can be used, or a cluster query language expression", constraint)
+		}
+		if expression == "" || len(expression) > maxPlacementExpressionLength {
+			return nil, fmt.Errorf("placement constraint %q must be a non-empty expression of at most %d characters", constraint, maxPlacementExpressionLength)
+		}
+		placement = append(placement, ecs.Service_PlacementConstraint{
+			Type:       ecsapi.PlacementConstraintTypeMemberOf,
+			Expression: expression,
+		})
+	}
+	return placement, nil
+}
+
+// placementStrategies parses x-aws-placement_strategy, the list of strategies ECS applies in order to place tasks
+func placementStrategies(service types.ServiceConfig) ([]ecs.Service_PlacementStrategy, error) {
+	x, ok := service.Extensions[extensionPlacementStrategy]
+	if !ok {
+		return nil, nil
+	}
+	items, ok := x.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("%s must be a list of placement strategies", extensionPlacementStrategy)
+	}
+	if len(items) > maxPlacementStrategies {
+		return nil, fmt.Errorf("%s can't have more than %d strategies", extensionPlacementStrategy, maxPlacementStrategies)
+	}
+	var strategies []ecs.Service_PlacementStrategy
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of placement strategies", extensionPlacementStrategy)
+		}
+		var strategy ecs.Service_PlacementStrategy
+		for key, value := range m {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s %s must be a string", extensionPlacementStrategy, key)
+			}
+			switch key {
+			case "type":
+				strategy.Type = s
+			case "field":
+				strategy.Field = s
+			default:
+				return nil, fmt.Errorf("unsupported %s attribute %s", extensionPlacementStrategy, key)
+			}
+		}
+		if err := checkPlacementStrategy(strategy); err != nil {
+			return nil, err
+		}
+		strategies = append(strategies, strategy)
+	}
+	return strategies, nil
+}
+
+func checkPlacementStrategy(strategy ecs.Service_PlacementStrategy) error {
+	switch strategy.Type {
+	case ecsapi.PlacementStrategyTypeRandom:
+		if strategy.Field != "" {
+			return fmt.Errorf("%s %s doesn't accept a field", extensionPlacementStrategy, strategy.Type)
+		}
+	case ecsapi.PlacementStrategyTypeBinpack:
+		if strategy.Field != "cpu" && strategy.Field != "memory" {
+			return fmt.Errorf("%s %s field must be cpu or memory, got %q", extensionPlacementStrategy, strategy.Type, strategy.Field)
+		}
+	case ecsapi.PlacementStrategyTypeSpread:
+		if strategy.Field != "instanceId" && strategy.Field != "host" && !strings.HasPrefix(strategy.Field, "attribute:") {
+			return fmt.Errorf("%s %s field must be instanceId, host or an attribute, got %q", extensionPlacementStrategy, strategy.Type, strategy.Field)
+		}
+	default:
+		return fmt.Errorf("unsupported %s type %q, must be one of random, binpack or spread", extensionPlacementStrategy, strategy.Type)
+	}
+	return nil
+}
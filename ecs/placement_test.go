@@ -0,0 +1,128 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+func TestPlacementConstraintsAndStrategies(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: nginx
+    shm_size: 1g
+    deploy:
+      placement:
+        constraints:
+          - node.labels.instance-type==g4dn
+          - node.labels.tier != spot
+          - attribute:ecs.os-type == linux
+    x-aws-placement_strategy:
+      - type: spread
+        field: attribute:ecs.availability-zone
+      - type: binpack
+        field: memory
+`)
+	service := template.Resources["TestService"].(*ecs.Service)
+	assert.DeepEqual(t, service.PlacementConstraints, []ecs.Service_PlacementConstraint{
+		{Type: "memberOf", Expression: "attribute:instance-type == g4dn"},
+		{Type: "memberOf", Expression: "attribute:tier != spot"},
+		{Type: "memberOf", Expression: "attribute:ecs.os-type == linux"},
+	})
+	assert.DeepEqual(t, service.PlacementStrategies, []ecs.Service_PlacementStrategy{
+		{Type: "spread", Field: "attribute:ecs.availability-zone"},
+		{Type: "binpack", Field: "memory"},
+	})
+}
+
+func TestPlacementCompatibility(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    deploy:
+      placement:
+        constraints:
+          - node.labels.tier==web
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.DeepEqual(t, project.Services[0].Deploy.Placement.Constraints, []string{"node.labels.tier==web"})
+}
+
+func TestPlacementInvalid(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  test:
+    image: nginx
+    deploy:
+      placement:
+        constraints:
+          - node.labels.tier==web
+`: "deploy.placement.constraints and x-aws-placement_strategy require EC2 launch type",
+		`
+services:
+  test:
+    image: nginx
+    x-aws-placement_strategy:
+      - type: random
+`: "deploy.placement.constraints and x-aws-placement_strategy require EC2 launch type",
+		`
+services:
+  test:
+    image: nginx
+    shm_size: 1g
+    deploy:
+      placement:
+        constraints:
+          - node.role==manager
+`: `placement constraint "node.role==manager" has no ECS equivalent`,
+		`
+services:
+  test:
+    image: nginx
+    shm_size: 1g
+    x-aws-placement_strategy:
+      - type: binpack
+        field: disk
+`: `x-aws-placement_strategy binpack field must be cpu or memory, got "disk"`,
+		`
+services:
+  test:
+    image: nginx
+    shm_size: 1g
+    x-aws-placement_strategy:
+      - type: pack
+`: `unsupported x-aws-placement_strategy type "pack"`,
+		`
+services:
+  test:
+    image: nginx
+    x-aws-placement_strategy: spread
+`: "x-aws-placement_strategy must be a list of placement strategies",
+	} {
+		project := loadConfig(t, yaml)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.ErrorContains(t, err, expected)
+	}
+}
@@ -0,0 +1,470 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/compose-spec/compose-go/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/docker/compose-cli/formatter"
+)
+
+// preflightCheck is a capability required to deploy a project, with the IAM actions it relies on
+type preflightCheck struct {
+	Capability string
+	Actions    []string
+	Resources  []string
+}
+
+type preflightResult struct {
+	Capability string
+	Missing    []string
+}
+
+// preflightChecks lists the capabilities required to deploy project
+func preflightChecks(project *types.Project, partition string) []preflightCheck {
+	checks := []preflightCheck{
+		{
+			Capability: "Create or update CloudFormation stack",
+			Actions: []string{
+				"cloudformation:CreateStack",
+				"cloudformation:CreateChangeSet",
+				"cloudformation:ExecuteChangeSet",
+				"cloudformation:DescribeStacks",
+				"cloudformation:DescribeStackEvents",
+			},
+		},
+		{
+			Capability: "Create and pass IAM roles",
+			Actions: []string{
+				"iam:CreateRole",
+				"iam:PutRolePolicy",
+				"iam:AttachRolePolicy",
+				"iam:PassRole",
+			},
+		},
+		{
+			Capability: "Describe VPC and subnets",
+			Actions: []string{
+				"ec2:DescribeVpcs",
+				"ec2:DescribeSubnets",
+				"ec2:DescribeRouteTables",
+				"ec2:DescribeSecurityGroups",
+			},
+		},
+		{
+			Capability: "Create ECS services",
+			Actions: []string{
+				"ecs:CreateCluster",
+				"ecs:RegisterTaskDefinition",
+				"ecs:CreateService",
+				"ecs:UpdateService",
+			},
+		},
+	}
+
+	var (
+		secrets       []string
+		parameters    []string
+		createSecrets bool
+	)
+	for _, secret := range project.Secrets {
+		if !secret.External.External {
+			createSecrets = true
+			continue
+		}
+		if isSSMParameter(secret) {
+			parameters = append(parameters, secret.Name)
+			continue
+		}
+		secrets = append(secrets, secret.Name)
+	}
+	for _, service := range project.Services {
+		// conversion reports malformed pull credentials
+		if secret, ok, _ := pullCredentials(service); ok {
+			secrets = append(secrets, secret)
+		}
+	}
+	if len(secrets) > 0 {
+		checks = append(checks, preflightCheck{
+			Capability: "Read referenced secrets",
+			Actions:    []string{"secretsmanager:GetSecretValue"},
+			Resources:  resourceArns(secrets),
+		})
+	}
+	if len(parameters) > 0 {
+		checks = append(checks, preflightCheck{
+			Capability: "Read referenced SSM parameters",
+			Actions:    []string{"ssm:GetParameters"},
+			Resources:  resourceArns(parameters),
+		})
+	}
+	if createSecrets {
+		checks = append(checks, preflightCheck{
+			Capability: "Create secrets",
+			Actions: []string{
+				"secretsmanager:CreateSecret",
+				"secretsmanager:DescribeSecret",
+				"secretsmanager:PutSecretValue",
+				"secretsmanager:TagResource",
+				"secretsmanager:UpdateSecret",
+			},
+		})
+	}
+
+	for _, c := range project.Configs {
+		if !c.External.External {
+			checks = append(checks, preflightCheck{
+				Capability: "Create config parameters",
+				Actions:    []string{"ssm:PutParameter", "ssm:AddTagsToResource"},
+			})
+			break
+		}
+	}
+
+	if key, ok, _ := kmsKey(project); ok {
+		checks = append(checks, preflightCheck{
+			Capability: "Encrypt with customer-managed KMS key",
+			Actions: []string{
+				"kms:Decrypt",
+				"kms:GenerateDataKey",
+			},
+			Resources: []string{key},
+		})
+	}
+
+	for _, service := range project.Services {
+		if requireEC2(service) {
+			checks = append(checks, preflightCheck{
+				Capability: "Read ECS optimized AMI parameters",
+				Actions:    []string{"ssm:GetParameters"},
+			})
+			break
+		}
+	}
+
+	if _, ok := project.Extensions[extensionCapacityProvider]; ok {
+		checks = append(checks, preflightCheck{
+			Capability: "Use shared capacity provider",
+			Actions: []string{
+				"ecs:DescribeCapacityProviders",
+				"autoscaling:DescribeAutoScalingGroups",
+				"autoscaling:DescribeLaunchConfigurations",
+				"ec2:DescribeLaunchTemplateVersions",
+				"ec2:DescribeInstanceTypes",
+			},
+		})
+	}
+
+	if _, ok := project.Extensions[extensionEC2Spot]; ok {
+		checks = append(checks, preflightCheck{
+			Capability: "Launch spot instances",
+			Actions: []string{
+				"ec2:CreateLaunchTemplate",
+				"ec2:DescribeInstanceTypes",
+			},
+		})
+	}
+
+	if _, ok := project.Extensions[extensionAutoScalingGroup]; ok {
+		checks = append(checks, preflightCheck{
+			Capability: "Use existing Auto Scaling group",
+			Actions: []string{
+				"autoscaling:DescribeAutoScalingGroups",
+				"autoscaling:DescribeLaunchConfigurations",
+				"ec2:DescribeLaunchTemplateVersions",
+				"ec2:DescribeInstanceTypes",
+				"ecs:CreateCapacityProvider",
+			},
+		})
+	}
+
+	for _, service := range project.Services {
+		if _, ok := parseECRImage(service.Image); ok {
+			checks = append(checks, preflightCheck{
+				Capability: "Verify ECR images",
+				Actions:    []string{"ecr:DescribeImages"},
+			})
+			break
+		}
+	}
+
+	for _, service := range project.Services {
+		if _, ok := service.Extensions[extensionImageScan]; ok {
+			checks = append(checks, preflightCheck{
+				Capability: "Check image scan findings",
+				Actions: []string{
+					"ecr:DescribeImageScanFindings",
+					"ecr:StartImageScan",
+				},
+			})
+			break
+		}
+	}
+
+	for _, service := range project.Services {
+		if controller, _ := deploymentController(service); controller == ecsapi.DeploymentControllerTypeCodeDeploy {
+			checks = append(checks, preflightCheck{
+				Capability: "Create CodeDeploy deployment groups and deployments",
+				Actions: []string{
+					"codedeploy:CreateApplication",
+					"codedeploy:CreateDeploymentGroup",
+					"codedeploy:UpdateDeploymentGroup",
+					"codedeploy:CreateDeployment",
+					"codedeploy:GetDeployment",
+				},
+			})
+			break
+		}
+	}
+
+	if enabled, _ := buildImagesEnabled(project); enabled {
+		checks = append(checks, preflightCheck{
+			Capability: "Push images to ECR",
+			Actions: []string{
+				"ecr:GetAuthorizationToken",
+				"ecr:DescribeRepositories",
+				"ecr:CreateRepository",
+				"ecr:TagResource",
+				"ecr:BatchCheckLayerAvailability",
+				"ecr:InitiateLayerUpload",
+				"ecr:UploadLayerPart",
+				"ecr:CompleteLayerUpload",
+				"ecr:PutImage",
+			},
+		})
+	}
+
+	if bucket, ok := envFilesBucket(project); ok {
+		checks = append(checks, preflightCheck{
+			Capability: "Upload env files",
+			Actions:    []string{"s3:PutObject"},
+			Resources:  []string{s3Arn(partition, bucket, project.Name+"/*")},
+		}, preflightCheck{
+			Capability: "Record resources created outside of stack",
+			Actions:    []string{"ssm:GetParametersByPath", "ssm:PutParameter"},
+		})
+	}
+
+	if bucket, ok, _ := templateBucket(project); ok {
+		if bucket == "" {
+			bucket = createdTemplateBucketPrefix + "*"
+			checks = append(checks, preflightCheck{
+				Capability: "Create nested stacks templates bucket",
+				Actions:    []string{"s3:CreateBucket"},
+			})
+		}
+		checks = append(checks, preflightCheck{
+			Capability: "Upload nested stacks templates",
+			Actions:    []string{"s3:PutObject", "s3:GetObject"},
+			Resources:  []string{s3Arn(partition, bucket, nestedTemplatesPrefix(project.Name)+"*")},
+		}, preflightCheck{
+			Capability: "Record resources created outside of stack",
+			Actions:    []string{"ssm:GetParametersByPath", "ssm:PutParameter"},
+		})
+	}
+
+	for _, service := range project.Services {
+		if _, ok := service.Extensions[extensionMaxTaskLifetime]; ok {
+			checks = append(checks, preflightCheck{
+				Capability: "Create task recycling functions",
+				Actions: []string{
+					"lambda:CreateFunction",
+					"lambda:AddPermission",
+					"events:PutRule",
+					"events:PutTargets",
+				},
+			})
+			break
+		}
+	}
+
+	for _, service := range project.Services {
+		if isScheduled(service) {
+			checks = append(checks, preflightCheck{
+				Capability: "Create scheduled tasks",
+				Actions: []string{
+					"events:PutRule",
+					"events:PutTargets",
+				},
+			})
+			break
+		}
+	}
+
+	for _, volume := range project.Volumes {
+		if isLocalVolume(volume) {
+			continue
+		}
+		if !volume.External.External {
+			checks = append(checks, preflightCheck{
+				Capability: "Create EFS filesystems",
+				Actions: []string{
+					"elasticfilesystem:CreateFileSystem",
+					"elasticfilesystem:CreateMountTarget",
+				},
+			})
+			break
+		}
+	}
+	if deletion, _ := volumesDeletion(project); deletion == volumesDeletionBackup && len(project.Volumes) > 0 {
+		checks = append(checks, preflightCheck{
+			Capability: "Backup EFS filesystems on deletion",
+			Actions:    []string{"backup:StartBackupJob", "backup:DescribeBackupJob"},
+		})
+	}
+	for _, volume := range project.Volumes {
+		if isLocalVolume(volume) {
+			continue
+		}
+		if volume.External.External {
+			checks = append(checks, preflightCheck{
+				Capability: "Use existing EFS filesystems",
+				Actions: []string{
+					"elasticfilesystem:DescribeFileSystems",
+					"elasticfilesystem:DescribeMountTargets",
+					"elasticfilesystem:CreateMountTarget",
+				},
+			})
+			break
+		}
+	}
+	for _, volume := range project.Volumes {
+		if isLocalVolume(volume) {
+			continue
+		}
+		if _, ok := volume.DriverOpts[volumeLifecyclePolicy]; ok && !volume.External.External {
+			checks = append(checks, preflightCheck{
+				Capability: "Configure EFS lifecycle policies",
+				Actions:    []string{"elasticfilesystem:PutLifecycleConfiguration"},
+			})
+			break
+		}
+	}
+	for _, volume := range project.Volumes {
+		if isLocalVolume(volume) {
+			continue
+		}
+		if hasAccessPoint(volume) {
+			checks = append(checks, preflightCheck{
+				Capability: "Create EFS access points",
+				Actions:    []string{"elasticfilesystem:CreateAccessPoint"},
+			})
+			break
+		}
+	}
+	return checks
+}
+
+// resourceArns filters values which are ARNs, as secrets can also be referenced by name
+func resourceArns(values []string) []string {
+	var arns []string
+	for _, v := range values {
+		if arn.IsARN(v) {
+			arns = append(arns, v)
+		}
+	}
+	return arns
+}
+
+// principalArn converts an STS assumed-role ARN into the IAM role ARN, as required by policy simulation. The session
+// ARN doesn't hold the role path, so the role is looked up, and assumed to have none if it can't be
+func (b *ecsAPIService) principalArn(ctx context.Context, caller string) (string, error) {
+	parsed, err := arn.Parse(caller)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Service != "sts" || !strings.HasPrefix(parsed.Resource, "assumed-role/") {
+		return caller, nil
+	}
+	name := strings.Split(parsed.Resource, "/")[1]
+	role, err := b.SDK.GetRoleArn(ctx, name)
+	if err == nil {
+		return role, nil
+	}
+	logrus.Debugf("failed to get role %s, assuming it has no path: %s", name, err)
+	parsed.Service = "iam"
+	parsed.Resource = "role/" + name
+	return parsed.String(), nil
+}
+
+// preflight simulates the calls required to deploy project with the current credentials
+func (b *ecsAPIService) preflight(ctx context.Context, project *types.Project) error {
+	caller, err := b.SDK.GetCallerIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	principal, err := b.principalArn(ctx, caller)
+	if err != nil {
+		return err
+	}
+
+	var results []preflightResult
+	for _, check := range preflightChecks(project, b.partition()) {
+		missing, err := b.SDK.SimulatePrincipalPolicy(ctx, principal, check.Actions, check.Resources)
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "AccessDenied" {
+				logrus.Warnf("skipping preflight check as %s isn't allowed to simulate IAM policies", principal)
+				return nil
+			}
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+				// a role with a path which couldn't be looked up
+				logrus.Warnf("skipping preflight check as %s can't be found to simulate its policies", principal)
+				return nil
+			}
+			return err
+		}
+		results = append(results, preflightResult{
+			Capability: check.Capability,
+			Missing:    missing,
+		})
+	}
+
+	failed, err := printPreflight(os.Stdout, results)
+	if err != nil {
+		return err
+	}
+	if failed {
+		return fmt.Errorf("%s is missing permissions to deploy project %s, use --skip-preflight to deploy anyway", principal, project.Name)
+	}
+	return nil
+}
+
+func printPreflight(out io.Writer, results []preflightResult) (bool, error) {
+	failed := false
+	err := formatter.PrintPrettySection(out, func(w io.Writer) {
+		for _, result := range results {
+			status := "PASS"
+			if len(result.Missing) > 0 {
+				status = "FAIL"
+				failed = true
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", result.Capability, status, strings.Join(result.Missing, ", "))
+		}
+	}, "CAPABILITY", "STATUS", "MISSING ACTIONS")
+	return failed, err
+}
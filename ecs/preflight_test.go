@@ -0,0 +1,115 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/iam"
+	stsapi "github.com/aws/aws-sdk-go/service/sts"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestPrincipalArn(t *testing.T) {
+	iamMock := &mockIAM{}
+	iamMock.On("GetRoleWithContext", "Deployer").Return(&iam.GetRoleOutput{
+		Role: &iam.Role{Arn: aws.String("arn:aws:iam::123456789012:role/ci/Deployer")},
+	}, nil)
+	iamMock.On("GetRoleWithContext", "Restricted").Return(&iam.GetRoleOutput{}, awserr.New("AccessDenied", "not allowed", nil))
+	backend := &ecsAPIService{SDK: sdk{IAM: iamMock}}
+
+	principal, err := backend.principalArn(context.TODO(), "arn:aws:sts::123456789012:assumed-role/Deployer/session")
+	assert.NilError(t, err)
+	assert.Equal(t, principal, "arn:aws:iam::123456789012:role/ci/Deployer")
+
+	principal, err = backend.principalArn(context.TODO(), "arn:aws:sts::123456789012:assumed-role/Restricted/session")
+	assert.NilError(t, err)
+	assert.Equal(t, principal, "arn:aws:iam::123456789012:role/Restricted")
+
+	principal, err = backend.principalArn(context.TODO(), "arn:aws:iam::123456789012:user/alice")
+	assert.NilError(t, err)
+	assert.Equal(t, principal, "arn:aws:iam::123456789012:user/alice")
+}
+
+func TestPreflightSkippedForUnknownRole(t *testing.T) {
+	sts := &mockSTS{}
+	sts.On("GetCallerIdentityWithContext").Return(&stsapi.GetCallerIdentityOutput{
+		Arn: aws.String("arn:aws:sts::123456789012:assumed-role/Restricted/session"),
+	}, nil)
+	iamMock := &mockIAM{}
+	iamMock.On("GetRoleWithContext", "Restricted").Return(&iam.GetRoleOutput{}, awserr.New("AccessDenied", "not allowed", nil))
+	iamMock.On("SimulatePrincipalPolicyPagesWithContext", "arn:aws:iam::123456789012:role/Restricted").Return(&iam.SimulatePolicyResponse{},
+		awserr.New(iam.ErrCodeNoSuchEntityException, "role not found", nil))
+	backend := &ecsAPIService{SDK: sdk{STS: sts, IAM: iamMock}}
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+`)
+	assert.NilError(t, backend.preflight(context.TODO(), project))
+}
+
+func (m *mockIAM) GetRoleWithContext(_ aws.Context, in *iam.GetRoleInput, _ ...request.Option) (*iam.GetRoleOutput, error) {
+	args := m.Called(aws.StringValue(in.RoleName))
+	return args.Get(0).(*iam.GetRoleOutput), args.Error(1)
+}
+
+func TestPreflightChecks(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - password
+    volumes:
+      - data:/data
+secrets:
+  password:
+    external: true
+    name: arn:aws:secretsmanager:eu-west-3:123456789012:secret:password
+volumes:
+  data: {}
+`)
+	checks := map[string]preflightCheck{}
+	for _, check := range preflightChecks(project, "aws") {
+		checks[check.Capability] = check
+	}
+	assert.DeepEqual(t, checks["Read referenced secrets"].Resources, []string{"arn:aws:secretsmanager:eu-west-3:123456789012:secret:password"})
+	assert.Check(t, is.Contains(checks, "Create EFS filesystems"))
+	_, ok := checks["Create secrets"]
+	assert.Check(t, !ok)
+}
+
+func TestPrintPreflight(t *testing.T) {
+	out := bytes.Buffer{}
+	failed, err := printPreflight(&out, []preflightResult{
+		{Capability: "Describe VPC and subnets"},
+		{Capability: "Create EFS filesystems", Missing: []string{"elasticfilesystem:CreateFileSystem"}},
+	})
+	assert.NilError(t, err)
+	assert.Check(t, failed)
+	assert.Check(t, is.Contains(out.String(), "PASS"))
+	assert.Check(t, is.Contains(out.String(), "FAIL"))
+	assert.Check(t, is.Contains(out.String(), "elasticfilesystem:CreateFileSystem"))
+}
@@ -0,0 +1,164 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/compose-spec/compose-go/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/docker/compose-cli/errdefs"
+	"github.com/docker/compose-cli/formatter"
+)
+
+// statefulResourceTypes are the resources whose replacement or removal loses the data they hold
+var statefulResourceTypes = map[string]bool{
+	"AWS::EFS::FileSystem":        true,
+	"AWS::EFS::MountTarget":       true,
+	"AWS::EFS::AccessPoint":       true,
+	"AWS::SecretsManager::Secret": true,
+	"AWS::Logs::LogGroup":         true,
+}
+
+// previewChanges prints the changes deploying template would apply to the project's stack, without applying them.
+// The change set computed for an existing stack is deleted once described.
+func (b *ecsAPIService) previewChanges(ctx context.Context, project *types.Project, template []byte, out io.Writer, color bool) error {
+	tags, err := projectStackTags(project)
+	if err != nil {
+		return classify(err, errdefs.ErrValidation)
+	}
+	topics, err := snsTopics(project)
+	if err != nil {
+		return classify(err, errdefs.ErrValidation)
+	}
+	exists, err := b.SDK.StackExists(ctx, project.Name)
+	if err != nil {
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+
+	var changes []resourceChange
+	if exists {
+		if err := b.checkStackOwner(ctx, project.Name); err != nil {
+			return classify(err, errdefs.ErrDeploymentFailed)
+		}
+		var changeset string
+		changeset, changes, err = b.createChangeSet(ctx, project.Name, template, tags, topics)
+		if changeset != "" {
+			b.SDK.DeleteChangeSet(ctx, changeset) // nolint:errcheck
+		}
+		if err != nil {
+			return classify(err, errdefs.ErrDeploymentFailed)
+		}
+	} else {
+		changes, err = templateResources(template)
+		if err != nil {
+			return classify(err, errdefs.ErrDeploymentFailed)
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "no changes to stack %s\n", project.Name)
+		return nil
+	}
+	printChanges(out, changes, color)
+	if len(b.nested.templates) > 0 {
+		// the SDK doesn't describe changes of nested stacks, only that the nested stack resource is modified
+		logrus.Warn("changes within nested stacks aren't listed, stateful resources they replace or remove can't be checked")
+	}
+
+	var lost []string
+	for _, change := range changes {
+		if !statefulResourceTypes[change.resourceType] {
+			continue
+		}
+		if change.replacement == cloudformation.ReplacementTrue || change.action == cloudformation.ChangeActionRemove {
+			lost = append(lost, fmt.Sprintf("%s (%s)", change.resource, change.resourceType))
+		}
+	}
+	if len(lost) > 0 {
+		return classify(fmt.Errorf("deployment would replace or remove stateful resources, losing their data: %s", strings.Join(lost, ", ")), errdefs.ErrValidation)
+	}
+	return nil
+}
+
+// createChangeSet creates a change set to update stack with template, and returns the changes it computed, none when
+// the template doesn't change the stack
+func (b *ecsAPIService) createChangeSet(ctx context.Context, stack string, template []byte, tags map[string]string, notifications []string) (string, []resourceChange, error) {
+	changeset, err := b.SDK.CreateChangeSet(ctx, stack, template, tags, notifications)
+	if changeset == "" {
+		return "", nil, err
+	}
+	// the change set waiter also fails when the template doesn't change the stack, which describing it tells apart
+	changes, descErr := b.SDK.GetChangeSetChanges(ctx, changeset)
+	if descErr != nil {
+		return changeset, nil, descErr
+	}
+	if err != nil && len(changes) > 0 {
+		return changeset, nil, err
+	}
+	return changeset, changes, nil
+}
+
+// templateResources lists the resources of a template as added, for a stack yet to be created
+func templateResources(template []byte) ([]resourceChange, error) {
+	var parsed struct {
+		Resources map[string]struct {
+			Type string
+		}
+	}
+	if err := json.Unmarshal(template, &parsed); err != nil {
+		return nil, err
+	}
+	var changes []resourceChange
+	for name, resource := range parsed.Resources {
+		changes = append(changes, resourceChange{
+			action:       cloudformation.ChangeActionAdd,
+			resource:     name,
+			resourceType: resource.Type,
+		})
+	}
+	return changes, nil
+}
+
+func printChanges(out io.Writer, changes []resourceChange, color bool) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].resource < changes[j].resource
+	})
+	highlight := func(s string) string { return s }
+	if color {
+		highlight = makeColorFunc("31;1")
+	}
+	_ = formatter.PrintPrettySection(out, func(w io.Writer) {
+		for _, change := range changes {
+			replacement := change.replacement
+			switch replacement {
+			case cloudformation.ReplacementTrue, cloudformation.ReplacementConditional:
+				replacement = highlight(replacement)
+			case "":
+				replacement = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.resource, change.resourceType, change.action, replacement)
+		}
+	}, "RESOURCE", "TYPE", "ACTION", "REPLACEMENT")
+}
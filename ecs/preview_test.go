@@ -0,0 +1,172 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
+)
+
+func previewBackend(changes ...*cloudformation.Change) (*ecsAPIService, *mockCloudFormation) {
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "Test").Return(&cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{{
+			StackName: aws.String("Test"),
+			Tags:      []*cloudformation.Tag{{Key: aws.String(compose.ProjectTag), Value: aws.String("Test")}},
+		}},
+	}, nil)
+	cf.On("CreateChangeSetWithContext", "Test").Return(&cloudformation.CreateChangeSetOutput{Id: aws.String("changeset")}, nil)
+	cf.On("WaitUntilChangeSetCreateCompleteWithContext", "changeset").Return(nil)
+	cf.On("DescribeChangeSetWithContext", "changeset").Return(&cloudformation.DescribeChangeSetOutput{
+		Status:  aws.String(cloudformation.ChangeSetStatusCreateComplete),
+		Changes: changes,
+	}, nil)
+	cf.On("DeleteChangeSetWithContext", "changeset").Return(nil)
+	return &ecsAPIService{SDK: sdk{CF: cf}}, cf
+}
+
+func resourceChangeOf(action, resource, resourceType, replacement string) *cloudformation.Change {
+	change := &cloudformation.ResourceChange{
+		Action:            aws.String(action),
+		LogicalResourceId: aws.String(resource),
+		ResourceType:      aws.String(resourceType),
+	}
+	if replacement != "" {
+		change.Replacement = aws.String(replacement)
+	}
+	return &cloudformation.Change{ResourceChange: change}
+}
+
+func TestPreviewChanges(t *testing.T) {
+	backend, cf := previewBackend(
+		resourceChangeOf("Modify", "WebService", "AWS::ECS::Service", "False"),
+		resourceChangeOf("Add", "ApiService", "AWS::ECS::Service", ""),
+		resourceChangeOf("Remove", "WorkerService", "AWS::ECS::Service", ""),
+		resourceChangeOf("Modify", "WebTaskDefinition", "AWS::ECS::TaskDefinition", "True"),
+	)
+	project := loadConfig(t, `
+services:
+  web:
+    image: nginx
+`)
+	out := &bytes.Buffer{}
+	err := backend.previewChanges(context.TODO(), project, []byte("{}"), out, false)
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), `RESOURCE            TYPE                       ACTION              REPLACEMENT
+ApiService          AWS::ECS::Service          Add                 -
+WebService          AWS::ECS::Service          Modify              False
+WebTaskDefinition   AWS::ECS::TaskDefinition   Modify              True
+WorkerService       AWS::ECS::Service          Remove              -
+`)
+	cf.AssertCalled(t, "DeleteChangeSetWithContext", "changeset")
+	cf.AssertNotCalled(t, "ExecuteChangeSet")
+}
+
+func TestPreviewChangesFailsOnStatefulReplacement(t *testing.T) {
+	backend, cf := previewBackend(
+		resourceChangeOf("Modify", "DataNFSMountTargetOnSubnet1", "AWS::EFS::MountTarget", "True"),
+		resourceChangeOf("Modify", "DbPasswordSecret", "AWS::SecretsManager::Secret", "Conditional"),
+	)
+	project := loadConfig(t, `
+services:
+  web:
+    image: nginx
+`)
+	err := backend.previewChanges(context.TODO(), project, []byte("{}"), &bytes.Buffer{}, false)
+	assert.Error(t, err, "deployment would replace or remove stateful resources, losing their data: DataNFSMountTargetOnSubnet1 (AWS::EFS::MountTarget)")
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeValidation)
+	cf.AssertCalled(t, "DeleteChangeSetWithContext", "changeset")
+}
+
+func TestPreviewChangesFailsOnStatefulRemoval(t *testing.T) {
+	// moving a project onto nested stacks removes its secrets from the parent stack
+	backend, _ := previewBackend(
+		resourceChangeOf("Add", "WebStack", "AWS::CloudFormation::Stack", ""),
+		resourceChangeOf("Remove", "DbPasswordSecret", "AWS::SecretsManager::Secret", ""),
+	)
+	backend.nested = nestedTemplates{bucket: "templates", templates: []nestedTemplate{{bucket: "templates", key: "Test/templates/web.json"}}}
+	project := loadConfig(t, `
+services:
+  web:
+    image: nginx
+`)
+	logs := test.NewGlobal()
+	defer logs.Reset()
+	err := backend.previewChanges(context.TODO(), project, []byte("{}"), &bytes.Buffer{}, false)
+	assert.Error(t, err, "deployment would replace or remove stateful resources, losing their data: DbPasswordSecret (AWS::SecretsManager::Secret)")
+	assert.Equal(t, logs.LastEntry().Level, logrus.WarnLevel)
+	assert.Equal(t, logs.LastEntry().Message, "changes within nested stacks aren't listed, stateful resources they replace or remove can't be checked")
+}
+
+func TestPreviewUnchangedStack(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "Test").Return(&cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{{
+			StackName: aws.String("Test"),
+			Tags:      []*cloudformation.Tag{{Key: aws.String(compose.ProjectTag), Value: aws.String("Test")}},
+		}},
+	}, nil)
+	cf.On("CreateChangeSetWithContext", "Test").Return(&cloudformation.CreateChangeSetOutput{Id: aws.String("changeset")}, nil)
+	cf.On("WaitUntilChangeSetCreateCompleteWithContext", "changeset").Return(errors.New("ResourceNotReady: failed waiting for successful resource state"))
+	cf.On("DescribeChangeSetWithContext", "changeset").Return(&cloudformation.DescribeChangeSetOutput{
+		Status:       aws.String(cloudformation.ChangeSetStatusFailed),
+		StatusReason: aws.String("The submitted information didn't contain changes. Submit different information to create a change set."),
+	}, nil)
+	cf.On("DeleteChangeSetWithContext", "changeset").Return(nil)
+	backend := &ecsAPIService{SDK: sdk{CF: cf}}
+	project := loadConfig(t, `
+services:
+  web:
+    image: nginx
+`)
+	out := &bytes.Buffer{}
+	err := backend.previewChanges(context.TODO(), project, []byte("{}"), out, false)
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "no changes to stack Test\n")
+}
+
+func TestPreviewNewStack(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "Test").Return((*cloudformation.DescribeStacksOutput)(nil),
+		errors.New("ValidationError: Stack with ID Test does not exist"))
+	backend := &ecsAPIService{SDK: sdk{CF: cf}}
+	project := loadConfig(t, `
+services:
+  web:
+    image: nginx
+`)
+	out := &bytes.Buffer{}
+	template := []byte(`{"Resources": {"WebService": {"Type": "AWS::ECS::Service"}, "Cluster": {"Type": "AWS::ECS::Cluster"}}}`)
+	err := backend.previewChanges(context.TODO(), project, template, out, false)
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), `RESOURCE            TYPE                ACTION              REPLACEMENT
+Cluster             AWS::ECS::Cluster   Add                 -
+WebService          AWS::ECS::Service   Add                 -
+`)
+	cf.AssertNotCalled(t, "CreateChangeSetWithContext", "Test")
+}
@@ -0,0 +1,93 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// protectionEnabled tells if project opts in x-aws-protect to protect its stack against deletion and data loss
+func protectionEnabled(project *types.Project) (bool, error) {
+	x, ok := project.Extensions[extensionProtect]
+	if !ok {
+		return false, nil
+	}
+	enabled, ok := x.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean", extensionProtect)
+	}
+	return enabled, nil
+}
+
+type stackPolicyStatement struct {
+	Effect    string
+	Action    interface{}
+	Principal string
+	Resource  string
+	Condition map[string]map[string][]string `json:",omitempty"`
+}
+
+// stackPolicy allows all updates but the replacement and deletion of stateful resources when protected, and all
+// updates otherwise, as a stack policy can't be removed once set
+func stackPolicy(protected bool) (string, error) {
+	statements := []stackPolicyStatement{
+		{Effect: "Allow", Action: "Update:*", Principal: "*", Resource: "*"},
+	}
+	if protected {
+		var stateful []string
+		for t := range statefulResourceTypes {
+			stateful = append(stateful, t)
+		}
+		sort.Strings(stateful)
+		statements = append(statements, stackPolicyStatement{
+			Effect:    "Deny",
+			Action:    []string{"Update:Replace", "Update:Delete"},
+			Principal: "*",
+			Resource:  "*",
+			Condition: map[string]map[string][]string{
+				"StringEquals": {"ResourceType": stateful},
+			},
+		})
+	}
+	policy, err := json.Marshal(map[string]interface{}{"Statement": statements})
+	return string(policy), err
+}
+
+// updateStackProtection enables termination protection and the stack policy of an existing stack when project gets
+// protected before it is updated, and disables them when it isn't protected anymore
+func (b *ecsAPIService) updateStackProtection(ctx context.Context, name string, protected bool) error {
+	enabled, err := b.SDK.GetStackTerminationProtection(ctx, name)
+	if err != nil {
+		return err
+	}
+	if enabled == protected {
+		return nil
+	}
+	policy, err := stackPolicy(protected)
+	if err != nil {
+		return err
+	}
+	if err := b.SDK.SetStackPolicy(ctx, name, policy); err != nil {
+		return err
+	}
+	return b.SDK.UpdateTerminationProtection(ctx, name, protected)
+}
@@ -0,0 +1,108 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
+)
+
+func TestProtectionEnabled(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+x-aws-protect: true
+`)
+	protected, err := protectionEnabled(project)
+	assert.NilError(t, err)
+	assert.Check(t, protected)
+
+	project.Extensions[extensionProtect] = "yes"
+	_, err = protectionEnabled(project)
+	assert.Error(t, err, "x-aws-protect must be a boolean")
+}
+
+func TestStackPolicy(t *testing.T) {
+	policy, err := stackPolicy(false)
+	assert.NilError(t, err)
+	assert.Equal(t, policy, `{"Statement":[{"Effect":"Allow","Action":"Update:*","Principal":"*","Resource":"*"}]}`)
+
+	policy, err = stackPolicy(true)
+	assert.NilError(t, err)
+	assert.Equal(t, policy, `{"Statement":[{"Effect":"Allow","Action":"Update:*","Principal":"*","Resource":"*"},`+
+		`{"Effect":"Deny","Action":["Update:Replace","Update:Delete"],"Principal":"*","Resource":"*",`+
+		`"Condition":{"StringEquals":{"ResourceType":["AWS::EFS::AccessPoint","AWS::EFS::FileSystem","AWS::EFS::MountTarget",`+
+		`"AWS::Logs::LogGroup","AWS::SecretsManager::Secret"]}}}]}`)
+}
+
+func TestDownRefusedOnProtectedStack(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "test").Return(&cloudformation.ListStackResourcesOutput{}, nil)
+	cf.On("DescribeStacksWithContext", "test").Return(&cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{{EnableTerminationProtection: aws.Bool(true)}},
+	}, nil)
+
+	backend := &ecsAPIService{Region: "eu-west-3", SDK: sdk{CF: cf}}
+	err := backend.Down(context.TODO(), "test", compose.DownOptions{Force: true})
+	assert.Error(t, err, "stack test in region eu-west-3 has termination protection, use --force to disable it and delete the stack")
+	assert.Check(t, errors.Is(err, errdefs.ErrValidation))
+	var protectedErr *compose.ProtectedStackError
+	assert.Check(t, errors.As(err, &protectedErr))
+	cf.AssertNotCalled(t, "UpdateTerminationProtectionWithContext", mock.Anything, mock.Anything)
+	cf.AssertNotCalled(t, "DeleteStackWithContext", mock.Anything)
+}
+
+func TestUpdateStackProtection(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "test").Return(&cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{{EnableTerminationProtection: aws.Bool(false)}},
+	}, nil)
+	cf.On("SetStackPolicyWithContext", "test", mock.Anything).Return(nil)
+	cf.On("UpdateTerminationProtectionWithContext", "test", true).Return(nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cf}}
+	err := backend.updateStackProtection(context.TODO(), "test", false)
+	assert.NilError(t, err)
+	cf.AssertNotCalled(t, "SetStackPolicyWithContext", mock.Anything, mock.Anything)
+
+	err = backend.updateStackProtection(context.TODO(), "test", true)
+	assert.NilError(t, err)
+	policy, _ := stackPolicy(true)
+	cf.AssertCalled(t, "SetStackPolicyWithContext", "test", policy)
+	cf.AssertCalled(t, "UpdateTerminationProtectionWithContext", "test", true)
+}
+
+func (m *mockCloudFormation) SetStackPolicyWithContext(_ aws.Context, in *cloudformation.SetStackPolicyInput, _ ...request.Option) (*cloudformation.SetStackPolicyOutput, error) {
+	args := m.Called(aws.StringValue(in.StackName), aws.StringValue(in.StackPolicyBody))
+	return &cloudformation.SetStackPolicyOutput{}, args.Error(0)
+}
+
+func (m *mockCloudFormation) UpdateTerminationProtectionWithContext(_ aws.Context, in *cloudformation.UpdateTerminationProtectionInput, _ ...request.Option) (*cloudformation.UpdateTerminationProtectionOutput, error) {
+	args := m.Called(aws.StringValue(in.StackName), aws.BoolValue(in.EnableTerminationProtection))
+	return &cloudformation.UpdateTerminationProtectionOutput{}, args.Error(0)
+}
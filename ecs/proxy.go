@@ -0,0 +1,130 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"strings"
+
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// default ports Envoy listens on for intercepted traffic
+const (
+	defaultProxyIngressPort = "15000"
+	defaultProxyEgressPort  = "15001"
+)
+
+// setProxyConfiguration declares the x-aws-proxy-configuration proxy so ECS sets up traffic interception
+// to a user managed Envoy. It must run once all containers are defined, as proxy can be any of them
+func setProxyConfiguration(service types.ServiceConfig, definition *ecs.TaskDefinition) error {
+	x, ok := service.Extensions[extensionProxyConfiguration]
+	if !ok {
+		return nil
+	}
+	config, ok := x.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("service %s: %s must be a mapping", service.Name, extensionProxyConfiguration)
+	}
+
+	container, _ := config["container"].(string)
+	if container == "" {
+		return fmt.Errorf("service %s: %s requires a proxy container", service.Name, extensionProxyConfiguration)
+	}
+	var found bool
+	for _, c := range definition.ContainerDefinitions {
+		if c.Name == container {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("service %s: proxy container %s isn't part of the task", service.Name, container)
+	}
+
+	// proxy configuration is supported by both Fargate (platform 1.3.0+) and EC2 launch types, but only
+	// redirects traffic for tasks with networking
+	if definition.NetworkMode != ecsapi.NetworkModeAwsvpc || service.NetworkMode == "none" || service.NetworkMode == "host" {
+		return fmt.Errorf("service %s: %s requires network mode awsvpc", service.Name, extensionProxyConfiguration)
+	}
+
+	ports, err := proxyConfigurationList(config["app_ports"])
+	if err != nil || len(ports) == 0 {
+		return fmt.Errorf("service %s: %s requires app_ports to be a list of ports", service.Name, extensionProxyConfiguration)
+	}
+	properties := []ecs.TaskDefinition_KeyValuePair{
+		{Name: "AppPorts", Value: strings.Join(ports, ",")},
+		{Name: "ProxyIngressPort", Value: proxyConfigurationValue(config, "proxy_ingress_port", defaultProxyIngressPort)},
+		{Name: "ProxyEgressPort", Value: proxyConfigurationValue(config, "proxy_egress_port", defaultProxyEgressPort)},
+	}
+
+	_, uid := config["ignored_uid"]
+	_, gid := config["ignored_gid"]
+	if !uid && !gid {
+		// without them, proxy's own traffic would be intercepted
+		return fmt.Errorf("service %s: %s requires ignored_uid or ignored_gid", service.Name, extensionProxyConfiguration)
+	}
+	if uid {
+		properties = append(properties, ecs.TaskDefinition_KeyValuePair{Name: "IgnoredUID", Value: fmt.Sprint(config["ignored_uid"])})
+	}
+	if gid {
+		properties = append(properties, ecs.TaskDefinition_KeyValuePair{Name: "IgnoredGID", Value: fmt.Sprint(config["ignored_gid"])})
+	}
+	for _, p := range []struct{ key, name string }{
+		{"egress_ignored_ports", "EgressIgnoredPorts"},
+		{"egress_ignored_ips", "EgressIgnoredIPs"},
+	} {
+		values, err := proxyConfigurationList(config[p.key])
+		if err != nil {
+			return fmt.Errorf("service %s: %s %s must be a list", service.Name, extensionProxyConfiguration, p.key)
+		}
+		if len(values) > 0 {
+			properties = append(properties, ecs.TaskDefinition_KeyValuePair{Name: p.name, Value: strings.Join(values, ",")})
+		}
+	}
+
+	definition.ProxyConfiguration = &ecs.TaskDefinition_ProxyConfiguration{
+		ContainerName:                container,
+		ProxyConfigurationProperties: properties,
+		Type:                         ecsapi.ProxyConfigurationTypeAppmesh,
+	}
+	return nil
+}
+
+func proxyConfigurationValue(config map[string]interface{}, key string, defaultValue string) string {
+	if v, ok := config[key]; ok {
+		return fmt.Sprint(v)
+	}
+	return defaultValue
+}
+
+func proxyConfigurationList(v interface{}) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("not a list")
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		values[i] = fmt.Sprint(item)
+	}
+	return values, nil
+}
@@ -0,0 +1,87 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+func TestProxyConfiguration(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  envoy:
+    image: envoyproxy/envoy
+    x-aws-proxy-configuration:
+      container: envoy
+      app_ports: [8080, 8443]
+      ignored_uid: 1337
+      egress_ignored_ips:
+        - 169.254.170.2
+        - 169.254.169.254
+`)
+	def := template.Resources["EnvoyTaskDefinition"].(*ecs.TaskDefinition)
+	assert.DeepEqual(t, def.ProxyConfiguration, &ecs.TaskDefinition_ProxyConfiguration{
+		ContainerName: "envoy",
+		Type:          "APPMESH",
+		ProxyConfigurationProperties: []ecs.TaskDefinition_KeyValuePair{
+			{Name: "AppPorts", Value: "8080,8443"},
+			{Name: "ProxyIngressPort", Value: "15000"},
+			{Name: "ProxyEgressPort", Value: "15001"},
+			{Name: "IgnoredUID", Value: "1337"},
+			{Name: "EgressIgnoredIPs", Value: "169.254.170.2,169.254.169.254"},
+		},
+	})
+}
+
+func TestProxyConfigurationErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "unknown container",
+			config: "{ container: envoy, app_ports: [8080], ignored_uid: 1337 }",
+			err:    "proxy container envoy isn't part of the task",
+		},
+		{
+			name:   "no app ports",
+			config: "{ container: test, ignored_uid: 1337 }",
+			err:    "requires app_ports",
+		},
+		{
+			name:   "no ignored uid or gid",
+			config: "{ container: test, app_ports: [8080] }",
+			err:    "requires ignored_uid or ignored_gid",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    x-aws-proxy-configuration: `+tc.config+`
+`)
+			backend := &ecsAPIService{}
+			_, err := backend.convert(project, awsResources{})
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
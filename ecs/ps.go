@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
+
 	"github.com/docker/compose-cli/api/compose"
 )
 
@@ -37,6 +39,10 @@ func (b *ecsAPIService) Ps(ctx context.Context, project string) ([]compose.Servi
 	if len(servicesARN) == 0 {
 		return nil, nil
 	}
+	stopped, err := b.stoppedServices(ctx, project)
+	if err != nil {
+		return nil, err
+	}
 
 	status := []compose.ServiceStatus{}
 	for _, arn := range servicesARN {
@@ -54,6 +60,25 @@ func (b *ecsAPIService) Ps(ctx context.Context, project string) ([]compose.Servi
 				strings.ToLower(lb.Protocol)))
 		}
 		state.Ports = ports
+		state.Status = compose.RUNNING
+		if _, ok := stopped[state.Name]; ok && state.Desired == 0 {
+			state.Status = compose.STOPPED
+		} else if state.Replicas < state.Desired {
+			state.Status = compose.STARTING
+		}
+
+		tasks, err := b.SDK.GetServiceTasks(ctx, cluster, arn, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tasks {
+			state.Tasks = append(state.Tasks, compose.TaskStatus{
+				ID:      aws.StringValue(t.TaskArn),
+				Started: aws.TimeValue(t.StartedAt),
+				Status:  aws.StringValue(t.LastStatus),
+				Health:  aws.StringValue(t.HealthStatus),
+			})
+		}
 		status = append(status, state)
 	}
 	return status, nil
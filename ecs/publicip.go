@@ -0,0 +1,63 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// publicIPAssignment tells if tasks of service get a public IP, as set by x-aws-assign_public_ip. By default Fargate tasks
+// get one so they can pull images from public subnets, while tasks on EC2 instances can't get one
+func publicIPAssignment(service types.ServiceConfig, ec2 bool) (string, error) {
+	x, ok := service.Extensions[extensionAssignPublicIP]
+	if !ok {
+		if ec2 {
+			return ecsapi.AssignPublicIpDisabled, nil
+		}
+		return ecsapi.AssignPublicIpEnabled, nil
+	}
+	enabled, ok := x.(bool)
+	if !ok {
+		return "", fmt.Errorf("%s must be true or false", extensionAssignPublicIP)
+	}
+	if !enabled {
+		return ecsapi.AssignPublicIpDisabled, nil
+	}
+	if ec2 {
+		return "", fmt.Errorf("%s can't be enabled with %s launch type, as tasks on EC2 instances don't get a public IP",
+			extensionAssignPublicIP, ecsapi.LaunchTypeEc2)
+	}
+	return ecsapi.AssignPublicIpEnabled, nil
+}
+
+// checkPrivateTaskSubnets warns about Fargate tasks without a public IP running in public subnets only, which can't
+// reach image registries unless the VPC has a NAT gateway or VPC endpoints
+func (b *ecsAPIService) checkPrivateTaskSubnets(service types.ServiceConfig, subnets []string, resources awsResources) {
+	if len(subnets) == 0 {
+		return
+	}
+	for _, subnet := range subnets {
+		if !resources.publicSubnets[subnet] {
+			return
+		}
+	}
+	b.warn(warningPrivateTaskPull, severityWarning, service.Name,
+		"tasks don't get a public IP but only run in public subnets, image pulls may fail without a NAT gateway or VPC endpoints")
+}
@@ -0,0 +1,97 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+func TestAssignPublicIP(t *testing.T) {
+	for name, tc := range map[string]struct {
+		gpu       bool
+		extension string
+		expected  string
+	}{
+		"fargate default":  {expected: "ENABLED"},
+		"fargate enabled":  {extension: "x-aws-assign_public_ip: true", expected: "ENABLED"},
+		"fargate disabled": {extension: "x-aws-assign_public_ip: false", expected: "DISABLED"},
+		"ec2 default":      {gpu: true, expected: "DISABLED"},
+		"ec2 disabled":     {gpu: true, extension: "x-aws-assign_public_ip: false", expected: "DISABLED"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			template := convertYaml(t, publicIPYaml(tc.gpu, tc.extension))
+			service := template.Resources["TestService"].(*ecs.Service)
+			assert.Equal(t, service.NetworkConfiguration.AwsvpcConfiguration.AssignPublicIp, tc.expected)
+		})
+	}
+}
+
+func TestAssignPublicIPEC2Enabled(t *testing.T) {
+	_, err := (&ecsAPIService{}).convert(loadConfig(t, publicIPYaml(true, "x-aws-assign_public_ip: true")), awsResources{})
+	assert.ErrorContains(t, err, "x-aws-assign_public_ip can't be enabled with EC2 launch type")
+}
+
+func TestAssignPublicIPInvalid(t *testing.T) {
+	_, err := (&ecsAPIService{}).convert(loadConfig(t, publicIPYaml(false, "x-aws-assign_public_ip: yes please")), awsResources{})
+	assert.ErrorContains(t, err, "x-aws-assign_public_ip must be true or false")
+}
+
+func TestPrivateTaskInPublicSubnets(t *testing.T) {
+	project := loadConfig(t, publicIPYaml(false, "x-aws-assign_public_ip: false"))
+	backend := &ecsAPIService{}
+	_, err := backend.convert(project, awsResources{
+		subnets:       []string{"subnet1", "subnet2"},
+		publicSubnets: map[string]bool{"subnet1": true, "subnet2": true},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(backend.warnings), 1)
+	assert.Equal(t, backend.warnings[0].Code, warningPrivateTaskPull)
+	assert.Equal(t, backend.warnings[0].Service, "test")
+
+	// a private subnet may route to a NAT gateway
+	backend = &ecsAPIService{}
+	_, err = backend.convert(project, awsResources{
+		subnets:       []string{"subnet1", "subnet2"},
+		publicSubnets: map[string]bool{"subnet1": true},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(backend.warnings), 0)
+}
+
+func publicIPYaml(gpu bool, extension string) string {
+	var resources string
+	if gpu {
+		resources = `
+    deploy:
+      resources:
+        reservations:
+          generic_resources:
+            - discrete_resource_spec:
+                kind: gpus
+                value: 1`
+	}
+	return fmt.Sprintf(`
+services:
+  test:
+    image: nginx
+    %s%s
+`, extension, resources)
+}
@@ -0,0 +1,275 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/distribution/reference"
+)
+
+// errImageNotFound is returned when registry doesn't know about the image
+var errImageNotFound = errors.New("image not found")
+
+// manifestMediaTypes are the manifests a registry can return for an image tag, either single or multi-platform
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// pinImages tells if project sets x-aws-pin_images to deploy services images by digest
+func pinImages(project *types.Project) (bool, error) {
+	x, ok := project.Extensions[extensionPinImages]
+	if !ok {
+		return false, nil
+	}
+	pin, ok := x.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean", extensionPinImages)
+	}
+	return pin, nil
+}
+
+// resolveImages fails if a service image doesn't exist in its registry, so a typo doesn't let half of the stack get
+// created. Images are rewritten as repository@digest when project sets x-aws-pin_images
+func (b *ecsAPIService) resolveImages(ctx context.Context, project *types.Project) error {
+	pin, err := pinImages(project)
+	if err != nil {
+		return err
+	}
+	b.digests = map[string]string{}
+	for i, service := range project.Services {
+		digest, err := b.imageDigest(ctx, service)
+		if errors.Is(err, errImageNotFound) {
+			return fmt.Errorf("service %s: image %s not found", service.Name, service.Image)
+		}
+		if err != nil {
+			return fmt.Errorf("service %s: can't verify image %s: %w", service.Name, service.Image, err)
+		}
+		if digest != "" {
+			b.digests[service.Name] = digest
+		}
+		if !pin || digest == "" {
+			continue
+		}
+		if repository, _, ok := splitImageTag(service.Image); ok {
+			project.Services[i].Image = fmt.Sprintf("%s@%s", repository, digest)
+		}
+	}
+	return nil
+}
+
+// imageDigest returns the digest of service image, or an empty string if it can't be checked from here
+func (b *ecsAPIService) imageDigest(ctx context.Context, service types.ServiceConfig) (string, error) {
+	if b.unbuilt[service.Name] {
+		return "", nil
+	}
+	named, err := reference.ParseNormalizedNamed(service.Image)
+	if err != nil {
+		return "", err
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest().String(), nil
+	}
+	tag := reference.TagNameOnly(named).(reference.Tagged).Tag()
+
+	if image, ok := parseECRImage(service.Image); ok {
+		if image.region != b.Region {
+			// createCrossAccountPullPolicies warns about images pulled from another region
+			return "", nil
+		}
+		return b.SDK.GetImageDigest(ctx, image)
+	}
+
+	var credentials *registryCredentials
+	secretID, ok, err := pullCredentials(service)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		secret, err := b.SDK.GetSecretValue(ctx, secretID)
+		if err != nil {
+			return "", err
+		}
+		credentials = &registryCredentials{}
+		if err := json.Unmarshal([]byte(secret), credentials); err != nil {
+			return "", fmt.Errorf("pull credentials %s must be a JSON document with username and password: %w", secretID, err)
+		}
+	}
+	return b.registry.digest(ctx, named, tag, credentials)
+}
+
+// pullCredentials returns the secret x-aws-pull_credentials sets for ECS to authenticate to service's image registry
+func pullCredentials(service types.ServiceConfig) (string, bool, error) {
+	x, ok := service.Extensions[extensionPullCredentials]
+	if !ok {
+		return "", false, nil
+	}
+	secret, ok := x.(string)
+	if !ok || secret == "" {
+		return "", false, fmt.Errorf("%s must be the ARN or name of a secret, got %v", extensionPullCredentials, x)
+	}
+	return secret, true, nil
+}
+
+// registryCredentials is the content of a pull credentials secret, as expected by ECS
+type registryCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// registryClient queries Docker registry HTTP API V2 compatible registries
+type registryClient struct {
+	client *http.Client
+}
+
+// digest resolves the manifest digest of image tag, with a HEAD request so it doesn't count as a pull
+func (r registryClient) digest(ctx context.Context, named reference.Named, tag string, credentials *registryCredentials) (string, error) {
+	host := reference.Domain(named)
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	manifest := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, reference.Path(named), tag)
+
+	response, err := r.head(ctx, manifest, "")
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode == http.StatusUnauthorized {
+		authorization, err := r.authorize(ctx, response.Header.Get("WWW-Authenticate"), credentials)
+		if err != nil {
+			return "", err
+		}
+		response, err = r.head(ctx, manifest, authorization)
+		if err != nil {
+			return "", err
+		}
+	}
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", errImageNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		if credentials == nil {
+			// registries may deny access to unknown repositories to anonymous users
+			return "", fmt.Errorf("access denied by %s, set %s if registry requires authentication", host, extensionPullCredentials)
+		}
+		return "", fmt.Errorf("access denied by %s", host)
+	default:
+		return "", fmt.Errorf("%s responded %s", host, response.Status)
+	}
+	digest := response.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("%s didn't return the image digest", host)
+	}
+	return digest, nil
+}
+
+func (r registryClient) head(ctx context.Context, manifest string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifest, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	response, err := r.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = response.Body.Close()
+	return response, nil
+}
+
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize answers registry authentication challenge, returning the Authorization header to retry with
+func (r registryClient) authorize(ctx context.Context, challenge string, credentials *registryCredentials) (string, error) {
+	scheme := strings.SplitN(challenge, " ", 2)[0]
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if credentials == nil {
+			return "", nil
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials.Username+":"+credentials.Password)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported registry authentication challenge %q", challenge)
+	}
+
+	params := map[string]string{}
+	for _, match := range challengeParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid registry authentication challenge %q", challenge)
+	}
+	query := realm.Query()
+	for _, p := range []string{"service", "scope"} {
+		if params[p] != "" {
+			query.Set(p, params[p])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if credentials != nil {
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+	response, err := r.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close() // nolint:errcheck
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token request responded %s", response.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+func (r registryClient) httpClient() *http.Client {
+	if r.client != nil {
+		return r.client
+	}
+	return http.DefaultClient
+}
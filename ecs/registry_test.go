@@ -0,0 +1,173 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+)
+
+const manifestDigest = "sha256:4fbd5fcf0e7dcf1bdc2e0cd2e4a09f1a47cec0bc1e7b2432cba06acf7e2ed5fb"
+
+// fakeRegistry serves manifests of app:1.0, and requires a bearer token obtained with user:password when private
+func fakeRegistry(t *testing.T, private bool) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			user, password, ok := r.BasicAuth()
+			if private && (!ok || user != "user" || password != "password") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, r.URL.Query().Get("scope"), "repository:app:pull")
+			_, _ = fmt.Fprint(w, `{"token": "secret-token"}`)
+		case r.Header.Get("Authorization") != "Bearer secret-token":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:app:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method == http.MethodHead && r.URL.Path == "/v2/app/manifests/1.0":
+			assert.Check(t, strings.Contains(r.Header.Get("Accept"), "application/vnd.docker.distribution.manifest.list.v2+json"))
+			w.Header().Set("Docker-Content-Digest", manifestDigest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+type mockSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+	mock.Mock
+}
+
+func (m *mockSecretsManager) GetSecretValueWithContext(_ aws.Context, in *secretsmanager.GetSecretValueInput, _ ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	args := m.Called(aws.StringValue(in.SecretId))
+	return args.Get(0).(*secretsmanager.GetSecretValueOutput), args.Error(1)
+}
+
+func (m *mockECR) DescribeImagesWithContext(_ aws.Context, in *ecr.DescribeImagesInput, _ ...request.Option) (*ecr.DescribeImagesOutput, error) {
+	args := m.Called(imageKey(in.RepositoryName, in.ImageIds[0]))
+	return args.Get(0).(*ecr.DescribeImagesOutput), args.Error(1)
+}
+
+func TestResolvePublicImage(t *testing.T) {
+	server := fakeRegistry(t, false)
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	project := loadConfig(t, `
+services:
+  web:
+    image: `+registry+`/app:1.0
+`)
+	backend := &ecsAPIService{registry: registryClient{client: server.Client()}}
+	assert.NilError(t, backend.resolveImages(context.TODO(), project))
+	assert.Equal(t, project.Services[0].Image, registry+"/app:1.0")
+
+	project.Extensions = map[string]interface{}{extensionPinImages: true}
+	assert.NilError(t, backend.resolveImages(context.TODO(), project))
+	assert.Equal(t, project.Services[0].Image, registry+"/app@"+manifestDigest)
+}
+
+func TestResolveMissingImage(t *testing.T) {
+	server := fakeRegistry(t, false)
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	project := loadConfig(t, `
+services:
+  web:
+    image: `+registry+`/app:1.O
+`)
+	backend := &ecsAPIService{registry: registryClient{client: server.Client()}}
+	err := backend.resolveImages(context.TODO(), project)
+	assert.Error(t, err, fmt.Sprintf("service web: image %s/app:1.O not found", registry))
+}
+
+func TestResolvePrivateImage(t *testing.T) {
+	server := fakeRegistry(t, true)
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	project := loadConfig(t, `
+services:
+  web:
+    image: `+registry+`/app:1.0
+    x-aws-pull_credentials: arn:aws:secretsmanager:eu-west-3:123456789012:secret:registry
+x-aws-pin_images: true
+`)
+	sm := &mockSecretsManager{}
+	sm.On("GetSecretValueWithContext", "arn:aws:secretsmanager:eu-west-3:123456789012:secret:registry").
+		Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String(`{"username": "user", "password": "password"}`)}, nil)
+	backend := &ecsAPIService{SDK: sdk{SM: sm}, registry: registryClient{client: server.Client()}}
+	assert.NilError(t, backend.resolveImages(context.TODO(), project))
+	sm.AssertExpectations(t)
+	assert.Equal(t, project.Services[0].Image, registry+"/app@"+manifestDigest)
+
+	project.Services[0].Extensions = nil
+	project.Services[0].Image = registry + "/app:1.0"
+	err := backend.resolveImages(context.TODO(), project)
+	assert.ErrorContains(t, err, "service web: can't verify image "+registry+"/app:1.0: registry token request responded 401 Unauthorized")
+}
+
+func TestResolveECRImage(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  web:
+    image: 123456789012.dkr.ecr.eu-west-3.amazonaws.com/web:1.0
+  api:
+    image: 123456789012.dkr.ecr.eu-west-3.amazonaws.com/api:1.0
+  front:
+    image: 123456789012.dkr.ecr.us-east-1.amazonaws.com/front:1.0
+x-aws-pin_images: true
+`)
+	m := &mockECR{}
+	m.On("DescribeImagesWithContext", "web:1.0").Return(&ecr.DescribeImagesOutput{
+		ImageDetails: []*ecr.ImageDetail{{ImageDigest: aws.String(manifestDigest)}},
+	}, nil)
+	m.On("DescribeImagesWithContext", "api:1.0").Return(&ecr.DescribeImagesOutput{},
+		awserr.New(ecr.ErrCodeImageNotFoundException, "not found", nil))
+	backend := &ecsAPIService{Region: "eu-west-3", SDK: sdk{ECR: m}}
+	err := backend.resolveImages(context.TODO(), project)
+	assert.Error(t, err, "service api: image 123456789012.dkr.ecr.eu-west-3.amazonaws.com/api:1.0 not found")
+
+	api, _ := project.GetService("api")
+	api.Image = "123456789012.dkr.ecr.eu-west-3.amazonaws.com/api@" + manifestDigest
+	for i, s := range project.Services {
+		if s.Name == "api" {
+			project.Services[i] = api
+		}
+	}
+	assert.NilError(t, backend.resolveImages(context.TODO(), project))
+	web, _ := project.GetService("web")
+	assert.Equal(t, web.Image, "123456789012.dkr.ecr.eu-west-3.amazonaws.com/web@"+manifestDigest)
+	front, _ := project.GetService("front")
+	assert.Equal(t, front.Image, "123456789012.dkr.ecr.us-east-1.amazonaws.com/front:1.0")
+}
@@ -0,0 +1,74 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultMaxRetries is the number of times a throttled or failed AWS API call is retried, unless set by the context
+	defaultMaxRetries = 8
+	// maxThrottleDelay caps the jittered exponential backoff between retries of a throttled call
+	maxThrottleDelay = 20 * time.Second
+)
+
+// throttlingRetryer retries AWS API calls with jittered exponential backoff, as SDK's default retryer, and logs
+// retries of throttled calls
+type throttlingRetryer struct {
+	client.DefaultRetryer
+}
+
+func newRetryer(maxRetries int) throttlingRetryer {
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+	return throttlingRetryer{
+		DefaultRetryer: client.DefaultRetryer{
+			NumMaxRetries:    maxRetries,
+			MinThrottleDelay: client.DefaultRetryerMinThrottleDelay,
+			MaxThrottleDelay: maxThrottleDelay,
+		},
+	}
+}
+
+func (r throttlingRetryer) RetryRules(req *request.Request) time.Duration {
+	delay := r.DefaultRetryer.RetryRules(req)
+	if req.IsErrorThrottle() {
+		logrus.Debugf("%s %s throttled, retrying in %s (attempt %d/%d)", req.ClientInfo.ServiceName, req.Operation.Name,
+			delay.Round(time.Millisecond), req.RetryCount+1, r.NumMaxRetries)
+	}
+	return delay
+}
+
+// reportThrottlingExhausted tells apart a call still throttled after all retries from other failures of the request.
+// It runs after the retry decision, as request error is left set only once the SDK gave up on the call
+func reportThrottlingExhausted(req *request.Request) {
+	if req.Error == nil || !req.IsErrorThrottle() || req.RetryCount == 0 {
+		return
+	}
+	if aerr, ok := req.Error.(awserr.Error); ok {
+		req.Error = awserr.New(aerr.Code(), fmt.Sprintf("%s %s still throttled after %d retries, AWS API rate limit exceeded: %s",
+			req.ClientInfo.ServiceName, req.Operation.Name, req.RetryCount, aerr.Message()), aerr.OrigErr())
+	}
+}
@@ -0,0 +1,86 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/errdefs"
+)
+
+// fakeAPI returns an sdk which ECS API answers each call with the next error code, then succeeds
+func fakeAPI(t *testing.T, maxRetries int, codes ...string) (sdk, *int) {
+	retryer := newRetryer(maxRetries)
+	retryer.MinThrottleDelay = time.Millisecond
+	retryer.MaxThrottleDelay = time.Millisecond
+	sess, err := session.NewSession(request.WithRetryer(&aws.Config{
+		Region:      aws.String("eu-west-3"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}, retryer))
+	assert.NilError(t, err)
+
+	calls := 0
+	sess.Handlers.Send.Clear()
+	sess.Handlers.Send.PushBack(func(r *request.Request) {
+		status, body := http.StatusOK, `{"clusterArns": []}`
+		if calls < len(codes) {
+			status, body = http.StatusBadRequest, `{"__type": "`+codes[calls]+`", "message": "failed"}`
+		}
+		calls++
+		r.HTTPResponse = &http.Response{
+			StatusCode: status,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}
+	})
+	return newSDK(sess), &calls
+}
+
+func TestRetryThrottledCalls(t *testing.T) {
+	api, calls := fakeAPI(t, 3, "ThrottlingException", "ThrottlingException")
+	_, err := api.ECS.ListClustersWithContext(context.TODO(), &ecsapi.ListClustersInput{})
+	assert.NilError(t, err)
+	assert.Equal(t, *calls, 3)
+}
+
+func TestThrottlingExhausted(t *testing.T) {
+	api, calls := fakeAPI(t, 2, "ThrottlingException", "ThrottlingException", "ThrottlingException")
+	_, err := api.ECS.ListClustersWithContext(context.TODO(), &ecsapi.ListClustersInput{})
+	assert.ErrorContains(t, err, "ecs ListClusters still throttled after 2 retries, AWS API rate limit exceeded: failed")
+	assert.Equal(t, *calls, 3)
+	assert.Equal(t, errdefs.ExitCode(classify(err, errdefs.ErrDeploymentFailed)), errdefs.ExitCodeThrottled)
+}
+
+func TestPermissionErrorsNotRetried(t *testing.T) {
+	api, calls := fakeAPI(t, 2, "AccessDeniedException")
+	_, err := api.ECS.ListClustersWithContext(context.TODO(), &ecsapi.ListClustersInput{})
+	assert.ErrorContains(t, err, "AccessDeniedException: failed")
+	assert.Equal(t, *calls, 1)
+	assert.Equal(t, errdefs.ExitCode(classify(err, errdefs.ErrDeploymentFailed)), errdefs.ExitCodeAuthentication)
+}
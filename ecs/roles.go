@@ -0,0 +1,191 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// roleArn returns the pre-created IAM role set by extension on service, which is then used instead of a generated one
+func roleArn(service types.ServiceConfig, extension string) (string, bool, error) {
+	x, ok := service.Extensions[extension]
+	if !ok {
+		return "", false, nil
+	}
+	value := fmt.Sprint(x)
+	parsed, err := arn.Parse(value)
+	if err != nil || parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+		return "", false, fmt.Errorf("invalid %s: %q is not an IAM role ARN", extension, value)
+	}
+	return value, true, nil
+}
+
+// roleSettings returns the permissions boundary and path project sets for the IAM roles it creates
+func roleSettings(project *types.Project) (string, string, error) {
+	var boundary, path string
+	if x, ok := project.Extensions[extensionPermissionsBoundary]; ok {
+		boundary = fmt.Sprint(x)
+		parsed, err := arn.Parse(boundary)
+		if err != nil || parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "policy/") {
+			return "", "", fmt.Errorf("%s must be an IAM policy ARN, got %q", extensionPermissionsBoundary, boundary)
+		}
+	}
+	if x, ok := project.Extensions[extensionIAMPath]; ok {
+		path = fmt.Sprint(x)
+		if !strings.HasPrefix(path, "/") || !strings.HasSuffix(path, "/") {
+			return "", "", fmt.Errorf("%s must start and end with /, got %q", extensionIAMPath, path)
+		}
+	}
+	return boundary, path, nil
+}
+
+// applyRoleSettings sets project's permissions boundary and path on all the IAM roles created by template
+func applyRoleSettings(project *types.Project, template *cloudformation.Template) error {
+	boundary, path, err := roleSettings(project)
+	if err != nil {
+		return err
+	}
+	for _, r := range template.Resources {
+		if role, ok := r.(*iam.Role); ok {
+			role.PermissionsBoundary = boundary
+			role.Path = path
+		}
+	}
+	return nil
+}
+
+// taskExecutionRoleArn returns the execution role of service's task, created unless set by x-aws-execution_role_arn
+func (b *ecsAPIService) taskExecutionRoleArn(project *types.Project, service types.ServiceConfig, secretRefs map[string]string, template *cloudformation.Template) (string, error) {
+	role, ok, err := roleArn(service, extensionExecutionRoleArn)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		taskExecutionRole, err := b.createTaskExecutionRole(project, service, secretRefs, template)
+		if err != nil {
+			return "", err
+		}
+		return cloudformation.Ref(taskExecutionRole), nil
+	}
+	for _, member := range taskServices(project, service) {
+		if _, ok := member.Extensions[extensionExecutionManagedPolicies]; ok {
+			return "", fmt.Errorf("%s can't be set with %s, as policies can't be attached to a pre-created role", extensionExecutionManagedPolicies, extensionExecutionRoleArn)
+		}
+	}
+	return role, nil
+}
+
+// taskRoleArn returns the role of service's task, created unless set by x-aws-task_role_arn, empty if none is required
+func (b *ecsAPIService) taskRoleArn(project *types.Project, service types.ServiceConfig, template *cloudformation.Template) (string, error) {
+	role, ok, err := roleArn(service, extensionTaskRoleArn)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		taskRole, err := b.createTaskRole(project, service, template)
+		if err != nil || taskRole == "" {
+			return "", err
+		}
+		return cloudformation.Ref(taskRole), nil
+	}
+	if executeCommandEnabled(project, service) {
+		b.warn(warningRolePermissions, severityWarning, service.Name, "task role %s must grant ssmmessages channels access for ECS Exec", role)
+	}
+	if meshed(project, service) {
+		b.warn(warningRolePermissions, severityWarning, service.Name, "task role %s must grant %s access to the service's virtual node for Envoy", role, actionStreamAggregatedResources)
+	}
+	if xrayEnabled(project, service) {
+		b.warn(warningRolePermissions, severityWarning, service.Name, "task role %s must grant X-Ray write access for the X-Ray daemon", role)
+	}
+	for _, member := range taskServices(project, service) {
+		for _, x := range []string{extensionRole, extensionManagedPolicies} {
+			if _, ok := member.Extensions[x]; ok {
+				return "", fmt.Errorf("%s can't be set with %s, as policies can't be attached to a pre-created role", x, extensionTaskRoleArn)
+			}
+		}
+		resourcesPolicies, err := applicationResourcesPolicies(project, member)
+		if err != nil {
+			return "", err
+		}
+		if len(resourcesPolicies) > 0 || len(volumesAccessPolicies(project, member)) > 0 {
+			b.warn(warningRolePermissions, severityWarning, member.Name, "task role %s must grant access to the resources and volumes service uses", role)
+		}
+	}
+	return role, nil
+}
+
+// checkExecutionRoles checks pre-created execution roles can read the secrets services consume, as the policy granting
+// access can't be attached to them
+func (b *ecsAPIService) checkExecutionRoles(ctx context.Context, project *types.Project, resources awsResources) error {
+	for _, service := range project.Services {
+		role, ok, err := roleArn(service, extensionExecutionRoleArn)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		var secrets, parameters []string
+		for _, member := range taskServices(project, service) {
+			if secret, ok, _ := pullCredentials(member); ok {
+				secrets = append(secrets, secret)
+			}
+			for _, s := range member.Secrets {
+				secret := project.Secrets[s.Source]
+				switch {
+				case isSSMParameter(secret):
+					parameters = append(parameters, secret.Name)
+				case secret.External.External:
+					secrets = append(secrets, secret.Name)
+				case resources.secrets[s.Source] != "":
+					secrets = append(secrets, resources.secrets[s.Source])
+				default:
+					b.warn(warningRolePermissions, severityWarning, member.Name, "execution role %s can't be granted access to secret %s embedded in the template", role, s.Source)
+				}
+			}
+		}
+		var checks []preflightCheck
+		if arns := resourceArns(secrets); len(arns) > 0 {
+			checks = append(checks, preflightCheck{Actions: []string{actionGetSecretValue}, Resources: arns})
+		}
+		if arns := resourceArns(parameters); len(arns) > 0 {
+			checks = append(checks, preflightCheck{Actions: []string{actionGetParameters}, Resources: arns})
+		}
+		for _, check := range checks {
+			missing, err := b.SDK.SimulatePrincipalPolicy(ctx, role, check.Actions, check.Resources)
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "AccessDenied" {
+				b.warn(warningRolePermissions, severityWarning, service.Name, "can't check execution role %s is allowed to read %s, as simulating IAM policies isn't allowed", role, strings.Join(check.Resources, ", "))
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if len(missing) > 0 {
+				b.warn(warningRolePermissions, severityWarning, service.Name, "execution role %s isn't allowed %s on %s, tasks will fail to start", role, strings.Join(missing, ", "), strings.Join(check.Resources, ", "))
+			}
+		}
+	}
+	return nil
+}
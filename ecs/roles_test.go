@@ -0,0 +1,245 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	iamapi "github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestPreCreatedRoles(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: nginx
+    x-aws-task_role_arn: arn:aws:iam::012345678910:role/app
+    x-aws-execution_role_arn: arn:aws:iam::012345678910:role/execution
+`)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, def.TaskRoleArn, "arn:aws:iam::012345678910:role/app")
+	assert.Equal(t, def.ExecutionRoleArn, "arn:aws:iam::012345678910:role/execution")
+	_, ok := template.Resources["TestTaskRole"]
+	assert.Check(t, !ok)
+	_, ok = template.Resources["TestTaskExecutionRole"]
+	assert.Check(t, !ok)
+}
+
+func TestPreCreatedRolesErrors(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  test:
+    image: nginx
+    x-aws-task_role_arn: arn:aws:iam::012345678910:user/app
+`: `invalid x-aws-task_role_arn: "arn:aws:iam::012345678910:user/app" is not an IAM role ARN`,
+		`
+services:
+  test:
+    image: nginx
+    x-aws-execution_role_arn: execution
+`: `invalid x-aws-execution_role_arn: "execution" is not an IAM role ARN`,
+		`
+services:
+  test:
+    image: nginx
+    x-aws-task_role_arn: arn:aws:iam::012345678910:role/app
+    x-aws-policies:
+      - arn:aws:iam::aws:policy/AmazonS3ReadOnlyAccess
+`: "x-aws-policies can't be set with x-aws-task_role_arn, as policies can't be attached to a pre-created role",
+		`
+services:
+  test:
+    image: nginx
+    x-aws-execution_role_arn: arn:aws:iam::012345678910:role/execution
+    x-aws-execution_managed_policies:
+      - arn:aws:iam::012345678910:policy/ECRPull
+`: "x-aws-execution_managed_policies can't be set with x-aws-execution_role_arn, as policies can't be attached to a pre-created role",
+	} {
+		project := loadConfig(t, yaml)
+		_, err := (&ecsAPIService{}).convert(project, awsResources{})
+		assert.Error(t, err, expected)
+	}
+}
+
+func TestExecutionManagedPolicies(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: nginx
+    x-aws-execution_managed_policies:
+      - arn:aws:iam::012345678910:policy/ECRPull
+      - arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly
+      - arn:aws:iam::012345678910:policy/ECRPull
+`)
+	role := template.Resources["TestTaskExecutionRole"].(*iam.Role)
+	assert.DeepEqual(t, role.ManagedPolicyArns, []string{
+		ecsTaskExecutionPolicy,
+		ecrReadOnlyPolicy,
+		"arn:aws:iam::012345678910:policy/ECRPull",
+	})
+}
+
+func TestExecutionManagedPoliciesMalformed(t *testing.T) {
+	for value, expected := range map[string]string{
+		"arn:aws:iam::012345678910:policy/ECRPull": "service test: x-aws-execution_managed_policies must be a list of policy ARNs, got arn:aws:iam::012345678910:policy/ECRPull",
+		"[42]":            "service test: x-aws-execution_managed_policies must be a list of policy ARNs, got 42",
+		"[{arn: policy}]": "service test: x-aws-execution_managed_policies must be a list of policy ARNs, got map[arn:policy]",
+	} {
+		project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    x-aws-execution_managed_policies: `+value+`
+`)
+		_, err := (&ecsAPIService{}).convert(project, awsResources{})
+		assert.Error(t, err, expected, value)
+	}
+}
+
+func TestExecutionManagedPoliciesLimit(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    x-aws-execution_managed_policies:
+      - arn:aws:iam::012345678910:policy/P1
+      - arn:aws:iam::012345678910:policy/P2
+      - arn:aws:iam::012345678910:policy/P3
+      - arn:aws:iam::012345678910:policy/P4
+      - arn:aws:iam::012345678910:policy/P5
+      - arn:aws:iam::012345678910:policy/P6
+      - arn:aws:iam::012345678910:policy/P7
+      - arn:aws:iam::012345678910:policy/P8
+      - arn:aws:iam::012345678910:policy/P9
+`)
+	_, err := (&ecsAPIService{}).convert(project, awsResources{})
+	assert.Error(t, err, "task execution role can't have more than 10 managed policies, got 11")
+}
+
+func TestCheckExecutionRoles(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    x-aws-execution_role_arn: arn:aws:iam::012345678910:role/execution
+    secrets:
+      - password
+      - token
+secrets:
+  password:
+    external: true
+    name: arn:aws:secretsmanager:eu-west-3:012345678910:secret:password
+  token:
+    external: true
+    name: arn:aws:ssm:eu-west-3:012345678910:parameter/token
+`)
+	iamMock := &mockIAM{}
+	iamMock.On("SimulatePrincipalPolicyPagesWithContext", "arn:aws:iam::012345678910:role/execution").Return(&iamapi.SimulatePolicyResponse{
+		EvaluationResults: []*iamapi.EvaluationResult{
+			{EvalActionName: aws.String(actionGetSecretValue), EvalDecision: aws.String(iamapi.PolicyEvaluationDecisionTypeAllowed)},
+			{EvalActionName: aws.String(actionGetParameters), EvalDecision: aws.String(iamapi.PolicyEvaluationDecisionTypeImplicitDeny)},
+		},
+	}, nil)
+
+	backend := &ecsAPIService{SDK: sdk{IAM: iamMock}}
+	err := backend.checkExecutionRoles(context.TODO(), project, awsResources{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, backend.warnings, convertWarnings{
+		{
+			Code:     warningRolePermissions,
+			Severity: severityWarning,
+			Service:  "test",
+			Message:  "execution role arn:aws:iam::012345678910:role/execution isn't allowed ssm:GetParameters on arn:aws:ssm:eu-west-3:012345678910:parameter/token, tasks will fail to start",
+		},
+	})
+}
+
+func TestPermissionsBoundary(t *testing.T) {
+	project := load(t, "testdata/input/iam-permissions-boundary.yaml")
+	result := convertResultAsString(t, project)
+	golden.Assert(t, result, "iam/iam-permissions-boundary.golden")
+}
+
+func TestPermissionsBoundaryEC2InstanceRole(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    shm_size: 1gb
+x-aws-iam_permissions_boundary: arn:aws:iam::012345678910:policy/Boundary
+`)
+	ssmMock := &mockSSM{}
+	ssmMock.On("GetParameterWithContext", "/aws/service/ecs/optimized-ami/amazon-linux-2/recommended").Return(&ssm.GetParameterOutput{
+		Parameter: &ssm.Parameter{Value: aws.String(`{"image_id": "ami-123456"}`)},
+	}, nil)
+	backend := &ecsAPIService{SDK: sdk{SSM: ssmMock}}
+	template, err := backend.convert(project, awsResources{})
+	assert.NilError(t, err)
+	err = backend.createCapacityProvider(context.TODO(), project, template, awsResources{})
+	assert.NilError(t, err)
+	for _, name := range []string{"TestTaskExecutionRole", "EC2InstanceRole"} {
+		role := template.Resources[name].(*iam.Role)
+		assert.Equal(t, role.PermissionsBoundary, "arn:aws:iam::012345678910:policy/Boundary", name)
+	}
+}
+
+func TestRoleSettingsErrors(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		"x-aws-iam_permissions_boundary: Boundary":                                `x-aws-iam_permissions_boundary must be an IAM policy ARN, got "Boundary"`,
+		"x-aws-iam_permissions_boundary: arn:aws:iam::012345678910:role/Boundary": `x-aws-iam_permissions_boundary must be an IAM policy ARN, got "arn:aws:iam::012345678910:role/Boundary"`,
+		"x-aws-iam_path: compose":                                                 `x-aws-iam_path must start and end with /, got "compose"`,
+	} {
+		project := loadConfig(t, "services:\n  test:\n    image: nginx\n"+yaml)
+		_, err := (&ecsAPIService{}).convert(project, awsResources{})
+		assert.Error(t, err, expected)
+	}
+}
+
+type mockSSM struct {
+	ssmiface.SSMAPI
+	mock.Mock
+}
+
+func (m *mockSSM) GetParameterWithContext(_ aws.Context, in *ssm.GetParameterInput, _ ...request.Option) (*ssm.GetParameterOutput, error) {
+	args := m.Called(aws.StringValue(in.Name))
+	return args.Get(0).(*ssm.GetParameterOutput), args.Error(1)
+}
+
+type mockIAM struct {
+	iamiface.IAMAPI
+	mock.Mock
+}
+
+func (m *mockIAM) SimulatePrincipalPolicyPagesWithContext(_ aws.Context, in *iamapi.SimulatePrincipalPolicyInput, fn func(*iamapi.SimulatePolicyResponse, bool) bool, _ ...request.Option) error {
+	args := m.Called(aws.StringValue(in.PolicySourceArn))
+	if args.Error(1) == nil {
+		fn(args.Get(0).(*iamapi.SimulatePolicyResponse), true)
+	}
+	return args.Error(1)
+}
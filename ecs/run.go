@@ -0,0 +1,141 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/sirupsen/logrus"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+// runTaskPollInterval is the delay between checks of a one-off task state and logs
+var runTaskPollInterval = 2 * time.Second
+
+// Run starts a one-off task of service with its deployed task definition and network configuration, streams the
+// container logs until the task stops and returns the container exit code
+func (b *ecsAPIService) Run(ctx context.Context, project string, options compose.RunOptions) (int, error) {
+	cluster, err := b.SDK.GetStackClusterID(ctx, project)
+	if err != nil {
+		return 0, err
+	}
+	serviceARN, err := b.findService(ctx, project, cluster, options.Service)
+	if err != nil {
+		return 0, err
+	}
+	task, err := b.SDK.GetOneOffTask(ctx, cluster, serviceARN)
+	if err != nil {
+		return 0, err
+	}
+	task.Container = options.Service
+	task.Command = options.Command
+
+	logGroup, streamPrefix, err := b.SDK.GetContainerLogs(ctx, task.TaskDefinition, task.Container)
+	if err != nil {
+		return 0, err
+	}
+
+	taskArn, err := b.SDK.RunTask(ctx, cluster, task)
+	if err != nil {
+		return 0, err
+	}
+	logrus.Debugf("running task %s", taskArn)
+
+	tail := &logTail{group: logGroup, out: options.Stdout}
+	if logGroup != "" {
+		tail.stream = fmt.Sprintf("%s/%s/%s", streamPrefix, task.Container, resourceName(taskArn))
+	}
+	code, err := b.waitOneOffTask(ctx, cluster, taskArn, task.Container, tail)
+	if ctx.Err() != nil {
+		// the task would keep running after the command is interrupted
+		if err := b.SDK.StopTask(context.Background(), cluster, taskArn, "Interrupted by compose run"); err != nil {
+			return 0, err
+		}
+		return 0, ctx.Err()
+	}
+	return code, err
+}
+
+// waitOneOffTask waits for task to stop, streaming container logs meanwhile, and returns the container exit code
+func (b *ecsAPIService) waitOneOffTask(ctx context.Context, cluster string, taskArn string, container string, tail *logTail) (int, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(runTaskPollInterval):
+		}
+		task, err := b.SDK.DescribeTask(ctx, cluster, taskArn)
+		if err != nil {
+			return 0, err
+		}
+		// logs are read after task state so that all of them are read once the task is stopped
+		if err := tail.read(ctx, b.SDK); err != nil {
+			return 0, err
+		}
+		if aws.StringValue(task.LastStatus) != ecsapi.DesiredStatusStopped {
+			continue
+		}
+		for _, c := range task.Containers {
+			if aws.StringValue(c.Name) != container {
+				continue
+			}
+			if c.ExitCode == nil {
+				return 0, fmt.Errorf("task %s stopped before %s exited: %s", resourceName(taskArn), container, aws.StringValue(task.StoppedReason))
+			}
+			return int(aws.Int64Value(c.ExitCode)), nil
+		}
+		return 0, fmt.Errorf("task %s has no container %s", resourceName(taskArn), container)
+	}
+}
+
+// logTail reads a CloudWatch log stream as it is written
+type logTail struct {
+	group  string
+	stream string
+	token  *string
+	out    io.Writer
+}
+
+func (l *logTail) read(ctx context.Context, s sdk) error {
+	if l.stream == "" || l.out == nil {
+		return nil
+	}
+	for {
+		messages, token, err := s.GetLogEvents(ctx, l.group, l.stream, l.token)
+		if err != nil {
+			return err
+		}
+		for _, message := range messages {
+			_, _ = fmt.Fprintln(l.out, message)
+		}
+		// the forward token stays the same once the end of the stream is reached
+		if token == nil || (l.token != nil && *token == *l.token) {
+			l.token = token
+			return nil
+		}
+		l.token = token
+		if len(messages) == 0 {
+			return nil
+		}
+	}
+}
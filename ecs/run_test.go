@@ -0,0 +1,192 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+const migrateTask = "arn:aws:ecs:eu-west-1:123456789012:task/cluster/0123456789abcdef"
+
+func runTaskBackend(t *testing.T) (*ecsAPIService, *mockECS, *mockCloudWatchLogs) {
+	interval := runTaskPollInterval
+	runTaskPollInterval = 0
+	t.Cleanup(func() {
+		runTaskPollInterval = interval
+	})
+
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "test").Return(&cloudformation.ListStackResourcesOutput{
+		StackResourceSummaries: []*cloudformation.StackResourceSummary{
+			{LogicalResourceId: aws.String("Cluster"), ResourceType: aws.String("AWS::ECS::Cluster"), PhysicalResourceId: aws.String("cluster")},
+			{LogicalResourceId: aws.String("MigrateService"), ResourceType: aws.String("AWS::ECS::Service"), PhysicalResourceId: aws.String("arn:migrate")},
+		},
+	}, nil)
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeServicesWithContext", "arn:migrate").Return(&ecsapi.DescribeServicesOutput{
+		Services: []*ecsapi.Service{
+			{
+				ServiceArn:      aws.String("arn:migrate"),
+				ServiceName:     aws.String("test-MigrateService"),
+				TaskDefinition:  aws.String("arn:migrate-task:3"),
+				LaunchType:      aws.String(ecsapi.LaunchTypeFargate),
+				PlatformVersion: aws.String(fargatePlatformVersion),
+				NetworkConfiguration: &ecsapi.NetworkConfiguration{
+					AwsvpcConfiguration: &ecsapi.AwsVpcConfiguration{
+						Subnets:        aws.StringSlice([]string{"subnet-1"}),
+						SecurityGroups: aws.StringSlice([]string{"sg-1"}),
+					},
+				},
+				Tags: []*ecsapi.Tag{{Key: aws.String(compose.ServiceTag), Value: aws.String("migrate")}},
+			},
+		},
+	}, nil)
+	ecsMock.On("DescribeTaskDefinitionWithContext", "arn:migrate-task:3").Return(&ecsapi.DescribeTaskDefinitionOutput{
+		TaskDefinition: &ecsapi.TaskDefinition{
+			ContainerDefinitions: []*ecsapi.ContainerDefinition{
+				{
+					Name: aws.String("migrate"),
+					LogConfiguration: &ecsapi.LogConfiguration{
+						LogDriver: aws.String(ecsapi.LogDriverAwslogs),
+						Options: aws.StringMap(map[string]string{
+							"awslogs-group":         "/docker-compose/test",
+							"awslogs-stream-prefix": "test",
+						}),
+					},
+				},
+			},
+		},
+	}, nil)
+	ecsMock.On("RunTaskWithContext", "arn:migrate-task:3", mock.Anything).Return(&ecsapi.RunTaskOutput{
+		Tasks: []*ecsapi.Task{{TaskArn: aws.String(migrateTask)}},
+	}, nil)
+
+	logs := &mockCloudWatchLogs{}
+	backend := &ecsAPIService{SDK: sdk{CF: cf, ECS: ecsMock, CW: logs}}
+	return backend, ecsMock, logs
+}
+
+func TestRunOneOffTask(t *testing.T) {
+	backend, ecsMock, logs := runTaskBackend(t)
+	ecsMock.On("DescribeTasksWithContext", migrateTask).Return(&ecsapi.DescribeTasksOutput{
+		Tasks: []*ecsapi.Task{{LastStatus: aws.String("PROVISIONING")}},
+	}, nil).Once()
+	ecsMock.On("DescribeTasksWithContext", migrateTask).Return(&ecsapi.DescribeTasksOutput{
+		Tasks: []*ecsapi.Task{
+			{
+				LastStatus: aws.String(ecsapi.DesiredStatusStopped),
+				Containers: []*ecsapi.Container{{Name: aws.String("migrate"), ExitCode: aws.Int64(3)}},
+			},
+		},
+	}, nil)
+	stream := "test/migrate/0123456789abcdef"
+	logs.On("GetLogEventsWithContext", stream, "").Return(&cloudwatchlogs.GetLogEventsOutput{},
+		awserr.New(cloudwatchlogs.ErrCodeResourceNotFoundException, "The specified log stream does not exist.", nil)).Once()
+	logs.On("GetLogEventsWithContext", stream, "").Return(&cloudwatchlogs.GetLogEventsOutput{
+		Events:           []*cloudwatchlogs.OutputLogEvent{{Message: aws.String("applying migration 42")}},
+		NextForwardToken: aws.String("f/1"),
+	}, nil)
+	logs.On("GetLogEventsWithContext", stream, "f/1").Return(&cloudwatchlogs.GetLogEventsOutput{
+		NextForwardToken: aws.String("f/1"),
+	}, nil)
+
+	out := &bytes.Buffer{}
+	code, err := backend.Run(context.TODO(), "test", compose.RunOptions{
+		Service: "migrate",
+		Command: []string{"migrate", "up"},
+		Stdout:  out,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, code, 3)
+	assert.Equal(t, out.String(), "applying migration 42\n")
+
+	var input *ecsapi.RunTaskInput
+	for _, call := range ecsMock.Calls {
+		if call.Method == "RunTaskWithContext" {
+			input = call.Arguments.Get(1).(*ecsapi.RunTaskInput)
+		}
+	}
+	assert.DeepEqual(t, aws.StringValueSlice(input.Overrides.ContainerOverrides[0].Command), []string{"migrate", "up"})
+	assert.Equal(t, aws.StringValue(input.LaunchType), ecsapi.LaunchTypeFargate)
+	assert.DeepEqual(t, aws.StringValueSlice(input.NetworkConfiguration.AwsvpcConfiguration.Subnets), []string{"subnet-1"})
+	ecsMock.AssertNotCalled(t, "StopTaskWithContext", migrateTask)
+}
+
+func TestRunOneOffTaskInterrupted(t *testing.T) {
+	backend, ecsMock, _ := runTaskBackend(t)
+	ctx, cancel := context.WithCancel(context.TODO())
+	ecsMock.On("DescribeTasksWithContext", migrateTask).Return(&ecsapi.DescribeTasksOutput{
+		Tasks: []*ecsapi.Task{{LastStatus: aws.String("RUNNING")}},
+	}, nil).Run(func(mock.Arguments) {
+		cancel()
+	})
+	ecsMock.On("StopTaskWithContext", migrateTask).Return(&ecsapi.StopTaskOutput{}, nil)
+
+	_, err := backend.Run(ctx, "test", compose.RunOptions{Service: "migrate"})
+	assert.Equal(t, err, context.Canceled)
+	ecsMock.AssertCalled(t, "StopTaskWithContext", migrateTask)
+}
+
+func TestRunOneOffTaskUnknownService(t *testing.T) {
+	backend, _, _ := runTaskBackend(t)
+	_, err := backend.Run(context.TODO(), "test", compose.RunOptions{Service: "web"})
+	assert.Error(t, err, "no service web in project test")
+}
+
+func (m *mockECS) DescribeTaskDefinitionWithContext(_ aws.Context, in *ecsapi.DescribeTaskDefinitionInput, _ ...request.Option) (*ecsapi.DescribeTaskDefinitionOutput, error) {
+	args := m.Called(aws.StringValue(in.TaskDefinition))
+	return args.Get(0).(*ecsapi.DescribeTaskDefinitionOutput), args.Error(1)
+}
+
+func (m *mockECS) RunTaskWithContext(_ aws.Context, in *ecsapi.RunTaskInput, _ ...request.Option) (*ecsapi.RunTaskOutput, error) {
+	args := m.Called(aws.StringValue(in.TaskDefinition), in)
+	return args.Get(0).(*ecsapi.RunTaskOutput), args.Error(1)
+}
+
+func (m *mockECS) DescribeTasksWithContext(_ aws.Context, in *ecsapi.DescribeTasksInput, _ ...request.Option) (*ecsapi.DescribeTasksOutput, error) {
+	args := m.Called(aws.StringValue(in.Tasks[0]))
+	return args.Get(0).(*ecsapi.DescribeTasksOutput), args.Error(1)
+}
+
+func (m *mockECS) StopTaskWithContext(_ aws.Context, in *ecsapi.StopTaskInput, _ ...request.Option) (*ecsapi.StopTaskOutput, error) {
+	args := m.Called(aws.StringValue(in.Task))
+	return args.Get(0).(*ecsapi.StopTaskOutput), args.Error(1)
+}
+
+type mockCloudWatchLogs struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+	mock.Mock
+}
+
+func (m *mockCloudWatchLogs) GetLogEventsWithContext(_ aws.Context, in *cloudwatchlogs.GetLogEventsInput, _ ...request.Option) (*cloudwatchlogs.GetLogEventsOutput, error) {
+	args := m.Called(aws.StringValue(in.LogStreamName), aws.StringValue(in.NextToken))
+	return args.Get(0).(*cloudwatchlogs.GetLogEventsOutput), args.Error(1)
+}
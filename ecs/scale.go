@@ -0,0 +1,162 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
+	"github.com/docker/compose-cli/progress"
+)
+
+// Scale updates the desired count of the project's ECS services directly, which is much faster than redeploying the
+// stack. The stack keeps the replicas it was deployed with, which the next deployment restores
+func (b *ecsAPIService) Scale(ctx context.Context, projectName string, options compose.ScaleOptions) error {
+	cluster, services, err := b.projectServices(ctx, projectName)
+	if err != nil {
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+	autoscaled, err := b.autoscaledServices(ctx, projectName)
+	if err != nil {
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+
+	var names []string
+	for name := range options.Replicas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := services[name]; !ok {
+			return &errdefs.Error{
+				Kind:     errdefs.ErrValidation,
+				Resource: name,
+				Err:      fmt.Errorf("no service %s in project %s", name, projectName),
+			}
+		}
+		if autoscaled[normalizeResourceName(name)] && !options.Force {
+			return &errdefs.Error{
+				Kind:     errdefs.ErrValidation,
+				Resource: name,
+				Err: fmt.Errorf("service %s replicas are managed by an autoscaling policy, which would override them, "+
+					"use --force to scale it anyway", name),
+			}
+		}
+	}
+
+	selected := map[string]*ecsapi.Service{}
+	for _, name := range names {
+		selected[name] = services[name]
+	}
+	err = b.updateServices(ctx, cluster, selected, "Scaling", "Scaled", func(ctx context.Context, name string, svc *ecsapi.Service) (int, error) {
+		return options.Replicas[name], nil
+	})
+	return classify(err, errdefs.ErrDeploymentFailed)
+}
+
+// projectServices returns the cluster and ECS services of project, by compose service name
+func (b *ecsAPIService) projectServices(ctx context.Context, projectName string) (string, map[string]*ecsapi.Service, error) {
+	cluster, err := b.SDK.GetStackClusterID(ctx, projectName)
+	if err != nil {
+		return "", nil, err
+	}
+	arns, err := b.SDK.ListStackServices(ctx, projectName)
+	if err != nil {
+		return "", nil, err
+	}
+	services, err := b.SDK.GetServices(ctx, cluster, arns)
+	if err != nil {
+		return "", nil, err
+	}
+	return cluster, services, nil
+}
+
+// updateServices sets services desired count to the one returned by prepare, and waits for them to reach a steady state
+func (b *ecsAPIService) updateServices(ctx context.Context, cluster string, services map[string]*ecsapi.Service, working string, done string,
+	prepare func(ctx context.Context, name string, svc *ecsapi.Service) (int, error)) error {
+	var names []string
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := progress.ContextWriter(ctx)
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, name := range names {
+		name, svc := name, services[name]
+		eg.Go(func() error {
+			w.Event(progress.Event{
+				ID:         name,
+				Status:     progress.Working,
+				StatusText: working,
+			})
+			desired, err := prepare(ctx, name, svc)
+			if err != nil {
+				return err
+			}
+			arn := aws.StringValue(svc.ServiceArn)
+			if err := b.SDK.UpdateServiceDesiredCount(ctx, cluster, arn, desired); err != nil {
+				return err
+			}
+			if err := b.SDK.WaitServiceStable(ctx, cluster, arn); err != nil {
+				return classify(err, errdefs.ErrDeploymentFailed)
+			}
+			w.Event(progress.Event{
+				ID:         name,
+				Status:     progress.Done,
+				StatusText: done,
+			})
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// autoscaledServices returns the normalized name of the services which get a ScalableTarget, in stack and nested stacks
+func (b *ecsAPIService) autoscaledServices(ctx context.Context, stack string) (map[string]bool, error) {
+	resources, err := b.SDK.ListStackResources(ctx, stack)
+	if err != nil {
+		return nil, err
+	}
+	autoscaled := map[string]bool{}
+	for _, r := range resources {
+		switch r.Type {
+		case "AWS::ApplicationAutoScaling::ScalableTarget":
+			autoscaled[strings.TrimSuffix(r.LogicalID, "ScalableTarget")] = true
+		case awsTypeStack:
+			if r.ARN == "" {
+				continue
+			}
+			nested, err := b.autoscaledServices(ctx, r.ARN)
+			if err != nil {
+				return nil, err
+			}
+			for name := range nested {
+				autoscaled[name] = true
+			}
+		}
+	}
+	return autoscaled, nil
+}
@@ -0,0 +1,117 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
+)
+
+func (m *mockECS) WaitUntilServicesStableWithContext(_ aws.Context, in *ecsapi.DescribeServicesInput, _ ...request.WaiterOption) error {
+	args := m.Called(aws.StringValue(in.Services[0]))
+	return args.Error(0)
+}
+
+func scaleBackend(autoscaled bool) (*ecsAPIService, *mockECS) {
+	resources := stackServices()
+	if autoscaled {
+		resources.StackResourceSummaries = append(resources.StackResourceSummaries, &cloudformation.StackResourceSummary{
+			LogicalResourceId: aws.String("WebScalableTarget"), ResourceType: aws.String("AWS::ApplicationAutoScaling::ScalableTarget"),
+		})
+	}
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "test").Return(resources, nil)
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeServicesWithContext", "arn:web").Return(servicesEvents(
+		&ecsapi.Service{ServiceArn: aws.String("arn:web"), Tags: []*ecsapi.Tag{{Key: aws.String(compose.ServiceTag), Value: aws.String("web")}}},
+		&ecsapi.Service{ServiceArn: aws.String("arn:db"), Tags: []*ecsapi.Tag{{Key: aws.String(compose.ServiceTag), Value: aws.String("db")}}},
+	), nil)
+	ecsMock.On("UpdateServiceWithContext", "arn:web").Return(&ecsapi.UpdateServiceOutput{}, nil)
+	ecsMock.On("WaitUntilServicesStableWithContext", "arn:web").Return(nil)
+	return &ecsAPIService{SDK: sdk{CF: cf, ECS: ecsMock}}, ecsMock
+}
+
+func TestScale(t *testing.T) {
+	backend, ecsMock := scaleBackend(false)
+	recorder := newRecordingECS(ecsMock)
+	backend.SDK.ECS = recorder
+	err := backend.Scale(context.TODO(), "test", compose.ScaleOptions{Replicas: map[string]int{"web": 3}})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, recorder.desired, map[string]int64{"arn:web": 3})
+	ecsMock.AssertCalled(t, "WaitUntilServicesStableWithContext", "arn:web")
+}
+
+// recordingECS records the desired count services get updated with
+type recordingECS struct {
+	*mockECS
+	lock    sync.Mutex
+	desired map[string]int64
+}
+
+func newRecordingECS(m *mockECS) *recordingECS {
+	return &recordingECS{mockECS: m, desired: map[string]int64{}}
+}
+
+func (m *recordingECS) UpdateServiceWithContext(_ aws.Context, in *ecsapi.UpdateServiceInput, _ ...request.Option) (*ecsapi.UpdateServiceOutput, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.desired[aws.StringValue(in.Service)] = aws.Int64Value(in.DesiredCount)
+	return &ecsapi.UpdateServiceOutput{}, nil
+}
+
+func TestScaleAutoscaledService(t *testing.T) {
+	backend, ecsMock := scaleBackend(true)
+	err := backend.Scale(context.TODO(), "test", compose.ScaleOptions{Replicas: map[string]int{"web": 3}})
+	assert.Error(t, err, "service web replicas are managed by an autoscaling policy, which would override them, use --force to scale it anyway")
+	assert.Assert(t, errors.Is(err, errdefs.ErrValidation))
+	ecsMock.AssertNotCalled(t, "UpdateServiceWithContext", "arn:web")
+
+	err = backend.Scale(context.TODO(), "test", compose.ScaleOptions{Replicas: map[string]int{"web": 3}, Force: true})
+	assert.NilError(t, err)
+	ecsMock.AssertCalled(t, "UpdateServiceWithContext", "arn:web")
+}
+
+func TestScaleFailure(t *testing.T) {
+	backend, ecsMock := scaleBackend(false)
+	ecsMock.ExpectedCalls = nil
+	ecsMock.On("DescribeServicesWithContext", "arn:web").Return(servicesEvents(
+		&ecsapi.Service{ServiceArn: aws.String("arn:web"), Tags: []*ecsapi.Tag{{Key: aws.String(compose.ServiceTag), Value: aws.String("web")}}},
+	), nil)
+	ecsMock.On("UpdateServiceWithContext", "arn:web").Return(&ecsapi.UpdateServiceOutput{}, errors.New("service is draining"))
+	err := backend.Scale(context.TODO(), "test", compose.ScaleOptions{Replicas: map[string]int{"web": 3}})
+	assert.ErrorContains(t, err, "service is draining")
+	assert.Equal(t, errdefs.ToDetails(err).Code, "deployment-failed")
+}
+
+func TestScaleUnknownService(t *testing.T) {
+	backend, _ := scaleBackend(false)
+	err := backend.Scale(context.TODO(), "test", compose.ScaleOptions{Replicas: map[string]int{"worker": 3}})
+	assert.Error(t, err, "no service worker in project test")
+	assert.Assert(t, errors.Is(err, errdefs.ErrValidation))
+	assert.Equal(t, errdefs.ToDetails(err).Code, "validation-error")
+}
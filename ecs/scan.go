@@ -0,0 +1,214 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/compose-spec/compose-go/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/docker/compose-cli/progress"
+)
+
+// findingSeverities are ECR scan findings severities, by decreasing order
+var findingSeverities = []string{
+	ecr.FindingSeverityCritical,
+	ecr.FindingSeverityHigh,
+	ecr.FindingSeverityMedium,
+	ecr.FindingSeverityLow,
+	ecr.FindingSeverityInformational,
+	ecr.FindingSeverityUndefined,
+}
+
+func severityRank(severity string) int {
+	for i, s := range findingSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(findingSeverities)
+}
+
+// imageScanThreshold returns the lowest severity of findings which block deployment of service, set by x-aws-image-scan
+func imageScanThreshold(service types.ServiceConfig) (string, bool, error) {
+	x, ok := service.Extensions[extensionImageScan]
+	if !ok {
+		return "", false, nil
+	}
+	config, ok := x.(map[string]interface{})
+	if !ok {
+		return "", false, fmt.Errorf("service %s: %s must be a mapping", service.Name, extensionImageScan)
+	}
+	blockOn := strings.ToUpper(fmt.Sprint(config["block_on"]))
+	if severityRank(blockOn) >= severityRank(ecr.FindingSeverityUndefined) {
+		return "", false, fmt.Errorf("service %s: %s block_on must be one of critical, high, medium, low or informational", service.Name, extensionImageScan)
+	}
+	return blockOn, true, nil
+}
+
+// ecrImage is an image hosted on Amazon ECR
+type ecrImage struct {
+	ref        string
+	registry   string
+	region     string
+	repository string
+	tag        string
+	digest     string
+}
+
+var ecrImageRegexp = regexp.MustCompile(`^(\d{12})\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?/([^:@]+)(?::([^@]+))?(?:@(sha256:[0-9a-f]{64}))?$`)
+
+func parseECRImage(image string) (ecrImage, bool) {
+	match := ecrImageRegexp.FindStringSubmatch(image)
+	if match == nil {
+		return ecrImage{}, false
+	}
+	parsed := ecrImage{
+		ref:        image,
+		registry:   match[1],
+		region:     match[2],
+		repository: match[3],
+		tag:        match[4],
+		digest:     match[5],
+	}
+	if parsed.tag == "" && parsed.digest == "" {
+		parsed.tag = "latest"
+	}
+	return parsed, true
+}
+
+func (i ecrImage) identifier() *ecr.ImageIdentifier {
+	if i.digest != "" {
+		return &ecr.ImageIdentifier{ImageDigest: aws.String(i.digest)}
+	}
+	return &ecr.ImageIdentifier{ImageTag: aws.String(i.tag)}
+}
+
+type imageScanFinding struct {
+	name     string
+	severity string
+	uri      string
+}
+
+type imageScanResult struct {
+	digest   string
+	findings []imageScanFinding
+}
+
+// summary counts findings by severity, like "CRITICAL 1, HIGH 3"
+func (r imageScanResult) summary() string {
+	counts := map[string]int{}
+	for _, f := range r.findings {
+		counts[f.severity]++
+	}
+	var summary []string
+	for _, severity := range findingSeverities {
+		if counts[severity] > 0 {
+			summary = append(summary, fmt.Sprintf("%s %d", severity, counts[severity]))
+		}
+	}
+	if len(summary) == 0 {
+		return "no findings"
+	}
+	return strings.Join(summary, ", ")
+}
+
+// blocking returns findings of severity threshold or higher
+func (r imageScanResult) blocking(threshold string) []imageScanFinding {
+	var blocking []imageScanFinding
+	for _, f := range r.findings {
+		if severityRank(f.severity) <= severityRank(threshold) {
+			blocking = append(blocking, f)
+		}
+	}
+	sort.SliceStable(blocking, func(i, j int) bool {
+		return severityRank(blocking[i].severity) < severityRank(blocking[j].severity)
+	})
+	return blocking
+}
+
+// checkImageScans aborts deployment when ECR scan of a service image opting in x-aws-image-scan reports findings
+// with a blocking severity. Scan is started if image has not been scanned yet
+func (b *ecsAPIService) checkImageScans(ctx context.Context, project *types.Project) error {
+	w := progress.ContextWriter(ctx)
+	var blocked []string
+	for _, service := range project.Services {
+		threshold, ok, err := imageScanThreshold(service)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		image, ok := parseECRImage(service.Image)
+		if !ok {
+			logrus.Warnf("service %s: skipping image scan as %s isn't hosted on Amazon ECR", service.Name, service.Image)
+			continue
+		}
+		if image.region != b.Region {
+			logrus.Warnf("service %s: skipping image scan as %s is hosted in region %s", service.Name, service.Image, image.region)
+			continue
+		}
+
+		id := fmt.Sprintf("%s image scan", service.Name)
+		w.Event(progress.Event{
+			ID:         id,
+			Status:     progress.Working,
+			StatusText: "SCAN_IN_PROGRESS",
+		})
+		result, err := b.SDK.GetImageScanFindings(ctx, image)
+		if err != nil {
+			w.Event(progress.Event{
+				ID:         id,
+				Status:     progress.Error,
+				StatusText: "SCAN_FAILED",
+			})
+			return err
+		}
+
+		blocking := result.blocking(threshold)
+		if len(blocking) == 0 {
+			w.Event(progress.Event{
+				ID:         id,
+				Status:     progress.Done,
+				StatusText: result.summary(),
+			})
+			continue
+		}
+		w.Event(progress.Event{
+			ID:         id,
+			Status:     progress.Error,
+			StatusText: result.summary(),
+		})
+		lines := []string{fmt.Sprintf("  %s (%s@%s):", service.Name, image.ref, result.digest)}
+		for _, f := range blocking {
+			lines = append(lines, fmt.Sprintf("    - %s %s %s", f.severity, f.name, f.uri))
+		}
+		blocked = append(blocked, strings.Join(lines, "\n"))
+	}
+	if len(blocked) > 0 {
+		return fmt.Errorf("deployment blocked by image scan findings:\n%s\nuse --skip-scan to deploy anyway", strings.Join(blocked, "\n"))
+	}
+	return nil
+}
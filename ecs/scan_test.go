@@ -0,0 +1,157 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+)
+
+const scannedImage = "123456789012.dkr.ecr.eu-west-3.amazonaws.com/web:1.0"
+
+func scanFindings(findings ...*ecr.ImageScanFinding) *ecr.DescribeImageScanFindingsOutput {
+	return &ecr.DescribeImageScanFindingsOutput{
+		ImageId:           &ecr.ImageIdentifier{ImageDigest: aws.String("sha256:1234")},
+		ImageScanStatus:   &ecr.ImageScanStatus{Status: aws.String(ecr.ScanStatusComplete)},
+		ImageScanFindings: &ecr.ImageScanFindings{Findings: findings},
+	}
+}
+
+func TestImageScanBlocksDeployment(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  web:
+    image: `+scannedImage+`
+    x-aws-image-scan:
+      block_on: high
+  front:
+    image: nginx
+    x-aws-image-scan:
+      block_on: critical
+`)
+	m := &mockECR{}
+	findings := scanFindings(
+		&ecr.ImageScanFinding{Name: aws.String("CVE-2020-0002"), Severity: aws.String(ecr.FindingSeverityLow), Uri: aws.String("https://cve/2")},
+		&ecr.ImageScanFinding{Name: aws.String("CVE-2020-0003"), Severity: aws.String(ecr.FindingSeverityHigh), Uri: aws.String("https://cve/3")},
+		&ecr.ImageScanFinding{Name: aws.String("CVE-2020-0001"), Severity: aws.String(ecr.FindingSeverityCritical), Uri: aws.String("https://cve/1")},
+	)
+	m.On("DescribeImageScanFindingsWithContext", "web:1.0").Return(findings, nil)
+	m.On("WaitUntilImageScanCompleteWithContext", "web:1.0").Return(nil)
+	m.On("DescribeImageScanFindingsPagesWithContext", "web:1.0").Return(findings, nil)
+
+	backend := &ecsAPIService{Region: "eu-west-3", SDK: sdk{ECR: m}}
+	err := backend.checkImageScans(context.TODO(), project)
+	assert.Error(t, err, `deployment blocked by image scan findings:
+  web (`+scannedImage+`@sha256:1234):
+    - CRITICAL CVE-2020-0001 https://cve/1
+    - HIGH CVE-2020-0003 https://cve/3
+use --skip-scan to deploy anyway`)
+	m.AssertNotCalled(t, "StartImageScanWithContext", mock.Anything)
+}
+
+func TestImageScanStartedWhenMissing(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  web:
+    image: `+scannedImage+`
+    x-aws-image-scan:
+      block_on: critical
+`)
+	m := &mockECR{}
+	findings := scanFindings(
+		&ecr.ImageScanFinding{Name: aws.String("CVE-2020-0003"), Severity: aws.String(ecr.FindingSeverityHigh)},
+	)
+	m.On("DescribeImageScanFindingsWithContext", "web:1.0").Return((*ecr.DescribeImageScanFindingsOutput)(nil), awserr.New(ecr.ErrCodeScanNotFoundException, "no scan", nil))
+	m.On("StartImageScanWithContext", "web:1.0").Return(&ecr.StartImageScanOutput{}, nil)
+	m.On("WaitUntilImageScanCompleteWithContext", "web:1.0").Return(nil)
+	m.On("DescribeImageScanFindingsPagesWithContext", "web:1.0").Return(findings, nil)
+
+	backend := &ecsAPIService{Region: "eu-west-3", SDK: sdk{ECR: m}}
+	assert.NilError(t, backend.checkImageScans(context.TODO(), project))
+	m.AssertCalled(t, "StartImageScanWithContext", "web:1.0")
+}
+
+func TestImageScanThreshold(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  web:
+    image: nginx
+    x-aws-image-scan:
+      block_on: urgent
+`)
+	_, _, err := imageScanThreshold(project.Services[0])
+	assert.Error(t, err, "service web: x-aws-image-scan block_on must be one of critical, high, medium, low or informational")
+}
+
+func TestParseECRImage(t *testing.T) {
+	image, ok := parseECRImage("123456789012.dkr.ecr.eu-west-3.amazonaws.com/team/web")
+	assert.Check(t, ok)
+	assert.Equal(t, image.registry, "123456789012")
+	assert.Equal(t, image.region, "eu-west-3")
+	assert.Equal(t, image.repository, "team/web")
+	assert.Equal(t, image.tag, "latest")
+
+	digest := "sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	image, ok = parseECRImage("123456789012.dkr.ecr.eu-west-3.amazonaws.com/web@" + digest)
+	assert.Check(t, ok)
+	assert.Equal(t, image.digest, digest)
+	assert.Equal(t, image.tag, "")
+
+	_, ok = parseECRImage("docker.io/library/nginx:latest")
+	assert.Check(t, !ok)
+}
+
+type mockECR struct {
+	ecriface.ECRAPI
+	mock.Mock
+}
+
+func imageKey(repository *string, id *ecr.ImageIdentifier) string {
+	if id.ImageDigest != nil {
+		return aws.StringValue(repository) + "@" + aws.StringValue(id.ImageDigest)
+	}
+	return aws.StringValue(repository) + ":" + aws.StringValue(id.ImageTag)
+}
+
+func (m *mockECR) DescribeImageScanFindingsWithContext(_ aws.Context, in *ecr.DescribeImageScanFindingsInput, _ ...request.Option) (*ecr.DescribeImageScanFindingsOutput, error) {
+	args := m.Called(imageKey(in.RepositoryName, in.ImageId))
+	return args.Get(0).(*ecr.DescribeImageScanFindingsOutput), args.Error(1)
+}
+
+func (m *mockECR) StartImageScanWithContext(_ aws.Context, in *ecr.StartImageScanInput, _ ...request.Option) (*ecr.StartImageScanOutput, error) {
+	args := m.Called(imageKey(in.RepositoryName, in.ImageId))
+	return args.Get(0).(*ecr.StartImageScanOutput), args.Error(1)
+}
+
+func (m *mockECR) WaitUntilImageScanCompleteWithContext(_ aws.Context, in *ecr.DescribeImageScanFindingsInput, _ ...request.WaiterOption) error {
+	args := m.Called(imageKey(in.RepositoryName, in.ImageId))
+	return args.Error(0)
+}
+
+func (m *mockECR) DescribeImageScanFindingsPagesWithContext(_ aws.Context, in *ecr.DescribeImageScanFindingsInput, fn func(*ecr.DescribeImageScanFindingsOutput, bool) bool, _ ...request.Option) error {
+	args := m.Called(imageKey(in.RepositoryName, in.ImageId))
+	fn(args.Get(0).(*ecr.DescribeImageScanFindingsOutput), true)
+	return args.Error(1)
+}
@@ -0,0 +1,151 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/events"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/compose-spec/compose-go/types"
+)
+
+const ecsEventsRolePolicy = "arn:aws:iam::aws:policy/service-role/AmazonEC2ContainerServiceEventsRole"
+
+var eventsAssumeRolePolicyDocument = policyDocument("events.amazonaws.com")
+
+// serviceSchedule returns the EventBridge schedule expression set by x-aws-schedule, to run service's
+// task on schedule rather than as a long-running ECS service
+func serviceSchedule(service types.ServiceConfig) (string, bool, error) {
+	x, ok := service.Extensions[extensionSchedule]
+	if !ok {
+		return "", false, nil
+	}
+	expression, ok := x.(string)
+	if !ok || !strings.HasSuffix(expression, ")") ||
+		!(strings.HasPrefix(expression, "cron(") || strings.HasPrefix(expression, "rate(")) {
+		return "", false, fmt.Errorf("%s must be a cron(...) or rate(...) expression, got %v", extensionSchedule, x)
+	}
+	return expression, true, nil
+}
+
+func isScheduled(service types.ServiceConfig) bool {
+	_, ok, _ := serviceSchedule(service)
+	return ok
+}
+
+// checkScheduledTask rejects settings which only apply to long-running services
+func checkScheduledTask(service types.ServiceConfig, members []types.ServiceConfig) error {
+	for _, member := range members {
+		if len(member.Ports) > 0 {
+			return fmt.Errorf("%s can't be set on a service exposing ports, as scheduled tasks aren't registered with the load balancer", extensionSchedule)
+		}
+	}
+	for _, extension := range []string{extensionDeploymentController, extensionMaxTaskLifetime,
+		extensionFallbackCapacity, extensionMinPercent, extensionMaxPercent, extensionPlacementStrategy, extensionAlarms} {
+		if _, ok := service.Extensions[extension]; ok {
+			return fmt.Errorf("%s can't be set with %s", extension, extensionSchedule)
+		}
+	}
+	if hasAutoscaling(service) {
+		return fmt.Errorf("deploy.%s can't be set with %s", extensionAutoScaling, extensionSchedule)
+	}
+	if service.Deploy != nil && len(service.Deploy.Placement.Constraints) > 0 {
+		return fmt.Errorf("deploy.placement.constraints can't be set with %s", extensionSchedule)
+	}
+	if service.Deploy != nil && service.Deploy.UpdateConfig != nil {
+		return fmt.Errorf("deploy.update_config can't be set with %s", extensionSchedule)
+	}
+	return nil
+}
+
+// scheduleResourceName is the EventBridge rule running service's task
+func scheduleResourceName(service string) string {
+	return fmt.Sprintf("%sSchedule", normalizeResourceName(service))
+}
+
+// clusterArn is the ARN of the cluster tasks run on, as EventBridge targets don't accept a cluster name
+func clusterArn(template *cloudformation.Template, resources awsResources) string {
+	if _, ok := template.Resources["Cluster"]; ok {
+		return cloudformation.GetAtt("Cluster", "Arn")
+	}
+	if arn.IsARN(resources.cluster) {
+		return resources.cluster
+	}
+	return cloudformation.Sub("arn:${AWS::Partition}:ecs:${AWS::Region}:${AWS::AccountId}:cluster/" + resources.cluster)
+}
+
+// scheduledTask configures how EventBridge runs the task
+type scheduledTask struct {
+	schedule        string
+	taskDefinition  string
+	taskCount       int
+	launchType      string
+	platformVersion string
+	assignPublicIP  string
+	securityGroups  []string
+	subnets         []string
+	dependsOn       []string
+}
+
+// createScheduledTask creates the EventBridge rule running service's task on schedule, and the role it runs tasks with
+func (b *ecsAPIService) createScheduledTask(project *types.Project, service types.ServiceConfig, template *cloudformation.Template,
+	resources awsResources, task scheduledTask) {
+	// replicas: 0 keeps the rule, but stops running tasks
+	state := "ENABLED"
+	if task.taskCount == 0 {
+		state = "DISABLED"
+	}
+
+	rule := scheduleResourceName(service.Name)
+	role := fmt.Sprintf("%sRole", rule)
+	template.Resources[role] = &iam.Role{
+		AssumeRolePolicyDocument: eventsAssumeRolePolicyDocument,
+		ManagedPolicyArns:        []string{b.partitionArn(ecsEventsRolePolicy)},
+		Tags:                     serviceTags(project, service),
+	}
+
+	template.Resources[rule] = &events.Rule{
+		AWSCloudFormationDependsOn: task.dependsOn,
+		Description:                fmt.Sprintf("Run %s tasks on schedule", service.Name),
+		ScheduleExpression:         task.schedule,
+		State:                      state,
+		Targets: []events.Rule_Target{
+			{
+				Arn: clusterArn(template, resources),
+				EcsParameters: &events.Rule_EcsParameters{
+					LaunchType: task.launchType,
+					NetworkConfiguration: &events.Rule_NetworkConfiguration{
+						AwsVpcConfiguration: &events.Rule_AwsVpcConfiguration{
+							AssignPublicIp: task.assignPublicIP,
+							SecurityGroups: task.securityGroups,
+							Subnets:        task.subnets,
+						},
+					},
+					PlatformVersion:   task.platformVersion,
+					TaskCount:         task.taskCount,
+					TaskDefinitionArn: cloudformation.Ref(task.taskDefinition),
+				},
+				Id:      "RunTask",
+				RoleArn: cloudformation.GetAtt(role, "Arn"),
+			},
+		},
+	}
+}
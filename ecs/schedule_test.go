@@ -0,0 +1,120 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/events"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"gotest.tools/v3/assert"
+)
+
+func TestScheduledService(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  backup:
+    image: hello_world
+    x-aws-schedule: cron(0 3 * * ? *)
+    deploy:
+      replicas: 2
+`)
+	_, ok := template.Resources["BackupService"]
+	assert.Check(t, !ok)
+	_, ok = template.Outputs["BackupServiceArn"]
+	assert.Check(t, !ok)
+	_, ok = template.Resources["BackupServiceDiscoveryEntry"]
+	assert.Check(t, !ok)
+
+	rule := template.Resources["BackupSchedule"].(*events.Rule)
+	assert.Equal(t, rule.ScheduleExpression, "cron(0 3 * * ? *)")
+	assert.Equal(t, rule.State, "ENABLED")
+	target := rule.Targets[0]
+	assert.Equal(t, target.Arn, cloudformation.GetAtt("Cluster", "Arn"))
+	assert.Equal(t, target.RoleArn, cloudformation.GetAtt("BackupScheduleRole", "Arn"))
+	assert.Equal(t, target.EcsParameters.TaskDefinitionArn, cloudformation.Ref("BackupTaskDefinition"))
+	assert.Equal(t, target.EcsParameters.TaskCount, 2)
+	assert.Equal(t, target.EcsParameters.LaunchType, "FARGATE")
+	assert.Equal(t, target.EcsParameters.PlatformVersion, fargatePlatformVersion)
+	assert.DeepEqual(t, target.EcsParameters.NetworkConfiguration.AwsVpcConfiguration.SecurityGroups, []string{cloudformation.Ref("DefaultNetwork")})
+
+	role := template.Resources["BackupScheduleRole"].(*iam.Role)
+	assert.DeepEqual(t, role.ManagedPolicyArns, []string{ecsEventsRolePolicy})
+	assert.DeepEqual(t, role.AssumeRolePolicyDocument, eventsAssumeRolePolicyDocument)
+}
+
+func TestScheduledServiceExistingCluster(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  backup:
+    image: hello_world
+    x-aws-schedule: rate(1 hour)
+    deploy:
+      replicas: 0
+`)
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{cluster: "shared"})
+	assert.NilError(t, err)
+	rule := template.Resources["BackupSchedule"].(*events.Rule)
+	assert.Equal(t, rule.State, "DISABLED")
+	assert.Equal(t, rule.Targets[0].Arn, cloudformation.Sub("arn:${AWS::Partition}:ecs:${AWS::Region}:${AWS::AccountId}:cluster/shared"))
+}
+
+func TestScheduledServiceInvalid(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  backup:
+    image: hello_world
+    x-aws-schedule: every day
+`: "x-aws-schedule must be a cron(...) or rate(...) expression, got every day",
+		`
+services:
+  backup:
+    image: hello_world
+    x-aws-schedule: rate(1 day)
+    ports:
+      - 80:80
+`: "x-aws-schedule can't be set on a service exposing ports",
+		`
+services:
+  backup:
+    image: hello_world
+    x-aws-schedule: rate(1 day)
+    deploy:
+      x-aws-autoscaling:
+        cpu: 75
+        max: 4
+`: "deploy.x-aws-autoscaling can't be set with x-aws-schedule",
+		`
+services:
+  backup:
+    image: hello_world
+    x-aws-schedule: rate(1 day)
+  web:
+    image: nginx
+    depends_on:
+      - backup
+`: "service web depends on backup, which only runs on schedule",
+	} {
+		project := loadConfig(t, yaml)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.ErrorContains(t, err, expected)
+	}
+}
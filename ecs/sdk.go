@@ -17,29 +17,50 @@
 package ecs
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 
+	"github.com/compose-spec/compose-go/types"
+	moby "github.com/docker/docker/api/types"
+	"github.com/docker/go-units"
+
 	"github.com/docker/compose-cli/api/compose"
 	"github.com/docker/compose-cli/api/secrets"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling/applicationautoscalingiface"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/backup"
+	"github.com/aws/aws-sdk-go/service/backup/backupiface"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/codedeploy/codedeployiface"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
 	"github.com/aws/aws-sdk-go/service/efs"
@@ -48,8 +69,20 @@ import (
 	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/servicediscovery"
+	"github.com/aws/aws-sdk-go/service/servicediscovery/servicediscoveryiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
 	"github.com/hashicorp/go-multierror"
 	"github.com/sirupsen/logrus"
 )
@@ -60,28 +93,53 @@ type sdk struct {
 	EFS efsiface.EFSAPI
 	ELB elbv2iface.ELBV2API
 	CW  cloudwatchlogsiface.CloudWatchLogsAPI
+	CWM cloudwatchiface.CloudWatchAPI
 	IAM iamiface.IAMAPI
 	CF  cloudformationiface.CloudFormationAPI
 	SM  secretsmanageriface.SecretsManagerAPI
 	SSM ssmiface.SSMAPI
 	AG  autoscalingiface.AutoScalingAPI
+	STS stsiface.STSAPI
+	S3  s3iface.S3API
+	ECR ecriface.ECRAPI
+	RGT resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI
+	KMS kmsiface.KMSAPI
+	SD  servicediscoveryiface.ServiceDiscoveryAPI
+	BK  backupiface.BackupAPI
+	AAS applicationautoscalingiface.ApplicationAutoScalingAPI
+	SNS snsiface.SNSAPI
+	CD  codedeployiface.CodeDeployAPI
 }
 
 func newSDK(sess *session.Session) sdk {
+	// handlers are added to a copy, so sessions derived from sess don't get them twice
+	sess = sess.Copy()
 	sess.Handlers.Build.PushBack(func(r *request.Request) {
 		request.AddToUserAgent(r, "Docker CLI")
 	})
+	sess.Handlers.AfterRetry.PushBack(reportThrottlingExhausted)
 	return sdk{
 		ECS: ecs.New(sess),
 		EC2: ec2.New(sess),
 		EFS: efs.New(sess),
 		ELB: elbv2.New(sess),
 		CW:  cloudwatchlogs.New(sess),
+		CWM: cloudwatch.New(sess),
 		IAM: iam.New(sess),
 		CF:  cloudformation.New(sess),
 		SM:  secretsmanager.New(sess),
 		SSM: ssm.New(sess),
 		AG:  autoscaling.New(sess),
+		STS: sts.New(sess),
+		S3:  s3.New(sess),
+		ECR: ecr.New(sess),
+		RGT: resourcegroupstaggingapi.New(sess),
+		KMS: kms.New(sess),
+		SD:  servicediscovery.New(sess),
+		BK:  backup.New(sess),
+		AAS: applicationautoscaling.New(sess),
+		SNS: sns.New(sess),
+		CD:  codedeploy.New(sess),
 	}
 }
 
@@ -113,6 +171,24 @@ func (s sdk) ClusterExists(ctx context.Context, name string) (bool, error) {
 	return len(clusters.Clusters) > 0, nil
 }
 
+// GetClusterSettings returns the settings of cluster, by name
+func (s sdk) GetClusterSettings(ctx context.Context, cluster string) (map[string]string, error) {
+	clusters, err := s.ECS.DescribeClustersWithContext(ctx, &ecs.DescribeClustersInput{
+		Clusters: []*string{aws.String(cluster)},
+		Include:  aws.StringSlice([]string{ecs.ClusterFieldSettings}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]string{}
+	for _, c := range clusters.Clusters {
+		for _, setting := range c.Settings {
+			settings[aws.StringValue(setting.Name)] = aws.StringValue(setting.Value)
+		}
+	}
+	return settings, nil
+}
+
 func (s sdk) CreateCluster(ctx context.Context, name string) (string, error) {
 	logrus.Debug("Create cluster ", name)
 	response, err := s.ECS.CreateClusterWithContext(ctx, &ecs.CreateClusterInput{ClusterName: aws.String(name)})
@@ -122,6 +198,210 @@ func (s sdk) CreateCluster(ctx context.Context, name string) (string, error) {
 	return *response.Cluster.Status, nil
 }
 
+// GetClusterCapacityProviders returns the capacity providers associated with cluster
+func (s sdk) GetClusterCapacityProviders(ctx context.Context, cluster string) ([]string, error) {
+	clusters, err := s.ECS.DescribeClustersWithContext(ctx, &ecs.DescribeClustersInput{
+		Clusters: []*string{aws.String(cluster)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters.Clusters) == 0 {
+		return nil, fmt.Errorf("cluster does not exist: %s", cluster)
+	}
+	return aws.StringValueSlice(clusters.Clusters[0].CapacityProviders), nil
+}
+
+// GetCapacityProviderAutoScalingGroup returns the ARN of the Auto Scaling group an active capacity provider manages, or
+// an empty ARN if the capacity provider doesn't exist, or has been deleted
+func (s sdk) GetCapacityProviderAutoScalingGroup(ctx context.Context, name string) (string, error) {
+	logrus.Debug("Describe capacity provider ", name)
+	providers, err := s.ECS.DescribeCapacityProvidersWithContext(ctx, &ecs.DescribeCapacityProvidersInput{
+		CapacityProviders: []*string{aws.String(name)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(providers.CapacityProviders) == 0 {
+		return "", nil
+	}
+	provider := providers.CapacityProviders[0]
+	if aws.StringValue(provider.Status) == ecs.CapacityProviderStatusInactive {
+		return "", nil
+	}
+	if aws.StringValue(provider.Status) != ecs.CapacityProviderStatusActive {
+		return "", fmt.Errorf("capacity provider %s is %s", name, aws.StringValue(provider.Status))
+	}
+	if provider.AutoScalingGroupProvider == nil {
+		return "", fmt.Errorf("capacity provider %s isn't backed by an Auto Scaling group", name)
+	}
+	return aws.StringValue(provider.AutoScalingGroupProvider.AutoScalingGroupArn), nil
+}
+
+// AddClusterCapacityProvider associates a capacity provider with cluster, keeping its other capacity providers and its
+// default capacity provider strategy
+func (s sdk) AddClusterCapacityProvider(ctx context.Context, cluster string, provider string) error {
+	logrus.Debug("Associate capacity provider ", provider, " with cluster ", cluster)
+	clusters, err := s.ECS.DescribeClustersWithContext(ctx, &ecs.DescribeClustersInput{
+		Clusters: []*string{aws.String(cluster)},
+	})
+	if err != nil {
+		return err
+	}
+	if len(clusters.Clusters) == 0 {
+		return fmt.Errorf("cluster does not exist: %s", cluster)
+	}
+	providers := clusters.Clusters[0].CapacityProviders
+	for _, p := range providers {
+		if aws.StringValue(p) == provider {
+			return nil
+		}
+	}
+	strategy := clusters.Clusters[0].DefaultCapacityProviderStrategy
+	if strategy == nil {
+		strategy = []*ecs.CapacityProviderStrategyItem{}
+	}
+	_, err = s.ECS.PutClusterCapacityProvidersWithContext(ctx, &ecs.PutClusterCapacityProvidersInput{
+		Cluster:                         aws.String(cluster),
+		CapacityProviders:               append(providers, aws.String(provider)),
+		DefaultCapacityProviderStrategy: strategy,
+	})
+	return err
+}
+
+// autoScalingGroupName accepts an Auto Scaling group name or ARN, as the API only accepts names
+func autoScalingGroupName(group string) string {
+	if i := strings.Index(group, "autoScalingGroupName/"); i >= 0 {
+		return group[i+len("autoScalingGroupName/"):]
+	}
+	return group
+}
+
+// autoScalingGroup is an existing Auto Scaling group a capacity provider can attach to
+type autoScalingGroup struct {
+	arn                  string
+	subnets              []string
+	protectedFromScaleIn bool
+}
+
+// GetAutoScalingGroup describes an existing Auto Scaling group by name or ARN
+func (s sdk) GetAutoScalingGroup(ctx context.Context, group string) (autoScalingGroup, error) {
+	name := autoScalingGroupName(group)
+	logrus.Debug("Describe auto scaling group ", name)
+	groups, err := s.AG.DescribeAutoScalingGroupsWithContext(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(name)},
+	})
+	if err != nil {
+		return autoScalingGroup{}, err
+	}
+	if len(groups.AutoScalingGroups) == 0 {
+		return autoScalingGroup{}, fmt.Errorf("auto scaling group does not exist: %s", name)
+	}
+	g := groups.AutoScalingGroups[0]
+	var subnets []string
+	for _, subnet := range strings.Split(aws.StringValue(g.VPCZoneIdentifier), ",") {
+		if subnet = strings.TrimSpace(subnet); subnet != "" {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return autoScalingGroup{
+		arn:                  aws.StringValue(g.AutoScalingGroupARN),
+		subnets:              subnets,
+		protectedFromScaleIn: aws.BoolValue(g.NewInstancesProtectedFromScaleIn),
+	}, nil
+}
+
+// GetAutoScalingGroupInstanceTypes returns the instance types an Auto Scaling group can launch
+func (s sdk) GetAutoScalingGroupInstanceTypes(ctx context.Context, groupArn string) ([]string, error) {
+	name := autoScalingGroupName(groupArn)
+	groups, err := s.AG.DescribeAutoScalingGroupsWithContext(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(name)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(groups.AutoScalingGroups) == 0 {
+		return nil, fmt.Errorf("auto scaling group does not exist: %s", name)
+	}
+	group := groups.AutoScalingGroups[0]
+	switch {
+	case group.LaunchConfigurationName != nil:
+		configurations, err := s.AG.DescribeLaunchConfigurationsWithContext(ctx, &autoscaling.DescribeLaunchConfigurationsInput{
+			LaunchConfigurationNames: []*string{group.LaunchConfigurationName},
+		})
+		if err != nil {
+			return nil, err
+		}
+		var instanceTypes []string
+		for _, c := range configurations.LaunchConfigurations {
+			instanceTypes = append(instanceTypes, aws.StringValue(c.InstanceType))
+		}
+		return instanceTypes, nil
+	case group.MixedInstancesPolicy != nil && group.MixedInstancesPolicy.LaunchTemplate != nil:
+		var instanceTypes []string
+		for _, o := range group.MixedInstancesPolicy.LaunchTemplate.Overrides {
+			instanceTypes = append(instanceTypes, aws.StringValue(o.InstanceType))
+		}
+		if len(instanceTypes) > 0 {
+			return instanceTypes, nil
+		}
+		instanceType, err := s.getLaunchTemplateInstanceType(ctx, group.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification)
+		return []string{instanceType}, err
+	case group.LaunchTemplate != nil:
+		instanceType, err := s.getLaunchTemplateInstanceType(ctx, group.LaunchTemplate)
+		return []string{instanceType}, err
+	}
+	return nil, fmt.Errorf("can't find instance type for auto scaling group %s", name)
+}
+
+func (s sdk) getLaunchTemplateInstanceType(ctx context.Context, template *autoscaling.LaunchTemplateSpecification) (string, error) {
+	version := aws.StringValue(template.Version)
+	if version == "" {
+		version = "$Default"
+	}
+	versions, err := s.EC2.DescribeLaunchTemplateVersionsWithContext(ctx, &ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateId:   template.LaunchTemplateId,
+		LaunchTemplateName: template.LaunchTemplateName,
+		Versions:           []*string{aws.String(version)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(versions.LaunchTemplateVersions) == 0 || versions.LaunchTemplateVersions[0].LaunchTemplateData == nil {
+		return "", fmt.Errorf("launch template version %s not found", version)
+	}
+	return aws.StringValue(versions.LaunchTemplateVersions[0].LaunchTemplateData.InstanceType), nil
+}
+
+// DescribeInstanceTypes returns the resources available on instance types
+func (s sdk) DescribeInstanceTypes(ctx context.Context, instanceTypes []string) ([]machine, error) {
+	described, err := s.EC2.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: aws.StringSlice(instanceTypes),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var machines []machine
+	for _, t := range described.InstanceTypes {
+		m := machine{
+			id: aws.StringValue(t.InstanceType),
+		}
+		if t.VCpuInfo != nil {
+			m.cpus = float64(aws.Int64Value(t.VCpuInfo.DefaultVCpus))
+		}
+		if t.MemoryInfo != nil {
+			m.memory = types.UnitBytes(aws.Int64Value(t.MemoryInfo.SizeInMiB) * units.MiB)
+		}
+		if t.GpuInfo != nil {
+			for _, gpu := range t.GpuInfo.Gpus {
+				m.gpus += aws.Int64Value(gpu.Count)
+			}
+		}
+		machines = append(machines, m)
+	}
+	return machines, nil
+}
+
 func (s sdk) CheckVPC(ctx context.Context, vpcID string) error {
 	logrus.Debug("CheckRequirements on VPC : ", vpcID)
 	output, err := s.EC2.DescribeVpcAttributeWithContext(ctx, &ec2.DescribeVpcAttributeInput{
@@ -156,7 +436,7 @@ func (s sdk) GetDefaultVPC(ctx context.Context) (string, error) {
 	return *vpcs.Vpcs[0].VpcId, nil
 }
 
-func (s sdk) GetSubNets(ctx context.Context, vpcID string) ([]string, error) {
+func (s sdk) GetSubNets(ctx context.Context, vpcID string) ([]*ec2.Subnet, error) {
 	logrus.Debug("Retrieve SubNets")
 	subnets, err := s.EC2.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{
 		DryRun: nil,
@@ -170,12 +450,52 @@ func (s sdk) GetSubNets(ctx context.Context, vpcID string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	return subnets.Subnets, nil
+}
 
-	ids := []string{}
-	for _, subnet := range subnets.Subnets {
-		ids = append(ids, *subnet.SubnetId)
+// GetPublicSubnets tells which subnets of VPC are public, their route table routing traffic to an internet gateway.
+// Subnets without an explicit route table association use the main route table of the VPC
+func (s sdk) GetPublicSubnets(ctx context.Context, vpcID string, subnets []string) (map[string]bool, error) {
+	logrus.Debug("Retrieve route tables of VPC ", vpcID)
+	var main bool
+	explicit := map[string]bool{}
+	public := map[string]bool{}
+	err := s.EC2.DescribeRouteTablesPagesWithContext(ctx, &ec2.DescribeRouteTablesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpcID)},
+			},
+		},
+	}, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+		for _, table := range page.RouteTables {
+			var igw bool
+			for _, route := range table.Routes {
+				if strings.HasPrefix(aws.StringValue(route.GatewayId), "igw-") {
+					igw = true
+				}
+			}
+			for _, association := range table.Associations {
+				if aws.BoolValue(association.Main) {
+					main = igw
+				}
+				if subnet := aws.StringValue(association.SubnetId); subnet != "" {
+					explicit[subnet] = true
+					public[subnet] = igw
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, subnet := range subnets {
+		if !explicit[subnet] {
+			public[subnet] = main
+		}
 	}
-	return ids, nil
+	return public, nil
 }
 
 func (s sdk) GetRoleArn(ctx context.Context, name string) (string, error) {
@@ -201,28 +521,116 @@ func (s sdk) StackExists(ctx context.Context, name string) (bool, error) {
 	return len(stacks.Stacks) > 0, nil
 }
 
-func (s sdk) CreateStack(ctx context.Context, name string, template []byte) error {
+// SharedProjectStackExists tells if a stack deployed from compose project exists, whatever the environment it's named after
+func (s sdk) SharedProjectStackExists(ctx context.Context, project string) (bool, error) {
+	found := false
+	err := s.CF.DescribeStacksPagesWithContext(ctx, &cloudformation.DescribeStacksInput{}, func(page *cloudformation.DescribeStacksOutput, lastPage bool) bool {
+		for _, stack := range page.Stacks {
+			for _, t := range stack.Tags {
+				if aws.StringValue(t.Key) == sharedProjectTag && aws.StringValue(t.Value) == project {
+					found = true
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return found, err
+}
+
+func stackTags(tags map[string]string) []*cloudformation.Tag {
+	var stackTags []*cloudformation.Tag
+	for k, v := range tags {
+		stackTags = append(stackTags, &cloudformation.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		})
+	}
+	return stackTags
+}
+
+// CreateStack creates a stack, failing if not completed within timeout unless zero. A failed stack is deleted, or kept
+// as is for inspection when rollback is disabled. A stack created with a policy also gets termination protection.
+// Stack events are published to the notifications SNS topics
+func (s sdk) CreateStack(ctx context.Context, name string, template []byte, tags map[string]string, timeout time.Duration, rollback bool, policy string, notifications []string) error {
 	logrus.Debug("Create CloudFormation stack")
 
+	onFailure := cloudformation.OnFailureDelete
+	if !rollback {
+		onFailure = cloudformation.OnFailureDoNothing
+	}
+	var timeoutInMinutes *int64
+	if timeout > 0 {
+		timeoutInMinutes = aws.Int64(int64(math.Ceil(timeout.Minutes())))
+	}
+	var stackPolicy *string
+	if policy != "" {
+		stackPolicy = aws.String(policy)
+	}
 	_, err := s.CF.CreateStackWithContext(ctx, &cloudformation.CreateStackInput{
-		OnFailure:        aws.String("DELETE"),
+		OnFailure:        aws.String(onFailure),
 		StackName:        aws.String(name),
 		TemplateBody:     aws.String(string(template)),
-		TimeoutInMinutes: nil,
+		TimeoutInMinutes: timeoutInMinutes,
 		Capabilities: []*string{
 			aws.String(cloudformation.CapabilityCapabilityIam),
 		},
-		Tags: []*cloudformation.Tag{
-			{
-				Key:   aws.String(compose.ProjectTag),
-				Value: aws.String(name),
-			},
-		},
+		Tags:                        stackTags(tags),
+		StackPolicyBody:             stackPolicy,
+		EnableTerminationProtection: aws.Bool(policy != ""),
+		NotificationARNs:            aws.StringSlice(notifications),
 	})
 	return err
 }
 
-func (s sdk) CreateChangeSet(ctx context.Context, name string, template []byte) (string, error) {
+// GetStackTerminationProtection tells whether stack has termination protection enabled
+func (s sdk) GetStackTerminationProtection(ctx context.Context, name string) (bool, error) {
+	stacks, err := s.CF.DescribeStacksWithContext(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(name),
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, stack := range stacks.Stacks {
+		return aws.BoolValue(stack.EnableTerminationProtection), nil
+	}
+	return false, nil
+}
+
+// GetStackNotificationARNs returns the SNS topics stack events are published to
+func (s sdk) GetStackNotificationARNs(ctx context.Context, name string) ([]string, error) {
+	stacks, err := s.CF.DescribeStacksWithContext(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, stack := range stacks.Stacks {
+		return aws.StringValueSlice(stack.NotificationARNs), nil
+	}
+	return nil, nil
+}
+
+func (s sdk) UpdateTerminationProtection(ctx context.Context, name string, enabled bool) error {
+	logrus.Debugf("Set termination protection of stack %s to %t", name, enabled)
+	_, err := s.CF.UpdateTerminationProtectionWithContext(ctx, &cloudformation.UpdateTerminationProtectionInput{
+		StackName:                   aws.String(name),
+		EnableTerminationProtection: aws.Bool(enabled),
+	})
+	return err
+}
+
+func (s sdk) SetStackPolicy(ctx context.Context, name string, policy string) error {
+	_, err := s.CF.SetStackPolicyWithContext(ctx, &cloudformation.SetStackPolicyInput{
+		StackName:       aws.String(name),
+		StackPolicyBody: aws.String(policy),
+	})
+	return err
+}
+
+// CreateChangeSet creates a change set to update stack with template. The stack events get published to the
+// notifications SNS topics, replacing the ones previously set
+func (s sdk) CreateChangeSet(ctx context.Context, name string, template []byte, tags map[string]string, notifications []string) (string, error) {
 	logrus.Debug("Create CloudFormation Changeset")
 
 	update := fmt.Sprintf("Update%s", time.Now().Format("2006-01-02-15-04-05"))
@@ -234,6 +642,8 @@ func (s sdk) CreateChangeSet(ctx context.Context, name string, template []byte)
 		Capabilities: []*string{
 			aws.String(cloudformation.CapabilityCapabilityIam),
 		},
+		Tags:             stackTags(tags),
+		NotificationARNs: aws.StringSlice(notifications),
 	})
 	if err != nil {
 		return "", err
@@ -257,12 +667,158 @@ func (s sdk) UpdateStack(ctx context.Context, changeset string) error {
 		return nil
 	}
 
-	_, err = s.CF.ExecuteChangeSet(&cloudformation.ExecuteChangeSetInput{
+	_, err = s.CF.ExecuteChangeSetWithContext(ctx, &cloudformation.ExecuteChangeSetInput{
+		ChangeSetName: aws.String(changeset),
+	})
+	return err
+}
+
+// resourceChange is the change a change set applies to a stack resource
+type resourceChange struct {
+	action       string // Add, Modify, Remove, Import or Dynamic
+	resource     string
+	resourceType string
+	replacement  string // True, False or Conditional, for Modify actions
+}
+
+// GetChangeSetChanges returns the resource changes a change set computed, none when the template didn't change the stack
+func (s sdk) GetChangeSetChanges(ctx context.Context, changeset string) ([]resourceChange, error) {
+	var (
+		changes   []resourceChange
+		nextToken *string
+	)
+	for {
+		desc, err := s.CF.DescribeChangeSetWithContext(ctx, &cloudformation.DescribeChangeSetInput{
+			ChangeSetName: aws.String(changeset),
+			NextToken:     nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if aws.StringValue(desc.Status) == cloudformation.ChangeSetStatusFailed {
+			reason := aws.StringValue(desc.StatusReason)
+			if strings.HasPrefix(reason, "The submitted information didn't contain changes.") {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("change set failed: %s", reason)
+		}
+		for _, change := range desc.Changes {
+			if c := change.ResourceChange; c != nil {
+				changes = append(changes, resourceChange{
+					action:       aws.StringValue(c.Action),
+					resource:     aws.StringValue(c.LogicalResourceId),
+					resourceType: aws.StringValue(c.ResourceType),
+					replacement:  aws.StringValue(c.Replacement),
+				})
+			}
+		}
+		nextToken = desc.NextToken
+		if nextToken == nil {
+			return changes, nil
+		}
+	}
+}
+
+func (s sdk) DeleteChangeSet(ctx context.Context, changeset string) error {
+	logrus.Debug("Delete CloudFormation Changeset ", changeset)
+	_, err := s.CF.DeleteChangeSetWithContext(ctx, &cloudformation.DeleteChangeSetInput{
 		ChangeSetName: aws.String(changeset),
 	})
 	return err
 }
 
+// driftDetectionPollInterval is the delay between checks of a stack drift detection status
+var driftDetectionPollInterval = 5 * time.Second
+
+// DetectStackDrift runs drift detection on a stack and returns its resources modified or deleted out of band
+func (s sdk) DetectStackDrift(ctx context.Context, name string) ([]compose.DriftedResource, error) {
+	logrus.Debug("Detect CloudFormation stack drift ", name)
+	detection, err := s.CF.DetectStackDriftWithContext(ctx, &cloudformation.DetectStackDriftInput{
+		StackName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		status, err := s.CF.DescribeStackDriftDetectionStatusWithContext(ctx, &cloudformation.DescribeStackDriftDetectionStatusInput{
+			StackDriftDetectionId: detection.StackDriftDetectionId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		switch aws.StringValue(status.DetectionStatus) {
+		case cloudformation.StackDriftDetectionStatusDetectionComplete:
+			return s.stackResourceDrifts(ctx, name)
+		case cloudformation.StackDriftDetectionStatusDetectionFailed:
+			// results are still available for the resources drift could be detected on
+			logrus.Warnf("drift detection failed for some resources of stack %s: %s", name, aws.StringValue(status.DetectionStatusReason))
+			return s.stackResourceDrifts(ctx, name)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(driftDetectionPollInterval):
+		}
+	}
+}
+
+func (s sdk) stackResourceDrifts(ctx context.Context, name string) ([]compose.DriftedResource, error) {
+	var drifted []compose.DriftedResource
+	err := s.CF.DescribeStackResourceDriftsPagesWithContext(ctx, &cloudformation.DescribeStackResourceDriftsInput{
+		StackName: aws.String(name),
+		StackResourceDriftStatusFilters: aws.StringSlice([]string{
+			cloudformation.StackResourceDriftStatusModified,
+			cloudformation.StackResourceDriftStatusDeleted,
+		}),
+	}, func(page *cloudformation.DescribeStackResourceDriftsOutput, lastPage bool) bool {
+		for _, drift := range page.StackResourceDrifts {
+			status := aws.StringValue(drift.StackResourceDriftStatus)
+			resource := compose.DriftedResource{
+				ID:         aws.StringValue(drift.LogicalResourceId),
+				PhysicalID: aws.StringValue(drift.PhysicalResourceId),
+				Type:       aws.StringValue(drift.ResourceType),
+				Status:     status,
+			}
+			for _, difference := range drift.PropertyDifferences {
+				resource.Differences = append(resource.Differences, compose.PropertyDifference{
+					Path:     aws.StringValue(difference.PropertyPath),
+					Expected: aws.StringValue(difference.ExpectedValue),
+					Actual:   aws.StringValue(difference.ActualValue),
+					Type:     aws.StringValue(difference.DifferenceType),
+				})
+			}
+			drifted = append(drifted, resource)
+		}
+		return true
+	})
+	return drifted, err
+}
+
+func (s sdk) ListChangeSetResources(ctx context.Context, changeset string) ([]string, error) {
+	var (
+		resources []string
+		nextToken *string
+	)
+	for {
+		desc, err := s.CF.DescribeChangeSetWithContext(ctx, &cloudformation.DescribeChangeSetInput{
+			ChangeSetName: aws.String(changeset),
+			NextToken:     nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range desc.Changes {
+			if change.ResourceChange != nil {
+				resources = append(resources, aws.StringValue(change.ResourceChange.LogicalResourceId))
+			}
+		}
+		nextToken = desc.NextToken
+		if nextToken == nil {
+			return resources, nil
+		}
+	}
+}
+
 const (
 	stackCreate = iota
 	stackUpdate
@@ -276,6 +832,8 @@ func (s sdk) WaitStackComplete(ctx context.Context, name string, operation int)
 	switch operation {
 	case stackCreate:
 		return s.CF.WaitUntilStackCreateCompleteWithContext(ctx, input)
+	case stackUpdate:
+		return s.CF.WaitUntilStackUpdateCompleteWithContext(ctx, input)
 	case stackDelete:
 		return s.CF.WaitUntilStackDeleteCompleteWithContext(ctx, input)
 	default:
@@ -362,36 +920,231 @@ func (s sdk) GetServiceTaskDefinition(ctx context.Context, cluster string, servi
 	return defs, nil
 }
 
-func (s sdk) ListStackServices(ctx context.Context, stack string) ([]string, error) {
-	arns := []string{}
-	var nextToken *string
-	for {
-		response, err := s.CF.ListStackResourcesWithContext(ctx, &cloudformation.ListStackResourcesInput{
-			StackName: aws.String(stack),
-			NextToken: nextToken,
-		})
-		if err != nil {
-			return nil, err
+// GetServiceEvents returns the messages of events ECS reported for service's current deployment
+func (s sdk) GetServiceEvents(ctx context.Context, cluster string, service string) ([]string, error) {
+	services, err := s.ECS.DescribeServicesWithContext(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(cluster),
+		Services: []*string{aws.String(service)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for _, svc := range services.Services {
+		var since time.Time
+		for _, d := range svc.Deployments {
+			if aws.StringValue(d.Status) == "PRIMARY" {
+				since = aws.TimeValue(d.CreatedAt)
+			}
 		}
-		for _, r := range response.StackResourceSummaries {
-			if aws.StringValue(r.ResourceType) == "AWS::ECS::Service" {
-				if r.PhysicalResourceId != nil {
-					arns = append(arns, aws.StringValue(r.PhysicalResourceId))
-				}
+		for _, event := range svc.Events {
+			if aws.TimeValue(event.CreatedAt).Before(since) {
+				continue
 			}
+			messages = append(messages, aws.StringValue(event.Message))
 		}
-		nextToken = response.NextToken
-		if nextToken == nil {
-			break
+	}
+	return messages, nil
+}
+
+// describeServicesLimit is the maximum number of services a single DescribeServices call accepts
+const describeServicesLimit = 10
+
+// describeServices calls fn with each of the services, in as many DescribeServices calls as required
+func (s sdk) describeServices(ctx context.Context, cluster string, arns []string, fn func(*ecs.Service)) error {
+	for len(arns) > 0 {
+		batch := arns
+		if len(batch) > describeServicesLimit {
+			batch = batch[:describeServicesLimit]
+		}
+		arns = arns[len(batch):]
+		services, err := s.ECS.DescribeServicesWithContext(ctx, &ecs.DescribeServicesInput{
+			Cluster:  aws.String(cluster),
+			Services: aws.StringSlice(batch),
+			Include:  aws.StringSlice([]string{"TAGS"}),
+		})
+		if err != nil {
+			return err
+		}
+		for _, svc := range services.Services {
+			fn(svc)
 		}
 	}
-	return arns, nil
+	return nil
 }
 
-func (s sdk) GetServiceTasks(ctx context.Context, cluster string, service string, stopped bool) ([]*ecs.Task, error) {
-	state := "RUNNING"
-	if stopped {
-		state = "STOPPED"
+// composeServiceName is the name of the compose service an ECS service was created for, or its ECS name when untagged
+func composeServiceName(svc *ecs.Service) string {
+	for _, t := range svc.Tags {
+		if aws.StringValue(t.Key) == compose.ServiceTag {
+			return aws.StringValue(t.Value)
+		}
+	}
+	return aws.StringValue(svc.ServiceName)
+}
+
+// GetServicesEvents returns the events ECS reported for services, named after their compose service
+func (s sdk) GetServicesEvents(ctx context.Context, cluster string, arns []string) ([]compose.ServiceEvent, error) {
+	var events []compose.ServiceEvent
+	err := s.describeServices(ctx, cluster, arns, func(svc *ecs.Service) {
+		name := composeServiceName(svc)
+		for _, event := range svc.Events {
+			events = append(events, compose.ServiceEvent{
+				ID:        aws.StringValue(event.Id),
+				Service:   name,
+				Timestamp: aws.TimeValue(event.CreatedAt),
+				Message:   aws.StringValue(event.Message),
+			})
+		}
+	})
+	return events, err
+}
+
+// GetServices returns services, by compose service name
+func (s sdk) GetServices(ctx context.Context, cluster string, arns []string) (map[string]*ecs.Service, error) {
+	services := map[string]*ecs.Service{}
+	err := s.describeServices(ctx, cluster, arns, func(svc *ecs.Service) {
+		services[composeServiceName(svc)] = svc
+	})
+	return services, err
+}
+
+// scalableTargetID is the Application Auto Scaling resource ID of an ECS service's desired count
+func scalableTargetID(svc *ecs.Service) string {
+	return fmt.Sprintf("service/%s/%s", resourceName(aws.StringValue(svc.ClusterArn)), aws.StringValue(svc.ServiceName))
+}
+
+// GetScalableTargetMinCapacity returns the minimum capacity of the scalable target of service's desired count, if any
+func (s sdk) GetScalableTargetMinCapacity(ctx context.Context, svc *ecs.Service) (int, bool, error) {
+	targets, err := s.AAS.DescribeScalableTargetsWithContext(ctx, &applicationautoscaling.DescribeScalableTargetsInput{
+		ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceEcs),
+		ScalableDimension: aws.String(applicationautoscaling.ScalableDimensionEcsServiceDesiredCount),
+		ResourceIds:       aws.StringSlice([]string{scalableTargetID(svc)}),
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if len(targets.ScalableTargets) == 0 {
+		return 0, false, nil
+	}
+	return int(aws.Int64Value(targets.ScalableTargets[0].MinCapacity)), true, nil
+}
+
+// SetScalableTargetMinCapacity updates the minimum capacity of the scalable target of service's desired count
+func (s sdk) SetScalableTargetMinCapacity(ctx context.Context, svc *ecs.Service, min int) error {
+	_, err := s.AAS.RegisterScalableTargetWithContext(ctx, &applicationautoscaling.RegisterScalableTargetInput{
+		ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceEcs),
+		ScalableDimension: aws.String(applicationautoscaling.ScalableDimensionEcsServiceDesiredCount),
+		ResourceId:        aws.String(scalableTargetID(svc)),
+		MinCapacity:       aws.Int64(int64(min)),
+	})
+	return err
+}
+
+// UpdateServiceDesiredCount sets the number of tasks ECS runs for service, out of the stack
+func (s sdk) UpdateServiceDesiredCount(ctx context.Context, cluster string, service string, count int) error {
+	logrus.Debugf("Update desired count of service %s to %d", service, count)
+	_, err := s.ECS.UpdateServiceWithContext(ctx, &ecs.UpdateServiceInput{
+		Cluster:      aws.String(cluster),
+		Service:      aws.String(service),
+		DesiredCount: aws.Int64(int64(count)),
+	})
+	return err
+}
+
+// WaitServiceStable waits for service to run its desired count of tasks, all from its current deployment
+func (s sdk) WaitServiceStable(ctx context.Context, cluster string, service string) error {
+	return s.ECS.WaitUntilServicesStableWithContext(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(cluster),
+		Services: []*string{aws.String(service)},
+	})
+}
+
+// CreateECSDeployment starts a CodeDeploy deployment of the ECS service described by appSpec, and returns its ID
+func (s sdk) CreateECSDeployment(ctx context.Context, application string, group string, appSpec string) (string, error) {
+	deployment, err := s.CD.CreateDeploymentWithContext(ctx, &codedeploy.CreateDeploymentInput{
+		ApplicationName:     aws.String(application),
+		DeploymentGroupName: aws.String(group),
+		Revision: &codedeploy.RevisionLocation{
+			RevisionType: aws.String(codedeploy.RevisionLocationTypeAppSpecContent),
+			AppSpecContent: &codedeploy.AppSpecContent{
+				Content: aws.String(appSpec),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(deployment.DeploymentId), nil
+}
+
+// WaitDeploymentSuccessful waits for CodeDeploy deployment id to succeed, and fails once it's stopped or has failed
+func (s sdk) WaitDeploymentSuccessful(ctx context.Context, id string) error {
+	return s.CD.WaitUntilDeploymentSuccessfulWithContext(ctx, &codedeploy.GetDeploymentInput{
+		DeploymentId: aws.String(id),
+	})
+}
+
+// UpdateServiceCapacity redeploys service with tasks running on the capacity providers selected by strategy
+func (s sdk) UpdateServiceCapacity(ctx context.Context, cluster string, service string, strategy []capacityStrategy) error {
+	logrus.Debug("Update capacity provider strategy of service ", service)
+	var items []*ecs.CapacityProviderStrategyItem
+	for _, i := range strategy {
+		items = append(items, &ecs.CapacityProviderStrategyItem{
+			CapacityProvider: aws.String(i.CapacityProvider),
+			Weight:           aws.Int64(int64(i.Weight)),
+			Base:             aws.Int64(int64(i.Base)),
+		})
+	}
+	_, err := s.ECS.UpdateServiceWithContext(ctx, &ecs.UpdateServiceInput{
+		Cluster:                  aws.String(cluster),
+		Service:                  aws.String(service),
+		CapacityProviderStrategy: items,
+		ForceNewDeployment:       aws.Bool(true),
+	})
+	return err
+}
+
+func (s sdk) ListStackServices(ctx context.Context, stack string) ([]string, error) {
+	arns := []string{}
+	var nextToken *string
+	for {
+		response, err := s.CF.ListStackResourcesWithContext(ctx, &cloudformation.ListStackResourcesInput{
+			StackName: aws.String(stack),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range response.StackResourceSummaries {
+			switch aws.StringValue(r.ResourceType) {
+			case "AWS::ECS::Service":
+				if r.PhysicalResourceId != nil {
+					arns = append(arns, aws.StringValue(r.PhysicalResourceId))
+				}
+			case awsTypeStack:
+				// services are deployed by nested stacks when project sets x-aws-template_bucket
+				if r.PhysicalResourceId != nil {
+					nested, err := s.ListStackServices(ctx, aws.StringValue(r.PhysicalResourceId))
+					if err != nil {
+						return nil, err
+					}
+					arns = append(arns, nested...)
+				}
+			}
+		}
+		nextToken = response.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	return arns, nil
+}
+
+func (s sdk) GetServiceTasks(ctx context.Context, cluster string, service string, stopped bool) ([]*ecs.Task, error) {
+	state := "RUNNING"
+	if stopped {
+		state = "STOPPED"
 	}
 	tasks, err := s.ECS.ListTasksWithContext(ctx, &ecs.ListTasksInput{
 		Cluster:       aws.String(cluster),
@@ -414,23 +1167,222 @@ func (s sdk) GetServiceTasks(ctx context.Context, cluster string, service string
 	return nil, nil
 }
 
-func (s sdk) GetTaskStoppedReason(ctx context.Context, cluster string, taskArn string) (string, error) {
-	taskDescriptions, err := s.ECS.DescribeTasksWithContext(ctx, &ecs.DescribeTasksInput{
+// executeCommandSession is the Session Manager session ECS opens to run a command in a container
+type executeCommandSession struct {
+	SessionID  *string `locationName:"sessionId" type:"string" json:"SessionId"`
+	StreamURL  *string `locationName:"streamUrl" type:"string" json:"StreamUrl"`
+	TokenValue *string `locationName:"tokenValue" type:"string" json:"TokenValue"`
+}
+
+type executeCommandInput struct {
+	_           struct{} `type:"structure"`
+	Cluster     *string  `locationName:"cluster" type:"string"`
+	Container   *string  `locationName:"container" type:"string"`
+	Command     *string  `locationName:"command" type:"string" required:"true"`
+	Interactive *bool    `locationName:"interactive" type:"boolean" required:"true"`
+	Task        *string  `locationName:"task" type:"string" required:"true"`
+}
+
+type executeCommandOutput struct {
+	_       struct{}               `type:"structure"`
+	Session *executeCommandSession `locationName:"session" type:"structure"`
+}
+
+// ECSEndpoint returns the URL of the ECS API endpoint the client sends requests to in region
+func (s sdk) ECSEndpoint(region string) string {
+	if client, ok := s.ECS.(*ecs.ECS); ok {
+		return client.Endpoint
+	}
+	resolved, err := endpoints.DefaultResolver().EndpointFor(ecs.EndpointsID, region)
+	if err != nil {
+		return fmt.Sprintf("https://ecs.%s.amazonaws.com", region)
+	}
+	return resolved.URL
+}
+
+// ObjectURL returns the URL of key in bucket, built from the S3 client endpoint so it matches the partition or a custom
+// endpoint, using path-style addressing when the client is configured for it or the bucket name isn't a valid host label
+func (s sdk) ObjectURL(region string, bucket string, key string) string {
+	endpoint := ""
+	pathStyle := strings.Contains(bucket, ".")
+	if client, ok := s.S3.(*s3.S3); ok {
+		endpoint = client.Endpoint
+		pathStyle = pathStyle || aws.BoolValue(client.Config.S3ForcePathStyle)
+	} else if resolved, err := endpoints.DefaultResolver().EndpointFor(s3.EndpointsID, region); err == nil {
+		endpoint = resolved.URL
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, key)
+	}
+	if pathStyle {
+		u.Path = "/" + bucket + "/" + key
+	} else {
+		u.Host = bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return u.String()
+}
+
+// ExecuteCommand opens a session to run command in a task's container. The AWS SDK version we use doesn't support ECS
+// Exec yet, so the request is built from the ECS client
+func (s sdk) ExecuteCommand(ctx context.Context, cluster string, task string, container string, command string) (executeCommandSession, error) {
+	client, ok := s.ECS.(*ecs.ECS)
+	if !ok {
+		return executeCommandSession{}, fmt.Errorf("ExecuteCommand requires an ECS client")
+	}
+	output := &executeCommandOutput{}
+	req := client.NewRequest(&request.Operation{
+		Name:       "ExecuteCommand",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, &executeCommandInput{
+		Cluster:     aws.String(cluster),
+		Container:   aws.String(container),
+		Command:     aws.String(command),
+		Interactive: aws.Bool(true),
+		Task:        aws.String(task),
+	}, output)
+	req.SetContext(ctx)
+	if err := req.Send(); err != nil {
+		return executeCommandSession{}, err
+	}
+	if output.Session == nil {
+		return executeCommandSession{}, fmt.Errorf("no session opened to execute command in task %s", task)
+	}
+	return *output.Session, nil
+}
+
+// oneOffTask is a task run outside of its service, with the same task definition and network configuration
+type oneOffTask struct {
+	TaskDefinition       string
+	LaunchType           string
+	PlatformVersion      string
+	NetworkConfiguration *ecs.NetworkConfiguration
+	Container            string
+	Command              []string
+}
+
+// GetOneOffTask returns the configuration to run a one-off task of service
+func (s sdk) GetOneOffTask(ctx context.Context, cluster string, service string) (oneOffTask, error) {
+	services, err := s.ECS.DescribeServicesWithContext(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(cluster),
+		Services: []*string{aws.String(service)},
+	})
+	if err != nil {
+		return oneOffTask{}, err
+	}
+	if len(services.Services) == 0 {
+		return oneOffTask{}, fmt.Errorf("service %s not found", service)
+	}
+	svc := services.Services[0]
+	return oneOffTask{
+		TaskDefinition:       aws.StringValue(svc.TaskDefinition),
+		LaunchType:           aws.StringValue(svc.LaunchType),
+		PlatformVersion:      aws.StringValue(svc.PlatformVersion),
+		NetworkConfiguration: svc.NetworkConfiguration,
+	}, nil
+}
+
+// GetContainerLogs returns the log group and stream prefix container of taskDefinition sends logs to
+func (s sdk) GetContainerLogs(ctx context.Context, taskDefinition string, container string) (string, string, error) {
+	definition, err := s.ECS.DescribeTaskDefinitionWithContext(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinition),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	for _, c := range definition.TaskDefinition.ContainerDefinitions {
+		if aws.StringValue(c.Name) != container {
+			continue
+		}
+		if c.LogConfiguration == nil || aws.StringValue(c.LogConfiguration.LogDriver) != ecs.LogDriverAwslogs {
+			return "", "", nil
+		}
+		options := c.LogConfiguration.Options
+		return aws.StringValue(options["awslogs-group"]), aws.StringValue(options["awslogs-stream-prefix"]), nil
+	}
+	return "", "", fmt.Errorf("task definition %s has no container %s", taskDefinition, container)
+}
+
+// RunTask starts a one-off task and returns its ARN
+func (s sdk) RunTask(ctx context.Context, cluster string, task oneOffTask) (string, error) {
+	input := &ecs.RunTaskInput{
+		Cluster:              aws.String(cluster),
+		Count:                aws.Int64(1),
+		NetworkConfiguration: task.NetworkConfiguration,
+		Overrides: &ecs.TaskOverride{
+			ContainerOverrides: []*ecs.ContainerOverride{
+				{
+					Name:    aws.String(task.Container),
+					Command: aws.StringSlice(task.Command),
+				},
+			},
+		},
+		PropagateTags:  aws.String(ecs.PropagateTagsTaskDefinition),
+		StartedBy:      aws.String("compose-run"),
+		TaskDefinition: aws.String(task.TaskDefinition),
+	}
+	if task.LaunchType != "" {
+		input.LaunchType = aws.String(task.LaunchType)
+	}
+	if task.PlatformVersion != "" {
+		input.PlatformVersion = aws.String(task.PlatformVersion)
+	}
+	response, err := s.ECS.RunTaskWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	if len(response.Failures) > 0 {
+		failure := response.Failures[0]
+		return "", fmt.Errorf("failed to run task: %s %s", aws.StringValue(failure.Reason), aws.StringValue(failure.Detail))
+	}
+	return aws.StringValue(response.Tasks[0].TaskArn), nil
+}
+
+func (s sdk) DescribeTask(ctx context.Context, cluster string, taskArn string) (*ecs.Task, error) {
+	response, err := s.ECS.DescribeTasksWithContext(ctx, &ecs.DescribeTasksInput{
 		Cluster: aws.String(cluster),
 		Tasks:   []*string{aws.String(taskArn)},
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if len(taskDescriptions.Tasks) == 0 {
-		return "", nil
+	if len(response.Tasks) == 0 {
+		return nil, fmt.Errorf("task %s not found", taskArn)
 	}
-	task := taskDescriptions.Tasks[0]
-	return fmt.Sprintf(
-		"%s: %s",
-		aws.StringValue(task.StopCode),
-		aws.StringValue(task.StoppedReason)), nil
+	return response.Tasks[0], nil
+}
 
+func (s sdk) StopTask(ctx context.Context, cluster string, taskArn string, reason string) error {
+	_, err := s.ECS.StopTaskWithContext(ctx, &ecs.StopTaskInput{
+		Cluster: aws.String(cluster),
+		Task:    aws.String(taskArn),
+		Reason:  aws.String(reason),
+	})
+	return err
+}
+
+// GetLogEvents returns the messages of a log stream following token, and the token to read next ones. Streams only
+// exist once a container has logged, so a missing stream has no messages yet
+func (s sdk) GetLogEvents(ctx context.Context, group string, stream string, token *string) ([]string, *string, error) {
+	response, err := s.CW.GetLogEventsWithContext(ctx, &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(group),
+		LogStreamName: aws.String(stream),
+		NextToken:     token,
+		StartFromHead: aws.Bool(true),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudwatchlogs.ErrCodeResourceNotFoundException {
+			return nil, token, nil
+		}
+		return nil, nil, err
+	}
+	var messages []string
+	for _, event := range response.Events {
+		messages = append(messages, aws.StringValue(event.Message))
+	}
+	return messages, response.NextForwardToken, nil
 }
 
 func (s sdk) DescribeStackEvents(ctx context.Context, stackID string) ([]*cloudformation.StackEvent, error) {
@@ -454,6 +1406,22 @@ func (s sdk) DescribeStackEvents(ctx context.Context, stackID string) ([]*cloudf
 	}
 }
 
+func (s sdk) GetStackTags(ctx context.Context, name string) (map[string]string, error) {
+	st, err := s.CF.DescribeStacksWithContext(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	for _, stack := range st.Stacks {
+		for _, tag := range stack.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+	}
+	return tags, nil
+}
+
 func (s sdk) ListStackParameters(ctx context.Context, name string) (map[string]string, error) {
 	st, err := s.CF.DescribeStacksWithContext(ctx, &cloudformation.DescribeStacksInput{
 		NextToken: nil,
@@ -469,6 +1437,62 @@ func (s sdk) ListStackParameters(ctx context.Context, name string) (map[string]s
 	return parameters, nil
 }
 
+// ListStackOutputs returns the values of stack's outputs by key
+func (s sdk) ListStackOutputs(ctx context.Context, name string) (map[string]string, error) {
+	st, err := s.CF.DescribeStacksWithContext(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	outputs := map[string]string{}
+	for _, output := range st.Stacks[0].Outputs {
+		outputs[aws.StringValue(output.OutputKey)] = aws.StringValue(output.OutputValue)
+	}
+	return outputs, nil
+}
+
+// ListStackExports returns the names of the outputs stack exports
+func (s sdk) ListStackExports(ctx context.Context, name string) ([]string, error) {
+	st, err := s.CF.DescribeStacksWithContext(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var exports []string
+	for _, output := range st.Stacks[0].Outputs {
+		if output.ExportName != nil {
+			exports = append(exports, aws.StringValue(output.ExportName))
+		}
+	}
+	return exports, nil
+}
+
+// ListImports returns the names of the stacks importing export
+func (s sdk) ListImports(ctx context.Context, export string) ([]string, error) {
+	logrus.Debug("List stacks importing " + export)
+	var stacks []string
+	var token *string
+	for {
+		imports, err := s.CF.ListImportsWithContext(ctx, &cloudformation.ListImportsInput{
+			ExportName: aws.String(export),
+			NextToken:  token,
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), "is not imported by any stack") {
+				return nil, nil
+			}
+			return nil, err
+		}
+		stacks = append(stacks, aws.StringValueSlice(imports.Imports)...)
+		if imports.NextToken == nil {
+			return stacks, nil
+		}
+		token = imports.NextToken
+	}
+}
+
 type stackResource struct {
 	LogicalID string
 	Type      string
@@ -493,6 +1517,15 @@ func (resources stackResources) apply(awsType string, fn stackResourceFn) error
 	return errs.ErrorOrNil()
 }
 
+func (resources stackResources) hasType(awsType string) bool {
+	for _, r := range resources {
+		if r.Type == awsType {
+			return true
+		}
+	}
+	return false
+}
+
 func (s sdk) ListStackResources(ctx context.Context, name string) (stackResources, error) {
 	// FIXME handle pagination
 	res, err := s.CF.ListStackResourcesWithContext(ctx, &cloudformation.ListStackResourcesInput{
@@ -522,6 +1555,15 @@ func (s sdk) DeleteStack(ctx context.Context, name string) error {
 	return err
 }
 
+// CancelUpdateStack stops a stack update in progress, which gets rolled back
+func (s sdk) CancelUpdateStack(ctx context.Context, name string) error {
+	logrus.Debug("Cancel CloudFormation stack update")
+	_, err := s.CF.CancelUpdateStackWithContext(ctx, &cloudformation.CancelUpdateStackInput{
+		StackName: aws.String(name),
+	})
+	return err
+}
+
 func (s sdk) CreateSecret(ctx context.Context, secret secrets.Secret) (string, error) {
 	logrus.Debug("Create secret " + secret.Name)
 	secretStr, err := secret.GetCredString()
@@ -529,7 +1571,7 @@ func (s sdk) CreateSecret(ctx context.Context, secret secrets.Secret) (string, e
 		return "", err
 	}
 
-	response, err := s.SM.CreateSecret(&secretsmanager.CreateSecretInput{
+	response, err := s.SM.CreateSecretWithContext(ctx, &secretsmanager.CreateSecretInput{
 		Name:         &secret.Name,
 		SecretString: &secretStr,
 		Description:  &secret.Description,
@@ -540,9 +1582,91 @@ func (s sdk) CreateSecret(ctx context.Context, secret secrets.Secret) (string, e
 	return aws.StringValue(response.ARN), nil
 }
 
+// PutSecret creates secret, or set a new value if it already exists. When kmsKey is set, secret is encrypted by this customer-managed key instead of the account default one
+func (s sdk) PutSecret(ctx context.Context, name string, content []byte, kmsKey string, tags map[string]string) (string, error) {
+	logrus.Debug("Put secret " + name)
+	secretString := aws.String(string(content))
+	var keyID *string
+	if kmsKey != "" {
+		keyID = aws.String(kmsKey)
+	}
+
+	existing, err := s.SM.DescribeSecretWithContext(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(name),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		var smTags []*secretsmanager.Tag
+		for k, v := range tags {
+			smTags = append(smTags, &secretsmanager.Tag{
+				Key:   aws.String(k),
+				Value: aws.String(v),
+			})
+		}
+		created, err := s.SM.CreateSecretWithContext(ctx, &secretsmanager.CreateSecretInput{
+			Name:         aws.String(name),
+			SecretString: secretString,
+			KmsKeyId:     keyID,
+			Tags:         smTags,
+		})
+		if err != nil {
+			return "", err
+		}
+		return aws.StringValue(created.ARN), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if kmsKey != "" && kmsKey != aws.StringValue(existing.KmsKeyId) {
+		// UpdateSecret re-encrypts the new value with the key
+		_, err = s.SM.UpdateSecretWithContext(ctx, &secretsmanager.UpdateSecretInput{
+			SecretId:     existing.ARN,
+			SecretString: secretString,
+			KmsKeyId:     keyID,
+		})
+	} else {
+		_, err = s.SM.PutSecretValueWithContext(ctx, &secretsmanager.PutSecretValueInput{
+			SecretId:     existing.ARN,
+			SecretString: secretString,
+		})
+	}
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(existing.ARN), nil
+}
+
+// SecretArn returns the ARN of secret name, or an empty string if it doesn't exist
+func (s sdk) SecretArn(ctx context.Context, name string) (string, error) {
+	logrus.Debug("Describe secret " + name)
+	secret, err := s.SM.DescribeSecretWithContext(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(name),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(secret.ARN), nil
+}
+
+// GetKeyPolicy returns the default policy document of KMS key
+func (s sdk) GetKeyPolicy(ctx context.Context, key string) (string, error) {
+	logrus.Debug("Retrieve policy of KMS key " + key)
+	policy, err := s.KMS.GetKeyPolicyWithContext(ctx, &kms.GetKeyPolicyInput{
+		KeyId:      aws.String(key),
+		PolicyName: aws.String("default"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(policy.Policy), nil
+}
+
 func (s sdk) InspectSecret(ctx context.Context, id string) (secrets.Secret, error) {
 	logrus.Debug("Inspect secret " + id)
-	response, err := s.SM.DescribeSecret(&secretsmanager.DescribeSecretInput{SecretId: &id})
+	response, err := s.SM.DescribeSecretWithContext(ctx, &secretsmanager.DescribeSecretInput{SecretId: &id})
 	if err != nil {
 		return secrets.Secret{}, err
 	}
@@ -563,7 +1687,7 @@ func (s sdk) InspectSecret(ctx context.Context, id string) (secrets.Secret, erro
 
 func (s sdk) ListSecrets(ctx context.Context) ([]secrets.Secret, error) {
 	logrus.Debug("List secrets ...")
-	response, err := s.SM.ListSecrets(&secretsmanager.ListSecretsInput{})
+	response, err := s.SM.ListSecretsWithContext(ctx, &secretsmanager.ListSecretsInput{})
 	if err != nil {
 		return nil, err
 	}
@@ -592,44 +1716,52 @@ func (s sdk) ListSecrets(ctx context.Context) ([]secrets.Secret, error) {
 func (s sdk) DeleteSecret(ctx context.Context, id string, recover bool) error {
 	logrus.Debug("List secrets ...")
 	force := !recover
-	_, err := s.SM.DeleteSecret(&secretsmanager.DeleteSecretInput{SecretId: &id, ForceDeleteWithoutRecovery: &force})
+	_, err := s.SM.DeleteSecretWithContext(ctx, &secretsmanager.DeleteSecretInput{SecretId: &id, ForceDeleteWithoutRecovery: &force})
 	return err
 }
 
-func (s sdk) GetLogs(ctx context.Context, name string, consumer func(service, container, message string)) error {
-	logGroup := fmt.Sprintf("/docker-compose/%s", name)
-	var startTime int64
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-			var hasMore = true
-			var token *string
-			for hasMore {
-				events, err := s.CW.FilterLogEvents(&cloudwatchlogs.FilterLogEventsInput{
-					LogGroupName: aws.String(logGroup),
-					NextToken:    token,
-					StartTime:    aws.Int64(startTime),
-				})
-				if err != nil {
-					return err
-				}
-				if events.NextToken == nil {
-					hasMore = false
-				} else {
-					token = events.NextToken
-				}
+// logEvent is a log event of a task's container, as sent by the awslogs driver
+type logEvent struct {
+	ID string
+	// Timestamp is the time the event was emitted, in milliseconds since epoch
+	Timestamp int64
+	Service   string
+	Task      string
+	Message   string
+}
 
-				for _, event := range events.Events {
-					p := strings.Split(aws.StringValue(event.LogStreamName), "/")
-					consumer(p[1], p[2], aws.StringValue(event.Message))
-					startTime = *event.IngestionTime
-				}
+// FilterLogEvents returns log events sent to logGroup since start and until end if set, in milliseconds since epoch,
+// selecting log streams by prefix if set
+func (s sdk) FilterLogEvents(ctx context.Context, logGroup string, streamPrefix string, start int64, end int64) ([]logEvent, error) {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(logGroup),
+		StartTime:    aws.Int64(start),
+	}
+	if end > 0 {
+		input.EndTime = aws.Int64(end)
+	}
+	if streamPrefix != "" {
+		input.LogStreamNamePrefix = aws.String(streamPrefix)
+	}
+	var events []logEvent
+	err := s.CW.FilterLogEventsPagesWithContext(ctx, input, func(page *cloudwatchlogs.FilterLogEventsOutput, _ bool) bool {
+		for _, event := range page.Events {
+			// awslogs driver names streams <prefix>/<container>/<task ID>
+			p := strings.SplitN(aws.StringValue(event.LogStreamName), "/", 3)
+			if len(p) < 3 {
+				continue
 			}
+			events = append(events, logEvent{
+				ID:        aws.StringValue(event.EventId),
+				Timestamp: aws.Int64Value(event.Timestamp),
+				Service:   p[1],
+				Task:      p[2],
+				Message:   aws.StringValue(event.Message),
+			})
 		}
-		time.Sleep(500 * time.Millisecond)
-	}
+		return true
+	})
+	return events, err
 }
 
 func (s sdk) DescribeService(ctx context.Context, cluster string, arn string) (compose.ServiceStatus, error) {
@@ -675,7 +1807,7 @@ func (s sdk) getURLWithPortMapping(ctx context.Context, targetGroupArns []string
 	if len(targetGroupArns) == 0 {
 		return nil, nil
 	}
-	groups, err := s.ELB.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
+	groups, err := s.ELB.DescribeTargetGroupsWithContext(ctx, &elbv2.DescribeTargetGroupsInput{
 		TargetGroupArns: aws.StringSlice(targetGroupArns),
 	})
 	if err != nil {
@@ -740,7 +1872,7 @@ func (s sdk) ListTasks(ctx context.Context, cluster string, family string) ([]st
 }
 
 func (s sdk) GetPublicIPs(ctx context.Context, interfaces ...string) (map[string]string, error) {
-	desc, err := s.EC2.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+	desc, err := s.EC2.DescribeNetworkInterfacesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 		NetworkInterfaceIds: aws.StringSlice(interfaces),
 	})
 	if err != nil {
@@ -831,6 +1963,40 @@ func (s sdk) GetParameter(ctx context.Context, name string) (string, error) {
 	return ami.ImageID, nil
 }
 
+// GetStringParameter returns the value of an SSM parameter, and false if it doesn't exist
+func (s sdk) GetStringParameter(ctx context.Context, name string) (string, bool, error) {
+	parameter, err := s.SSM.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name: aws.String(name),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return aws.StringValue(parameter.Parameter.Value), true, nil
+}
+
+func (s sdk) PutStringParameter(ctx context.Context, name string, value string) error {
+	_, err := s.SSM.PutParameterWithContext(ctx, &ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Value:     aws.String(value),
+		Type:      aws.String(ssm.ParameterTypeString),
+		Overwrite: aws.Bool(true),
+	})
+	return err
+}
+
+func (s sdk) DeleteParameter(ctx context.Context, name string) error {
+	_, err := s.SSM.DeleteParameterWithContext(ctx, &ssm.DeleteParameterInput{
+		Name: aws.String(name),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+		return nil
+	}
+	return err
+}
+
 func (s sdk) SecurityGroupExists(ctx context.Context, sg string) (bool, error) {
 	desc, err := s.EC2.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
 		GroupIds: aws.StringSlice([]string{sg}),
@@ -842,16 +2008,795 @@ func (s sdk) SecurityGroupExists(ctx context.Context, sg string) (bool, error) {
 }
 
 func (s sdk) DeleteCapacityProvider(ctx context.Context, arn string) error {
-	_, err := s.ECS.DeleteCapacityProvider(&ecs.DeleteCapacityProviderInput{
+	_, err := s.ECS.DeleteCapacityProviderWithContext(ctx, &ecs.DeleteCapacityProviderInput{
 		CapacityProvider: aws.String(arn),
 	})
 	return err
 }
 
 func (s sdk) DeleteAutoscalingGroup(ctx context.Context, arn string) error {
-	_, err := s.AG.DeleteAutoScalingGroup(&autoscaling.DeleteAutoScalingGroupInput{
+	_, err := s.AG.DeleteAutoScalingGroupWithContext(ctx, &autoscaling.DeleteAutoScalingGroupInput{
 		AutoScalingGroupName: aws.String(arn),
 		ForceDelete:          aws.Bool(true),
 	})
 	return err
 }
+
+func (s sdk) PutDeploymentMetric(ctx context.Context, project string, services []string) error {
+	logrus.Debug("Put deployment metric for ", project)
+	data := []*cloudwatch.MetricDatum{
+		{
+			MetricName: aws.String("Deployment"),
+			Dimensions: []*cloudwatch.Dimension{
+				{
+					Name:  aws.String("Project"),
+					Value: aws.String(project),
+				},
+			},
+			Unit:  aws.String(cloudwatch.StandardUnitCount),
+			Value: aws.Float64(1),
+		},
+	}
+	for _, service := range services {
+		data = append(data, &cloudwatch.MetricDatum{
+			MetricName: aws.String("Deployment"),
+			Dimensions: []*cloudwatch.Dimension{
+				{
+					Name:  aws.String("Project"),
+					Value: aws.String(project),
+				},
+				{
+					Name:  aws.String("Service"),
+					Value: aws.String(service),
+				},
+			},
+			Unit:  aws.String(cloudwatch.StandardUnitCount),
+			Value: aws.Float64(1),
+		})
+	}
+	_, err := s.CWM.PutMetricDataWithContext(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String("DockerCompose"),
+		MetricData: data,
+	})
+	return err
+}
+
+func (s sdk) Publish(ctx context.Context, topic string, subject string, message string) error {
+	logrus.Debug("Publish message to SNS topic ", topic)
+	_, err := s.SNS.PublishWithContext(ctx, &sns.PublishInput{
+		TopicArn: aws.String(topic),
+		Subject:  aws.String(subject),
+		Message:  aws.String(message),
+	})
+	return err
+}
+
+func (s sdk) GetCallerIdentity(ctx context.Context) (string, error) {
+	logrus.Debug("Retrieve caller identity")
+	identity, err := s.STS.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return *identity.Arn, nil
+}
+
+// SimulatePrincipalPolicy returns the actions the principal isn't allowed to run on resources
+func (s sdk) SimulatePrincipalPolicy(ctx context.Context, principal string, actions []string, resources []string) ([]string, error) {
+	logrus.Debugf("Simulate %s policy for %s", principal, strings.Join(actions, ","))
+	input := &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(principal),
+		ActionNames:     aws.StringSlice(actions),
+	}
+	if len(resources) > 0 {
+		input.ResourceArns = aws.StringSlice(resources)
+	}
+	denied := map[string]struct{}{}
+	err := s.IAM.SimulatePrincipalPolicyPagesWithContext(ctx, input, func(output *iam.SimulatePolicyResponse, last bool) bool {
+		for _, result := range output.EvaluationResults {
+			if aws.StringValue(result.EvalDecision) != iam.PolicyEvaluationDecisionTypeAllowed {
+				denied[aws.StringValue(result.EvalActionName)] = struct{}{}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	missing := []string{}
+	for _, action := range actions {
+		if _, ok := denied[action]; ok {
+			missing = append(missing, action)
+		}
+	}
+	return missing, nil
+}
+
+// CreateBucket creates S3 bucket in region, unless it already exists and belongs to the account
+func (s sdk) CreateBucket(ctx context.Context, bucket string, region string) error {
+	logrus.Debug("Create S3 bucket ", bucket)
+	input := &s3.CreateBucketInput{
+		Bucket: aws.String(bucket),
+	}
+	if region != "us-east-1" {
+		// us-east-1 is the default location, which can't be set as a constraint
+		input.CreateBucketConfiguration = &s3.CreateBucketConfiguration{
+			LocationConstraint: aws.String(region),
+		}
+	}
+	_, err := s.S3.CreateBucketWithContext(ctx, input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
+		return nil
+	}
+	return err
+}
+
+func (s sdk) PutObject(ctx context.Context, bucket string, key string, content []byte) error {
+	logrus.Debugf("Upload s3://%s/%s", bucket, key)
+	_, err := s.S3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader(content),
+		ServerSideEncryption: aws.String(s3.ServerSideEncryptionAes256),
+	})
+	return err
+}
+
+func projectTagFilter(project string) *resourcegroupstaggingapi.TagFilter {
+	filter := &resourcegroupstaggingapi.TagFilter{
+		Key: aws.String(compose.ProjectTag),
+	}
+	if project != "" {
+		filter.Values = aws.StringSlice([]string{project})
+	}
+	return filter
+}
+
+func hasProjectTag(tags map[string]string, project string) bool {
+	value, ok := tags[compose.ProjectTag]
+	return ok && (project == "" || value == project)
+}
+
+func (s sdk) GetTaggedResources(ctx context.Context, project string) ([]taggedResource, error) {
+	logrus.Debug("Retrieve resources tagged for project ", project)
+	var resources []taggedResource
+	err := s.RGT.GetResourcesPagesWithContext(ctx, &resourcegroupstaggingapi.GetResourcesInput{
+		TagFilters: []*resourcegroupstaggingapi.TagFilter{projectTagFilter(project)},
+	}, func(page *resourcegroupstaggingapi.GetResourcesOutput, lastPage bool) bool {
+		for _, r := range page.ResourceTagMappingList {
+			tags := map[string]string{}
+			for _, t := range r.Tags {
+				tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+			}
+			resources = append(resources, taggedResource{
+				ARN:  aws.StringValue(r.ResourceARN),
+				Tags: tags,
+			})
+		}
+		return true
+	})
+	return resources, err
+}
+
+func (s sdk) ListFileSystems(ctx context.Context, project string) ([]taggedResource, error) {
+	logrus.Debug("List EFS filesystems for project ", project)
+	var resources []taggedResource
+	err := s.EFS.DescribeFileSystemsPagesWithContext(ctx, &efs.DescribeFileSystemsInput{},
+		func(page *efs.DescribeFileSystemsOutput, lastPage bool) bool {
+			for _, fs := range page.FileSystems {
+				tags := map[string]string{}
+				for _, t := range fs.Tags {
+					tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+				}
+				if !hasProjectTag(tags, project) {
+					continue
+				}
+				resource := taggedResource{
+					ARN:     aws.StringValue(fs.FileSystemArn),
+					Tags:    tags,
+					Created: aws.TimeValue(fs.CreationTime),
+				}
+				if fs.SizeInBytes != nil {
+					resource.Size = aws.Int64Value(fs.SizeInBytes.Value)
+				}
+				resources = append(resources, resource)
+			}
+			return true
+		})
+	return resources, err
+}
+
+func (s sdk) ListRepositories(ctx context.Context, project string) ([]taggedResource, error) {
+	logrus.Debug("List ECR repositories for project ", project)
+	var repositories []*ecr.Repository
+	err := s.ECR.DescribeRepositoriesPagesWithContext(ctx, &ecr.DescribeRepositoriesInput{},
+		func(page *ecr.DescribeRepositoriesOutput, lastPage bool) bool {
+			repositories = append(repositories, page.Repositories...)
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	var resources []taggedResource
+	for _, repository := range repositories {
+		list, err := s.ECR.ListTagsForResourceWithContext(ctx, &ecr.ListTagsForResourceInput{
+			ResourceArn: repository.RepositoryArn,
+		})
+		if err != nil {
+			return nil, err
+		}
+		tags := map[string]string{}
+		for _, t := range list.Tags {
+			tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+		}
+		if !hasProjectTag(tags, project) {
+			continue
+		}
+		var size int64
+		err = s.ECR.DescribeImagesPagesWithContext(ctx, &ecr.DescribeImagesInput{
+			RepositoryName: repository.RepositoryName,
+		}, func(page *ecr.DescribeImagesOutput, lastPage bool) bool {
+			for _, image := range page.ImageDetails {
+				size += aws.Int64Value(image.ImageSizeInBytes)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, taggedResource{
+			ARN:     aws.StringValue(repository.RepositoryArn),
+			Tags:    tags,
+			Created: aws.TimeValue(repository.CreatedAt),
+			Size:    size,
+		})
+	}
+	return resources, nil
+}
+
+func (s sdk) GetSecretCreationDate(ctx context.Context, arn string) (time.Time, error) {
+	secret, err := s.SM.DescribeSecretWithContext(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(arn),
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return aws.TimeValue(secret.CreatedDate), nil
+}
+
+func (s sdk) DescribeObject(ctx context.Context, bucket string, key string) (time.Time, int64, error) {
+	head, err := s.S3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return aws.TimeValue(head.LastModified), aws.Int64Value(head.ContentLength), nil
+}
+
+func (s sdk) DeleteObject(ctx context.Context, bucket string, key string) error {
+	logrus.Debugf("Delete s3://%s/%s", bucket, key)
+	_, err := s.S3.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s sdk) FileSystemExists(ctx context.Context, id string) (bool, error) {
+	logrus.Debug("Check EFS file system exists: ", id)
+	_, err := s.EFS.DescribeFileSystemsWithContext(ctx, &efs.DescribeFileSystemsInput{
+		FileSystemId: aws.String(id),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == efs.ErrCodeFileSystemNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// fileSystemPollInterval is the delay between checks of an EFS file system state
+var fileSystemPollInterval = 5 * time.Second
+
+// WaitFileSystemAvailable waits for an EFS file system and its mount targets to be available, up to timeout
+func (s sdk) WaitFileSystemAvailable(ctx context.Context, id string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		resource, state, err := s.fileSystemState(ctx, id)
+		if err != nil {
+			return err
+		}
+		switch state {
+		case efs.LifeCycleStateAvailable:
+			return nil
+		case efs.LifeCycleStateDeleting, efs.LifeCycleStateDeleted:
+			return fmt.Errorf("EFS %s is %s", resource, state)
+		}
+		logrus.Infof("Waiting for EFS %s to be available, currently %s", resource, state)
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("EFS %s still %s after %s: %w", resource, state, timeout, ctx.Err())
+			}
+			return ctx.Err()
+		case <-time.After(fileSystemPollInterval):
+		}
+	}
+}
+
+// fileSystemState returns the lifecycle state of an EFS file system, or of the first of its mount targets which isn't available
+func (s sdk) fileSystemState(ctx context.Context, id string) (string, string, error) {
+	fs, err := s.EFS.DescribeFileSystemsWithContext(ctx, &efs.DescribeFileSystemsInput{
+		FileSystemId: aws.String(id),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if len(fs.FileSystems) == 0 {
+		return "", "", fmt.Errorf("EFS file system %s not found", id)
+	}
+	resource := "file system " + id
+	if state := aws.StringValue(fs.FileSystems[0].LifeCycleState); state != efs.LifeCycleStateAvailable {
+		return resource, state, nil
+	}
+	mounts, err := s.EFS.DescribeMountTargetsWithContext(ctx, &efs.DescribeMountTargetsInput{
+		FileSystemId: aws.String(id),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	for _, mount := range mounts.MountTargets {
+		if state := aws.StringValue(mount.LifeCycleState); state != efs.LifeCycleStateAvailable {
+			return "mount target " + aws.StringValue(mount.MountTargetId), state, nil
+		}
+	}
+	return resource, efs.LifeCycleStateAvailable, nil
+}
+
+// GetMountTargetZones returns the availability zones an EFS file system already has a mount target in
+func (s sdk) GetMountTargetZones(ctx context.Context, id string) ([]string, error) {
+	mounts, err := s.EFS.DescribeMountTargetsWithContext(ctx, &efs.DescribeMountTargetsInput{
+		FileSystemId: aws.String(id),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var zones []string
+	for _, mount := range mounts.MountTargets {
+		zones = append(zones, aws.StringValue(mount.AvailabilityZoneName))
+	}
+	return zones, nil
+}
+
+func (s sdk) DescribeFileSystem(ctx context.Context, id string) (fileSystemSettings, error) {
+	res, err := s.EFS.DescribeFileSystemsWithContext(ctx, &efs.DescribeFileSystemsInput{
+		FileSystemId: aws.String(id),
+	})
+	if err != nil {
+		return fileSystemSettings{}, err
+	}
+	if len(res.FileSystems) == 0 {
+		return fileSystemSettings{}, fmt.Errorf("EFS file system %s not found", id)
+	}
+	fs := res.FileSystems[0]
+	settings := fileSystemSettings{
+		ThroughputMode:        aws.StringValue(fs.ThroughputMode),
+		ProvisionedThroughput: aws.Float64Value(fs.ProvisionedThroughputInMibps),
+		PerformanceMode:       aws.StringValue(fs.PerformanceMode),
+	}
+	lifecycle, err := s.EFS.DescribeLifecycleConfigurationWithContext(ctx, &efs.DescribeLifecycleConfigurationInput{
+		FileSystemId: aws.String(id),
+	})
+	if err != nil {
+		return fileSystemSettings{}, err
+	}
+	for _, policy := range lifecycle.LifecyclePolicies {
+		settings.LifecyclePolicy = aws.StringValue(policy.TransitionToIA)
+	}
+	return settings, nil
+}
+
+// BackupFileSystem takes an on-demand AWS Backup of an EFS file system in vault, assuming role (a name or an ARN), and
+// waits for it to complete
+func (s sdk) BackupFileSystem(ctx context.Context, id string, vault string, role string) error {
+	logrus.Debug("Backup EFS filesystem ", id)
+	fs, err := s.EFS.DescribeFileSystemsWithContext(ctx, &efs.DescribeFileSystemsInput{
+		FileSystemId: aws.String(id),
+	})
+	if err != nil {
+		return err
+	}
+	if len(fs.FileSystems) == 0 {
+		return fmt.Errorf("EFS file system %s not found", id)
+	}
+	fsArn, err := arn.Parse(aws.StringValue(fs.FileSystems[0].FileSystemArn))
+	if err != nil {
+		return err
+	}
+	if !arn.IsARN(role) {
+		role = arn.ARN{
+			Partition: fsArn.Partition,
+			Service:   "iam",
+			AccountID: fsArn.AccountID,
+			Resource:  "role/" + role,
+		}.String()
+	}
+	job, err := s.BK.StartBackupJobWithContext(ctx, &backup.StartBackupJobInput{
+		BackupVaultName: aws.String(vault),
+		IamRoleArn:      aws.String(role),
+		ResourceArn:     aws.String(fsArn.String()),
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		status, err := s.BK.DescribeBackupJobWithContext(ctx, &backup.DescribeBackupJobInput{
+			BackupJobId: job.BackupJobId,
+		})
+		if err != nil {
+			return err
+		}
+		switch aws.StringValue(status.State) {
+		case backup.JobStateCompleted:
+			return nil
+		case backup.JobStateAborted, backup.JobStateFailed, backup.JobStateExpired:
+			return fmt.Errorf("backup of EFS file system %s %s: %s", id, strings.ToLower(aws.StringValue(status.State)), aws.StringValue(status.StatusMessage))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+func (s sdk) DeleteFileSystem(ctx context.Context, id string) error {
+	logrus.Debug("Delete EFS filesystem ", id)
+	targets, err := s.EFS.DescribeMountTargetsWithContext(ctx, &efs.DescribeMountTargetsInput{
+		FileSystemId: aws.String(id),
+	})
+	if err != nil {
+		return err
+	}
+	for _, target := range targets.MountTargets {
+		_, err = s.EFS.DeleteMountTargetWithContext(ctx, &efs.DeleteMountTargetInput{
+			MountTargetId: target.MountTargetId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// filesystem can't be deleted until mount targets are gone
+	for {
+		fs, err := s.EFS.DescribeFileSystemsWithContext(ctx, &efs.DescribeFileSystemsInput{
+			FileSystemId: aws.String(id),
+		})
+		if err != nil {
+			return err
+		}
+		if len(fs.FileSystems) == 0 || aws.Int64Value(fs.FileSystems[0].NumberOfMountTargets) == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+	_, err = s.EFS.DeleteFileSystemWithContext(ctx, &efs.DeleteFileSystemInput{
+		FileSystemId: aws.String(id),
+	})
+	return err
+}
+
+func (s sdk) DeleteRepository(ctx context.Context, name string) error {
+	logrus.Debug("Delete ECR repository ", name)
+	_, err := s.ECR.DeleteRepositoryWithContext(ctx, &ecr.DeleteRepositoryInput{
+		RepositoryName: aws.String(name),
+		Force:          aws.Bool(true),
+	})
+	return err
+}
+
+// EnsureRepository returns the URI of ECR repository name, which is created with project tag if it doesn't exist yet
+func (s sdk) EnsureRepository(ctx context.Context, project string, name string) (string, error) {
+	logrus.Debug("Ensure ECR repository ", name)
+	repositories, err := s.ECR.DescribeRepositoriesWithContext(ctx, &ecr.DescribeRepositoriesInput{
+		RepositoryNames: aws.StringSlice([]string{name}),
+	})
+	if err == nil && len(repositories.Repositories) > 0 {
+		return aws.StringValue(repositories.Repositories[0].RepositoryUri), nil
+	}
+	if aerr, ok := err.(awserr.Error); err != nil && (!ok || aerr.Code() != ecr.ErrCodeRepositoryNotFoundException) {
+		return "", err
+	}
+	logrus.Debug("Create ECR repository ", name)
+	created, err := s.ECR.CreateRepositoryWithContext(ctx, &ecr.CreateRepositoryInput{
+		RepositoryName: aws.String(name),
+		Tags: []*ecr.Tag{
+			{
+				Key:   aws.String(compose.ProjectTag),
+				Value: aws.String(project),
+			},
+			{
+				Key:   aws.String(sharedProjectTag),
+				Value: aws.String(project),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(created.Repository.RepositoryUri), nil
+}
+
+// GetImageDigest returns the digest of an image hosted by ECR in the current region
+func (s sdk) GetImageDigest(ctx context.Context, image ecrImage) (string, error) {
+	logrus.Debug("Describe ECR image ", image.ref)
+	images, err := s.ECR.DescribeImagesWithContext(ctx, &ecr.DescribeImagesInput{
+		RegistryId:     aws.String(image.registry),
+		RepositoryName: aws.String(image.repository),
+		ImageIds:       []*ecr.ImageIdentifier{image.identifier()},
+	})
+	if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == ecr.ErrCodeImageNotFoundException || aerr.Code() == ecr.ErrCodeRepositoryNotFoundException) {
+		return "", errImageNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(images.ImageDetails) == 0 {
+		return "", errImageNotFound
+	}
+	return aws.StringValue(images.ImageDetails[0].ImageDigest), nil
+}
+
+// GetSecretValue returns the current value of secret id
+func (s sdk) GetSecretValue(ctx context.Context, id string) (string, error) {
+	logrus.Debug("Retrieve secret value ", id)
+	secret, err := s.SM.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(secret.SecretString), nil
+}
+
+// GetRegistryAuth returns the encoded credentials for the Docker engine to push images to the account's ECR registry
+func (s sdk) GetRegistryAuth(ctx context.Context) (string, error) {
+	logrus.Debug("Retrieve ECR authorization token")
+	token, err := s.ECR.GetAuthorizationTokenWithContext(ctx, &ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return "", err
+	}
+	if len(token.AuthorizationData) == 0 {
+		return "", fmt.Errorf("no ECR authorization token returned")
+	}
+	data := token.AuthorizationData[0]
+	decoded, err := base64.StdEncoding.DecodeString(aws.StringValue(data.AuthorizationToken))
+	if err != nil {
+		return "", err
+	}
+	credentials := strings.SplitN(string(decoded), ":", 2)
+	if len(credentials) != 2 {
+		return "", fmt.Errorf("malformed ECR authorization token")
+	}
+	auth, err := json.Marshal(moby.AuthConfig{
+		Username:      credentials[0],
+		Password:      credentials[1],
+		ServerAddress: aws.StringValue(data.ProxyEndpoint),
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(auth), nil
+}
+
+func inventoryParameter(project string) string {
+	return fmt.Sprintf("%s%s", inventoryPath, project)
+}
+
+func (s sdk) GetInventories(ctx context.Context) (map[string][]string, error) {
+	logrus.Debug("Retrieve out-of-band resources inventories")
+	inventories := map[string][]string{}
+	var errs *multierror.Error
+	err := s.SSM.GetParametersByPathPagesWithContext(ctx, &ssm.GetParametersByPathInput{
+		Path: aws.String(inventoryPath),
+	}, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, p := range page.Parameters {
+			var arns []string
+			if err := json.Unmarshal([]byte(aws.StringValue(p.Value)), &arns); err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			inventories[strings.TrimPrefix(aws.StringValue(p.Name), inventoryPath)] = arns
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inventories, errs.ErrorOrNil()
+}
+
+func (s sdk) PutInventory(ctx context.Context, project string, arns []string) error {
+	logrus.Debug("Update out-of-band resources inventory for project ", project)
+	if len(arns) == 0 {
+		_, err := s.SSM.DeleteParameterWithContext(ctx, &ssm.DeleteParameterInput{
+			Name: aws.String(inventoryParameter(project)),
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+			return nil
+		}
+		return err
+	}
+	value, err := json.Marshal(arns)
+	if err != nil {
+		return err
+	}
+	_, err = s.SSM.PutParameterWithContext(ctx, &ssm.PutParameterInput{
+		Name:      aws.String(inventoryParameter(project)),
+		Type:      aws.String(ssm.ParameterTypeString),
+		Value:     aws.String(string(value)),
+		Overwrite: aws.Bool(true),
+	})
+	return err
+}
+
+// GetSecurityGroupReferences returns the security groups which rules reference one of groups
+func (s sdk) GetSecurityGroupReferences(ctx context.Context, groups []string) ([]securityGroupReference, error) {
+	logrus.Debug("Retrieve security groups referencing " + strings.Join(groups, ","))
+	referenced := map[string]bool{}
+	for _, g := range groups {
+		referenced[g] = true
+	}
+	var references []securityGroupReference
+	seen := map[string]bool{}
+	for _, filter := range []string{"ip-permission.group-id", "egress.ip-permission.group-id"} {
+		err := s.EC2.DescribeSecurityGroupsPagesWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+			Filters: []*ec2.Filter{
+				{
+					Name:   aws.String(filter),
+					Values: aws.StringSlice(groups),
+				},
+			},
+		}, func(page *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+			for _, sg := range page.SecurityGroups {
+				id := aws.StringValue(sg.GroupId)
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				reference := securityGroupReference{
+					GroupID: id,
+				}
+				for _, t := range sg.Tags {
+					if aws.StringValue(t.Key) == compose.ProjectTag {
+						reference.Project = aws.StringValue(t.Value)
+					}
+				}
+				refs := map[string]bool{}
+				for _, permission := range append(sg.IpPermissions, sg.IpPermissionsEgress...) {
+					for _, pair := range permission.UserIdGroupPairs {
+						g := aws.StringValue(pair.GroupId)
+						if referenced[g] && !refs[g] {
+							refs[g] = true
+							reference.References = append(reference.References, g)
+						}
+					}
+				}
+				references = append(references, reference)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return references, nil
+}
+
+// ListNamespaceServices returns the Cloud Map services registered in namespace
+func (s sdk) ListNamespaceServices(ctx context.Context, namespace string) ([]cloudMapService, error) {
+	logrus.Debug("List Cloud Map services in namespace " + namespace)
+	var services []cloudMapService
+	err := s.SD.ListServicesPagesWithContext(ctx, &servicediscovery.ListServicesInput{
+		Filters: []*servicediscovery.ServiceFilter{
+			{
+				Name:      aws.String(servicediscovery.ServiceFilterNameNamespaceId),
+				Condition: aws.String(servicediscovery.FilterConditionEq),
+				Values:    aws.StringSlice([]string{namespace}),
+			},
+		},
+	}, func(page *servicediscovery.ListServicesOutput, lastPage bool) bool {
+		for _, service := range page.Services {
+			services = append(services, cloudMapService{
+				ID:   aws.StringValue(service.Id),
+				ARN:  aws.StringValue(service.Arn),
+				Name: aws.StringValue(service.Name),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, service := range services {
+		tags, err := s.SD.ListTagsForResourceWithContext(ctx, &servicediscovery.ListTagsForResourceInput{
+			ResourceARN: aws.String(service.ARN),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tags.Tags {
+			if aws.StringValue(t.Key) == compose.ProjectTag {
+				services[i].Project = aws.StringValue(t.Value)
+			}
+		}
+	}
+	return services, nil
+}
+
+// ListInstanceIPs returns the IPv4 addresses of the instances registered for a Cloud Map service
+func (s sdk) ListInstanceIPs(ctx context.Context, service string) ([]string, error) {
+	logrus.Debug("List Cloud Map instances of service " + service)
+	var ips []string
+	err := s.SD.ListInstancesPagesWithContext(ctx, &servicediscovery.ListInstancesInput{
+		ServiceId: aws.String(service),
+	}, func(page *servicediscovery.ListInstancesOutput, lastPage bool) bool {
+		for _, instance := range page.Instances {
+			if ip, ok := instance.Attributes["AWS_INSTANCE_IPV4"]; ok {
+				ips = append(ips, aws.StringValue(ip))
+			}
+		}
+		return true
+	})
+	return ips, err
+}
+
+func (s sdk) GetImageScanFindings(ctx context.Context, image ecrImage) (imageScanResult, error) {
+	logrus.Debug("Get scan findings of image ", image.ref)
+	input := &ecr.DescribeImageScanFindingsInput{
+		RegistryId:     aws.String(image.registry),
+		RepositoryName: aws.String(image.repository),
+		ImageId:        image.identifier(),
+	}
+	_, err := s.ECR.DescribeImageScanFindingsWithContext(ctx, input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ecr.ErrCodeScanNotFoundException {
+		logrus.Debug("Start scan of image ", image.ref)
+		_, err = s.ECR.StartImageScanWithContext(ctx, &ecr.StartImageScanInput{
+			RegistryId:     input.RegistryId,
+			RepositoryName: input.RepositoryName,
+			ImageId:        input.ImageId,
+		})
+	}
+	if err != nil {
+		return imageScanResult{}, err
+	}
+	err = s.ECR.WaitUntilImageScanCompleteWithContext(ctx, input)
+	if err != nil {
+		return imageScanResult{}, fmt.Errorf("scan of image %s did not complete: %w", image.ref, err)
+	}
+
+	var result imageScanResult
+	err = s.ECR.DescribeImageScanFindingsPagesWithContext(ctx, input, func(page *ecr.DescribeImageScanFindingsOutput, lastPage bool) bool {
+		if page.ImageId != nil {
+			result.digest = aws.StringValue(page.ImageId.ImageDigest)
+		}
+		if page.ImageScanFindings == nil {
+			return true
+		}
+		for _, f := range page.ImageScanFindings.Findings {
+			result.findings = append(result.findings, imageScanFinding{
+				name:     aws.StringValue(f.Name),
+				severity: aws.StringValue(f.Severity),
+				uri:      aws.StringValue(f.Uri),
+			})
+		}
+		return true
+	})
+	return result, err
+}
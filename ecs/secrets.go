@@ -18,6 +18,13 @@ package ecs
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/compose-spec/compose-go/types"
 
 	"github.com/docker/compose-cli/api/secrets"
 )
@@ -37,3 +44,94 @@ func (b *ecsAPIService) ListSecrets(ctx context.Context) ([]secrets.Secret, erro
 func (b *ecsAPIService) DeleteSecret(ctx context.Context, id string, recover bool) error {
 	return b.SDK.DeleteSecret(ctx, id, recover)
 }
+
+// uploadSecrets creates or updates project's secrets in Secrets Manager, so their content doesn't get
+// embedded in the CloudFormation template. Returns the secrets ARNs, by name
+func (b *ecsAPIService) uploadSecrets(ctx context.Context, project *types.Project) (map[string]string, error) {
+	key, _, err := kmsKey(project)
+	if err != nil {
+		return nil, err
+	}
+	arns := map[string]string{}
+	for name, secret := range project.Secrets {
+		if secret.External.External {
+			continue
+		}
+		content, err := readSecretFile(name, secret)
+		if err != nil {
+			return nil, err
+		}
+		tags := map[string]string{}
+		for _, t := range projectTags(project) {
+			tags[t.Key] = t.Value
+		}
+		arn, err := b.SDK.PutSecret(ctx, secretName(project, name), content, key, tags)
+		if err != nil {
+			return nil, err
+		}
+		arns[name] = arn
+	}
+	return arns, nil
+}
+
+// readSecretFile reads the content of a file secret, which must be text: ECS only injects SecretString values in
+// containers
+func readSecretFile(name string, secret types.SecretConfig) ([]byte, error) {
+	content, err := ioutil.ReadFile(secret.File)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(content) {
+		return nil, fmt.Errorf("secret %s is binary, which ECS can't inject in containers, base64-encode its file", name)
+	}
+	return content, nil
+}
+
+// secretName is the name of the Secrets Manager secret storing content of a project's file secret
+func secretName(project *types.Project, name string) string {
+	return fmt.Sprintf("%s/%s", project.Name, name)
+}
+
+// secretPolicyResource returns the resource policies grant access to secret name by, given the reference tasks use.
+// IAM only accepts ARNs, secrets referenced by name, such as those up has yet to create, get an ARN pattern matching the
+// random suffix Secrets Manager appends to their name
+func secretPolicyResource(project *types.Project, name string, ref string) string {
+	if !referencedByName(project, name, ref) {
+		return ref
+	}
+	return cloudformation.Sub(fmt.Sprintf("arn:${AWS::Partition}:secretsmanager:${AWS::Region}:${AWS::AccountId}:secret:%s-*", ref))
+}
+
+// referencedByName tells if tasks reference secret name by its name rather than by an ARN
+func referencedByName(project *types.Project, name string, ref string) bool {
+	if strings.HasPrefix(ref, "arn:") {
+		return false
+	}
+	return project.Secrets[name].External.External || ref == secretName(project, name)
+}
+
+// secretRefs returns the references to project's file secrets. up creates them before the conversion, convert doesn't
+// create any: it references existing secrets by ARN, and others by name, as up will create them
+func (b *ecsAPIService) secretRefs(ctx context.Context, project *types.Project) (map[string]string, error) {
+	if b.secrets != nil {
+		return b.secrets, nil
+	}
+	refs := map[string]string{}
+	for name, secret := range project.Secrets {
+		if secret.External.External {
+			continue
+		}
+		if _, err := readSecretFile(name, secret); err != nil {
+			return nil, err
+		}
+		arn, err := b.SDK.SecretArn(ctx, secretName(project, name))
+		if err != nil {
+			return nil, err
+		}
+		if arn == "" {
+			arn = secretName(project, name)
+		}
+		refs[name] = arn
+	}
+	return refs, nil
+}
@@ -28,6 +28,8 @@ import (
 type Secret struct {
 	Name string
 	Keys []string
+	// Path is the file to create, relative to secrets folder. Defaults to Name
+	Path string `json:",omitempty"`
 }
 
 // CreateSecretFiles retrieve sensitive data from env and store as plain text a a file in path
@@ -38,10 +40,17 @@ func CreateSecretFiles(secret Secret, path string) error {
 	}
 
 	secrets := filepath.Join(path, secret.Name)
+	if secret.Path != "" {
+		secrets = filepath.Join(path, secret.Path)
+	}
 
 	if len(secret.Keys) == 0 {
 		// raw Secret
 		fmt.Printf("inject Secret %q info %s\n", secret.Name, secrets)
+		err := os.MkdirAll(filepath.Dir(secrets), 0755)
+		if err != nil {
+			return err
+		}
 		return ioutil.WriteFile(secrets, []byte(value), 0444)
 	}
 
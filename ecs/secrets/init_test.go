@@ -43,6 +43,22 @@ func TestRawSecret(t *testing.T) {
 	assert.Equal(t, content, "something_secret")
 }
 
+func TestRawSecretWithPath(t *testing.T) {
+	dir := fs.NewDir(t, "secrets").Path()
+	err := os.Setenv("raw", "some_config")
+	assert.NilError(t, err)
+	defer os.Unsetenv("raw") // nolint:errcheck
+
+	err = CreateSecretFiles(Secret{
+		Name: "raw",
+		Path: "configs-0/app.conf",
+	}, dir)
+	assert.NilError(t, err)
+	file, err := ioutil.ReadFile(filepath.Join(dir, "configs-0", "app.conf"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(file), "some_config")
+}
+
 func TestSelectedKeysSecret(t *testing.T) {
 	dir := fs.NewDir(t, "secrets").Path()
 	err := os.Setenv("json", `
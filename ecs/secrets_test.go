@@ -0,0 +1,133 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"gotest.tools/v3/assert"
+)
+
+func (m *mockSecretsManager) DescribeSecretWithContext(_ aws.Context, in *secretsmanager.DescribeSecretInput, _ ...request.Option) (*secretsmanager.DescribeSecretOutput, error) {
+	args := m.Called(aws.StringValue(in.SecretId))
+	return args.Get(0).(*secretsmanager.DescribeSecretOutput), args.Error(1)
+}
+
+func TestConvertReferencesSecretsWithoutCreatingThem(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - db
+      - api
+      - token
+secrets:
+  db:
+    file: ./testdata/input/envfile
+  api:
+    file: ./testdata/input/envfile
+  token:
+    external: true
+    name: arn:aws:secretsmanager:eu-west-3:123456789012:secret:token
+`)
+	sm := &mockSecretsManager{}
+	sm.On("DescribeSecretWithContext", "Test/db").Return(&secretsmanager.DescribeSecretOutput{
+		ARN: aws.String("arn:aws:secretsmanager:eu-west-3:123456789012:secret:Test/db-AbCdEf"),
+	}, nil)
+	sm.On("DescribeSecretWithContext", "Test/api").Return((*secretsmanager.DescribeSecretOutput)(nil),
+		awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil))
+	backend := &ecsAPIService{SDK: sdk{SM: sm}}
+
+	refs, err := backend.secretRefs(context.TODO(), project)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, refs, map[string]string{
+		"db":  "arn:aws:secretsmanager:eu-west-3:123456789012:secret:Test/db-AbCdEf",
+		"api": "Test/api",
+	})
+	sm.AssertExpectations(t)
+}
+
+func TestUpCreatedSecretsAreReferenced(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - db
+secrets:
+  db:
+    file: ./testdata/input/envfile
+`)
+	created := map[string]string{"db": "arn:aws:secretsmanager:eu-west-3:123456789012:secret:Test/db-AbCdEf"}
+	backend := &ecsAPIService{SDK: sdk{SM: &mockSecretsManager{}}, secrets: created}
+
+	refs, err := backend.secretRefs(context.TODO(), project)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, refs, created)
+}
+
+func TestBinarySecretRejected(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - cert
+secrets:
+  cert:
+    file: ./testdata/input/binary
+`)
+	backend := &ecsAPIService{SDK: sdk{SM: &mockSecretsManager{}}}
+	_, err := backend.secretRefs(context.TODO(), project)
+	assert.Error(t, err, "secret cert is binary, which ECS can't inject in containers, base64-encode its file")
+	_, err = backend.uploadSecrets(context.TODO(), project)
+	assert.ErrorContains(t, err, "secret cert is binary")
+}
+
+func TestSecretsReferencedByName(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+    secrets:
+      - api
+secrets:
+  api:
+    file: ./testdata/input/envfile
+`)
+	backend := &ecsAPIService{}
+	// api is yet to be created by up, the policy matches its ARN
+	template, err := backend.convert(project, awsResources{secrets: map[string]string{"api": "Test/api"}})
+	assert.NilError(t, err)
+	role := template.Resources["TestTaskExecutionRole"].(*iam.Role)
+	policy := role.Policies[0].PolicyDocument.(*PolicyDocument)
+	assert.DeepEqual(t, policy.Statement[0].Resource, []string{
+		cloudformation.Sub("arn:${AWS::Partition}:secretsmanager:${AWS::Region}:${AWS::AccountId}:secret:Test/api-*"),
+	})
+
+	project.Services[0].Secrets[0].Extensions = map[string]interface{}{extensionKeys: "password"}
+	_, err = backend.convert(project, awsResources{secrets: map[string]string{"api": "Test/api"}})
+	assert.ErrorContains(t, err, "x-aws-keys of secret api requires its ARN, which Test/api isn't")
+}
@@ -0,0 +1,107 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// serviceConnect tells if x-aws-service_connect makes services reach each other by ECS Service Connect proxies, instead
+// of Cloud Map DNS records. This is a project setting, as both discovery mechanisms can't be mixed
+func serviceConnect(project *types.Project) (bool, error) {
+	enabled := project.Extensions[extensionServiceConnect] == true
+	for _, service := range project.Services {
+		if x, ok := service.Extensions[extensionServiceConnect]; ok && (x == true) != enabled {
+			return false, serviceError(service.Name, fmt.Errorf("%s must be set on the project: Service Connect clients "+
+				"only resolve Service Connect endpoints, so services using Cloud Map DNS records and Service Connect can't reach each other",
+				extensionServiceConnect))
+		}
+	}
+	return enabled, nil
+}
+
+// serviceConnectPortName is the name of the port mapping Service Connect exposes port by
+func serviceConnectPortName(service types.ServiceConfig, port types.ServicePortConfig) string {
+	return fmt.Sprintf("%s-%d", service.Name, port.Target)
+}
+
+// applyServiceConnect sets ServiceConnectConfiguration on services, exposing each port under the service name, and names
+// the port mappings it relies on. goformation doesn't support these properties, so they are set on the marshalled template
+func applyServiceConnect(project *types.Project, raw []byte) ([]byte, error) {
+	connect, err := serviceConnect(project)
+	if err != nil || !connect {
+		return raw, err
+	}
+
+	var template map[string]interface{}
+	if err := json.Unmarshal(raw, &template); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %s", err)
+	}
+	resources := template["Resources"].(map[string]interface{})
+	for _, service := range project.Services {
+		if _, ok := sidecarOf(service); ok || isScheduled(service) || externalTask(project, service) {
+			continue
+		}
+		definition := resources[fmt.Sprintf("%sTaskDefinition", normalizeResourceName(service.Name))].(map[string]interface{})
+		containers := definition["Properties"].(map[string]interface{})["ContainerDefinitions"].([]interface{})
+
+		var endpoints []interface{}
+		for _, member := range taskServices(project, service) {
+			for _, port := range member.Ports {
+				name := serviceConnectPortName(member, port)
+				for _, c := range containers {
+					container := c.(map[string]interface{})
+					if container["Name"] != member.Name {
+						continue
+					}
+					mappings, _ := container["PortMappings"].([]interface{})
+					for _, m := range mappings {
+						mapping := m.(map[string]interface{})
+						if mapping["ContainerPort"] == float64(port.Target) {
+							mapping["Name"] = name
+						}
+					}
+				}
+				endpoints = append(endpoints, map[string]interface{}{
+					"PortName":      name,
+					"DiscoveryName": name,
+					"ClientAliases": []interface{}{
+						map[string]interface{}{
+							"DnsName": service.Name,
+							"Port":    port.Published,
+						},
+					},
+				})
+			}
+		}
+
+		// services without ports still get Service Connect enabled, as clients of the other ones
+		configuration := map[string]interface{}{
+			"Enabled":   true,
+			"Namespace": map[string]interface{}{"Fn::GetAtt": []interface{}{"CloudMap", "Arn"}},
+		}
+		if len(endpoints) > 0 {
+			configuration["Services"] = endpoints
+		}
+		properties := resources[serviceResourceName(service.Name)].(map[string]interface{})["Properties"].(map[string]interface{})
+		properties["ServiceConnectConfiguration"] = configuration
+	}
+	return json.MarshalIndent(sortArrays(template), "", "  ")
+}
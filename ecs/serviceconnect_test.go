@@ -0,0 +1,116 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/servicediscovery"
+	"gotest.tools/v3/assert"
+)
+
+const serviceConnectProject = `
+services:
+  front:
+    image: nginx
+    ports:
+      - 80:80
+  proxy:
+    image: envoyproxy/envoy
+    ports:
+      - 9901:9901
+    x-aws-sidecar_of: front
+  worker:
+    image: app
+x-aws-service_connect: true
+`
+
+func TestServiceConnectNamespace(t *testing.T) {
+	template := convertYaml(t, serviceConnectProject)
+	namespace, ok := template.Resources["CloudMap"].(*servicediscovery.HttpNamespace)
+	assert.Check(t, ok)
+	assert.Equal(t, namespace.Name, "Test.local")
+	_, ok = template.Resources["FrontServiceDiscoveryEntry"]
+	assert.Check(t, !ok)
+	assert.Equal(t, len(template.Resources["FrontService"].(*ecs.Service).ServiceRegistries), 0)
+}
+
+func TestApplyServiceConnect(t *testing.T) {
+	project := loadConfig(t, serviceConnectProject)
+	template, err := (&ecsAPIService{}).convert(project, awsResources{})
+	assert.NilError(t, err)
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	raw, err = applyServiceConnect(project, raw)
+	assert.NilError(t, err)
+
+	var marshalled struct {
+		Resources map[string]struct {
+			Properties struct {
+				ServiceConnectConfiguration map[string]interface{}
+				ContainerDefinitions        []struct {
+					Name         string
+					PortMappings []map[string]interface{}
+				}
+			}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &marshalled))
+	namespace := map[string]interface{}{"Fn::GetAtt": []interface{}{"CloudMap", "Arn"}}
+	assert.DeepEqual(t, marshalled.Resources["FrontService"].Properties.ServiceConnectConfiguration, map[string]interface{}{
+		"Enabled":   true,
+		"Namespace": namespace,
+		"Services": []interface{}{
+			map[string]interface{}{
+				"PortName":      "front-80",
+				"DiscoveryName": "front-80",
+				"ClientAliases": []interface{}{map[string]interface{}{"DnsName": "front", "Port": float64(80)}},
+			},
+			map[string]interface{}{
+				"PortName":      "proxy-9901",
+				"DiscoveryName": "proxy-9901",
+				"ClientAliases": []interface{}{map[string]interface{}{"DnsName": "front", "Port": float64(9901)}},
+			},
+		},
+	})
+	assert.DeepEqual(t, marshalled.Resources["WorkerService"].Properties.ServiceConnectConfiguration, map[string]interface{}{
+		"Enabled":   true,
+		"Namespace": namespace,
+	})
+
+	names := map[string]interface{}{}
+	for _, container := range marshalled.Resources["FrontTaskDefinition"].Properties.ContainerDefinitions {
+		for _, mapping := range container.PortMappings {
+			names[container.Name] = mapping["Name"]
+		}
+	}
+	assert.DeepEqual(t, names, map[string]interface{}{"front": "front-80", "proxy": "proxy-9901"})
+}
+
+func TestServiceConnectMixedMode(t *testing.T) {
+	_, err := (&ecsAPIService{}).convert(loadConfig(t, `
+services:
+  front:
+    image: nginx
+    x-aws-service_connect: true
+  back:
+    image: app
+`), awsResources{})
+	assert.ErrorContains(t, err, "x-aws-service_connect must be set on the project")
+}
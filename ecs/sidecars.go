@@ -0,0 +1,165 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// sidecarOf returns the service set by x-aws-sidecar_of, which task runs service as an additional container
+func sidecarOf(service types.ServiceConfig) (string, bool) {
+	x, ok := service.Extensions[extensionSidecarOf]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(x), true
+}
+
+// taskOwner returns the name of the service which task runs the named service
+func taskOwner(project *types.Project, name string) string {
+	service, err := project.GetService(name)
+	if err != nil {
+		return name
+	}
+	if owner, ok := sidecarOf(service); ok {
+		return owner
+	}
+	return name
+}
+
+// taskServices returns the services running in service's task, starting with service itself
+func taskServices(project *types.Project, service types.ServiceConfig) []types.ServiceConfig {
+	services := []types.ServiceConfig{service}
+	for _, s := range project.Services {
+		if owner, ok := sidecarOf(s); ok && owner == service.Name {
+			services = append(services, s)
+		}
+	}
+	return services
+}
+
+func taskRequiresEC2(services []types.ServiceConfig) bool {
+	for _, s := range services {
+		if requireEC2(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSidecars validates sidecars can share their owner's task, which sets replicas and network interface
+func checkSidecars(project *types.Project) error {
+	for _, service := range project.Services {
+		owner, ok := sidecarOf(service)
+		if !ok {
+			continue
+		}
+		target, err := project.GetService(owner)
+		if err != nil {
+			return fmt.Errorf("service %s: %s %s doesn't exist", service.Name, extensionSidecarOf, owner)
+		}
+		if _, ok := sidecarOf(target); ok {
+			return fmt.Errorf("service %s can't be a sidecar of %s, which is itself a sidecar", service.Name, owner)
+		}
+		if service.Deploy != nil && service.Deploy.Replicas != nil {
+			return fmt.Errorf("service %s: sidecar runs as many replicas as %s, and can't set deploy.replicas", service.Name, owner)
+		}
+		for net := range service.Networks {
+			if _, ok := target.Networks[net]; !ok {
+				return fmt.Errorf("service %s: sidecar shares %s network interface, and can't join network %s", service.Name, owner, net)
+			}
+		}
+	}
+	return nil
+}
+
+// renameSidecarVolumes prefixes the name of task local volumes used by a sidecar, so they don't conflict with the ones of other
+// containers in the task. EFS volumes are named after the compose volume, and shared
+func renameSidecarVolumes(service types.ServiceConfig, containers []ecs.TaskDefinition_ContainerDefinition, volumes []ecs.TaskDefinition_Volume) {
+	renamed := map[string]string{}
+	for i, v := range volumes {
+		if v.EFSVolumeConfiguration != nil || v.DockerVolumeConfiguration != nil || v.Host != nil {
+			continue
+		}
+		name := fmt.Sprintf("%s_%s", normalizeResourceName(service.Name), v.Name)
+		renamed[v.Name] = name
+		volumes[i].Name = name
+	}
+	for i := range containers {
+		for j, m := range containers[i].MountPoints {
+			if name, ok := renamed[m.SourceVolume]; ok {
+				containers[i].MountPoints[j].SourceVolume = name
+			}
+		}
+	}
+}
+
+func mergeVolumes(volumes []ecs.TaskDefinition_Volume, others []ecs.TaskDefinition_Volume) []ecs.TaskDefinition_Volume {
+	for _, o := range others {
+		var found bool
+		for _, v := range volumes {
+			if v.Name == o.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			volumes = append(volumes, o)
+		}
+	}
+	return volumes
+}
+
+// setSidecarDependencies maps depends_on between containers of a task to ECS container dependencies
+func setSidecarDependencies(services []types.ServiceConfig, containers []ecs.TaskDefinition_ContainerDefinition) error {
+	inTask := map[string]types.ServiceConfig{}
+	for _, s := range services {
+		inTask[s.Name] = s
+	}
+	for _, s := range services {
+		for _, name := range sortedDependencies(s) {
+			upstream, ok := inTask[name]
+			if !ok {
+				continue
+			}
+			condition := ecsapi.ContainerConditionStart
+			if s.DependsOn[name].Condition == types.ServiceConditionHealthy {
+				healthCheck, err := toHealthCheck(upstream)
+				if err != nil {
+					return err
+				}
+				if healthCheck == nil {
+					return fmt.Errorf("service %s depends on %s being healthy, but %s has no healthcheck", s.Name, name, name)
+				}
+				condition = ecsapi.ContainerConditionHealthy
+			}
+			for i, c := range containers {
+				if c.Name == s.Name {
+					containers[i].DependsOnProp = append(containers[i].DependsOnProp, ecs.TaskDefinition_ContainerDependency{
+						Condition:     condition,
+						ContainerName: name,
+					})
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+func TestSidecarMergedIntoTask(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  app:
+    image: app
+    ports:
+      - 80:80
+    volumes:
+      - data:/data
+    deploy:
+      resources:
+        limits:
+          cpus: '0.25'
+          memory: 512M
+  envoy:
+    image: envoyproxy/envoy
+    x-aws-sidecar_of: app
+    ports:
+      - 9901:9901
+    volumes:
+      - data:/var/log/envoy
+    secrets:
+      - envoy_config
+    deploy:
+      resources:
+        limits:
+          cpus: '0.25'
+          memory: 512M
+volumes:
+  data:
+secrets:
+  envoy_config:
+    name: arn:aws:secretsmanager:eu-west-3:123456789012:secret:envoy
+    external: true
+`)
+	assert.Check(t, template.Resources["EnvoyService"] == nil)
+	assert.Check(t, template.Resources["EnvoyTaskDefinition"] == nil)
+
+	def := template.Resources["AppTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, def.Cpu, "512")
+	assert.Equal(t, def.Memory, "1024")
+	var names []string
+	for _, c := range def.ContainerDefinitions {
+		names = append(names, c.Name)
+		if c.Name == "envoy" {
+			assert.DeepEqual(t, c.MountPoints, []ecs.TaskDefinition_MountPoint{
+				{ContainerPath: "/run/secrets/", ReadOnly: true, SourceVolume: "Envoy_secrets"},
+				{ContainerPath: "/var/log/envoy", SourceVolume: "data"},
+			})
+		}
+	}
+	assert.DeepEqual(t, names, []string{"App_ResolvConf_InitContainer", "app", "Envoy_Secrets_InitContainer", "Envoy_ResolvConf_InitContainer", "envoy"})
+	var volumes []string
+	for _, v := range def.Volumes {
+		volumes = append(volumes, v.Name)
+	}
+	assert.DeepEqual(t, volumes, []string{"data", "Envoy_secrets"})
+
+	service := template.Resources["AppService"].(*ecs.Service)
+	assert.Equal(t, len(service.LoadBalancers), 2)
+	assert.Equal(t, service.LoadBalancers[1].ContainerName, "envoy")
+	assert.Equal(t, service.LoadBalancers[1].ContainerPort, 9901)
+	assert.Equal(t, len(service.ServiceRegistries), 1)
+}
+
+func TestSidecarDependsOn(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  app:
+    image: app
+    depends_on:
+      envoy:
+        condition: service_healthy
+      db:
+        condition: service_started
+  envoy:
+    image: envoyproxy/envoy
+    x-aws-sidecar_of: app
+    depends_on:
+      - db
+    healthcheck:
+      test: ["CMD", "curl", "localhost:9901/ready"]
+  db:
+    image: mysql
+`)
+	def := template.Resources["AppTaskDefinition"].(*ecs.TaskDefinition)
+	for _, c := range def.ContainerDefinitions {
+		if c.Name == "app" {
+			assert.DeepEqual(t, c.DependsOnProp, []ecs.TaskDefinition_ContainerDependency{
+				{Condition: "SUCCESS", ContainerName: "App_ResolvConf_InitContainer"},
+				{Condition: "HEALTHY", ContainerName: "envoy"},
+			})
+		}
+	}
+	service := template.Resources["AppService"].(*ecs.Service)
+	assert.DeepEqual(t, service.AWSCloudFormationDependsOn, []string{"DbService"})
+}
+
+func TestSidecarErrors(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  envoy:
+    image: envoyproxy/envoy
+    x-aws-sidecar_of: app
+`: "service envoy: x-aws-sidecar_of app doesn't exist",
+		`
+services:
+  app:
+    image: app
+  envoy:
+    image: envoyproxy/envoy
+    x-aws-sidecar_of: app
+    deploy:
+      replicas: 2
+`: "service envoy: sidecar runs as many replicas as app, and can't set deploy.replicas",
+		`
+services:
+  app:
+    image: app
+    networks:
+      - front
+  envoy:
+    image: envoyproxy/envoy
+    x-aws-sidecar_of: app
+    networks:
+      - back
+networks:
+  front:
+  back:
+`: "service envoy: sidecar shares app network interface, and can't join network back",
+		`
+services:
+  app:
+    image: app
+    depends_on:
+      envoy:
+        condition: service_healthy
+  envoy:
+    image: envoyproxy/envoy
+    x-aws-sidecar_of: app
+`: "service app depends on envoy being healthy, but envoy has no healthcheck",
+	} {
+		_, err := (&ecsAPIService{}).convert(loadConfig(t, yaml), awsResources{})
+		assert.Error(t, err, expected)
+	}
+}
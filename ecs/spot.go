@@ -0,0 +1,143 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/autoscaling"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// spotAllocationStrategy launches spot instances from the pools with the most spare capacity, which are the least likely
+// to be interrupted
+const spotAllocationStrategy = "capacity-optimized"
+
+// spotCapacity is set by x-aws-ec2_spot, mixing on-demand and spot instances in the Auto Scaling group
+type spotCapacity struct {
+	onDemandBase   int
+	spotPercentage int
+	instanceTypes  []string
+}
+
+func ec2Spot(project *types.Project) (*spotCapacity, error) {
+	x, ok := project.Extensions[extensionEC2Spot]
+	if !ok {
+		return nil, nil
+	}
+	m, ok := x.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a mapping of on_demand_base, spot_percentage and instance_types", extensionEC2Spot)
+	}
+	spot := &spotCapacity{spotPercentage: 100}
+	for key, value := range m {
+		switch key {
+		case "on_demand_base", "spot_percentage":
+			i, ok := value.(int)
+			if !ok || i < 0 {
+				return nil, fmt.Errorf("%s %s must be a positive integer", extensionEC2Spot, key)
+			}
+			if key == "on_demand_base" {
+				spot.onDemandBase = i
+				continue
+			}
+			if i > 100 {
+				return nil, fmt.Errorf("%s spot_percentage must be between 0 and 100, got %d", extensionEC2Spot, i)
+			}
+			spot.spotPercentage = i
+		case "instance_types":
+			list, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s instance_types must be a list of instance types", extensionEC2Spot)
+			}
+			seen := map[string]bool{}
+			for _, item := range list {
+				instanceType := fmt.Sprint(item)
+				if seen[instanceType] {
+					return nil, fmt.Errorf("%s instance type %s is listed more than once", extensionEC2Spot, instanceType)
+				}
+				seen[instanceType] = true
+				spot.instanceTypes = append(spot.instanceTypes, instanceType)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported %s attribute %s", extensionEC2Spot, key)
+		}
+	}
+	if len(spot.instanceTypes) < 2 {
+		return nil, fmt.Errorf("%s requires at least 2 instance_types, so spot capacity can be diversified", extensionEC2Spot)
+	}
+	return spot, nil
+}
+
+// mixedInstancesPolicy launches the instance types from launchTemplate, with the configured share of spot instances
+func (s *spotCapacity) mixedInstancesPolicy(launchTemplate string) *autoscaling.AutoScalingGroup_MixedInstancesPolicy {
+	var overrides []autoscaling.AutoScalingGroup_LaunchTemplateOverrides
+	for _, instanceType := range s.instanceTypes {
+		overrides = append(overrides, autoscaling.AutoScalingGroup_LaunchTemplateOverrides{
+			InstanceType: instanceType,
+		})
+	}
+	return &autoscaling.AutoScalingGroup_MixedInstancesPolicy{
+		InstancesDistribution: &autoscaling.AutoScalingGroup_InstancesDistribution{
+			OnDemandBaseCapacity:                s.onDemandBase,
+			OnDemandPercentageAboveBaseCapacity: 100 - s.spotPercentage,
+			SpotAllocationStrategy:              spotAllocationStrategy,
+		},
+		LaunchTemplate: &autoscaling.AutoScalingGroup_LaunchTemplate{
+			LaunchTemplateSpecification: &autoscaling.AutoScalingGroup_LaunchTemplateSpecification{
+				LaunchTemplateId: cloudformation.Ref(launchTemplate),
+				Version:          cloudformation.GetAtt(launchTemplate, "LatestVersionNumber"),
+			},
+			Overrides: overrides,
+		},
+	}
+}
+
+// spotAutoScalingGroup is an AutoScalingGroup with capacity rebalance enabled, not supported by goformation, so spot
+// instances at elevated risk of interruption get replaced proactively
+type spotAutoScalingGroup struct {
+	autoscaling.AutoScalingGroup
+}
+
+func (r spotAutoScalingGroup) MarshalJSON() ([]byte, error) {
+	return marshalResource(r.AutoScalingGroup, func(properties map[string]interface{}) {
+		properties["CapacityRebalance"] = true
+		// goformation omits a 0 on-demand percentage, which CloudFormation then defaults to 100, so set it explicitly
+		if policy := r.MixedInstancesPolicy; policy != nil && policy.InstancesDistribution != nil {
+			if mixed, ok := properties["MixedInstancesPolicy"].(map[string]interface{}); ok {
+				if distribution, ok := mixed["InstancesDistribution"].(map[string]interface{}); ok {
+					distribution["OnDemandPercentageAboveBaseCapacity"] = policy.InstancesDistribution.OnDemandPercentageAboveBaseCapacity
+				}
+			}
+		}
+	})
+}
+
+// checkSpotInstanceTypes fails if an instance type listed by x-aws-ec2_spot doesn't meet project's requirements
+func (b *ecsAPIService) checkSpotInstanceTypes(ctx context.Context, project *types.Project, spot *spotCapacity) error {
+	requirements, err := getResourceRequirements(project)
+	if err != nil {
+		return err
+	}
+	machines, err := b.SDK.DescribeInstanceTypes(ctx, spot.instanceTypes)
+	if err != nil {
+		return err
+	}
+	return checkMachines(extensionEC2Spot, machines, requirements)
+}
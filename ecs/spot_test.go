@@ -0,0 +1,193 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	ec2api "github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/autoscaling"
+	"github.com/awslabs/goformation/v4/cloudformation/ec2"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/policies"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func spotBackend() *ecsAPIService {
+	ssmMock := &mockSSM{}
+	ssmMock.On("GetParameterWithContext", "/aws/service/ecs/optimized-ami/amazon-linux-2/recommended").Return(&ssm.GetParameterOutput{
+		Parameter: &ssm.Parameter{Value: aws.String(`{"image_id": "ami-123456"}`)},
+	}, nil)
+	ec2Mock := &mockEC2{}
+	ec2Mock.On("DescribeInstanceTypesWithContext", "m5.large").Return(&ec2api.DescribeInstanceTypesOutput{
+		InstanceTypes: []*ec2api.InstanceTypeInfo{
+			{
+				InstanceType: aws.String("m5.large"),
+				VCpuInfo:     &ec2api.VCpuInfo{DefaultVCpus: aws.Int64(2)},
+				MemoryInfo:   &ec2api.MemoryInfo{SizeInMiB: aws.Int64(8192)},
+			},
+			{
+				InstanceType: aws.String("m5a.large"),
+				VCpuInfo:     &ec2api.VCpuInfo{DefaultVCpus: aws.Int64(2)},
+				MemoryInfo:   &ec2api.MemoryInfo{SizeInMiB: aws.Int64(8192)},
+			},
+		},
+	}, nil)
+	return &ecsAPIService{SDK: sdk{SSM: ssmMock, EC2: ec2Mock}}
+}
+
+func TestSpotCapacity(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  worker:
+    image: hello_world
+    shm_size: 64M
+x-aws-ec2_spot:
+  on_demand_base: 1
+  spot_percentage: 80
+  instance_types:
+    - m5.large
+    - m5a.large
+`)
+	template := cloudformation.NewTemplate()
+	template.Resources["Cluster"] = &ecs.Cluster{}
+	err := spotBackend().createCapacityProvider(context.TODO(), project, template, awsResources{
+		cluster: cloudformation.Ref("Cluster"),
+		subnets: []string{"subnet1", "subnet2"},
+	})
+	assert.NilError(t, err)
+	_, ok := template.Resources["LaunchConfiguration"]
+	assert.Check(t, !ok)
+
+	launchTemplate := template.Resources["LaunchTemplate"].(*ec2.LaunchTemplate)
+	assert.Equal(t, launchTemplate.LaunchTemplateData.InstanceType, "m5.large")
+	userData, err := base64.StdEncoding.DecodeString(launchTemplate.LaunchTemplateData.UserData)
+	assert.NilError(t, err)
+	assert.Check(t, cmp.Contains(string(userData), "echo ECS_ENABLE_SPOT_INSTANCE_DRAINING=true >> /etc/ecs/ecs.config"))
+
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	var parsed struct {
+		Resources map[string]struct {
+			Properties struct {
+				CapacityRebalance    bool
+				MixedInstancesPolicy struct {
+					InstancesDistribution map[string]interface{}
+					LaunchTemplate        struct {
+						Overrides []map[string]interface{}
+					}
+				}
+			}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &parsed))
+	group := parsed.Resources["AutoscalingGroup"].Properties
+	assert.Check(t, group.CapacityRebalance)
+	assert.DeepEqual(t, group.MixedInstancesPolicy.InstancesDistribution, map[string]interface{}{
+		"OnDemandBaseCapacity":                float64(1),
+		"OnDemandPercentageAboveBaseCapacity": float64(20),
+		"SpotAllocationStrategy":              "capacity-optimized",
+	})
+	assert.DeepEqual(t, group.MixedInstancesPolicy.LaunchTemplate.Overrides, []map[string]interface{}{
+		{"InstanceType": "m5.large"},
+		{"InstanceType": "m5a.large"},
+	})
+}
+
+func TestSpotOnly(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  worker:
+    image: hello_world
+    shm_size: 64M
+x-aws-ec2_spot:
+  instance_types:
+    - m5.large
+    - m5a.large
+`)
+	template := cloudformation.NewTemplate()
+	template.Resources["Cluster"] = &ecs.Cluster{}
+	err := spotBackend().createCapacityProvider(context.TODO(), project, template, awsResources{
+		cluster: cloudformation.Ref("Cluster"),
+	})
+	assert.NilError(t, err)
+
+	raw, err := marshall(template)
+	assert.NilError(t, err)
+	assert.Check(t, cmp.Contains(string(raw), `"OnDemandPercentageAboveBaseCapacity": 0`))
+}
+
+func TestSpotAutoScalingGroupMarshalledAttributes(t *testing.T) {
+	raw, err := json.Marshal(spotAutoScalingGroup{
+		AutoScalingGroup: autoscaling.AutoScalingGroup{
+			MaxSize:                         "10",
+			MinSize:                         "0",
+			AWSCloudFormationDependsOn:      []string{"LaunchTemplate"},
+			AWSCloudFormationDeletionPolicy: "Retain",
+			AWSCloudFormationUpdatePolicy: &policies.UpdatePolicy{
+				AutoScalingReplacingUpdate: &policies.AutoScalingReplacingUpdate{WillReplace: true},
+			},
+		},
+	})
+	assert.NilError(t, err)
+	var parsed struct {
+		DependsOn      []string
+		DeletionPolicy string
+		UpdatePolicy   map[string]interface{}
+		Properties     map[string]interface{}
+	}
+	assert.NilError(t, json.Unmarshal(raw, &parsed))
+	assert.DeepEqual(t, parsed.DependsOn, []string{"LaunchTemplate"})
+	assert.Equal(t, parsed.DeletionPolicy, "Retain")
+	assert.DeepEqual(t, parsed.UpdatePolicy, map[string]interface{}{
+		"AutoScalingReplacingUpdate": map[string]interface{}{"WillReplace": true},
+	})
+	assert.Equal(t, parsed.Properties["CapacityRebalance"], true)
+}
+
+func TestInvalidSpotCapacity(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+x-aws-ec2_spot:
+  instance_types:
+    - m5.large
+`: "x-aws-ec2_spot requires at least 2 instance_types, so spot capacity can be diversified",
+		`
+x-aws-ec2_spot:
+  spot_percentage: 120
+  instance_types:
+    - m5.large
+    - m5a.large
+`: "x-aws-ec2_spot spot_percentage must be between 0 and 100, got 120",
+		`
+x-aws-ec2_spot:
+  instance_types:
+    - m5.large
+    - m5.large
+`: "x-aws-ec2_spot instance type m5.large is listed more than once",
+	} {
+		_, err := ec2Spot(loadConfig(t, "services:\n  worker:\n    image: hello_world\n"+yaml))
+		assert.Error(t, err, expected)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+// stoppedService is the state a service had before the project got stopped, which starting it restores
+type stoppedService struct {
+	Desired int `json:"desired"`
+	// MinCapacity is the minimum capacity of service's scalable target, which is set to 0 while stopped
+	MinCapacity *int `json:"min_capacity,omitempty"`
+}
+
+// stoppedServicesParameter is the SSM parameter recording the state of project's services while stopped
+func stoppedServicesParameter(projectName string) string {
+	return fmt.Sprintf("/docker-compose/%s/stopped-services", projectName)
+}
+
+// stoppedServices returns the state of project's services before they got stopped, nil if the project isn't stopped
+func (b *ecsAPIService) stoppedServices(ctx context.Context, projectName string) (map[string]stoppedService, error) {
+	value, ok, err := b.SDK.GetStringParameter(ctx, stoppedServicesParameter(projectName))
+	if err != nil || !ok {
+		return nil, err
+	}
+	stopped := map[string]stoppedService{}
+	if err := json.Unmarshal([]byte(value), &stopped); err != nil {
+		return nil, fmt.Errorf("invalid %s parameter: %w", stoppedServicesParameter(projectName), err)
+	}
+	return stopped, nil
+}
+
+// Stop sets all services desired count to 0, recording the previous one so Start restores it. The stack is left as is
+func (b *ecsAPIService) Stop(ctx context.Context, projectName string) error {
+	cluster, services, err := b.projectServices(ctx, projectName)
+	if err != nil {
+		return err
+	}
+	stopped, err := b.stoppedServices(ctx, projectName)
+	if err != nil {
+		return err
+	}
+	if stopped == nil {
+		stopped = map[string]stoppedService{}
+	}
+	for name, svc := range services {
+		if _, ok := stopped[name]; ok {
+			// stopped already, the recorded state is the one to restore
+			continue
+		}
+		state := stoppedService{Desired: int(aws.Int64Value(svc.DesiredCount))}
+		min, ok, err := b.SDK.GetScalableTargetMinCapacity(ctx, svc)
+		if err != nil {
+			return err
+		}
+		if ok {
+			state.MinCapacity = &min
+		}
+		stopped[name] = state
+	}
+	// state is recorded before services get stopped, so an interrupted stop can still be started again
+	value, err := json.Marshal(stopped)
+	if err != nil {
+		return err
+	}
+	if err := b.SDK.PutStringParameter(ctx, stoppedServicesParameter(projectName), string(value)); err != nil {
+		return err
+	}
+
+	return b.updateServices(ctx, cluster, services, "Stopping", "Stopped", func(ctx context.Context, name string, svc *ecsapi.Service) (int, error) {
+		if stopped[name].MinCapacity != nil {
+			// Application Auto Scaling would otherwise scale the service back to its minimum capacity
+			if err := b.SDK.SetScalableTargetMinCapacity(ctx, svc, 0); err != nil {
+				return 0, err
+			}
+		}
+		return 0, nil
+	})
+}
+
+// Start restores the desired count services had when stopped, or their replicas for those which weren't. Nothing is
+// changed when the project isn't stopped
+func (b *ecsAPIService) Start(ctx context.Context, projectName string, options compose.StartOptions) error {
+	cluster, services, err := b.projectServices(ctx, projectName)
+	if err != nil {
+		return err
+	}
+	stopped, err := b.stoppedServices(ctx, projectName)
+	if err != nil {
+		return err
+	}
+	if stopped == nil {
+		// project isn't stopped, services keep their current desired count
+		return nil
+	}
+
+	err = b.updateServices(ctx, cluster, services, "Starting", "Started", func(ctx context.Context, name string, svc *ecsapi.Service) (int, error) {
+		state, ok := stopped[name]
+		if !ok {
+			if replicas, ok := options.Replicas[name]; ok {
+				return replicas, nil
+			}
+			return 1, nil
+		}
+		if state.MinCapacity != nil {
+			if err := b.SDK.SetScalableTargetMinCapacity(ctx, svc, *state.MinCapacity); err != nil {
+				return 0, err
+			}
+		}
+		return state.Desired, nil
+	})
+	if err != nil {
+		return err
+	}
+	return b.SDK.DeleteParameter(ctx, stoppedServicesParameter(projectName))
+}
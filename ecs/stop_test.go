@@ -0,0 +1,142 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling/applicationautoscalingiface"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+type mockApplicationAutoScaling struct {
+	applicationautoscalingiface.ApplicationAutoScalingAPI
+	mock.Mock
+}
+
+func (m *mockApplicationAutoScaling) DescribeScalableTargetsWithContext(_ aws.Context, in *applicationautoscaling.DescribeScalableTargetsInput, _ ...request.Option) (*applicationautoscaling.DescribeScalableTargetsOutput, error) {
+	args := m.Called(aws.StringValue(in.ResourceIds[0]))
+	return args.Get(0).(*applicationautoscaling.DescribeScalableTargetsOutput), args.Error(1)
+}
+
+func (m *mockApplicationAutoScaling) RegisterScalableTargetWithContext(_ aws.Context, in *applicationautoscaling.RegisterScalableTargetInput, _ ...request.Option) (*applicationautoscaling.RegisterScalableTargetOutput, error) {
+	args := m.Called(aws.StringValue(in.ResourceId), aws.Int64Value(in.MinCapacity))
+	return args.Get(0).(*applicationautoscaling.RegisterScalableTargetOutput), args.Error(1)
+}
+
+func (m *mockSSM) PutParameterWithContext(_ aws.Context, in *ssm.PutParameterInput, _ ...request.Option) (*ssm.PutParameterOutput, error) {
+	args := m.Called(aws.StringValue(in.Name), aws.StringValue(in.Value))
+	return args.Get(0).(*ssm.PutParameterOutput), args.Error(1)
+}
+
+func (m *mockSSM) DeleteParameterWithContext(_ aws.Context, in *ssm.DeleteParameterInput, _ ...request.Option) (*ssm.DeleteParameterOutput, error) {
+	args := m.Called(aws.StringValue(in.Name))
+	return args.Get(0).(*ssm.DeleteParameterOutput), args.Error(1)
+}
+
+func stopBackend(stopped string) (*ecsAPIService, *recordingECS, *mockSSM, *mockApplicationAutoScaling) {
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "test").Return(stackServices(), nil)
+	service := func(name string, desired int64) *ecsapi.Service {
+		return &ecsapi.Service{
+			ServiceArn:   aws.String("arn:" + name),
+			ServiceName:  aws.String("test-" + name),
+			ClusterArn:   aws.String("arn:aws:ecs:us-east-1:012345678912:cluster/test"),
+			DesiredCount: aws.Int64(desired),
+			Tags:         []*ecsapi.Tag{{Key: aws.String(compose.ServiceTag), Value: aws.String(name)}},
+		}
+	}
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeServicesWithContext", "arn:web").Return(servicesEvents(service("web", 2), service("db", 1)), nil)
+	ecsMock.On("WaitUntilServicesStableWithContext", "arn:web").Return(nil)
+	ecsMock.On("WaitUntilServicesStableWithContext", "arn:db").Return(nil)
+
+	ssmMock := &mockSSM{}
+	if stopped == "" {
+		ssmMock.On("GetParameterWithContext", "/docker-compose/test/stopped-services").
+			Return(&ssm.GetParameterOutput{}, awserr.New(ssm.ErrCodeParameterNotFound, "", nil))
+	} else {
+		ssmMock.On("GetParameterWithContext", "/docker-compose/test/stopped-services").
+			Return(&ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(stopped)}}, nil)
+	}
+	aas := &mockApplicationAutoScaling{}
+	aas.On("DescribeScalableTargetsWithContext", "service/test/test-web").Return(&applicationautoscaling.DescribeScalableTargetsOutput{
+		ScalableTargets: []*applicationautoscaling.ScalableTarget{{MinCapacity: aws.Int64(1)}},
+	}, nil)
+	aas.On("DescribeScalableTargetsWithContext", "service/test/test-db").Return(&applicationautoscaling.DescribeScalableTargetsOutput{}, nil)
+
+	recorder := newRecordingECS(ecsMock)
+	return &ecsAPIService{SDK: sdk{CF: cf, ECS: recorder, SSM: ssmMock, AAS: aas}}, recorder, ssmMock, aas
+}
+
+func TestStop(t *testing.T) {
+	backend, recorder, ssmMock, aas := stopBackend("")
+	ssmMock.On("PutParameterWithContext", "/docker-compose/test/stopped-services", `{"db":{"desired":1},"web":{"desired":2,"min_capacity":1}}`).
+		Return(&ssm.PutParameterOutput{}, nil)
+	aas.On("RegisterScalableTargetWithContext", "service/test/test-web", int64(0)).Return(&applicationautoscaling.RegisterScalableTargetOutput{}, nil)
+
+	err := backend.Stop(context.TODO(), "test")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, recorder.desired, map[string]int64{"arn:web": 0, "arn:db": 0})
+	ssmMock.AssertExpectations(t)
+	aas.AssertExpectations(t)
+}
+
+func TestStopAlreadyStopped(t *testing.T) {
+	// services desired count is 0 once stopped, the recorded one is kept
+	backend, _, ssmMock, aas := stopBackend(`{"db":{"desired":1},"web":{"desired":2,"min_capacity":1}}`)
+	ssmMock.On("PutParameterWithContext", "/docker-compose/test/stopped-services", `{"db":{"desired":1},"web":{"desired":2,"min_capacity":1}}`).
+		Return(&ssm.PutParameterOutput{}, nil)
+	aas.On("RegisterScalableTargetWithContext", "service/test/test-web", int64(0)).Return(&applicationautoscaling.RegisterScalableTargetOutput{}, nil)
+
+	err := backend.Stop(context.TODO(), "test")
+	assert.NilError(t, err)
+	aas.AssertNotCalled(t, "DescribeScalableTargetsWithContext", "service/test/test-web")
+}
+
+func TestStart(t *testing.T) {
+	backend, recorder, ssmMock, aas := stopBackend(`{"web":{"desired":2,"min_capacity":1}}`)
+	ssmMock.On("DeleteParameterWithContext", "/docker-compose/test/stopped-services").Return(&ssm.DeleteParameterOutput{}, nil)
+	aas.On("RegisterScalableTargetWithContext", "service/test/test-web", int64(1)).Return(&applicationautoscaling.RegisterScalableTargetOutput{}, nil)
+
+	// db wasn't recorded when stopped, it gets the replicas of the compose file
+	err := backend.Start(context.TODO(), "test", compose.StartOptions{Replicas: map[string]int{"db": 3}})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, recorder.desired, map[string]int64{"arn:web": 2, "arn:db": 3})
+	ssmMock.AssertExpectations(t)
+	aas.AssertCalled(t, "RegisterScalableTargetWithContext", "service/test/test-web", int64(1))
+}
+
+func TestStartNotStopped(t *testing.T) {
+	backend, recorder, ssmMock, aas := stopBackend("")
+
+	err := backend.Start(context.TODO(), "test", compose.StartOptions{Replicas: map[string]int{"db": 3}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(recorder.desired), 0)
+	ssmMock.AssertNotCalled(t, "DeleteParameterWithContext", "/docker-compose/test/stopped-services")
+	aas.AssertNotCalled(t, "RegisterScalableTargetWithContext", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,136 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// parseNetworkSubnets selects the VPC subnets services attached to a network get deployed into. x-aws-subnets
+// explicitly lists them and takes precedence, otherwise ipam config subnets are used as a filter on subnets CIDR.
+func (r *awsResources) parseNetworkSubnets(project *types.Project) error {
+	r.networkSubnets = map[string][]string{}
+	names := make([]string, 0, len(project.Networks))
+	for name := range project.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		network := project.Networks[name]
+		if x, ok := network.Extensions[extensionSubnets]; ok {
+			subnets, err := r.explicitSubnets(name, x)
+			if err != nil {
+				return err
+			}
+			r.networkSubnets[name] = subnets
+			continue
+		}
+		var ranges []*net.IPNet
+		for _, pool := range network.Ipam.Config {
+			if pool == nil || pool.Subnet == "" {
+				continue
+			}
+			_, cidr, err := net.ParseCIDR(pool.Subnet)
+			if err != nil {
+				return fmt.Errorf("network %s: invalid ipam subnet %q: %w", name, pool.Subnet, err)
+			}
+			ranges = append(ranges, cidr)
+		}
+		if len(ranges) == 0 {
+			continue
+		}
+		var subnets []string
+		for _, subnet := range r.subnets {
+			if containedIn(r.cidrs[subnet], ranges) {
+				subnets = append(subnets, subnet)
+			}
+		}
+		if len(subnets) == 0 {
+			return fmt.Errorf("network %s: none of the VPC subnets is within the ipam subnets", name)
+		}
+		r.networkSubnets[name] = subnets
+	}
+	return nil
+}
+
+func (r *awsResources) explicitSubnets(network string, x interface{}) ([]string, error) {
+	values, ok := x.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("network %s: %s must be a list of subnet IDs", network, extensionSubnets)
+	}
+	known := map[string]bool{}
+	for _, s := range r.subnets {
+		known[s] = true
+	}
+	var subnets []string
+	for _, v := range values {
+		subnet := fmt.Sprint(v)
+		if !known[subnet] {
+			return nil, fmt.Errorf("network %s: subnet %s doesn't belong to VPC %s", network, subnet, r.vpc)
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+// containedIn tells if cidr is fully contained in one of ranges
+func containedIn(cidr string, ranges []*net.IPNet) bool {
+	ip, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	ones, _ := subnet.Mask.Size()
+	for _, r := range ranges {
+		rangeOnes, _ := r.Mask.Size()
+		if r.Contains(ip) && ones >= rangeOnes {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceNetworkSubnets returns the subnets allowed by all of service's networks
+func (r *awsResources) serviceNetworkSubnets(service types.ServiceConfig) ([]string, error) {
+	subnets := r.subnets
+	filtered := false
+	for name := range service.Networks {
+		selected, ok := r.networkSubnets[name]
+		if !ok {
+			continue
+		}
+		filtered = true
+		allowed := map[string]bool{}
+		for _, s := range selected {
+			allowed[s] = true
+		}
+		var kept []string
+		for _, s := range subnets {
+			if allowed[s] {
+				kept = append(kept, s)
+			}
+		}
+		subnets = kept
+	}
+	if filtered && len(subnets) == 0 {
+		return nil, fmt.Errorf("service %s is attached to networks which select no common subnet", service.Name)
+	}
+	return subnets, nil
+}
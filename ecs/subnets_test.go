@@ -0,0 +1,104 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+func subnetsResources() awsResources {
+	return awsResources{
+		vpc:     "vpc-123",
+		subnets: []string{"subnet1", "subnet2", "subnet3"},
+		cidrs: map[string]string{
+			"subnet1": "10.1.1.0/24",
+			"subnet2": "10.1.2.0/24",
+			"subnet3": "10.2.0.0/24",
+		},
+	}
+}
+
+func TestNetworkIpamSubnets(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+networks:
+  default:
+    ipam:
+      config:
+        - subnet: 10.1.0.0/16
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	resources := subnetsResources()
+	assert.NilError(t, resources.parseNetworkSubnets(project))
+	template, err := backend.convert(project, resources)
+	assert.NilError(t, err)
+	service := template.Resources["TestService"].(*ecs.Service)
+	assert.DeepEqual(t, service.NetworkConfiguration.AwsvpcConfiguration.Subnets, []string{"subnet1", "subnet2"})
+}
+
+func TestNetworkSubnetsExtensionTakesPrecedence(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+networks:
+  default:
+    ipam:
+      config:
+        - subnet: 10.1.0.0/16
+    x-aws-subnets:
+      - subnet3
+`)
+	resources := subnetsResources()
+	assert.NilError(t, resources.parseNetworkSubnets(project))
+	template, err := (&ecsAPIService{}).convert(project, resources)
+	assert.NilError(t, err)
+	service := template.Resources["TestService"].(*ecs.Service)
+	assert.DeepEqual(t, service.NetworkConfiguration.AwsvpcConfiguration.Subnets, []string{"subnet3"})
+}
+
+func TestNetworkSubnetsErrors(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: hello_world
+networks:
+  default:
+    ipam:
+      config:
+        - subnet: 192.168.0.0/16
+`)
+	resources := subnetsResources()
+	assert.ErrorContains(t, resources.parseNetworkSubnets(project), "none of the VPC subnets is within the ipam subnets")
+
+	project = loadConfig(t, `
+services:
+  test:
+    image: hello_world
+networks:
+  default:
+    x-aws-subnets:
+      - subnet4
+`)
+	assert.ErrorContains(t, resources.parseNetworkSubnets(project), "subnet subnet4 doesn't belong to VPC vpc-123")
+}
@@ -17,22 +17,29 @@
 package ecs
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/awslabs/goformation/v4/cloudformation/efs"
 	"github.com/awslabs/goformation/v4/cloudformation/tags"
 	"github.com/compose-spec/compose-go/types"
 	"github.com/docker/compose-cli/api/compose"
 )
 
 func projectTags(project *types.Project) []tags.Tag {
-	return []tags.Tag{
+	return append([]tags.Tag{
 		{
 			Key:   compose.ProjectTag,
 			Value: project.Name,
 		},
-	}
+	}, userTags(project)...)
 }
 
 func serviceTags(project *types.Project, service types.ServiceConfig) []tags.Tag {
-	return []tags.Tag{
+	serviceTags := []tags.Tag{
 		{
 			Key:   compose.ProjectTag,
 			Value: project.Name,
@@ -42,10 +49,16 @@ func serviceTags(project *types.Project, service types.ServiceConfig) []tags.Tag
 			Value: service.Name,
 		},
 	}
+	if labelsAsTags(project) {
+		// service labels override project tags with the same key
+		labels, _ := labelTags(service.Labels)
+		return append(serviceTags, mergeTags(userTags(project), labels)...)
+	}
+	return append(serviceTags, userTags(project)...)
 }
 
 func networkTags(project *types.Project, net types.NetworkConfig) []tags.Tag {
-	return []tags.Tag{
+	return append([]tags.Tag{
 		{
 			Key:   compose.ProjectTag,
 			Value: project.Name,
@@ -54,5 +67,139 @@ func networkTags(project *types.Project, net types.NetworkConfig) []tags.Tag {
 			Key:   compose.NetworkTag,
 			Value: net.Name,
 		},
+	}, userTags(project)...)
+}
+
+func volumeTags(project *types.Project, name string) []efs.FileSystem_ElasticFileSystemTag {
+	volumeTags := []efs.FileSystem_ElasticFileSystemTag{
+		{
+			Key:   compose.ProjectTag,
+			Value: project.Name,
+		},
+		{
+			Key:   compose.VolumeTag,
+			Value: name,
+		},
+	}
+	for _, tag := range userTags(project) {
+		volumeTags = append(volumeTags, efs.FileSystem_ElasticFileSystemTag{
+			Key:   tag.Key,
+			Value: tag.Value,
+		})
+	}
+	return volumeTags
+}
+
+// checkUserTags validates x-aws-tags is a mapping of tag keys to values
+func checkUserTags(project *types.Project) error {
+	x, ok := project.Extensions[extensionTags]
+	if !ok {
+		return nil
+	}
+	if _, ok := x.(map[string]interface{}); !ok {
+		return fmt.Errorf("%s must be a mapping of tag keys to values", extensionTags)
+	}
+	return nil
+}
+
+// userTags returns the tags set by x-aws-tags, sorted by key. x-aws-tags is validated by checkUserTags
+func userTags(project *types.Project) []tags.Tag {
+	x, ok := project.Extensions[extensionTags].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	values := map[string]string{}
+	for k, v := range x {
+		values[k] = fmt.Sprint(v)
+	}
+	return mergeTags(nil, values)
+}
+
+// mergeTags overrides tags with values, sorted by key
+func mergeTags(tagList []tags.Tag, values map[string]string) []tags.Tag {
+	merged := map[string]string{}
+	for _, tag := range tagList {
+		merged[tag.Key] = tag.Value
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	var result []tags.Tag
+	for k, v := range merged {
+		result = append(result, tags.Tag{
+			Key:   k,
+			Value: v,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// labelsAsTags tells if --labels-as-tags or x-aws-labels_as_tags is set, so service labels are also set as tags on
+// service's resources
+func labelsAsTags(project *types.Project) bool {
+	enabled, ok := project.Extensions[extensionLabelsAsTags].(bool)
+	return ok && enabled
+}
+
+// applyLabelsAsTags enables labels as tags when --labels-as-tags is set, x-aws-labels_as_tags applies otherwise
+func applyLabelsAsTags(project *types.Project, enabled bool) {
+	if !enabled {
+		return
+	}
+	if project.Extensions == nil {
+		project.Extensions = map[string]interface{}{}
+	}
+	project.Extensions[extensionLabelsAsTags] = true
+}
+
+const (
+	// ECS limits on tags, in unicode characters
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// invalidTagCharacters matches the characters tags don't allow
+var invalidTagCharacters = regexp.MustCompile(`[^\pL\pZ\pN_.:/=+\-@]`)
+
+// labelTags converts service labels into tags, replacing characters tags don't allow. Labels which still can't be
+// set as tags, as they exceed tags limits or use a reserved key, are returned sorted as skipped
+func labelTags(labels types.Labels) (map[string]string, []string) {
+	values := map[string]string{}
+	var skipped []string
+	for k, v := range labels {
+		key := invalidTagCharacters.ReplaceAllString(k, "_")
+		value := invalidTagCharacters.ReplaceAllString(v, "_")
+		if utf8.RuneCountInString(key) > maxTagKeyLength || utf8.RuneCountInString(value) > maxTagValueLength ||
+			strings.HasPrefix(strings.ToLower(key), "aws:") || key == compose.ProjectTag || key == compose.ServiceTag {
+			skipped = append(skipped, k)
+			continue
+		}
+		values[key] = value
+	}
+	sort.Strings(skipped)
+	return values, skipped
+}
+
+// checkLabelTags warns about service labels which can't be set as tags, when --labels-as-tags or x-aws-labels_as_tags
+// enables them
+func (b *ecsAPIService) checkLabelTags(project *types.Project, service types.ServiceConfig) {
+	if !labelsAsTags(project) {
+		return
+	}
+	_, skipped := labelTags(service.Labels)
+	for _, key := range skipped {
+		b.warn(warningInvalidTag, severityWarning, service.Name, "label %s isn't set as a tag, as it exceeds tags limits or uses a reserved key", key)
+	}
+}
+
+// userStackTags returns the user tags set on project's stack, which CloudFormation propagates to resources it supports tagging
+func userStackTags(project *types.Project) map[string]string {
+	stackTags := map[string]string{}
+	for _, tag := range userTags(project) {
+		stackTags[tag.Key] = tag.Value
 	}
+	return stackTags
 }
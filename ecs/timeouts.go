@@ -0,0 +1,78 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// fargateMaxContainerTimeout is the maximum start and stop timeout of containers deployed on Fargate
+const fargateMaxContainerTimeout = 120 * time.Second
+
+// stopTimeout is the delay ECS waits after stopping service's container before killing it
+func stopTimeout(service types.ServiceConfig) time.Duration {
+	if service.StopGracePeriod == nil {
+		return 0
+	}
+	return time.Duration(*service.StopGracePeriod)
+}
+
+// startTimeout parses x-aws-start_timeout, the delay ECS waits for service's container to reach the condition
+// containers depending on it wait for. A bare integer is a number of seconds, as ECS sets it
+func startTimeout(service types.ServiceConfig) (time.Duration, error) {
+	x, ok := service.Extensions[extensionStartTimeout]
+	if !ok {
+		return 0, nil
+	}
+	value := fmt.Sprint(x)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		value = fmt.Sprintf("%ds", seconds)
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid %s %v, must be a positive duration", extensionStartTimeout, x)
+	}
+	return timeout, nil
+}
+
+// checkContainerTimeouts rejects timeouts Fargate doesn't support
+func checkContainerTimeouts(service types.ServiceConfig, ec2 bool) error {
+	if ec2 {
+		return nil
+	}
+	if timeout := stopTimeout(service); timeout > fargateMaxContainerTimeout {
+		return fmt.Errorf("stop_grace_period %s exceeds Fargate maximum of %s", timeout, fargateMaxContainerTimeout)
+	}
+	timeout, err := startTimeout(service)
+	if err != nil {
+		return err
+	}
+	if timeout > fargateMaxContainerTimeout {
+		return fmt.Errorf("%s %s exceeds Fargate maximum of %s", extensionStartTimeout, timeout, fargateMaxContainerTimeout)
+	}
+	return nil
+}
+
+// toSeconds rounds d up to the second, as ECS timeouts are set in seconds and must never be shortened
+func toSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
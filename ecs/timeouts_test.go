@@ -0,0 +1,114 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"gotest.tools/v3/assert"
+)
+
+func TestContainerTimeouts(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: nginx
+    stop_grace_period: 1m30s
+    x-aws-start_timeout: 1500ms
+  db:
+    image: postgres
+`)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.Equal(t, container.StopTimeout, 90)
+	assert.Equal(t, container.StartTimeout, 2)
+
+	def = template.Resources["DbTaskDefinition"].(*ecs.TaskDefinition)
+	container = getMainContainer(def, t)
+	assert.Equal(t, container.StopTimeout, 0)
+	assert.Equal(t, container.StartTimeout, 0)
+}
+
+func TestStartTimeoutSeconds(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  test:
+    image: nginx
+    x-aws-start_timeout: 45
+`)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	container := getMainContainer(def, t)
+	assert.Equal(t, container.StartTimeout, 45)
+}
+
+func TestStopGracePeriodCompatibility(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  test:
+    image: nginx
+    stop_grace_period: 90s
+`)
+	backend := &ecsAPIService{}
+	assert.NilError(t, backend.checkCompatibility(project, false))
+	assert.Check(t, project.Services[0].StopGracePeriod != nil)
+}
+
+func TestContainerTimeoutsInvalid(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  test:
+    image: nginx
+    stop_grace_period: 3m
+`: "stop_grace_period 3m0s exceeds Fargate maximum of 2m0s",
+		`
+services:
+  test:
+    image: nginx
+    x-aws-start_timeout: 5m
+`: "x-aws-start_timeout 5m0s exceeds Fargate maximum of 2m0s",
+		`
+services:
+  test:
+    image: nginx
+    x-aws-start_timeout: soon
+`: "invalid x-aws-start_timeout soon, must be a positive duration",
+		`
+services:
+  test:
+    image: nginx
+    x-aws-start_timeout: 0
+`: "invalid x-aws-start_timeout 0, must be a positive duration",
+	} {
+		project := loadConfig(t, yaml)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.ErrorContains(t, err, expected)
+	}
+
+	// EC2 launch type has no maximum
+	template := convertYaml(t, `
+services:
+  test:
+    image: nginx
+    shm_size: 1g
+    stop_grace_period: 10m
+`)
+	def := template.Resources["TestTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, getMainContainer(def, t).StopTimeout, 600)
+}
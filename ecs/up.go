@@ -22,53 +22,220 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/compose-spec/compose-go/types"
+	"github.com/moby/term"
+	"github.com/sirupsen/logrus"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/errdefs"
 )
 
-func (b *ecsAPIService) Up(ctx context.Context, project *types.Project, detach bool) error {
-	err := b.SDK.CheckRequirements(ctx, b.Region)
+func (b *ecsAPIService) Up(ctx context.Context, project *types.Project, options compose.UpOptions) error {
+	start := time.Now()
+	// images are shared by the environments deployed from the project, under its own name
+	projectName := project.Name
+	if err := applyStackName(project, options.StackName); err != nil {
+		return classify(err, errdefs.ErrValidation)
+	}
+	if err := checkResourceNames(project); err != nil {
+		return classify(err, errdefs.ErrValidation)
+	}
+	applyEnvFilesBucket(project, options.EnvFilesBucket)
+	applyLabelsAsTags(project, options.LabelsAsTags)
+	protected, err := protectionEnabled(project)
+	if err != nil {
+		return classify(err, errdefs.ErrValidation)
+	}
+	topics, err := snsTopics(project)
+	if err != nil {
+		return classify(err, errdefs.ErrValidation)
+	}
+	if err := b.assumeProjectRole(project); err != nil {
+		return classify(err, errdefs.ErrAuthentication)
+	}
+
+	err = b.SDK.CheckRequirements(ctx, b.Region)
+	if err != nil {
+		return classify(err, errdefs.ErrValidation)
+	}
+
+	// preflight runs before the deployment, as secrets get created before conversion
+	if !options.SkipPreflight {
+		err = b.preflight(ctx, project)
+		if err != nil {
+			return classify(err, errdefs.ErrAuthentication)
+		}
+	}
+
+	// a dry run previews the deployment without building images nor creating secrets
+	if options.DryRun {
+		err = b.skipImageBuilds(project, projectName, options.Build)
+		if err != nil {
+			return classify(err, errdefs.ErrValidation)
+		}
+	} else {
+		err = b.buildImages(ctx, project, projectName, options.Build)
+		if err != nil {
+			return classify(err, errdefs.ErrDeploymentFailed)
+		}
+	}
+
+	if !options.SkipScan && !options.DryRun {
+		err = b.checkImageScans(ctx, project)
+		if err != nil {
+			return classify(err, errdefs.ErrValidation)
+		}
+	}
+
+	fallbacks, err := fallbackCapacities(project)
+	if err != nil {
+		return classify(err, errdefs.ErrValidation)
+	}
+
+	if !options.InlineSecrets && !options.DryRun {
+		b.secrets, err = b.uploadSecrets(ctx, project)
+		if err != nil {
+			return classify(err, errdefs.ErrDeploymentFailed)
+		}
+	}
+
+	converted, err := b.Convert(ctx, project, compose.ConvertOptions{
+		InlineSecrets:  options.InlineSecrets,
+		Force:          options.Force,
+		StackName:      options.StackName,
+		EnvFilesBucket: options.EnvFilesBucket,
+		LabelsAsTags:   options.LabelsAsTags,
+	})
+	if err != nil {
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+	template := converted.Template
+
+	// CloudFormation reads nested stacks templates to create the stack, and to compute a change set
+	err = b.uploadNestedTemplates(ctx, project)
+	if err != nil {
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+
+	if options.DryRun {
+		return b.previewChanges(ctx, project, template, os.Stdout, term.IsTerminal(os.Stdout.Fd()))
+	}
+
+	err = b.uploadEnvFiles(ctx, project)
+	if err != nil {
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+
+	// services reference the shared capacity provider by name, which must then be associated with the cluster
+	err = b.createSharedCapacityProvider(ctx)
 	if err != nil {
-		return err
+		return classify(err, errdefs.ErrDeploymentFailed)
 	}
 
-	template, err := b.Convert(ctx, project)
+	tags, err := projectStackTags(project)
 	if err != nil {
-		return err
+		return classify(err, errdefs.ErrValidation)
 	}
+	tags[sharedProjectTag] = projectName
 
 	update, err := b.SDK.StackExists(ctx, project.Name)
 	if err != nil {
-		return err
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+	if update {
+		if err := b.checkStackOwner(ctx, project.Name); err != nil {
+			return classify(err, errdefs.ErrDeploymentFailed)
+		}
 	}
 	operation := stackCreate
+	var changed []string
 	if update {
 		operation = stackUpdate
-		changeset, err := b.SDK.CreateChangeSet(ctx, project.Name, template)
+		changeset, changes, err := b.createChangeSet(ctx, project.Name, template, tags, topics)
+		if err != nil {
+			return classify(err, errdefs.ErrDeploymentFailed)
+		}
+		if len(changes) == 0 {
+			// there's no update to wait for, but a detached up may have left task definitions for CodeDeploy to deploy
+			b.SDK.DeleteChangeSet(ctx, changeset) // nolint:errcheck
+			if err := b.deployCodeDeployedServices(ctx, project); err != nil {
+				return classify(err, errdefs.ErrDeploymentFailed)
+			}
+			return nil
+		}
+		if deployMarkersEnabled(project) {
+			resources, err := b.SDK.ListChangeSetResources(ctx, changeset)
+			if err != nil {
+				// markers never fail the deployment, they then report all services
+				logrus.Warnf("failed to list resources updated by change set, deployment markers will report all services: %s", err.Error())
+				changed = project.ServiceNames()
+			} else {
+				changed = changedServices(project, resources)
+			}
+		}
+		err = b.updateStackProtection(ctx, project.Name, protected)
 		if err != nil {
-			return err
+			b.SDK.DeleteChangeSet(ctx, changeset) // nolint:errcheck
+			return classify(err, errdefs.ErrDeploymentFailed)
 		}
 		err = b.SDK.UpdateStack(ctx, changeset)
 		if err != nil {
-			return err
+			return classify(err, errdefs.ErrDeploymentFailed)
 		}
 	} else {
-		err = b.SDK.CreateStack(ctx, project.Name, template)
+		var policy string
+		if protected {
+			policy, err = stackPolicy(true)
+			if err != nil {
+				return classify(err, errdefs.ErrDeploymentFailed)
+			}
+		}
+		err = b.SDK.CreateStack(ctx, project.Name, template, tags, options.Timeout, !options.NoRollback, policy, topics)
 		if err != nil {
-			return err
+			return classify(err, errdefs.ErrDeploymentFailed)
 		}
 	}
-	if detach {
+	if options.Detach {
+		b.notifyDeployment(ctx, topics, newDeploymentNotification(project, b.digests, start, deploymentStarted, nil))
 		return nil
 	}
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-signalChan
+		if operation == stackUpdate {
+			// deleting the stack would delete resources the previous deployment is running
+			fmt.Println("user interrupted deployment. Rolling back stack update...")
+			b.SDK.CancelUpdateStack(ctx, project.Name) // nolint:errcheck
+			return
+		}
 		fmt.Println("user interrupted deployment. Deleting stack...")
-		b.Down(ctx, project.Name) // nolint:errcheck
+		b.Down(ctx, project.Name, compose.DownOptions{Force: true, Volumes: true, Unprotect: true}) // nolint:errcheck
 	}()
 
-	err = b.WaitStackCompletion(ctx, project.Name, operation)
-	return err
+	err = b.waitStackCompletion(ctx, project.Name, operation, waitOptions{
+		fallbacks: fallbacks,
+		timeout:   options.Timeout,
+		rollback:  !options.NoRollback,
+	})
+	if err != nil {
+		outcome := deploymentFailed
+		if !options.NoRollback {
+			outcome = deploymentRolledBack
+		}
+		b.notifyDeployment(ctx, topics, newDeploymentNotification(project, b.digests, start, outcome, err))
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+	err = b.deployCodeDeployedServices(ctx, project)
+	if err != nil {
+		b.notifyDeployment(ctx, topics, newDeploymentNotification(project, b.digests, start, deploymentFailed, err))
+		return classify(err, errdefs.ErrDeploymentFailed)
+	}
+	b.notifyDeployment(ctx, topics, newDeploymentNotification(project, b.digests, start, deploymentSucceeded, nil))
+	if deployMarkersEnabled(project) && (operation == stackCreate || len(changed) > 0) {
+		b.publishDeploymentMarkers(ctx, newDeploymentMarker(project, changed, b.digests))
+	}
+	return nil
 }
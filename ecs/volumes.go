@@ -17,14 +17,620 @@
 package ecs
 
 import (
+	"context"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws/arn"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	efsapi "github.com/aws/aws-sdk-go/service/efs"
 	"github.com/awslabs/goformation/v4/cloudformation"
 	"github.com/awslabs/goformation/v4/cloudformation/ec2"
 	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/efs"
+	"github.com/awslabs/goformation/v4/cloudformation/events"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
 	"github.com/compose-spec/compose-go/types"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/docker/compose-cli/utils"
+)
+
+// volumeAvailabilityZone is the volume driver_opt to select EFS One Zone storage in a specific availability zone
+const volumeAvailabilityZone = "availability_zone"
+
+// volume driver_opts to mount an EFS access point. Files are created by the POSIX user, under root directory created on first mount
+const (
+	volumeUID           = "uid"
+	volumeGID           = "gid"
+	volumeRootDirectory = "root_directory"
+	volumePermissions   = "permissions"
 )
 
+var octalPermissions = regexp.MustCompile(`^[0-7]{3,4}$`)
+
+// volume driver_opts to configure the EFS file system created for a volume
+const (
+	volumeThroughputMode        = "throughput_mode"
+	volumeProvisionedThroughput = "provisioned_throughput_mibps"
+	volumePerformanceMode       = "performance_mode"
+	volumeLifecyclePolicy       = "lifecycle_policy"
+)
+
+// fileSystemSettings are the EFS file system settings a volume can select, unset ones use EFS defaults
+type fileSystemSettings struct {
+	ThroughputMode        string
+	ProvisionedThroughput float64
+	PerformanceMode       string
+	LifecyclePolicy       string
+}
+
+// volumeFileSystemSettings parses and validates the file system settings selected by volume's driver_opts
+func volumeFileSystemSettings(name string, volume types.VolumeConfig) (fileSystemSettings, error) {
+	var settings fileSystemSettings
+	opts := volume.DriverOpts
+	if mode, ok := opts[volumeThroughputMode]; ok {
+		if !utils.StringContains(efsapi.ThroughputMode_Values(), mode) {
+			return settings, fmt.Errorf("volume %s: invalid %s %q, must be one of %s", name, volumeThroughputMode, mode, strings.Join(efsapi.ThroughputMode_Values(), ", "))
+		}
+		settings.ThroughputMode = mode
+	}
+	if throughput, ok := opts[volumeProvisionedThroughput]; ok {
+		if settings.ThroughputMode != efsapi.ThroughputModeProvisioned {
+			return settings, fmt.Errorf("volume %s: %s requires %s to be %s", name, volumeProvisionedThroughput, volumeThroughputMode, efsapi.ThroughputModeProvisioned)
+		}
+		mibps, err := strconv.ParseFloat(throughput, 64)
+		if err != nil || mibps <= 0 {
+			return settings, fmt.Errorf("volume %s: invalid %s %q", name, volumeProvisionedThroughput, throughput)
+		}
+		settings.ProvisionedThroughput = mibps
+	} else if settings.ThroughputMode == efsapi.ThroughputModeProvisioned {
+		return settings, fmt.Errorf("volume %s: %s %s requires %s to be set", name, volumeThroughputMode, efsapi.ThroughputModeProvisioned, volumeProvisionedThroughput)
+	}
+	if mode, ok := opts[volumePerformanceMode]; ok {
+		if !utils.StringContains(efsapi.PerformanceMode_Values(), mode) {
+			return settings, fmt.Errorf("volume %s: invalid %s %q, must be one of %s", name, volumePerformanceMode, mode, strings.Join(efsapi.PerformanceMode_Values(), ", "))
+		}
+		if _, ok := opts[volumeAvailabilityZone]; ok && mode == efsapi.PerformanceModeMaxIo {
+			return settings, fmt.Errorf("volume %s: EFS One Zone storage doesn't support %s %s", name, volumePerformanceMode, mode)
+		}
+		settings.PerformanceMode = mode
+	}
+	if policy, ok := opts[volumeLifecyclePolicy]; ok {
+		if !utils.StringContains(efsapi.TransitionToIARules_Values(), policy) {
+			return settings, fmt.Errorf("volume %s: invalid %s %q, must be one of %s", name, volumeLifecyclePolicy, policy, strings.Join(efsapi.TransitionToIARules_Values(), ", "))
+		}
+		settings.LifecyclePolicy = policy
+	}
+	return settings, nil
+}
+
+// apply sets the selected settings on a file system resource
+func (settings fileSystemSettings) apply(fs *efs.FileSystem) {
+	fs.ThroughputMode = settings.ThroughputMode
+	fs.ProvisionedThroughputInMibps = settings.ProvisionedThroughput
+	fs.PerformanceMode = settings.PerformanceMode
+	if settings.LifecyclePolicy != "" {
+		fs.LifecyclePolicies = []efs.FileSystem_LifecyclePolicy{
+			{TransitionToIA: settings.LifecyclePolicy},
+		}
+	}
+}
+
+// mismatches lists the selected settings which differ from an actual file system ones
+func (settings fileSystemSettings) mismatches(actual fileSystemSettings) []string {
+	var mismatches []string
+	if settings.ThroughputMode != "" && settings.ThroughputMode != actual.ThroughputMode {
+		mismatches = append(mismatches, fmt.Sprintf("%s %s (requested %s)", volumeThroughputMode, actual.ThroughputMode, settings.ThroughputMode))
+	}
+	if settings.ProvisionedThroughput != 0 && settings.ProvisionedThroughput != actual.ProvisionedThroughput {
+		mismatches = append(mismatches, fmt.Sprintf("%s %g (requested %g)", volumeProvisionedThroughput, actual.ProvisionedThroughput, settings.ProvisionedThroughput))
+	}
+	if settings.PerformanceMode != "" && settings.PerformanceMode != actual.PerformanceMode {
+		mismatches = append(mismatches, fmt.Sprintf("%s %s (requested %s)", volumePerformanceMode, actual.PerformanceMode, settings.PerformanceMode))
+	}
+	if settings.LifecyclePolicy != "" && settings.LifecyclePolicy != actual.LifecyclePolicy {
+		mismatches = append(mismatches, fmt.Sprintf("%s %q (requested %s)", volumeLifecyclePolicy, actual.LifecyclePolicy, settings.LifecyclePolicy))
+	}
+	return mismatches
+}
+
+// checkFileSystemSettings warns when a volume's file system already deployed by the stack doesn't match the selected settings.
+// CloudFormation updates throughput and lifecycle in place, but changing performance mode replaces the file system
+func (b *ecsAPIService) checkFileSystemSettings(ctx context.Context, project *types.Project) error {
+	requested := map[string]fileSystemSettings{}
+	for name, volume := range project.Volumes {
+		if volume.External.External || isLocalVolume(volume) {
+			continue
+		}
+		settings, err := volumeFileSystemSettings(name, volume)
+		if err != nil {
+			return err
+		}
+		if settings != (fileSystemSettings{}) {
+			requested[fmt.Sprintf("%sFilesystem", normalizeResourceName(name))] = settings
+		}
+	}
+	if len(requested) == 0 {
+		return nil
+	}
+	exists, err := b.SDK.StackExists(ctx, project.Name)
+	if err != nil || !exists {
+		return err
+	}
+	resources, err := b.SDK.ListStackResources(ctx, project.Name)
+	if err != nil {
+		return err
+	}
+	for _, r := range resources {
+		settings, ok := requested[r.LogicalID]
+		if !ok {
+			continue
+		}
+		actual, err := b.SDK.DescribeFileSystem(ctx, r.ARN)
+		if err != nil {
+			return err
+		}
+		mismatches := settings.mismatches(actual)
+		if len(mismatches) == 0 {
+			continue
+		}
+		message := "file system %s doesn't match volume settings: %s, it will be updated"
+		if settings.PerformanceMode != "" && settings.PerformanceMode != actual.PerformanceMode {
+			message = "file system %s doesn't match volume settings: %s, it will be replaced and its content lost"
+		}
+		b.warn(warningFileSystemSettings, severityWarning, "", message, r.ARN, strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
+// hasAccessPoint tells if volume is mounted through an EFS access point
+func hasAccessPoint(volume types.VolumeConfig) bool {
+	if isLocalVolume(volume) {
+		return false
+	}
+	for _, opt := range []string{volumeUID, volumeGID, volumePermissions} {
+		if _, ok := volume.DriverOpts[opt]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func accessPointResourceName(volume string) string {
+	return fmt.Sprintf("%sAccessPoint", normalizeResourceName(volume))
+}
+
+// createAccessPoints creates an EFS access point for volumes to be mounted by a POSIX user
+func (b *ecsAPIService) createAccessPoints(project *types.Project, template *cloudformation.Template) error {
+	for name, volume := range project.Volumes {
+		if !hasAccessPoint(volume) {
+			continue
+		}
+		uid, okUID := volume.DriverOpts[volumeUID]
+		gid, okGID := volume.DriverOpts[volumeGID]
+		if !okUID || !okGID {
+			return fmt.Errorf("volume %s: %s and %s driver options must both be set", name, volumeUID, volumeGID)
+		}
+		for opt, id := range map[string]string{volumeUID: uid, volumeGID: gid} {
+			if _, err := strconv.ParseUint(id, 10, 32); err != nil {
+				return fmt.Errorf("volume %s: invalid %s %q", name, opt, id)
+			}
+		}
+		permissions := "0755"
+		if p, ok := volume.DriverOpts[volumePermissions]; ok {
+			if !octalPermissions.MatchString(p) {
+				return fmt.Errorf("volume %s: invalid %s %q, octal mode is expected", name, volumePermissions, p)
+			}
+			permissions = p
+		}
+		root := "/"
+		if r, ok := volume.DriverOpts[volumeRootDirectory]; ok {
+			root = r
+		}
+
+		var tags []efs.AccessPoint_AccessPointTag
+		for _, tag := range volumeTags(project, name) {
+			tags = append(tags, efs.AccessPoint_AccessPointTag{
+				Key:   tag.Key,
+				Value: tag.Value,
+			})
+		}
+		template.Resources[accessPointResourceName(name)] = &efs.AccessPoint{
+			AccessPointTags: tags,
+			FileSystemId:    volume.Name,
+			PosixUser: &efs.AccessPoint_PosixUser{
+				Uid: uid,
+				Gid: gid,
+			},
+			RootDirectory: &efs.AccessPoint_RootDirectory{
+				Path: root,
+				CreationInfo: &efs.AccessPoint_CreationInfo{
+					OwnerUid:    uid,
+					OwnerGid:    gid,
+					Permissions: permissions,
+				},
+			},
+		}
+	}
+	return nil
+}
+
+// volumeEFSConfiguration returns the EFS configuration for a task to mount volume
+func volumeEFSConfiguration(name string, volume types.VolumeConfig) *ecs.TaskDefinition_EFSVolumeConfiguration {
+	if !hasAccessPoint(volume) {
+		return &ecs.TaskDefinition_EFSVolumeConfiguration{
+			FilesystemId:  volume.Name,
+			RootDirectory: volume.DriverOpts[volumeRootDirectory],
+		}
+	}
+	// root directory is set by access point, IAM authorization requires encryption in transit
+	return &ecs.TaskDefinition_EFSVolumeConfiguration{
+		FilesystemId:      volume.Name,
+		TransitEncryption: ecsapi.EFSTransitEncryptionEnabled,
+		AuthorizationConfig: &ecs.TaskDefinition_AuthorizationConfig{
+			AccessPointId: cloudformation.Ref(accessPointResourceName(name)),
+			IAM:           ecsapi.EFSAuthorizationConfigIAMEnabled,
+		},
+	}
+}
+
+// volumesAccessPolicies grants service's task role access to the EFS access points of volumes it mounts
+func volumesAccessPolicies(project *types.Project, service types.ServiceConfig) []iam.Role_Policy {
+	var statements []PolicyStatement
+	for _, v := range service.Volumes {
+		volume, ok := project.Volumes[v.Source]
+		if !ok || !hasAccessPoint(volume) {
+			continue
+		}
+		fileSystem := cloudformation.GetAtt(fmt.Sprintf("%sFilesystem", normalizeResourceName(v.Source)), "Arn")
+		actions := []string{actionClientMount}
+		if !v.ReadOnly {
+			actions = append(actions, actionClientWrite)
+		}
+		statements = append(statements, PolicyStatement{
+			Effect:   "Allow",
+			Action:   actions,
+			Resource: []string{fileSystem},
+			Condition: map[string]interface{}{
+				"StringEquals": map[string]interface{}{
+					"elasticfilesystem:AccessPointArn": cloudformation.GetAtt(accessPointResourceName(v.Source), "Arn"),
+				},
+			},
+		})
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+	return []iam.Role_Policy{
+		{
+			PolicyDocument: &PolicyDocument{
+				Statement: statements,
+			},
+			PolicyName: fmt.Sprintf("%sVolumesAccess", normalizeResourceName(service.Name)),
+		},
+	}
+}
+
+var fileSystemID = regexp.MustCompile(`^fs-[0-9a-f]+$`)
+
+// fileSystemAvailableTimeout is how long to wait for an external volume's file system to be available
+const fileSystemAvailableTimeout = 10 * time.Minute
+
+// parseExternalVolumes checks external volumes are existing EFS file systems, set by ID or ARN, and returns the availability
+// zones they already have a mount target in. Volumes are looked up concurrently
+func (b *ecsAPIService) parseExternalVolumes(ctx context.Context, project *types.Project) (map[string][]string, error) {
+	var (
+		lock  sync.Mutex
+		zones = map[string][]string{}
+		ids   = map[string]string{}
+	)
+	eg, ctx := errgroup.WithContext(ctx)
+	slots := make(chan struct{}, maxParallelLookups)
+	for name, volume := range project.Volumes {
+		if !volume.External.External || isLocalVolume(volume) {
+			continue
+		}
+		name, volume := name, volume
+		eg.Go(func() error {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			id, mounted, err := b.parseExternalVolume(ctx, name, volume)
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			ids[name] = id
+			zones[name] = mounted
+			return nil
+		})
+	}
+	err := eg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	for name, id := range ids {
+		volume := project.Volumes[name]
+		volume.Name = id
+		project.Volumes[name] = volume
+	}
+	return zones, nil
+}
+
+// parseExternalVolume returns the EFS file system ID of an external volume and the availability zones it has a mount target in
+func (b *ecsAPIService) parseExternalVolume(ctx context.Context, name string, volume types.VolumeConfig) (string, []string, error) {
+	id := volume.Name
+	if arn.IsARN(id) {
+		parsed, err := arn.Parse(id)
+		if err != nil {
+			return "", nil, err
+		}
+		if parsed.Service != "elasticfilesystem" || !strings.HasPrefix(parsed.Resource, "file-system/") {
+			return "", nil, fmt.Errorf("volume %s: %s is not an EFS file system ARN", name, id)
+		}
+		if parsed.Region != b.Region {
+			return "", nil, fmt.Errorf("volume %s: EFS file system %s is in region %s, but project is deployed in %s", name, id, parsed.Region, b.Region)
+		}
+		id = strings.TrimPrefix(parsed.Resource, "file-system/")
+	}
+	if !fileSystemID.MatchString(id) {
+		return "", nil, fmt.Errorf("volume %s: external volume name %q must be an EFS file system ID or ARN", name, volume.Name)
+	}
+	ok, err := b.SDK.FileSystemExists(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		return "", nil, fmt.Errorf("volume %s: EFS file system %s not found in region %s", name, id, b.Region)
+	}
+	// a file system just created, or its mount targets, may not be available yet to create the stack's mount targets
+	err = b.SDK.WaitFileSystemAvailable(ctx, id, fileSystemAvailableTimeout)
+	if err != nil {
+		return "", nil, fmt.Errorf("volume %s: %w", name, err)
+	}
+	zones, err := b.SDK.GetMountTargetZones(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	return id, zones, nil
+}
+
+// createExternalMountTargets creates mount targets for an external volume in the selected availability zones it has none.
+// EFS only accept a single mount target per availability zone
+func createExternalMountTargets(name string, volume types.VolumeConfig, template *cloudformation.Template, resources *awsResources) {
+	zones := map[string]bool{}
+	for _, zone := range resources.mountZones[name] {
+		zones[zone] = true
+	}
+	var mountTargets []string
+	for _, subnet := range resources.subnets {
+		zone := resources.zones[subnet]
+		if zones[zone] {
+			continue
+		}
+		zones[zone] = true
+		mountTarget := fmt.Sprintf("%sNFSMountTargetOn%s", normalizeResourceName(name), normalizeResourceName(subnet))
+		template.Resources[mountTarget] = &efs.MountTarget{
+			FileSystemId:   volume.Name,
+			SecurityGroups: resources.allSecurityGroups(),
+			SubnetId:       subnet,
+		}
+		mountTargets = append(mountTargets, mountTarget)
+	}
+	if len(mountTargets) == 0 {
+		return
+	}
+	if resources.mountTargets == nil {
+		resources.mountTargets = map[string][]string{}
+	}
+	resources.mountTargets[name] = mountTargets
+}
+
+// volumeDriverLocal selects a Docker volume on the EC2 instances running the task, instead of an EFS file system
+const volumeDriverLocal = "local"
+
+// isLocalVolume tells if volume is a Docker volume local to EC2 instances
+func isLocalVolume(volume types.VolumeConfig) bool {
+	return volume.Driver == volumeDriverLocal
+}
+
+// checkVolumeBackends checks services only mount bind mounts and local volumes when deployed on EC2, as Fargate only supports EFS
+func checkVolumeBackends(project *types.Project) error {
+	for _, service := range project.Services {
+		if requireEC2(service) {
+			continue
+		}
+		for _, v := range service.Volumes {
+			if v.Type == types.VolumeTypeBind {
+				return fmt.Errorf("service %s: bind mount of %s requires EC2 launch type, Fargate services can only mount EFS volumes", service.Name, v.Source)
+			}
+			if !isLocalVolume(project.Volumes[v.Source]) {
+				continue
+			}
+			var ec2Services []string
+			for _, s := range project.Services {
+				if !requireEC2(s) {
+					continue
+				}
+				for _, m := range s.Volumes {
+					if m.Source == v.Source {
+						ec2Services = append(ec2Services, s.Name)
+						break
+					}
+				}
+			}
+			if len(ec2Services) > 0 {
+				return fmt.Errorf("service %s: volume %s uses the %s driver to store data on EC2 instances used by %s, but Fargate services can only mount EFS volumes", service.Name, v.Source, volumeDriverLocal, strings.Join(ec2Services, ", "))
+			}
+			return fmt.Errorf("service %s: volume %s uses the %s driver to store data on EC2 instances, but Fargate services can only mount EFS volumes", service.Name, v.Source, volumeDriverLocal)
+		}
+	}
+	return nil
+}
+
+var invalidVolumeNameCharacters = regexp.MustCompile("[^a-zA-Z0-9_-]+")
+
+// hostVolumeName is the task volume name for a bind mount from an EC2 instance path
+func hostVolumeName(path string) string {
+	return "host" + invalidVolumeNameCharacters.ReplaceAllString(path, "_")
+}
+
+// taskVolume returns the volume a task uses for a service's volume mount
+func taskVolume(project *types.Project, v types.ServiceVolumeConfig) ecs.TaskDefinition_Volume {
+	if v.Type == types.VolumeTypeBind {
+		return ecs.TaskDefinition_Volume{
+			Name: hostVolumeName(v.Source),
+			Host: &ecs.TaskDefinition_HostVolumeProperties{
+				SourcePath: v.Source,
+			},
+		}
+	}
+	volume := project.Volumes[v.Source]
+	if isLocalVolume(volume) {
+		// shared scope Docker volumes outlive the task, and are named after the task volume on the instance,
+		// so they get prefixed by project name as instances can be shared by projects
+		name := fmt.Sprintf("%s_%s", project.Name, v.Source)
+		if volume.External.External {
+			name = volume.Name
+		}
+		return ecs.TaskDefinition_Volume{
+			Name: name,
+			DockerVolumeConfiguration: &ecs.TaskDefinition_DockerVolumeConfiguration{
+				Autoprovision: !volume.External.External,
+				Driver:        volumeDriverLocal,
+				DriverOpts:    volume.DriverOpts,
+				Labels:        volume.Labels,
+				Scope:         ecsapi.ScopeShared,
+			},
+		}
+	}
+	return ecs.TaskDefinition_Volume{
+		EFSVolumeConfiguration: volumeEFSConfiguration(v.Source, volume),
+		Name:                   v.Source,
+	}
+}
+
+// createVolumes create an EFS file system with mount targets for each non-external volume
+func (b *ecsAPIService) createVolumes(project *types.Project, template *cloudformation.Template, resources *awsResources) error {
+	for name, volume := range project.Volumes {
+		if isLocalVolume(volume) {
+			continue
+		}
+		if volume.External.External {
+			createExternalMountTargets(name, volume, template, resources)
+			continue
+		}
+		fileSystem := fmt.Sprintf("%sFilesystem", normalizeResourceName(name))
+		settings, err := volumeFileSystemSettings(name, volume)
+		if err != nil {
+			return err
+		}
+		subnets := resources.subnets
+		if zone, ok := volume.DriverOpts[volumeAvailabilityZone]; ok {
+			subnets = resources.subnetsInZone(zone, resources.subnets)
+			if len(subnets) == 0 {
+				return fmt.Errorf("volume %s requires availability zone %s but none of the selected subnets are in this zone", name, zone)
+			}
+			// One Zone file system only accept a single mount target, in the same availability zone
+			subnets = subnets[:1]
+			b.warn(warningSingleAvailabilityZone, severityWarning, "", "volume %s uses EFS One Zone storage in %s, data is not replicated across availability zones", name, zone)
+			oneZone := &oneZoneFileSystem{
+				FileSystem: efs.FileSystem{
+					Encrypted:      true,
+					FileSystemTags: volumeTags(project, name),
+				},
+				AvailabilityZoneName: zone,
+			}
+			settings.apply(&oneZone.FileSystem)
+			err = applyVolumesPolicies(project, &oneZone.FileSystem)
+			if err != nil {
+				return err
+			}
+			template.Resources[fileSystem] = oneZone
+		} else {
+			fs := &efs.FileSystem{
+				Encrypted:      true,
+				FileSystemTags: volumeTags(project, name),
+			}
+			settings.apply(fs)
+			err = applyVolumesPolicies(project, fs)
+			if err != nil {
+				return err
+			}
+			template.Resources[fileSystem] = fs
+		}
+
+		var mountTargets []string
+		for _, subnet := range subnets {
+			mountTarget := fmt.Sprintf("%sNFSMountTargetOn%s", normalizeResourceName(name), normalizeResourceName(subnet))
+			template.Resources[mountTarget] = &efs.MountTarget{
+				FileSystemId:   cloudformation.Ref(fileSystem),
+				SecurityGroups: resources.allSecurityGroups(),
+				SubnetId:       subnet,
+			}
+			mountTargets = append(mountTargets, mountTarget)
+		}
+		if resources.mountTargets == nil {
+			resources.mountTargets = map[string][]string{}
+		}
+		resources.mountTargets[name] = mountTargets
+
+		volume.Name = cloudformation.Ref(fileSystem)
+		project.Volumes[name] = volume
+	}
+	return nil
+}
+
+// serviceSubnets select the subnets a service can be deployed to, as One Zone volumes constrain the availability zone
+func (r *awsResources) serviceSubnets(project *types.Project, service types.ServiceConfig) ([]string, error) {
+	var zone string
+	for _, v := range service.Volumes {
+		if isLocalVolume(project.Volumes[v.Source]) {
+			continue
+		}
+		z, ok := project.Volumes[v.Source].DriverOpts[volumeAvailabilityZone]
+		if !ok {
+			continue
+		}
+		if zone != "" && zone != z {
+			return nil, fmt.Errorf("service %s mounts volumes from distinct availability zones %s and %s", service.Name, zone, z)
+		}
+		zone = z
+	}
+	subnets, err := r.serviceNetworkSubnets(service)
+	if err != nil {
+		return nil, err
+	}
+	if zone == "" {
+		return subnets, nil
+	}
+	if service.Deploy != nil && service.Deploy.Replicas != nil && *service.Deploy.Replicas > 1 {
+		return nil, fmt.Errorf("service %s requires %d replicas to be spread across availability zones, but mounts One Zone volume in %s", service.Name, *service.Deploy.Replicas, zone)
+	}
+	subnets = r.subnetsInZone(zone, subnets)
+	if len(subnets) == 0 {
+		return nil, fmt.Errorf("service %s requires availability zone %s but none of the selected subnets are in this zone", service.Name, zone)
+	}
+	return subnets, nil
+}
+
+// oneZoneFileSystem is an EFS FileSystem using One Zone storage, not supported by goformation
+type oneZoneFileSystem struct {
+	efs.FileSystem
+	AvailabilityZoneName string
+}
+
+func (r oneZoneFileSystem) MarshalJSON() ([]byte, error) {
+	return marshalResource(r.FileSystem, func(properties map[string]interface{}) {
+		properties["AvailabilityZoneName"] = r.AvailabilityZoneName
+	})
+}
+
 func (b *ecsAPIService) createNFSmountIngress(securityGroups []string, project *types.Project, n string, template *cloudformation.Template) error {
 	target := securityGroups[0]
 	for _, s := range project.Services {
@@ -51,8 +657,13 @@ func (b *ecsAPIService) createNFSmountIngress(securityGroups []string, project *
 				FromPort:              2049,
 				ToPort:                2049,
 			}
-			service := template.Resources[serviceResourceName(s.Name)].(*ecs.Service)
-			service.AWSCloudFormationDependsOn = append(service.AWSCloudFormationDependsOn, name)
+			switch r := template.Resources[serviceResourceName(taskOwner(project, s.Name))].(type) {
+			case *ecs.Service:
+				r.AWSCloudFormationDependsOn = append(r.AWSCloudFormationDependsOn, name)
+			default:
+				rule := template.Resources[scheduleResourceName(taskOwner(project, s.Name))].(*events.Rule)
+				rule.AWSCloudFormationDependsOn = append(rule.AWSCloudFormationDependsOn, name)
+			}
 		}
 	}
 	return nil
@@ -0,0 +1,544 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	cf "github.com/aws/aws-sdk-go/service/cloudformation"
+	efsapi "github.com/aws/aws-sdk-go/service/efs"
+	"github.com/aws/aws-sdk-go/service/efs/efsiface"
+	"github.com/awslabs/goformation/v4/cloudformation"
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/efs"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+func TestVolumeAccessPoint(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/var/lib/postgresql/data
+      - backups:/backups:ro
+volumes:
+  data:
+    driver_opts:
+      uid: "1000"
+      gid: "1000"
+      root_directory: /pgdata
+  backups:
+    driver_opts:
+      uid: "1000"
+      gid: "1000"
+      permissions: "0700"
+`)
+	ap := template.Resources["DataAccessPoint"].(*efs.AccessPoint)
+	assert.Equal(t, ap.FileSystemId, cloudformation.Ref("DataFilesystem"))
+	assert.DeepEqual(t, ap.PosixUser, &efs.AccessPoint_PosixUser{Uid: "1000", Gid: "1000"})
+	assert.DeepEqual(t, ap.RootDirectory, &efs.AccessPoint_RootDirectory{
+		Path: "/pgdata",
+		CreationInfo: &efs.AccessPoint_CreationInfo{
+			OwnerUid:    "1000",
+			OwnerGid:    "1000",
+			Permissions: "0755",
+		},
+	})
+	backups := template.Resources["BackupsAccessPoint"].(*efs.AccessPoint)
+	assert.Equal(t, backups.FileSystemId, cloudformation.Ref("BackupsFilesystem"))
+	assert.Equal(t, backups.RootDirectory.Path, "/")
+	assert.Equal(t, backups.RootDirectory.CreationInfo.Permissions, "0700")
+
+	def := template.Resources["DbTaskDefinition"].(*ecs.TaskDefinition)
+	for _, v := range def.Volumes {
+		if v.Name != "data" {
+			continue
+		}
+		assert.DeepEqual(t, v.EFSVolumeConfiguration, &ecs.TaskDefinition_EFSVolumeConfiguration{
+			FilesystemId:      cloudformation.Ref("DataFilesystem"),
+			TransitEncryption: "ENABLED",
+			AuthorizationConfig: &ecs.TaskDefinition_AuthorizationConfig{
+				AccessPointId: cloudformation.Ref("DataAccessPoint"),
+				IAM:           "ENABLED",
+			},
+		})
+	}
+
+	role := template.Resources["DbTaskRole"].(*iam.Role)
+	assert.Equal(t, len(role.Policies), 1)
+	statements := role.Policies[0].PolicyDocument.(*PolicyDocument).Statement
+	assert.Equal(t, len(statements), 2)
+	assert.DeepEqual(t, statements[0], PolicyStatement{
+		Effect:   "Allow",
+		Action:   []string{actionClientMount, actionClientWrite},
+		Resource: []string{cloudformation.GetAtt("DataFilesystem", "Arn")},
+		Condition: map[string]interface{}{
+			"StringEquals": map[string]interface{}{
+				"elasticfilesystem:AccessPointArn": cloudformation.GetAtt("DataAccessPoint", "Arn"),
+			},
+		},
+	})
+	assert.DeepEqual(t, statements[1].Action, []string{actionClientMount})
+	assert.DeepEqual(t, statements[1].Resource, []string{cloudformation.GetAtt("BackupsFilesystem", "Arn")})
+}
+
+func TestVolumeWithoutAccessPoint(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/var/lib/postgresql/data
+volumes:
+  data:
+    driver_opts:
+      root_directory: /pgdata
+`)
+	_, ok := template.Resources["DataAccessPoint"]
+	assert.Check(t, !ok)
+	_, ok = template.Resources["DbTaskRole"]
+	assert.Check(t, !ok)
+	def := template.Resources["DbTaskDefinition"].(*ecs.TaskDefinition)
+	assert.DeepEqual(t, def.Volumes[0].EFSVolumeConfiguration, &ecs.TaskDefinition_EFSVolumeConfiguration{
+		FilesystemId:  cloudformation.Ref("DataFilesystem"),
+		RootDirectory: "/pgdata",
+	})
+}
+
+func TestVolumeAccessPointErrors(t *testing.T) {
+	for opts, expected := range map[string]string{
+		`{uid: "1000"}`:                               "volume data: uid and gid driver options must both be set",
+		`{uid: "root", gid: "0"}`:                     `volume data: invalid uid "root"`,
+		`{uid: "1000", gid: "1000", permissions: rw}`: `volume data: invalid permissions "rw", octal mode is expected`,
+	} {
+		project := loadConfig(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/data
+volumes:
+  data:
+    driver_opts: `+opts+`
+`)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.Error(t, err, expected, opts)
+	}
+}
+
+func TestVolumeFileSystemSettings(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/var/lib/postgresql/data
+volumes:
+  data:
+    driver_opts:
+      throughput_mode: provisioned
+      provisioned_throughput_mibps: "128"
+      performance_mode: maxIO
+      lifecycle_policy: AFTER_30_DAYS
+  default:
+`)
+	fs := template.Resources["DataFilesystem"].(*efs.FileSystem)
+	assert.Equal(t, fs.ThroughputMode, "provisioned")
+	assert.Equal(t, fs.ProvisionedThroughputInMibps, 128.0)
+	assert.Equal(t, fs.PerformanceMode, "maxIO")
+	assert.DeepEqual(t, fs.LifecyclePolicies, []efs.FileSystem_LifecyclePolicy{{TransitionToIA: "AFTER_30_DAYS"}})
+
+	fs = template.Resources["DefaultFilesystem"].(*efs.FileSystem)
+	assert.Equal(t, fs.ThroughputMode, "")
+	assert.Equal(t, fs.PerformanceMode, "")
+	assert.Check(t, fs.LifecyclePolicies == nil)
+}
+
+func TestVolumeFileSystemSettingsErrors(t *testing.T) {
+	for opts, expected := range map[string]string{
+		`{throughput_mode: elastic}`:                         `volume data: invalid throughput_mode "elastic", must be one of bursting, provisioned`,
+		`{throughput_mode: provisioned}`:                     "volume data: throughput_mode provisioned requires provisioned_throughput_mibps to be set",
+		`{provisioned_throughput_mibps: "10"}`:               "volume data: provisioned_throughput_mibps requires throughput_mode to be provisioned",
+		`{performance_mode: fast}`:                           `volume data: invalid performance_mode "fast", must be one of generalPurpose, maxIO`,
+		`{lifecycle_policy: AFTER_1_DAY}`:                    `volume data: invalid lifecycle_policy "AFTER_1_DAY", must be one of AFTER_7_DAYS, AFTER_14_DAYS, AFTER_30_DAYS, AFTER_60_DAYS, AFTER_90_DAYS`,
+		`{performance_mode: maxIO, availability_zone: zone}`: "volume data: EFS One Zone storage doesn't support performance_mode maxIO",
+	} {
+		project := loadConfig(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/data
+volumes:
+  data:
+    driver_opts: `+opts+`
+`)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.Error(t, err, expected, opts)
+	}
+}
+
+func TestCheckFileSystemSettings(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/data
+      - logs:/logs
+volumes:
+  data:
+    driver_opts:
+      performance_mode: maxIO
+  logs:
+    driver_opts:
+      lifecycle_policy: AFTER_7_DAYS
+`)
+	cfMock := &mockCloudFormation{}
+	cfMock.On("DescribeStacksWithContext", "Test").Return(&cf.DescribeStacksOutput{
+		Stacks: []*cf.Stack{{StackName: aws.String("Test")}},
+	}, nil)
+	cfMock.On("ListStackResourcesWithContext", "Test").Return(&cf.ListStackResourcesOutput{
+		StackResourceSummaries: []*cf.StackResourceSummary{
+			{LogicalResourceId: aws.String("DataFilesystem"), ResourceType: aws.String("AWS::EFS::FileSystem"), PhysicalResourceId: aws.String("fs-data")},
+			{LogicalResourceId: aws.String("LogsFilesystem"), ResourceType: aws.String("AWS::EFS::FileSystem"), PhysicalResourceId: aws.String("fs-logs")},
+		},
+	}, nil)
+	efsMock := &mockEFS{}
+	for _, id := range []string{"fs-data", "fs-logs"} {
+		efsMock.On("DescribeFileSystemsWithContext", id).Return(&efsapi.DescribeFileSystemsOutput{
+			FileSystems: []*efsapi.FileSystemDescription{
+				{
+					FileSystemId:    aws.String(id),
+					ThroughputMode:  aws.String("bursting"),
+					PerformanceMode: aws.String("generalPurpose"),
+				},
+			},
+		}, nil)
+	}
+	efsMock.On("DescribeLifecycleConfigurationWithContext", "fs-data").Return(&efsapi.DescribeLifecycleConfigurationOutput{}, nil)
+	efsMock.On("DescribeLifecycleConfigurationWithContext", "fs-logs").Return(&efsapi.DescribeLifecycleConfigurationOutput{
+		LifecyclePolicies: []*efsapi.LifecyclePolicy{{TransitionToIA: aws.String("AFTER_7_DAYS")}},
+	}, nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cfMock, EFS: efsMock}}
+	err := backend.checkFileSystemSettings(context.TODO(), project)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, backend.warnings, convertWarnings{
+		{
+			Code:     warningFileSystemSettings,
+			Severity: severityWarning,
+			Message:  "file system fs-data doesn't match volume settings: performance_mode generalPurpose (requested maxIO), it will be replaced and its content lost",
+		},
+	})
+}
+
+func TestParseExternalVolumes(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/data
+      - logs:/logs
+volumes:
+  data:
+    external: true
+    name: fs-0123456789abcdef0
+  logs:
+    external: true
+    name: arn:aws:elasticfilesystem:eu-west-3:012345678910:file-system/fs-12345678
+`)
+	efsMock := &mockEFS{}
+	for _, id := range []string{"fs-0123456789abcdef0", "fs-12345678"} {
+		efsMock.On("DescribeFileSystemsWithContext", id).Return(&efsapi.DescribeFileSystemsOutput{
+			FileSystems: []*efsapi.FileSystemDescription{{LifeCycleState: aws.String("available")}},
+		}, nil)
+	}
+	efsMock.On("DescribeMountTargetsWithContext", "fs-0123456789abcdef0").Return(&efsapi.DescribeMountTargetsOutput{
+		MountTargets: []*efsapi.MountTargetDescription{{AvailabilityZoneName: aws.String("eu-west-3a"), LifeCycleState: aws.String("available")}},
+	}, nil)
+	efsMock.On("DescribeMountTargetsWithContext", "fs-12345678").Return(&efsapi.DescribeMountTargetsOutput{}, nil)
+
+	backend := &ecsAPIService{Region: "eu-west-3", SDK: sdk{EFS: efsMock}}
+	zones, err := backend.parseExternalVolumes(context.TODO(), project)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, zones, map[string][]string{
+		"data": {"eu-west-3a"},
+		"logs": nil,
+	})
+	assert.Equal(t, project.Volumes["logs"].Name, "fs-12345678")
+}
+
+func TestParseExternalVolumesErrors(t *testing.T) {
+	efsMock := &mockEFS{}
+	efsMock.On("DescribeFileSystemsWithContext", "fs-12345678").Return((*efsapi.DescribeFileSystemsOutput)(nil),
+		awserr.New(efsapi.ErrCodeFileSystemNotFound, "File system 'fs-12345678' does not exist.", nil))
+	backend := &ecsAPIService{Region: "eu-west-3", SDK: sdk{EFS: efsMock}}
+	for name, expected := range map[string]string{
+		"fs-12345678": "volume data: EFS file system fs-12345678 not found in region eu-west-3",
+		"arn:aws:elasticfilesystem:us-east-1:012345678910:file-system/fs-12345678": "volume data: EFS file system arn:aws:elasticfilesystem:us-east-1:012345678910:file-system/fs-12345678 is in region us-east-1, but project is deployed in eu-west-3",
+		"arn:aws:s3:::bucket": "volume data: arn:aws:s3:::bucket is not an EFS file system ARN",
+		"my_data":             `volume data: external volume name "my_data" must be an EFS file system ID or ARN`,
+	} {
+		project := loadConfig(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/data
+volumes:
+  data:
+    external: true
+    name: `+name+`
+`)
+		_, err := backend.parseExternalVolumes(context.TODO(), project)
+		assert.Error(t, err, expected, name)
+	}
+}
+
+func TestExternalVolumeMountTargets(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/data
+volumes:
+  data:
+    external: true
+    name: fs-12345678
+`)
+	backend := &ecsAPIService{}
+	template, err := backend.convert(project, awsResources{
+		subnets:    []string{"subnet1", "subnet2", "subnet3"},
+		zones:      map[string]string{"subnet1": "zone-a", "subnet2": "zone-b", "subnet3": "zone-b"},
+		mountZones: map[string][]string{"data": {"zone-a"}},
+	})
+	assert.NilError(t, err)
+	_, ok := template.Resources["DataFilesystem"]
+	assert.Check(t, !ok)
+	_, ok = template.Resources["DataNFSMountTargetOnSubnet1"]
+	assert.Check(t, !ok)
+	_, ok = template.Resources["DataNFSMountTargetOnSubnet3"]
+	assert.Check(t, !ok)
+	mountTarget := template.Resources["DataNFSMountTargetOnSubnet2"].(*efs.MountTarget)
+	assert.Equal(t, mountTarget.FileSystemId, "fs-12345678")
+	assert.Equal(t, mountTarget.SubnetId, "subnet2")
+
+	service := template.Resources["DbService"].(*ecs.Service)
+	assert.Check(t, is.Contains(service.AWSCloudFormationDependsOn, "DataNFSMountTargetOnSubnet2"))
+}
+
+func TestWaitFileSystemAvailable(t *testing.T) {
+	interval := fileSystemPollInterval
+	fileSystemPollInterval = time.Millisecond
+	defer func() { fileSystemPollInterval = interval }()
+
+	efsMock := &mockEFS{}
+	efsMock.On("DescribeFileSystemsWithContext", "fs-12345678").Return(&efsapi.DescribeFileSystemsOutput{
+		FileSystems: []*efsapi.FileSystemDescription{{LifeCycleState: aws.String("creating")}},
+	}, nil).Once()
+	efsMock.On("DescribeFileSystemsWithContext", "fs-12345678").Return(&efsapi.DescribeFileSystemsOutput{
+		FileSystems: []*efsapi.FileSystemDescription{{LifeCycleState: aws.String("available")}},
+	}, nil)
+	efsMock.On("DescribeMountTargetsWithContext", "fs-12345678").Return(&efsapi.DescribeMountTargetsOutput{
+		MountTargets: []*efsapi.MountTargetDescription{{MountTargetId: aws.String("fsmt-1"), LifeCycleState: aws.String("creating")}},
+	}, nil).Once()
+	efsMock.On("DescribeMountTargetsWithContext", "fs-12345678").Return(&efsapi.DescribeMountTargetsOutput{
+		MountTargets: []*efsapi.MountTargetDescription{{MountTargetId: aws.String("fsmt-1"), LifeCycleState: aws.String("available")}},
+	}, nil)
+
+	err := sdk{EFS: efsMock}.WaitFileSystemAvailable(context.TODO(), "fs-12345678", time.Minute)
+	assert.NilError(t, err)
+	efsMock.AssertNumberOfCalls(t, "DescribeFileSystemsWithContext", 3)
+	efsMock.AssertNumberOfCalls(t, "DescribeMountTargetsWithContext", 2)
+}
+
+func TestWaitFileSystemAvailableErrors(t *testing.T) {
+	interval := fileSystemPollInterval
+	fileSystemPollInterval = time.Millisecond
+	defer func() { fileSystemPollInterval = interval }()
+
+	efsMock := &mockEFS{}
+	efsMock.On("DescribeFileSystemsWithContext", "fs-deleting").Return(&efsapi.DescribeFileSystemsOutput{
+		FileSystems: []*efsapi.FileSystemDescription{{LifeCycleState: aws.String("deleting")}},
+	}, nil)
+	efsMock.On("DescribeFileSystemsWithContext", "fs-creating").Return(&efsapi.DescribeFileSystemsOutput{
+		FileSystems: []*efsapi.FileSystemDescription{{LifeCycleState: aws.String("creating")}},
+	}, nil)
+
+	err := sdk{EFS: efsMock}.WaitFileSystemAvailable(context.TODO(), "fs-deleting", time.Minute)
+	assert.Error(t, err, "EFS file system fs-deleting is deleting")
+
+	err = sdk{EFS: efsMock}.WaitFileSystemAvailable(context.TODO(), "fs-creating", 20*time.Millisecond)
+	assert.Error(t, err, "EFS file system fs-creating still creating after 20ms: context deadline exceeded")
+	assert.Check(t, errors.Is(err, context.DeadlineExceeded))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	err = sdk{EFS: efsMock}.WaitFileSystemAvailable(ctx, "fs-creating", time.Minute)
+	assert.Check(t, errors.Is(err, context.Canceled))
+}
+
+func TestEC2LocalVolumeAndBindMount(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  db:
+    image: postgres
+    shm_size: 1G
+    volumes:
+      - data:/var/lib/postgresql/data
+      - /var/log/db:/logs:ro
+volumes:
+  data:
+    driver: local
+    driver_opts:
+      type: tmpfs
+      device: tmpfs
+`)
+	_, ok := template.Resources["DataFilesystem"]
+	assert.Check(t, !ok)
+
+	def := template.Resources["DbTaskDefinition"].(*ecs.TaskDefinition)
+	assert.DeepEqual(t, def.Volumes, []ecs.TaskDefinition_Volume{
+		{
+			Name: "Test_data",
+			DockerVolumeConfiguration: &ecs.TaskDefinition_DockerVolumeConfiguration{
+				Autoprovision: true,
+				Driver:        "local",
+				DriverOpts:    map[string]string{"type": "tmpfs", "device": "tmpfs"},
+				Scope:         "shared",
+			},
+		},
+		{
+			Name: "host_var_log_db",
+			Host: &ecs.TaskDefinition_HostVolumeProperties{SourcePath: "/var/log/db"},
+		},
+	})
+	container := getMainContainer(def, t)
+	assert.DeepEqual(t, container.MountPoints, []ecs.TaskDefinition_MountPoint{
+		{ContainerPath: "/var/lib/postgresql/data", SourceVolume: "Test_data"},
+		{ContainerPath: "/logs", ReadOnly: true, SourceVolume: "host_var_log_db"},
+	})
+}
+
+func TestFargateVolumeBackends(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`
+services:
+  web:
+    image: nginx
+    volumes:
+      - /var/log/web:/logs
+`: "service web: bind mount of /var/log/web requires EC2 launch type, Fargate services can only mount EFS volumes",
+		`
+services:
+  db:
+    image: postgres
+    shm_size: 1G
+    volumes:
+      - data:/data
+  backup:
+    image: backup
+    volumes:
+      - data:/data
+volumes:
+  data:
+    driver: local
+`: "service backup: volume data uses the local driver to store data on EC2 instances used by db, but Fargate services can only mount EFS volumes",
+	} {
+		project := loadConfig(t, yaml)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.Error(t, err, expected)
+	}
+}
+
+type mockEFS struct {
+	efsiface.EFSAPI
+	mock.Mock
+}
+
+func (m *mockEFS) DescribeFileSystemsWithContext(_ aws.Context, in *efsapi.DescribeFileSystemsInput, _ ...request.Option) (*efsapi.DescribeFileSystemsOutput, error) {
+	args := m.Called(aws.StringValue(in.FileSystemId))
+	return args.Get(0).(*efsapi.DescribeFileSystemsOutput), args.Error(1)
+}
+
+func (m *mockEFS) DescribeLifecycleConfigurationWithContext(_ aws.Context, in *efsapi.DescribeLifecycleConfigurationInput, _ ...request.Option) (*efsapi.DescribeLifecycleConfigurationOutput, error) {
+	args := m.Called(aws.StringValue(in.FileSystemId))
+	return args.Get(0).(*efsapi.DescribeLifecycleConfigurationOutput), args.Error(1)
+}
+
+func (m *mockEFS) DescribeMountTargetsWithContext(_ aws.Context, in *efsapi.DescribeMountTargetsInput, _ ...request.Option) (*efsapi.DescribeMountTargetsOutput, error) {
+	args := m.Called(aws.StringValue(in.FileSystemId))
+	return args.Get(0).(*efsapi.DescribeMountTargetsOutput), args.Error(1)
+}
+
+func (m *mockEFS) DescribeFileSystemsPagesWithContext(_ aws.Context, in *efsapi.DescribeFileSystemsInput, fn func(*efsapi.DescribeFileSystemsOutput, bool) bool, _ ...request.Option) error {
+	args := m.Called()
+	pages := args.Get(0).([]*efsapi.DescribeFileSystemsOutput)
+	for i, page := range pages {
+		if !fn(page, i == len(pages)-1) {
+			break
+		}
+	}
+	return args.Error(1)
+}
+
+func TestListFileSystemsPaginates(t *testing.T) {
+	fileSystem := func(id string, project string) *efsapi.FileSystemDescription {
+		return &efsapi.FileSystemDescription{
+			FileSystemArn: aws.String("arn:aws:elasticfilesystem:eu-west-3:123456789012:file-system/" + id),
+			SizeInBytes:   &efsapi.FileSystemSize{Value: aws.Int64(1024)},
+			Tags: []*efsapi.Tag{
+				{Key: aws.String(compose.ProjectTag), Value: aws.String(project)},
+				{Key: aws.String(compose.VolumeTag), Value: aws.String("data")},
+			},
+		}
+	}
+	efsMock := &mockEFS{}
+	efsMock.On("DescribeFileSystemsPagesWithContext").Return([]*efsapi.DescribeFileSystemsOutput{
+		{FileSystems: []*efsapi.FileSystemDescription{fileSystem("fs-1", "test"), fileSystem("fs-2", "test-other")}},
+		{FileSystems: []*efsapi.FileSystemDescription{fileSystem("fs-3", "test")}},
+	}, nil)
+
+	resources, err := sdk{EFS: efsMock}.ListFileSystems(context.TODO(), "test")
+	assert.NilError(t, err)
+	var arns []string
+	for _, r := range resources {
+		arns = append(arns, r.ARN)
+	}
+	assert.DeepEqual(t, arns, []string{
+		"arn:aws:elasticfilesystem:eu-west-3:123456789012:file-system/fs-1",
+		"arn:aws:elasticfilesystem:eu-west-3:123456789012:file-system/fs-3",
+	})
+}
@@ -0,0 +1,217 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/awslabs/goformation/v4/cloudformation/efs"
+	"github.com/awslabs/goformation/v4/cloudformation/policies"
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose-cli/api/compose"
+	"github.com/docker/compose-cli/progress"
+)
+
+// volumes deletion policies select what down does with the file systems created by project's stack
+const (
+	volumesDeletionDelete = "delete"
+	volumesDeletionRetain = "retain"
+	volumesDeletionBackup = "backup"
+)
+
+// stack tags recording the volumes deletion policy and backup settings, so down honors the ones set on last deployment
+const (
+	volumesDeletionTag    = "com.docker.compose.volumes.deletion"
+	volumesBackupVaultTag = "com.docker.compose.volumes.backup_vault"
+	volumesBackupRoleTag  = "com.docker.compose.volumes.backup_role"
+)
+
+// final backups are stored in the AWS Backup default vault, using the AWS Backup default service role, unless project
+// sets x-aws-volumes_backup_vault and x-aws-volumes_backup_role
+const (
+	backupVault       = "Default"
+	backupServiceRole = "AWSBackupDefaultServiceRole"
+)
+
+// backupTimeout bounds the wait for a final backup, down fails without deleting the stack when exceeded
+const backupTimeout = time.Hour
+
+// volumesDeletion returns the volumes deletion policy set by x-aws-volumes_deletion, delete if unset
+func volumesDeletion(project *types.Project) (string, error) {
+	x, ok := project.Extensions[extensionVolumesDeletion]
+	if !ok {
+		return volumesDeletionDelete, nil
+	}
+	policy := fmt.Sprint(x)
+	switch policy {
+	case volumesDeletionDelete, volumesDeletionRetain, volumesDeletionBackup:
+		return policy, nil
+	}
+	return "", fmt.Errorf("%s must be one of %s, %s or %s, got %q", extensionVolumesDeletion, volumesDeletionDelete, volumesDeletionRetain, volumesDeletionBackup, policy)
+}
+
+// volumesBackup tells if x-aws-volumes_backup enables AWS Backup automatic backups on created file systems
+func volumesBackup(project *types.Project) (bool, error) {
+	x, ok := project.Extensions[extensionVolumesBackup]
+	if !ok {
+		return false, nil
+	}
+	enabled, ok := x.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean, got %v", extensionVolumesBackup, x)
+	}
+	return enabled, nil
+}
+
+// volumesBackupSettings returns the vault and role final backups use, set by x-aws-volumes_backup_vault and
+// x-aws-volumes_backup_role, empty for the defaults
+func volumesBackupSettings(project *types.Project) (string, string, error) {
+	var settings []string
+	for _, key := range []string{extensionVolumesBackupVault, extensionVolumesBackupRole} {
+		x, ok := project.Extensions[key]
+		if !ok {
+			settings = append(settings, "")
+			continue
+		}
+		value, ok := x.(string)
+		if !ok || value == "" {
+			return "", "", fmt.Errorf("%s must be a non-empty string, got %v", key, x)
+		}
+		settings = append(settings, value)
+	}
+	return settings[0], settings[1], nil
+}
+
+// applyVolumesPolicies sets the backup and deletion policies selected by project on a created file system
+func applyVolumesPolicies(project *types.Project, fs *efs.FileSystem) error {
+	backup, err := volumesBackup(project)
+	if err != nil {
+		return err
+	}
+	if backup {
+		fs.BackupPolicy = &efs.FileSystem_BackupPolicy{Status: "ENABLED"}
+	}
+	deletion, err := volumesDeletion(project)
+	if err != nil {
+		return err
+	}
+	if deletion == volumesDeletionRetain {
+		fs.AWSCloudFormationDeletionPolicy = policies.DeletionPolicy("Retain")
+	}
+	return nil
+}
+
+// projectStackTags are the tags set on project's stack
+func projectStackTags(project *types.Project) (map[string]string, error) {
+	deletion, err := volumesDeletion(project)
+	if err != nil {
+		return nil, err
+	}
+	vault, role, err := volumesBackupSettings(project)
+	if err != nil {
+		return nil, err
+	}
+	tags := userStackTags(project)
+	tags[compose.ProjectTag] = project.Name
+	tags[volumesDeletionTag] = deletion
+	if vault != "" {
+		tags[volumesBackupVaultTag] = vault
+	}
+	if role != "" {
+		tags[volumesBackupRoleTag] = role
+	}
+	return tags, nil
+}
+
+// prepareVolumesDeletion applies the volumes deletion policy recorded on project's stack before it gets deleted.
+// Deleting file systems requires confirmation, and the backup policy takes a final backup of each of them first
+func (b *ecsAPIService) prepareVolumesDeletion(ctx context.Context, project string, resources stackResources, options compose.DownOptions) error {
+	var fileSystems []stackResource
+	for _, r := range resources {
+		if r.Type == awsTypeFileSystem {
+			fileSystems = append(fileSystems, r)
+		}
+	}
+	if len(fileSystems) == 0 {
+		return nil
+	}
+	tags, err := b.SDK.GetStackTags(ctx, project)
+	if err != nil {
+		return err
+	}
+	switch tags[volumesDeletionTag] {
+	case volumesDeletionRetain:
+		return nil
+	case volumesDeletionBackup:
+		w := progress.ContextWriter(ctx)
+		vault, role := tags[volumesBackupVaultTag], tags[volumesBackupRoleTag]
+		if vault == "" {
+			vault = backupVault
+		}
+		if role == "" {
+			role = "service-role/" + backupServiceRole
+		}
+		for _, fs := range fileSystems {
+			w.Event(progress.Event{
+				ID:         fs.LogicalID,
+				Status:     progress.Working,
+				StatusText: "BACKUP_IN_PROGRESS",
+			})
+			err := b.backupFileSystem(ctx, fs.ARN, vault, role)
+			if err != nil {
+				return err
+			}
+			w.Event(progress.Event{
+				ID:         fs.LogicalID,
+				Status:     progress.Done,
+				StatusText: "BACKUP_COMPLETE",
+			})
+		}
+		return nil
+	default:
+		if options.Volumes || options.Force {
+			return nil
+		}
+		var volumes []string
+		for _, fs := range fileSystems {
+			volumes = append(volumes, fmt.Sprintf("%s (%s)", strings.TrimSuffix(fs.LogicalID, "Filesystem"), fs.ARN))
+		}
+		return &compose.VolumesDeletionError{
+			Project: project,
+			Volumes: volumes,
+		}
+	}
+}
+
+// backupFileSystem takes a final backup of file system id, waiting for it up to backupTimeout
+func (b *ecsAPIService) backupFileSystem(ctx context.Context, id string, vault string, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, backupTimeout)
+	defer cancel()
+	err := b.SDK.BackupFileSystem(ctx, id, vault, role)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("backup of EFS file system %s still running after %s, the stack was not deleted", id, backupTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("can't back up EFS file system %s in vault %s with role %s, set %s and %s to an existing vault and role: %w",
+			id, vault, role, extensionVolumesBackupVault, extensionVolumesBackupRole, err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/backup"
+	"github.com/aws/aws-sdk-go/service/backup/backupiface"
+	cf "github.com/aws/aws-sdk-go/service/cloudformation"
+	efsapi "github.com/aws/aws-sdk-go/service/efs"
+	"github.com/awslabs/goformation/v4/cloudformation/efs"
+	"github.com/awslabs/goformation/v4/cloudformation/policies"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+func TestVolumesPolicies(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/data
+volumes:
+  data:
+x-aws-volumes_backup: true
+x-aws-volumes_deletion: retain
+`)
+	fs := template.Resources["DataFilesystem"].(*efs.FileSystem)
+	assert.DeepEqual(t, fs.BackupPolicy, &efs.FileSystem_BackupPolicy{Status: "ENABLED"})
+	assert.Equal(t, fs.AWSCloudFormationDeletionPolicy, policies.DeletionPolicy("Retain"))
+
+	template = convertYaml(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/data
+volumes:
+  data:
+`)
+	fs = template.Resources["DataFilesystem"].(*efs.FileSystem)
+	assert.Check(t, fs.BackupPolicy == nil)
+	assert.Equal(t, fs.AWSCloudFormationDeletionPolicy, policies.DeletionPolicy(""))
+}
+
+func TestOneZoneVolumeRetained(t *testing.T) {
+	fs := oneZoneFileSystem{
+		FileSystem: efs.FileSystem{
+			AWSCloudFormationDeletionPolicy: policies.DeletionPolicy("Retain"),
+			AWSCloudFormationDependsOn:      []string{"Key"},
+		},
+		AvailabilityZoneName: "zone-a",
+	}
+	raw, err := json.Marshal(fs)
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), `{"DeletionPolicy":"Retain","DependsOn":["Key"],"Properties":{"AvailabilityZoneName":"zone-a"},"Type":"AWS::EFS::FileSystem"}`)
+}
+
+func TestVolumesPoliciesInvalid(t *testing.T) {
+	for x, expected := range map[string]string{
+		"x-aws-volumes_deletion: snapshot": `x-aws-volumes_deletion must be one of delete, retain or backup, got "snapshot"`,
+		"x-aws-volumes_backup: daily":      "x-aws-volumes_backup must be a boolean, got daily",
+	} {
+		project := loadConfig(t, `
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/data
+volumes:
+  data:
+`+x+`
+`)
+		backend := &ecsAPIService{}
+		_, err := backend.convert(project, awsResources{})
+		assert.Error(t, err, expected, x)
+	}
+}
+
+func TestProjectStackTags(t *testing.T) {
+	project := loadConfig(t, `
+services:
+  db:
+    image: postgres
+x-aws-volumes_deletion: backup
+`)
+	tags, err := projectStackTags(project)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, tags, map[string]string{
+		"com.docker.compose.project":          "Test",
+		"com.docker.compose.volumes.deletion": "backup",
+	})
+
+	project.Extensions[extensionVolumesBackupVault] = "compliance"
+	project.Extensions[extensionVolumesBackupRole] = "arn:aws:iam::012345678910:role/backup"
+	tags, err = projectStackTags(project)
+	assert.NilError(t, err)
+	assert.Equal(t, tags["com.docker.compose.volumes.backup_vault"], "compliance")
+	assert.Equal(t, tags["com.docker.compose.volumes.backup_role"], "arn:aws:iam::012345678910:role/backup")
+
+	project.Extensions[extensionVolumesBackupVault] = 42
+	_, err = projectStackTags(project)
+	assert.Error(t, err, "x-aws-volumes_backup_vault must be a non-empty string, got 42")
+}
+
+func volumesDeletionStack(policy string, tags ...*cf.Tag) *mockCloudFormation {
+	cfMock := &mockCloudFormation{}
+	stack := &cf.Stack{StackName: aws.String("test"), Tags: tags}
+	if policy != "" {
+		stack.Tags = append(stack.Tags, &cf.Tag{Key: aws.String(volumesDeletionTag), Value: aws.String(policy)})
+	}
+	cfMock.On("DescribeStacksWithContext", "test").Return(&cf.DescribeStacksOutput{
+		Stacks: []*cf.Stack{stack},
+	}, nil)
+	return cfMock
+}
+
+var volumesDeletionResources = stackResources{
+	{LogicalID: "Cluster", Type: "AWS::ECS::Cluster", ARN: "cluster"},
+	{LogicalID: "DataFilesystem", Type: awsTypeFileSystem, ARN: "fs-12345678"},
+}
+
+func TestPrepareVolumesDeletionRequiresConfirmation(t *testing.T) {
+	backend := &ecsAPIService{SDK: sdk{CF: volumesDeletionStack("")}}
+	err := backend.prepareVolumesDeletion(context.TODO(), "test", volumesDeletionResources, compose.DownOptions{})
+	assert.Error(t, err, "deleting project test also deletes volumes Data (fs-12345678), use --volumes to confirm")
+
+	err = backend.prepareVolumesDeletion(context.TODO(), "test", volumesDeletionResources, compose.DownOptions{Volumes: true})
+	assert.NilError(t, err)
+
+	backend = &ecsAPIService{SDK: sdk{CF: volumesDeletionStack(volumesDeletionRetain)}}
+	err = backend.prepareVolumesDeletion(context.TODO(), "test", volumesDeletionResources, compose.DownOptions{})
+	assert.NilError(t, err)
+
+	// no file system, stack tags don't need to be checked
+	backend = &ecsAPIService{SDK: sdk{CF: &mockCloudFormation{}}}
+	err = backend.prepareVolumesDeletion(context.TODO(), "test", volumesDeletionResources[:1], compose.DownOptions{})
+	assert.NilError(t, err)
+}
+
+func TestPrepareVolumesDeletionBackup(t *testing.T) {
+	efsMock := &mockEFS{}
+	efsMock.On("DescribeFileSystemsWithContext", "fs-12345678").Return(&efsapi.DescribeFileSystemsOutput{
+		FileSystems: []*efsapi.FileSystemDescription{
+			{FileSystemArn: aws.String("arn:aws:elasticfilesystem:eu-west-3:012345678910:file-system/fs-12345678")},
+		},
+	}, nil)
+	backupMock := &mockBackup{}
+	backupMock.On("StartBackupJobWithContext", "arn:aws:elasticfilesystem:eu-west-3:012345678910:file-system/fs-12345678", "Default", "arn:aws:iam::012345678910:role/service-role/AWSBackupDefaultServiceRole").Return(&backup.StartBackupJobOutput{
+		BackupJobId: aws.String("job"),
+	}, nil)
+	backupMock.On("DescribeBackupJobWithContext", "job").Return(&backup.DescribeBackupJobOutput{
+		State: aws.String(backup.JobStateCompleted),
+	}, nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: volumesDeletionStack(volumesDeletionBackup), EFS: efsMock, BK: backupMock}}
+	err := backend.prepareVolumesDeletion(context.TODO(), "test", volumesDeletionResources, compose.DownOptions{})
+	assert.NilError(t, err)
+	backupMock.AssertExpectations(t)
+}
+
+func TestPrepareVolumesDeletionBackupSettings(t *testing.T) {
+	efsMock := &mockEFS{}
+	efsMock.On("DescribeFileSystemsWithContext", "fs-12345678").Return(&efsapi.DescribeFileSystemsOutput{
+		FileSystems: []*efsapi.FileSystemDescription{
+			{FileSystemArn: aws.String("arn:aws:elasticfilesystem:eu-west-3:012345678910:file-system/fs-12345678")},
+		},
+	}, nil)
+	backupMock := &mockBackup{}
+	backupMock.On("StartBackupJobWithContext", "arn:aws:elasticfilesystem:eu-west-3:012345678910:file-system/fs-12345678", "compliance", "arn:aws:iam::012345678910:role/backup").Return(
+		(*backup.StartBackupJobOutput)(nil), awserr.New(backup.ErrCodeResourceNotFoundException, "vault not found", nil))
+
+	cfMock := volumesDeletionStack(volumesDeletionBackup,
+		&cf.Tag{Key: aws.String(volumesBackupVaultTag), Value: aws.String("compliance")},
+		&cf.Tag{Key: aws.String(volumesBackupRoleTag), Value: aws.String("backup")})
+	backend := &ecsAPIService{SDK: sdk{CF: cfMock, EFS: efsMock, BK: backupMock}}
+	err := backend.prepareVolumesDeletion(context.TODO(), "test", volumesDeletionResources, compose.DownOptions{})
+	assert.ErrorContains(t, err, "can't back up EFS file system fs-12345678 in vault compliance with role backup, set x-aws-volumes_backup_vault and x-aws-volumes_backup_role to an existing vault and role")
+	backupMock.AssertExpectations(t)
+}
+
+type mockBackup struct {
+	backupiface.BackupAPI
+	mock.Mock
+}
+
+func (m *mockBackup) StartBackupJobWithContext(_ aws.Context, in *backup.StartBackupJobInput, _ ...request.Option) (*backup.StartBackupJobOutput, error) {
+	args := m.Called(aws.StringValue(in.ResourceArn), aws.StringValue(in.BackupVaultName), aws.StringValue(in.IamRoleArn))
+	return args.Get(0).(*backup.StartBackupJobOutput), args.Error(1)
+}
+
+func (m *mockBackup) DescribeBackupJobWithContext(_ aws.Context, in *backup.DescribeBackupJobInput, _ ...request.Option) (*backup.DescribeBackupJobOutput, error) {
+	args := m.Called(aws.StringValue(in.BackupJobId))
+	return args.Get(0).(*backup.DescribeBackupJobOutput), args.Error(1)
+}
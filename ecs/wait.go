@@ -18,22 +18,39 @@ package ecs
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/docker/compose-cli/errdefs"
 	"github.com/docker/compose-cli/progress"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/moby/term"
+	"github.com/sirupsen/logrus"
 )
 
-func (b *ecsAPIService) WaitStackCompletion(ctx context.Context, name string, operation int, ignored ...string) error { //nolint:gocyclo
-	knownEvents := map[string]struct{}{}
-	for _, id := range ignored {
-		knownEvents[id] = struct{}{}
-	}
+func (b *ecsAPIService) WaitStackCompletion(ctx context.Context, name string, operation int, ignored ...string) error {
+	return b.waitStackCompletion(ctx, name, operation, waitOptions{rollback: true}, ignored...)
+}
+
+// waitOptions tune how a deployment is waited for
+type waitOptions struct {
+	// fallbacks are the capacity provider strategies services which can't get capacity get redeployed with
+	fallbacks map[string][]capacityStrategy
+	// timeout cancels a stack update not completed in time. Stack creation timeout is set on the stack itself
+	timeout time.Duration
+	// rollback deletes a stack creation failing early, which is otherwise left as is
+	rollback bool
+}
 
+// waitStackCompletion reports stack events until operation completes, redeploying services which can't get capacity
+// with their fallback capacity provider strategy. The update is cancelled if ctx gets cancelled
+func (b *ecsAPIService) waitStackCompletion(ctx context.Context, name string, operation int, options waitOptions, ignored ...string) error {
 	// progress writer
 	w := progress.ContextWriter(ctx)
 	// Get the unique Stack ID so we can collect events without getting some from previous deployments with same name
@@ -43,89 +60,224 @@ func (b *ecsAPIService) WaitStackCompletion(ctx context.Context, name string, op
 	}
 
 	ticker := time.NewTicker(1 * time.Second)
-	done := make(chan bool)
+	done := make(chan bool, 1)
+	var waitErr error
 	go func() {
-		b.SDK.WaitStackComplete(ctx, stackID, operation) //nolint:errcheck
+		waitErr = b.SDK.WaitStackComplete(ctx, stackID, operation)
 		ticker.Stop()
 		done <- true
 	}()
 
-	var completed bool
-	var stackErr error
+	p := newStackProgress(operation, ignored)
+	serviceEvents := newServiceEvents(time.Now(), nil)
+	var (
+		completed bool
+		taskErr   error // services failing to start during an update, the user is offered to cancel
+		deadline  <-chan time.Time
+	)
+	if operation == stackUpdate && options.timeout > 0 {
+		timer := time.NewTimer(options.timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
 	for !completed {
 		select {
 		case <-done:
 			completed = true
 		case <-ticker.C:
+		case <-ctx.Done():
+			if p.operation == stackUpdate {
+				// the deployment isn't waited for anymore, so it's not left running unattended
+				b.SDK.CancelUpdateStack(context.Background(), name) // nolint:errcheck
+			}
+			return ctx.Err()
+		case <-deadline:
+			if p.operation == stackUpdate && p.failure() == nil {
+				if err := b.SDK.CancelUpdateStack(ctx, name); err != nil {
+					return err
+				}
+				p.err = &errdefs.Error{
+					Kind: errdefs.ErrTimeout,
+					Err:  fmt.Errorf("stack update didn't complete within %s, it is rolled back", options.timeout),
+				}
+				p.operation = stackDelete
+			}
 		}
 		events, err := b.SDK.DescribeStackEvents(ctx, stackID)
 		if err != nil {
 			return err
 		}
+		p.report(w, events)
+		serviceEvents.stackChanged(events)
+		if p.operation != stackDelete {
+			// service events are diagnostics, failing to get them doesn't fail the deployment
+			if err := b.reportServiceEvents(ctx, name, serviceEvents, w); err != nil {
+				logrus.Debugf("failed to get services events: %v", err)
+			}
+		}
 
-		sort.Slice(events, func(i, j int) bool {
-			return events[i].Timestamp.Before(*events[j].Timestamp)
+		if p.operation == stackDelete || p.failure() != nil {
+			continue
+		}
+		err = b.checkStackState(ctx, name, options.fallbacks)
+		if err == nil {
+			continue
+		}
+		if p.operation == stackCreate && !options.rollback {
+			// failed resources are kept for inspection, stack creation won't complete before it times out
+			return classify(err, errdefs.ErrDeploymentFailed)
+		}
+		if p.operation == stackUpdate && interactive() {
+			// the update may still succeed, the user decides whether to wait for it or interrupt it
+			if taskErr == nil || taskErr.Error() != err.Error() {
+				taskErr = err
+				w.Event(progress.Event{
+					ID:         name,
+					Status:     progress.Error,
+					StatusText: fmt.Sprintf("%s, press Ctrl+C to cancel the update", err.Error()),
+				})
+			}
+			continue
+		}
+		cancel := b.deleteFailedStack
+		if p.operation == stackUpdate {
+			cancel = b.SDK.CancelUpdateStack
+		}
+		if e := cancel(ctx, name); e != nil {
+			return e
+		}
+		p.err = classify(err, errdefs.ErrDeploymentFailed)
+		p.operation = stackDelete
+		w.Event(progress.Event{
+			ID:         name,
+			Status:     progress.Error,
+			StatusText: err.Error(),
 		})
+	}
+
+	stackErr := p.failure()
+	if p.err == nil && stackErr != nil && taskErr != nil {
+		// cancellations of the update don't explain why it failed, the services failing to start do
+		return classify(taskErr, errdefs.ErrDeploymentFailed)
+	}
+	if stackErr == nil && waitErr != nil {
+		// waiter fails when stack reaches a failure state, which is reported by stack events, or on timeout
+		if err := classify(waitErr, errdefs.ErrDeploymentFailed); errors.Is(err, errdefs.ErrTimeout) {
+			return err
+		}
+	}
+	return stackErr
+}
 
-		for _, event := range events {
-			if _, ok := knownEvents[*event.EventId]; ok {
-				continue
+// deleteFailedStack deletes a stack which failed to be created, disabling the termination protection it may have been
+// created with
+func (b *ecsAPIService) deleteFailedStack(ctx context.Context, name string) error {
+	if err := b.SDK.UpdateTerminationProtection(ctx, name, false); err != nil {
+		return err
+	}
+	return b.SDK.DeleteStack(ctx, name)
+}
+
+// stackProgress reports stack events as the progress of each resource, and keeps the error explaining a failed operation
+type stackProgress struct {
+	operation int
+	known     map[string]struct{}
+	statuses  map[string]string // last reported status, by resource
+	err       error             // first resource failure
+	cancelled error             // first resource cancellation, which only explains a failure when no other is reported
+}
+
+func newStackProgress(operation int, ignored []string) *stackProgress {
+	p := &stackProgress{
+		operation: operation,
+		known:     map[string]struct{}{},
+		statuses:  map[string]string{},
+	}
+	for _, id := range ignored {
+		p.known[id] = struct{}{}
+	}
+	return p
+}
+
+// report writes the events not reported yet, in chronological order
+func (p *stackProgress) report(w progress.Writer, events []*cloudformation.StackEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(*events[j].Timestamp)
+	})
+
+	for _, event := range events {
+		if _, ok := p.known[*event.EventId]; ok {
+			continue
+		}
+		p.known[*event.EventId] = struct{}{}
+
+		resource := aws.StringValue(event.LogicalResourceId)
+		reason := aws.StringValue(event.ResourceStatusReason)
+		status := aws.StringValue(event.ResourceStatus)
+		progressStatus := progress.Working
+
+		switch status {
+		case "CREATE_COMPLETE":
+			if p.operation == stackCreate {
+				progressStatus = progress.Done
 			}
-			knownEvents[*event.EventId] = struct{}{}
-
-			resource := aws.StringValue(event.LogicalResourceId)
-			reason := shortenMessage(
-				aws.StringValue(event.ResourceStatusReason))
-			status := aws.StringValue(event.ResourceStatus)
-			progressStatus := progress.Working
-
-			switch status {
-			case "CREATE_COMPLETE":
-				if operation == stackCreate {
-					progressStatus = progress.Done
-				}
-			case "UPDATE_COMPLETE":
-				if operation == stackUpdate {
-					progressStatus = progress.Done
-				}
-			case "DELETE_COMPLETE":
-				if operation == stackDelete {
-					progressStatus = progress.Done
-				}
-			default:
-				if strings.HasSuffix(status, "_FAILED") {
-					progressStatus = progress.Error
-					if stackErr == nil {
-						operation = stackDelete
-						stackErr = fmt.Errorf(reason)
-					}
-				}
+		case "UPDATE_COMPLETE":
+			if p.operation == stackUpdate {
+				progressStatus = progress.Done
+			}
+		case "DELETE_COMPLETE":
+			if p.operation == stackDelete {
+				progressStatus = progress.Done
+			}
+		default:
+			if strings.HasSuffix(status, "_FAILED") {
+				progressStatus = progress.Error
+				p.resourceFailed(resource, reason)
 			}
-			w.Event(progress.Event{
-				ID:         resource,
-				Status:     progressStatus,
-				StatusText: fmt.Sprintf("%s %s", status, reason),
-			})
 		}
-		if operation != stackCreate || stackErr != nil {
+
+		// CloudFormation reports intermediate states more than once, with reasons such as "Resource creation Initiated"
+		if progressStatus == progress.Working && p.statuses[resource] == status {
 			continue
 		}
-		if err := b.checkStackState(ctx, name); err != nil {
-			if e := b.SDK.DeleteStack(ctx, name); e != nil {
-				return e
-			}
-			stackErr = err
-			operation = stackDelete
-			reason := shortenMessage(err.Error())
-			w.Event(progress.Event{
-				ID:         name,
-				Status:     progress.Error,
-				StatusText: reason,
-			})
+		p.statuses[resource] = status
+
+		text := fmt.Sprintf("%s %s", status, shortenMessage(reason))
+		if progressStatus == progress.Error {
+			// failure reasons are reported in full, as they explain what went wrong
+			text = fmt.Sprintf("%s %s", status, reason)
+		}
+		w.Event(progress.Event{
+			ID:         resource,
+			Status:     progressStatus,
+			StatusText: text,
+		})
+	}
+}
+
+func (p *stackProgress) resourceFailed(resource string, reason string) {
+	// once a resource fails, CloudFormation cancels the ones still in progress, which doesn't explain the failure
+	if strings.HasPrefix(reason, "Resource creation cancelled") || strings.HasPrefix(reason, "Resource update cancelled") {
+		if p.cancelled == nil {
+			p.cancelled = stackEventError(resource, reason)
 		}
+	} else if p.err == nil {
+		p.err = stackEventError(resource, reason)
+	}
+	p.operation = stackDelete
+}
+
+// failure is the error explaining the operation failure, if any
+func (p *stackProgress) failure() error {
+	if p.err != nil {
+		return p.err
 	}
+	return p.cancelled
+}
 
-	return stackErr
+// interactive tells whether the user can interrupt a deployment, which is otherwise cancelled as soon as it fails
+var interactive = func() bool {
+	return term.IsTerminal(os.Stdin.Fd())
 }
 
 func shortenMessage(message string) string {
@@ -0,0 +1,245 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+
+	"github.com/docker/compose-cli/errdefs"
+	"github.com/docker/compose-cli/progress"
+)
+
+type recordingWriter struct {
+	events []progress.Event
+}
+
+func (w *recordingWriter) Start(context.Context) error { return nil }
+
+func (w *recordingWriter) Stop() {}
+
+func (w *recordingWriter) Event(e progress.Event) {
+	w.events = append(w.events, e)
+}
+
+func stackEvent(id int, resource string, status string, reason string) *cloudformation.StackEvent {
+	return &cloudformation.StackEvent{
+		EventId:              aws.String(resource + status + reason),
+		LogicalResourceId:    aws.String(resource),
+		ResourceStatus:       aws.String(status),
+		ResourceStatusReason: aws.String(reason),
+		Timestamp:            aws.Time(time.Unix(int64(id), 0)),
+	}
+}
+
+func TestStackProgress(t *testing.T) {
+	w := &recordingWriter{}
+	p := newStackProgress(stackCreate, nil)
+	p.report(w, []*cloudformation.StackEvent{
+		stackEvent(5, "Cluster", "CREATE_FAILED", "Resource creation cancelled"),
+		stackEvent(1, "Cluster", "CREATE_IN_PROGRESS", ""),
+		stackEvent(2, "Cluster", "CREATE_IN_PROGRESS", "Resource creation Initiated"),
+		stackEvent(3, "LoadBalancer", "CREATE_IN_PROGRESS", ""),
+		stackEvent(4, "LoadBalancer", "CREATE_FAILED", "At least two subnets in two different Availability Zones must be specified"),
+	})
+	// events already reported are skipped
+	p.report(w, []*cloudformation.StackEvent{
+		stackEvent(4, "LoadBalancer", "CREATE_FAILED", "At least two subnets in two different Availability Zones must be specified"),
+		stackEvent(6, "LoadBalancer", "DELETE_COMPLETE", ""),
+	})
+
+	var reported []string
+	for _, e := range w.events {
+		reported = append(reported, fmt.Sprintf("%s %d %s", e.ID, e.Status, e.StatusText))
+	}
+	assert.DeepEqual(t, reported, []string{
+		fmt.Sprintf("Cluster %d CREATE_IN_PROGRESS ", progress.Working),
+		fmt.Sprintf("LoadBalancer %d CREATE_IN_PROGRESS ", progress.Working),
+		fmt.Sprintf("LoadBalancer %d CREATE_FAILED At least two subnets in two different Availability Zones must be specified", progress.Error),
+		fmt.Sprintf("Cluster %d CREATE_FAILED Resource creation cancelled", progress.Error),
+		fmt.Sprintf("LoadBalancer %d DELETE_COMPLETE ", progress.Done),
+	})
+	assert.Equal(t, p.operation, stackDelete)
+
+	err := p.failure()
+	assert.Error(t, err, "At least two subnets in two different Availability Zones must be specified")
+	assert.Check(t, errors.Is(err, errdefs.ErrDeploymentFailed))
+	var details *errdefs.Error
+	assert.Check(t, errors.As(err, &details))
+	assert.Equal(t, details.Resource, "LoadBalancer")
+}
+
+func TestStackProgressCancelledOnly(t *testing.T) {
+	p := newStackProgress(stackCreate, nil)
+	p.report(&recordingWriter{}, []*cloudformation.StackEvent{
+		stackEvent(1, "Cluster", "CREATE_FAILED", "Resource creation cancelled"),
+	})
+	assert.Error(t, p.failure(), "Resource creation cancelled")
+}
+
+func failingServiceBackend(stopped ...*ecsapi.Task) *ecsAPIService {
+	cf := &mockCloudFormation{}
+	cf.On("ListStackResourcesWithContext", "test").Return(&cloudformation.ListStackResourcesOutput{
+		StackResourceSummaries: []*cloudformation.StackResourceSummary{
+			{LogicalResourceId: aws.String("Cluster"), ResourceType: aws.String("AWS::ECS::Cluster"), PhysicalResourceId: aws.String("cluster")},
+			{LogicalResourceId: aws.String("WebService"), ResourceType: aws.String("AWS::ECS::Service"), PhysicalResourceId: aws.String("arn:web")},
+		},
+	}, nil)
+	ecsMock := &mockECS{}
+	ecsMock.On("DescribeServicesWithContext", "arn:web").Return(&ecsapi.DescribeServicesOutput{
+		Services: []*ecsapi.Service{
+			{ServiceArn: aws.String("arn:web"), TaskDefinition: aws.String("arn:web-task:2")},
+		},
+	}, nil)
+	ecsMock.On("ListTasksWithContext", "RUNNING").Return(&ecsapi.ListTasksOutput{
+		TaskArns: aws.StringSlice([]string{"arn:previous"}),
+	}, nil)
+	ecsMock.On("DescribeTasksWithContext", "arn:previous").Return(&ecsapi.DescribeTasksOutput{
+		Tasks: []*ecsapi.Task{{TaskArn: aws.String("arn:previous"), TaskDefinitionArn: aws.String("arn:web-task:1")}},
+	}, nil)
+	var arns []string
+	for _, t := range stopped {
+		arns = append(arns, aws.StringValue(t.TaskArn))
+	}
+	ecsMock.On("ListTasksWithContext", "STOPPED").Return(&ecsapi.ListTasksOutput{
+		TaskArns: aws.StringSlice(arns),
+	}, nil)
+	ecsMock.On("DescribeTasksWithContext", arns[0]).Return(&ecsapi.DescribeTasksOutput{Tasks: stopped}, nil)
+	return &ecsAPIService{SDK: sdk{CF: cf, ECS: ecsMock}}
+}
+
+func stoppedTask(arn string, taskdef string, stopped int, containers ...*ecsapi.Container) *ecsapi.Task {
+	return &ecsapi.Task{
+		TaskArn:           aws.String(arn),
+		TaskDefinitionArn: aws.String(taskdef),
+		StopCode:          aws.String(ecsapi.TaskStopCodeTaskFailedToStart),
+		StoppedReason:     aws.String(fmt.Sprintf("failure %d", stopped)),
+		StoppedAt:         aws.Time(time.Unix(int64(stopped), 0)),
+		Containers:        containers,
+	}
+}
+
+func TestCheckStackStateTasksFailingToStart(t *testing.T) {
+	backend := failingServiceBackend(
+		stoppedTask("arn:1", "arn:web-task:2", 1),
+		stoppedTask("arn:3", "arn:web-task:2", 3,
+			&ecsapi.Container{Name: aws.String("web"), Reason: aws.String("CannotPullContainerError: pull access denied")},
+			&ecsapi.Container{Name: aws.String("sidecar"), ExitCode: aws.Int64(1)},
+		),
+		stoppedTask("arn:2", "arn:web-task:2", 2),
+		stoppedTask("arn:0", "arn:web-task:1", 4),
+	)
+	err := backend.checkStackState(context.TODO(), "test", nil)
+	assert.Error(t, err, "WebService 3 tasks failed to start, last one stopped with TaskFailedToStart: failure 3 (web: CannotPullContainerError: pull access denied, sidecar exited with code 1)")
+	var e *errdefs.Error
+	assert.Assert(t, errors.As(err, &e))
+	assert.Equal(t, e.Resource, "WebService")
+}
+
+func TestCheckStackStateTasksBelowFailureThreshold(t *testing.T) {
+	backend := failingServiceBackend(
+		stoppedTask("arn:1", "arn:web-task:2", 1),
+		stoppedTask("arn:2", "arn:web-task:2", 2),
+		stoppedTask("arn:0", "arn:web-task:1", 4),
+		stoppedTask("arn:-1", "arn:web-task:1", 5),
+	)
+	err := backend.checkStackState(context.TODO(), "test", nil)
+	assert.NilError(t, err)
+}
+
+func (m *mockCloudFormation) CreateStackWithContext(_ aws.Context, in *cloudformation.CreateStackInput, _ ...request.Option) (*cloudformation.CreateStackOutput, error) {
+	args := m.Called(aws.StringValue(in.OnFailure), aws.Int64Value(in.TimeoutInMinutes))
+	return &cloudformation.CreateStackOutput{}, args.Error(0)
+}
+
+func (m *mockCloudFormation) CancelUpdateStackWithContext(_ aws.Context, in *cloudformation.CancelUpdateStackInput, _ ...request.Option) (*cloudformation.CancelUpdateStackOutput, error) {
+	args := m.Called(aws.StringValue(in.StackName))
+	return &cloudformation.CancelUpdateStackOutput{}, args.Error(0)
+}
+
+func (m *mockCloudFormation) WaitUntilStackUpdateCompleteWithContext(_ aws.Context, in *cloudformation.DescribeStacksInput, _ ...request.WaiterOption) error {
+	args := m.Called(aws.StringValue(in.StackName))
+	return args.Error(0)
+}
+
+func (m *mockCloudFormation) DescribeStackEventsWithContext(_ aws.Context, in *cloudformation.DescribeStackEventsInput, _ ...request.Option) (*cloudformation.DescribeStackEventsOutput, error) {
+	args := m.Called(aws.StringValue(in.StackName))
+	return args.Get(0).(*cloudformation.DescribeStackEventsOutput), args.Error(1)
+}
+
+func TestCreateStackTimeoutAndRollback(t *testing.T) {
+	cf := &mockCloudFormation{}
+	cf.On("CreateStackWithContext", "DELETE", int64(0)).Return(nil)
+	cf.On("CreateStackWithContext", "DO_NOTHING", int64(2)).Return(nil)
+
+	err := sdk{CF: cf}.CreateStack(context.TODO(), "test", []byte("{}"), nil, 0, true, "", nil)
+	assert.NilError(t, err)
+	err = sdk{CF: cf}.CreateStack(context.TODO(), "test", []byte("{}"), nil, 90*time.Second, false, "", nil)
+	assert.NilError(t, err)
+	cf.AssertExpectations(t)
+}
+
+func TestWaitStackUpdateTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "test").Return(&cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{{StackId: aws.String("arn:test")}},
+	}, nil)
+	cf.On("WaitUntilStackUpdateCompleteWithContext", "arn:test").Return(errors.New("ResourceNotReady: failed waiting for successful resource state")).
+		Run(func(mock.Arguments) { <-cancelled })
+	cf.On("CancelUpdateStackWithContext", "test").Return(nil).Run(func(mock.Arguments) { close(cancelled) })
+	cf.On("DescribeStackEventsWithContext", "arn:test").Return(&cloudformation.DescribeStackEventsOutput{}, nil)
+	cf.On("ListStackResourcesWithContext", "test").Return(&cloudformation.ListStackResourcesOutput{}, nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cf}}
+	err := backend.waitStackCompletion(context.TODO(), "test", stackUpdate, waitOptions{timeout: 10 * time.Millisecond, rollback: true})
+	assert.Error(t, err, "stack update didn't complete within 10ms, it is rolled back")
+	assert.Equal(t, errdefs.ExitCode(err), errdefs.ExitCodeTimeout)
+	cf.AssertNumberOfCalls(t, "CancelUpdateStackWithContext", 1)
+}
+
+func TestWaitStackUpdateCancelledWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	released := make(chan struct{})
+	cf := &mockCloudFormation{}
+	cf.On("DescribeStacksWithContext", "test").Return(&cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{{StackId: aws.String("arn:test")}},
+	}, nil)
+	cf.On("WaitUntilStackUpdateCompleteWithContext", "arn:test").Return(context.Canceled).
+		Run(func(mock.Arguments) {
+			cancel()
+			<-released
+		})
+	cf.On("CancelUpdateStackWithContext", "test").Return(nil).Run(func(mock.Arguments) { close(released) })
+	cf.On("DescribeStackEventsWithContext", "arn:test").Return(&cloudformation.DescribeStackEventsOutput{}, nil)
+	cf.On("ListStackResourcesWithContext", "test").Return(&cloudformation.ListStackResourcesOutput{}, nil)
+
+	backend := &ecsAPIService{SDK: sdk{CF: cf}}
+	err := backend.waitStackCompletion(ctx, "test", stackUpdate, waitOptions{rollback: true})
+	assert.Assert(t, errors.Is(err, context.Canceled))
+	cf.AssertNumberOfCalls(t, "CancelUpdateStackWithContext", 1)
+}
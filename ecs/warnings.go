@@ -0,0 +1,148 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/docker/compose-cli/api/compose"
+)
+
+// Warning codes are used by automation to gate on specific warnings, they MUST be kept stable across releases
+const (
+	warningUnsupportedAttribute   = "unsupported-attribute"
+	warningSecurityGroupExtension = "deprecated-securitygroup-extension"
+	warningPublicIngress          = "public-ingress"
+	warningSecretInTemplate       = "secret-in-template"
+	warningEC2LaunchType          = "ec2-launch-type"
+	warningSingleAvailabilityZone = "single-availability-zone"
+	warningContextDefault         = "context-default"
+	warningKMSKeyPolicy           = "kms-key-policy"
+	warningUnknownLoggingOption   = "unknown-logging-option"
+	warningFileSystemSettings     = "filesystem-settings"
+	warningRolePermissions        = "role-permissions"
+	warningCrossAccountImage      = "cross-account-image"
+	warningCrossRegionImage       = "cross-region-image"
+	warningContainerInsights      = "container-insights"
+	warningIgnoredAttribute       = "ignored-attribute"
+	warningInvalidTag             = "invalid-tag"
+	warningDashboardTrimmed       = "dashboard-trimmed"
+	warningPrivateTaskPull        = "private-task-pull"
+)
+
+const (
+	severityInfo    = "info"
+	severityWarning = "warning"
+)
+
+const warningsFormatJSON = "json"
+
+// convertWarning is a non-fatal issue detected while converting a compose project
+type convertWarning struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Service  string `json:"service,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (w convertWarning) String() string {
+	if w.Service != "" {
+		return fmt.Sprintf("[%s] service %s: %s", w.Code, w.Service, w.Message)
+	}
+	return fmt.Sprintf("[%s] %s", w.Code, w.Message)
+}
+
+type convertWarnings []convertWarning
+
+func (b *ecsAPIService) warn(code string, severity string, service string, message string, args ...interface{}) {
+	b.warningsLock.Lock()
+	defer b.warningsLock.Unlock()
+	b.warnings = append(b.warnings, convertWarning{
+		Code:     code,
+		Severity: severity,
+		Service:  service,
+		Message:  fmt.Sprintf(message, args...),
+	})
+}
+
+// report writes warnings in the selected format
+func (warnings convertWarnings) report(w io.Writer, format string) error {
+	if format == warningsFormatJSON {
+		if len(warnings) == 0 {
+			return nil
+		}
+		raw, err := json.MarshalIndent(warnings, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(raw))
+		return err
+	}
+	for _, warning := range warnings {
+		switch warning.Severity {
+		case severityInfo:
+			logrus.Info(warning.String())
+		default:
+			logrus.Warn(warning.String())
+		}
+	}
+	return nil
+}
+
+// toAPI returns warnings as reported to API callers
+func (warnings convertWarnings) toAPI() []compose.Warning {
+	reported := make([]compose.Warning, len(warnings))
+	for i, w := range warnings {
+		reported[i] = compose.Warning(w)
+	}
+	return reported
+}
+
+// sortFrom sorts warnings reported after the first n ones by code and message
+func (warnings convertWarnings) sortFrom(n int) {
+	reported := warnings[n:]
+	sort.SliceStable(reported, func(i, j int) bool {
+		if reported[i].Code != reported[j].Code {
+			return reported[i].Code < reported[j].Code
+		}
+		return reported[i].Message < reported[j].Message
+	})
+}
+
+// check returns an error if any warning has a code listed as to be considered an error
+func (warnings convertWarnings) check(asErrors []string) error {
+	fatal := map[string]bool{}
+	for _, code := range asErrors {
+		fatal[strings.TrimSpace(code)] = true
+	}
+	var failed []string
+	for _, warning := range warnings {
+		if fatal[warning.Code] {
+			failed = append(failed, warning.String())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("conversion failed due to warnings treated as errors:\n%s", strings.Join(failed, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWarningsToAPI(t *testing.T) {
+	b := &ecsAPIService{}
+	b.warn(warningPublicIngress, severityInfo, "web", "port %d/%s is open to 0.0.0.0/0 on network %s", 80, "tcp", "default")
+	b.warn(warningSecretInTemplate, severityWarning, "", "content of secret %s is embedded in the CloudFormation template", "token")
+
+	raw, err := json.Marshal(b.warnings.toAPI())
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), `[`+
+		`{"code":"public-ingress","severity":"info","service":"web","message":"port 80/tcp is open to 0.0.0.0/0 on network default"},`+
+		`{"code":"secret-in-template","severity":"warning","message":"content of secret token is embedded in the CloudFormation template"}`+
+		`]`)
+}
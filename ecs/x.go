@@ -17,17 +17,77 @@
 package ecs
 
 const (
-	extensionSecurityGroup   = "x-aws-securitygroup"
-	extensionVPC             = "x-aws-vpc"
-	extensionPullCredentials = "x-aws-pull_credentials"
-	extensionLoadBalancer    = "x-aws-loadbalancer"
-	extensionProtocol        = "x-aws-protocol"
-	extensionCluster         = "x-aws-cluster"
-	extensionKeys            = "x-aws-keys"
-	extensionMinPercent      = "x-aws-min_percent"
-	extensionMaxPercent      = "x-aws-max_percent"
-	extensionRetention       = "x-aws-logs_retention"
-	extensionRole            = "x-aws-role"
-	extensionManagedPolicies = "x-aws-policies"
-	extensionAutoScaling     = "x-aws-autoscaling"
+	extensionSecurityGroup                = "x-aws-securitygroup"
+	extensionVPC                          = "x-aws-vpc"
+	extensionParameterizeImages           = "x-aws-parameterize_images"
+	extensionPullCredentials              = "x-aws-pull_credentials"
+	extensionLoadBalancer                 = "x-aws-loadbalancer"
+	extensionProtocol                     = "x-aws-protocol"
+	extensionCluster                      = "x-aws-cluster"
+	extensionKeys                         = "x-aws-keys"
+	extensionMinPercent                   = "x-aws-min_percent"
+	extensionMaxPercent                   = "x-aws-max_percent"
+	extensionRetention                    = "x-aws-logs_retention"
+	extensionLogsGroup                    = "x-aws-logs_group"
+	extensionLogsRetain                   = "x-aws-logs_retain"
+	extensionRole                         = "x-aws-role"
+	extensionManagedPolicies              = "x-aws-policies"
+	extensionAutoScaling                  = "x-aws-autoscaling"
+	extensionDeployMarkers                = "x-aws-deploy-markers"
+	extensionLabelsAsTags                 = "x-aws-labels_as_tags"
+	extensionTemplateBucket               = "x-aws-template_bucket"
+	extensionNestedStacks                 = "x-aws-nested_stacks"
+	extensionTags                         = "x-aws-tags"
+	extensionExportsPrefix                = "x-aws-exports_prefix"
+	extensionEnvFilesBucket               = "x-aws-env_files_bucket"
+	extensionResources                    = "x-aws-resources"
+	extensionSSMParameter                 = "x-aws-ssm_parameter"
+	extensionMaxTaskLifetime              = "x-aws-max-task-lifetime"
+	extensionSubnets                      = "x-aws-subnets"
+	extensionKMSKey                       = "x-aws-kms_key"
+	extensionProxyConfiguration           = "x-aws-proxy-configuration"
+	extensionEnvironment                  = "x-aws-environment"
+	extensionCapacityProvider             = "x-aws-capacity-provider"
+	extensionImageScan                    = "x-aws-image-scan"
+	extensionSidecarOf                    = "x-aws-sidecar_of"
+	extensionFallbackCapacity             = "x-aws-fallback-capacity"
+	extensionVolumesBackup                = "x-aws-volumes_backup"
+	extensionVolumesDeletion              = "x-aws-volumes_deletion"
+	extensionVolumesBackupVault           = "x-aws-volumes_backup_vault"
+	extensionVolumesBackupRole            = "x-aws-volumes_backup_role"
+	extensionTaskRoleArn                  = "x-aws-task_role_arn"
+	extensionExecutionRoleArn             = "x-aws-execution_role_arn"
+	extensionPermissionsBoundary          = "x-aws-iam_permissions_boundary"
+	extensionExecutionManagedPolicies     = "x-aws-execution_managed_policies"
+	extensionIAMPath                      = "x-aws-iam_path"
+	extensionCloudFormation               = "x-aws-cloudformation"
+	extensionOverrides                    = "x-aws-overrides"
+	extensionDeploymentController         = "x-aws-deployment_controller"
+	extensionTestListenerPort             = "x-aws-test_listener_port"
+	extensionEnableExecuteCommand         = "x-aws-enable_execute_command"
+	extensionServiceConnect               = "x-aws-service_connect"
+	extensionContainerInsights            = "x-aws-container_insights"
+	extensionIgnore                       = "x-aws-ignore"
+	extensionBuildImages                  = "x-aws-build_images"
+	extensionPinImages                    = "x-aws-pin_images"
+	extensionSchedule                     = "x-aws-schedule"
+	extensionPlacementStrategy            = "x-aws-placement_strategy"
+	extensionStartTimeout                 = "x-aws-start_timeout"
+	extensionAutoScalingGroup             = "x-aws-autoscaling_group"
+	extensionManagedScaling               = "x-aws-managed_scaling"
+	extensionManagedTerminationProtection = "x-aws-managed_termination_protection"
+	extensionEC2Spot                      = "x-aws-ec2_spot"
+	extensionEC2UserData                  = "x-aws-ec2_user_data"
+	extensionEC2VolumeSize                = "x-aws-ec2_volume_size"
+	extensionEC2VolumeType                = "x-aws-ec2_volume_type"
+	extensionRoleArn                      = "x-aws-role_arn"
+	extensionStackName                    = "x-aws-stack_name"
+	extensionProtect                      = "x-aws-protect"
+	extensionSNSTopic                     = "x-aws-sns_topic"
+	extensionAlarms                       = "x-aws-alarms"
+	extensionDashboard                    = "x-aws-dashboard"
+	extensionXRay                         = "x-aws-xray"
+	extensionAppMesh                      = "x-aws-appmesh"
+	extensionLaunchType                   = "x-aws-launch_type"
+	extensionAssignPublicIP               = "x-aws-assign_public_ip"
 )
@@ -0,0 +1,87 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"fmt"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/compose-spec/compose-go/types"
+)
+
+const (
+	xrayDaemonImage       = "amazon/aws-xray-daemon"
+	xrayDaemonWriteAccess = "arn:aws:iam::aws:policy/AWSXRayDaemonWriteAccess"
+	xrayDaemonEnvironment = "AWS_XRAY_DAEMON_ADDRESS"
+	xrayDaemonPort        = 2000
+	xrayDaemonCPU         = 32
+	// xrayDaemonMemoryReservation is the daemon memory reservation, in MiB
+	xrayDaemonMemoryReservation = 256
+	// xrayDaemonAddress is where containers reach the daemon, as containers of a task share its network interface
+	xrayDaemonAddress = "127.0.0.1:2000"
+)
+
+// xrayEnabled tells if x-aws-xray is set on service, or if service is a sidecar, on the service running its task
+func xrayEnabled(project *types.Project, service types.ServiceConfig) bool {
+	for _, member := range taskServices(project, service) {
+		if member.Extensions[extensionXRay] == true {
+			return true
+		}
+	}
+	return false
+}
+
+// addXRayDaemon runs the X-Ray daemon in service's task, for all its containers to send trace segments to
+func (b *ecsAPIService) addXRayDaemon(project *types.Project, service types.ServiceConfig, definition *ecs.TaskDefinition) {
+	if !xrayEnabled(project, service) {
+		return
+	}
+	members := map[string]bool{}
+	for _, member := range taskServices(project, service) {
+		members[member.Name] = true
+	}
+	for i, container := range definition.ContainerDefinitions {
+		if !members[container.Name] || hasEnvironment(container, xrayDaemonEnvironment) {
+			continue
+		}
+		definition.ContainerDefinitions[i].Environment = append(container.Environment, ecs.TaskDefinition_KeyValuePair{
+			Name:  xrayDaemonEnvironment,
+			Value: xrayDaemonAddress,
+		})
+	}
+	definition.ContainerDefinitions = append(definition.ContainerDefinitions, ecs.TaskDefinition_ContainerDefinition{
+		Cpu:   xrayDaemonCPU,
+		Image: xrayDaemonImage,
+		// the daemon failing must not stop the application
+		Essential:         false,
+		LogConfiguration:  b.getLogConfiguration(service, project),
+		MemoryReservation: xrayDaemonMemoryReservation,
+		Name:              fmt.Sprintf("%s_XRayDaemon", normalizeResourceName(service.Name)),
+		PortMappings: []ecs.TaskDefinition_PortMapping{
+			{ContainerPort: xrayDaemonPort, Protocol: "udp"},
+		},
+	})
+}
+
+func hasEnvironment(container ecs.TaskDefinition_ContainerDefinition, name string) bool {
+	for _, pair := range container.Environment {
+		if pair.Name == name {
+			return true
+		}
+	}
+	return false
+}
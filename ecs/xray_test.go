@@ -0,0 +1,89 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v4/cloudformation/ecs"
+	"github.com/awslabs/goformation/v4/cloudformation/iam"
+	"gotest.tools/v3/assert"
+)
+
+func TestXRayDaemon(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  app:
+    image: app
+    x-aws-xray: true
+  envoy:
+    image: envoyproxy/envoy
+    x-aws-sidecar_of: app
+    environment:
+      AWS_XRAY_DAEMON_ADDRESS: xray.local:2000
+  db:
+    image: mysql
+`)
+	def := template.Resources["AppTaskDefinition"].(*ecs.TaskDefinition)
+	containers := map[string]ecs.TaskDefinition_ContainerDefinition{}
+	for _, c := range def.ContainerDefinitions {
+		containers[c.Name] = c
+	}
+	daemon, ok := containers["App_XRayDaemon"]
+	assert.Check(t, ok)
+	assert.Equal(t, daemon.Image, "amazon/aws-xray-daemon")
+	assert.Check(t, !daemon.Essential)
+	assert.DeepEqual(t, daemon.PortMappings, []ecs.TaskDefinition_PortMapping{{ContainerPort: 2000, Protocol: "udp"}})
+
+	environment := func(container string) string {
+		for _, pair := range containers[container].Environment {
+			if pair.Name == "AWS_XRAY_DAEMON_ADDRESS" {
+				return pair.Value
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, environment("app"), "127.0.0.1:2000")
+	assert.Equal(t, environment("envoy"), "xray.local:2000")
+
+	role := template.Resources["AppTaskRole"].(*iam.Role)
+	assert.DeepEqual(t, role.ManagedPolicyArns, []string{"arn:aws:iam::aws:policy/AWSXRayDaemonWriteAccess"})
+
+	for _, c := range template.Resources["DbTaskDefinition"].(*ecs.TaskDefinition).ContainerDefinitions {
+		assert.Check(t, c.Image != "amazon/aws-xray-daemon")
+	}
+	_, ok = template.Resources["DbTaskRole"]
+	assert.Check(t, !ok)
+}
+
+func TestXRayDaemonTaskSize(t *testing.T) {
+	template := convertYaml(t, `
+services:
+  app:
+    image: app
+    x-aws-xray: true
+    deploy:
+      resources:
+        limits:
+          cpus: '0.25'
+          memory: 512M
+`)
+	// the daemon's reservations don't fit a 0.25 vCPU task with the app's limits
+	def := template.Resources["AppTaskDefinition"].(*ecs.TaskDefinition)
+	assert.Equal(t, def.Cpu, "512")
+	assert.Equal(t, def.Memory, "1024")
+}
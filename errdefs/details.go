@@ -0,0 +1,142 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Error is an error of a known kind, with details automation can rely on
+type Error struct {
+	// Kind is one of the ErrXXX errors, used to select the exit code
+	Kind error
+	// Service is the compose service the error relates to, if any
+	Service string
+	// Resource is the cloud resource the error relates to, if any
+	Resource string
+	// ProviderCode is the raw error code returned by the cloud provider API, if any
+	ProviderCode string
+	Err          error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is tells if target is the kind of this error
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// errorCodes are reported in machine-readable errors, they MUST be kept stable across releases
+var errorCodes = []struct {
+	kind     error
+	code     string
+	exitCode int
+}{
+	{ErrLoginRequired, "login-required", ExitCodeLoginRequired},
+	{ErrValidation, "validation-error", ExitCodeValidation},
+	{ErrAuthentication, "auth-error", ExitCodeAuthentication},
+	{ErrQuotaExceeded, "quota-exceeded", ExitCodeQuotaExceeded},
+	{ErrDeploymentFailed, "deployment-failed", ExitCodeDeploymentFailed},
+	{ErrTimeout, "timeout", ExitCodeTimeout},
+	{ErrThrottled, "throttled", ExitCodeThrottled},
+}
+
+// ExitCode returns the exit code matching the kind of err, 1 when unknown
+func ExitCode(err error) int {
+	var status exitStatusError
+	if errors.As(err, &status) {
+		return status.code
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.kind) {
+			return c.exitCode
+		}
+	}
+	return 1
+}
+
+// Details is the machine-readable form of an error
+type Details struct {
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+	Service      string `json:"service,omitempty"`
+	Resource     string `json:"resource,omitempty"`
+	ProviderCode string `json:"provider_code,omitempty"`
+}
+
+// ToDetails returns the machine-readable form of err
+func ToDetails(err error) Details {
+	details := Details{
+		Code:    "unknown",
+		Message: err.Error(),
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.kind) {
+			details.Code = c.code
+			break
+		}
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		details.Service = e.Service
+		details.Resource = e.Resource
+		details.ProviderCode = e.ProviderCode
+	}
+	return details
+}
+
+// exitStatusError is the non-zero exit code of a command run by a backend
+type exitStatusError struct {
+	code int
+}
+
+func (e exitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// ExitStatus returns an error the CLI exits with code from
+func ExitStatus(code int) error {
+	return exitStatusError{code}
+}
+
+// reportedError is an error which has already been reported to the user
+type reportedError struct {
+	error
+}
+
+func (e reportedError) Unwrap() error {
+	return e.error
+}
+
+// Reported marks err as already reported to the user, so it doesn't get printed again on exit
+func Reported(err error) error {
+	return reportedError{err}
+}
+
+// IsReported returns true if err has already been reported to the user
+func IsReported(err error) bool {
+	return errors.As(err, &reportedError{})
+}
@@ -0,0 +1,65 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitCode(errors.New("another error")), 1)
+	assert.Equal(t, ExitCode(errors.Wrap(ErrLoginRequired, "aci")), ExitCodeLoginRequired)
+	assert.Equal(t, ExitCode(&Error{Kind: ErrQuotaExceeded, Err: errors.New("too many VPCs")}), ExitCodeQuotaExceeded)
+
+	err := fmt.Errorf("deploying: %w", &Error{Kind: ErrDeploymentFailed, Err: errors.New("rollback")})
+	assert.Equal(t, ExitCode(err), ExitCodeDeploymentFailed)
+	assert.Equal(t, ExitCode(Reported(err)), ExitCodeDeploymentFailed)
+	assert.Equal(t, ExitCode(Reported(ExitStatus(3))), 3)
+}
+
+func TestToDetails(t *testing.T) {
+	err := errors.Wrap(&Error{
+		Kind:         ErrAuthentication,
+		Service:      "web",
+		Resource:     "WebService",
+		ProviderCode: "AccessDeniedException",
+		Err:          errors.New("not authorized"),
+	}, "deploying")
+	assert.DeepEqual(t, ToDetails(err), Details{
+		Code:         "auth-error",
+		Message:      "deploying: not authorized",
+		Service:      "web",
+		Resource:     "WebService",
+		ProviderCode: "AccessDeniedException",
+	})
+
+	assert.DeepEqual(t, ToDetails(errors.New("another error")), Details{
+		Code:    "unknown",
+		Message: "another error",
+	})
+}
+
+func TestIsReported(t *testing.T) {
+	err := errors.New("already printed")
+	assert.Assert(t, !IsReported(err))
+	assert.Assert(t, IsReported(errors.Wrap(Reported(err), "up")))
+	assert.Assert(t, errors.Is(Reported(ErrCanceled), ErrCanceled))
+}
@@ -24,6 +24,18 @@ const (
 	//ExitCodeLoginRequired exit code when command cannot execute because it requires cloud login
 	// This will be used by VSCode to detect when creating context if the user needs to login first
 	ExitCodeLoginRequired = 5
+	// ExitCodeValidation exit code when the project or command arguments are invalid
+	ExitCodeValidation = 10
+	// ExitCodeAuthentication exit code when cloud credentials are missing, expired or lack permissions
+	ExitCodeAuthentication = 11
+	// ExitCodeQuotaExceeded exit code when a cloud quota or limit has been reached
+	ExitCodeQuotaExceeded = 12
+	// ExitCodeDeploymentFailed exit code when deployment failed and has been rolled back
+	ExitCodeDeploymentFailed = 13
+	// ExitCodeTimeout exit code when an operation did not complete in time
+	ExitCodeTimeout = 14
+	// ExitCodeThrottled exit code when cloud API calls were still throttled after retries, the command can be retried later
+	ExitCodeThrottled = 15
 )
 
 var (
@@ -49,6 +61,20 @@ var (
 	// ErrWrongContextType is returned when the caller tries to get a context
 	// with the wrong type
 	ErrWrongContextType = errors.New("wrong context type")
+	// ErrValidation is returned when the project or command arguments are invalid
+	ErrValidation = errors.New("validation failed")
+	// ErrAuthentication is returned when cloud credentials are missing, expired
+	// or lack permissions
+	ErrAuthentication = errors.New("authentication failed")
+	// ErrQuotaExceeded is returned when a cloud quota or limit has been reached
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrDeploymentFailed is returned when deployment failed and has been
+	// rolled back
+	ErrDeploymentFailed = errors.New("deployment failed")
+	// ErrTimeout is returned when an operation did not complete in time
+	ErrTimeout = errors.New("timeout")
+	// ErrThrottled is returned when cloud API calls were still throttled after retries
+	ErrThrottled = errors.New("throttled")
 )
 
 // IsNotFoundError returns true if the unwrapped error is ErrNotFound
@@ -133,12 +133,12 @@ func (cs *containerService) Delete(ctx context.Context, id string, request conta
 
 type composeService struct{}
 
-func (cs *composeService) Up(ctx context.Context, project *types.Project, detach bool) error {
+func (cs *composeService) Up(ctx context.Context, project *types.Project, options compose.UpOptions) error {
 	fmt.Printf("Up command on project %q", project.Name)
 	return nil
 }
 
-func (cs *composeService) Down(ctx context.Context, project string) error {
+func (cs *composeService) Down(ctx context.Context, project string, options compose.DownOptions) error {
 	fmt.Printf("Down command on project %q", project)
 	return nil
 }
@@ -149,10 +149,54 @@ func (cs *composeService) Ps(ctx context.Context, project string) ([]compose.Ser
 func (cs *composeService) List(ctx context.Context, project string) ([]compose.Stack, error) {
 	return nil, errdefs.ErrNotImplemented
 }
-func (cs *composeService) Logs(ctx context.Context, project string, w io.Writer) error {
+func (cs *composeService) Logs(ctx context.Context, project string, w io.Writer, options compose.LogOptions) error {
 	return errdefs.ErrNotImplemented
 }
 
-func (cs *composeService) Convert(ctx context.Context, project *types.Project) ([]byte, error) {
+func (cs *composeService) Convert(ctx context.Context, project *types.Project, options compose.ConvertOptions) (compose.ConvertResult, error) {
+	return compose.ConvertResult{}, errdefs.ErrNotImplemented
+}
+
+func (cs *composeService) Orphans(ctx context.Context, project string) ([]compose.Orphan, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (cs *composeService) RemoveOrphans(ctx context.Context, orphans []compose.Orphan) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (cs *composeService) ProjectOrphans(ctx context.Context, project *types.Project) ([]compose.Orphan, error) {
 	return nil, errdefs.ErrNotImplemented
 }
+
+func (cs *composeService) DNSRecords(ctx context.Context, project string) ([]compose.DNSRecord, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (cs *composeService) Drift(ctx context.Context, project string) ([]compose.DriftedResource, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (cs *composeService) Exec(ctx context.Context, project string, options compose.ExecOptions) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (cs *composeService) Run(ctx context.Context, project string, options compose.RunOptions) (int, error) {
+	return 0, errdefs.ErrNotImplemented
+}
+
+func (cs *composeService) Events(ctx context.Context, project string, consumer func(compose.ServiceEvent), options compose.EventsOptions) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (cs *composeService) Scale(ctx context.Context, project string, options compose.ScaleOptions) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (cs *composeService) Stop(ctx context.Context, project string) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (cs *composeService) Start(ctx context.Context, project string, options compose.StartOptions) error {
+	return errdefs.ErrNotImplemented
+}